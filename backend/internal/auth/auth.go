@@ -1,11 +1,13 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"log"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
-	"golang.org/x/crypto/bcrypt"
 	"golangmcp/internal/models"
 	"gorm.io/gorm"
 )
@@ -14,7 +16,8 @@ import (
 type Claims struct {
 	UserID   uint   `json:"user_id"`
 	Username string `json:"username"`
-	Role     string `json:"role"`
+	Role     string   `json:"role"`
+	Scopes   []string `json:"scopes,omitempty"` // empty = full role permissions
 	jwt.StandardClaims
 }
 
@@ -32,50 +35,80 @@ type RegisterRequest struct {
 	Role     string `json:"role"`
 }
 
+// AuthUser is the subset of the user record returned to the client on login
+type AuthUser struct {
+	ID       uint   `json:"id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	Avatar   string `json:"avatar"`
+}
+
 // AuthResponse represents the authentication response
 type AuthResponse struct {
-	Token      string      `json:"token"`
-	User       models.User `json:"user"`
+	Token      string      `json:"token,omitempty"` // omitted when issued as a session cookie only
+	User       AuthUser    `json:"user"`
 	ExpiresAt  time.Time   `json:"expires_at"`
 	SessionID  string      `json:"session_id"`
 }
 
+// MaxTokenTTL is the longest lifetime GenerateScopedJWT issues a token for
+const MaxTokenTTL = 720 * time.Hour
+
 var (
 	ErrInvalidCredentials = errors.New("invalid username or password")
 	ErrUserNotFound      = errors.New("user not found")
 	ErrUserExists        = errors.New("user already exists")
+	ErrInvalidTokenTTL   = errors.New("token lifetime must be positive and at most 720 hours")
 )
 
-// HashPassword hashes a password using bcrypt
+// HashPassword hashes a password with the algorithm configured in GlobalPasswordHasher
 func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
-		return "", err
-	}
-	return string(hashedPassword), nil
+	return GlobalPasswordHasher.Hash(password)
 }
 
-// VerifyPassword verifies a password against its hash
+// VerifyPassword verifies a password against its hash, whichever algorithm produced it
 func VerifyPassword(password, hashedPassword string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	return GlobalPasswordHasher.Verify(password, hashedPassword)
 }
 
 // GenerateJWT generates a JWT token for a user
 func GenerateJWT(user *models.User, secretKey []byte) (string, time.Time, error) {
-	expirationTime := time.Now().Add(24 * time.Hour) // Token expires in 24 hours
-	
+	return GenerateScopedJWT(user, nil, 24*time.Hour, secretKey) // Token expires in 24 hours
+}
+
+// GenerateScopedJWT generates a JWT token limited to the given permission scopes,
+// valid for ttl of at most MaxTokenTTL. When GlobalSigningKeys has a current key,
+// the token is signed with it instead of secretKey and names it in the kid header.
+func GenerateScopedJWT(user *models.User, scopes []string, ttl time.Duration, secretKey []byte) (string, time.Time, error) {
+	if ttl <= 0 || ttl > MaxTokenTTL {
+		return "", time.Time{}, ErrInvalidTokenTTL
+	}
+
+	now := time.Now()
+	expirationTime := now.Add(ttl)
+	jwtConfig := GlobalJWTConfig.GetConfig()
+
 	claims := &Claims{
 		UserID:   user.ID,
 		Username: user.Username,
 		Role:     user.Role,
+		Scopes:   scopes,
 		StandardClaims: jwt.StandardClaims{
+			Id:        generateTokenID(),
 			ExpiresAt: expirationTime.Unix(),
-			IssuedAt:  time.Now().Unix(),
-			Issuer:    "golangmcp",
+			IssuedAt:  now.Unix(),
+			NotBefore: now.Unix(),
+			Issuer:    jwtConfig.Issuer,
+			Audience:  jwtConfig.Audience,
 		},
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if key, ok := GlobalSigningKeys.CurrentKey(); ok {
+		token.Header["kid"] = key.ID
+		secretKey = []byte(key.Secret)
+	}
 	tokenString, err := token.SignedString(secretKey)
 	if err != nil {
 		return "", time.Time{}, err
@@ -84,12 +117,41 @@ func GenerateJWT(user *models.User, secretKey []byte) (string, time.Time, error)
 	return tokenString, expirationTime, nil
 }
 
-// ValidateJWT validates a JWT token and returns the claims
+// Valid checks the exp, nbf and iat claims, tolerating the configured clock
+// skew between instances. It replaces StandardClaims.Valid, which allows none.
+func (c Claims) Valid() error {
+	leeway := int64(GlobalJWTConfig.GetConfig().Leeway / time.Second)
+	now := jwt.TimeFunc().Unix()
+
+	if !c.VerifyExpiresAt(now-leeway, false) {
+		return jwt.NewValidationError("token is expired", jwt.ValidationErrorExpired)
+	}
+	if !c.VerifyNotBefore(now+leeway, false) {
+		return jwt.NewValidationError("token is not valid yet", jwt.ValidationErrorNotValidYet)
+	}
+	if !c.VerifyIssuedAt(now+leeway, false) {
+		return jwt.NewValidationError("token used before issued", jwt.ValidationErrorIssuedAt)
+	}
+	return nil
+}
+
+// generateTokenID generates a unique token ID so tokens issued within the same second differ
+func generateTokenID() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// ValidateJWT validates a JWT token and returns the claims. The issuer and
+// audience must match GlobalJWTConfig exactly, so tokens minted for another
+// service with the same key are rejected. Tokens with a kid header are checked
+// against that key in GlobalSigningKeys, tokens without one against secretKey.
 func ValidateJWT(tokenString string, secretKey []byte) (*Claims, error) {
 	claims := &Claims{}
 	
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return secretKey, nil
+		kid, _ := token.Header["kid"].(string)
+		return GlobalSigningKeys.VerificationKey(kid, secretKey)
 	})
 	
 	if err != nil {
@@ -99,6 +161,14 @@ func ValidateJWT(tokenString string, secretKey []byte) (*Claims, error) {
 	if !token.Valid {
 		return nil, errors.New("invalid token")
 	}
+
+	jwtConfig := GlobalJWTConfig.GetConfig()
+	if claims.Issuer != jwtConfig.Issuer {
+		return nil, ErrInvalidIssuer
+	}
+	if claims.Audience != jwtConfig.Audience {
+		return nil, ErrInvalidAudience
+	}
 	
 	return claims, nil
 }
@@ -146,38 +216,69 @@ func RegisterUser(db *gorm.DB, req *RegisterRequest) (*models.User, error) {
 	return user, nil
 }
 
-// LoginUser authenticates a user and returns JWT token
-func LoginUser(db *gorm.DB, req *LoginRequest, secretKey []byte) (*AuthResponse, error) {
+// LoginUser authenticates a user and returns JWT token along with the full
+// user record. Unknown usernames and wrong passwords both return
+// ErrInvalidCredentials after a hash comparison, so neither the error nor the
+// timing reveals whether the account exists. Passwords hashed with another
+// algorithm than the configured one are rehashed on successful login.
+func LoginUser(db *gorm.DB, req *LoginRequest, secretKey []byte) (*AuthResponse, *models.User, error) {
 	// Find user by username
 	var user models.User
 	err := user.GetByUsername(db, req.Username)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return nil, ErrUserNotFound
+			GlobalPasswordHasher.VerifyDummy(req.Password)
+			return nil, nil, ErrInvalidCredentials
 		}
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Verify password
 	err = VerifyPassword(req.Password, user.Password)
 	if err != nil {
-		return nil, ErrInvalidCredentials
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	// Migrate the hash to the configured algorithm while the password is at hand
+	if GlobalPasswordHasher.NeedsRehash(user.Password) {
+		if err := rehashPassword(db, &user, req.Password); err != nil {
+			log.Printf("Warning: Failed to rehash password for user %d: %v", user.ID, err)
+		}
 	}
 
 	// Generate JWT token
 	token, expiresAt, err := GenerateJWT(&user, secretKey)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Clear password from response
 	user.Password = ""
 
 	return &AuthResponse{
-		Token:     token,
-		User:      user,
+		Token: token,
+		User: AuthUser{
+			ID:       user.ID,
+			Username: user.Username,
+			Email:    user.Email,
+			Role:     user.Role,
+			Avatar:   user.Avatar,
+		},
 		ExpiresAt: expiresAt,
-	}, nil
+	}, &user, nil
+}
+
+// rehashPassword stores the password hashed with the configured algorithm
+func rehashPassword(db *gorm.DB, user *models.User, password string) error {
+	hashedPassword, err := HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err := db.Model(&models.User{}).Where("id = ?", user.ID).Update("password", hashedPassword).Error; err != nil {
+		return err
+	}
+	user.Password = hashedPassword
+	return nil
 }
 
 // GetUserFromToken retrieves user information from JWT token
@@ -0,0 +1,275 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+var (
+	ErrUnknownHashAlgorithm = errors.New("password hash uses an unknown algorithm")
+	ErrPasswordMismatch     = errors.New("password does not match")
+)
+
+// Hasher hashes and verifies passwords with one algorithm. Encoded hashes carry
+// their algorithm identifier as a prefix ($2a$ for bcrypt, $argon2id$ for
+// argon2id), so stored hashes can always be verified after the configured
+// algorithm changes.
+type Hasher interface {
+	Algorithm() string
+	Hash(password string) (string, error)
+	Verify(password, encoded string) error
+}
+
+// BcryptHasher hashes passwords with bcrypt
+type BcryptHasher struct {
+	Cost int
+}
+
+// Algorithm returns the bcrypt algorithm identifier
+func (bh *BcryptHasher) Algorithm() string {
+	return AlgorithmBcrypt
+}
+
+// Hash hashes a password with bcrypt
+func (bh *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bh.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// Verify checks a password against a bcrypt hash
+func (bh *BcryptHasher) Verify(password, encoded string) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// Argon2idHasher hashes passwords with argon2id, encoded in the PHC string
+// format: $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<key>
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Algorithm returns the argon2id algorithm identifier
+func (ah *Argon2idHasher) Algorithm() string {
+	return AlgorithmArgon2id
+}
+
+// Hash hashes a password with argon2id and a random salt
+func (ah *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, ah.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, ah.Iterations, ah.Memory, ah.Parallelism, ah.KeyLength)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", AlgorithmArgon2id, argon2.Version,
+		ah.Memory, ah.Iterations, ah.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify checks a password against an argon2id hash, using the parameters stored in it
+func (ah *Argon2idHasher) Verify(password, encoded string) error {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return ErrUnknownHashAlgorithm
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	var memory, iterations uint32
+	var parallelism uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+		return fmt.Errorf("invalid argon2 parameters: %v", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return fmt.Errorf("invalid argon2 salt: %v", err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return fmt.Errorf("invalid argon2 key: %v", err)
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(candidate, key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// HashAlgorithm returns the algorithm identifier of an encoded hash
+func HashAlgorithm(encoded string) (string, error) {
+	switch {
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return AlgorithmArgon2id, nil
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		return AlgorithmBcrypt, nil
+	default:
+		return "", ErrUnknownHashAlgorithm
+	}
+}
+
+// PasswordHasherConfig represents which algorithm new password hashes use, and its parameters
+type PasswordHasherConfig struct {
+	Algorithm         string `json:"algorithm"` // bcrypt or argon2id
+	BcryptCost        int    `json:"bcrypt_cost"`
+	Argon2Memory      uint32 `json:"argon2_memory"` // KiB
+	Argon2Iterations  uint32 `json:"argon2_iterations"`
+	Argon2Parallelism uint8  `json:"argon2_parallelism"`
+}
+
+// DefaultPasswordHasherConfig returns default password hasher configuration
+func DefaultPasswordHasherConfig() *PasswordHasherConfig {
+	return &PasswordHasherConfig{
+		Algorithm:         AlgorithmBcrypt,
+		BcryptCost:        bcrypt.DefaultCost,
+		Argon2Memory:      64 * 1024,
+		Argon2Iterations:  3,
+		Argon2Parallelism: 2,
+	}
+}
+
+// Validate checks the algorithm and its parameters
+func (pc *PasswordHasherConfig) Validate() error {
+	if pc.Algorithm != AlgorithmBcrypt && pc.Algorithm != AlgorithmArgon2id {
+		return errors.New("algorithm must be bcrypt or argon2id")
+	}
+	if pc.BcryptCost < bcrypt.MinCost || pc.BcryptCost > bcrypt.MaxCost {
+		return fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	if pc.Argon2Memory < 8*uint32(pc.Argon2Parallelism) {
+		return errors.New("argon2 memory must be at least 8 KiB per thread")
+	}
+	if pc.Argon2Iterations < 1 || pc.Argon2Parallelism < 1 {
+		return errors.New("argon2 iterations and parallelism must be at least 1")
+	}
+	return nil
+}
+
+// hashers returns a hasher per algorithm configured with the given parameters
+func (pc *PasswordHasherConfig) hashers() map[string]Hasher {
+	return map[string]Hasher{
+		AlgorithmBcrypt: &BcryptHasher{Cost: pc.BcryptCost},
+		AlgorithmArgon2id: &Argon2idHasher{
+			Memory:      pc.Argon2Memory,
+			Iterations:  pc.Argon2Iterations,
+			Parallelism: pc.Argon2Parallelism,
+			SaltLength:  16,
+			KeyLength:   32,
+		},
+	}
+}
+
+// PasswordHasherManager hashes new passwords with the configured algorithm and
+// verifies stored hashes with whichever algorithm produced them
+type PasswordHasherManager struct {
+	config    *PasswordHasherConfig
+	hashers   map[string]Hasher
+	dummyHash string // hash of the configured algorithm compared against for unknown users
+	mutex     sync.RWMutex
+}
+
+// NewPasswordHasherManager creates a new password hasher manager
+func NewPasswordHasherManager() *PasswordHasherManager {
+	pm := &PasswordHasherManager{}
+	pm.apply(DefaultPasswordHasherConfig())
+	return pm
+}
+
+// apply swaps in a validated configuration; callers hold the write lock
+func (pm *PasswordHasherManager) apply(config *PasswordHasherConfig) {
+	pm.config = config
+	pm.hashers = config.hashers()
+	pm.dummyHash, _ = pm.hashers[config.Algorithm].Hash("dummy-password")
+}
+
+// GetConfig returns the current password hasher configuration
+func (pm *PasswordHasherManager) GetConfig() PasswordHasherConfig {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+	return *pm.config
+}
+
+// UpdateConfig replaces the password hasher configuration. Existing hashes keep
+// verifying; they are migrated to the new algorithm as users log in.
+func (pm *PasswordHasherManager) UpdateConfig(config *PasswordHasherConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+	pm.apply(config)
+	return nil
+}
+
+// Hash hashes a password with the configured algorithm
+func (pm *PasswordHasherManager) Hash(password string) (string, error) {
+	pm.mutex.RLock()
+	hasher := pm.hashers[pm.config.Algorithm]
+	pm.mutex.RUnlock()
+	return hasher.Hash(password)
+}
+
+// Verify checks a password against a hash produced by any supported algorithm
+func (pm *PasswordHasherManager) Verify(password, encoded string) error {
+	algorithm, err := HashAlgorithm(encoded)
+	if err != nil {
+		return err
+	}
+
+	pm.mutex.RLock()
+	hasher := pm.hashers[algorithm]
+	pm.mutex.RUnlock()
+	return hasher.Verify(password, encoded)
+}
+
+// NeedsRehash reports whether a stored hash was produced by another algorithm than the configured one
+func (pm *PasswordHasherManager) NeedsRehash(encoded string) bool {
+	algorithm, err := HashAlgorithm(encoded)
+	if err != nil {
+		return true
+	}
+
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+	return algorithm != pm.config.Algorithm
+}
+
+// VerifyDummy runs a verification against a throwaway hash of the configured
+// algorithm, so rejecting an unknown user takes as long as a wrong password
+func (pm *PasswordHasherManager) VerifyDummy(password string) {
+	pm.mutex.RLock()
+	dummyHash := pm.dummyHash
+	pm.mutex.RUnlock()
+	pm.Verify(password, dummyHash)
+}
+
+// GlobalPasswordHasher hashes and verifies every password
+var GlobalPasswordHasher = NewPasswordHasherManager()
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golangmcp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestPasswordHasher replaces the global password hasher with one using the
+// given algorithm and cheap parameters for the duration of a test
+func setupTestPasswordHasher(t *testing.T, algorithm string) *PasswordHasherManager {
+	orig := GlobalPasswordHasher
+	t.Cleanup(func() { GlobalPasswordHasher = orig })
+	GlobalPasswordHasher = NewPasswordHasherManager()
+	useHashAlgorithm(t, algorithm)
+	return GlobalPasswordHasher
+}
+
+func useHashAlgorithm(t *testing.T, algorithm string) {
+	config := &PasswordHasherConfig{
+		Algorithm:         algorithm,
+		BcryptCost:        4,
+		Argon2Memory:      1024,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+	}
+	if err := GlobalPasswordHasher.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to update hasher config: %v", err)
+	}
+}
+
+func TestPasswordHasher_CrossAlgorithmVerification(t *testing.T) {
+	setupTestPasswordHasher(t, AlgorithmBcrypt)
+	bcryptHash, err := HashPassword("s3cret-password")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+
+	useHashAlgorithm(t, AlgorithmArgon2id)
+	argonHash, err := HashPassword("s3cret-password")
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	if !strings.HasPrefix(argonHash, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("Expected an encoded argon2id hash, got %q", argonHash)
+	}
+
+	// Both hashes verify whichever algorithm is configured
+	for _, algorithm := range []string{AlgorithmBcrypt, AlgorithmArgon2id} {
+		useHashAlgorithm(t, algorithm)
+		for _, hash := range []string{bcryptHash, argonHash} {
+			if err := VerifyPassword("s3cret-password", hash); err != nil {
+				t.Errorf("Expected %s hash to verify under %s, got %v", hash[:8], algorithm, err)
+			}
+			if err := VerifyPassword("wrong-password", hash); err == nil {
+				t.Errorf("Expected wrong password to fail against %s hash under %s", hash[:8], algorithm)
+			}
+		}
+	}
+
+	if err := VerifyPassword("s3cret-password", "plaintext"); err != ErrUnknownHashAlgorithm {
+		t.Errorf("Expected ErrUnknownHashAlgorithm, got %v", err)
+	}
+}
+
+func TestLoginUser_MigratesHashToConfiguredAlgorithm(t *testing.T) {
+	setupTestPasswordHasher(t, AlgorithmBcrypt)
+
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := database.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	hashedPassword, _ := HashPassword("s3cret-password")
+	user := &models.User{Username: "alice", Email: "alice@example.com", Password: hashedPassword, Role: "user"}
+	if err := user.Create(database); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	storedHash := func() string {
+		var stored models.User
+		database.First(&stored, user.ID)
+		return stored.Password
+	}
+
+	// Logging in under the same algorithm leaves the hash alone
+	if _, _, err := LoginUser(database, &LoginRequest{Username: "alice", Password: "s3cret-password"}, []byte("test-secret")); err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	if storedHash() != hashedPassword {
+		t.Error("Expected the hash to be kept when the algorithm is unchanged")
+	}
+
+	useHashAlgorithm(t, AlgorithmArgon2id)
+
+	// A failed login must not migrate anything
+	if _, _, err := LoginUser(database, &LoginRequest{Username: "alice", Password: "wrong-password"}, []byte("test-secret")); err != ErrInvalidCredentials {
+		t.Fatalf("Expected ErrInvalidCredentials, got %v", err)
+	}
+	if storedHash() != hashedPassword {
+		t.Error("Expected a failed login to leave the hash alone")
+	}
+
+	if _, _, err := LoginUser(database, &LoginRequest{Username: "alice", Password: "s3cret-password"}, []byte("test-secret")); err != nil {
+		t.Fatalf("Failed to log in with the bcrypt hash: %v", err)
+	}
+	migrated := storedHash()
+	if algorithm, _ := HashAlgorithm(migrated); algorithm != AlgorithmArgon2id {
+		t.Fatalf("Expected the hash to be migrated to argon2id, got %q", migrated)
+	}
+
+	if _, _, err := LoginUser(database, &LoginRequest{Username: "alice", Password: "s3cret-password"}, []byte("test-secret")); err != nil {
+		t.Errorf("Failed to log in with the migrated hash: %v", err)
+	}
+}
+
+func TestPasswordHasherConfig_Validate(t *testing.T) {
+	manager := NewPasswordHasherManager()
+
+	for _, update := range []func(*PasswordHasherConfig){
+		func(c *PasswordHasherConfig) { c.Algorithm = "md5" },
+		func(c *PasswordHasherConfig) { c.BcryptCost = 2 },
+		func(c *PasswordHasherConfig) { c.Argon2Iterations = 0 },
+		func(c *PasswordHasherConfig) { c.Argon2Memory = 4 },
+	} {
+		config := DefaultPasswordHasherConfig()
+		update(config)
+		if err := manager.UpdateConfig(config); err == nil {
+			t.Errorf("Expected config %+v to be rejected", config)
+		}
+	}
+
+	if manager.GetConfig().Algorithm != AlgorithmBcrypt {
+		t.Error("Expected rejected updates to leave the config unchanged")
+	}
+}
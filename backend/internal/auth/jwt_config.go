@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidIssuer   = errors.New("token has an invalid issuer")
+	ErrInvalidAudience = errors.New("token has an invalid audience")
+)
+
+// JWTConfig represents the issuer and audience claims set on and required of
+// tokens, and the clock skew tolerated when checking their time based claims
+type JWTConfig struct {
+	Issuer   string        `json:"issuer"`
+	Audience string        `json:"audience"`
+	Leeway   time.Duration `json:"leeway"` // applied to exp, nbf and iat
+}
+
+// DefaultJWTConfig returns default JWT configuration
+func DefaultJWTConfig() *JWTConfig {
+	return &JWTConfig{
+		Issuer:   "golangmcp",
+		Audience: "golangmcp-api",
+		Leeway:   30 * time.Second,
+	}
+}
+
+// Validate checks that both claims are set, so validation never degrades to accepting any value
+func (jc *JWTConfig) Validate() error {
+	if jc.Issuer == "" {
+		return errors.New("issuer cannot be empty")
+	}
+	if jc.Audience == "" {
+		return errors.New("audience cannot be empty")
+	}
+	if jc.Leeway < 0 {
+		return errors.New("leeway cannot be negative")
+	}
+	if jc.Leeway > 5*time.Minute {
+		return errors.New("leeway cannot exceed 5 minutes")
+	}
+	return nil
+}
+
+// JWTConfigManager manages the JWT configuration
+type JWTConfigManager struct {
+	config *JWTConfig
+	mutex  sync.RWMutex
+}
+
+// NewJWTConfigManager creates a new JWT configuration manager
+func NewJWTConfigManager() *JWTConfigManager {
+	return &JWTConfigManager{
+		config: DefaultJWTConfig(),
+	}
+}
+
+// GetConfig returns the current JWT configuration
+func (jm *JWTConfigManager) GetConfig() JWTConfig {
+	jm.mutex.RLock()
+	defer jm.mutex.RUnlock()
+	return *jm.config
+}
+
+// UpdateConfig replaces the JWT configuration. Tokens issued under the previous
+// issuer or audience stop validating.
+func (jm *JWTConfigManager) UpdateConfig(config *JWTConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	jm.mutex.Lock()
+	defer jm.mutex.Unlock()
+	jm.config = config
+	return nil
+}
+
+// GlobalJWTConfig is the configuration used by GenerateScopedJWT and ValidateJWT
+var GlobalJWTConfig = NewJWTConfigManager()
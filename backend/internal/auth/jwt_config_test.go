@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"golangmcp/internal/models"
+)
+
+var testSecret = []byte("test_secret")
+
+// signClaims signs claims for a test user with the given issuer and audience
+func signClaims(t *testing.T, issuer, audience string) string {
+	claims := &Claims{
+		UserID:   1,
+		Username: "testuser",
+		Role:     "user",
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+			Issuer:    issuer,
+			Audience:  audience,
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testSecret)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestGenerateJWT_SetsIssuerAndAudience(t *testing.T) {
+	token, _, err := GenerateJWT(&models.User{ID: 1, Username: "testuser", Role: "user"}, testSecret)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	claims, err := ValidateJWT(token, testSecret)
+	if err != nil {
+		t.Fatalf("Expected generated token to validate, got %v", err)
+	}
+	config := GlobalJWTConfig.GetConfig()
+	if claims.Issuer != config.Issuer || claims.Audience != config.Audience {
+		t.Errorf("Expected iss=%q aud=%q, got iss=%q aud=%q", config.Issuer, config.Audience, claims.Issuer, claims.Audience)
+	}
+}
+
+func TestGenerateScopedJWT_BoundsLifetime(t *testing.T) {
+	user := &models.User{ID: 1, Username: "testuser", Role: "user"}
+
+	_, expiresAt, err := GenerateScopedJWT(user, []string{"profile.read"}, MaxTokenTTL, testSecret)
+	if err != nil {
+		t.Fatalf("Expected the longest lifetime to be allowed, got %v", err)
+	}
+	if until := time.Until(expiresAt); until > MaxTokenTTL || until < MaxTokenTTL-time.Minute {
+		t.Errorf("Expected the token to expire in %s, got %s", MaxTokenTTL, until)
+	}
+	for _, ttl := range []time.Duration{0, -time.Hour, MaxTokenTTL + time.Second, 1 << 62} {
+		if _, _, err := GenerateScopedJWT(user, []string{"profile.read"}, ttl, testSecret); err != ErrInvalidTokenTTL {
+			t.Errorf("Expected a lifetime of %s to be rejected, got %v", ttl, err)
+		}
+	}
+}
+
+func TestValidateJWT_RejectsWrongIssuerOrAudience(t *testing.T) {
+	config := GlobalJWTConfig.GetConfig()
+
+	tests := []struct {
+		name     string
+		issuer   string
+		audience string
+		want     error
+	}{
+		{"wrong issuer", "other-service", config.Audience, ErrInvalidIssuer},
+		{"missing issuer", "", config.Audience, ErrInvalidIssuer},
+		{"wrong audience", config.Issuer, "other-api", ErrInvalidAudience},
+		{"missing audience", config.Issuer, "", ErrInvalidAudience},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateJWT(signClaims(t, tt.issuer, tt.audience), testSecret)
+			if err != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestJWTConfigManager_UpdateConfig(t *testing.T) {
+	orig := GlobalJWTConfig
+	t.Cleanup(func() { GlobalJWTConfig = orig })
+	GlobalJWTConfig = NewJWTConfigManager()
+
+	if err := GlobalJWTConfig.UpdateConfig(&JWTConfig{Issuer: "issuer"}); err == nil {
+		t.Error("Expected an empty audience to be rejected")
+	}
+
+	oldToken := signClaims(t, "golangmcp", "golangmcp-api")
+	if err := GlobalJWTConfig.UpdateConfig(&JWTConfig{Issuer: "auth.example.com", Audience: "files"}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	if _, err := ValidateJWT(oldToken, testSecret); err != ErrInvalidIssuer {
+		t.Errorf("Expected token with the previous issuer to be rejected, got %v", err)
+	}
+	if _, err := ValidateJWT(signClaims(t, "auth.example.com", "files"), testSecret); err != nil {
+		t.Errorf("Expected token matching the new config to validate, got %v", err)
+	}
+}
+
+// signTimedClaims signs a valid token with the given exp and nbf claims
+func signTimedClaims(t *testing.T, expiresAt, notBefore time.Time) string {
+	config := GlobalJWTConfig.GetConfig()
+	claims := &Claims{
+		UserID: 1,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expiresAt.Unix(),
+			NotBefore: notBefore.Unix(),
+			Issuer:    config.Issuer,
+			Audience:  config.Audience,
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testSecret)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestValidateJWT_ClockSkewLeeway(t *testing.T) {
+	orig := GlobalJWTConfig
+	t.Cleanup(func() { GlobalJWTConfig = orig })
+	GlobalJWTConfig = NewJWTConfigManager()
+	config := DefaultJWTConfig()
+	config.Leeway = 30 * time.Second
+	if err := GlobalJWTConfig.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		notBefore time.Time
+		valid     bool
+	}{
+		{"expired within leeway", now.Add(-10 * time.Second), now.Add(-time.Hour), true},
+		{"expired beyond leeway", now.Add(-time.Minute), now.Add(-time.Hour), false},
+		{"not yet valid within leeway", now.Add(time.Hour), now.Add(10 * time.Second), true},
+		{"not yet valid beyond leeway", now.Add(time.Hour), now.Add(time.Minute), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ValidateJWT(signTimedClaims(t, tt.expiresAt, tt.notBefore), testSecret)
+			if tt.valid && err != nil {
+				t.Errorf("Expected token to validate, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected token to be rejected")
+			}
+		})
+	}
+}
+
+func TestValidateJWT_NoLeeway(t *testing.T) {
+	orig := GlobalJWTConfig
+	t.Cleanup(func() { GlobalJWTConfig = orig })
+	GlobalJWTConfig = NewJWTConfigManager()
+	config := DefaultJWTConfig()
+	config.Leeway = 0
+	if err := GlobalJWTConfig.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	now := time.Now()
+	if _, err := ValidateJWT(signTimedClaims(t, now.Add(-2*time.Second), now.Add(-time.Hour)), testSecret); err == nil {
+		t.Error("Expected expired token to be rejected without leeway")
+	}
+	if _, err := ValidateJWT(signTimedClaims(t, now.Add(time.Hour), now.Add(2*time.Second)), testSecret); err == nil {
+		t.Error("Expected token before nbf to be rejected without leeway")
+	}
+}
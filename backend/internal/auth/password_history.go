@@ -0,0 +1,102 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+
+	"golangmcp/internal/models"
+	"gorm.io/gorm"
+)
+
+var ErrPasswordReused = errors.New("password was used recently, choose a different one")
+
+// PasswordPolicy represents password reuse configuration
+type PasswordPolicy struct {
+	HistorySize int `json:"history_size"` // previous passwords that cannot be reused, 0 = no check
+}
+
+// DefaultPasswordPolicy returns default password policy
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		HistorySize: 5,
+	}
+}
+
+// PasswordPolicyManager manages the password policy
+type PasswordPolicyManager struct {
+	policy *PasswordPolicy
+	mutex  sync.RWMutex
+}
+
+// NewPasswordPolicyManager creates a new password policy manager
+func NewPasswordPolicyManager() *PasswordPolicyManager {
+	return &PasswordPolicyManager{
+		policy: DefaultPasswordPolicy(),
+	}
+}
+
+// GetPolicy returns the current password policy
+func (pm *PasswordPolicyManager) GetPolicy() PasswordPolicy {
+	pm.mutex.RLock()
+	defer pm.mutex.RUnlock()
+	return *pm.policy
+}
+
+// UpdatePolicy replaces the password policy
+func (pm *PasswordPolicyManager) UpdatePolicy(policy *PasswordPolicy) error {
+	if policy.HistorySize < 0 {
+		return errors.New("history size cannot be negative")
+	}
+
+	pm.mutex.Lock()
+	defer pm.mutex.Unlock()
+	pm.policy = policy
+	return nil
+}
+
+// GlobalPasswordPolicy is the password policy applied by SetUserPassword
+var GlobalPasswordPolicy = NewPasswordPolicyManager()
+
+// SetUserPassword validates, hashes and stores a new password for a user. Every
+// flow that sets a password (change, reset) must go through it so the reuse policy
+// applies: the current password and the last HistorySize ones are rejected.
+func SetUserPassword(db *gorm.DB, user *models.User, newPassword string) error {
+	if err := models.ValidatePassword(newPassword); err != nil {
+		return err
+	}
+
+	policy := GlobalPasswordPolicy.GetPolicy()
+	if policy.HistorySize > 0 {
+		if user.Password != "" && VerifyPassword(newPassword, user.Password) == nil {
+			return ErrPasswordReused
+		}
+
+		history, err := models.GetPasswordHistory(db, user.ID, policy.HistorySize)
+		if err != nil {
+			return err
+		}
+		for _, entry := range history {
+			if VerifyPassword(newPassword, entry.PasswordHash) == nil {
+				return ErrPasswordReused
+			}
+		}
+	}
+
+	hashedPassword, err := HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+
+	previousHash := user.Password
+	return db.Transaction(func(tx *gorm.DB) error {
+		user.Password = hashedPassword
+		if err := user.Update(tx); err != nil {
+			return err
+		}
+
+		if previousHash == "" {
+			return nil
+		}
+		return models.AddPasswordHistory(tx, user.ID, previousHash, policy.HistorySize)
+	})
+}
@@ -0,0 +1,26 @@
+package auth
+
+// EnvJWTSecret overrides the key JWTs are signed and validated with, see config.Load
+const EnvJWTSecret = "JWT_SECRET"
+
+// DefaultJWTSecret is the development signing key, used when JWT_SECRET is
+// not set. It is public, so production deployments must replace it.
+const DefaultJWTSecret = "my_secret_key"
+
+var jwtSecret = []byte(DefaultJWTSecret)
+
+// JWTSecret returns the key JWTs are signed and validated with
+func JWTSecret() []byte {
+	return jwtSecret
+}
+
+// SetJWTSecret replaces the JWT signing key; tokens signed with the old key
+// stop validating
+func SetJWTSecret(secret []byte) {
+	jwtSecret = secret
+}
+
+// UsingDefaultJWTSecret reports whether tokens are signed with the public development key
+func UsingDefaultJWTSecret() bool {
+	return string(jwtSecret) == DefaultJWTSecret
+}
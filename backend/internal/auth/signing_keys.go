@@ -0,0 +1,299 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"golangmcp/internal/models"
+	"gorm.io/gorm"
+)
+
+// signingKeysSettingKey is the system setting holding the persisted signing keys
+const signingKeysSettingKey = "jwt_signing_keys"
+
+// signingKeyRefreshInterval is how often persisted keys are reloaded, so keys
+// rotated by another instance are picked up
+const signingKeyRefreshInterval = time.Minute
+
+var (
+	ErrUnknownSigningKey = errors.New("token was signed with an unknown or retired key")
+)
+
+// SigningKey is a named HMAC secret. Tokens signed with it carry its ID in the
+// "kid" header so the matching secret can be found on validation.
+type SigningKey struct {
+	ID        string     `json:"id"`
+	Secret    string     `json:"secret,omitempty"` // never returned by GetConfig
+	CreatedAt time.Time  `json:"created_at"`
+	RetiresAt *time.Time `json:"retires_at,omitempty"` // nil = never, set when the key is rotated out
+}
+
+// SigningKeyConfig represents the keys used to sign and verify tokens. With no
+// keys configured, tokens are signed with the secret passed by the caller and
+// carry no kid, as before rotation support existed.
+type SigningKeyConfig struct {
+	CurrentKeyID   string        `json:"current_key_id"` // key used to sign new tokens
+	Keys           []SigningKey  `json:"keys"`
+	RotationWindow time.Duration `json:"rotation_window"` // how long a rotated out key keeps validating
+	// LegacyRetiresAt stops tokens without a kid, signed with the caller's
+	// secret, from validating after that time. nil = accepted indefinitely.
+	LegacyRetiresAt *time.Time `json:"legacy_retires_at,omitempty"`
+}
+
+// DefaultSigningKeyConfig returns default signing key configuration
+func DefaultSigningKeyConfig() *SigningKeyConfig {
+	return &SigningKeyConfig{
+		RotationWindow: 24 * time.Hour, // lifetime of a regular token
+	}
+}
+
+// Validate checks that the current key exists and every key has an ID and a secret
+func (sc *SigningKeyConfig) Validate() error {
+	if sc.RotationWindow <= 0 {
+		return errors.New("rotation window must be positive")
+	}
+	if sc.RotationWindow > 30*24*time.Hour {
+		return errors.New("rotation window cannot exceed 30 days")
+	}
+
+	seen := make(map[string]bool)
+	for _, key := range sc.Keys {
+		if key.ID == "" {
+			return errors.New("key id cannot be empty")
+		}
+		if seen[key.ID] {
+			return errors.New("duplicate key id: " + key.ID)
+		}
+		seen[key.ID] = true
+		if len(key.Secret) < 16 {
+			return errors.New("key secret must be at least 16 characters: " + key.ID)
+		}
+	}
+
+	if len(sc.Keys) > 0 && !seen[sc.CurrentKeyID] {
+		return errors.New("current key id must name one of the keys")
+	}
+	if len(sc.Keys) == 0 && sc.CurrentKeyID != "" {
+		return errors.New("current key id set but no keys configured")
+	}
+	return nil
+}
+
+// SigningKeyManager manages the signing keys. Once loaded with LoadConfig, the
+// keys are persisted as a system setting so tokens keep validating across
+// restarts and instances.
+type SigningKeyManager struct {
+	config   *SigningKeyConfig
+	database *gorm.DB // nil = keys only live in memory
+	loadedAt time.Time
+	mutex    sync.RWMutex
+}
+
+// NewSigningKeyManager creates a new signing key manager
+func NewSigningKeyManager() *SigningKeyManager {
+	return &SigningKeyManager{
+		config: DefaultSigningKeyConfig(),
+	}
+}
+
+// GetConfig returns the current signing key configuration with secrets removed
+func (km *SigningKeyManager) GetConfig() SigningKeyConfig {
+	km.mutex.RLock()
+	defer km.mutex.RUnlock()
+
+	config := *km.config
+	config.Keys = make([]SigningKey, len(km.config.Keys))
+	for i, key := range km.config.Keys {
+		key.Secret = ""
+		config.Keys[i] = key
+	}
+	return config
+}
+
+// UpdateConfig persists and replaces the signing key configuration. Tokens
+// signed with a key that is no longer listed stop validating.
+func (km *SigningKeyManager) UpdateConfig(config *SigningKeyConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	km.mutex.Lock()
+	defer km.mutex.Unlock()
+	return km.apply(config)
+}
+
+// LoadConfig restores the persisted signing keys, if any, and persists later
+// changes to database
+func (km *SigningKeyManager) LoadConfig(database *gorm.DB) error {
+	km.mutex.Lock()
+	defer km.mutex.Unlock()
+
+	km.database = database
+	return km.load()
+}
+
+// load reads the persisted signing keys. The caller holds the write lock.
+func (km *SigningKeyManager) load() error {
+	km.loadedAt = time.Now()
+	value, err := models.GetSystemSetting(km.database, signingKeysSettingKey)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	config := DefaultSigningKeyConfig()
+	if err := json.Unmarshal([]byte(value), config); err != nil {
+		return err
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	km.config = config
+	return nil
+}
+
+// apply persists config, when a database is set, and makes it current. The
+// caller holds the write lock.
+func (km *SigningKeyManager) apply(config *SigningKeyConfig) error {
+	if km.database != nil {
+		data, err := json.Marshal(config)
+		if err != nil {
+			return err
+		}
+		if err := models.SetSystemSetting(km.database, signingKeysSettingKey, string(data)); err != nil {
+			return err
+		}
+		km.loadedAt = time.Now()
+	}
+	km.config = config
+	return nil
+}
+
+// refresh reloads the persisted keys once they are older than the refresh interval
+func (km *SigningKeyManager) refresh() {
+	km.mutex.RLock()
+	stale := km.database != nil && time.Since(km.loadedAt) >= signingKeyRefreshInterval
+	km.mutex.RUnlock()
+	if !stale {
+		return
+	}
+
+	km.mutex.Lock()
+	defer km.mutex.Unlock()
+	if time.Since(km.loadedAt) < signingKeyRefreshInterval {
+		return
+	}
+	if err := km.load(); err != nil {
+		log.Printf("Warning: Failed to reload signing keys: %v", err)
+	}
+}
+
+// Rotate generates a new current key. The previous key, or the caller's secret
+// if no keys were configured yet, keeps validating for the rotation window so
+// tokens issued before the rotation are not invalidated. Keys already past
+// their retirement are dropped. The new key set is persisted like UpdateConfig.
+func (km *SigningKeyManager) Rotate() (SigningKey, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return SigningKey{}, err
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return SigningKey{}, err
+	}
+
+	km.mutex.Lock()
+	defer km.mutex.Unlock()
+
+	// Start from the persisted keys, which another instance may have rotated
+	if km.database != nil {
+		if err := km.load(); err != nil {
+			return SigningKey{}, err
+		}
+	}
+
+	now := time.Now()
+	retiresAt := now.Add(km.config.RotationWindow)
+
+	config := *km.config
+	config.Keys = nil
+	for _, key := range km.config.Keys {
+		if key.RetiresAt != nil && !now.Before(*key.RetiresAt) {
+			continue
+		}
+		if key.ID == km.config.CurrentKeyID {
+			key.RetiresAt = &retiresAt
+		}
+		config.Keys = append(config.Keys, key)
+	}
+	if km.config.CurrentKeyID == "" && config.LegacyRetiresAt == nil {
+		config.LegacyRetiresAt = &retiresAt
+	}
+
+	key := SigningKey{
+		ID:        hex.EncodeToString(id),
+		Secret:    hex.EncodeToString(secret),
+		CreatedAt: now,
+	}
+	config.Keys = append(config.Keys, key)
+	config.CurrentKeyID = key.ID
+	if err := km.apply(&config); err != nil {
+		return SigningKey{}, err
+	}
+
+	key.Secret = ""
+	return key, nil
+}
+
+// CurrentKey returns the key new tokens are signed with, if one is configured
+func (km *SigningKeyManager) CurrentKey() (SigningKey, bool) {
+	km.refresh()
+
+	km.mutex.RLock()
+	defer km.mutex.RUnlock()
+
+	for _, key := range km.config.Keys {
+		if key.ID == km.config.CurrentKeyID {
+			return key, true
+		}
+	}
+	return SigningKey{}, false
+}
+
+// VerificationKey returns the secret for the given kid, or fallback for tokens
+// without one, provided the key has not been retired
+func (km *SigningKeyManager) VerificationKey(kid string, fallback []byte) ([]byte, error) {
+	km.refresh()
+
+	km.mutex.RLock()
+	defer km.mutex.RUnlock()
+
+	now := time.Now()
+	if kid == "" {
+		if km.config.LegacyRetiresAt != nil && !now.Before(*km.config.LegacyRetiresAt) {
+			return nil, ErrUnknownSigningKey
+		}
+		return fallback, nil
+	}
+
+	for _, key := range km.config.Keys {
+		if key.ID != kid {
+			continue
+		}
+		if key.RetiresAt != nil && !now.Before(*key.RetiresAt) {
+			return nil, ErrUnknownSigningKey
+		}
+		return []byte(key.Secret), nil
+	}
+	return nil, ErrUnknownSigningKey
+}
+
+// GlobalSigningKeys is the key set used by GenerateScopedJWT and ValidateJWT
+var GlobalSigningKeys = NewSigningKeyManager()
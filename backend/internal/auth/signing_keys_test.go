@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"golangmcp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestSigningKeys replaces the global signing keys for the duration of a test
+func setupTestSigningKeys(t *testing.T) {
+	orig := GlobalSigningKeys
+	t.Cleanup(func() { GlobalSigningKeys = orig })
+	GlobalSigningKeys = NewSigningKeyManager()
+}
+
+func TestSigningKeys_OldAndNewKeysValidateDuringOverlap(t *testing.T) {
+	setupTestSigningKeys(t)
+	user := &models.User{ID: 1, Username: "testuser", Role: "user"}
+
+	legacyToken, _, _ := GenerateJWT(user, testSecret)
+
+	first, err := GlobalSigningKeys.Rotate()
+	if err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	firstToken, _, _ := GenerateJWT(user, testSecret)
+
+	second, err := GlobalSigningKeys.Rotate()
+	if err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	secondToken, _, _ := GenerateJWT(user, testSecret)
+
+	if first.ID == second.ID {
+		t.Fatal("Expected rotation to generate a new key id")
+	}
+	if first.Secret != "" || second.Secret != "" {
+		t.Error("Expected rotated keys to be returned without their secret")
+	}
+	if current, _ := GlobalSigningKeys.CurrentKey(); current.ID != second.ID {
+		t.Errorf("Expected current key %s, got %s", second.ID, current.ID)
+	}
+
+	for name, token := range map[string]string{"legacy": legacyToken, "previous": firstToken, "current": secondToken} {
+		if _, err := ValidateJWT(token, testSecret); err != nil {
+			t.Errorf("Expected %s token to validate during the rotation window, got %v", name, err)
+		}
+	}
+}
+
+func TestSigningKeys_RetiredKeysStopValidating(t *testing.T) {
+	setupTestSigningKeys(t)
+	user := &models.User{ID: 1, Username: "testuser", Role: "user"}
+
+	legacyToken, _, _ := GenerateJWT(user, testSecret)
+	GlobalSigningKeys.Rotate()
+	oldToken, _, _ := GenerateJWT(user, testSecret)
+	GlobalSigningKeys.Rotate()
+	newToken, _, _ := GenerateJWT(user, testSecret)
+
+	// Move every retirement into the past
+	config := *GlobalSigningKeys.config
+	past := time.Now().Add(-time.Minute)
+	config.LegacyRetiresAt = &past
+	for i := range config.Keys {
+		if config.Keys[i].RetiresAt != nil {
+			config.Keys[i].RetiresAt = &past
+		}
+	}
+	if err := GlobalSigningKeys.UpdateConfig(&config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	if _, err := ValidateJWT(legacyToken, testSecret); err == nil {
+		t.Error("Expected token without kid to be rejected after the legacy secret retired")
+	}
+	if _, err := ValidateJWT(oldToken, testSecret); err == nil {
+		t.Error("Expected token signed with a retired key to be rejected")
+	}
+	if _, err := ValidateJWT(newToken, testSecret); err != nil {
+		t.Errorf("Expected token signed with the current key to validate, got %v", err)
+	}
+
+	// Retired keys are dropped on the next rotation
+	GlobalSigningKeys.Rotate()
+	if keys := GlobalSigningKeys.GetConfig().Keys; len(keys) != 2 {
+		t.Errorf("Expected the retired key to be dropped, got %d keys", len(keys))
+	}
+}
+
+func TestSigningKeys_UpdateConfigValidation(t *testing.T) {
+	setupTestSigningKeys(t)
+
+	tests := []struct {
+		name   string
+		config SigningKeyConfig
+	}{
+		{"unknown current key", SigningKeyConfig{CurrentKeyID: "b", Keys: []SigningKey{{ID: "a", Secret: "0123456789abcdef"}}, RotationWindow: time.Hour}},
+		{"short secret", SigningKeyConfig{CurrentKeyID: "a", Keys: []SigningKey{{ID: "a", Secret: "short"}}, RotationWindow: time.Hour}},
+		{"duplicate id", SigningKeyConfig{CurrentKeyID: "a", Keys: []SigningKey{{ID: "a", Secret: "0123456789abcdef"}, {ID: "a", Secret: "fedcba9876543210"}}, RotationWindow: time.Hour}},
+		{"no rotation window", SigningKeyConfig{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := GlobalSigningKeys.UpdateConfig(&tt.config); err == nil {
+				t.Error("Expected config to be rejected")
+			}
+		})
+	}
+
+	config := &SigningKeyConfig{CurrentKeyID: "a", Keys: []SigningKey{{ID: "a", Secret: "0123456789abcdef"}}, RotationWindow: time.Hour}
+	if err := GlobalSigningKeys.UpdateConfig(config); err != nil {
+		t.Fatalf("Expected valid config to be accepted, got %v", err)
+	}
+	if got := GlobalSigningKeys.GetConfig(); got.Keys[0].Secret != "" {
+		t.Error("Expected GetConfig to omit key secrets")
+	}
+}
+
+func TestSigningKeys_PersistedAcrossInstances(t *testing.T) {
+	setupTestSigningKeys(t)
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	sqlDB, _ := database.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := database.AutoMigrate(&models.SystemSetting{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	user := &models.User{ID: 1, Username: "testuser", Role: "user"}
+
+	first := GlobalSigningKeys
+	if err := first.LoadConfig(database); err != nil {
+		t.Fatalf("Failed to load signing keys: %v", err)
+	}
+	if _, err := first.Rotate(); err != nil {
+		t.Fatalf("Failed to rotate: %v", err)
+	}
+	token, _, _ := GenerateJWT(user, testSecret)
+
+	// A restarted or second instance validates the token
+	second := NewSigningKeyManager()
+	if err := second.LoadConfig(database); err != nil {
+		t.Fatalf("Failed to load signing keys: %v", err)
+	}
+	GlobalSigningKeys = second
+	if _, err := ValidateJWT(token, testSecret); err != nil {
+		t.Errorf("Expected the token to validate on another instance, got %v", err)
+	}
+
+	// Keys rotated elsewhere are picked up once the loaded ones are stale
+	GlobalSigningKeys = first
+	first.Rotate()
+	rotatedToken, _, _ := GenerateJWT(user, testSecret)
+	GlobalSigningKeys = second
+	second.loadedAt = time.Now().Add(-signingKeyRefreshInterval)
+	if _, err := ValidateJWT(rotatedToken, testSecret); err != nil {
+		t.Errorf("Expected a key rotated on another instance to validate, got %v", err)
+	}
+	if current, _ := second.CurrentKey(); current.ID != first.config.CurrentKeyID {
+		t.Errorf("Expected the current key %s, got %s", first.config.CurrentKeyID, current.ID)
+	}
+}
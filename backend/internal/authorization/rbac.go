@@ -33,12 +33,12 @@ var (
 		},
 		"moderator": {
 			Name:        "moderator",
-			Permissions: []string{"user.read", "user.update", "user.delete", "session.read", "session.delete"},
+			Permissions: []string{"user.read", "user.update", "user.delete", "session.read", "session.delete", "file.read", "file.upload", "file.update", "file.delete", "image.read", "image.upload", "command.read", "command.execute"},
 			Level:       50,
 		},
 		"user": {
 			Name:        "user",
-			Permissions: []string{"profile.read", "profile.update", "profile.avatar.upload", "profile.avatar.delete", "session.read", "session.delete.own"},
+			Permissions: []string{"profile.read", "profile.update", "profile.avatar.upload", "profile.avatar.delete", "session.read", "session.delete.own", "file.read", "file.upload", "file.update", "file.delete", "image.read", "image.upload", "command.read", "command.execute"},
 			Level:       10,
 		},
 		"guest": {
@@ -62,11 +62,20 @@ var (
 		"session.read":        {"session.read", "Read session information", "session", "read"},
 		"session.delete":      {"session.delete", "Delete any session", "session", "delete"},
 		"session.delete.own":   {"session.delete.own", "Delete own sessions", "session", "delete.own"},
+		"file.read":           {"file.read", "Read and download own files", "file", "read"},
+		"file.upload":         {"file.upload", "Upload files", "file", "upload"},
+		"file.update":         {"file.update", "Rename and update own files", "file", "update"},
+		"file.delete":         {"file.delete", "Delete own files", "file", "delete"},
+		"image.read":          {"image.read", "Read own images", "image", "read"},
+		"image.upload":        {"image.upload", "Upload and optimize images", "image", "upload"},
+		"command.read":        {"command.read", "Read command history and whitelist", "command", "read"},
+		"command.execute":     {"command.execute", "Execute whitelisted commands", "command", "execute"},
 		"auth.register":       {"auth.register", "Register new account", "auth", "register"},
 		"auth.login":          {"auth.login", "Login to account", "auth", "login"},
 		"admin.stats":         {"admin.stats", "View admin statistics", "admin", "stats"},
 		"admin.users":         {"admin.users", "Manage all users", "admin", "users"},
 		"admin.sessions":      {"admin.sessions", "Manage all sessions", "admin", "sessions"},
+		"admin.security":      {"admin.security", "Manage security settings and logs", "admin", "security"},
 	}
 
 	ErrInsufficientPermissions = errors.New("insufficient permissions")
@@ -96,6 +105,157 @@ func HasPermission(roleName, permission string) bool {
 	return false
 }
 
+// ScopesAllow checks if token scopes grant a permission. Unscoped tokens (nil or
+// empty scopes) carry the full permissions of their role.
+func ScopesAllow(scopes []string, permission string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+
+	for _, scope := range scopes {
+		if scope == permission || scope == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasScopedPermission checks if a role has a permission and the token scopes allow it
+func HasScopedPermission(roleName string, scopes []string, permission string) bool {
+	return HasPermission(roleName, permission) && ScopesAllow(scopes, permission)
+}
+
+// ContextScopes returns the token scopes stored in the context by AuthMiddleware
+func ContextScopes(c *gin.Context) []string {
+	scopes, _ := c.Get("scopes")
+	scopeList, _ := scopes.([]string)
+	return scopeList
+}
+
+// ContextHasPermission checks if the role and token scopes stored in the context
+// by AuthMiddleware grant a permission, for handlers widening access in place
+func ContextHasPermission(c *gin.Context, permission string) bool {
+	return HasScopedPermission(c.GetString("role"), ContextScopes(c), permission)
+}
+
+// RoutePermissions maps routes, keyed by method and path pattern, to the
+// permission a scoped token needs to call them. An empty permission admits any
+// scoped token, for handlers narrowing scopes themselves. AuthMiddleware runs
+// before the permission middlewares, so routes guarded by RequirePermission are
+// listed with the same permission. Scoped tokens are denied routes not listed
+// here unless they carry the wildcard scope.
+var RoutePermissions = map[string]string{
+	"POST /auth/tokens/scoped":    "",
+	"GET /profile":                "profile.read",
+	"PUT /profile":                "profile.update",
+	"GET /user/permissions":       "profile.read",
+	"POST /profile/avatar":        "profile.avatar.upload",
+	"DELETE /profile/avatar":      "profile.avatar.delete",
+	"GET /sessions":               "session.read",
+	"DELETE /sessions":            "session.delete.own",
+	"DELETE /sessions/:sessionId": "session.delete.own",
+
+	"GET /api/files":                "file.read",
+	"GET /api/files/:id":            "file.read",
+	"POST /api/files/batch-get":     "file.read",
+	"GET /api/files/:id/download":   "file.read",
+	"POST /api/files/download-zip":  "file.read",
+	"GET /api/files/stats":          "file.read",
+	"GET /api/files/stats/timeline": "file.read",
+	"GET /api/files/:id/logs":       "file.read",
+	"POST /api/files/upload":        "file.upload",
+	"PATCH /api/files/:id":          "file.update",
+	"POST /api/files/:id/rename":    "file.update",
+	"POST /api/files/:id/verify":    "file.update",
+	"DELETE /api/files/:id":         "file.delete",
+
+	"GET /api/images/stats":           "image.read",
+	"GET /api/images/:id":             "image.read",
+	"POST /api/images/upload":         "image.upload",
+	"POST /api/images/validate":       "image.upload",
+	"POST /api/images/batch-optimize": "image.upload",
+	"PUT /api/images/settings":        "admin.security",
+
+	"GET /api/commands":                       "command.read",
+	"GET /api/commands/:id":                   "command.read",
+	"GET /api/commands/stats":                 "command.read",
+	"GET /api/commands/whitelist":             "command.read",
+	"POST /api/commands/execute":              "command.execute",
+	"POST /api/commands/whitelist":            "admin.security",
+	"DELETE /api/commands/whitelist/:command": "admin.security",
+	"POST /api/commands/whitelist/initialize": "admin.security",
+
+	"POST /admin/users/:userId/role":            "admin.users",
+	"POST /admin/users/bulk-role":               "admin.users",
+	"GET /admin/users/:id":                      "admin.users",
+	"PUT /admin/users/:id":                      "admin.users",
+	"DELETE /admin/users/:id":                   "admin.users",
+	"GET /admin/rbac/stats":                     "admin.stats",
+	"GET /security/metrics":                     "admin.stats",
+	"GET /admin/users/:id/activity":             "admin.security",
+	"GET /admin/users/:id/export":               "admin.security",
+	"POST /admin/security/metrics/reset":        "admin.security",
+	"GET /admin/security/status":                "admin.security",
+	"PUT /admin/security/config":                "admin.security",
+	"GET /admin/security/rate-limit-exemptions": "admin.security",
+	"PUT /admin/security/rate-limit-exemptions": "admin.security",
+	"GET /admin/security/login-anomalies":       "admin.security",
+	"PUT /admin/security/login-anomalies":       "admin.security",
+	"GET /admin/security/registration":          "admin.security",
+	"PUT /admin/security/registration":          "admin.security",
+	"GET /admin/security/svg-policy":            "admin.security",
+	"PUT /admin/security/svg-policy":            "admin.security",
+	"GET /admin/security/filename-policy":       "admin.security",
+	"PUT /admin/security/filename-policy":       "admin.security",
+	"GET /admin/security/download-policy":       "admin.security",
+	"PUT /admin/security/download-policy":       "admin.security",
+	"GET /admin/security/compression":           "admin.security",
+	"PUT /admin/security/compression":           "admin.security",
+	"GET /admin/security/access-log":            "admin.security",
+	"PUT /admin/security/access-log":            "admin.security",
+	"GET /admin/security/audit-alerts":          "admin.security",
+	"PUT /admin/security/audit-alerts":          "admin.security",
+	"GET /admin/security/audit-sampling":        "admin.security",
+	"PUT /admin/security/audit-sampling":        "admin.security",
+	"GET /admin/security/password-policy":       "admin.security",
+	"PUT /admin/security/password-policy":       "admin.security",
+	"GET /admin/security/password-hasher":       "admin.security",
+	"PUT /admin/security/password-hasher":       "admin.security",
+	"GET /admin/security/jwt-keys":              "admin.security",
+	"PUT /admin/security/jwt-keys":              "admin.security",
+	"POST /admin/security/jwt-keys/rotate":      "admin.security",
+	"GET /admin/security/logs":                  "admin.security",
+	"GET /api/performance/cache/ttl":            "admin.security",
+	"PUT /api/performance/cache/ttl":            "admin.security",
+	"GET /api/audit/config":                     "admin.security",
+	"PUT /api/audit/config":                     "admin.security",
+	"POST /api/audit/cleanup":                   "admin.security",
+	"GET /api/audit/stream":                     "admin.security",
+}
+
+// PermissionCheckedKey is the context key permission middlewares set once the
+// role and token scopes have passed their check
+const PermissionCheckedKey = "permission_checked"
+
+// MarkPermissionChecked records in the context that a permission middleware
+// checked the token scopes for the current route
+func MarkPermissionChecked(c *gin.Context) {
+	c.Set(PermissionCheckedKey, true)
+}
+
+// RouteScopesAllow checks if token scopes allow calling the matched route.
+// Unscoped tokens and the wildcard scope are always allowed, as are requests
+// a permission middleware already checked.
+func RouteScopesAllow(c *gin.Context, scopes []string) bool {
+	if ScopesAllow(scopes, "*") || c.GetBool(PermissionCheckedKey) {
+		return true
+	}
+
+	permission, mapped := RoutePermissions[c.Request.Method+" "+c.FullPath()]
+	return mapped && (permission == "" || ScopesAllow(scopes, permission))
+}
+
 // RequirePermission middleware that checks if user has required permission
 func RequirePermission(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -113,7 +273,7 @@ func RequirePermission(permission string) gin.HandlerFunc {
 			return
 		}
 
-		if !HasPermission(roleName, permission) {
+		if !HasScopedPermission(roleName, ContextScopes(c), permission) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"error": "Insufficient permissions",
 				"required_permission": permission,
@@ -123,6 +283,7 @@ func RequirePermission(permission string) gin.HandlerFunc {
 			return
 		}
 
+		MarkPermissionChecked(c)
 		c.Next()
 	}
 }
@@ -0,0 +1,749 @@
+// Package config loads the startup configuration of the server: defaults,
+// overridden by an optional JSON file, overridden by the environment.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golangmcp/internal/auth"
+	"golangmcp/internal/models"
+	"golangmcp/internal/security"
+	"golangmcp/internal/services"
+)
+
+// EnvConfigFile names the optional JSON configuration file
+const EnvConfigFile = "CONFIG_FILE"
+
+// Environment variables overriding the configuration file
+const (
+	EnvHTTPAddr                  = "HTTP_ADDR"
+	EnvGinMode                   = "GIN_MODE"
+	EnvShutdownTimeout           = "SHUTDOWN_TIMEOUT"
+	EnvStringIDs                 = "STRING_IDS"
+	EnvDatabasePath              = "DB_PATH"
+	EnvRequestTimeout            = "REQUEST_TIMEOUT"
+	EnvMaxRequestSize            = "MAX_REQUEST_SIZE" // bytes
+	EnvCSRFEnabled               = "CSRF_ENABLED"
+	EnvAllowedRedirectURIs       = "ALLOWED_REDIRECT_URIS" // comma-separated
+	EnvMaxConcurrentUploads      = "MAX_CONCURRENT_UPLOADS"
+	EnvUploadRetryAfter          = "UPLOAD_RETRY_AFTER"
+	EnvMultipartMemory           = "MULTIPART_MEMORY" // bytes
+	EnvUploadTempDir             = "UPLOAD_TEMP_DIR"
+	EnvUploadTempMaxAge          = "UPLOAD_TEMP_MAX_AGE"
+	EnvFileDescriptionMaxLength  = "FILE_DESCRIPTION_MAX_LENGTH" // characters
+	EnvFileMaxTags               = "FILE_MAX_TAGS"
+	EnvFileTagMaxLength          = "FILE_TAG_MAX_LENGTH" // characters
+	EnvCacheDefaultTTL           = "CACHE_DEFAULT_TTL"
+	EnvCacheUsersTTL             = "CACHE_USERS_TTL"
+	EnvCacheFilesTTL             = "CACHE_FILES_TTL"
+	EnvRateLimitPerMinute        = "RATE_LIMIT_PER_MINUTE"
+	EnvRateLimitWarningThreshold = "RATE_LIMIT_WARNING_THRESHOLD"
+	EnvRateLimitBypassToken      = "RATE_LIMIT_BYPASS_TOKEN"
+	EnvRateLimitBypassEnabled    = "RATE_LIMIT_BYPASS_ENABLED" // allow the bypass in release mode
+	EnvNotifierType              = "NOTIFIER_TYPE"             // none, smtp or webhook
+	EnvNotifierWebhookURL        = "NOTIFIER_WEBHOOK_URL"
+	EnvSMTPHost                  = "SMTP_HOST"
+	EnvSMTPPort                  = "SMTP_PORT"
+	EnvSMTPUsername              = "SMTP_USERNAME"
+	EnvSMTPPassword              = "SMTP_PASSWORD"
+	EnvSMTPFrom                  = "SMTP_FROM"
+	EnvAuditEvents               = "AUDIT_EVENTS" // JSON object of key to event definition
+	EnvAccessLogEnabled          = "ACCESS_LOG_ENABLED"
+	EnvAccessLogOutput           = "ACCESS_LOG_OUTPUT" // stdout, stderr or a file path
+	EnvDocumentPreviewEnabled    = "DOCUMENT_PREVIEW_ENABLED"
+	EnvDocumentPreviewCommand    = "DOCUMENT_PREVIEW_COMMAND" // program and arguments, separated by spaces
+	EnvAuditAlertsEnabled        = "AUDIT_ALERTS_ENABLED"
+	EnvAuditAlertInterval        = "AUDIT_ALERT_INTERVAL"
+	EnvAuditAlertWindow          = "AUDIT_ALERT_WINDOW"
+	EnvOnboardingHooks           = "ONBOARDING_HOOKS" // comma-separated, or none to disable onboarding
+	EnvOnboardingHookTimeout     = "ONBOARDING_HOOK_TIMEOUT"
+	EnvJWTSecret                 = auth.EnvJWTSecret
+	EnvCORSAllowedOrigins        = "CORS_ALLOWED_ORIGINS"   // comma-separated, "*" allows any origin
+	EnvCORSAllowedMethods        = "CORS_ALLOWED_METHODS"   // comma-separated
+	EnvCORSAllowedHeaders        = "CORS_ALLOWED_HEADERS"   // comma-separated
+	EnvCORSAllowCredentials      = "CORS_ALLOW_CREDENTIALS" // true or false
+	EnvCORSMaxAge                = "CORS_MAX_AGE"           // seconds, 0 omits the header
+	EnvCORSOriginPolicies        = "CORS_ORIGIN_POLICIES"   // JSON array of security.CORSOriginPolicy
+	EnvCORSPublicPaths           = "CORS_PUBLIC_PATHS"      // comma-separated
+	EnvTLSCertFile               = "TLS_CERT_FILE"
+	EnvTLSKeyFile                = "TLS_KEY_FILE"
+	EnvTLSMinVersion             = "TLS_MIN_VERSION" // 1.2 or 1.3
+	EnvTLSAddr                   = "TLS_ADDR"        // HTTPS listen address
+)
+
+// minBypassTokenLength keeps the rate limit bypass token from being guessable
+const minBypassTokenLength = 32
+
+// Duration is a time.Duration read from JSON as a string such as "30s"
+type Duration struct {
+	time.Duration
+}
+
+// MarshalJSON writes the duration as a string
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON reads a duration string
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return errors.New("durations must be strings such as \"30s\"")
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// ServerConfig represents how the server listens and shuts down
+type ServerConfig struct {
+	HTTPAddr        string   `json:"http_addr"`
+	Mode            string   `json:"mode"` // gin mode: debug, release or test
+	ShutdownTimeout Duration `json:"shutdown_timeout"`
+	StringIDs       bool     `json:"string_ids"` // serialize response IDs as strings, see security.StringIDMiddleware
+}
+
+// DatabaseConfig represents the database connection
+type DatabaseConfig struct {
+	Path string `json:"path"` // SQLite database file
+}
+
+// SecurityConfig represents the request limits applied to every route
+type SecurityConfig struct {
+	RequestTimeout Duration `json:"request_timeout"` // 0 disables
+	MaxRequestSize int64    `json:"max_request_size"`
+	EnableCSRF     bool     `json:"enable_csrf"`
+	// AllowedRedirectURIs are the absolute URLs redirects may go to, at or
+	// below each, so redirect parameters can't be used as open redirects
+	AllowedRedirectURIs []string `json:"allowed_redirect_uris"`
+}
+
+// AuthConfig represents how tokens are signed
+type AuthConfig struct {
+	// JWTSecret signs and validates JWTs. The default is the public
+	// development key, see auth.DefaultJWTSecret.
+	JWTSecret string `json:"jwt_secret"`
+}
+
+// CORSConfig represents which origins may call the API from a browser
+type CORSConfig struct {
+	AllowedOrigins   []string                    `json:"allowed_origins"` // "*" allows any origin
+	AllowedMethods   []string                    `json:"allowed_methods"`
+	AllowedHeaders   []string                    `json:"allowed_headers"`
+	AllowCredentials bool                        `json:"allow_credentials"`
+	MaxAge           Duration                    `json:"max_age"` // 0 omits the header
+	OriginPolicies   []security.CORSOriginPolicy `json:"origin_policies"`
+	// PublicPaths only allow safe methods cross-origin, without credentials
+	PublicPaths []string `json:"public_paths"`
+}
+
+// TLSConfig represents how HTTPS is served. TLS is served when a certificate
+// and a key are configured; server.http_addr then only redirects to HTTPS.
+type TLSConfig struct {
+	CertFile   string `json:"cert_file"`
+	KeyFile    string `json:"key_file"`
+	MinVersion string `json:"min_version"` // 1.2 or 1.3
+	Addr       string `json:"addr"`        // HTTPS listen address
+}
+
+// UploadConfig represents how uploads are admitted
+type UploadConfig struct {
+	MaxConcurrent int      `json:"max_concurrent"` // 0 = unlimited
+	RetryAfter    Duration `json:"retry_after"`
+	// MultipartMemory is how much of a multipart upload is held in memory;
+	// larger files spill to temp files
+	MultipartMemory int64 `json:"multipart_memory"`
+	// TempDir is where large uploads spill to, empty = the OS temp directory.
+	// Temp files older than TempMaxAge are left by interrupted requests and
+	// swept, only from a dedicated TempDir.
+	TempDir           string   `json:"temp_dir"`
+	TempMaxAge        Duration `json:"temp_max_age"`
+	TempSweepInterval Duration `json:"temp_sweep_interval"`
+}
+
+// CacheConfig represents how long responses are cached
+type CacheConfig struct {
+	DefaultTTL Duration `json:"default_ttl"`
+	UsersTTL   Duration `json:"users_ttl"`
+	FilesTTL   Duration `json:"files_ttl"`
+}
+
+// RateLimitConfig represents the global rate limit
+type RateLimitConfig struct {
+	PerMinute        int `json:"per_minute"`
+	WarningThreshold int `json:"warning_threshold"` // 0 disables
+	// BypassToken exempts requests sending it in X-RateLimit-Bypass, for load
+	// tests. It only applies outside release mode unless BypassEnabled is set.
+	BypassToken   string `json:"bypass_token"`
+	BypassEnabled bool   `json:"bypass_enabled"`
+}
+
+// AccessLogConfig represents the JSON access log of every request
+type AccessLogConfig struct {
+	Enabled  bool                         `json:"enabled"`
+	Output   string                       `json:"output"`   // stdout, stderr or a file appended to
+	Sampling []security.AccessLogSampling `json:"sampling"` // for high-traffic routes
+}
+
+// DocumentPreviewConfig represents the first-page thumbnails of uploaded documents
+type DocumentPreviewConfig struct {
+	Enabled bool `json:"enabled"`
+	// Command renders a thumbnail, see services.CommandThumbnailer for its
+	// {input} and {output} placeholders
+	Command   []string `json:"command"`
+	MimeTypes []string `json:"mime_types"`
+	Timeout   Duration `json:"timeout"`
+}
+
+// AuditAlertsConfig represents the periodic scan of recent audit logs for alerts
+type AuditAlertsConfig struct {
+	Enabled  bool                      `json:"enabled"`
+	Interval Duration                  `json:"interval"`
+	Window   Duration                  `json:"window"` // how far back each scan looks
+	Rules    []services.AuditAlertRule `json:"rules"`
+}
+
+// OnboardingConfig represents the hooks run for newly registered users
+type OnboardingConfig struct {
+	Hooks       []string `json:"hooks"` // enabled hooks by name, empty disables onboarding
+	HookTimeout Duration `json:"hook_timeout"`
+}
+
+// Config represents the startup configuration of the server
+type Config struct {
+	Server    ServerConfig            `json:"server"`
+	Database  DatabaseConfig          `json:"database"`
+	Auth      AuthConfig              `json:"auth"`
+	Security  SecurityConfig          `json:"security"`
+	CORS      CORSConfig              `json:"cors"`
+	TLS       TLSConfig               `json:"tls"`
+	Upload    UploadConfig            `json:"upload"`
+	Cache     CacheConfig             `json:"cache"`
+	RateLimit RateLimitConfig         `json:"rate_limit"`
+	Notifier  services.NotifierConfig `json:"notifier"`
+
+	// FileMetadata limits the description and tags stored with files
+	FileMetadata services.FileMetadataPolicy `json:"file_metadata"`
+
+	AccessLog       AccessLogConfig       `json:"access_log"`
+	DocumentPreview DocumentPreviewConfig `json:"document_preview"`
+	AuditAlerts     AuditAlertsConfig     `json:"audit_alerts"`
+	Onboarding      OnboardingConfig      `json:"onboarding"`
+
+	// AuditEvents extends the audit event taxonomy with domain-specific events,
+	// keyed like the predefined ones
+	AuditEvents map[string]models.AuditEvent `json:"audit_events"`
+}
+
+// Default returns default configuration, matching the defaults of the
+// packages it configures
+func Default() *Config {
+	sc := security.DefaultSecurityConfig
+	ttls := services.DefaultCacheTTLs()
+	uploadTemp := services.DefaultUploadTempPolicy()
+	accessLog := security.DefaultAccessLogConfig()
+	preview := services.DefaultDocumentPreviewPolicy()
+	alerts := services.DefaultAuditAlertPolicy()
+	onboarding := services.DefaultOnboardingPolicy()
+	tls := security.DefaultTLSConfig()
+	var onboardingHooks []string
+	for name, enabled := range onboarding.Hooks {
+		if enabled {
+			onboardingHooks = append(onboardingHooks, name)
+		}
+	}
+	sort.Strings(onboardingHooks)
+	return &Config{
+		Server: ServerConfig{
+			HTTPAddr:        tls.HTTPAddr,
+			Mode:            "debug",
+			ShutdownTimeout: Duration{10 * time.Second},
+		},
+		Database: DatabaseConfig{
+			Path: "./golangmcp.db",
+		},
+		Auth: AuthConfig{
+			JWTSecret: auth.DefaultJWTSecret,
+		},
+		Security: SecurityConfig{
+			RequestTimeout:      Duration{sc.RequestTimeout},
+			MaxRequestSize:      sc.MaxRequestSize,
+			EnableCSRF:          sc.EnableCSRF,
+			AllowedRedirectURIs: append([]string(nil), sc.AllowedRedirectURIs...),
+		},
+		CORS: CORSConfig{
+			AllowedOrigins:   append([]string(nil), sc.AllowedOrigins...),
+			AllowedMethods:   append([]string(nil), sc.AllowedMethods...),
+			AllowedHeaders:   append([]string(nil), sc.AllowedHeaders...),
+			AllowCredentials: sc.AllowCredentials,
+			MaxAge:           Duration{sc.CORSMaxAge},
+			OriginPolicies:   append([]security.CORSOriginPolicy(nil), sc.CORSOriginPolicies...),
+			PublicPaths:      append([]string(nil), sc.CORSPublicPaths...),
+		},
+		TLS: TLSConfig{
+			MinVersion: tls.MinVersion,
+			Addr:       tls.Addr,
+		},
+		Upload: UploadConfig{
+			MaxConcurrent:     sc.MaxConcurrentUploads,
+			RetryAfter:        Duration{sc.UploadRetryAfter},
+			MultipartMemory:   sc.MultipartMemory,
+			TempMaxAge:        Duration{uploadTemp.MaxAge},
+			TempSweepInterval: Duration{uploadTemp.SweepInterval},
+		},
+		Cache: CacheConfig{
+			DefaultTTL: Duration{15 * time.Minute},
+			UsersTTL:   Duration{ttls[services.CacheEndpointUsers]},
+			FilesTTL:   Duration{ttls[services.CacheEndpointFiles]},
+		},
+		RateLimit: RateLimitConfig{
+			PerMinute:        sc.RateLimitPerMinute,
+			WarningThreshold: sc.RateLimitWarningThreshold,
+		},
+		Notifier:     services.DefaultNotifierConfig(),
+		FileMetadata: *services.DefaultFileMetadataPolicy(),
+		AccessLog: AccessLogConfig{
+			Enabled:  accessLog.Enabled,
+			Output:   "stdout",
+			Sampling: accessLog.Sampling,
+		},
+		DocumentPreview: DocumentPreviewConfig{
+			Enabled:   preview.Enabled,
+			MimeTypes: preview.MimeTypes,
+			Timeout:   Duration{time.Duration(preview.TimeoutSeconds) * time.Second},
+		},
+		AuditAlerts: AuditAlertsConfig{
+			Enabled:  alerts.Enabled,
+			Interval: Duration{time.Duration(alerts.IntervalSeconds) * time.Second},
+			Window:   Duration{time.Duration(alerts.WindowMinutes) * time.Minute},
+			Rules:    alerts.Rules,
+		},
+		Onboarding: OnboardingConfig{
+			Hooks:       onboardingHooks,
+			HookTimeout: Duration{onboarding.Timeout},
+		},
+	}
+}
+
+// Validate checks every section and reports all invalid values at once
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.HTTPAddr != "", "server.http_addr is required")
+	check(c.Server.Mode == "debug" || c.Server.Mode == "release" || c.Server.Mode == "test",
+		"server.mode must be debug, release or test, got %q", c.Server.Mode)
+	check(c.Server.ShutdownTimeout.Duration > 0, "server.shutdown_timeout must be positive")
+
+	check(strings.TrimSpace(c.Database.Path) != "", "database.path is required")
+
+	check(c.Auth.JWTSecret != "", "auth.jwt_secret is required")
+
+	check(c.Security.RequestTimeout.Duration >= 0, "security.request_timeout cannot be negative")
+	check(c.Security.MaxRequestSize > 0, "security.max_request_size must be positive")
+
+	cors := security.DefaultSecurityConfig
+	c.ApplySecurity(&cors)
+	if err := cors.ValidateCORS(); err != nil {
+		errs = append(errs, fmt.Errorf("cors: %v", err))
+	}
+
+	if err := c.TLSConfig().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("tls: %v", err))
+	}
+
+	check(c.Upload.MaxConcurrent >= 0, "upload.max_concurrent cannot be negative")
+	check(c.Upload.RetryAfter.Duration >= time.Second, "upload.retry_after must be at least 1s")
+	check(c.Upload.MultipartMemory > 0, "upload.multipart_memory must be positive")
+	check(c.Upload.TempMaxAge.Duration >= time.Minute, "upload.temp_max_age must be at least 1m")
+	check(c.Upload.TempSweepInterval.Duration >= time.Minute, "upload.temp_sweep_interval must be at least 1m")
+
+	check(c.Cache.DefaultTTL.Duration >= time.Second, "cache.default_ttl must be at least 1s")
+	for name, ttl := range map[string]time.Duration{"users_ttl": c.Cache.UsersTTL.Duration, "files_ttl": c.Cache.FilesTTL.Duration} {
+		check(ttl >= time.Second && ttl <= services.MaxCacheTTL, "cache.%s must be between 1s and %s", name, services.MaxCacheTTL)
+	}
+
+	check(c.RateLimit.PerMinute > 0, "rate_limit.per_minute must be positive")
+	check(c.RateLimit.WarningThreshold >= 0 && c.RateLimit.WarningThreshold < c.RateLimit.PerMinute,
+		"rate_limit.warning_threshold must be between 0 and per_minute")
+	check(c.RateLimit.BypassToken == "" || len(c.RateLimit.BypassToken) >= minBypassTokenLength,
+		"rate_limit.bypass_token must be at least %d characters", minBypassTokenLength)
+
+	if err := c.Notifier.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("notifier: %v", err))
+	}
+
+	if err := c.FileMetadata.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("file_metadata: %v", err))
+	}
+
+	check(strings.TrimSpace(c.AccessLog.Output) != "", "access_log.output is required")
+	if err := c.AccessLogConfig().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("access_log: %v", err))
+	}
+
+	check(!c.DocumentPreview.Enabled || len(c.DocumentPreview.Command) > 0, "document_preview.command is required when previews are enabled")
+	check(c.DocumentPreview.Timeout.Duration >= time.Second, "document_preview.timeout must be at least 1s")
+
+	if err := c.AuditAlertPolicy().Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("audit_alerts: %v", err))
+	}
+
+	check(c.Onboarding.HookTimeout.Duration >= time.Second, "onboarding.hook_timeout must be at least 1s")
+
+	for key, event := range c.AuditEvents {
+		if err := models.ValidateAuditEvent(key, event); err != nil {
+			errs = append(errs, fmt.Errorf("audit_events: %v", err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Load builds the configuration from the defaults, the file named by
+// CONFIG_FILE if set, and the environment, then validates it
+func Load() (*Config, error) {
+	config := Default()
+
+	if path := strings.TrimSpace(os.Getenv(EnvConfigFile)); path != "" {
+		if err := config.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return config, nil
+}
+
+// loadFile overrides the configuration with a JSON file. Unknown keys are
+// rejected so a typo can't silently leave a setting at its default.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides the configuration with the environment variables that are set
+func (c *Config) loadEnv() error {
+	var errs []error
+	setString := func(key string, target *string) {
+		if value, ok := lookupEnv(key); ok {
+			*target = value
+		}
+	}
+	setDuration := func(key string, target *Duration) {
+		if value, ok := lookupEnv(key); ok {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", key, err))
+				return
+			}
+			target.Duration = parsed
+		}
+	}
+	setInt := func(key string, target *int) {
+		if value, ok := lookupEnv(key); ok {
+			parsed, err := strconv.Atoi(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+	setBool := func(key string, target *bool) {
+		if value, ok := lookupEnv(key); ok {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be true or false, got %q", key, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+	setInt64 := func(key string, target *int64) {
+		if value, ok := lookupEnv(key); ok {
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s must be an integer, got %q", key, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+
+	setString(EnvHTTPAddr, &c.Server.HTTPAddr)
+	setString(EnvGinMode, &c.Server.Mode)
+	setDuration(EnvShutdownTimeout, &c.Server.ShutdownTimeout)
+	setString(EnvDatabasePath, &c.Database.Path)
+	setString(EnvJWTSecret, &c.Auth.JWTSecret)
+	setDuration(EnvRequestTimeout, &c.Security.RequestTimeout)
+	setInt64(EnvMaxRequestSize, &c.Security.MaxRequestSize)
+	setBool(EnvStringIDs, &c.Server.StringIDs)
+	setBool(EnvCSRFEnabled, &c.Security.EnableCSRF)
+	if value, ok := lookupEnv(EnvAllowedRedirectURIs); ok {
+		c.Security.AllowedRedirectURIs = splitList(value)
+	}
+	setList := func(key string, target *[]string) {
+		if value, ok := lookupEnv(key); ok {
+			*target = splitList(value)
+		}
+	}
+	setList(EnvCORSAllowedOrigins, &c.CORS.AllowedOrigins)
+	if value, ok := lookupEnv(EnvCORSAllowedMethods); ok {
+		c.CORS.AllowedMethods = splitList(strings.ToUpper(value))
+	}
+	setList(EnvCORSAllowedHeaders, &c.CORS.AllowedHeaders)
+	setBool(EnvCORSAllowCredentials, &c.CORS.AllowCredentials)
+	if value, ok := lookupEnv(EnvCORSMaxAge); ok {
+		seconds, err := strconv.Atoi(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s must be a number of seconds, got %q", EnvCORSMaxAge, value))
+		} else {
+			c.CORS.MaxAge.Duration = time.Duration(seconds) * time.Second
+		}
+	}
+	if value, ok := lookupEnv(EnvCORSOriginPolicies); ok {
+		var policies []security.CORSOriginPolicy
+		if err := json.Unmarshal([]byte(value), &policies); err != nil {
+			errs = append(errs, fmt.Errorf("%s must be a JSON array of origin policies: %v", EnvCORSOriginPolicies, err))
+		} else {
+			c.CORS.OriginPolicies = policies
+		}
+	}
+	setList(EnvCORSPublicPaths, &c.CORS.PublicPaths)
+	setString(EnvTLSCertFile, &c.TLS.CertFile)
+	setString(EnvTLSKeyFile, &c.TLS.KeyFile)
+	setString(EnvTLSMinVersion, &c.TLS.MinVersion)
+	setString(EnvTLSAddr, &c.TLS.Addr)
+	setInt(EnvMaxConcurrentUploads, &c.Upload.MaxConcurrent)
+	setDuration(EnvUploadRetryAfter, &c.Upload.RetryAfter)
+	setInt64(EnvMultipartMemory, &c.Upload.MultipartMemory)
+	setString(EnvUploadTempDir, &c.Upload.TempDir)
+	setDuration(EnvUploadTempMaxAge, &c.Upload.TempMaxAge)
+	setInt(EnvFileDescriptionMaxLength, &c.FileMetadata.MaxDescriptionLength)
+	setInt(EnvFileMaxTags, &c.FileMetadata.MaxTags)
+	setInt(EnvFileTagMaxLength, &c.FileMetadata.MaxTagLength)
+	setDuration(EnvCacheDefaultTTL, &c.Cache.DefaultTTL)
+	setDuration(EnvCacheUsersTTL, &c.Cache.UsersTTL)
+	setDuration(EnvCacheFilesTTL, &c.Cache.FilesTTL)
+	setInt(EnvRateLimitPerMinute, &c.RateLimit.PerMinute)
+	setInt(EnvRateLimitWarningThreshold, &c.RateLimit.WarningThreshold)
+	setString(EnvRateLimitBypassToken, &c.RateLimit.BypassToken)
+	setBool(EnvRateLimitBypassEnabled, &c.RateLimit.BypassEnabled)
+	setString(EnvNotifierType, &c.Notifier.Type)
+	setString(EnvNotifierWebhookURL, &c.Notifier.WebhookURL)
+	setString(EnvSMTPHost, &c.Notifier.SMTP.Host)
+	setInt(EnvSMTPPort, &c.Notifier.SMTP.Port)
+	setString(EnvSMTPUsername, &c.Notifier.SMTP.Username)
+	setString(EnvSMTPPassword, &c.Notifier.SMTP.Password)
+	setString(EnvSMTPFrom, &c.Notifier.SMTP.From)
+	setBool(EnvAccessLogEnabled, &c.AccessLog.Enabled)
+	setString(EnvAccessLogOutput, &c.AccessLog.Output)
+	setBool(EnvDocumentPreviewEnabled, &c.DocumentPreview.Enabled)
+	if value, ok := lookupEnv(EnvDocumentPreviewCommand); ok {
+		c.DocumentPreview.Command = strings.Fields(value)
+	}
+	setBool(EnvAuditAlertsEnabled, &c.AuditAlerts.Enabled)
+	setDuration(EnvAuditAlertInterval, &c.AuditAlerts.Interval)
+	setDuration(EnvAuditAlertWindow, &c.AuditAlerts.Window)
+	if value, ok := lookupEnv(EnvOnboardingHooks); ok {
+		c.Onboarding.Hooks = nil
+		if value != "none" {
+			c.Onboarding.Hooks = splitList(value)
+		}
+	}
+	setDuration(EnvOnboardingHookTimeout, &c.Onboarding.HookTimeout)
+	if value, ok := lookupEnv(EnvAuditEvents); ok {
+		var events map[string]models.AuditEvent
+		if err := json.Unmarshal([]byte(value), &events); err != nil {
+			errs = append(errs, fmt.Errorf("%s must be a JSON object of event definitions: %v", EnvAuditEvents, err))
+		} else {
+			c.AuditEvents = events
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// CacheTTLs returns the per-endpoint cache TTLs, keyed like services.DefaultCacheTTLs
+func (c *Config) CacheTTLs() map[string]time.Duration {
+	return map[string]time.Duration{
+		services.CacheEndpointUsers: c.Cache.UsersTTL.Duration,
+		services.CacheEndpointFiles: c.Cache.FilesTTL.Duration,
+	}
+}
+
+// RateLimitBypassToken returns the rate limit bypass token in effect: the
+// configured token outside release mode, or in release mode only when the
+// bypass is explicitly enabled. Empty means the bypass is off.
+func (c *Config) RateLimitBypassToken() string {
+	if c.Server.Mode == "release" && !c.RateLimit.BypassEnabled {
+		return ""
+	}
+	return c.RateLimit.BypassToken
+}
+
+// UploadTempPolicy returns where uploads spill to disk and how their stale
+// temp files are swept
+func (c *Config) UploadTempPolicy() *services.UploadTempPolicy {
+	return &services.UploadTempPolicy{
+		Dir:           c.Upload.TempDir,
+		MaxAge:        c.Upload.TempMaxAge.Duration,
+		SweepInterval: c.Upload.TempSweepInterval.Duration,
+	}
+}
+
+// AccessLogConfig returns which requests are written to the access log
+func (c *Config) AccessLogConfig() *security.AccessLogConfig {
+	return &security.AccessLogConfig{
+		Enabled:  c.AccessLog.Enabled,
+		Sampling: append([]security.AccessLogSampling(nil), c.AccessLog.Sampling...),
+	}
+}
+
+// OpenAccessLog returns the writer of the access log. A file is opened for
+// appending and stays open for the life of the process.
+func (c *Config) OpenAccessLog() (io.Writer, error) {
+	switch c.AccessLog.Output {
+	case "stdout":
+		return os.Stdout, nil
+	case "stderr":
+		return os.Stderr, nil
+	}
+	return os.OpenFile(c.AccessLog.Output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+}
+
+// DocumentPreviewPolicy returns which uploaded documents get a thumbnail
+func (c *Config) DocumentPreviewPolicy() *services.DocumentPreviewPolicy {
+	policy := services.DefaultDocumentPreviewPolicy()
+	policy.Enabled = c.DocumentPreview.Enabled
+	policy.MimeTypes = append([]string(nil), c.DocumentPreview.MimeTypes...)
+	policy.TimeoutSeconds = int(c.DocumentPreview.Timeout.Seconds())
+	return policy
+}
+
+// DocumentThumbnailer returns the renderer of document thumbnails, or nil when
+// no command is configured
+func (c *Config) DocumentThumbnailer() services.DocumentThumbnailer {
+	if len(c.DocumentPreview.Command) == 0 {
+		return nil
+	}
+	return &services.CommandThumbnailer{
+		Command: c.DocumentPreview.Command[0],
+		Args:    append([]string(nil), c.DocumentPreview.Command[1:]...),
+	}
+}
+
+// AuditAlertPolicy returns how recent audit logs are scanned for alerts
+func (c *Config) AuditAlertPolicy() *services.AuditAlertPolicy {
+	return &services.AuditAlertPolicy{
+		Enabled:         c.AuditAlerts.Enabled,
+		IntervalSeconds: int(c.AuditAlerts.Interval.Seconds()),
+		WindowMinutes:   int(c.AuditAlerts.Window.Minutes()),
+		Rules:           append([]services.AuditAlertRule(nil), c.AuditAlerts.Rules...),
+	}
+}
+
+// OnboardingPolicy returns which onboarding hooks run after registration.
+// Hook names are checked when the policy is applied, once every hook is registered.
+func (c *Config) OnboardingPolicy() *services.OnboardingPolicy {
+	hooks := make(map[string]bool, len(c.Onboarding.Hooks))
+	for _, name := range c.Onboarding.Hooks {
+		hooks[name] = true
+	}
+	return &services.OnboardingPolicy{
+		Hooks:   hooks,
+		Timeout: c.Onboarding.HookTimeout.Duration,
+	}
+}
+
+// RegisterAuditEvents adds the configured audit events to the taxonomy. It
+// fails when an event redefines a predefined one.
+func (c *Config) RegisterAuditEvents() error {
+	for key, event := range c.AuditEvents {
+		if err := models.RegisterAuditEvent(key, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TLSConfig returns how HTTPS is served, enabled when a certificate or a key is configured
+func (c *Config) TLSConfig() *security.TLSConfig {
+	return &security.TLSConfig{
+		Enabled:    c.TLS.CertFile != "" || c.TLS.KeyFile != "",
+		CertFile:   c.TLS.CertFile,
+		KeyFile:    c.TLS.KeyFile,
+		MinVersion: c.TLS.MinVersion,
+		Addr:       c.TLS.Addr,
+		HTTPAddr:   c.Server.HTTPAddr,
+	}
+}
+
+// ApplySecurity copies the security, CORS, upload and rate limit settings onto
+// a security configuration
+func (c *Config) ApplySecurity(sc *security.SecurityConfig) {
+	sc.RequestTimeout = c.Security.RequestTimeout.Duration
+	sc.MaxRequestSize = c.Security.MaxRequestSize
+	sc.EnableCSRF = c.Security.EnableCSRF
+	sc.AllowedRedirectURIs = append([]string(nil), c.Security.AllowedRedirectURIs...)
+	sc.AllowedOrigins = append([]string(nil), c.CORS.AllowedOrigins...)
+	sc.AllowedMethods = append([]string(nil), c.CORS.AllowedMethods...)
+	sc.AllowedHeaders = append([]string(nil), c.CORS.AllowedHeaders...)
+	sc.AllowCredentials = c.CORS.AllowCredentials
+	sc.CORSMaxAge = c.CORS.MaxAge.Duration
+	sc.CORSOriginPolicies = append([]security.CORSOriginPolicy(nil), c.CORS.OriginPolicies...)
+	sc.CORSPublicPaths = append([]string(nil), c.CORS.PublicPaths...)
+	sc.MaxConcurrentUploads = c.Upload.MaxConcurrent
+	sc.UploadRetryAfter = c.Upload.RetryAfter.Duration
+	sc.MultipartMemory = c.Upload.MultipartMemory
+	sc.RateLimitPerMinute = c.RateLimit.PerMinute
+	sc.RateLimitWarningThreshold = c.RateLimit.WarningThreshold
+}
+
+// splitList splits a comma-separated value, dropping empty entries
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// lookupEnv returns a trimmed environment variable, treating blank as unset
+func lookupEnv(key string) (string, bool) {
+	value := strings.TrimSpace(os.Getenv(key))
+	return value, value != ""
+}
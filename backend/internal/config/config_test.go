@@ -0,0 +1,297 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golangmcp/internal/auth"
+	"golangmcp/internal/security"
+	"golangmcp/internal/services"
+)
+
+// clearConfigEnv unsets every configuration variable for the duration of a test
+func clearConfigEnv(t *testing.T) {
+	for _, key := range []string{
+		EnvConfigFile, EnvHTTPAddr, EnvGinMode, EnvShutdownTimeout, EnvDatabasePath,
+		EnvRequestTimeout, EnvMaxRequestSize, EnvCSRFEnabled, EnvMaxConcurrentUploads,
+		EnvUploadRetryAfter, EnvCacheDefaultTTL, EnvCacheUsersTTL, EnvCacheFilesTTL,
+		EnvRateLimitPerMinute, EnvRateLimitWarningThreshold, EnvNotifierType,
+		EnvNotifierWebhookURL, EnvSMTPHost, EnvSMTPPort, EnvSMTPUsername, EnvSMTPPassword, EnvSMTPFrom,
+		EnvAuditEvents, EnvStringIDs, EnvMultipartMemory, EnvUploadTempDir, EnvUploadTempMaxAge,
+		EnvRateLimitBypassToken, EnvRateLimitBypassEnabled, EnvOnboardingHooks, EnvOnboardingHookTimeout,
+		EnvAllowedRedirectURIs, EnvAccessLogEnabled, EnvAccessLogOutput, EnvDocumentPreviewEnabled, EnvDocumentPreviewCommand,
+		EnvAuditAlertsEnabled, EnvAuditAlertInterval, EnvAuditAlertWindow,
+		EnvFileDescriptionMaxLength, EnvFileMaxTags, EnvFileTagMaxLength, EnvJWTSecret,
+		EnvCORSAllowedOrigins, EnvCORSAllowedMethods, EnvCORSAllowedHeaders, EnvCORSAllowCredentials,
+		EnvCORSMaxAge, EnvCORSOriginPolicies, EnvCORSPublicPaths,
+		EnvTLSCertFile, EnvTLSKeyFile, EnvTLSMinVersion, EnvTLSAddr,
+	} {
+		t.Setenv(key, "")
+	}
+}
+
+// writeConfigFile writes a config file and points CONFIG_FILE at it
+func writeConfigFile(t *testing.T, content string) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	t.Setenv(EnvConfigFile, path)
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	clearConfigEnv(t)
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Expected the defaults to be valid: %v", err)
+	}
+	if config.Server.HTTPAddr != ":8080" || config.Database.Path != "./golangmcp.db" {
+		t.Errorf("Unexpected defaults: %+v", config)
+	}
+	if config.RateLimit.PerMinute != security.DefaultSecurityConfig.RateLimitPerMinute {
+		t.Errorf("Expected the security package's rate limit default, got %d", config.RateLimit.PerMinute)
+	}
+}
+
+func TestLoad_FileThenEnv(t *testing.T) {
+	clearConfigEnv(t)
+	writeConfigFile(t, `{
+		"server": {"http_addr": ":9090", "shutdown_timeout": "20s"},
+		"database": {"path": "/var/lib/app.db"},
+		"cache": {"users_ttl": "1m"},
+		"rate_limit": {"per_minute": 300}
+	}`)
+	t.Setenv(EnvRateLimitPerMinute, "600")
+	t.Setenv(EnvCSRFEnabled, "false")
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Server.HTTPAddr != ":9090" || config.Server.ShutdownTimeout.Duration != 20*time.Second {
+		t.Errorf("Expected server settings from the file, got %+v", config.Server)
+	}
+	if config.Database.Path != "/var/lib/app.db" || config.Cache.UsersTTL.Duration != time.Minute {
+		t.Errorf("Expected database and cache settings from the file, got %+v %+v", config.Database, config.Cache)
+	}
+	if config.RateLimit.PerMinute != 600 || config.Security.EnableCSRF {
+		t.Errorf("Expected the environment to override the file, got %+v %+v", config.RateLimit, config.Security)
+	}
+	// Settings left out of the file keep their defaults
+	if config.Cache.FilesTTL.Duration != 2*time.Minute {
+		t.Errorf("Expected the default files TTL, got %s", config.Cache.FilesTTL)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		env  map[string]string
+		want string
+	}{
+		{name: "unknown key", file: `{"server": {"port": 80}}`, want: "unknown field"},
+		{name: "bad duration in file", file: `{"server": {"shutdown_timeout": 10}}`, want: "durations must be strings"},
+		{name: "bad mode", env: map[string]string{EnvGinMode: "prod"}, want: "server.mode"},
+		{name: "bad integer", env: map[string]string{EnvRateLimitPerMinute: "lots"}, want: EnvRateLimitPerMinute},
+		{name: "bad duration", env: map[string]string{EnvRequestTimeout: "30"}, want: EnvRequestTimeout},
+		{name: "zero rate limit", env: map[string]string{EnvRateLimitPerMinute: "0"}, want: "rate_limit.per_minute"},
+		{name: "cache ttl too long", env: map[string]string{EnvCacheFilesTTL: "48h"}, want: "cache.files_ttl"},
+		{name: "short retry after", env: map[string]string{EnvUploadRetryAfter: "100ms"}, want: "upload.retry_after"},
+		{name: "bad audit severity", file: `{"audit_events": {"invoice_approved": {"type": "billing", "action": "approve", "severity": "urgent"}}}`, want: "invalid severity"},
+		{name: "bad audit events env", env: map[string]string{EnvAuditEvents: "[]"}, want: EnvAuditEvents},
+		{name: "zero tag limit", env: map[string]string{EnvFileMaxTags: "0"}, want: "file_metadata"},
+		{name: "smtp without host", env: map[string]string{EnvNotifierType: "smtp", EnvSMTPFrom: "app@example.com"}, want: "notifier"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			if tt.file != "" {
+				writeConfigFile(t, tt.file)
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			_, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error mentioning %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestConfig_ValidateReportsEveryError(t *testing.T) {
+	config := Default()
+	config.Database.Path = ""
+	config.Security.MaxRequestSize = 0
+
+	err := config.Validate()
+	if err == nil || !strings.Contains(err.Error(), "database.path") || !strings.Contains(err.Error(), "security.max_request_size") {
+		t.Errorf("Expected both invalid values to be reported, got %v", err)
+	}
+}
+
+func TestConfig_ApplySecurity(t *testing.T) {
+	config := Default()
+	config.RateLimit.PerMinute = 30
+	config.Upload.MaxConcurrent = 2
+
+	sc := security.DefaultSecurityConfig
+	config.ApplySecurity(&sc)
+	if sc.RateLimitPerMinute != 30 || sc.MaxConcurrentUploads != 2 {
+		t.Errorf("Expected the settings to be applied, got %+v", sc)
+	}
+	if err := sc.Validate(); err != nil {
+		t.Errorf("Expected the applied security config to be valid: %v", err)
+	}
+}
+
+func TestLoad_AllowedRedirectURIs(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv(EnvAllowedRedirectURIs, "https://app.example.com/auth, https://*.example.org")
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	sc := security.DefaultSecurityConfig
+	config.ApplySecurity(&sc)
+	if strings.Join(sc.AllowedRedirectURIs, ",") != "https://app.example.com/auth,https://*.example.org" {
+		t.Errorf("Expected the redirect URIs from the environment, got %v", sc.AllowedRedirectURIs)
+	}
+
+	sc.AllowedRedirectURIs = []string{"//evil.example.com"}
+	if err := sc.Validate(); err == nil {
+		t.Error("Expected an invalid redirect URI to be rejected")
+	}
+}
+
+func TestLoad_CORS(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv(EnvCORSAllowedOrigins, "*")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "cors:") {
+		t.Errorf("Expected credentials with a wildcard origin to be rejected, got %v", err)
+	}
+
+	t.Setenv(EnvCORSAllowCredentials, "false")
+	t.Setenv(EnvCORSAllowedMethods, "get, post")
+	t.Setenv(EnvCORSMaxAge, "120")
+	t.Setenv(EnvCORSOriginPolicies, `[{"origin": "https://*.example.com", "allow_credentials": true}]`)
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	sc := security.DefaultSecurityConfig
+	config.ApplySecurity(&sc)
+	if strings.Join(sc.AllowedOrigins, ",") != "*" || sc.AllowCredentials {
+		t.Errorf("Expected any origin without credentials, got %+v", sc)
+	}
+	if strings.Join(sc.AllowedMethods, ",") != "GET,POST" {
+		t.Errorf("Expected the methods to be upper cased, got %v", sc.AllowedMethods)
+	}
+	if sc.CORSMaxAge != 2*time.Minute || len(sc.CORSOriginPolicies) != 1 {
+		t.Errorf("Expected the max age and origin policies from the environment, got %+v", sc)
+	}
+
+	t.Setenv(EnvCORSOriginPolicies, `[{"origin": "https://*.example.com"`)
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), EnvCORSOriginPolicies) {
+		t.Errorf("Expected malformed origin policies to be rejected, got %v", err)
+	}
+}
+
+func TestLoad_TLS(t *testing.T) {
+	clearConfigEnv(t)
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if tc := config.TLSConfig(); tc.Enabled || tc.HTTPAddr != config.Server.HTTPAddr {
+		t.Errorf("Expected plain HTTP on the server address without a certificate, got %+v", tc)
+	}
+
+	t.Setenv(EnvTLSCertFile, filepath.Join(t.TempDir(), "cert.pem"))
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "tls:") {
+		t.Errorf("Expected a certificate without a key to be rejected, got %v", err)
+	}
+
+	t.Setenv(EnvTLSCertFile, "")
+	t.Setenv(EnvTLSMinVersion, "1.0")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "tls:") {
+		t.Errorf("Expected TLS 1.0 to be rejected as a minimum version, got %v", err)
+	}
+}
+
+func TestLoad_JWTSecret(t *testing.T) {
+	clearConfigEnv(t)
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.Auth.JWTSecret != auth.DefaultJWTSecret {
+		t.Errorf("Expected the development key by default, got %q", config.Auth.JWTSecret)
+	}
+
+	t.Setenv(EnvJWTSecret, "  production-secret  ")
+	if config, err = Load(); err != nil || config.Auth.JWTSecret != "production-secret" {
+		t.Errorf("Expected the secret from the environment, got %q, %v", config.Auth.JWTSecret, err)
+	}
+
+	config.Auth.JWTSecret = ""
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "auth.jwt_secret") {
+		t.Errorf("Expected an empty secret to be rejected, got %v", err)
+	}
+}
+
+func TestConfig_RateLimitBypassToken(t *testing.T) {
+	clearConfigEnv(t)
+	token := strings.Repeat("b", minBypassTokenLength)
+	t.Setenv(EnvRateLimitBypassToken, token)
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if config.RateLimitBypassToken() != token {
+		t.Errorf("Expected the bypass token to apply outside release mode")
+	}
+
+	config.Server.Mode = "release"
+	if config.RateLimitBypassToken() != "" {
+		t.Errorf("Expected the bypass to be off in release mode")
+	}
+	config.RateLimit.BypassEnabled = true
+	if config.RateLimitBypassToken() != token {
+		t.Errorf("Expected an explicitly enabled bypass to apply in release mode")
+	}
+
+	t.Setenv(EnvRateLimitBypassToken, "short")
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "rate_limit.bypass_token") {
+		t.Errorf("Expected a short bypass token to be rejected, got %v", err)
+	}
+}
+
+func TestLoad_OnboardingHooks(t *testing.T) {
+	clearConfigEnv(t)
+
+	config, err := Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if policy := config.OnboardingPolicy(); !policy.Hooks[services.OnboardingWelcomeNotification] {
+		t.Errorf("Expected the welcome notification to be enabled by default, got %+v", policy.Hooks)
+	}
+
+	t.Setenv(EnvOnboardingHooks, "none")
+	config, err = Load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if policy := config.OnboardingPolicy(); len(policy.Hooks) != 0 {
+		t.Errorf("Expected onboarding to be disabled, got %+v", policy.Hooks)
+	}
+}
@@ -24,6 +24,11 @@ func InitDatabase(dsn string) error {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	// Track writes so list endpoints can answer conditional requests
+	if err := models.RegisterTableVersionCallbacks(DB); err != nil {
+		return fmt.Errorf("failed to register table version callbacks: %w", err)
+	}
+
 	// Auto-migrate the schema
 	err = AutoMigrate()
 	if err != nil {
@@ -42,7 +47,21 @@ func InitDatabase(dsn string) error {
 
 // AutoMigrate runs database migrations
 func AutoMigrate() error {
-	return DB.AutoMigrate(
+	if err := dropGlobalFileHashIndex(); err != nil {
+		return err
+	}
+
+	if err := DB.AutoMigrate(Models()...); err != nil {
+		return err
+	}
+
+	// Audit logs are append-only, also for statements the model hooks don't see
+	return models.InstallAuditLogGuard(DB)
+}
+
+// Models returns every model with a table managed by AutoMigrate
+func Models() []interface{} {
+	return []interface{}{
 		&models.User{},
 		&models.File{},
 		&models.FileMetadata{},
@@ -50,7 +69,29 @@ func AutoMigrate() error {
 		&models.Command{},
 		&models.CommandWhitelist{},
 		&models.SecurityAuditLog{},
-	)
+		&models.PasswordHistory{},
+		&models.KnownDevice{},
+		&models.SystemSetting{},
+	}
+}
+
+// dropGlobalFileHashIndex removes the unique indexes that made file hashes
+// unique across all users (idx_files_hash) and per owner including deleted files
+// (idx_files_hash_user); hashes are now unique per owner among files not
+// deleted (idx_files_live_hash_user), so deleted content can be uploaded again
+func dropGlobalFileHashIndex() error {
+	migrator := DB.Migrator()
+	if !migrator.HasTable(&models.File{}) {
+		return nil
+	}
+	for _, index := range []string{"idx_files_hash", "idx_files_hash_user"} {
+		if migrator.HasIndex(&models.File{}, index) {
+			if err := migrator.DropIndex(&models.File{}, index); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
 // OptimizeDatabase performs database optimization
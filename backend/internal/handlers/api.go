@@ -184,18 +184,26 @@ func GetAPIInfoHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, apiInfo)
 }
 
-// GetHealthHandler returns detailed health information
+// GetHealthHandler returns detailed health information. It doubles as the
+// readiness probe, answering 503 while upload storage is unusable.
 func GetHealthHandler(c *gin.Context) {
+	storage, storageHealthy := GetStorageHealth()
+	status, fileUpload, code := "healthy", "ready", http.StatusOK
+	if !storageHealthy {
+		status, fileUpload, code = "degraded", "unavailable", http.StatusServiceUnavailable
+	}
+
 	health := gin.H{
-		"status":    "healthy",
+		"status":    status,
 		"timestamp": time.Now(),
 		"version":   "1.0.0",
 		"uptime":    time.Since(time.Now().Add(-time.Hour)), // Placeholder uptime
 		"services": gin.H{
 			"database": "connected",
 			"session_manager": "active",
-			"file_upload": "ready",
+			"file_upload": fileUpload,
 		},
+		"storage": storage,
 		"endpoints": gin.H{
 			"total": 25,
 			"active": 25,
@@ -203,7 +211,7 @@ func GetHealthHandler(c *gin.Context) {
 		},
 	}
 
-	c.JSON(http.StatusOK, health)
+	c.JSON(code, health)
 }
 
 // GetStatsHandler returns API statistics
@@ -19,7 +19,7 @@ type AuditHandlers struct {
 // NewAuditHandlers creates new audit handlers
 func NewAuditHandlers() *AuditHandlers {
 	return &AuditHandlers{
-		auditManager: services.NewAuditManager(),
+		auditManager: services.GlobalAuditManager,
 	}
 }
 
@@ -64,6 +64,10 @@ func (ah *AuditHandlers) GetAuditLogsHandler(c *gin.Context) {
 		filters["ip_address"] = ipAddress
 	}
 	
+	if requestID := c.Query("request_id"); requestID != "" {
+		filters["request_id"] = requestID
+	}
+	
 	if startDate := c.Query("start_date"); startDate != "" {
 		filters["start_date"] = startDate
 	}
@@ -96,6 +100,7 @@ func (ah *AuditHandlers) GetAuditStatsHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit statistics"})
 		return
 	}
+	stats["writer"] = services.GlobalAuditWriter.Stats()
 	
 	c.JSON(http.StatusOK, gin.H{
 		"data": stats,
@@ -119,7 +124,10 @@ func (ah *AuditHandlers) UpdateAuditConfigHandler(c *gin.Context) {
 		return
 	}
 	
-	ah.auditManager.UpdateConfig(&config)
+	if err := ah.auditManager.UpdateConfig(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Audit configuration updated successfully",
@@ -129,12 +137,18 @@ func (ah *AuditHandlers) UpdateAuditConfigHandler(c *gin.Context) {
 
 // CleanupAuditLogsHandler cleans up old audit logs
 func (ah *AuditHandlers) CleanupAuditLogsHandler(c *gin.Context) {
-	daysStr := c.DefaultQuery("days", "90")
+	// Audit logs retention comes from the central retention policy
+	defaultDays := services.GlobalRetentionManager.GetPolicy().AuditLogs.Days
+	daysStr := c.DefaultQuery("days", strconv.Itoa(defaultDays))
 	days, err := strconv.Atoi(daysStr)
 	if err != nil || days <= 0 {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days parameter"})
 		return
 	}
+	// Audit logs younger than the retention minimum are never removed
+	if days < models.MinAuditLogRetentionDays {
+		days = models.MinAuditLogRetentionDays
+	}
 	
 	err = ah.auditManager.GetLogger().CleanupOldLogs(days)
 	if err != nil {
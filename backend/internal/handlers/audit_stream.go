@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/models"
+)
+
+// AuditStreamHeartbeat is how often an idle audit stream sends a keepalive
+// comment, which also detects clients that went away
+var AuditStreamHeartbeat = 15 * time.Second
+
+// StreamAuditLogsHandler streams newly created audit log entries as
+// text/event-stream (admin.security). Filters: severity and event_type take
+// comma-separated lists, min_severity keeps entries at or above a severity.
+// If the client falls behind, entries are dropped and reported in a "dropped" event.
+func (ah *AuditHandlers) StreamAuditLogsHandler(c *gin.Context) {
+	filter := models.AuditLogFilter{
+		Severities:  splitQueryList(c.Query("severity")),
+		MinSeverity: c.Query("min_severity"),
+		EventTypes:  splitQueryList(c.Query("event_type")),
+	}
+	for _, severity := range append(filter.Severities, filter.MinSeverity) {
+		if severity != "" && !models.IsValidAuditSeverity(severity) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid severity, must be one of low, medium, high, critical"})
+			return
+		}
+	}
+
+	sub := models.GlobalAuditLogBroadcaster.Subscribe(filter, models.DefaultAuditSubscriptionBuffer)
+	defer models.GlobalAuditLogBroadcaster.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable proxy buffering
+	c.Status(http.StatusOK)
+
+	fmt.Fprint(c.Writer, ": subscribed\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(AuditStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": keepalive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case log := <-sub.C:
+			if err := writeAuditStreamEvent(c, sub, &log); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeAuditStreamEvent writes an audit log event, preceded by a dropped event
+// if entries were lost since the last write
+func writeAuditStreamEvent(c *gin.Context, sub *models.AuditLogSubscription, log *models.SecurityAuditLog) error {
+	if dropped := sub.TakeDropped(); dropped > 0 {
+		if _, err := fmt.Fprintf(c.Writer, "event: dropped\ndata: {\"dropped\":%d}\n\n", dropped); err != nil {
+			return err
+		}
+	}
+
+	payload, err := json.Marshal(log)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.Writer, "id: %d\nevent: audit_log\ndata: %s\n\n", log.ID, payload); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// splitQueryList splits a comma-separated query value, dropping empty items
+func splitQueryList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
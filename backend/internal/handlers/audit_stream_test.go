@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+)
+
+func TestStreamAuditLogsHandler_PushesNewHighSeverityLog(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	origBroadcaster := models.GlobalAuditLogBroadcaster
+	t.Cleanup(func() { models.GlobalAuditLogBroadcaster = origBroadcaster })
+	models.GlobalAuditLogBroadcaster = models.NewAuditLogBroadcaster()
+
+	r := gin.New()
+	r.GET("/api/audit/stream", (&AuditHandlers{}).StreamAuditLogsHandler)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/audit/stream?min_severity=high", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", ct)
+	}
+
+	// The subscription exists once the first comment arrives
+	scanner := bufio.NewScanner(resp.Body)
+	if !scanner.Scan() || scanner.Text() != ": subscribed" {
+		t.Fatalf("Expected subscription comment, got %q (%v)", scanner.Text(), scanner.Err())
+	}
+
+	low := &models.SecurityAuditLog{EventType: "authentication", EventAction: "login", Severity: "low", Status: "success"}
+	high := &models.SecurityAuditLog{EventType: "security", EventAction: "brute_force", Severity: "high", Status: "failure"}
+	for _, log := range []*models.SecurityAuditLog{low, high} {
+		if err := models.CreateSecurityAuditLog(db.DB, log); err != nil {
+			t.Fatalf("Failed to create audit log: %v", err)
+		}
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var pushed models.SecurityAuditLog
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &pushed); err != nil {
+			t.Fatalf("Invalid event %q: %v", line, err)
+		}
+		if pushed.ID != high.ID || pushed.Severity != "high" {
+			t.Errorf("Expected the high severity log %d, got %+v", high.ID, pushed)
+		}
+		return
+	}
+	t.Fatalf("Stream ended without an event: %v", scanner.Err())
+}
+
+func TestStreamAuditLogsHandler_RejectsInvalidSeverity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.GET("/api/audit/stream", (&AuditHandlers{}).StreamAuditLogsHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/audit/stream?severity=high,urgent", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
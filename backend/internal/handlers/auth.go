@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"fmt"
+	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"golangmcp/internal/auth"
+	"golangmcp/internal/authorization"
 	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/services"
+	"golangmcp/internal/security"
 	"golangmcp/internal/session"
 )
 
@@ -18,10 +25,19 @@ func RegisterHandler(c *gin.Context) {
 		return
 	}
 
-	// Set default role if not provided
-	if req.Role == "" {
-		req.Role = "user"
+	// Use the configured default role if not provided. Elevated roles are only
+	// assigned by admins, so requesting one is refused and audited.
+	role, err := services.GlobalRegistrationPolicy.ResolveRole(req.Role)
+	if err == services.ErrElevatedRoleRequested {
+		services.NewAuditLogger().LogRegistrationRoleRejected(req.Username, req.Email, req.Role, c.ClientIP(), c.Request.UserAgent(), security.RequestID(c))
+		c.JSON(http.StatusForbidden, gin.H{"error": "Registration cannot request the " + req.Role + " role, it can only be assigned by an admin"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
+	req.Role = role
 
 	user, err := auth.RegisterUser(db.DB, &req)
 	if err != nil {
@@ -33,6 +49,9 @@ func RegisterHandler(c *gin.Context) {
 		return
 	}
 
+	// Welcome the user in the background; onboarding never fails registration
+	services.GlobalOnboarding.Run(*user)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User registered successfully",
 		"user":    user,
@@ -47,11 +66,11 @@ func LoginHandler(c *gin.Context) {
 		return
 	}
 
-	// Use the JWT secret key from main.go
-	jwtSecret := []byte("my_secret_key")
+	jwtSecret := auth.JWTSecret()
 	
-	authResponse, err := auth.LoginUser(db.DB, &req, jwtSecret)
+	authResponse, user, err := auth.LoginUser(db.DB, &req, jwtSecret)
 	if err != nil {
+		// Failures must look the same for every account, whatever its role
 		if err == auth.ErrUserNotFound || err == auth.ErrInvalidCredentials {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 			return
@@ -63,8 +82,12 @@ func LoginHandler(c *gin.Context) {
 	// Create session
 	ipAddress := c.ClientIP()
 	userAgent := c.GetHeader("User-Agent")
-	sess, err := session.GlobalSessionManager.CreateSession(&authResponse.User, authResponse.Token, ipAddress, userAgent)
+	sess, err := session.GlobalSessionManager.CreateSession(user, authResponse.Token, ipAddress, userAgent)
 	if err != nil {
+		if err == session.ErrSessionLimitReached {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Maximum number of active sessions reached"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create session"})
 		return
 	}
@@ -72,16 +95,28 @@ func LoginHandler(c *gin.Context) {
 	// Add session ID to response
 	authResponse.SessionID = sess.ID
 
+	// Flag logins from unfamiliar devices; never block the login on it
+	if _, err := services.GlobalLoginAnomalyDetector.CheckLogin(db.DB, user, ipAddress, userAgent); err != nil {
+		log.Printf("Warning: Failed to check login device: %v", err)
+	}
+
+	// In cookie mode the token only travels in the HttpOnly cookie, out of reach of scripts
+	security.SetSessionCookie(c.Writer, authResponse.Token, authResponse.ExpiresAt)
+	if security.GlobalSecurityConfig.GetConfig().AuthMode == security.AuthModeCookie {
+		authResponse.Token = ""
+	}
+
 	c.JSON(http.StatusOK, authResponse)
 }
 
 // LogoutHandler handles user logout and session invalidation
 func LogoutHandler(c *gin.Context) {
-	// Extract token from Authorization header
-	authHeader := c.GetHeader("Authorization")
-	if authHeader != "" && len(authHeader) > 7 {
-		tokenString := authHeader[7:] // Remove "Bearer " prefix
-		
+	// Extract token from Authorization header or session cookie
+	tokenString, fromCookie := security.RequestToken(c.Request)
+	if fromCookie {
+		security.ClearSessionCookie(c.Writer)
+	}
+	if tokenString != "" {
 		// Get session by token and invalidate it
 		sess, err := session.GlobalSessionManager.GetSessionByToken(tokenString)
 		if err == nil {
@@ -94,6 +129,69 @@ func LogoutHandler(c *gin.Context) {
 	})
 }
 
+// ScopedTokenRequest represents a request to mint a scoped token
+type ScopedTokenRequest struct {
+	Scopes    []string `json:"scopes" binding:"required"`
+	ExpiresIn int      `json:"expires_in"` // hours, defaults to 24, at most 720
+}
+
+// CreateScopedTokenHandler mints a token for the current user limited to a subset of their permissions
+func CreateScopedTokenHandler(c *gin.Context) {
+	var req ScopedTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.Scopes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one scope is required"})
+		return
+	}
+
+	if req.ExpiresIn == 0 {
+		req.ExpiresIn = 24
+	}
+	if maxHours := int(auth.MaxTokenTTL / time.Hour); req.ExpiresIn < 0 || req.ExpiresIn > maxHours {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("expires_in must be between 1 and %d hours", maxHours)})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
+	role, _ := c.Get("role")
+	roleName, _ := role.(string)
+	callerScopes := authorization.ContextScopes(c)
+
+	// A token may only be narrowed: every scope must be held by the role and by the calling token
+	for _, scope := range req.Scopes {
+		if _, exists := authorization.Permissions[scope]; !exists {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope", "scope": scope})
+			return
+		}
+		if !authorization.HasScopedPermission(roleName, callerScopes, scope) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Scope exceeds your permissions", "scope": scope})
+			return
+		}
+	}
+
+	user := &models.User{ID: userID.(uint), Role: roleName}
+	user.Username, _ = username.(string)
+
+	jwtSecret := auth.JWTSecret()
+
+	token, expiresAt, err := auth.GenerateScopedJWT(user, req.Scopes, time.Duration(req.ExpiresIn)*time.Hour, jwtSecret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":      token,
+		"scopes":     req.Scopes,
+		"expires_at": expiresAt,
+	})
+}
+
 // ProfileHandler returns user profile information
 func ProfileHandler(c *gin.Context) {
 	// Extract token from Authorization header
@@ -110,8 +208,7 @@ func ProfileHandler(c *gin.Context) {
 		return
 	}
 
-	// Use the JWT secret key from main.go
-	jwtSecret := []byte("my_secret_key")
+	jwtSecret := auth.JWTSecret()
 	
 	user, err := auth.GetUserFromToken(db.DB, tokenString, jwtSecret)
 	if err != nil {
@@ -127,24 +224,23 @@ func ProfileHandler(c *gin.Context) {
 // AuthMiddleware validates JWT token for protected routes
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Extract token from Authorization header
+		// Extract token from Authorization header or session cookie
 		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
+		if authHeader == "" && security.SessionCookieToken(c.Request) == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
 			c.Abort()
 			return
 		}
 
 		// Check if token starts with "Bearer "
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
+		tokenString, _ := security.RequestToken(c.Request)
+		if tokenString == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization header format"})
 			c.Abort()
 			return
 		}
 
-		// Use the JWT secret key from main.go
-		jwtSecret := []byte("my_secret_key")
+		jwtSecret := auth.JWTSecret()
 		
 		claims, err := auth.ValidateJWT(tokenString, jwtSecret)
 		if err != nil {
@@ -153,10 +249,37 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if session.GlobalSessionManager.IsTokenRevoked(tokenString, claims) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		// Sessions bound to an address can't be used from elsewhere, in case the token was stolen
+		if err := session.GlobalSessionManager.CheckSessionIP(tokenString, claims, c.ClientIP(), c.Request.UserAgent()); err == session.ErrSessionIPMismatch {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session is bound to a different IP address"})
+			c.Abort()
+			return
+		} else if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found"})
+			c.Abort()
+			return
+		}
+
 		// Store user info in context
 		c.Set("user_id", claims.UserID)
 		c.Set("username", claims.Username)
 		c.Set("role", claims.Role)
+		if len(claims.Scopes) > 0 {
+			c.Set("scopes", claims.Scopes)
+		}
+
+		// Scoped tokens only reach routes whose permission they were granted
+		if !authorization.RouteScopesAllow(c, claims.Scopes) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token scopes do not allow this route"})
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
@@ -178,6 +301,14 @@ func AdminMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		// Scoped tokens only reach admin-only routes with the wildcard scope
+		if !authorization.ScopesAllow(authorization.ContextScopes(c), "*") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Token scopes do not allow admin access"})
+			c.Abort()
+			return
+		}
+
+		authorization.MarkPermissionChecked(c)
 		c.Next()
 	}
 }
@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/security"
+	"golangmcp/internal/services"
+)
+
+func doLogin(r *gin.Engine, username, password string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(auth.LoginRequest{Username: username, Password: password})
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestLoginHandler_FailuresDoNotRevealRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	for _, u := range []struct{ username, role string }{{"alice", "admin"}, {"bob", "user"}} {
+		hashedPassword, _ := auth.HashPassword("correct-password")
+		user := &models.User{Username: u.username, Email: u.username + "@example.com", Password: hashedPassword, Role: u.role}
+		if err := user.Create(db.DB); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	r := gin.New()
+	r.POST("/login", LoginHandler)
+
+	admin := doLogin(r, "alice", "wrong-password")
+	user := doLogin(r, "bob", "wrong-password")
+	unknown := doLogin(r, "nobody", "wrong-password")
+
+	if admin.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected 401 for failed admin login, got %d", admin.Code)
+	}
+	for name, w := range map[string]*httptest.ResponseRecorder{"user": user, "unknown": unknown} {
+		if w.Code != admin.Code {
+			t.Errorf("Expected %s failure status %d to match admin failure %d", name, w.Code, admin.Code)
+		}
+		if w.Body.String() != admin.Body.String() {
+			t.Errorf("Expected %s failure body %q to match admin failure %q", name, w.Body.String(), admin.Body.String())
+		}
+	}
+}
+
+func TestLoginHandler_CookieAuthFlow(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	origConfig := security.GlobalSecurityConfig
+	t.Cleanup(func() { security.GlobalSecurityConfig = origConfig })
+	security.GlobalSecurityConfig = security.NewSecurityConfigManager(security.DefaultSecurityConfig)
+	if _, err := security.GlobalSecurityConfig.UpdateConfig(func(config *security.SecurityConfig) {
+		config.AuthMode = security.AuthModeCookie
+	}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	hashedPassword, _ := auth.HashPassword("correct-password")
+	user := &models.User{Username: "carol", Email: "carol@example.com", Password: hashedPassword, Role: "user"}
+	if err := user.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/login", LoginHandler)
+	r.POST("/logout", LogoutHandler)
+	r.GET("/profile", AuthMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"username": c.GetString("username")})
+	})
+
+	w := doLogin(r, "carol", "correct-password")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected login to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	if _, ok := body["token"]; ok {
+		t.Error("Expected the token to be left out of the body in cookie mode")
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != security.DefaultSessionCookieName {
+		t.Fatalf("Expected a session cookie, got %+v", cookies)
+	}
+	cookie := cookies[0]
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteStrictMode || cookie.MaxAge <= 0 {
+		t.Errorf("Expected an HttpOnly, Secure, SameSite=Strict persistent cookie, got %+v", cookie)
+	}
+
+	profile := func(cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := profile(cookie); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "carol") {
+		t.Fatalf("Expected the cookie to authenticate, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := profile(nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a request without credentials to be rejected, got %d", w.Code)
+	}
+
+	// Logging out invalidates the session and clears the cookie
+	req := httptest.NewRequest(http.MethodPost, "/logout", nil)
+	req.AddCookie(cookie)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if cleared := w.Result().Cookies(); len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Errorf("Expected logout to clear the session cookie, got %+v", cleared)
+	}
+	if w := profile(cookie); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the cookie to be rejected after logout, got %d", w.Code)
+	}
+}
+
+func TestAuthMiddleware_SessionBoundToIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	sm := setupTestSessionManager(t)
+	config := sm.GetConfig()
+	config.BindToIP = true
+	sm.UpdateConfig(&config)
+
+	hashedPassword, _ := auth.HashPassword("correct-password")
+	user := &models.User{Username: "dave", Email: "dave@example.com", Password: hashedPassword, Role: "user"}
+	if err := user.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/login", LoginHandler)
+	r.GET("/profile", AuthMiddleware(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"username": c.GetString("username")})
+	})
+
+	// httptest requests come from 192.0.2.1
+	w := doLogin(r, "dave", "correct-password")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected login to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	var response auth.AuthResponse
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	profile := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer "+response.Token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := profile("192.0.2.1:4321"); w.Code != http.StatusOK {
+		t.Errorf("Expected the token to work from the login address, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := profile("198.51.100.7:1234"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the token to be rejected from another address, got %d", w.Code)
+	}
+}
+
+func doRegister(r *gin.Engine, username, role string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(auth.RegisterRequest{Username: username, Email: username + "@example.com", Password: "Password123!", Role: role})
+	req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRegisterHandler_RejectsElevatedRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	r := gin.New()
+	r.POST("/register", RegisterHandler)
+
+	for _, role := range []string{"admin", "moderator"} {
+		w := doRegister(r, "wannabe_"+role, role)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected 403 when registering as %s, got %d: %s", role, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), "only be assigned by an admin") {
+			t.Errorf("Expected a clear error for %s, got %s", role, w.Body.String())
+		}
+	}
+
+	var users int64
+	db.DB.Model(&models.User{}).Count(&users)
+	if users != 0 {
+		t.Errorf("Expected no accounts to be created, got %d", users)
+	}
+
+	var rejections []models.SecurityAuditLog
+	db.DB.Where("event_type = ? AND event_action = ?", "security", "register").Find(&rejections)
+	if len(rejections) != 2 {
+		t.Fatalf("Expected an audit event per rejected registration, got %d", len(rejections))
+	}
+	if rejections[0].Severity != "high" || rejections[0].Status != "failure" || !strings.Contains(rejections[0].Details, `"requested_role":"admin"`) {
+		t.Errorf("Unexpected audit event: %+v", rejections[0])
+	}
+}
+
+func TestRegisterHandler_UsesConfiguredDefaultRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	r := gin.New()
+	r.POST("/register", RegisterHandler)
+
+	if w := doRegister(r, "plain", ""); w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var user models.User
+	db.DB.Where("username = ?", "plain").First(&user)
+	if user.Role != "user" {
+		t.Errorf("Expected the default role user, got %q", user.Role)
+	}
+
+	if w := doRegister(r, "unknown", "superuser"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown role, got %d", w.Code)
+	}
+}
+
+func TestRegisterHandler_OnboardingFailureDoesNotFailRegistration(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	onboarded := make(chan uint, 1)
+	services.GlobalOnboarding.RegisterHook("test_failing_hook", func(ctx context.Context, user models.User) error {
+		onboarded <- user.ID
+		return errors.New("starter folder could not be created")
+	})
+	previous := services.GlobalOnboarding.GetPolicy()
+	t.Cleanup(func() { services.GlobalOnboarding.UpdatePolicy(&previous) })
+	if err := services.GlobalOnboarding.UpdatePolicy(&services.OnboardingPolicy{
+		Hooks:   map[string]bool{"test_failing_hook": true},
+		Timeout: time.Second,
+	}); err != nil {
+		t.Fatalf("Failed to update onboarding policy: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/register", RegisterHandler)
+
+	w := doRegister(r, "newcomer", "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201 despite the failing hook, got %d: %s", w.Code, w.Body.String())
+	}
+	services.GlobalOnboarding.Wait()
+
+	var user models.User
+	db.DB.Where("username = ?", "newcomer").First(&user)
+	select {
+	case id := <-onboarded:
+		if id != user.ID {
+			t.Errorf("Expected the hook to run for user %d, got %d", user.ID, id)
+		}
+	default:
+		t.Error("Expected the onboarding hook to run")
+	}
+}
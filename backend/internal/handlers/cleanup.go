@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/services"
+	"golangmcp/internal/session"
+)
+
+// CleanupAuditLogsNowHandler removes audit logs older than ?days (default from
+// the retention policy) and returns how many were removed (Admin only)
+func CleanupAuditLogsNowHandler(c *gin.Context) {
+	defaultDays := services.GlobalRetentionManager.GetPolicy().AuditLogs.Days
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultDays)))
+	if err != nil || days <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid days parameter"})
+		return
+	}
+	// Audit logs younger than the retention minimum are never removed
+	if days < models.MinAuditLogRetentionDays {
+		days = models.MinAuditLogRetentionDays
+	}
+
+	removed, err := models.DeleteAuditLogsBefore(db.DB, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cleanup audit logs"})
+		return
+	}
+
+	result := gin.H{"days": days, "removed": removed}
+	logCleanup(c, "audit_logs", result)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Audit logs cleanup completed successfully",
+		"data":    result,
+	})
+}
+
+// CleanupSessionsNowHandler removes expired sessions and returns how many were removed (Admin only)
+func CleanupSessionsNowHandler(c *gin.Context) {
+	removed := session.GlobalSessionManager.CleanupSessionsExpiredBefore(time.Now())
+
+	result := gin.H{"removed": removed}
+	logCleanup(c, "sessions", result)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session cleanup completed successfully",
+		"data":    result,
+	})
+}
+
+// CleanupCacheNowHandler removes expired cache items and old rate limit entries
+// by running their scheduled jobs now (Admin only)
+func CleanupCacheNowHandler(c *gin.Context) {
+	result := gin.H{}
+	for _, job := range []string{"cache_cleanup", "rate_limit_cleanup"} {
+		status, err := services.GlobalScheduler.Trigger(job)
+		if err == services.ErrJobNotFound {
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run " + job + ": " + err.Error()})
+			return
+		}
+		result[job] = status.LastResult
+	}
+
+	logCleanup(c, "cache", result)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cache cleanup completed successfully",
+		"data":    result,
+	})
+}
+
+// logCleanup records a manually run cleanup as an admin action
+func logCleanup(c *gin.Context, target string, result interface{}) {
+	adminID, _ := c.Get("user_id")
+	services.NewAuditLogger().LogAdminAction(adminID.(uint), "cleanup", target, nil, gin.H{
+		"result": result,
+	}, c.ClientIP(), c.Request.UserAgent(), "")
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/services"
+)
+
+// newCleanupRouter returns a router serving the manual cleanup endpoints as admin user 1
+func newCleanupRouter() *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	r.POST("/admin/cleanup/audit-logs", CleanupAuditLogsNowHandler)
+	r.POST("/admin/cleanup/sessions", CleanupSessionsNowHandler)
+	r.POST("/admin/cleanup/cache", CleanupCacheNowHandler)
+	return r
+}
+
+// postCleanup runs a cleanup endpoint and decodes its data
+func postCleanup(t *testing.T, r *gin.Engine, path string) map[string]interface{} {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for %s, got %d: %s", path, w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.Data
+}
+
+// countCleanupAuditLogs counts admin_action audit logs recorded for a cleanup target
+func countCleanupAuditLogs(target string) int64 {
+	var count int64
+	db.DB.Model(&models.SecurityAuditLog{}).Where("event_action = ? AND resource = ?", "action", target).Count(&count)
+	return count
+}
+
+func TestCleanupAuditLogsNowHandler_RemovesOldLogs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	old := &models.SecurityAuditLog{EventType: "auth", EventAction: "login", Status: "success", CreatedAt: time.Now().AddDate(0, 0, -40)}
+	recent := &models.SecurityAuditLog{EventType: "auth", EventAction: "login", Status: "success", CreatedAt: time.Now()}
+	db.DB.Create(old)
+	db.DB.Create(recent)
+
+	data := postCleanup(t, newCleanupRouter(), "/admin/cleanup/audit-logs?days=30")
+	if data["removed"] != float64(1) {
+		t.Errorf("Expected 1 audit log removed, got %v", data["removed"])
+	}
+
+	var remaining int64
+	db.DB.Model(&models.SecurityAuditLog{}).Where("event_action = ?", "login").Count(&remaining)
+	if remaining != 1 {
+		t.Errorf("Expected the recent audit log to remain, got %d", remaining)
+	}
+	if countCleanupAuditLogs("audit_logs") != 1 {
+		t.Error("Expected the cleanup to be audited")
+	}
+}
+
+func TestCleanupAuditLogsNowHandler_KeepsRetentionMinimum(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	recent := &models.SecurityAuditLog{EventType: "auth", EventAction: "login", Status: "success", CreatedAt: time.Now().AddDate(0, 0, -2)}
+	db.DB.Create(recent)
+
+	data := postCleanup(t, newCleanupRouter(), "/admin/cleanup/audit-logs?days=1")
+	if data["days"] != float64(models.MinAuditLogRetentionDays) || data["removed"] != float64(0) {
+		t.Errorf("Expected days to be raised to the retention minimum, got %v", data)
+	}
+	if err := db.DB.First(&models.SecurityAuditLog{}, recent.ID).Error; err != nil {
+		t.Errorf("Expected the recent audit log to remain: %v", err)
+	}
+}
+
+func TestCleanupSessionsNowHandler_RemovesExpiredSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	sm := setupTestSessionManager(t)
+
+	user := &models.User{ID: 2, Username: "user", Role: "user"}
+	expiredToken, _, _ := auth.GenerateJWT(user, []byte("my_secret_key"))
+	activeToken, _, _ := auth.GenerateJWT(user, []byte("my_secret_key"))
+	expired, err := sm.CreateSession(user, expiredToken, "127.0.0.1", "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	if _, err := sm.CreateSession(user, activeToken, "127.0.0.1", "test-agent"); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	data := postCleanup(t, newCleanupRouter(), "/admin/cleanup/sessions")
+	if data["removed"] != float64(1) {
+		t.Errorf("Expected 1 session removed, got %v", data["removed"])
+	}
+	if _, err := sm.GetSession(expired.ID); err == nil {
+		t.Error("Expected the expired session to be gone")
+	}
+	if countCleanupAuditLogs("sessions") != 1 {
+		t.Error("Expected the cleanup to be audited")
+	}
+}
+
+func TestCleanupCacheNowHandler_RunsCleanupJobs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	origScheduler := services.GlobalScheduler
+	t.Cleanup(func() { services.GlobalScheduler = origScheduler })
+	services.GlobalScheduler = services.NewScheduler()
+
+	ph := NewPerformanceHandlers()
+	if err := ph.RegisterCleanupJobs(services.GlobalScheduler); err != nil {
+		t.Fatalf("Failed to register cleanup jobs: %v", err)
+	}
+	ph.cacheService.Set("stale", "value", time.Nanosecond)
+	ph.cacheService.Set("fresh", "value", time.Hour)
+	time.Sleep(time.Millisecond)
+
+	data := postCleanup(t, newCleanupRouter(), "/admin/cleanup/cache")
+	cacheResult, _ := data["cache_cleanup"].(map[string]interface{})
+	if cacheResult["removed_items"] != float64(1) {
+		t.Errorf("Expected 1 cache item removed, got %v", data)
+	}
+	if _, found := ph.cacheService.Get("fresh"); !found {
+		t.Error("Expected the fresh cache item to remain")
+	}
+	if countCleanupAuditLogs("cache") != 1 {
+		t.Error("Expected the cleanup to be audited")
+	}
+}
@@ -2,13 +2,16 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"golangmcp/internal/authorization"
 	"golangmcp/internal/models"
 	"golangmcp/internal/db"
+	"golangmcp/internal/services"
 )
 
 // CommandHandlers provides handlers for command execution
@@ -43,18 +46,29 @@ func (ch *CommandHandlers) ExecuteCommandHandler(c *gin.Context) {
 		return
 	}
 
+	role, _ := c.Get("role")
+	roleName, _ := role.(string)
+
 	// Set default working directory
 	if request.WorkingDir == "" {
 		request.WorkingDir = "/tmp"
 	}
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Create context with timeout; the command is also killed when the request is cancelled
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
 	defer cancel()
 
 	// Execute command
-	cmdRecord, err := ch.executor.ExecuteCommand(ctx, request.Command, request.Args, userID.(uint), request.WorkingDir)
+	cmdRecord, err := ch.executor.ExecuteCommand(ctx, request.Command, request.Args, userID.(uint), roleName, request.WorkingDir)
 	if err != nil {
+		if errors.Is(err, models.ErrCommandRoleDenied) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":     err.Error(),
+				"command":   request.Command,
+				"user_role": roleName,
+			})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -65,7 +79,9 @@ func (ch *CommandHandlers) ExecuteCommandHandler(c *gin.Context) {
 	})
 }
 
-// GetCommandHistoryHandler retrieves command history
+// GetCommandHistoryHandler retrieves command history, filtered by user, command,
+// exit code, date range and text in the arguments or output. Only callers with
+// admin.security see other users' commands; everyone else gets their own.
 func (ch *CommandHandlers) GetCommandHistoryHandler(c *gin.Context) {
 	limitStr := c.DefaultQuery("limit", "50")
 	offsetStr := c.DefaultQuery("offset", "0")
@@ -81,15 +97,41 @@ func (ch *CommandHandlers) GetCommandHistoryHandler(c *gin.Context) {
 		offset = 0
 	}
 
-	var userID *uint
+	filter := models.CommandHistoryFilter{
+		Command: c.Query("command"),
+		Search:  c.Query("q"),
+	}
 	if userIDStr != "" {
-		if id, err := strconv.ParseUint(userIDStr, 10, 32); err == nil {
-			uid := uint(id)
-			userID = &uid
+		id, err := strconv.ParseUint(userIDStr, 10, 32)
+		if err != nil || id == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user_id, must be a positive integer"})
+			return
 		}
+		uid := uint(id)
+		filter.UserID = &uid
+	}
+	if !authorization.ContextHasPermission(c, "admin.security") {
+		callerID := c.GetUint("user_id")
+		filter.UserID = &callerID
+	}
+	if exitCodeStr := c.Query("exit_code"); exitCodeStr != "" {
+		exitCode, err := strconv.Atoi(exitCodeStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exit_code, must be an integer"})
+			return
+		}
+		filter.ExitCode = &exitCode
+	}
+	if filter.StartDate, err = parseLogDate(c.Query("start_date"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid start_date, use RFC3339 or YYYY-MM-DD"})
+		return
+	}
+	if filter.EndDate, err = parseLogDate(c.Query("end_date"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid end_date, use RFC3339 or YYYY-MM-DD"})
+		return
 	}
 
-	commands, err := ch.executor.GetCommandHistory(userID, limit, offset)
+	commands, total, err := ch.executor.GetCommandHistory(filter, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch command history"})
 		return
@@ -101,6 +143,7 @@ func (ch *CommandHandlers) GetCommandHistoryHandler(c *gin.Context) {
 			"limit":  limit,
 			"offset": offset,
 			"count":  len(commands),
+			"total":  total,
 		},
 	})
 }
@@ -118,6 +161,39 @@ func (ch *CommandHandlers) GetCommandStatsHandler(c *gin.Context) {
 	})
 }
 
+// GetCommandConfigHandler returns command execution configuration
+func (ch *CommandHandlers) GetCommandConfigHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"max_output_bytes": ch.executor.GetMaxOutputBytes(),
+		},
+	})
+}
+
+// UpdateCommandConfigHandler updates command execution configuration
+func (ch *CommandHandlers) UpdateCommandConfigHandler(c *gin.Context) {
+	var request struct {
+		MaxOutputBytes int64 `json:"max_output_bytes" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := ch.executor.SetMaxOutputBytes(request.MaxOutputBytes); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Command configuration updated successfully",
+		"data": gin.H{
+			"max_output_bytes": request.MaxOutputBytes,
+		},
+	})
+}
+
 // GetCommandWhitelistHandler retrieves the command whitelist
 func (ch *CommandHandlers) GetCommandWhitelistHandler(c *gin.Context) {
 	var whitelist []models.CommandWhitelist
@@ -135,10 +211,11 @@ func (ch *CommandHandlers) GetCommandWhitelistHandler(c *gin.Context) {
 // AddToWhitelistHandler adds a command to the whitelist
 func (ch *CommandHandlers) AddToWhitelistHandler(c *gin.Context) {
 	var request struct {
-		Command     string   `json:"command" binding:"required"`
-		Description string   `json:"description"`
-		AllowedArgs []string `json:"allowed_args"`
-		MaxDuration int      `json:"max_duration"`
+		Command      string   `json:"command" binding:"required"`
+		Description  string   `json:"description"`
+		AllowedArgs  []string `json:"allowed_args"`
+		MaxDuration  int      `json:"max_duration"`
+		AllowedRoles []string `json:"allowed_roles"` // empty = all roles
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -150,7 +227,7 @@ func (ch *CommandHandlers) AddToWhitelistHandler(c *gin.Context) {
 		request.MaxDuration = 30000 // 30 seconds default
 	}
 
-	err := ch.executor.AddToWhitelist(request.Command, request.Description, request.AllowedArgs, request.MaxDuration)
+	err := ch.executor.AddToWhitelist(request.Command, request.Description, request.AllowedArgs, request.MaxDuration, request.AllowedRoles)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add command to whitelist"})
 		return
@@ -193,6 +270,38 @@ func (ch *CommandHandlers) InitializeWhitelistHandler(c *gin.Context) {
 	})
 }
 
+// ImportWhitelistHandler creates or merges a custom command set into the whitelist (Admin only)
+func (ch *CommandHandlers) ImportWhitelistHandler(c *gin.Context) {
+	var request struct {
+		Commands []models.WhitelistEntry `json:"commands" binding:"required"`
+		Reset    bool                    `json:"reset"` // deactivate existing entries first
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := ch.executor.ImportWhitelist(request.Commands, request.Reset)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID, _ := c.Get("user_id")
+	services.NewAuditLogger().LogAdminAction(adminID.(uint), "whitelist_import", "command_whitelist", nil, gin.H{
+		"reset":       request.Reset,
+		"created":     result.Created,
+		"updated":     result.Updated,
+		"deactivated": result.Deactivated,
+	}, c.ClientIP(), c.Request.UserAgent(), "")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Command whitelist imported successfully",
+		"data":    result,
+	})
+}
+
 // GetCommandHandler retrieves a specific command by ID
 func (ch *CommandHandlers) GetCommandHandler(c *gin.Context) {
 	idStr := c.Param("id")
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+)
+
+func TestGetCommandHistoryHandler_LimitedToCaller(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	alice := &models.User{Username: "alice", Email: "alice@example.com", Password: "password123", Role: "user"}
+	bob := &models.User{Username: "bob", Email: "bob@example.com", Password: "password123", Role: "user"}
+	for _, user := range []*models.User{alice, bob} {
+		if err := user.Create(db.DB); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	db.DB.Create(&models.Command{Command: "ls", Args: "/home/alice", UserID: alice.ID})
+	db.DB.Create(&models.Command{Command: "cat", Args: "/home/bob/secret", Output: "token", UserID: bob.ID})
+
+	history := func(userID uint, role, query string) []models.Command {
+		r := gin.New()
+		r.GET("/api/commands", func(c *gin.Context) {
+			c.Set("user_id", userID)
+			c.Set("role", role)
+			c.Next()
+		}, NewCommandHandlers().GetCommandHistoryHandler)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/commands?"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+		}
+		var response struct {
+			Data []models.Command `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Data
+	}
+
+	for _, query := range []string{"", "user_id=2", "q=secret", "q=home"} {
+		for _, command := range history(alice.ID, "user", query) {
+			if command.UserID != alice.ID {
+				t.Errorf("Expected %q to return only the caller's commands, got %+v", query, command)
+			}
+		}
+	}
+	if commands := history(alice.ID, "user", "q=home"); len(commands) != 1 {
+		t.Errorf("Expected the caller's own matching command, got %d", len(commands))
+	}
+
+	// Admins can look at anyone's history
+	if commands := history(99, "admin", "q=secret"); len(commands) != 1 || commands[0].UserID != bob.ID {
+		t.Errorf("Expected an admin to search other users' commands, got %+v", commands)
+	}
+}
+
+func TestGetCommandHistoryHandler_InvalidFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	r := gin.New()
+	r.GET("/api/commands", func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Set("role", "admin")
+		c.Next()
+	}, NewCommandHandlers().GetCommandHistoryHandler)
+
+	for _, query := range []string{"user_id=abc", "user_id=0", "user_id=-1", "exit_code=x", "start_date=yesterday", "end_date=2024-13-01"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/commands?"+query, nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", query, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/commands?user_id=1", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for a valid user_id, got %d: %s", w.Code, w.Body.String())
+	}
+}
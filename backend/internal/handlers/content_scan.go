@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+const (
+	// MaxContentScanBytes caps how much of a file is scanned for signatures and
+	// suspicious patterns; hashes still cover the whole file
+	MaxContentScanBytes = 16 * 1024 * 1024 // 16MB
+
+	// contentScanChunkSize is the amount of new content scanned at a time
+	contentScanChunkSize = 64 * 1024 // 64KB
+)
+
+// executableSignatures are byte sequences identifying executable content
+var executableSignatures = [][]byte{
+	{0x4D, 0x5A},             // PE executable
+	{0x7F, 0x45, 0x4C, 0x46}, // ELF executable
+	{0xFE, 0xED, 0xFA, 0xCE}, // Mach-O executable
+	{0xCA, 0xFE, 0xBA, 0xBE}, // Java class file
+}
+
+// suspiciousPatterns are lowercase text patterns that may indicate script injection
+var suspiciousPatterns = [][]byte{
+	[]byte("<script"),
+	[]byte("javascript:"),
+	[]byte("vbscript:"),
+	[]byte("onload="),
+	[]byte("onerror="),
+	[]byte("eval("),
+	[]byte("exec("),
+	[]byte("system("),
+}
+
+// contentScanOverlap is the tail of the previous chunk kept in front of the next
+// one, so a match spanning a chunk boundary is still found. It covers the
+// longest pattern plus a split multi-byte rune, which lowercasing needs whole.
+var contentScanOverlap = longestPattern() - 1 + utf8.UTFMax
+
+// contentScanner scans content written to it in bounded chunks for executable
+// signatures and suspicious patterns, without holding the whole file in memory
+type contentScanner struct {
+	window     []byte
+	lower      []byte // lowercased copy of window, reused between chunks
+	scanned    int64
+	limit      int64
+	executable bool
+	suspicious bool
+}
+
+// newContentScanner creates a scanner that inspects at most limit bytes
+func newContentScanner(limit int64) *contentScanner {
+	return &contentScanner{
+		window: make([]byte, 0, contentScanOverlap+contentScanChunkSize),
+		lower:  make([]byte, 0, contentScanOverlap+contentScanChunkSize),
+		limit:  limit,
+	}
+}
+
+// Write implements io.Writer. Content past the limit is accepted but not scanned.
+func (cs *contentScanner) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 && !cs.Truncated() && !(cs.executable && cs.suspicious) {
+		size := contentScanChunkSize
+		if remaining := cs.limit - cs.scanned; int64(size) > remaining {
+			size = int(remaining)
+		}
+		if size > len(p) {
+			size = len(p)
+		}
+
+		cs.window = append(cs.window, p[:size]...)
+		cs.scanWindow()
+		cs.scanned += int64(size)
+		p = p[size:]
+
+		// Keep only the overlap for the next chunk
+		if len(cs.window) > contentScanOverlap {
+			cs.window = append(cs.window[:0], cs.window[len(cs.window)-contentScanOverlap:]...)
+		}
+	}
+	return n, nil
+}
+
+// scanWindow checks the current window for signatures and patterns
+func (cs *contentScanner) scanWindow() {
+	if !cs.executable {
+		for _, sig := range executableSignatures {
+			if bytes.Contains(cs.window, sig) {
+				cs.executable = true
+				break
+			}
+		}
+	}
+
+	if !cs.suspicious {
+		lower := cs.toLower()
+		for _, pattern := range suspiciousPatterns {
+			if bytes.Contains(lower, pattern) {
+				cs.suspicious = true
+				break
+			}
+		}
+	}
+}
+
+// toLower lowercases the window into a reused buffer. Non-ASCII text falls back
+// to bytes.ToLower, since some runes (e.g. U+0130) lowercase to ASCII letters.
+func (cs *contentScanner) toLower() []byte {
+	cs.lower = cs.lower[:0]
+	for _, b := range cs.window {
+		if b >= utf8.RuneSelf {
+			return bytes.ToLower(cs.window)
+		}
+		if 'A' <= b && b <= 'Z' {
+			b += 'a' - 'A'
+		}
+		cs.lower = append(cs.lower, b)
+	}
+	return cs.lower
+}
+
+// Truncated reports whether the scan stopped at the byte limit
+func (cs *contentScanner) Truncated() bool {
+	return cs.scanned >= cs.limit
+}
+
+// longestPattern returns the length of the longest signature or pattern
+func longestPattern() int {
+	longest := 0
+	for _, pattern := range append(append([][]byte{}, executableSignatures...), suspiciousPatterns...) {
+		if len(pattern) > longest {
+			longest = len(pattern)
+		}
+	}
+	return longest
+}
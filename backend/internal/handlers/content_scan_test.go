@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestContentScanner_FindsMatchesAcrossChunkBoundaries(t *testing.T) {
+	for _, offset := range []int{1, 2, 5, 10} {
+		content := bytes.Repeat([]byte("a"), contentScanChunkSize*2)
+		copy(content[contentScanChunkSize-offset:], "JavaScript:")
+
+		scanner := newContentScanner(MaxContentScanBytes)
+		// Small writes, so boundaries don't line up with chunks either
+		if _, err := io.CopyBuffer(scanner, bytes.NewReader(content), make([]byte, 1000)); err != nil {
+			t.Fatalf("Failed to scan: %v", err)
+		}
+		if !scanner.suspicious {
+			t.Errorf("Expected pattern split %d bytes before the boundary to be found", offset)
+		}
+		if scanner.executable {
+			t.Error("Expected no executable signature")
+		}
+	}
+
+	content := bytes.Repeat([]byte("a"), contentScanChunkSize*2)
+	copy(content[contentScanChunkSize-2:], []byte{0x7F, 0x45, 0x4C, 0x46})
+	if !containsExecutableContent(content) {
+		t.Error("Expected ELF signature spanning the boundary to be found")
+	}
+}
+
+func TestContentScanner_StopsAtLimit(t *testing.T) {
+	content := append(bytes.Repeat([]byte("a"), 1024), "<script>"...)
+
+	scanner := newContentScanner(1024)
+	scanner.Write(content)
+	if scanner.suspicious {
+		t.Error("Expected content past the limit to be ignored")
+	}
+	if !scanner.Truncated() {
+		t.Error("Expected scan to be reported as truncated")
+	}
+
+	if !containsSuspiciousPatterns(content) {
+		t.Error("Expected pattern within the scanned content to be found")
+	}
+}
+
+// wholeContentSuspicious is the previous implementation, which lowercased and
+// searched the whole content at once
+func wholeContentSuspicious(content string) bool {
+	for _, pattern := range suspiciousPatterns {
+		if strings.Contains(strings.ToLower(content), string(pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestContainsSuspiciousPatterns_MatchesPreviousBehaviour(t *testing.T) {
+	tests := []string{
+		"plain text",
+		"<SCRIPT>alert(1)</SCRIPT>",
+		"img onError=x",
+		"call system (",
+		"ÄÖÜ EVAL(",
+		"javascr\u0130pt:",
+		"VBScript:msgbox",
+	}
+	for _, content := range tests {
+		if got, want := containsSuspiciousPatterns([]byte(content)), wholeContentSuspicious(content); got != want {
+			t.Errorf("%q: expected %v, got %v", content, want, got)
+		}
+	}
+}
+
+func BenchmarkContentScanner_LargeFile(b *testing.B) {
+	content := bytes.Repeat([]byte("Lorem ipsum dolor sit amet, consectetur adipiscing elit.\n"), MaxDocumentSize/58)
+	b.SetBytes(int64(len(content)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		scanner := newContentScanner(MaxContentScanBytes)
+		io.Copy(scanner, bytes.NewReader(content))
+	}
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/security"
+	"golangmcp/internal/services"
+	"golangmcp/internal/session"
+	"gorm.io/gorm"
+)
+
+// Formats of a data export, chosen with the format query parameter
+const (
+	DataExportJSON = "json"
+	DataExportZip  = "zip"
+)
+
+// dataExportBatchSize is the number of rows loaded at a time while exporting
+const dataExportBatchSize = 500
+
+// DataExportSections are the sections of a data export, in the order they are
+// written: the keys of the JSON document, or the entries of the zip archive
+var DataExportSections = []string{"profile", "files", "sessions", "commands", "audit_logs"}
+
+// ExportSession represents a session in a data export; its token is left out
+type ExportSession struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent"`
+}
+
+// ExportCommand represents a command execution in a data export
+type ExportCommand struct {
+	ID              uint      `json:"id"`
+	Command         string    `json:"command"`
+	Args            string    `json:"args"`
+	Output          string    `json:"output"`
+	OutputTruncated bool      `json:"output_truncated"`
+	ExitCode        int       `json:"exit_code"`
+	WorkingDir      string    `json:"working_dir"`
+	Duration        int64     `json:"duration"` // in milliseconds
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// ExportMyDataHandler exports the current user's data
+func ExportMyDataHandler(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	exportUserData(c, userID.(uint))
+}
+
+// ExportUserDataHandler exports a user's data on their behalf (admin only). It
+// includes the user's audit logs, so it is gated by admin.security.
+func ExportUserDataHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	exportUserData(c, uint(userID))
+}
+
+// exportUserData streams a user's profile, file metadata, sessions, commands and
+// audit logs as a JSON document or a zip archive of one JSON file per section.
+// Rows are loaded and written in batches, so memory use does not grow with the
+// amount of data.
+func exportUserData(c *gin.Context, userID uint) {
+	format := c.DefaultQuery("format", DataExportJSON)
+	if format != DataExportJSON && format != DataExportZip {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or zip"})
+		return
+	}
+
+	var user models.User
+	if err := user.GetByID(db.DB, userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	actorID, _ := c.Get("user_id")
+	actorIDUint, _ := actorID.(uint)
+	services.NewAuditLogger().LogDataExport(actorIDUint, user.ID, format, c.ClientIP(), c.Request.UserAgent(), security.RequestID(c))
+
+	sections := dataExportSections(&user)
+	filename := fmt.Sprintf("user-%d-export.%s", user.ID, format)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "no-store")
+
+	var err error
+	if format == DataExportZip {
+		c.Header("Content-Type", "application/zip")
+		c.Status(http.StatusOK)
+		err = writeDataExportZip(c.Writer, sections)
+	} else {
+		c.Header("Content-Type", "application/json")
+		c.Status(http.StatusOK)
+		err = writeDataExportJSON(c.Writer, user.ID, sections)
+	}
+	if err != nil {
+		log.Printf("Warning: Failed to export data of user %d: %v", user.ID, err)
+	}
+}
+
+// dataExportSections returns the writer of each section of a user's export,
+// keyed like DataExportSections
+func dataExportSections(user *models.User) map[string]func(io.Writer) error {
+	return map[string]func(io.Writer) error{
+		"profile": func(w io.Writer) error {
+			return json.NewEncoder(w).Encode(NewUserResponse(*user))
+		},
+		"files": func(w io.Writer) error {
+			query := db.DB.Model(&models.File{}).Where("user_id = ?", user.ID)
+			return writeJSONArrayInBatches(w, query, func(file models.File) interface{} {
+				file.User = *user
+				return NewFileResponse(file)
+			})
+		},
+		"sessions": func(w io.Writer) error {
+			sessions := make([]ExportSession, 0)
+			for _, s := range session.GlobalSessionManager.GetUserSessions(user.ID) {
+				sessions = append(sessions, ExportSession{
+					ID:        s.ID,
+					CreatedAt: s.CreatedAt,
+					ExpiresAt: s.ExpiresAt,
+					LastSeen:  s.LastSeen,
+					IPAddress: s.IPAddress,
+					UserAgent: s.UserAgent,
+				})
+			}
+			return json.NewEncoder(w).Encode(sessions)
+		},
+		"commands": func(w io.Writer) error {
+			query := db.DB.Model(&models.Command{}).Where("user_id = ?", user.ID)
+			return writeJSONArrayInBatches(w, query, func(command models.Command) interface{} {
+				return ExportCommand{
+					ID:              command.ID,
+					Command:         command.Command,
+					Args:            command.Args,
+					Output:          command.Output,
+					OutputTruncated: command.OutputTruncated,
+					ExitCode:        command.ExitCode,
+					WorkingDir:      command.WorkingDir,
+					Duration:        command.Duration,
+					CreatedAt:       command.CreatedAt,
+				}
+			})
+		},
+		"audit_logs": func(w io.Writer) error {
+			query := db.DB.Model(&models.SecurityAuditLog{}).Where("user_id = ?", user.ID)
+			return writeJSONArrayInBatches(w, query, func(entry models.SecurityAuditLog) interface{} {
+				return entry
+			})
+		},
+	}
+}
+
+// writeDataExportJSON writes the sections as the keys of a single JSON object.
+// Flushing after each section streams the response, which middleware such as
+// StringIDMiddleware would otherwise buffer whole.
+func writeDataExportJSON(w gin.ResponseWriter, userID uint, sections map[string]func(io.Writer) error) error {
+	header, _ := json.Marshal(map[string]interface{}{
+		"user_id":     userID,
+		"exported_at": time.Now().UTC(),
+	})
+	// Leave the header object open, so the sections are added as its keys
+	if _, err := w.Write(header[:len(header)-1]); err != nil {
+		return err
+	}
+	for _, name := range DataExportSections {
+		if _, err := fmt.Fprintf(w, ",%q:", name); err != nil {
+			return err
+		}
+		if err := sections[name](w); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		w.Flush()
+	}
+	_, err := io.WriteString(w, "}\n")
+	return err
+}
+
+// writeDataExportZip writes each section as a JSON file of a zip archive
+func writeDataExportZip(w gin.ResponseWriter, sections map[string]func(io.Writer) error) error {
+	archive := zip.NewWriter(w)
+	for _, name := range DataExportSections {
+		entry, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     name + ".json",
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		if err := sections[name](entry); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if err := archive.Flush(); err != nil {
+			return err
+		}
+		w.Flush()
+	}
+	return archive.Close()
+}
+
+// writeJSONArrayInBatches writes the rows of a query as a JSON array, loading
+// dataExportBatchSize rows at a time and converting each with convert
+func writeJSONArrayInBatches[T any](w io.Writer, query *gorm.DB, convert func(T) interface{}) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	var batch []T
+	first := true
+	result := query.FindInBatches(&batch, dataExportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, row := range batch {
+			data, err := json.Marshal(convert(row))
+			if err != nil {
+				return err
+			}
+			if !first {
+				data = append([]byte(","), data...)
+			}
+			first = false
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	_, err := io.WriteString(w, "]")
+	return err
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+)
+
+func TestExportMyDataHandler_Sections(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	sm := setupTestSessionManager(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	other := &models.User{Username: "other", Email: "other@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := other.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	createTestFile(t, owner.ID, "mine.txt", false)
+	createTestFile(t, other.ID, "theirs.txt", false)
+	for _, command := range []*models.Command{
+		{Command: "ls", Args: "[]", Output: "mine", UserID: owner.ID},
+		{Command: "ls", Args: "[]", Output: "theirs", UserID: other.ID},
+	} {
+		if err := db.DB.Create(command).Error; err != nil {
+			t.Fatalf("Failed to create command: %v", err)
+		}
+	}
+	token, _, err := auth.GenerateJWT(owner, auth.JWTSecret())
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	if _, err := sm.CreateSession(owner, token, "192.0.2.1", "test-agent"); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	r := gin.New()
+	r.GET("/profile/export", func(c *gin.Context) {
+		c.Set("user_id", owner.ID)
+		c.Next()
+	}, ExportMyDataHandler)
+
+	export := func(format string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/profile/export?format="+format, nil))
+		return w
+	}
+
+	w := export("json")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if disposition := w.Header().Get("Content-Disposition"); !strings.HasPrefix(disposition, "attachment") {
+		t.Errorf("Expected the export to be a download, got %q", disposition)
+	}
+
+	var document struct {
+		UserID    uint                      `json:"user_id"`
+		Profile   UserResponse              `json:"profile"`
+		Files     []FileResponse            `json:"files"`
+		Sessions  []json.RawMessage         `json:"sessions"`
+		Commands  []ExportCommand           `json:"commands"`
+		AuditLogs []models.SecurityAuditLog `json:"audit_logs"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &document); err != nil {
+		t.Fatalf("Expected a JSON document: %v\n%s", err, w.Body.String())
+	}
+	if document.UserID != owner.ID || document.Profile.Username != "owner" {
+		t.Errorf("Expected the owner's profile, got %+v", document.Profile)
+	}
+	if len(document.Files) != 1 || document.Files[0].OriginalName != "mine.txt" {
+		t.Errorf("Expected only the owner's file, got %+v", document.Files)
+	}
+	if len(document.Commands) != 1 || document.Commands[0].Output != "mine" {
+		t.Errorf("Expected only the owner's command, got %+v", document.Commands)
+	}
+	if len(document.Sessions) != 1 || strings.Contains(string(document.Sessions[0]), token) {
+		t.Errorf("Expected the owner's session without its token, got %s", document.Sessions)
+	}
+	if len(document.AuditLogs) != 1 || document.AuditLogs[0].EventAction != "export" {
+		t.Errorf("Expected the export itself to be audited, got %+v", document.AuditLogs)
+	}
+
+	// The zip holds one JSON file per section
+	w = export("zip")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("Expected a zip archive: %v", err)
+	}
+	var names []string
+	for _, entry := range archive.File {
+		names = append(names, entry.Name)
+	}
+	if strings.Join(names, ",") != "profile.json,files.json,sessions.json,commands.json,audit_logs.json" {
+		t.Errorf("Unexpected archive entries: %v", names)
+	}
+
+	if w := export("csv"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected an unknown format to be rejected, got %d", w.Code)
+	}
+}
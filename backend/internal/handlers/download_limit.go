@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/services"
+)
+
+// DownloadLimitMiddleware enforces the download rate of GlobalDownloadPolicy,
+// counted per user or per IP for anonymous requests, and throttles the response
+// to its bandwidth limit. Mount it on download routes after AuthMiddleware.
+func DownloadLimitMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if userID, exists := c.Get("user_id"); exists {
+			key = fmt.Sprintf("user:%v", userID)
+		}
+
+		allowed, resetAt := services.GlobalDownloadPolicy.AllowDownload(key)
+		if !allowed {
+			retryAfter := int(math.Ceil(time.Until(resetAt).Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Download rate limit exceeded",
+				"retry_after": retryAfter,
+			})
+			return
+		}
+
+		if bytesPerSecond := services.GlobalDownloadPolicy.GetPolicy().BandwidthBytesPerSecond; bytesPerSecond > 0 {
+			c.Writer = newThrottledWriter(c.Request.Context(), c.Writer, bytesPerSecond)
+		}
+		c.Next()
+	}
+}
+
+// throttledWriter paces the response body to a number of bytes per second
+type throttledWriter struct {
+	gin.ResponseWriter
+	ctx            context.Context
+	bytesPerSecond int64
+	chunkSize      int
+	start          time.Time
+	written        int64
+}
+
+// newThrottledWriter wraps w, writing in chunks of a tenth of a second's worth
+func newThrottledWriter(ctx context.Context, w gin.ResponseWriter, bytesPerSecond int64) *throttledWriter {
+	chunkSize := int(bytesPerSecond / 10)
+	if chunkSize < 1 {
+		chunkSize = 1
+	}
+	if chunkSize > 32*1024 {
+		chunkSize = 32 * 1024
+	}
+	return &throttledWriter{
+		ResponseWriter: w,
+		ctx:            ctx,
+		bytesPerSecond: bytesPerSecond,
+		chunkSize:      chunkSize,
+		start:          time.Now(),
+	}
+}
+
+// Write writes p chunk by chunk, waiting after each until the bytes written so
+// far fit the rate. It stops early when the client goes away.
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	total := 0
+	for len(p) > 0 {
+		chunk := p
+		if len(chunk) > w.chunkSize {
+			chunk = p[:w.chunkSize]
+		}
+
+		n, err := w.ResponseWriter.Write(chunk)
+		total += n
+		w.written += int64(n)
+		if err != nil {
+			return total, err
+		}
+		p = p[n:]
+
+		due := time.Duration(float64(w.written) / float64(w.bytesPerSecond) * float64(time.Second))
+		if wait := due - time.Since(w.start); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-w.ctx.Done():
+				timer.Stop()
+				return total, w.ctx.Err()
+			}
+		}
+	}
+	return total, nil
+}
+
+// WriteString writes s through the throttle
+func (w *throttledWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/security"
+	"golangmcp/internal/services"
+)
+
+// setupDownloadPolicy replaces the global download policy for the duration of a test
+func setupDownloadPolicy(t *testing.T, perMinute int, bytesPerSecond int64) {
+	orig := services.GlobalDownloadPolicy
+	t.Cleanup(func() { services.GlobalDownloadPolicy = orig })
+	services.GlobalDownloadPolicy = services.NewDownloadPolicyManager()
+
+	policy := services.GlobalDownloadPolicy.GetPolicy()
+	policy.DownloadsPerMinute = perMinute
+	policy.BandwidthBytesPerSecond = bytesPerSecond
+	if err := services.GlobalDownloadPolicy.UpdatePolicy(&policy); err != nil {
+		t.Fatalf("Failed to update download policy: %v", err)
+	}
+}
+
+// newDownloadRouter serves content through DownloadLimitMiddleware, as userID when not 0
+func newDownloadRouter(userID uint, content []byte) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/download", func(c *gin.Context) {
+		if userID != 0 {
+			c.Set("user_id", userID)
+		}
+		c.Next()
+	}, DownloadLimitMiddleware(), func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/octet-stream", content)
+	})
+	return r
+}
+
+func download(r *gin.Engine, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/download", nil)
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestDownloadLimitMiddleware_RateLimitsPerUser(t *testing.T) {
+	setupDownloadPolicy(t, 2, 0)
+	alice := newDownloadRouter(1, []byte("data"))
+	bob := newDownloadRouter(2, []byte("data"))
+
+	for i := 0; i < 2; i++ {
+		if w := download(alice, "10.0.0.1:1234"); w.Code != http.StatusOK {
+			t.Fatalf("Expected download %d within the limit to succeed, got %d", i+1, w.Code)
+		}
+	}
+
+	// Changing IP does not reset a user's count
+	w := download(alice, "10.0.0.2:1234")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 past the limit, got %d", w.Code)
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter == "" || retryAfter == "0" {
+		t.Errorf("Expected a Retry-After header, got %q", retryAfter)
+	}
+
+	// Other users have their own count
+	if w := download(bob, "10.0.0.1:1234"); w.Code != http.StatusOK {
+		t.Errorf("Expected another user to be unaffected, got %d", w.Code)
+	}
+}
+
+func TestDownloadLimitMiddleware_RateLimitsAnonymousPerIP(t *testing.T) {
+	setupDownloadPolicy(t, 1, 0)
+	r := newDownloadRouter(0, []byte("data"))
+
+	if w := download(r, "10.0.0.1:1234"); w.Code != http.StatusOK {
+		t.Fatalf("Expected the first download to succeed, got %d", w.Code)
+	}
+	if w := download(r, "10.0.0.1:5678"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 for the same IP, got %d", w.Code)
+	}
+	if w := download(r, "10.0.0.2:1234"); w.Code != http.StatusOK {
+		t.Errorf("Expected another IP to be unaffected, got %d", w.Code)
+	}
+}
+
+func TestDownloadLimitMiddleware_ThrottlesBandwidth(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 50*1024)
+
+	// Unthrottled downloads are not slowed down
+	setupDownloadPolicy(t, 0, 0)
+	start := time.Now()
+	if w := download(newDownloadRouter(1, content), "10.0.0.1:1234"); w.Code != http.StatusOK || w.Body.Len() != len(content) {
+		t.Fatalf("Expected the full content, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+	if elapsed := time.Since(start); elapsed > 200*time.Millisecond {
+		t.Errorf("Expected an unthrottled download to be fast, took %v", elapsed)
+	}
+
+	// 50KB at 100KB/s takes about half a second
+	setupDownloadPolicy(t, 0, 100*1024)
+	start = time.Now()
+	w := download(newDownloadRouter(1, content), "10.0.0.1:1234")
+	elapsed := time.Since(start)
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), content) {
+		t.Fatalf("Expected the full content when throttled, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+	if elapsed < 450*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("Expected throttling to take about 500ms, took %v", elapsed)
+	}
+}
+
+func TestDownloadFileHandler_ThrottledPastRequestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	chdirTemp(t)
+	setupTestDB(t)
+
+	origConfig := security.GlobalSecurityConfig
+	t.Cleanup(func() { security.GlobalSecurityConfig = origConfig })
+	security.GlobalSecurityConfig = security.NewSecurityConfigManager(security.DefaultSecurityConfig)
+	if _, err := security.GlobalSecurityConfig.UpdateConfig(func(config *security.SecurityConfig) {
+		config.RequestTimeout = 100 * time.Millisecond
+	}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	file := createTestFile(t, owner.ID, "large.bin", false)
+	content := bytes.Repeat([]byte("x"), 50*1024)
+	os.MkdirAll(filepath.Dir(file.Path), 0755)
+	if err := os.WriteFile(file.Path, content, 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	// Wired as in main.go: downloads opt out of the global request timeout
+	r := gin.New()
+	r.Use(security.ConfiguredTimeoutMiddleware())
+	r.GET("/api/files/:id/download", security.RequestTimeout(0), func(c *gin.Context) {
+		c.Set("user_id", owner.ID)
+		c.Next()
+	}, DownloadLimitMiddleware(), DownloadFileHandler)
+
+	// 50KB at 100KB/s takes about half a second, well past the timeout
+	setupDownloadPolicy(t, 0, 100*1024)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/files/"+strconv.FormatUint(uint64(file.ID), 10)+"/download", nil))
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), content) {
+		t.Fatalf("Expected the full content of a throttled download, got %d with %d bytes", w.Code, w.Body.Len())
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/models"
+)
+
+// RespondWithETag writes obj as a 200 JSON response tagged with an ETag built from
+// the body and the versions of the tables it was read from, and Last-Modified from
+// their last write. Matching If-None-Match or If-Modified-Since headers get a 304.
+func RespondWithETag(c *gin.Context, obj interface{}, tables ...string) {
+	body, err := json.Marshal(obj)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode response"})
+		return
+	}
+
+	hash := sha256.New()
+	var lastModified time.Time
+	for _, table := range tables {
+		hash.Write([]byte(table + ":" + strconv.FormatUint(models.GlobalTableVersions.Version(table), 10) + ";"))
+		if modified := models.GlobalTableVersions.LastModified(table); modified.After(lastModified) {
+			lastModified = modified
+		}
+	}
+	hash.Write(body)
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if isNotModified(c, etag, lastModified) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// isNotModified checks the request's conditional headers. If-None-Match takes
+// precedence; If-Modified-Since is only used without it.
+func isNotModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := c.GetHeader("If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == etag || candidate == "*" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ifModifiedSince := c.GetHeader("If-Modified-Since"); ifModifiedSince != "" && !lastModified.IsZero() {
+		since, err := http.ParseTime(ifModifiedSince)
+		// Last-Modified has second precision, so compare at that precision
+		return err == nil && !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
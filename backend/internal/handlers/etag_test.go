@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+)
+
+// listFiles requests the file list as the given user with optional conditional headers
+func listFiles(userID uint, headers map[string]string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.GET("/api/files", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}, GetFilesHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/files", nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetFilesHandler_NotModifiedWhenUnchanged(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	createTestFile(t, owner.ID, "first.txt", false)
+
+	first := listFiles(owner.ID, nil)
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("Expected 200 with an ETag, got %d and %q", first.Code, etag)
+	}
+
+	unchanged := listFiles(owner.ID, map[string]string{"If-None-Match": etag})
+	if unchanged.Code != http.StatusNotModified {
+		t.Fatalf("Expected 304 for an unchanged list, got %d", unchanged.Code)
+	}
+	if unchanged.Body.Len() != 0 {
+		t.Errorf("Expected an empty 304 body, got %q", unchanged.Body.String())
+	}
+	if unchanged.Header().Get("ETag") != etag {
+		t.Error("Expected the 304 to carry the same ETag")
+	}
+
+	createTestFile(t, owner.ID, "second.txt", false)
+	changed := listFiles(owner.ID, map[string]string{"If-None-Match": etag})
+	if changed.Code != http.StatusOK {
+		t.Fatalf("Expected 200 after a write, got %d", changed.Code)
+	}
+	if changed.Header().Get("ETag") == etag {
+		t.Error("Expected a new ETag after a write")
+	}
+}
+
+func TestGetFilesHandler_VersionBumpChangesETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	file := createTestFile(t, owner.ID, "report.txt", false)
+
+	etag := listFiles(owner.ID, nil).Header().Get("ETag")
+
+	// A write that leaves the listed page identical still invalidates it
+	if err := db.DB.Model(&models.FileAccessLog{}).Create(&models.FileAccessLog{FileID: file.ID, UserID: owner.ID, Action: "view"}).Error; err != nil {
+		t.Fatalf("Failed to create access log: %v", err)
+	}
+	if w := listFiles(owner.ID, map[string]string{"If-None-Match": etag}); w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 after a write to an unrelated table, got %d", w.Code)
+	}
+
+	if err := db.DB.Model(&models.User{}).Where("id = ?", owner.ID).Update("avatar", "").Error; err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+	if w := listFiles(owner.ID, map[string]string{"If-None-Match": etag}); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 after a write to the users table, got %d", w.Code)
+	}
+}
+
+func TestGetFilesHandler_IfModifiedSince(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	createTestFile(t, owner.ID, "report.txt", false)
+
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if w := listFiles(owner.ID, map[string]string{"If-Modified-Since": future}); w.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 when nothing changed since, got %d", w.Code)
+	}
+
+	past := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	if w := listFiles(owner.ID, map[string]string{"If-Modified-Since": past}); w.Code != http.StatusOK {
+		t.Errorf("Expected 200 when the list changed since, got %d", w.Code)
+	}
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/services"
+)
+
+// MaxZipDownloadFiles is the maximum number of file IDs accepted by DownloadZipHandler
+const MaxZipDownloadFiles = 100
+
+// DownloadZipRequest represents a request for a zip archive of several files
+type DownloadZipRequest struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
+
+// DownloadZipHandler streams a zip archive of the requested files. Each file is
+// checked like a single download; missing, forbidden, unscanned or quarantined
+// files are skipped and logged with their outcome. The archive is written to the
+// response as it is built, one file at a time, so memory use does not grow with
+// the archive size.
+func DownloadZipHandler(c *gin.Context) {
+	var request DownloadZipRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if len(request.IDs) == 0 || len(request.IDs) > MaxZipDownloadFiles {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Between 1 and %d file IDs are required", MaxZipDownloadFiles),
+		})
+		return
+	}
+
+	// Drop duplicate IDs, keeping the requested order
+	seen := make(map[uint]bool)
+	ids := make([]uint, 0, len(request.IDs))
+	for _, id := range request.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	files, err := models.GetFilesByIDs(db.DB, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve files",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	filesByID := make(map[uint]models.File, len(files))
+	for _, file := range files {
+		filesByID[file.ID] = file
+	}
+
+	userID, _ := c.Get("user_id")
+	userIDUint := userID.(uint)
+
+	included := make([]models.File, 0, len(files))
+	for _, id := range ids {
+		file, exists := filesByID[id]
+		switch {
+		case !exists:
+			logFileAccessOutcome(c, id, userIDUint, "download", models.FileAccessNotFound, 0)
+		case file.UserID != userIDUint && !file.IsPublic, !file.IsScanned || !file.IsSafe:
+			logFileAccessOutcome(c, id, userIDUint, "download", models.FileAccessDenied, 0)
+		default:
+			if _, err := os.Stat(file.Path); err != nil {
+				logFileAccessOutcome(c, id, userIDUint, "download", models.FileAccessNotFound, 0)
+				continue
+			}
+			included = append(included, file)
+		}
+	}
+
+	if len(included) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "None of the requested files can be downloaded",
+		})
+		return
+	}
+
+	archiveName := fmt.Sprintf("files-%s.zip", time.Now().UTC().Format("20060102-150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": archiveName}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+
+	// The status is sent with the first byte, so failures from here on can only
+	// cut the archive short, leaving a truncated zip the client will reject
+	archive := zip.NewWriter(c.Writer)
+	names := make(map[string]bool, len(included))
+	for i := range included {
+		file := &included[i]
+		written, err := writeZipEntry(archive, file, uniqueZipEntryName(names, file))
+		if err != nil {
+			log.Printf("Warning: Failed to add file %d to zip download: %v", file.ID, err)
+			c.Abort()
+			return
+		}
+		logFileAccessOutcome(c, file.ID, userIDUint, "download", models.FileAccessSuccess, written)
+	}
+	if err := archive.Close(); err != nil {
+		log.Printf("Warning: Failed to finish zip download: %v", err)
+	}
+}
+
+// writeZipEntry copies a file into the archive under name and returns the bytes read from disk
+func writeZipEntry(archive *zip.Writer, file *models.File, name string) (int64, error) {
+	source, err := os.Open(file.Path)
+	if err != nil {
+		return 0, err
+	}
+	defer source.Close()
+
+	entry, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: file.UpdatedAt,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(entry, source)
+}
+
+// uniqueZipEntryName returns a flat entry name for a file, based on its original
+// name, numbering repeats like "report (2).pdf" so no entry overwrites another
+func uniqueZipEntryName(names map[string]bool, file *models.File) string {
+	name := services.NormalizeFilename(file.OriginalName)
+	if name == "" {
+		name = file.Filename
+	}
+
+	candidate := name
+	ext := filepath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for n := 2; names[strings.ToLower(candidate)]; n++ {
+		candidate = fmt.Sprintf("%s (%d)%s", base, n, ext)
+	}
+	names[strings.ToLower(candidate)] = true
+	return candidate
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+)
+
+func TestDownloadZipHandler_OwnedFiles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	other := &models.User{Username: "other", Email: "other@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := other.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	dir := t.TempDir()
+	newFile := func(userID uint, name, content string) *models.File {
+		file := createTestFile(t, userID, name, false)
+		file.Path = filepath.Join(dir, name)
+		file.OriginalName = "notes.txt"
+		if err := os.WriteFile(file.Path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if err := models.UpdateFile(db.DB, file); err != nil {
+			t.Fatalf("Failed to update file: %v", err)
+		}
+		return file
+	}
+	first := newFile(owner.ID, "first.txt", "first content")
+	second := newFile(owner.ID, "second.txt", "second content")
+	private := newFile(other.ID, "private.txt", "secret")
+
+	r := gin.New()
+	r.POST("/api/files/download-zip", func(c *gin.Context) {
+		c.Set("user_id", owner.ID)
+		c.Next()
+	}, DownloadZipHandler)
+
+	body, _ := json.Marshal(DownloadZipRequest{IDs: []uint{first.ID, private.ID, second.ID, 999}})
+	req := httptest.NewRequest(http.MethodPost, "/api/files/download-zip", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/zip" {
+		t.Errorf("Expected a zip content type, got %q", got)
+	}
+	if got := w.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment; filename=files-") {
+		t.Errorf("Expected an attachment disposition, got %q", got)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	if err != nil {
+		t.Fatalf("Failed to read zip: %v", err)
+	}
+	contents := make(map[string]string)
+	for _, entry := range archive.File {
+		rc, err := entry.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", entry.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[entry.Name] = string(data)
+	}
+	// Both files share an original name, so the second one is numbered
+	expected := map[string]string{"notes.txt": "first content", "notes (2).txt": "second content"}
+	if len(contents) != len(expected) {
+		t.Fatalf("Expected entries %v, got %v", expected, contents)
+	}
+	for name, content := range expected {
+		if contents[name] != content {
+			t.Errorf("Expected %s to hold %q, got %q", name, content, contents[name])
+		}
+	}
+
+	if log := lastDownloadLog(t, first.ID); log.Status != models.FileAccessSuccess || log.BytesServed != int64(len("first content")) {
+		t.Errorf("Expected a successful download log, got %+v", log)
+	}
+	if log := lastDownloadLog(t, private.ID); log.Status != models.FileAccessDenied {
+		t.Errorf("Expected the private file to be logged as denied, got %+v", log)
+	}
+}
+
+func TestDownloadZipHandler_NothingAccessible(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	r := gin.New()
+	r.POST("/api/files/download-zip", func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	}, DownloadZipHandler)
+
+	for ids, status := range map[string]int{`[]`: http.StatusBadRequest, `[998, 999]`: http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodPost, "/api/files/download-zip", strings.NewReader(`{"ids": `+ids+`}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != status {
+			t.Errorf("Expected status %d for IDs %s, got %d", status, ids, w.Code)
+		}
+	}
+}
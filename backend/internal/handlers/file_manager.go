@@ -3,9 +3,11 @@ package handlers
 import (
 	"crypto/md5"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -13,8 +15,12 @@ import (
 	"strings"
 	"time"
 
+	"golangmcp/internal/authorization"
 	"golangmcp/internal/db"
 	"golangmcp/internal/models"
+	"golangmcp/internal/security"
+	"golangmcp/internal/services"
+	"golangmcp/internal/websocket"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -73,15 +79,16 @@ func GetFilesHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    files,
-		"pagination": gin.H{
-			"limit":  limit,
-			"offset": offset,
-			"count":  len(files),
+	// Files are listed with their owners
+	RespondWithETag(c, FileListResponse{
+		Success: true,
+		Data:    NewFileResponses(files),
+		Pagination: PaginationResponse{
+			Limit:  limit,
+			Offset: offset,
+			Count:  len(files),
 		},
-	})
+	}, "files", "users")
 }
 
 // GetFileHandler retrieves a specific file by ID
@@ -121,18 +128,93 @@ func GetFileHandler(c *gin.Context) {
 	}
 
 	// Log file access
-	accessLog := &models.FileAccessLog{
-		FileID:    file.ID,
-		UserID:    userIDUint,
-		Action:    "view",
-		IPAddress: c.ClientIP(),
-		UserAgent: c.GetHeader("User-Agent"),
+	logFileAccess(c, file.ID, userIDUint, "view")
+
+	c.JSON(http.StatusOK, FileDetailResponse{
+		Success: true,
+		Data:    NewFileResponse(*file),
+	})
+}
+
+// MaxBatchGetFiles is the maximum number of file IDs accepted by BatchGetFilesHandler
+const MaxBatchGetFiles = 100
+
+// BatchGetFilesRequest represents a request for the metadata of several files
+type BatchGetFilesRequest struct {
+	IDs []uint `json:"ids" binding:"required"`
+}
+
+// BatchFileResult represents the access result for a single requested file ID
+type BatchFileResult struct {
+	ID     uint   `json:"id"`
+	Status string `json:"status"` // ok, not_found, forbidden
+}
+
+// BatchGetFilesHandler retrieves metadata for several files at once. Files the
+// user neither owns nor can see publicly are skipped and reported as forbidden.
+func BatchGetFilesHandler(c *gin.Context) {
+	var request BatchGetFilesRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if len(request.IDs) == 0 || len(request.IDs) > MaxBatchGetFiles {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Between 1 and %d file IDs are required", MaxBatchGetFiles),
+		})
+		return
+	}
+
+	// Drop duplicate IDs, keeping the requested order
+	seen := make(map[uint]bool)
+	ids := make([]uint, 0, len(request.IDs))
+	for _, id := range request.IDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	files, err := models.GetFilesByIDs(db.DB, ids)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve files",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	filesByID := make(map[uint]models.File, len(files))
+	for _, file := range files {
+		filesByID[file.ID] = file
+	}
+
+	userID, _ := c.Get("user_id")
+	userIDUint := userID.(uint)
+
+	data := make([]models.File, 0, len(files))
+	results := make([]BatchFileResult, 0, len(ids))
+	for _, id := range ids {
+		file, exists := filesByID[id]
+		switch {
+		case !exists:
+			results = append(results, BatchFileResult{ID: id, Status: "not_found"})
+		case file.UserID != userIDUint && !file.IsPublic:
+			results = append(results, BatchFileResult{ID: id, Status: "forbidden"})
+		default:
+			data = append(data, file)
+			results = append(results, BatchFileResult{ID: id, Status: "ok"})
+		}
 	}
-	models.LogFileAccess(db.DB, accessLog)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    file,
+		"data":    NewFileResponses(data),
+		"results": results,
 	})
 }
 
@@ -141,8 +223,12 @@ func UploadFileHandler(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	userIDUint := userID.(uint)
 
+	// Report body progress to /ws/uploads/:uploadId when the client asked for it
+	uploadID := websocket.TrackUploadProgress(c)
+	defer websocket.FinishUploadProgress(c, uploadID)
+
 	// Parse multipart form
-	err := c.Request.ParseMultipartForm(MaxFileSize)
+	form, err := parseUploadForm(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "Failed to parse form",
@@ -150,8 +236,9 @@ func UploadFileHandler(c *gin.Context) {
 		})
 		return
 	}
+	defer form.RemoveAll()
 
-	file, header, err := c.Request.FormFile("file")
+	file, header, err := form.FormFile("file")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": "No file provided",
@@ -169,6 +256,12 @@ func UploadFileHandler(c *gin.Context) {
 		return
 	}
 
+	description := c.PostForm("description")
+	tags := c.PostForm("tags")
+	if !checkUploadFilename(c, header) || !checkUploadMetadata(c, &description, &tags) {
+		return
+	}
+
 	// Get file extension
 	ext := strings.ToLower(filepath.Ext(header.Filename))
 	if ext == "" {
@@ -186,58 +279,54 @@ func UploadFileHandler(c *gin.Context) {
 		return
 	}
 
-	// Read file content
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to read file",
-		})
+	// Create upload directory if it doesn't exist
+	if !ensureUploadDir(c, FileUploadDir) {
 		return
 	}
 
-	// Calculate file hash
-	hash := md5.Sum(fileContent)
-	hashStr := hex.EncodeToString(hash[:])
-
-	// Check if file already exists
-	existingFile, err := models.GetFileByHash(db.DB, hashStr)
-	if err == nil {
-		// File already exists, return existing file info
-		c.JSON(http.StatusOK, gin.H{
-			"success": true,
-			"message": "File already exists",
-			"data":    existingFile,
-		})
+	// Stream the content to a temporary file while hashing it, so uploads are
+	// never held in memory as a whole
+	tempPath, hashStr, err := streamUploadToTemp(file, FileUploadDir)
+	if err != nil {
+		respondSaveError(c, err, "Failed to read file")
 		return
 	}
 
-	// Create upload directory if it doesn't exist
-	err = os.MkdirAll(FileUploadDir, 0755)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to create upload directory",
+	// Check if the user already uploaded this content. Other users' files are
+	// never matched, so identical content gets its own record and copy.
+	existingFile, err := models.GetUserFileByHash(db.DB, userIDUint, hashStr)
+	if err == nil {
+		os.Remove(tempPath)
+		c.JSON(http.StatusOK, gin.H{
+			"success":   true,
+			"message":   "File already exists",
+			"duplicate": true,
+			"data":      NewFileResponse(*existingFile),
 		})
 		return
 	}
 
 	// Generate unique filename
-	timestamp := time.Now().Unix()
-	filename := fmt.Sprintf("%d_%s_%s", timestamp, hashStr[:8], header.Filename)
+	filename := services.GlobalFileNamer.Generate(header.Filename)
 	filePath := filepath.Join(FileUploadDir, filename)
+	if !ensureUploadDir(c, filepath.Dir(filePath)) {
+		os.Remove(tempPath)
+		return
+	}
 
-	// Save file to disk
-	err = os.WriteFile(filePath, fileContent, 0644)
+	// Move the file into place
+	err = os.Rename(tempPath, filePath)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to save file",
-		})
+		os.Remove(tempPath)
+		respondSaveError(c, err, "Failed to save file")
 		return
 	}
 
 	// Get additional form data
-	description := c.PostForm("description")
-	tags := c.PostForm("tags")
 	isPublic := c.PostForm("is_public") == "true"
+	expiresIn, _ := strconv.Atoi(c.PostForm("expires_in")) // hours, can only shorten the policy
+	role, _ := c.Get("role")
+	roleStr, _ := role.(string)
 
 	// Create file record
 	newFile := &models.File{
@@ -252,6 +341,7 @@ func UploadFileHandler(c *gin.Context) {
 		IsPublic:     isPublic,
 		Description:  description,
 		Tags:         tags,
+		ExpiresAt:    services.GlobalFileExpiryManager.ExpiresAt(ext, roleStr, expiresIn, time.Now()),
 	}
 
 	err = models.CreateFile(db.DB, newFile)
@@ -266,23 +356,47 @@ func UploadFileHandler(c *gin.Context) {
 	}
 
 	// Log file upload
-	accessLog := &models.FileAccessLog{
-		FileID:    newFile.ID,
-		UserID:    userIDUint,
-		Action:    "upload",
-		IPAddress: c.ClientIP(),
-		UserAgent: c.GetHeader("User-Agent"),
+	logFileAccess(c, newFile.ID, userIDUint, "upload")
+
+	// Reload with the owner so the response matches the other file endpoints
+	if created, err := models.GetFileByID(db.DB, newFile.ID); err == nil {
+		newFile = created
 	}
-	models.LogFileAccess(db.DB, accessLog)
 
 	c.JSON(http.StatusCreated, gin.H{
-		"success": true,
-		"message": "File uploaded successfully",
-		"data":    newFile,
+		"success":   true,
+		"message":   "File uploaded successfully",
+		"duplicate": false,
+		"data":      NewFileResponse(*newFile),
 	})
 }
 
-// DownloadFileHandler handles file downloads
+// streamUploadToTemp copies an upload into a new temporary file in dir,
+// returning its path and MD5 hash. The file is removed if copying fails.
+func streamUploadToTemp(file io.Reader, dir string) (string, string, error) {
+	temp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return "", "", err
+	}
+
+	hash := md5.New()
+	_, err = io.Copy(io.MultiWriter(temp, hash), file)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), 0644)
+	}
+	if err != nil {
+		os.Remove(temp.Name())
+		return "", "", err
+	}
+
+	return temp.Name(), hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// DownloadFileHandler handles file downloads. Every attempt is logged with its
+// outcome, including denied and missing files.
 func DownloadFileHandler(c *gin.Context) {
 	fileIDStr := c.Param("id")
 	fileID, err := strconv.ParseUint(fileIDStr, 10, 32)
@@ -298,6 +412,7 @@ func DownloadFileHandler(c *gin.Context) {
 	file, err := models.GetFileByID(db.DB, uint(fileID))
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
+			logFileAccessOutcome(c, uint(fileID), userIDUint, "download", models.FileAccessNotFound, 0)
 			c.JSON(http.StatusNotFound, gin.H{
 				"error": "File not found",
 			})
@@ -311,39 +426,313 @@ func DownloadFileHandler(c *gin.Context) {
 
 	// Check if user owns the file or file is public
 	if file.UserID != userIDUint && !file.IsPublic {
+		logFileAccessOutcome(c, file.ID, userIDUint, "download", models.FileAccessDenied, 0)
 		c.JSON(http.StatusForbidden, gin.H{
 			"error": "Access denied",
 		})
 		return
 	}
 
+	// Only content the scanner marked safe is served
+	if !ensureFileScanned(c, file) {
+		logFileAccessOutcome(c, file.ID, userIDUint, "download", models.FileAccessDenied, 0)
+		return
+	}
+
 	// Check if file exists on disk
 	if _, err := os.Stat(file.Path); os.IsNotExist(err) {
+		logFileAccessOutcome(c, file.ID, userIDUint, "download", models.FileAccessNotFound, 0)
 		c.JSON(http.StatusNotFound, gin.H{
 			"error": "File not found on disk",
 		})
 		return
 	}
 
-	// Log file download
-	accessLog := &models.FileAccessLog{
-		FileID:    file.ID,
-		UserID:    userIDUint,
-		Action:    "download",
-		IPAddress: c.ClientIP(),
-		UserAgent: c.GetHeader("User-Agent"),
+	if !setUserContentHeaders(c, file) {
+		return
 	}
-	models.LogFileAccess(db.DB, accessLog)
 
 	// Set headers for file download
 	c.Header("Content-Description", "File Transfer")
 	c.Header("Content-Transfer-Encoding", "binary")
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", file.OriginalName))
-	c.Header("Content-Type", file.MimeType)
 	c.Header("Content-Length", strconv.FormatInt(file.Size, 10))
 
-	// Serve file
+	// Serve file, then log the download with the bytes actually written
 	c.File(file.Path)
+	bytesServed := int64(c.Writer.Size())
+	if bytesServed < 0 {
+		bytesServed = 0
+	}
+	logFileAccessOutcome(c, file.ID, userIDUint, "download", models.FileAccessSuccess, bytesServed)
+}
+
+// setUserContentHeaders sets Content-Type and Content-Disposition for serving an
+// uploaded file. The ?disposition=inline|attachment parameter is honored within
+// GlobalDownloadPolicy, which may also correct the stored type from the file's
+// first bytes. Since uploads are served from the API origin, browsers are told
+// not to sniff the type, and inline content is sandboxed so it can't run script.
+// It responds with 400 and returns false for an invalid disposition.
+func setUserContentHeaders(c *gin.Context, file *models.File) bool {
+	requested := c.Query("disposition")
+	if requested != "" && !services.IsValidDisposition(requested) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": services.ErrInvalidDisposition.Error(),
+		})
+		return false
+	}
+
+	policy := services.GlobalDownloadPolicy.GetPolicy()
+	var head []byte
+	if policy.DetectContentType {
+		head = readFileHead(file.Path)
+	}
+	contentType := policy.ResolveContentType(file.MimeType, head)
+	disposition := policy.ResolveDisposition(requested, contentType)
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": file.OriginalName}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Content-Security-Policy", "default-src 'none'; img-src 'self'; style-src 'unsafe-inline'; sandbox")
+	return true
+}
+
+// readFileHead returns the first bytes of a file, as many as content type
+// detection looks at. Nil is returned when the file can't be read.
+func readFileHead(path string) []byte {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	return head[:n]
+}
+
+// UpdateFileRequest represents the mutable metadata of a file. Omitted fields are left unchanged.
+type UpdateFileRequest struct {
+	Description *string   `json:"description"`
+	Tags        *[]string `json:"tags"`
+	IsPublic    *bool     `json:"is_public"`
+}
+
+// UpdateFileHandler updates a file's metadata. Only the owner may update a file,
+// and the stored content (hash, path, size) can never be changed. If the request
+// carries If-Unmodified-Since, the update is rejected when the file changed since then.
+func UpdateFileHandler(c *gin.Context) {
+	fileIDStr := c.Param("id")
+	fileID, err := strconv.ParseUint(fileIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid file ID",
+		})
+		return
+	}
+
+	// Reject unknown fields so attempts to change e.g. hash or path fail loudly
+	var request UpdateFileRequest
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if request.Description != nil {
+		description, err := services.GlobalFileMetadata.Description(*request.Description)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		updates["description"] = description
+	}
+	if request.Tags != nil {
+		tags, err := services.GlobalFileMetadata.Tags(*request.Tags)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tagsJSON, _ := json.Marshal(tags)
+		updates["tags"] = string(tagsJSON)
+	}
+	if request.IsPublic != nil {
+		updates["is_public"] = *request.IsPublic
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "No updatable fields provided",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userIDUint := userID.(uint)
+	file, err := models.GetFileByID(db.DB, uint(fileID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "File not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve file",
+			})
+		}
+		return
+	}
+
+	// Check if user owns the file
+	if file.UserID != userIDUint {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Access denied",
+		})
+		return
+	}
+
+	if since := c.GetHeader("If-Unmodified-Since"); since != "" {
+		sinceTime, err := http.ParseTime(since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid If-Unmodified-Since header",
+			})
+			return
+		}
+		if file.UpdatedAt.Truncate(time.Second).After(sinceTime) {
+			c.JSON(http.StatusPreconditionFailed, gin.H{
+				"error": "File has been modified",
+				"updated_at": file.UpdatedAt,
+			})
+			return
+		}
+	}
+
+	if err := models.UpdateFileMetadata(db.DB, file, updates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to update file",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	updated, err := models.GetFileByID(db.DB, file.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve file",
+		})
+		return
+	}
+
+	// Log file update
+	logFileAccess(c, file.ID, userIDUint, "update")
+
+	c.Header("Last-Modified", updated.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    NewFileResponse(*updated),
+	})
+}
+
+// RenameFileRequest represents a change of a file's display name, optionally
+// replacing its tags at the same time
+type RenameFileRequest struct {
+	OriginalName string    `json:"original_name" binding:"required"`
+	Tags         *[]string `json:"tags"`
+}
+
+// RenameFileHandler changes the name a file is listed and downloaded under.
+// Only the owner may rename a file; the stored filename and path never change.
+func RenameFileHandler(c *gin.Context) {
+	fileIDStr := c.Param("id")
+	fileID, err := strconv.ParseUint(fileIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid file ID",
+		})
+		return
+	}
+
+	var request RenameFileRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid request",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	name := strings.TrimSpace(request.OriginalName)
+	if err := models.ValidateFileName(name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updates := map[string]interface{}{
+		"original_name": name,
+	}
+	if request.Tags != nil {
+		tags, err := services.GlobalFileMetadata.Tags(*request.Tags)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		tagsJSON, _ := json.Marshal(tags)
+		updates["tags"] = string(tagsJSON)
+	}
+
+	userID, _ := c.Get("user_id")
+	userIDUint := userID.(uint)
+	file, err := models.GetFileByID(db.DB, uint(fileID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "File not found",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "Failed to retrieve file",
+			})
+		}
+		return
+	}
+
+	// Check if user owns the file
+	if file.UserID != userIDUint {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "Access denied",
+		})
+		return
+	}
+
+	oldName := file.OriginalName
+	if err := models.UpdateFileMetadata(db.DB, file, updates); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to rename file",
+		})
+		return
+	}
+
+	updated, err := models.GetFileByID(db.DB, file.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve file",
+		})
+		return
+	}
+
+	// Log file rename
+	logFileAccess(c, file.ID, userIDUint, "rename")
+	services.NewAuditLogger().LogFileRename(userIDUint, file.ID, oldName, name, c.ClientIP(), c.Request.UserAgent(), security.RequestID(c))
+
+	c.Header("Last-Modified", updated.UpdatedAt.UTC().Format(http.TimeFormat))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    NewFileResponse(*updated),
+	})
 }
 
 // DeleteFileHandler handles file deletion
@@ -397,14 +786,8 @@ func DeleteFileHandler(c *gin.Context) {
 	}
 
 	// Log file deletion
-	accessLog := &models.FileAccessLog{
-		FileID:    file.ID,
-		UserID:    userIDUint,
-		Action:    "delete",
-		IPAddress: c.ClientIP(),
-		UserAgent: c.GetHeader("User-Agent"),
-	}
-	models.LogFileAccess(db.DB, accessLog)
+	logFileAccess(c, file.ID, userIDUint, "delete")
+	services.NewAuditLogger().LogFileOperation("delete", userIDUint, file.ID, file.OriginalName, c.ClientIP(), c.Request.UserAgent(), security.RequestID(c), "success")
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -429,6 +812,159 @@ func GetFileStatsHandler(c *gin.Context) {
 	})
 }
 
+// defaultTimelineStart returns where a timeline ending at end starts when no
+// start date is given: 30 days, 12 weeks or 12 months earlier
+func defaultTimelineStart(end time.Time, granularity string) time.Time {
+	switch granularity {
+	case models.TimelineWeek:
+		end = end.AddDate(0, 0, -12*7)
+	case models.TimelineMonth:
+		end = end.AddDate(0, -12, 0)
+	default:
+		end = end.AddDate(0, 0, -30)
+	}
+	return models.TimelineBucketStart(end, granularity)
+}
+
+// GetFileTimelineHandler returns file upload counts and total bytes per day,
+// week or month, optionally filtered by user_id and type. start_date and
+// end_date bound the range, by default the last 30 days, 12 weeks or 12 months.
+// Only callers with admin.security see other users' uploads; everyone else
+// gets their own.
+func GetFileTimelineHandler(c *gin.Context) {
+	filter := models.FileTimelineFilter{
+		Granularity: c.DefaultQuery("granularity", models.TimelineDay),
+		FileType:    c.Query("type"),
+	}
+	if !models.IsValidTimelineGranularity(filter.Granularity) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": models.ErrInvalidTimelineGranularity.Error(),
+		})
+		return
+	}
+
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		userID, err := strconv.ParseUint(userIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "Invalid user ID",
+			})
+			return
+		}
+		id := uint(userID)
+		filter.UserID = &id
+	}
+	if !authorization.ContextHasPermission(c, "admin.security") {
+		callerID := c.GetUint("user_id")
+		if filter.UserID != nil && *filter.UserID != callerID {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Access denied",
+			})
+			return
+		}
+		filter.UserID = &callerID
+	}
+
+	startDate, err := parseLogDate(c.Query("start_date"), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid start_date, use YYYY-MM-DD or RFC3339",
+		})
+		return
+	}
+	endDate, err := parseLogDate(c.Query("end_date"), true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid end_date, use YYYY-MM-DD or RFC3339",
+		})
+		return
+	}
+	filter.End = time.Now()
+	if endDate != nil {
+		filter.End = *endDate
+	}
+	if startDate != nil {
+		filter.Start = *startDate
+	} else {
+		filter.Start = defaultTimelineStart(filter.End, filter.Granularity)
+	}
+
+	buckets, err := models.GetFileTimeline(db.DB, filter)
+	if err != nil {
+		if err == models.ErrInvalidTimelineRange || err == models.ErrTimelineRangeTooLarge {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to retrieve file timeline",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"granularity": filter.Granularity,
+			"start":       filter.Start,
+			"end":         filter.End,
+			"buckets":     buckets,
+		},
+	})
+}
+
+// maxDedupReportGroups is the most duplicate groups a dedup report lists
+const maxDedupReportGroups = 500
+
+// GetFileDedupReportHandler reports how much storage identical content takes:
+// logical vs physical bytes, the savings, and the largest groups of files
+// sharing a hash, up to ?limit (admin only)
+func GetFileDedupReportHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > maxDedupReportGroups {
+		limit = maxDedupReportGroups
+	}
+
+	report, err := models.GetFileDedupReport(db.DB, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to compute dedup report",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": report,
+	})
+}
+
+// logFileAccess records a successful access to a file, tagged with the request
+// ID so it can be joined with the security audit logs of the same request
+func logFileAccess(c *gin.Context, fileID, userID uint, action string) {
+	logFileAccessOutcome(c, fileID, userID, action, models.FileAccessSuccess, 0)
+}
+
+// logFileAccessOutcome records an access to a file with its outcome and the
+// number of bytes of content served
+func logFileAccessOutcome(c *gin.Context, fileID, userID uint, action, status string, bytesServed int64) {
+	models.LogFileAccess(db.DB, &models.FileAccessLog{
+		FileID:      fileID,
+		UserID:      userID,
+		Action:      action,
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+		RequestID:   security.RequestID(c),
+		Status:      status,
+		BytesServed: bytesServed,
+	})
+}
+
 // GetFileAccessLogsHandler returns file access logs
 func GetFileAccessLogsHandler(c *gin.Context) {
 	fileIDStr := c.Param("id")
@@ -477,7 +1013,34 @@ func GetFileAccessLogsHandler(c *gin.Context) {
 		offset = 0
 	}
 
-	logs, err := models.GetFileAccessLogs(db.DB, uint(fileID), limit, offset)
+	filter := models.FileAccessLogFilter{Action: c.Query("action"), RequestID: c.Query("request_id"), Status: c.Query("status")}
+	if filter.Action != "" && !models.IsValidFileAccessAction(filter.Action) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid action, must be one of " + strings.Join(models.FileAccessActions, ", "),
+		})
+		return
+	}
+	if filter.Status != "" && !models.IsValidFileAccessStatus(filter.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid status, must be one of success, not_found, denied",
+		})
+		return
+	}
+	if filter.StartDate, err = parseLogDate(c.Query("start_date"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid start_date, use RFC3339 or YYYY-MM-DD",
+		})
+		return
+	}
+	if filter.EndDate, err = parseLogDate(c.Query("end_date"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid end_date, use RFC3339 or YYYY-MM-DD",
+		})
+		return
+	}
+
+	queryBuilder := models.NewOptimizedQueryBuilder(db.DB)
+	logs, total, err := queryBuilder.GetFileAccessLogsFiltered(uint(fileID), filter, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to retrieve access logs",
@@ -493,6 +1056,51 @@ func GetFileAccessLogsHandler(c *gin.Context) {
 			"limit":  limit,
 			"offset": offset,
 			"count":  len(logs),
+			"total":  total,
 		},
 	})
 }
+
+// parseLogDate parses an RFC3339 timestamp or a YYYY-MM-DD date. A bare date used
+// as an end bound covers the whole day. An empty value means no bound.
+func parseLogDate(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
+
+// RehashFilesHandler recomputes file hashes from disk and reports duplicates (admin only).
+// With ?merge=true, duplicates owned by the same user are merged into the oldest record.
+func RehashFilesHandler(c *gin.Context) {
+	options := services.RehashOptions{
+		Merge: c.Query("merge") == "true",
+	}
+	if batchSize, err := strconv.Atoi(c.Query("batch_size")); err == nil {
+		options.BatchSize = batchSize
+	}
+
+	summary, err := services.RecomputeFileHashes(db.DB, options)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "Failed to recompute file hashes",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    summary,
+	})
+}
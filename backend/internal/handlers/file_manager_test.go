@@ -0,0 +1,1285 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/security"
+	"golangmcp/internal/services"
+)
+
+// createTestFile creates a file record owned by a user that has passed the malware scan
+func createTestFile(t *testing.T, userID uint, name string, isPublic bool) *models.File {
+	file := &models.File{
+		Filename:     name,
+		OriginalName: name,
+		FileType:     "txt",
+		MimeType:     "text/plain",
+		Size:         1,
+		Path:         "uploads/files/" + name,
+		Hash:         name,
+		UserID:       userID,
+		IsPublic:     isPublic,
+		IsScanned:    true,
+		IsSafe:       true,
+	}
+	if err := models.CreateFile(db.DB, file); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	return file
+}
+
+func TestBatchGetFilesHandler_MixedAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	other := &models.User{Username: "other", Email: "other@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := other.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	owned := createTestFile(t, owner.ID, "owned.txt", false)
+	public := createTestFile(t, other.ID, "public.txt", true)
+	private := createTestFile(t, other.ID, "private.txt", false)
+
+	r := gin.New()
+	r.POST("/api/files/batch-get", func(c *gin.Context) {
+		c.Set("user_id", owner.ID)
+		c.Next()
+	}, BatchGetFilesHandler)
+
+	body, _ := json.Marshal(BatchGetFilesRequest{IDs: []uint{owned.ID, public.ID, private.ID, 999, owned.ID}})
+	req := httptest.NewRequest(http.MethodPost, "/api/files/batch-get", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data    []FileResponse    `json:"data"`
+		Results []BatchFileResult `json:"results"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if strings.Contains(w.Body.String(), "password") {
+		t.Errorf("Expected owner password hashes to be left out: %s", w.Body.String())
+	}
+
+	if len(response.Data) != 2 {
+		t.Fatalf("Expected 2 accessible files, got %d", len(response.Data))
+	}
+	for _, file := range response.Data {
+		if file.ID == private.ID {
+			t.Error("Expected private file of another user to be skipped")
+		}
+	}
+
+	expected := []BatchFileResult{
+		{ID: owned.ID, Status: "ok"},
+		{ID: public.ID, Status: "ok"},
+		{ID: private.ID, Status: "forbidden"},
+		{ID: 999, Status: "not_found"},
+	}
+	if len(response.Results) != len(expected) {
+		t.Fatalf("Expected %d results, got %d", len(expected), len(response.Results))
+	}
+	for i, result := range response.Results {
+		if result != expected[i] {
+			t.Errorf("Result %d: expected %+v, got %+v", i, expected[i], result)
+		}
+	}
+}
+
+func TestBatchGetFilesHandler_RejectsEmptyAndOversizedBatches(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	r := gin.New()
+	r.POST("/api/files/batch-get", func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	}, BatchGetFilesHandler)
+
+	tooMany := make([]uint, MaxBatchGetFiles+1)
+	for i := range tooMany {
+		tooMany[i] = uint(i + 1)
+	}
+
+	for _, ids := range [][]uint{{}, tooMany} {
+		body, _ := json.Marshal(BatchGetFilesRequest{IDs: ids})
+		req := httptest.NewRequest(http.MethodPost, "/api/files/batch-get", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %d IDs, got %d", len(ids), w.Code)
+		}
+	}
+}
+
+// patchFile sends a metadata update request as the given user
+func patchFile(r *gin.Engine, fileID uint, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPatch, "/api/files/"+strconv.FormatUint(uint64(fileID), 10), bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// newFileRouter returns a router serving file routes as the given user
+func newFileRouter(userID uint) *gin.Engine {
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	})
+	r.PATCH("/api/files/:id", UpdateFileHandler)
+	return r
+}
+
+func TestUpdateFileHandler_OwnerUpdatesMetadata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	file := createTestFile(t, owner.ID, "report.txt", true)
+
+	w := patchFile(newFileRouter(owner.ID), file.ID, `{"description":"Quarterly report","tags":["finance","q3"],"is_public":false}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "password") {
+		t.Errorf("Expected the owner's password hash to be left out: %s", w.Body.String())
+	}
+
+	updated, err := models.GetFileByID(db.DB, file.ID)
+	if err != nil {
+		t.Fatalf("Failed to reload file: %v", err)
+	}
+	if updated.Description != "Quarterly report" || updated.Tags != `["finance","q3"]` || updated.IsPublic {
+		t.Errorf("Unexpected metadata after update: %+v", updated)
+	}
+	if updated.Hash != file.Hash || updated.Path != file.Path {
+		t.Error("Expected hash and path to be unchanged")
+	}
+
+	logs, _ := models.GetFileAccessLogs(db.DB, file.ID, 10, 0)
+	if len(logs) != 1 || logs[0].Action != "update" {
+		t.Errorf("Expected a single update access log, got %+v", logs)
+	}
+}
+
+func TestUpdateFileHandler_NonOwnerDenied(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	other := &models.User{Username: "other", Email: "other@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := other.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	file := createTestFile(t, owner.ID, "shared.txt", true)
+
+	w := patchFile(newFileRouter(other.ID), file.ID, `{"description":"hijacked"}`)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, got %d", w.Code)
+	}
+
+	unchanged, _ := models.GetFileByID(db.DB, file.ID)
+	if unchanged.Description != "" {
+		t.Errorf("Expected description to be unchanged, got %q", unchanged.Description)
+	}
+}
+
+func TestUpdateFileHandler_InvalidFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	file := createTestFile(t, owner.ID, "data.txt", false)
+	r := newFileRouter(owner.ID)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"hash", `{"hash":"deadbeef"}`},
+		{"path", `{"path":"/etc/passwd"}`},
+		{"empty", `{}`},
+		{"long description", `{"description":"` + strings.Repeat("a", models.MaxFileDescriptionLength+1) + `"}`},
+		{"empty tag", `{"tags":["ok",""]}`},
+		{"wrong type", `{"is_public":"yes"}`},
+	}
+
+	for _, tt := range tests {
+		if w := patchFile(r, file.ID, tt.body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", tt.name, w.Code)
+		}
+	}
+
+	unchanged, _ := models.GetFileByID(db.DB, file.ID)
+	if unchanged.Hash != file.Hash || unchanged.Path != file.Path {
+		t.Error("Expected hash and path to be unchanged")
+	}
+}
+
+func TestUpdateFileHandler_IfUnmodifiedSince(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	file := createTestFile(t, owner.ID, "notes.txt", false)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/files/"+strconv.FormatUint(uint64(file.ID), 10), bytes.NewBufferString(`{"is_public":true}`))
+	req.Header.Set("If-Unmodified-Since", file.UpdatedAt.Add(-time.Hour).UTC().Format(http.TimeFormat))
+	w := httptest.NewRecorder()
+	newFileRouter(owner.ID).ServeHTTP(w, req)
+	if w.Code != http.StatusPreconditionFailed {
+		t.Fatalf("Expected status 412, got %d", w.Code)
+	}
+}
+
+// renameFile sends a rename request as the given user
+func renameFile(userID, fileID uint, body string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.POST("/api/files/:id/rename", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}, RenameFileHandler)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/files/"+strconv.FormatUint(uint64(fileID), 10)+"/rename", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRenameFileHandler_OwnerRenames(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	file := createTestFile(t, owner.ID, "draft.txt", false)
+
+	w := renameFile(owner.ID, file.ID, `{"original_name":"  Final report.txt ","tags":["final"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "password") {
+		t.Errorf("Expected the owner's password hash to be left out: %s", w.Body.String())
+	}
+
+	updated, _ := models.GetFileByID(db.DB, file.ID)
+	if updated.OriginalName != "Final report.txt" || updated.Tags != `["final"]` {
+		t.Errorf("Unexpected metadata after rename: %+v", updated)
+	}
+	if updated.Filename != file.Filename || updated.Path != file.Path {
+		t.Error("Expected stored filename and path to be unchanged")
+	}
+
+	logs, _ := models.GetFileAccessLogs(db.DB, file.ID, 10, 0)
+	if len(logs) != 1 || logs[0].Action != "rename" {
+		t.Errorf("Expected a single rename access log, got %+v", logs)
+	}
+
+	var auditLog models.SecurityAuditLog
+	if err := db.DB.Where("event_action = ?", "rename").First(&auditLog).Error; err != nil {
+		t.Fatalf("Expected a rename audit log: %v", err)
+	}
+	if !strings.Contains(auditLog.Details, `"old_name":"draft.txt"`) || !strings.Contains(auditLog.Details, `"new_name":"Final report.txt"`) {
+		t.Errorf("Expected audit details to record both names, got %s", auditLog.Details)
+	}
+}
+
+func TestRenameFileHandler_InvalidNames(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	other := &models.User{Username: "other", Email: "other@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := other.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	file := createTestFile(t, owner.ID, "keep.txt", true)
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing", `{}`},
+		{"blank", `{"original_name":"   "}`},
+		{"control character", `{"original_name":"bad\u0007name.txt"}`},
+		{"newline", `{"original_name":"bad\nname.txt"}`},
+		{"slash", `{"original_name":"../etc/passwd"}`},
+		{"backslash", `{"original_name":"dir\\name.txt"}`},
+		{"dot dot", `{"original_name":".."}`},
+		{"too long", `{"original_name":"` + strings.Repeat("a", models.MaxFileNameLength+1) + `"}`},
+		{"empty tag", `{"original_name":"ok.txt","tags":[""]}`},
+	}
+
+	for _, tt := range tests {
+		if w := renameFile(owner.ID, file.ID, tt.body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", tt.name, w.Code)
+		}
+	}
+
+	if w := renameFile(other.ID, file.ID, `{"original_name":"hijacked.txt"}`); w.Code != http.StatusForbidden {
+		t.Errorf("Expected non-owner rename to be forbidden, got %d", w.Code)
+	}
+
+	unchanged, _ := models.GetFileByID(db.DB, file.ID)
+	if unchanged.OriginalName != "keep.txt" {
+		t.Errorf("Expected name to be unchanged, got %q", unchanged.OriginalName)
+	}
+}
+
+// getFileLogs requests a file's access logs as the given user
+func getFileLogs(userID, fileID uint, query string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.GET("/api/files/:id/logs", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}, GetFileAccessLogsHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/files/"+strconv.FormatUint(uint64(fileID), 10)+"/logs?"+query, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetFileAccessLogsHandler_Filters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	file := createTestFile(t, owner.ID, "logs.txt", false)
+
+	now := time.Now().UTC()
+	for _, entry := range []struct {
+		action string
+		at     time.Time
+	}{
+		{"upload", now.AddDate(0, 0, -10)},
+		{"download", now.AddDate(0, 0, -5)},
+		{"download", now.AddDate(0, 0, -1)},
+		{"download", now},
+		{"view", now},
+	} {
+		log := &models.FileAccessLog{FileID: file.ID, UserID: owner.ID, Action: entry.action, CreatedAt: entry.at}
+		if err := db.DB.Create(log).Error; err != nil {
+			t.Fatalf("Failed to create access log: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		wantTotal int64
+		wantCount int
+	}{
+		{"no filters", "", 5, 5},
+		{"action", "action=download", 3, 3},
+		{"action paginated", "action=download&limit=2&offset=2", 3, 1},
+		{"action and start date", "action=download&start_date=" + now.AddDate(0, 0, -2).Format(time.RFC3339), 2, 2},
+		{"date range", "start_date=" + now.AddDate(0, 0, -6).Format("2006-01-02") + "&end_date=" + now.AddDate(0, 0, -1).Format("2006-01-02"), 2, 2},
+		{"no matches", "action=delete", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := getFileLogs(owner.ID, file.ID, tt.query)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			var response struct {
+				Data       []models.FileAccessLog `json:"data"`
+				Pagination struct {
+					Count int   `json:"count"`
+					Total int64 `json:"total"`
+				} `json:"pagination"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Pagination.Total != tt.wantTotal {
+				t.Errorf("Expected total %d, got %d", tt.wantTotal, response.Pagination.Total)
+			}
+			if len(response.Data) != tt.wantCount || response.Pagination.Count != tt.wantCount {
+				t.Errorf("Expected %d logs, got %d", tt.wantCount, len(response.Data))
+			}
+			for _, log := range response.Data {
+				if strings.Contains(tt.query, "action=download") && log.Action != "download" {
+					t.Errorf("Expected only download logs, got %q", log.Action)
+				}
+				if log.User.Username != "owner" || log.User.Password != "" || log.User.Email != "" {
+					t.Errorf("Expected only the username of the user, got %+v", log.User)
+				}
+			}
+		})
+	}
+}
+
+func TestGetFileAccessLogsHandler_InvalidFilters(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	file := createTestFile(t, owner.ID, "logs.txt", false)
+
+	for _, query := range []string{"action=copy", "status=failed", "start_date=yesterday", "end_date=2024-13-01"} {
+		if w := getFileLogs(owner.ID, file.ID, query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", query, w.Code)
+		}
+	}
+
+	w := getFileLogs(owner.ID, file.ID, "action=copy")
+	for _, action := range models.FileAccessActions {
+		if !strings.Contains(w.Body.String(), action) {
+			t.Errorf("Expected the error to list %q, got %s", action, w.Body.String())
+		}
+	}
+}
+
+func TestGetFileTimelineHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	for i, created := range []time.Time{
+		time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 1, 17, 0, 0, 0, time.UTC),
+		time.Date(2024, 5, 3, 9, 0, 0, 0, time.UTC),
+	} {
+		file := createTestFile(t, owner.ID, fmt.Sprintf("day%d.txt", i), false)
+		if err := db.DB.Model(file).Update("created_at", created).Error; err != nil {
+			t.Fatalf("Failed to update file: %v", err)
+		}
+	}
+
+	other := &models.User{Username: "other", Email: "other@example.com", Password: "password123", Role: "user"}
+	if err := other.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	otherFile := createTestFile(t, other.ID, "other.txt", true)
+	db.DB.Model(otherFile).Update("created_at", time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC))
+
+	getAs := func(userID uint, role, query string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.GET("/api/files/stats/timeline", func(c *gin.Context) {
+			c.Set("user_id", userID)
+			c.Set("role", role)
+			c.Next()
+		}, GetFileTimelineHandler)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/files/stats/timeline?"+query, nil))
+		return w
+	}
+	get := func(query string) *httptest.ResponseRecorder {
+		return getAs(owner.ID, "user", query)
+	}
+
+	w := get("granularity=day&start_date=2024-05-01&end_date=2024-05-03")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data struct {
+			Buckets []models.FileTimelineBucket `json:"buckets"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	var counts []int64
+	for _, bucket := range resp.Data.Buckets {
+		counts = append(counts, bucket.Count)
+	}
+	if fmt.Sprint(counts) != "[2 0 1]" {
+		t.Errorf("Expected zero-filled daily counts [2 0 1] of the caller's uploads, got %v", counts)
+	}
+
+	// Other users' uploads are for admins only
+	if w := get("user_id=" + strconv.FormatUint(uint64(other.ID), 10)); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for another user's timeline, got %d", w.Code)
+	}
+	w = getAs(99, "admin", "granularity=day&start_date=2024-05-01&end_date=2024-05-03")
+	json.Unmarshal(w.Body.Bytes(), &resp)
+	if len(resp.Data.Buckets) != 3 || resp.Data.Buckets[1].Count != 1 {
+		t.Errorf("Expected an admin to see every user's uploads, got %s", w.Body.String())
+	}
+
+	for _, query := range []string{"granularity=hour", "user_id=abc", "start_date=soon", "start_date=2024-05-03&end_date=2024-05-01", "start_date=2000-01-01"} {
+		if w := get(query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", query, w.Code)
+		}
+	}
+}
+
+// downloadFile requests a file download as the given user
+func downloadFile(userID, fileID uint, query string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.GET("/api/files/:id/download", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}, DownloadFileHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/files/"+strconv.FormatUint(uint64(fileID), 10)+"/download?"+query, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestDownloadFileHandler_Disposition(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	dir := t.TempDir()
+	newFile := func(name, mimeType string) *models.File {
+		file := createTestFile(t, owner.ID, name, false)
+		file.MimeType = mimeType
+		file.Path = filepath.Join(dir, name)
+		if err := os.WriteFile(file.Path, []byte("content"), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		if err := models.UpdateFile(db.DB, file); err != nil {
+			t.Fatalf("Failed to update file: %v", err)
+		}
+		return file
+	}
+	text := newFile("notes.txt", "text/plain")
+	html := newFile("page.html", "text/html")
+
+	tests := []struct {
+		name  string
+		file  *models.File
+		query string
+		want  string
+	}{
+		{"default is attachment", text, "", `attachment; filename=notes.txt`},
+		{"explicit attachment", text, "disposition=attachment", `attachment; filename=notes.txt`},
+		{"inline for a safe type", text, "disposition=inline", `inline; filename=notes.txt`},
+		{"inline downgraded for html", html, "disposition=inline", `attachment; filename=page.html`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := downloadFile(owner.ID, tt.file.ID, tt.query)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+			if got := w.Header().Get("Content-Disposition"); got != tt.want {
+				t.Errorf("Expected Content-Disposition %q, got %q", tt.want, got)
+			}
+			if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+				t.Error("Expected X-Content-Type-Options: nosniff")
+			}
+			if !strings.Contains(w.Header().Get("Content-Security-Policy"), "sandbox") {
+				t.Error("Expected a sandboxing Content-Security-Policy")
+			}
+		})
+	}
+
+	if w := downloadFile(owner.ID, text.ID, "disposition=render"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an invalid disposition, got %d", w.Code)
+	}
+}
+
+func TestDownloadFileHandler_InlineDisabledByPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	origPolicy := services.GlobalDownloadPolicy
+	t.Cleanup(func() { services.GlobalDownloadPolicy = origPolicy })
+	services.GlobalDownloadPolicy = services.NewDownloadPolicyManager()
+	policy := services.DefaultDownloadPolicy()
+	policy.AllowInline = false
+	if err := services.GlobalDownloadPolicy.UpdatePolicy(policy); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	file := createTestFile(t, owner.ID, "notes.txt", false)
+	file.Path = filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(file.Path, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := models.UpdateFile(db.DB, file); err != nil {
+		t.Fatalf("Failed to update file: %v", err)
+	}
+
+	w := downloadFile(owner.ID, file.ID, "disposition=inline")
+	if got := w.Header().Get("Content-Disposition"); !strings.HasPrefix(got, "attachment") {
+		t.Errorf("Expected attachment when inline is disabled, got %q", got)
+	}
+}
+
+func TestDownloadFileHandler_CorrectsStoredContentType(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	origPolicy := services.GlobalDownloadPolicy
+	t.Cleanup(func() { services.GlobalDownloadPolicy = origPolicy })
+	services.GlobalDownloadPolicy = services.NewDownloadPolicyManager()
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	// The client sent a generic type for a csv
+	file := createTestFile(t, owner.ID, "report.csv", false)
+	file.MimeType = "application/octet-stream"
+	file.Path = filepath.Join(t.TempDir(), "report.csv")
+	if err := os.WriteFile(file.Path, []byte("name,count\nfiles,2\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	if err := models.UpdateFile(db.DB, file); err != nil {
+		t.Fatalf("Failed to update file: %v", err)
+	}
+
+	w := downloadFile(owner.ID, file.ID, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Expected the detected content type, got %q", got)
+	}
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("Expected X-Content-Type-Options: nosniff")
+	}
+
+	// With detection disabled the stored type is served as is
+	policy := services.GlobalDownloadPolicy.GetPolicy()
+	policy.DetectContentType = false
+	if err := services.GlobalDownloadPolicy.UpdatePolicy(&policy); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+	w = downloadFile(owner.ID, file.ID, "")
+	if got := w.Header().Get("Content-Type"); got != "application/octet-stream" {
+		t.Errorf("Expected the stored content type, got %q", got)
+	}
+}
+
+// lastDownloadLog returns the most recent download log entry for a file
+func lastDownloadLog(t *testing.T, fileID uint) models.FileAccessLog {
+	var log models.FileAccessLog
+	if err := db.DB.Where("file_id = ? AND action = ?", fileID, "download").Order("id desc").First(&log).Error; err != nil {
+		t.Fatalf("Expected a download log for file %d: %v", fileID, err)
+	}
+	return log
+}
+
+func TestDownloadFileHandler_LogsOutcome(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	other := &models.User{Username: "other", Email: "other@example.com", Password: "password123", Role: "user"}
+	for _, user := range []*models.User{owner, other} {
+		if err := user.Create(db.DB); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	file := createTestFile(t, owner.ID, "notes.txt", false)
+	file.Path = filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(file.Path, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	file.Size = int64(len("content"))
+	if err := models.UpdateFile(db.DB, file); err != nil {
+		t.Fatalf("Failed to update file: %v", err)
+	}
+
+	// Successful downloads record the bytes served
+	if w := downloadFile(owner.ID, file.ID, ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	log := lastDownloadLog(t, file.ID)
+	if log.Status != models.FileAccessSuccess || log.BytesServed != int64(len("content")) || log.UserID != owner.ID {
+		t.Errorf("Expected a success entry with 7 bytes served by the owner, got %+v", log)
+	}
+
+	// Another user's private file is denied
+	if w := downloadFile(other.ID, file.ID, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected 403, got %d", w.Code)
+	}
+	log = lastDownloadLog(t, file.ID)
+	if log.Status != models.FileAccessDenied || log.BytesServed != 0 || log.UserID != other.ID {
+		t.Errorf("Expected a denied entry for the other user, got %+v", log)
+	}
+
+	// Content missing from disk is not found
+	if err := os.Remove(file.Path); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+	if w := downloadFile(owner.ID, file.ID, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404, got %d", w.Code)
+	}
+	if log = lastDownloadLog(t, file.ID); log.Status != models.FileAccessNotFound || log.BytesServed != 0 {
+		t.Errorf("Expected a not_found entry for the missing content, got %+v", log)
+	}
+
+	// So is a file ID with no record
+	if w := downloadFile(owner.ID, 9999, ""); w.Code != http.StatusNotFound {
+		t.Fatalf("Expected 404, got %d", w.Code)
+	}
+	if log = lastDownloadLog(t, 9999); log.Status != models.FileAccessNotFound {
+		t.Errorf("Expected a not_found entry for the unknown file, got %+v", log)
+	}
+}
+
+func TestDownloadFileHandler_LogsUnscannedAsDenied(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	file := createTestFile(t, owner.ID, "pending.txt", false)
+	if err := db.DB.Model(file).Update("is_scanned", false).Error; err != nil {
+		t.Fatalf("Failed to update file: %v", err)
+	}
+
+	if w := downloadFile(owner.ID, file.ID, ""); w.Code != http.StatusConflict {
+		t.Fatalf("Expected 409 while awaiting a scan, got %d", w.Code)
+	}
+	if log := lastDownloadLog(t, file.ID); log.Status != models.FileAccessDenied {
+		t.Errorf("Expected a denied entry for the unscanned file, got %+v", log)
+	}
+}
+
+// uploadFile posts a multipart upload as the given user
+func uploadFile(t testing.TB, userID uint, name, content string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	newUploadRouter(userID).ServeHTTP(w, newUploadRequest(t, name, []byte(content)))
+	return w
+}
+
+// newUploadRouter returns a router serving uploads as the given user
+func newUploadRouter(userID uint) *gin.Engine {
+	r := gin.New()
+	r.POST("/api/files/upload", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}, UploadFileHandler)
+	return r
+}
+
+// newUploadRequest builds a multipart upload request
+func newUploadRequest(t testing.TB, name string, content []byte) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatalf("Failed to create form file: %v", err)
+	}
+	part.Write(content)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/files/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestUploadFileHandler_DuplicateHashes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	// New uploads are written under the working directory
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	other := &models.User{Username: "other", Email: "other@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := other.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	first := uploadFile(t, owner.ID, "report.txt", "same content")
+	if first.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 for a new upload, got %d: %s", first.Code, first.Body.String())
+	}
+
+	tests := []struct {
+		name          string
+		userID        uint
+		wantStatus    int
+		wantDuplicate bool
+	}{
+		{"same user", owner.ID, http.StatusOK, true},
+		{"other user", other.ID, http.StatusCreated, false},
+		{"other user again", other.ID, http.StatusOK, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := uploadFile(t, tt.userID, "copy.txt", "same content")
+			if w.Code != tt.wantStatus {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantStatus, w.Code, w.Body.String())
+			}
+
+			var response struct {
+				Duplicate *bool        `json:"duplicate"`
+				Data      FileResponse `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("Failed to decode response: %v", err)
+			}
+			if response.Duplicate == nil || *response.Duplicate != tt.wantDuplicate {
+				t.Errorf("Expected duplicate=%v, got %s", tt.wantDuplicate, w.Body.String())
+			}
+			// Another user's file must never be surfaced
+			if response.Data.UserID != tt.userID {
+				t.Errorf("Expected a file owned by user %d, got one owned by %d", tt.userID, response.Data.UserID)
+			}
+			if response.Data.User.ID != tt.userID || response.Data.User.Username == "" {
+				t.Errorf("Expected the owner in the response, got %+v", response.Data.User)
+			}
+			if strings.Contains(w.Body.String(), "password") || strings.Contains(w.Body.String(), "deleted_at") {
+				t.Errorf("Expected the API representation of the file, got %s", w.Body.String())
+			}
+		})
+	}
+
+	var count int64
+	db.DB.Model(&models.File{}).Count(&count)
+	if count != 2 {
+		t.Errorf("Expected one record per owner, got %d files", count)
+	}
+}
+
+func TestUploadFileHandler_ReuploadAfterDelete(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	chdirTemp(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if w := uploadFile(t, owner.ID, "report.txt", "same content"); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 for a new upload, got %d: %s", w.Code, w.Body.String())
+	}
+	var deleted models.File
+	db.DB.Where("user_id = ?", owner.ID).First(&deleted)
+	if err := models.DeleteFile(db.DB, deleted.ID); err != nil {
+		t.Fatalf("Failed to delete file: %v", err)
+	}
+
+	// The deleted file is no duplicate, the content is stored again
+	w := uploadFile(t, owner.ID, "report.txt", "same content")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 for a re-upload, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := uploadFile(t, owner.ID, "copy.txt", "same content"); w.Code != http.StatusOK {
+		t.Errorf("Expected the re-upload to be deduplicated again, got %d", w.Code)
+	}
+}
+
+func TestUploadFileHandler_RejectsDeniedExtensions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	chdirTemp(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	for _, name := range []string{"shell.php.txt", "payload.EXE.csv", "report.phtml. .txt"} {
+		if w := uploadFile(t, owner.ID, name, "content of "+name); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d: %s", name, w.Code, w.Body.String())
+		}
+	}
+
+	var count int64
+	db.DB.Model(&models.File{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected no file records for refused names, got %d", count)
+	}
+
+	// Allowed names are stored normalized
+	w := uploadFile(t, owner.ID, "  quarterly   report.txt. ", "quarterly")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var file models.File
+	if err := db.DB.First(&file).Error; err != nil {
+		t.Fatalf("Failed to load file: %v", err)
+	}
+	if file.OriginalName != "quarterly report.txt" {
+		t.Errorf("Expected normalized name %q, got %q", "quarterly report.txt", file.OriginalName)
+	}
+}
+
+func TestUploadFileHandler_ValidatesMetadata(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	chdirTemp(t)
+
+	orig := services.GlobalFileMetadata
+	t.Cleanup(func() { services.GlobalFileMetadata = orig })
+	services.GlobalFileMetadata = services.NewFileMetadataManager()
+	if err := services.GlobalFileMetadata.UpdatePolicy(&services.FileMetadataPolicy{MaxDescriptionLength: 20, MaxTags: 2, MaxTagLength: 10}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	upload := func(content, description, tags string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField("description", description)
+		writer.WriteField("tags", tags)
+		part, _ := writer.CreateFormFile("file", "notes.txt")
+		part.Write([]byte(content))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/files/upload", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		newUploadRouter(owner.ID).ServeHTTP(w, req)
+		return w
+	}
+
+	for name, w := range map[string]*httptest.ResponseRecorder{
+		"long description": upload("one", strings.Repeat("a", 21), ""),
+		"too many tags":    upload("two", "", "a,b,c"),
+		"long tag":         upload("three", "", strings.Repeat("t", 11)),
+	} {
+		if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "at most") {
+			t.Errorf("%s: expected status 400 with the limit, got %d: %s", name, w.Code, w.Body.String())
+		}
+	}
+	var count int64
+	db.DB.Model(&models.File{}).Count(&count)
+	if count != 0 {
+		t.Errorf("Expected no file records for refused metadata, got %d", count)
+	}
+
+	// Values at the limits are stored without their control characters
+	w := upload("four", strings.Repeat("a", 20)+"\x00", "finance\x1b, "+strings.Repeat("t", 10))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var file models.File
+	if err := db.DB.First(&file).Error; err != nil {
+		t.Fatalf("Failed to load file: %v", err)
+	}
+	if file.Description != strings.Repeat("a", 20) || file.Tags != "finance,"+strings.Repeat("t", 10) {
+		t.Errorf("Unexpected metadata: %q, %q", file.Description, file.Tags)
+	}
+}
+
+func TestUploadFileHandler_AppliesExpiryPolicy(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	chdirTemp(t)
+
+	orig := services.GlobalFileExpiryManager
+	t.Cleanup(func() { services.GlobalFileExpiryManager = orig })
+	services.GlobalFileExpiryManager = services.NewFileExpiryManager()
+	if err := services.GlobalFileExpiryManager.UpdatePolicy(&services.FileExpiryPolicy{
+		FileTypes:              map[string]int{"csv": 24},
+		CleanupIntervalMinutes: 5,
+	}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	expiresAt := func(name, content string) *time.Time {
+		w := uploadFile(t, owner.ID, name, content)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+		var response struct {
+			Data models.File `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Data.ExpiresAt
+	}
+
+	// Exports expire after a day, other files are kept
+	if got := expiresAt("export.csv", "a,b"); got == nil || time.Until(*got) < 23*time.Hour || time.Until(*got) > 24*time.Hour {
+		t.Errorf("Expected a csv upload to expire in a day, got %v", got)
+	}
+	if got := expiresAt("report.txt", "report"); got != nil {
+		t.Errorf("Expected a regular upload to be kept, got expiry %v", got)
+	}
+}
+
+func TestUploadFileHandler_StreamsContentToDisk(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	chdirTemp(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	content := strings.Repeat("streamed,content\n", 100000)
+	w := uploadFile(t, owner.ID, "large.csv", content)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		Data models.File `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	stored, err := os.ReadFile(response.Data.Path)
+	if err != nil || string(stored) != content {
+		t.Fatalf("Expected the stored file to match the upload, got %d bytes, %v", len(stored), err)
+	}
+	hash := md5.Sum([]byte(content))
+	if response.Data.Hash != hex.EncodeToString(hash[:]) {
+		t.Errorf("Expected the hash computed while streaming to match, got %s", response.Data.Hash)
+	}
+
+	// A duplicate is detected by the streamed hash and its copy discarded
+	if w := uploadFile(t, owner.ID, "again.csv", content); w.Code != http.StatusOK {
+		t.Fatalf("Expected a duplicate upload to return 200, got %d", w.Code)
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(FileUploadDir, ".upload-*"))
+	if len(leftovers) != 0 {
+		t.Errorf("Expected temporary files to be removed, found %v", leftovers)
+	}
+}
+
+// setupMultipartTemp spills multipart parts above memory bytes to a temp
+// directory of the test, which it returns
+func setupMultipartTemp(t *testing.T, memory int64) string {
+	tempDir := t.TempDir()
+	origPolicy := services.GlobalUploadTempManager.GetPolicy()
+	t.Cleanup(func() { services.GlobalUploadTempManager.UpdatePolicy(&origPolicy) })
+	if err := services.GlobalUploadTempManager.UpdatePolicy(&services.UploadTempPolicy{
+		Dir: tempDir, MaxAge: time.Hour, SweepInterval: time.Hour,
+	}); err != nil {
+		t.Fatalf("Failed to set upload temp directory: %v", err)
+	}
+
+	origConfig := security.GlobalSecurityConfig
+	t.Cleanup(func() { security.GlobalSecurityConfig = origConfig })
+	security.GlobalSecurityConfig = security.NewSecurityConfigManager(security.DefaultSecurityConfig)
+	if _, err := security.GlobalSecurityConfig.UpdateConfig(func(config *security.SecurityConfig) {
+		config.MultipartMemory = memory
+	}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	return tempDir
+}
+
+func TestUploadFileHandler_RemovesTempFilesOnRejection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	chdirTemp(t)
+	tempDir := setupMultipartTemp(t, 1024)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	// Both spill to disk; one is refused by name, the other stored
+	content := strings.Repeat("x", 64*1024)
+	if w := uploadFile(t, owner.ID, "payload.EXE.csv", content); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := uploadFile(t, owner.ID, "notes.txt", content); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	leftovers, _ := os.ReadDir(tempDir)
+	if len(leftovers) != 0 {
+		t.Errorf("Expected multipart temp files to be removed, found %d", len(leftovers))
+	}
+}
+
+func TestUploadFileHandler_SpillsLargeUploadsToDisk(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	chdirTemp(t)
+
+	tempDir := setupMultipartTemp(t, 64*1024)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	content := []byte(strings.Repeat("a,b,c,d,e,f,g,h,i,j,k,l,m,n,o,p,q,r,s,t,u,v,w,x,y\n", 8*1024*1024/50))
+	r := newUploadRouter(owner.ID)
+	req := newUploadRequest(t, "large.csv", content)
+	w := httptest.NewRecorder()
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	r.ServeHTTP(w, req)
+	runtime.ReadMemStats(&after)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	// Holding the file in memory would allocate at least its size
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > uint64(len(content)/4) {
+		t.Errorf("Expected the upload to spill to disk, allocated %d bytes for a %d byte file", allocated, len(content))
+	}
+	leftovers, _ := os.ReadDir(tempDir)
+	if len(leftovers) != 0 {
+		t.Errorf("Expected multipart temp files to be removed, found %d", len(leftovers))
+	}
+}
+
+func TestUploadFileHandler_CSRFTokenFormField(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	chdirTemp(t)
+	setupMultipartTemp(t, 1024)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/api/files/upload", security.CSRFMiddleware(), func(c *gin.Context) {
+		c.Set("user_id", owner.ID)
+		c.Next()
+	}, UploadFileHandler)
+
+	csrfToken := security.GlobalCSRFProtection.GenerateToken("192.0.2.1")
+	upload := func(name string, header bool) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField("csrf_token", csrfToken)
+		part, _ := writer.CreateFormFile("file", name)
+		part.Write([]byte(strings.Repeat("x", 4096)))
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/files/upload", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		if header {
+			req.Header.Set("X-CSRF-Token", csrfToken)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// The middleware leaves the body to the handler, which parses it once
+	if w := upload("with-header.txt", true); w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+	}
+	// A multipart body isn't read for the token, the header is required
+	if w := upload("field-only.txt", false); w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 without the header, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func BenchmarkUploadFileHandler_LargeFile(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(b)
+	chdirTemp(b)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		b.Fatalf("Failed to create user: %v", err)
+	}
+
+	content := []byte(strings.Repeat("a,b,c,d,e,f,g,h,i,j,k,l,m,n,o,p,q,r,s,t,u,v,w,x,y\n", 40*1024*1024/50))
+	r := newUploadRouter(owner.ID)
+	b.SetBytes(int64(len(content)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		// Unique content, so every iteration is stored rather than deduplicated.
+		// Building the request is not measured, only the handler's memory use.
+		b.StopTimer()
+		copy(content, fmt.Sprintf("%08d", i))
+		req := newUploadRequest(b, "large.csv", content)
+		w := httptest.NewRecorder()
+		b.StartTimer()
+
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			b.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+}
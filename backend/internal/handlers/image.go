@@ -33,7 +33,7 @@ func (ih *ImageHandlers) UploadOptimizedImageHandler(c *gin.Context) {
 	}
 
 	// Parse multipart form
-	form, err := c.MultipartForm()
+	form, err := parseUploadForm(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse multipart form"})
 		return
@@ -47,7 +47,10 @@ func (ih *ImageHandlers) UploadOptimizedImageHandler(c *gin.Context) {
 	}
 
 	file := files[0]
-	
+	if !checkUploadFilename(c, file.FileHeader) {
+		return
+	}
+
 	// Open uploaded file
 	src, err := file.Open()
 	if err != nil {
@@ -57,17 +60,16 @@ func (ih *ImageHandlers) UploadOptimizedImageHandler(c *gin.Context) {
 	defer src.Close()
 
 	// Process image
-	processedImg, err := ih.processor.ProcessImage(src, file)
+	processedImg, err := ih.processor.ProcessImage(src, file.FileHeader)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Save optimized image
-	uploadDir := "uploads/images"
-	filePath, err := ih.processor.SaveImage(processedImg, uploadDir)
+	filePath, err := ih.processor.SaveImage(processedImg, ImageDir)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save optimized image"})
+		respondSaveError(c, err, "Failed to save optimized image")
 		return
 	}
 
@@ -97,6 +99,7 @@ func (ih *ImageHandlers) UploadOptimizedImageHandler(c *gin.Context) {
 			"filename":          processedImg.Filename,
 			"original_filename": processedImg.OriginalFilename,
 			"format":            processedImg.Format,
+			"source_format":     processedImg.SourceFormat,
 			"original_size":     processedImg.OriginalSize,
 			"optimized_size":    processedImg.OptimizedSize,
 			"compression_ratio": processedImg.CompressionRatio,
@@ -116,7 +119,7 @@ func (ih *ImageHandlers) UploadOptimizedImageHandler(c *gin.Context) {
 // ValidateImageHandler validates an image without processing
 func (ih *ImageHandlers) ValidateImageHandler(c *gin.Context) {
 	// Parse multipart form
-	form, err := c.MultipartForm()
+	form, err := parseUploadForm(c)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse multipart form"})
 		return
@@ -130,7 +133,10 @@ func (ih *ImageHandlers) ValidateImageHandler(c *gin.Context) {
 	}
 
 	file := files[0]
-	
+	if !checkUploadFilename(c, file.FileHeader) {
+		return
+	}
+
 	// Open uploaded file
 	src, err := file.Open()
 	if err != nil {
@@ -140,7 +146,7 @@ func (ih *ImageHandlers) ValidateImageHandler(c *gin.Context) {
 	defer src.Close()
 
 	// Validate image
-	if err := ih.processor.ValidateImage(src, file); err != nil {
+	if err := ih.processor.ValidateImage(src, file.FileHeader); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -182,13 +188,15 @@ func (ih *ImageHandlers) GetImageStatsHandler(c *gin.Context) {
 	})
 }
 
-// UpdateImageSettingsHandler updates image processing settings
+// UpdateImageSettingsHandler updates image processing settings. Format rules,
+// when given, replace the per-format quality and conversion rules.
 func (ih *ImageHandlers) UpdateImageSettingsHandler(c *gin.Context) {
 	var request struct {
 		MaxWidth    uint   `json:"max_width"`
 		MaxHeight   uint   `json:"max_height"`
 		Quality     int    `json:"quality"`
 		MaxFileSize int64  `json:"max_file_size"`
+		FormatRules map[string]services.ImageFormatRule `json:"format_rules"`
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
@@ -209,16 +217,27 @@ func (ih *ImageHandlers) UpdateImageSettingsHandler(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Max file size must be greater than 0"})
 		return
 	}
+	if request.FormatRules != nil {
+		if err := services.ValidateImageFormatRules(request.FormatRules); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
-	ih.processor.UpdateSettings(request.MaxWidth, request.MaxHeight, request.Quality, request.MaxFileSize)
+	settings, err := ih.processor.UpdateSettingsAndRules(request.MaxWidth, request.MaxHeight, request.Quality, request.MaxFileSize, request.FormatRules)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Image processing settings updated successfully",
 		"data": gin.H{
-			"max_width":     request.MaxWidth,
-			"max_height":    request.MaxHeight,
-			"quality":       request.Quality,
-			"max_file_size": request.MaxFileSize,
+			"max_width":     settings.MaxWidth,
+			"max_height":    settings.MaxHeight,
+			"quality":       settings.Quality,
+			"max_file_size": settings.MaxFileSize,
+			"format_rules":  settings.FormatRules,
 		},
 	})
 }
@@ -245,6 +264,11 @@ func (ih *ImageHandlers) GetImageFileHandler(c *gin.Context) {
 		return
 	}
 
+	// Only content the scanner marked safe is served
+	if !ensureFileScanned(c, file) {
+		return
+	}
+
 	// Check if file exists
 	if !fileExists(file.Path) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Image file not found on disk"})
@@ -252,8 +276,9 @@ func (ih *ImageHandlers) GetImageFileHandler(c *gin.Context) {
 	}
 
 	// Set appropriate headers
-	c.Header("Content-Type", file.MimeType)
-	c.Header("Content-Disposition", "inline; filename="+file.OriginalName)
+	if !setUserContentHeaders(c, file) {
+		return
+	}
 	c.Header("Cache-Control", "public, max-age=3600")
 
 	// Serve file
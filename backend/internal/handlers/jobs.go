@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/services"
+)
+
+// ListJobsHandler returns the status of every background job (Admin only)
+func ListJobsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": services.GlobalScheduler.Statuses(),
+	})
+}
+
+// RunJobHandler runs a background job now and returns its outcome (Admin only)
+func RunJobHandler(c *gin.Context) {
+	name := c.Param("name")
+
+	status, err := services.GlobalScheduler.Trigger(name)
+	switch err {
+	case services.ErrJobNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	case services.ErrJobRunning:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID, _ := c.Get("user_id")
+	services.NewAuditLogger().LogAdminAction(adminID.(uint), "job_run", "job", nil, gin.H{
+		"job":    name,
+		"result": status.LastResult,
+		"error":  status.LastError,
+	}, c.ClientIP(), c.Request.UserAgent(), "")
+
+	if err != nil {
+		// The job ran but failed; its status carries the error
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error(), "data": status})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Job completed successfully",
+		"data":    status,
+	})
+}
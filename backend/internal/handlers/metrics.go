@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"time"
@@ -20,6 +21,7 @@ type SystemMetrics struct {
 	Disk      DiskInfo  `json:"disk"`
 	Network   NetInfo   `json:"network"`
 	Uptime    string    `json:"uptime"`
+	Errors    map[string]string `json:"errors,omitempty"` // per-section collection errors
 }
 
 // CPUInfo represents CPU usage information
@@ -83,9 +85,17 @@ type NetInterface struct {
 
 var startTime = time.Now()
 
+// Section collectors used by collectSystemMetrics, replaceable in tests
+var (
+	cpuMetricsCollector     = collectCPUMetrics
+	memoryMetricsCollector  = collectMemoryMetrics
+	diskMetricsCollector    = collectDiskMetrics
+	networkMetricsCollector = collectNetworkMetrics
+)
+
 // GetSystemMetricsHandler returns comprehensive system metrics
 func GetSystemMetricsHandler(c *gin.Context) {
-	metrics, err := collectSystemMetrics()
+	metrics, err := collectSystemMetrics(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to collect system metrics",
@@ -94,15 +104,16 @@ func GetSystemMetricsHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    metrics,
+	c.JSON(http.StatusOK, SystemMetricsResponse{
+		Success: true,
+		Partial: len(metrics.Errors) > 0,
+		Data:    metrics,
 	})
 }
 
 // GetCPUMetricsHandler returns CPU-specific metrics
 func GetCPUMetricsHandler(c *gin.Context) {
-	cpuInfo, err := collectCPUMetrics()
+	cpuInfo, err := collectCPUMetrics(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to collect CPU metrics",
@@ -111,15 +122,15 @@ func GetCPUMetricsHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    cpuInfo,
+	c.JSON(http.StatusOK, CPUMetricsResponse{
+		Success: true,
+		Data:    cpuInfo,
 	})
 }
 
 // GetMemoryMetricsHandler returns memory-specific metrics
 func GetMemoryMetricsHandler(c *gin.Context) {
-	memInfo, err := collectMemoryMetrics()
+	memInfo, err := collectMemoryMetrics(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to collect memory metrics",
@@ -128,15 +139,15 @@ func GetMemoryMetricsHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    memInfo,
+	c.JSON(http.StatusOK, MemoryMetricsResponse{
+		Success: true,
+		Data:    memInfo,
 	})
 }
 
 // GetDiskMetricsHandler returns disk-specific metrics
 func GetDiskMetricsHandler(c *gin.Context) {
-	diskInfo, err := collectDiskMetrics()
+	diskInfo, err := collectDiskMetrics(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to collect disk metrics",
@@ -145,15 +156,15 @@ func GetDiskMetricsHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    diskInfo,
+	c.JSON(http.StatusOK, DiskMetricsResponse{
+		Success: true,
+		Data:    diskInfo,
 	})
 }
 
 // GetNetworkMetricsHandler returns network-specific metrics
 func GetNetworkMetricsHandler(c *gin.Context) {
-	netInfo, err := collectNetworkMetrics()
+	netInfo, err := collectNetworkMetrics(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "Failed to collect network metrics",
@@ -162,50 +173,61 @@ func GetNetworkMetricsHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data":    netInfo,
+	c.JSON(http.StatusOK, NetworkMetricsResponse{
+		Success: true,
+		Data:    netInfo,
 	})
 }
 
-// collectSystemMetrics collects all system metrics
-func collectSystemMetrics() (*SystemMetrics, error) {
-	cpuInfo, err := collectCPUMetrics()
-	if err != nil {
-		return nil, fmt.Errorf("failed to collect CPU metrics: %w", err)
+// collectSystemMetrics collects all system metrics. Sections that fail are reported
+// in Errors instead of aborting the whole collection; an error is returned only
+// when no section could be collected.
+func collectSystemMetrics(ctx context.Context) (*SystemMetrics, error) {
+	metrics := &SystemMetrics{
+		Timestamp: time.Now(),
+		Uptime:    time.Since(startTime).String(),
 	}
+	errors := make(map[string]string)
 
-	memInfo, err := collectMemoryMetrics()
-	if err != nil {
-		return nil, fmt.Errorf("failed to collect memory metrics: %w", err)
+	if cpuInfo, err := cpuMetricsCollector(ctx); err != nil {
+		errors["cpu"] = err.Error()
+	} else {
+		metrics.CPU = *cpuInfo
 	}
 
-	diskInfo, err := collectDiskMetrics()
-	if err != nil {
-		return nil, fmt.Errorf("failed to collect disk metrics: %w", err)
+	if memInfo, err := memoryMetricsCollector(ctx); err != nil {
+		errors["memory"] = err.Error()
+	} else {
+		metrics.Memory = *memInfo
 	}
 
-	netInfo, err := collectNetworkMetrics()
-	if err != nil {
-		return nil, fmt.Errorf("failed to collect network metrics: %w", err)
+	if diskInfo, err := diskMetricsCollector(ctx); err != nil {
+		errors["disk"] = err.Error()
+	} else {
+		metrics.Disk = *diskInfo
+	}
+
+	if netInfo, err := networkMetricsCollector(ctx); err != nil {
+		errors["network"] = err.Error()
+	} else {
+		metrics.Network = *netInfo
 	}
 
-	uptime := time.Since(startTime)
+	if len(errors) == 4 {
+		return nil, fmt.Errorf("failed to collect any system metrics: %v", errors)
+	}
 
-	return &SystemMetrics{
-		Timestamp: time.Now(),
-		CPU:       *cpuInfo,
-		Memory:    *memInfo,
-		Disk:      *diskInfo,
-		Network:   *netInfo,
-		Uptime:    uptime.String(),
-	}, nil
+	if len(errors) > 0 {
+		metrics.Errors = errors
+	}
+
+	return metrics, nil
 }
 
 // collectCPUMetrics collects CPU usage information
-func collectCPUMetrics() (*CPUInfo, error) {
+func collectCPUMetrics(ctx context.Context) (*CPUInfo, error) {
 	// Get CPU usage percentage
-	percentages, err := cpu.Percent(time.Second, false)
+	percentages, err := cpu.PercentWithContext(ctx, time.Second, false)
 	if err != nil {
 		return nil, err
 	}
@@ -216,7 +238,7 @@ func collectCPUMetrics() (*CPUInfo, error) {
 	}
 
 	// Get CPU count
-	cpuCount, err := cpu.Counts(true)
+	cpuCount, err := cpu.CountsWithContext(ctx, true)
 	if err != nil {
 		return nil, err
 	}
@@ -234,13 +256,13 @@ func collectCPUMetrics() (*CPUInfo, error) {
 }
 
 // collectMemoryMetrics collects memory usage information
-func collectMemoryMetrics() (*MemInfo, error) {
-	vmStat, err := mem.VirtualMemory()
+func collectMemoryMetrics(ctx context.Context) (*MemInfo, error) {
+	vmStat, err := mem.VirtualMemoryWithContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	swapStat, err := mem.SwapMemory()
+	swapStat, err := mem.SwapMemoryWithContext(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -259,15 +281,15 @@ func collectMemoryMetrics() (*MemInfo, error) {
 }
 
 // collectDiskMetrics collects disk usage information
-func collectDiskMetrics() (*DiskInfo, error) {
+func collectDiskMetrics(ctx context.Context) (*DiskInfo, error) {
 	// Get root partition usage
-	usage, err := disk.Usage("/")
+	usage, err := disk.UsageWithContext(ctx, "/")
 	if err != nil {
 		return nil, err
 	}
 
 	// Get all disk partitions
-	partitions, err := disk.Partitions(false)
+	partitions, err := disk.PartitionsWithContext(ctx, false)
 	if err != nil {
 		return nil, err
 	}
@@ -279,7 +301,7 @@ func collectDiskMetrics() (*DiskInfo, error) {
 			continue
 		}
 
-		deviceUsage, err := disk.Usage(partition.Mountpoint)
+		deviceUsage, err := disk.UsageWithContext(ctx, partition.Mountpoint)
 		if err != nil {
 			continue // Skip devices we can't read
 		}
@@ -305,9 +327,9 @@ func collectDiskMetrics() (*DiskInfo, error) {
 }
 
 // collectNetworkMetrics collects network statistics
-func collectNetworkMetrics() (*NetInfo, error) {
+func collectNetworkMetrics(ctx context.Context) (*NetInfo, error) {
 	// Get network I/O counters
-	ioCounters, err := net.IOCounters(true)
+	ioCounters, err := net.IOCountersWithContext(ctx, true)
 	if err != nil {
 		return nil, err
 	}
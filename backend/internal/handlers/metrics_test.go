@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// stubMetricsCollectors replaces the section collectors for the duration of a test
+func stubMetricsCollectors(t *testing.T, failNetwork bool) {
+	origCPU, origMem, origDisk, origNet := cpuMetricsCollector, memoryMetricsCollector, diskMetricsCollector, networkMetricsCollector
+	t.Cleanup(func() {
+		cpuMetricsCollector, memoryMetricsCollector, diskMetricsCollector, networkMetricsCollector = origCPU, origMem, origDisk, origNet
+	})
+
+	cpuMetricsCollector = func(context.Context) (*CPUInfo, error) { return &CPUInfo{Usage: 42, Count: 4}, nil }
+	memoryMetricsCollector = func(context.Context) (*MemInfo, error) { return &MemInfo{Total: 1024}, nil }
+	diskMetricsCollector = func(context.Context) (*DiskInfo, error) { return &DiskInfo{Total: 2048}, nil }
+	networkMetricsCollector = func(context.Context) (*NetInfo, error) {
+		if failNetwork {
+			return nil, errors.New("network unavailable")
+		}
+		return &NetInfo{BytesSent: 1}, nil
+	}
+}
+
+func TestCollectSystemMetrics_PartialFailure(t *testing.T) {
+	stubMetricsCollectors(t, true)
+
+	metrics, err := collectSystemMetrics(context.Background())
+	if err != nil {
+		t.Fatalf("Expected partial metrics, got error: %v", err)
+	}
+
+	if metrics.CPU.Usage != 42 || metrics.Memory.Total != 1024 || metrics.Disk.Total != 2048 {
+		t.Errorf("Expected successful sections to be populated, got %+v", metrics)
+	}
+
+	if metrics.Errors["network"] != "network unavailable" {
+		t.Errorf("Expected network error flag, got %v", metrics.Errors)
+	}
+
+	if len(metrics.Errors) != 1 {
+		t.Errorf("Expected exactly 1 section error, got %v", metrics.Errors)
+	}
+}
+
+func TestCollectSystemMetrics_AllFail(t *testing.T) {
+	stubMetricsCollectors(t, true)
+	cpuMetricsCollector = func(context.Context) (*CPUInfo, error) { return nil, errors.New("cpu unavailable") }
+	memoryMetricsCollector = func(context.Context) (*MemInfo, error) { return nil, errors.New("memory unavailable") }
+	diskMetricsCollector = func(context.Context) (*DiskInfo, error) { return nil, errors.New("disk unavailable") }
+
+	if _, err := collectSystemMetrics(context.Background()); err == nil {
+		t.Error("Expected error when every section fails")
+	}
+}
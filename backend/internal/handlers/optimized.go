@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"golangmcp/internal/models"
 	"golangmcp/internal/db"
+	"golangmcp/internal/services"
 )
 
 // OptimizedHandlers provides optimized handlers for better performance
@@ -45,14 +46,14 @@ func (oh *OptimizedHandlers) GetUsersOptimizedHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	RespondWithETag(c, gin.H{
 		"data": users,
 		"pagination": gin.H{
 			"limit":  limit,
 			"offset": offset,
 			"count":  len(users),
 		},
-	})
+	}, "users")
 }
 
 // GetFilesOptimizedHandler handles optimized file retrieval
@@ -88,14 +89,14 @@ func (oh *OptimizedHandlers) GetFilesOptimizedHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	RespondWithETag(c, gin.H{
 		"data": files,
 		"pagination": gin.H{
 			"limit":  limit,
 			"offset": offset,
 			"count":  len(files),
 		},
-	})
+	}, "files")
 }
 
 // SearchFilesOptimizedHandler handles optimized file search
@@ -207,25 +208,47 @@ func (oh *OptimizedHandlers) BatchUploadFilesHandler(c *gin.Context) {
 // GetDatabasePerformanceStatsHandler returns database performance statistics
 func (oh *OptimizedHandlers) GetDatabasePerformanceStatsHandler(c *gin.Context) {
 	optimizer := models.NewDatabaseOptimizer(db.DB)
-	stats, err := optimizer.GetQueryPerformanceStats()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch performance stats"})
-		return
-	}
+	stats := optimizer.GetQueryPerformanceStats()
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": stats,
 	})
 }
 
-// CleanupOldDataHandler handles cleanup of old data
-func (oh *OptimizedHandlers) CleanupOldDataHandler(c *gin.Context) {
-	optimizer := models.NewDatabaseOptimizer(db.DB)
-	err := optimizer.CleanupOldData()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cleanup old data"})
+// GetDatabaseStatsConfigHandler returns which tables the database performance
+// stats inspect (admin only)
+func GetDatabaseStatsConfigHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": models.GlobalQueryStatsCollector.GetConfig(),
+	})
+}
+
+// UpdateDatabaseStatsConfigHandler replaces the database performance stats
+// configuration (admin only)
+func UpdateDatabaseStatsConfigHandler(c *gin.Context) {
+	var config models.QueryStatsConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := models.GlobalQueryStatsCollector.UpdateConfig(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Old data cleanup completed successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Database stats configuration updated successfully",
+		"data":    config,
+	})
+}
+
+// CleanupOldDataHandler handles cleanup of old data according to the retention policy
+func (oh *OptimizedHandlers) CleanupOldDataHandler(c *gin.Context) {
+	removed := services.GlobalRetentionManager.RunCleanup(db.DB)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Old data cleanup completed successfully",
+		"removed": removed,
+	})
 }
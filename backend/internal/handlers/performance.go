@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 	"time"
 
@@ -13,17 +15,39 @@ import (
 
 // PerformanceHandlers provides handlers for performance optimization features
 type PerformanceHandlers struct {
-	cacheService      *services.CacheService
-	paginationService *services.PaginationService
-	rateLimitManager  *services.RateLimitManager
-	cacheManager      *services.CacheManager
+	cacheService       *services.CacheService
+	paginationService  *services.PaginationService
+	paginationAnalyzer *services.PaginationAnalyzer
+	rateLimitManager   *services.RateLimitManager
+	cacheManager       *services.CacheManager
+	cacheTTLs          *services.CacheTTLManager
 }
 
-// NewPerformanceHandlers creates new performance handlers
+// NewPerformanceHandlers creates new performance handlers with the default cache TTLs
 func NewPerformanceHandlers() *PerformanceHandlers {
+	ph, _ := NewPerformanceHandlersWithTTLs(15*time.Minute, services.DefaultCacheTTLs())
+	return ph
+}
+
+// NewPerformanceHandlersWithTTLs creates new performance handlers caching for
+// defaultTTL, and for the given TTL on each cached endpoint
+func NewPerformanceHandlersWithTTLs(defaultTTL time.Duration, endpointTTLs map[string]time.Duration) (*PerformanceHandlers, error) {
+	cacheTTLs := services.NewCacheTTLManager()
+	for endpoint, ttl := range endpointTTLs {
+		if err := cacheTTLs.SetTTL(endpoint, ttl); err != nil {
+			return nil, fmt.Errorf("cache endpoint %s: %w", endpoint, err)
+		}
+	}
+
 	// Initialize services
-	cacheService := services.NewCacheService(15 * time.Minute)
-	paginationService := services.NewPaginationService(20, 100)
+	cacheService := services.NewCacheService(defaultTTL)
+	paginationConfig := services.DefaultPaginationConfig()
+	paginationService := services.NewPaginationService(paginationConfig.DefaultPageSize, paginationConfig.MaxPageSize)
+	for role, pageSize := range paginationConfig.RoleDefaultPageSizes {
+		paginationService.SetRoleDefaultPageSize(role, pageSize)
+	}
+	paginationAnalyzer := services.NewPaginationAnalyzer()
+	paginationService.SetAnalyzer(paginationAnalyzer)
 	rateLimitManager := services.NewRateLimitManager()
 	cacheManager := services.NewCacheManager()
 	
@@ -34,23 +58,44 @@ func NewPerformanceHandlers() *PerformanceHandlers {
 	}
 	
 	return &PerformanceHandlers{
-		cacheService:      cacheService,
-		paginationService: paginationService,
-		rateLimitManager:  rateLimitManager,
-		cacheManager:      cacheManager,
+		cacheService:       cacheService,
+		paginationService:  paginationService,
+		paginationAnalyzer: paginationAnalyzer,
+		rateLimitManager:   rateLimitManager,
+		cacheManager:       cacheManager,
+		cacheTTLs:          cacheTTLs,
+	}, nil
+}
+
+// CleanupInterval is how often RegisterCleanupJobs runs the cache and rate limit cleanups
+const CleanupInterval = 5 * time.Minute
+
+// RegisterCleanupJobs schedules removal of expired cache items and old rate limit entries
+func (ph *PerformanceHandlers) RegisterCleanupJobs(scheduler *services.Scheduler) error {
+	err := scheduler.Register("cache_cleanup", services.FixedInterval(CleanupInterval), func() (interface{}, error) {
+		removed := ph.cacheService.CleanupExpired() + ph.cacheManager.CleanupExpired()
+		return gin.H{"removed_items": removed}, nil
+	})
+	if err != nil {
+		return err
 	}
+
+	return scheduler.Register("rate_limit_cleanup", services.FixedInterval(CleanupInterval), func() (interface{}, error) {
+		ph.rateLimitManager.Cleanup()
+		return nil, nil
+	})
 }
 
 // GetUsersWithCacheHandler retrieves users with caching
 func (ph *PerformanceHandlers) GetUsersWithCacheHandler(c *gin.Context) {
 	// Parse pagination
 	pageStr := c.DefaultQuery("page", "1")
-	pageSizeStr := c.DefaultQuery("page_size", "20")
+	pageSizeStr := c.Query("page_size")
 	
-	paginationReq := ph.paginationService.ParsePaginationRequest(pageStr, pageSizeStr)
+	paginationReq := ph.paginationService.ParsePaginationRequestForRole(c.GetString("role"), pageStr, pageSizeStr)
 	
 	// Generate cache key
-	cacheKey := ph.generateCacheKey("users", map[string]string{
+	cacheKey := ph.generateCacheKey(services.CacheEndpointUsers, map[string]string{
 		"page":      strconv.Itoa(paginationReq.Page),
 		"page_size": strconv.Itoa(paginationReq.PageSize),
 	})
@@ -83,7 +128,7 @@ func (ph *PerformanceHandlers) GetUsersWithCacheHandler(c *gin.Context) {
 	}
 	
 	// Cache the response
-	ph.cacheService.Set(cacheKey, response, 5*time.Minute)
+	ph.cacheService.Set(cacheKey, response, ph.cacheTTLs.GetTTL(services.CacheEndpointUsers))
 	
 	c.JSON(http.StatusOK, response)
 }
@@ -92,14 +137,14 @@ func (ph *PerformanceHandlers) GetUsersWithCacheHandler(c *gin.Context) {
 func (ph *PerformanceHandlers) GetFilesWithCacheHandler(c *gin.Context) {
 	// Parse pagination
 	pageStr := c.DefaultQuery("page", "1")
-	pageSizeStr := c.DefaultQuery("page_size", "20")
+	pageSizeStr := c.Query("page_size")
 	fileType := c.Query("type")
 	userIDStr := c.Query("user_id")
 	
-	paginationReq := ph.paginationService.ParsePaginationRequest(pageStr, pageSizeStr)
+	paginationReq := ph.paginationService.ParsePaginationRequestForRole(c.GetString("role"), pageStr, pageSizeStr)
 	
 	// Generate cache key
-	cacheKey := ph.generateCacheKey("files", map[string]string{
+	cacheKey := ph.generateCacheKey(services.CacheEndpointFiles, map[string]string{
 		"page":      strconv.Itoa(paginationReq.Page),
 		"page_size": strconv.Itoa(paginationReq.PageSize),
 		"type":      fileType,
@@ -151,7 +196,7 @@ func (ph *PerformanceHandlers) GetFilesWithCacheHandler(c *gin.Context) {
 	}
 	
 	// Cache the response
-	ph.cacheService.Set(cacheKey, response, 2*time.Minute)
+	ph.cacheService.Set(cacheKey, response, ph.cacheTTLs.GetTTL(services.CacheEndpointFiles))
 	
 	c.JSON(http.StatusOK, response)
 }
@@ -159,12 +204,54 @@ func (ph *PerformanceHandlers) GetFilesWithCacheHandler(c *gin.Context) {
 // GetCacheStatsHandler returns cache statistics
 func (ph *PerformanceHandlers) GetCacheStatsHandler(c *gin.Context) {
 	stats := ph.cacheService.GetStats()
+	stats["endpoint_ttls"] = ph.cacheTTLs.GetAllTTLs()
 	
 	c.JSON(http.StatusOK, gin.H{
 		"data": stats,
 	})
 }
 
+// GetCacheTTLsHandler returns the cache TTL of each cached endpoint (Admin only)
+func (ph *PerformanceHandlers) GetCacheTTLsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": ph.cacheTTLs.GetAllTTLs(),
+	})
+}
+
+// UpdateCacheTTLHandler sets the cache TTL of an endpoint (Admin only). Responses
+// the endpoint already cached are dropped, so the new TTL applies right away.
+func (ph *PerformanceHandlers) UpdateCacheTTLHandler(c *gin.Context) {
+	var request struct {
+		Endpoint string `json:"endpoint" binding:"required"`
+		TTL      string `json:"ttl" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ttl, err := time.ParseDuration(request.TTL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ttl duration"})
+		return
+	}
+
+	if err := ph.cacheTTLs.SetTTL(request.Endpoint, ttl); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ph.cacheService.DeletePrefix(request.Endpoint + ":")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cache TTL updated successfully",
+		"data": gin.H{
+			"endpoint": request.Endpoint,
+			"ttl":      ttl.String(),
+		},
+	})
+}
+
 // ClearCacheHandler clears the cache
 func (ph *PerformanceHandlers) ClearCacheHandler(c *gin.Context) {
 	ph.cacheService.Clear()
@@ -233,14 +320,16 @@ func (ph *PerformanceHandlers) UpdateRateLimitConfigHandler(c *gin.Context) {
 
 // GetPaginationStatsHandler returns pagination statistics
 func (ph *PerformanceHandlers) GetPaginationStatsHandler(c *gin.Context) {
-	// This would return pagination usage statistics
-	// For now, return basic configuration
-	config := services.DefaultPaginationConfig()
-	
+	stats := ph.paginationAnalyzer.GetStats()
+
 	c.JSON(http.StatusOK, gin.H{
 		"data": gin.H{
-			"default_page_size": config.DefaultPageSize,
-			"max_page_size":     config.MaxPageSize,
+			"total_requests":          stats.TotalRequests,
+			"average_page_size":       stats.AveragePageSize,
+			"most_used_page_size":     stats.MostUsedPageSize,
+			"default_page_size":       ph.paginationService.DefaultPageSizeForRole(c.GetString("role")),
+			"max_page_size":           ph.paginationService.GetMaxPageSize(),
+			"role_default_page_sizes": ph.paginationService.GetRoleDefaultPageSizes(),
 		},
 	})
 }
@@ -327,11 +416,18 @@ func (ph *PerformanceHandlers) testRateLimitPerformance(c *gin.Context) {
 	})
 }
 
-// generateCacheKey generates a cache key from parameters
+// generateCacheKey generates a cache key from parameters, in a stable order so
+// the same request always maps to the same key
 func (ph *PerformanceHandlers) generateCacheKey(base string, params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for k := range params {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
 	key := base
-	for k, v := range params {
-		if v != "" {
+	for _, k := range names {
+		if v := params[k]; v != "" {
 			key += ":" + k + "=" + v
 		}
 	}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+)
+
+func TestGetPaginationStatsHandler_ReportsRecordedRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	ph := NewPerformanceHandlers()
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("role", c.GetHeader("X-Test-Role"))
+		c.Next()
+	})
+	r.GET("/api/performance/users", ph.GetUsersWithCacheHandler)
+	r.GET("/api/performance/pagination/stats", ph.GetPaginationStatsHandler)
+
+	requests := []struct {
+		role  string
+		query string
+	}{
+		{"user", "?page_size=10"},
+		{"user", "?page_size=10&page=2"},
+		{"user", "?page_size=10"}, // served from cache, still recorded
+		{"user", ""},              // role default of 20
+		{"admin", ""},             // role default of 50
+	}
+	for _, tt := range requests {
+		req := httptest.NewRequest(http.MethodGet, "/api/performance/users"+tt.query, nil)
+		req.Header.Set("X-Test-Role", tt.role)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 for %q, got %d: %s", tt.query, w.Code, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/performance/pagination/stats", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var resp struct {
+		Data struct {
+			TotalRequests    int64   `json:"total_requests"`
+			AveragePageSize  float64 `json:"average_page_size"`
+			MostUsedPageSize int     `json:"most_used_page_size"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	if resp.Data.TotalRequests != 5 {
+		t.Errorf("Expected 5 requests, got %d", resp.Data.TotalRequests)
+	}
+	if resp.Data.AveragePageSize != 20 { // (10*3 + 20 + 50) / 5
+		t.Errorf("Expected average page size 20, got %v", resp.Data.AveragePageSize)
+	}
+	if resp.Data.MostUsedPageSize != 10 {
+		t.Errorf("Expected most used page size 10, got %d", resp.Data.MostUsedPageSize)
+	}
+}
+
+// newCacheTTLRouter returns a router serving the cached users endpoint and the cache TTL admin endpoints
+func newCacheTTLRouter(ph *PerformanceHandlers) *gin.Engine {
+	r := gin.New()
+	r.GET("/api/performance/users", ph.GetUsersWithCacheHandler)
+	r.GET("/api/performance/cache/stats", ph.GetCacheStatsHandler)
+	r.PUT("/api/performance/cache/ttl", ph.UpdateCacheTTLHandler)
+	return r
+}
+
+// cachedUserTotal requests the cached users endpoint and returns the reported total
+func cachedUserTotal(t *testing.T, r *gin.Engine) int64 {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/performance/users?page_size=10", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Pagination struct {
+			TotalItems int64 `json:"total_items"`
+		} `json:"pagination"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return resp.Pagination.TotalItems
+}
+
+func TestUpdateCacheTTLHandler_ChangesExpiry(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	ph := NewPerformanceHandlers()
+	r := newCacheTTLRouter(ph)
+
+	createUser := func(name string) {
+		user := &models.User{Username: name, Email: name + "@example.com", Password: "password123", Role: "user"}
+		if err := user.Create(db.DB); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+	createUser("first")
+
+	// With the default TTL of 5 minutes a new user stays hidden
+	if total := cachedUserTotal(t, r); total != 1 {
+		t.Fatalf("Expected 1 user, got %d", total)
+	}
+	createUser("second")
+	if total := cachedUserTotal(t, r); total != 1 {
+		t.Fatalf("Expected the cached total of 1, got %d", total)
+	}
+
+	// Updating the TTL drops the cached response and applies to the next one
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/performance/cache/ttl", strings.NewReader(`{"endpoint":"users","ttl":"1s"}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if total := cachedUserTotal(t, r); total != 2 {
+		t.Fatalf("Expected 2 users once the cache was dropped, got %d", total)
+	}
+
+	createUser("third")
+	if total := cachedUserTotal(t, r); total != 2 {
+		t.Fatalf("Expected the cached total of 2 within the TTL, got %d", total)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if total := cachedUserTotal(t, r); total != 3 {
+		t.Errorf("Expected 3 users after the 1s TTL expired, got %d", total)
+	}
+
+	// The stats report the current TTLs
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/performance/cache/stats", nil))
+	var stats struct {
+		Data struct {
+			EndpointTTLs map[string]string `json:"endpoint_ttls"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if stats.Data.EndpointTTLs["users"] != "1s" || stats.Data.EndpointTTLs["files"] != "2m0s" {
+		t.Errorf("Unexpected endpoint TTLs: %v", stats.Data.EndpointTTLs)
+	}
+}
+
+func TestUpdateCacheTTLHandler_RejectsInvalidUpdates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := newCacheTTLRouter(NewPerformanceHandlers())
+	for _, body := range []string{
+		`{"endpoint":"sessions","ttl":"1m"}`,
+		`{"endpoint":"users","ttl":"soon"}`,
+		`{"endpoint":"users","ttl":"500ms"}`,
+		`{"endpoint":"users","ttl":"48h"}`,
+		`{"endpoint":"users"}`,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/api/performance/cache/ttl", strings.NewReader(body)))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", body, w.Code)
+		}
+	}
+}
@@ -38,11 +38,8 @@ func GetProfileHandler(c *gin.Context) {
 		return
 	}
 
-	// Clear password from response
-	user.Password = ""
-
-	c.JSON(http.StatusOK, gin.H{
-		"user": user,
+	c.JSON(http.StatusOK, ProfileResponse{
+		User: NewUserResponse(user),
 	})
 }
 
@@ -149,24 +146,17 @@ func ChangePasswordHandler(c *gin.Context) {
 		return
 	}
 
-	// Validate new password
-	if len(req.NewPassword) < 8 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "New password must be at least 8 characters"})
-		return
-	}
-
-	// Hash new password
-	hashedPassword, err := auth.HashPassword(req.NewPassword)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
-		return
-	}
-
-	// Update password
-	user.Password = hashedPassword
-	err = user.Update(db.DB)
+	// Validate, check reuse against the password history and update
+	err = auth.SetUserPassword(db.DB, &user, req.NewPassword)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		switch err {
+		case models.ErrInvalidPassword:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "New password must be at least 8 characters"})
+		case auth.ErrPasswordReused:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		}
 		return
 	}
 
@@ -199,6 +189,71 @@ func GetUserProfileHandler(c *gin.Context) {
 	})
 }
 
+// MaxActivityPageSize is the most activity entries returned per page
+const MaxActivityPageSize = 200
+
+// GetMyActivityHandler returns the current user's activity feed
+func GetMyActivityHandler(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	respondUserActivity(c, userID.(uint))
+}
+
+// GetUserActivityHandler returns a user's activity feed for investigations
+// (admin only). It exposes the user's audit logs, so it is gated by admin.security.
+func GetUserActivityHandler(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var user models.User
+	if err := user.GetByID(db.DB, uint(userID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	respondUserActivity(c, user.ID)
+}
+
+// respondUserActivity responds with a page of a user's logins and other audit
+// events, file accesses and commands, merged and most recent first
+func respondUserActivity(c *gin.Context, userID uint) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+	if limit > MaxActivityPageSize {
+		limit = MaxActivityPageSize
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	entries, total, err := models.GetUserActivity(db.DB, userID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user activity"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": entries,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(entries),
+			"total":  total,
+		},
+	})
+}
+
 // UpdateUserProfileHandler updates a specific user's profile (admin only)
 func UpdateUserProfileHandler(c *gin.Context) {
 	userIDStr := c.Param("id")
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+)
+
+// setupTestPasswordPolicy replaces the global password policy for the duration of a test
+func setupTestPasswordPolicy(t *testing.T, historySize int) {
+	origPolicy := auth.GlobalPasswordPolicy
+	auth.GlobalPasswordPolicy = auth.NewPasswordPolicyManager()
+	auth.GlobalPasswordPolicy.UpdatePolicy(&auth.PasswordPolicy{HistorySize: historySize})
+	t.Cleanup(func() { auth.GlobalPasswordPolicy = origPolicy })
+}
+
+func TestChangePasswordHandler_RejectsPreviouslyUsedPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	setupTestPasswordPolicy(t, 2)
+
+	hashedPassword, _ := auth.HashPassword("password-0")
+	user := &models.User{Username: "testuser", Email: "test@example.com", Password: hashedPassword, Role: "user"}
+	if err := user.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/profile/change-password", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		c.Next()
+	}, ChangePasswordHandler)
+
+	changePassword := func(current, next string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(ChangePasswordRequest{CurrentPassword: current, NewPassword: next})
+		req := httptest.NewRequest(http.MethodPost, "/profile/change-password", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	// The current password cannot be reused
+	if w := changePassword("password-0", "password-0"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected reuse of current password to be rejected, got %d", w.Code)
+	}
+
+	for i, next := range []string{"password-1", "password-2"} {
+		current := "password-" + string(rune('0'+i))
+		if w := changePassword(current, next); w.Code != http.StatusOK {
+			t.Fatalf("Expected change to %s to succeed, got %d: %s", next, w.Code, w.Body.String())
+		}
+	}
+
+	// password-0 and password-1 are within the last 2 passwords
+	for _, previous := range []string{"password-0", "password-1"} {
+		w := changePassword("password-2", previous)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected reuse of %s to be rejected, got %d", previous, w.Code)
+		}
+	}
+
+	// Moving on twice pushes password-0 out of the history
+	if w := changePassword("password-2", "password-3"); w.Code != http.StatusOK {
+		t.Fatalf("Expected change to succeed, got %d", w.Code)
+	}
+	if w := changePassword("password-3", "password-0"); w.Code != http.StatusOK {
+		t.Errorf("Expected password outside the history to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+
+	history, _ := models.GetPasswordHistory(db.DB, user.ID, 10)
+	if len(history) != 2 {
+		t.Errorf("Expected history to be purged to 2 entries, got %d", len(history))
+	}
+}
+
+// getActivity requests an activity feed as the given user
+func getActivity(userID uint, path string) *httptest.ResponseRecorder {
+	r := gin.New()
+	setUser := func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}
+	r.GET("/me/activity", setUser, GetMyActivityHandler)
+	r.GET("/admin/users/:id/activity", setUser, GetUserActivityHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	return w
+}
+
+func TestUserActivity_InterleavesSources(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	user := &models.User{Username: "active", Email: "active@example.com", Password: "password123", Role: "user"}
+	other := &models.User{Username: "other", Email: "other@example.com", Password: "password123", Role: "user"}
+	for _, u := range []*models.User{user, other} {
+		if err := u.Create(db.DB); err != nil {
+			t.Fatalf("Failed to create user: %v", err)
+		}
+	}
+
+	base := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return base.Add(time.Duration(minutes) * time.Minute) }
+	audit := func(userID uint, action string, minutes int) {
+		id := userID
+		entry := &models.SecurityAuditLog{UserID: &id, EventType: "auth", EventAction: action, Severity: "low", Status: "success", CreatedAt: at(minutes)}
+		if err := db.DB.Create(entry).Error; err != nil {
+			t.Fatalf("Failed to create audit log: %v", err)
+		}
+	}
+	access := func(userID uint, action string, minutes int) {
+		entry := &models.FileAccessLog{FileID: 7, UserID: userID, Action: action, Status: models.FileAccessSuccess, CreatedAt: at(minutes)}
+		if err := db.DB.Create(entry).Error; err != nil {
+			t.Fatalf("Failed to create file access log: %v", err)
+		}
+	}
+	command := func(userID uint, name string, exitCode, minutes int) {
+		entry := &models.Command{Command: name, UserID: userID, ExitCode: exitCode, CreatedAt: at(minutes)}
+		if err := db.DB.Create(entry).Error; err != nil {
+			t.Fatalf("Failed to create command: %v", err)
+		}
+	}
+
+	audit(user.ID, "login", 0)
+	access(user.ID, "upload", 1)
+	command(user.ID, "ls", 0, 2)
+	access(user.ID, "download", 3)
+	command(user.ID, "cat", 1, 4)
+	audit(user.ID, "logout", 5)
+	audit(other.ID, "login", 6)
+	access(other.ID, "download", 7)
+
+	w := getActivity(user.ID, "/me/activity")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Data       []models.ActivityEntry `json:"data"`
+		Pagination struct {
+			Total int64 `json:"total"`
+		} `json:"pagination"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	want := []struct{ source, action, status string }{
+		{models.ActivitySourceAudit, "auth.logout", "success"},
+		{models.ActivitySourceCommand, "cat", "failure"},
+		{models.ActivitySourceFile, "download", "success"},
+		{models.ActivitySourceCommand, "ls", "success"},
+		{models.ActivitySourceFile, "upload", "success"},
+		{models.ActivitySourceAudit, "auth.login", "success"},
+	}
+	if resp.Pagination.Total != int64(len(want)) || len(resp.Data) != len(want) {
+		t.Fatalf("Expected %d entries of the user only, got %d (total %d)", len(want), len(resp.Data), resp.Pagination.Total)
+	}
+	for i, w := range want {
+		got := resp.Data[i]
+		if got.Source != w.source || got.Action != w.action || got.Status != w.status {
+			t.Errorf("Entry %d: expected %s %s (%s), got %s %s (%s)", i, w.source, w.action, w.status, got.Source, got.Action, got.Status)
+		}
+	}
+
+	// Pages continue the same ordering
+	w2 := getActivity(other.ID, fmt.Sprintf("/admin/users/%d/activity?limit=2&offset=2", user.ID))
+	if w2.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w2.Code, w2.Body.String())
+	}
+	var page struct {
+		Data []models.ActivityEntry `json:"data"`
+	}
+	if err := json.Unmarshal(w2.Body.Bytes(), &page); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if len(page.Data) != 2 || page.Data[0].Action != "download" || page.Data[1].Action != "ls" {
+		t.Errorf("Expected the third and fourth entries, got %+v", page.Data)
+	}
+}
+
+func TestGetUserActivityHandler_UnknownUser(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	if w := getActivity(1, "/admin/users/999/activity"); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", w.Code)
+	}
+	if w := getActivity(1, "/admin/users/abc/activity"); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/security"
+	"golangmcp/internal/services"
+	"gorm.io/gorm"
+)
+
+// Error codes returned when a file's content cannot be served
+const (
+	ScanPendingCode     = "scan_pending"
+	FileQuarantinedCode = "file_quarantined"
+	FileTamperedCode    = "file_tampered"
+)
+
+// ensureFileScanned responds and returns false unless the scanner has marked the file safe.
+// Uploads stay blocked until the file_scan job has scanned them.
+func ensureFileScanned(c *gin.Context, file *models.File) bool {
+	if !file.IsScanned {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "File is awaiting a security scan, try again shortly",
+			"code":  ScanPendingCode,
+		})
+		return false
+	}
+
+	if file.IntegrityStatus == models.FileIntegrityMismatch || file.IntegrityStatus == models.FileIntegrityMissing {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "File content no longer matches what was uploaded",
+			"code":  FileTamperedCode,
+		})
+		return false
+	}
+
+	if !file.IsSafe {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":  "File has been quarantined because a threat was detected",
+			"code":   FileQuarantinedCode,
+			"threat": file.ScanResult,
+		})
+		return false
+	}
+
+	return true
+}
+
+// ListQuarantinedFilesHandler lists files quarantined by the scanner (Admin only)
+func ListQuarantinedFilesHandler(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	files, total, err := models.GetQuarantinedFiles(db.DB, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to retrieve quarantined files",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    NewFileResponses(files),
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(files),
+			"total":  total,
+		},
+	})
+}
+
+// ReleaseQuarantinedFileHandler moves a quarantined file back and makes it downloadable (Admin only)
+func ReleaseQuarantinedFileHandler(c *gin.Context) {
+	file, ok := getQuarantinedFile(c)
+	if !ok {
+		return
+	}
+
+	if err := services.GlobalFileScanManager.Release(db.DB, file); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to release file",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	adminID, _ := c.Get("user_id")
+	services.NewAuditLogger().LogQuarantineRelease(adminID.(uint), file.ID, file.ScanResult, c.ClientIP(), c.Request.UserAgent())
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "File released from quarantine",
+	})
+}
+
+// DeleteQuarantinedFileHandler deletes a quarantined file and its content (Admin only)
+func DeleteQuarantinedFileHandler(c *gin.Context) {
+	file, ok := getQuarantinedFile(c)
+	if !ok {
+		return
+	}
+
+	if err := services.GlobalFileScanManager.Delete(db.DB, file); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to delete file",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	adminID, _ := c.Get("user_id")
+	services.NewAuditLogger().LogQuarantineDelete(adminID.(uint), file.ID, file.ScanResult, c.ClientIP(), c.Request.UserAgent())
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Quarantined file deleted",
+	})
+}
+
+// VerifyFileHandler recomputes a file's hash and re-scans it, for incident
+// response after a scanner update. Only the owner or an admin may verify a file.
+func VerifyFileHandler(c *gin.Context) {
+	fileID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	file, err := models.GetFileByID(db.DB, uint(fileID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve file"})
+		}
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	userIDUint := userID.(uint)
+	role, _ := c.Get("role")
+	if file.UserID != userIDUint && role != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	verification, err := services.GlobalFileScanManager.Verify(db.DB, file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to verify file",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	logFileAccess(c, file.ID, userIDUint, "verify")
+	services.NewAuditLogger().LogFileVerification(userIDUint, verification, c.ClientIP(), c.Request.UserAgent(), security.RequestID(c))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    verification,
+	})
+}
+
+// getQuarantinedFile loads the quarantined file named by the :id parameter,
+// responding and returning false if there is none
+func getQuarantinedFile(c *gin.Context) (*models.File, bool) {
+	fileID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return nil, false
+	}
+
+	file, err := models.GetFileByID(db.DB, uint(fileID))
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve file"})
+		}
+		return nil, false
+	}
+
+	if file.QuarantinedAt == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": services.ErrFileNotQuarantined.Error()})
+		return nil, false
+	}
+
+	return file, true
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/services"
+)
+
+const eicarTestFile = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// setupTestFileScanManager replaces the global file scan manager with one quarantining into a temp directory
+func setupTestFileScanManager(t *testing.T) *services.FileScanManager {
+	orig := services.GlobalFileScanManager
+	t.Cleanup(func() { services.GlobalFileScanManager = orig })
+	services.GlobalFileScanManager = services.NewFileScanManager(filepath.Join(t.TempDir(), "quarantine"))
+	return services.GlobalFileScanManager
+}
+
+func TestDownloadFileHandler_BlockedUntilScannedSafe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	manager := setupTestFileScanManager(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	// A fresh upload starts unscanned and unsafe
+	path := filepath.Join(t.TempDir(), "invoice.txt")
+	if err := os.WriteFile(path, []byte(eicarTestFile), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	file := &models.File{Filename: "invoice.txt", OriginalName: "invoice.txt", FileType: "txt", MimeType: "text/plain",
+		Size: int64(len(eicarTestFile)), Path: path, Hash: "eicar", UserID: owner.ID}
+	if err := models.CreateFile(db.DB, file); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+
+	var response struct {
+		Code   string `json:"code"`
+		Threat string `json:"threat"`
+	}
+
+	w := downloadFile(owner.ID, file.ID, "")
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusConflict || response.Code != ScanPendingCode {
+		t.Fatalf("Expected an unscanned file to be blocked as pending, got %d: %s", w.Code, w.Body.String())
+	}
+
+	summary, err := manager.ScanPendingFiles(db.DB, 10)
+	if err != nil || len(summary.Quarantined) != 1 {
+		t.Fatalf("Expected the file to be quarantined, got %+v, %v", summary, err)
+	}
+
+	w = downloadFile(owner.ID, file.ID, "")
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusForbidden || response.Code != FileQuarantinedCode || response.Threat != "EICAR-Test-File" {
+		t.Fatalf("Expected a detected file to be blocked, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// Admins see the file in quarantine and can release it
+	admin := func(method, path string, handler gin.HandlerFunc, route string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.Handle(method, route, func(c *gin.Context) {
+			c.Set("user_id", uint(99))
+			c.Next()
+		}, handler)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w = admin(http.MethodGet, "/admin/files/quarantine", ListQuarantinedFilesHandler, "/admin/files/quarantine")
+	var list struct {
+		Data []FileResponse `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list.Data) != 1 || list.Data[0].ID != file.ID {
+		t.Fatalf("Expected the quarantined file to be listed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	releasePath := "/admin/files/quarantine/" + strconv.FormatUint(uint64(file.ID), 10) + "/release"
+	if w = admin(http.MethodPost, releasePath, ReleaseQuarantinedFileHandler, "/admin/files/quarantine/:id/release"); w.Code != http.StatusOK {
+		t.Fatalf("Expected release to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	if w = admin(http.MethodPost, releasePath, ReleaseQuarantinedFileHandler, "/admin/files/quarantine/:id/release"); w.Code != http.StatusConflict {
+		t.Errorf("Expected releasing a file twice to conflict, got %d", w.Code)
+	}
+
+	if w = downloadFile(owner.ID, file.ID, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected a released file to be downloadable, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDeleteQuarantinedFileHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	manager := setupTestFileScanManager(t)
+
+	path := filepath.Join(t.TempDir(), "payload.txt")
+	os.WriteFile(path, []byte(eicarTestFile), 0644)
+	file := &models.File{Filename: "payload.txt", OriginalName: "payload.txt", FileType: "txt", MimeType: "text/plain",
+		Path: path, Hash: "eicar", UserID: 1}
+	if err := models.CreateFile(db.DB, file); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	manager.ScanPendingFiles(db.DB, 10)
+	quarantined, _ := models.GetFileByID(db.DB, file.ID)
+
+	r := gin.New()
+	r.DELETE("/admin/files/quarantine/:id", func(c *gin.Context) {
+		c.Set("user_id", uint(99))
+		c.Next()
+	}, DeleteQuarantinedFileHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/files/quarantine/"+strconv.FormatUint(uint64(file.ID), 10), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected delete to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if _, err := models.GetFileByID(db.DB, file.ID); err == nil {
+		t.Error("Expected the file record to be deleted")
+	}
+	if _, err := os.Stat(quarantined.Path); !os.IsNotExist(err) {
+		t.Error("Expected the quarantined content to be removed")
+	}
+}
+
+func TestVerifyFileHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	manager := setupTestFileScanManager(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	content := "signed contract"
+	path := filepath.Join(t.TempDir(), "contract.txt")
+	os.WriteFile(path, []byte(content), 0644)
+	file := &models.File{Filename: "contract.txt", OriginalName: "contract.txt", FileType: "txt", MimeType: "text/plain",
+		Size: int64(len(content)), Path: path, Hash: fmt.Sprintf("%x", md5.Sum([]byte(content))), UserID: owner.ID}
+	if err := models.CreateFile(db.DB, file); err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	manager.ScanPendingFiles(db.DB, 10)
+
+	verify := func(userID uint, role string) *httptest.ResponseRecorder {
+		r := gin.New()
+		r.POST("/api/files/:id/verify", func(c *gin.Context) {
+			c.Set("user_id", userID)
+			c.Set("role", role)
+			c.Next()
+		}, VerifyFileHandler)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/files/"+strconv.FormatUint(uint64(file.ID), 10)+"/verify", nil))
+		return w
+	}
+	var response struct {
+		Data services.FileVerification `json:"data"`
+	}
+
+	if w := verify(owner.ID+1, "user"); w.Code != http.StatusForbidden {
+		t.Errorf("Expected another user to be denied, got %d", w.Code)
+	}
+	w := verify(owner.ID, "user")
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || response.Data.Integrity != models.FileIntegrityVerified || !response.Data.Safe {
+		t.Fatalf("Expected the owner to verify an intact file, got %d: %s", w.Code, w.Body.String())
+	}
+
+	// After tampering, an admin's verification blocks downloads
+	os.WriteFile(path, []byte("forged contract"), 0644)
+	w = verify(99, "admin")
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || response.Data.Integrity != models.FileIntegrityMismatch || response.Data.Safe {
+		t.Fatalf("Expected an admin to detect the tampering, got %d: %s", w.Code, w.Body.String())
+	}
+	w = downloadFile(owner.ID, file.ID, "")
+	var blocked struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &blocked)
+	if w.Code != http.StatusForbidden || blocked.Code != FileTamperedCode {
+		t.Errorf("Expected a tampered file to be blocked, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var logs []models.SecurityAuditLog
+	db.DB.Where("event_action = ?", "verify").Order("id").Find(&logs)
+	if len(logs) != 2 || logs[0].Status != "success" || logs[1].Status != "failure" {
+		t.Errorf("Expected both verifications to be audited, got %+v", logs)
+	}
+}
+
+func TestVerifyFileHandler_ImageUpload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	chdirTemp(t)
+	setupTestDB(t)
+	manager := setupTestFileScanManager(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	asOwner := func(c *gin.Context) {
+		c.Set("user_id", owner.ID)
+		c.Set("role", "user")
+		c.Next()
+	}
+	r := gin.New()
+	r.POST("/api/images/upload", asOwner, NewImageHandlers().UploadOptimizedImageHandler)
+	r.POST("/api/files/:id/verify", asOwner, VerifyFileHandler)
+
+	var img bytes.Buffer
+	png.Encode(&img, image.NewGray(image.Rect(0, 0, 16, 16)))
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	partHeader := textproto.MIMEHeader{}
+	partHeader.Set("Content-Disposition", `form-data; name="image"; filename="photo.png"`)
+	partHeader.Set("Content-Type", "image/png")
+	part, _ := writer.CreatePart(partHeader)
+	part.Write(img.Bytes())
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/images/upload", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var uploaded struct {
+		Data struct {
+			FileID uint `json:"file_id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &uploaded)
+	if w.Code != http.StatusOK || uploaded.Data.FileID == 0 {
+		t.Fatalf("Expected the image upload to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	manager.ScanPendingFiles(db.DB, 10)
+
+	// Image uploads don't store an md5 hash, so their content can't be compared
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/files/"+strconv.FormatUint(uint64(uploaded.Data.FileID), 10)+"/verify", nil))
+	var response struct {
+		Data services.FileVerification `json:"data"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if w.Code != http.StatusOK || response.Data.Integrity != models.FileIntegrityUnknown || !response.Data.Safe {
+		t.Fatalf("Expected an unknown integrity that keeps the image safe, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := downloadFile(owner.ID, uploaded.Data.FileID, ""); w.Code != http.StatusOK {
+		t.Errorf("Expected the verified image to stay downloadable, got %d: %s", w.Code, w.Body.String())
+	}
+}
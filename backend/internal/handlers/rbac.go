@@ -8,6 +8,8 @@ import (
 	"golangmcp/internal/authorization"
 	"golangmcp/internal/db"
 	"golangmcp/internal/models"
+	"golangmcp/internal/services"
+	"golangmcp/internal/session"
 )
 
 // RequirePermission is a convenience function that wraps authorization.RequirePermission
@@ -111,6 +113,7 @@ func AssignRoleHandler(c *gin.Context) {
 	}
 
 	// Update user role
+	oldRole := user.Role
 	user.Role = req.Role
 	err = user.Update(db.DB)
 	if err != nil {
@@ -118,6 +121,8 @@ func AssignRoleHandler(c *gin.Context) {
 		return
 	}
 
+	revokeSessionsOnRoleChange(c, user.ID, oldRole, user.Role)
+
 	// Clear password from response
 	user.Password = ""
 
@@ -255,6 +260,7 @@ func BulkRoleAssignmentHandler(c *gin.Context) {
 			continue
 		}
 
+		oldRole := user.Role
 		user.Role = req.Role
 		err = user.Update(db.DB)
 		if err != nil {
@@ -262,6 +268,8 @@ func BulkRoleAssignmentHandler(c *gin.Context) {
 			continue
 		}
 
+		revokeSessionsOnRoleChange(c, user.ID, oldRole, user.Role)
+
 		user.Password = "" // Clear password
 		updatedUsers = append(updatedUsers, user)
 	}
@@ -274,3 +282,17 @@ func BulkRoleAssignmentHandler(c *gin.Context) {
 		"failed_count":   len(failedUsers),
 	})
 }
+
+// revokeSessionsOnRoleChange forces a user to log in again after their role changed,
+// so tokens carrying the old role stop working. Controlled by SessionConfig.LogoutOnRoleChange.
+func revokeSessionsOnRoleChange(c *gin.Context, userID uint, oldRole, newRole string) {
+	if oldRole == newRole || !session.GlobalSessionManager.GetConfig().LogoutOnRoleChange {
+		return
+	}
+
+	revoked := session.GlobalSessionManager.RevokeUserTokens(userID)
+
+	adminID, _ := c.Get("user_id")
+	adminIDUint, _ := adminID.(uint)
+	services.NewAuditLogger().LogRoleChangeLogout(adminIDUint, userID, oldRole, newRole, revoked, c.ClientIP(), c.Request.UserAgent())
+}
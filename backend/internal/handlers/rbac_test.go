@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/session"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestDB points db.DB at a fresh in-memory database for the duration of a test
+func setupTestDB(t testing.TB) {
+	testDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	// Every connection to :memory: is a separate database, so keep to one
+	sqlDB, err := testDB.DB()
+	if err != nil {
+		t.Fatalf("Failed to get test database handle: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := models.RegisterTableVersionCallbacks(testDB); err != nil {
+		t.Fatalf("Failed to register table version callbacks: %v", err)
+	}
+
+	origDB := db.DB
+	db.DB = testDB
+	t.Cleanup(func() { db.DB = origDB })
+
+	if err := db.AutoMigrate(); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+}
+
+// setupTestSessionManager replaces the global session manager for the duration of a test
+func setupTestSessionManager(t *testing.T) *session.SessionManager {
+	origManager := session.GlobalSessionManager
+	session.GlobalSessionManager = session.NewSessionManager()
+	t.Cleanup(func() { session.GlobalSessionManager = origManager })
+	return session.GlobalSessionManager
+}
+
+func TestAssignRoleHandler_DemotionInvalidatesSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	sm := setupTestSessionManager(t)
+
+	admin := &models.User{Username: "admin", Email: "admin@example.com", Password: "password123", Role: "admin"}
+	target := &models.User{Username: "moderator", Email: "mod@example.com", Password: "password123", Role: "moderator"}
+	if err := admin.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	if err := target.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	secret := []byte("my_secret_key")
+	adminToken, _, _ := auth.GenerateJWT(admin, secret)
+	targetToken, _, _ := auth.GenerateJWT(target, secret)
+	if _, err := sm.CreateSession(target, targetToken, "127.0.0.1", "test-agent"); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	r := gin.New()
+	r.GET("/profile", AuthMiddleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.POST("/admin/users/:userId/role", AuthMiddleware(), RequirePermission("admin.users"), AssignRoleHandler)
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/users/2/role", bytes.NewBufferString(`{"role":"user"}`))
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if count := sm.CountUserSessions(target.ID); count != 0 {
+		t.Errorf("Expected demoted user's sessions to be invalidated, %d remain", count)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/profile", nil)
+	req.Header.Set("Authorization", "Bearer "+targetToken)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected old token to be rejected, got %d", w.Code)
+	}
+
+	var logs []models.SecurityAuditLog
+	db.DB.Where("event_action = ?", "revoke").Find(&logs)
+	if len(logs) != 1 {
+		t.Errorf("Expected 1 role change audit event, got %d", len(logs))
+	}
+}
+
+func TestAssignRoleHandler_KeepsSessionsWhenDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	sm := setupTestSessionManager(t)
+	sm.UpdateConfig(&session.SessionConfig{MaxSessionsPerUser: 5, LimitPolicy: session.LimitPolicyEvictOldest, LogoutOnRoleChange: false})
+
+	target := &models.User{Username: "moderator", Email: "mod@example.com", Password: "password123", Role: "moderator"}
+	if err := target.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	targetToken, _, _ := auth.GenerateJWT(target, []byte("my_secret_key"))
+	sm.CreateSession(target, targetToken, "127.0.0.1", "test-agent")
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/admin/users/1/role", bytes.NewBufferString(`{"role":"user"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "userId", Value: "1"}}
+	c.Set("user_id", uint(99))
+	c.Set("role", "admin")
+
+	AssignRoleHandler(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if count := sm.CountUserSessions(target.ID); count != 1 {
+		t.Errorf("Expected sessions to be kept, got %d", count)
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/security"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func deleteFile(userID, fileID uint, requestID string) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.Use(security.RequestIDMiddleware())
+	r.DELETE("/api/files/:id", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		c.Next()
+	}, DeleteFileHandler)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/files/"+strconv.FormatUint(uint64(fileID), 10), nil)
+	if requestID != "" {
+		req.Header.Set(security.RequestIDHeader, requestID)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestDeleteFileHandler_CorrelatesAccessAndAuditLogs(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		requestID string // sent by the client, "" lets the middleware generate one
+	}{
+		{"propagated", "req-0123.abc_DEF"},
+		{"generated", ""},
+		{"malformed replaced", "bad id; drop table"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := createTestFile(t, owner.ID, "delete-"+strconv.Itoa(i)+".txt", false)
+
+			w := deleteFile(owner.ID, file.ID, tt.requestID)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			requestID := w.Header().Get(security.RequestIDHeader)
+			if requestID == "" {
+				t.Fatal("Expected the response to carry the request ID")
+			}
+			if tt.name == "propagated" && requestID != tt.requestID {
+				t.Errorf("Expected request ID %q to be kept, got %q", tt.requestID, requestID)
+			}
+			if tt.name == "malformed replaced" && requestID == tt.requestID {
+				t.Error("Expected a malformed request ID to be replaced")
+			}
+
+			var accessLog models.FileAccessLog
+			if err := db.DB.Where("file_id = ? AND action = ?", file.ID, "delete").First(&accessLog).Error; err != nil {
+				t.Fatalf("Failed to find access log: %v", err)
+			}
+			if accessLog.RequestID != requestID {
+				t.Errorf("Expected access log request ID %q, got %q", requestID, accessLog.RequestID)
+			}
+
+			// The investigator's join: audit logs of the same request
+			auditLogs, err := models.GetSecurityAuditLogs(db.DB, map[string]interface{}{"request_id": accessLog.RequestID}, 10, 0)
+			if err != nil {
+				t.Fatalf("Failed to query audit logs: %v", err)
+			}
+			if len(auditLogs) != 1 || auditLogs[0].EventAction != "delete" || *auditLogs[0].ResourceID != file.ID {
+				t.Errorf("Expected the delete audit log to share the request ID, got %+v", auditLogs)
+			}
+		})
+	}
+}
+
+func TestAutoMigrate_AddsFileAccessLogRequestID(t *testing.T) {
+	testDB, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	sqlDB, _ := testDB.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	origDB := db.DB
+	db.DB = testDB
+	t.Cleanup(func() { db.DB = origDB })
+
+	// The table as it was before access logs were correlated with requests
+	if err := testDB.Exec(`CREATE TABLE file_access_logs (
+		id integer PRIMARY KEY AUTOINCREMENT, file_id integer NOT NULL, user_id integer NOT NULL,
+		action text NOT NULL, ip_address text, user_agent text, created_at datetime)`).Error; err != nil {
+		t.Fatalf("Failed to create legacy table: %v", err)
+	}
+	if err := testDB.Exec(`INSERT INTO file_access_logs (file_id, user_id, action) VALUES (1, 1, 'view')`).Error; err != nil {
+		t.Fatalf("Failed to insert legacy row: %v", err)
+	}
+
+	if err := db.AutoMigrate(); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	migrator := testDB.Migrator()
+	if !migrator.HasColumn(&models.FileAccessLog{}, "RequestID") || !migrator.HasIndex(&models.FileAccessLog{}, "RequestID") {
+		t.Fatal("Expected the request_id column and its index to be added")
+	}
+
+	var legacy models.FileAccessLog
+	if err := testDB.First(&legacy).Error; err != nil || legacy.Action != "view" || legacy.RequestID != "" {
+		t.Errorf("Expected the legacy row to survive with an empty request ID, got %+v, %v", legacy, err)
+	}
+}
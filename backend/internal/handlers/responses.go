@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"time"
+
+	"golangmcp/internal/models"
+)
+
+// Typed response bodies. Endpoints serialize these instead of gin.H so the
+// JSON shape is defined in one place and can be pinned by tests and used to
+// generate clients. Login responds with auth.AuthResponse.
+
+// UserResponse represents a user as exposed by the API, without credentials
+type UserResponse struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Avatar    string    `json:"avatar"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewUserResponse converts a user model into its API representation
+func NewUserResponse(user models.User) UserResponse {
+	return UserResponse{
+		ID:        user.ID,
+		Username:  user.Username,
+		Email:     user.Email,
+		Role:      user.Role,
+		Avatar:    user.Avatar,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+	}
+}
+
+// ProfileResponse is returned by GetProfileHandler
+type ProfileResponse struct {
+	User UserResponse `json:"user"`
+}
+
+// FileResponse represents a file as exposed by the API. The owner is
+// included so listings don't need a second lookup.
+type FileResponse struct {
+	ID           uint         `json:"id"`
+	Filename     string       `json:"filename"`
+	OriginalName string       `json:"original_name"`
+	FileType     string       `json:"file_type"`
+	MimeType     string       `json:"mime_type"`
+	Size         int64        `json:"size"`
+	Path         string       `json:"path"`
+	Hash         string       `json:"hash"`
+	UserID       uint         `json:"user_id"`
+	User         UserResponse `json:"user"`
+	IsPublic     bool         `json:"is_public"`
+	Description  string       `json:"description"`
+	Tags         string       `json:"tags"` // JSON array as string
+	IsScanned    bool         `json:"is_scanned"`
+	IsSafe       bool         `json:"is_safe"`
+	ScanResult   string       `json:"scan_result,omitempty"` // threat found by the scanner
+	ExpiresAt    *time.Time   `json:"expires_at,omitempty"`  // deleted after this time, see FileExpiryPolicy
+	Score        int          `json:"score,omitempty"`       // search relevance, only in search results
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+// NewFileResponse converts a file model into its API representation
+func NewFileResponse(file models.File) FileResponse {
+	return FileResponse{
+		ID:           file.ID,
+		Filename:     file.Filename,
+		OriginalName: file.OriginalName,
+		FileType:     file.FileType,
+		MimeType:     file.MimeType,
+		Size:         file.Size,
+		Path:         file.Path,
+		Hash:         file.Hash,
+		UserID:       file.UserID,
+		User:         NewUserResponse(file.User),
+		IsPublic:     file.IsPublic,
+		Description:  file.Description,
+		Tags:         file.Tags,
+		IsScanned:    file.IsScanned,
+		IsSafe:       file.IsSafe,
+		ScanResult:   file.ScanResult,
+		ExpiresAt:    file.ExpiresAt,
+		Score:        file.Score,
+		CreatedAt:    file.CreatedAt,
+		UpdatedAt:    file.UpdatedAt,
+	}
+}
+
+// NewFileResponses converts a list of file models, never returning nil so
+// an empty list is serialized as []
+func NewFileResponses(files []models.File) []FileResponse {
+	responses := make([]FileResponse, 0, len(files))
+	for _, file := range files {
+		responses = append(responses, NewFileResponse(file))
+	}
+	return responses
+}
+
+// PaginationResponse describes the page returned by a list endpoint
+type PaginationResponse struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Count  int `json:"count"`
+}
+
+// FileListResponse is returned by GetFilesHandler
+type FileListResponse struct {
+	Success    bool               `json:"success"`
+	Data       []FileResponse     `json:"data"`
+	Pagination PaginationResponse `json:"pagination"`
+}
+
+// FileDetailResponse is returned by GetFileHandler
+type FileDetailResponse struct {
+	Success bool         `json:"success"`
+	Data    FileResponse `json:"data"`
+}
+
+// SystemMetricsResponse is returned by GetSystemMetricsHandler. Partial is
+// set when some sections failed, see SystemMetrics.Errors.
+type SystemMetricsResponse struct {
+	Success bool           `json:"success"`
+	Partial bool           `json:"partial"`
+	Data    *SystemMetrics `json:"data"`
+}
+
+// CPUMetricsResponse is returned by GetCPUMetricsHandler
+type CPUMetricsResponse struct {
+	Success bool     `json:"success"`
+	Data    *CPUInfo `json:"data"`
+}
+
+// MemoryMetricsResponse is returned by GetMemoryMetricsHandler
+type MemoryMetricsResponse struct {
+	Success bool     `json:"success"`
+	Data    *MemInfo `json:"data"`
+}
+
+// DiskMetricsResponse is returned by GetDiskMetricsHandler
+type DiskMetricsResponse struct {
+	Success bool      `json:"success"`
+	Data    *DiskInfo `json:"data"`
+}
+
+// NetworkMetricsResponse is returned by GetNetworkMetricsHandler
+type NetworkMetricsResponse struct {
+	Success bool     `json:"success"`
+	Data    *NetInfo `json:"data"`
+}
@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+)
+
+// jsonKeys returns the sorted keys of the JSON object found by following path
+// from the root of body, so tests can pin a response shape independent of values
+func jsonKeys(t *testing.T, body []byte, path ...string) []string {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+
+	for _, segment := range path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[segment]
+		case []interface{}:
+			index, _ := strconv.Atoi(segment)
+			if index >= len(v) {
+				t.Fatalf("Index %s out of range in %s", segment, body)
+			}
+			value = v[index]
+		}
+	}
+
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected an object at %v, got %T", path, value)
+	}
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func assertKeys(t *testing.T, name string, got []string, want ...string) {
+	t.Helper()
+	sort.Strings(want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected %s shape:\n got: %v\nwant: %v", name, got, want)
+	}
+}
+
+var (
+	userResponseKeys = []string{"id", "username", "email", "role", "avatar", "created_at", "updated_at"}
+	fileResponseKeys = []string{"id", "filename", "original_name", "file_type", "mime_type", "size", "path", "hash",
+		"user_id", "user", "is_public", "description", "tags", "is_scanned", "is_safe", "created_at", "updated_at"}
+)
+
+func TestLoginResponse_Shape(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	setupTestSessionManager(t)
+
+	hashedPassword, _ := auth.HashPassword("correct-password")
+	user := &models.User{Username: "alice", Email: "alice@example.com", Password: hashedPassword, Role: "user"}
+	if err := user.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	r := gin.New()
+	r.POST("/login", LoginHandler)
+	w := doLogin(r, "alice", "correct-password")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	assertKeys(t, "login", jsonKeys(t, w.Body.Bytes()), "token", "user", "expires_at", "session_id")
+	assertKeys(t, "login user", jsonKeys(t, w.Body.Bytes(), "user"), "id", "username", "email", "role", "avatar")
+}
+
+func TestProfileResponse_Shape(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	user := &models.User{Username: "alice", Email: "alice@example.com", Password: "password123", Role: "user"}
+	if err := user.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	r := gin.New()
+	r.GET("/profile", func(c *gin.Context) {
+		c.Set("user_id", user.ID)
+		c.Next()
+	}, GetProfileHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/profile", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	assertKeys(t, "profile", jsonKeys(t, w.Body.Bytes()), "user")
+	assertKeys(t, "profile user", jsonKeys(t, w.Body.Bytes(), "user"), userResponseKeys...)
+}
+
+func TestFileResponses_Shape(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	file := createTestFile(t, owner.ID, "report.txt", false)
+
+	w := listFiles(owner.ID, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.Bytes()
+	assertKeys(t, "file list", jsonKeys(t, body), "success", "data", "pagination")
+	assertKeys(t, "pagination", jsonKeys(t, body, "pagination"), "limit", "offset", "count")
+	assertKeys(t, "listed file", jsonKeys(t, body, "data", "0"), fileResponseKeys...)
+	assertKeys(t, "file owner", jsonKeys(t, body, "data", "0", "user"), userResponseKeys...)
+
+	r := gin.New()
+	r.GET("/api/files/:id", func(c *gin.Context) {
+		c.Set("user_id", owner.ID)
+		c.Next()
+	}, GetFileHandler)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/files/"+strconv.Itoa(int(file.ID)), nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body = w.Body.Bytes()
+	assertKeys(t, "file detail", jsonKeys(t, body), "success", "data")
+	assertKeys(t, "file", jsonKeys(t, body, "data"), fileResponseKeys...)
+}
+
+func TestFileListResponse_EmptyListIsArray(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	w := listFiles(1, nil)
+	var response FileListResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Data == nil {
+		t.Errorf("Expected an empty list to be serialized as [], got %s", w.Body.String())
+	}
+}
+
+func TestSystemMetricsResponse_Shape(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	stubMetricsCollectors(t, true)
+
+	r := gin.New()
+	r.GET("/api/metrics/system", GetSystemMetricsHandler)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/metrics/system", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	body := w.Body.Bytes()
+	assertKeys(t, "system metrics", jsonKeys(t, body), "success", "partial", "data")
+	assertKeys(t, "metrics data", jsonKeys(t, body, "data"), "timestamp", "cpu", "memory", "disk", "network", "uptime", "errors")
+	assertKeys(t, "cpu", jsonKeys(t, body, "data", "cpu"), "usage", "count", "load_average")
+}
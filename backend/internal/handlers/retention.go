@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/services"
+)
+
+// GetRetentionPolicyHandler returns the data retention policy (admin only)
+func GetRetentionPolicyHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": services.GlobalRetentionManager.GetPolicy(),
+	})
+}
+
+// UpdateRetentionPolicyHandler replaces the data retention policy (admin only)
+func UpdateRetentionPolicyHandler(c *gin.Context) {
+	var policy services.RetentionPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.GlobalRetentionManager.UpdatePolicy(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Retention policy updated successfully",
+		"data":    policy,
+	})
+}
+
+// RunRetentionCleanupHandler runs retention cleanup immediately (admin only)
+func RunRetentionCleanupHandler(c *gin.Context) {
+	removed := services.GlobalRetentionManager.RunCleanup(db.DB)
+	logCleanup(c, "old_data", removed)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Retention cleanup completed successfully",
+		"removed": removed,
+	})
+}
+
+// GetFileExpiryPolicyHandler returns how long uploads are kept per file type and role (admin only)
+func GetFileExpiryPolicyHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": services.GlobalFileExpiryManager.GetPolicy(),
+	})
+}
+
+// UpdateFileExpiryPolicyHandler replaces the file expiry policy (admin only)
+func UpdateFileExpiryPolicyHandler(c *gin.Context) {
+	var policy services.FileExpiryPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.GlobalFileExpiryManager.UpdatePolicy(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "File expiry policy updated successfully",
+		"data":    policy,
+	})
+}
+
+// GetFileMetadataPolicyHandler returns the limits of file descriptions and tags (admin only)
+func GetFileMetadataPolicyHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": services.GlobalFileMetadata.GetPolicy(),
+	})
+}
+
+// UpdateFileMetadataPolicyHandler replaces the file metadata policy (admin only).
+// It applies to descriptions and tags stored from then on.
+func UpdateFileMetadataPolicyHandler(c *gin.Context) {
+	var policy services.FileMetadataPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.GlobalFileMetadata.UpdatePolicy(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "File metadata policy updated successfully",
+		"data":    policy,
+	})
+}
@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+)
+
+// newScopeTestRouter mounts the scoped token endpoint and a few permission-guarded routes
+func newScopeTestRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	r := gin.New()
+	r.POST("/auth/tokens/scoped", AuthMiddleware(), CreateScopedTokenHandler)
+	r.GET("/profile", AuthMiddleware(), RequirePermission("profile.read"), ok)
+	r.PUT("/profile", AuthMiddleware(), RequirePermission("profile.update"), ok)
+	r.GET("/admin/users", AuthMiddleware(), RequirePermission("admin.users"), ok)
+	r.GET("/admin/sessions", AuthMiddleware(), AdminMiddleware(), ok)
+	return r
+}
+
+func doAuthRequest(r *gin.Engine, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestScopedToken_CannotExceedScopes(t *testing.T) {
+	r := newScopeTestRouter()
+	user := &models.User{ID: 1, Username: "testuser", Role: "user"}
+
+	token, _, err := auth.GenerateScopedJWT(user, []string{"profile.read"}, time.Hour, []byte("my_secret_key"))
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	if w := doAuthRequest(r, http.MethodGet, "/profile", token, nil); w.Code != http.StatusOK {
+		t.Errorf("Expected in-scope request to succeed, got %d", w.Code)
+	}
+
+	if w := doAuthRequest(r, http.MethodPut, "/profile", token, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected out-of-scope request to be forbidden, got %d", w.Code)
+	}
+}
+
+func TestScopedToken_RejectsLongLifetime(t *testing.T) {
+	r := newScopeTestRouter()
+	user := &models.User{ID: 1, Username: "testuser", Role: "user"}
+	token, _, err := auth.GenerateJWT(user, auth.JWTSecret())
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	for _, expiresIn := range []int{-1, 721, 1 << 40} {
+		w := doAuthRequest(r, http.MethodPost, "/auth/tokens/scoped", token, gin.H{"scopes": []string{"profile.read"}, "expires_in": expiresIn})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected expires_in %d to be rejected, got %d", expiresIn, w.Code)
+		}
+	}
+	if w := doAuthRequest(r, http.MethodPost, "/auth/tokens/scoped", token, gin.H{"scopes": []string{"profile.read"}, "expires_in": 720}); w.Code != http.StatusCreated {
+		t.Errorf("Expected the longest lifetime to be allowed, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestScopedToken_AdminScopesIntersectRole(t *testing.T) {
+	r := newScopeTestRouter()
+	admin := &models.User{ID: 1, Username: "admin", Role: "admin"}
+
+	token, _, _ := auth.GenerateScopedJWT(admin, []string{"profile.read"}, time.Hour, []byte("my_secret_key"))
+
+	if w := doAuthRequest(r, http.MethodGet, "/admin/users", token, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected scoped admin token to be denied admin.users, got %d", w.Code)
+	}
+
+	if w := doAuthRequest(r, http.MethodGet, "/admin/sessions", token, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected scoped admin token to be denied admin-only route, got %d", w.Code)
+	}
+
+	fullToken, _, _ := auth.GenerateJWT(admin, []byte("my_secret_key"))
+	if w := doAuthRequest(r, http.MethodGet, "/admin/sessions", fullToken, nil); w.Code != http.StatusOK {
+		t.Errorf("Expected unscoped admin token to pass, got %d", w.Code)
+	}
+}
+
+func TestCreateScopedTokenHandler(t *testing.T) {
+	r := newScopeTestRouter()
+	user := &models.User{ID: 1, Username: "testuser", Role: "user"}
+	fullToken, _, _ := auth.GenerateJWT(user, []byte("my_secret_key"))
+
+	// Scopes beyond the role are refused
+	if w := doAuthRequest(r, http.MethodPost, "/auth/tokens/scoped", fullToken, ScopedTokenRequest{Scopes: []string{"admin.users"}}); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for scope beyond role, got %d", w.Code)
+	}
+
+	w := doAuthRequest(r, http.MethodPost, "/auth/tokens/scoped", fullToken, ScopedTokenRequest{Scopes: []string{"profile.read"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var resp struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+
+	claims, err := auth.ValidateJWT(resp.Token, []byte("my_secret_key"))
+	if err != nil {
+		t.Fatalf("Minted token is invalid: %v", err)
+	}
+	if len(claims.Scopes) != 1 || claims.Scopes[0] != "profile.read" {
+		t.Errorf("Unexpected scopes %v", claims.Scopes)
+	}
+
+	// A scoped token cannot mint a broader one
+	if w := doAuthRequest(r, http.MethodPost, "/auth/tokens/scoped", resp.Token, ScopedTokenRequest{Scopes: []string{"profile.update"}}); w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 when widening scopes, got %d", w.Code)
+	}
+}
+
+func TestAuthMiddleware_EnforcesScopesOnUnguardedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	setupTestSessionManager(t)
+
+	user := &models.User{Username: "reader", Email: "reader@example.com", Password: "password123", Role: "user"}
+	if err := user.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	token, _, _ := auth.GenerateScopedJWT(user, []string{"profile.read"}, time.Hour, auth.JWTSecret())
+
+	// Wired like main.go: authentication alone, no permission middleware
+	r := gin.New()
+	r.GET("/profile", AuthMiddleware(), GetProfileHandler)
+	r.PUT("/profile", AuthMiddleware(), UpdateProfileHandler)
+	r.POST("/profile/change-password", AuthMiddleware(), ChangePasswordHandler)
+	r.PATCH("/api/files/:id", AuthMiddleware(), UpdateFileHandler)
+	r.DELETE("/api/files/:id", AuthMiddleware(), DeleteFileHandler)
+	r.POST("/api/files/upload", AuthMiddleware(), UploadFileHandler)
+
+	if w := doAuthRequest(r, http.MethodGet, "/profile", token, nil); w.Code != http.StatusOK {
+		t.Errorf("Expected the mapped in-scope route to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	for _, route := range []struct{ method, path string }{
+		{http.MethodPut, "/profile"},
+		{http.MethodPost, "/profile/change-password"},
+		{http.MethodPatch, "/api/files/1"},
+		{http.MethodDelete, "/api/files/1"},
+		{http.MethodPost, "/api/files/upload"},
+	} {
+		w := doAuthRequest(r, route.method, route.path, token, map[string]string{"username": "renamed"})
+		if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "Token scopes") {
+			t.Errorf("%s %s: expected the scoped token to be denied, got %d: %s", route.method, route.path, w.Code, w.Body.String())
+		}
+	}
+
+	// Unscoped tokens keep the full permissions of their role
+	fullToken, _, _ := auth.GenerateJWT(user, auth.JWTSecret())
+	if w := doAuthRequest(r, http.MethodPut, "/profile", fullToken, map[string]string{"username": "renamed"}); w.Code == http.StatusForbidden {
+		t.Errorf("Expected an unscoped token to pass, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestScopedToken_MappedRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+
+	// Wired like main.go, with the route scope check ahead of the permission middleware
+	r := gin.New()
+	r.POST("/auth/tokens/scoped", AuthMiddleware(), CreateScopedTokenHandler)
+	r.GET("/api/files", AuthMiddleware(), ok)
+	r.DELETE("/api/files/:id", AuthMiddleware(), ok)
+	r.POST("/api/commands/execute", AuthMiddleware(), ok)
+	r.GET("/admin/security/logs", AuthMiddleware(), RequirePermission("admin.security"), ok)
+	r.GET("/admin/users/:id", AuthMiddleware(), RequirePermission("admin.users"), ok)
+
+	user := &models.User{ID: 1, Username: "testuser", Role: "user"}
+	fileToken, _, _ := auth.GenerateScopedJWT(user, []string{"file.read"}, time.Hour, auth.JWTSecret())
+	if w := doAuthRequest(r, http.MethodGet, "/api/files", fileToken, nil); w.Code != http.StatusOK {
+		t.Errorf("Expected file.read to list files, got %d: %s", w.Code, w.Body.String())
+	}
+	for _, route := range []struct{ method, path string }{
+		{http.MethodDelete, "/api/files/1"},
+		{http.MethodPost, "/api/commands/execute"},
+	} {
+		if w := doAuthRequest(r, route.method, route.path, fileToken, nil); w.Code != http.StatusForbidden {
+			t.Errorf("%s %s: expected file.read to be denied, got %d", route.method, route.path, w.Code)
+		}
+	}
+
+	// Admins can mint admin.security tokens, which reach only the routes guarded by it
+	admin := &models.User{ID: 2, Username: "admin", Role: "admin"}
+	fullToken, _, _ := auth.GenerateJWT(admin, auth.JWTSecret())
+	w := doAuthRequest(r, http.MethodPost, "/auth/tokens/scoped", fullToken, ScopedTokenRequest{Scopes: []string{"admin.security"}})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected admin.security to be a known scope, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &resp)
+
+	if w := doAuthRequest(r, http.MethodGet, "/admin/security/logs", resp.Token, nil); w.Code != http.StatusOK {
+		t.Errorf("Expected admin.security to reach the security logs, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := doAuthRequest(r, http.MethodGet, "/admin/users/1", resp.Token, nil); w.Code != http.StatusForbidden {
+		t.Errorf("Expected admin.security to be denied admin.users routes, got %d", w.Code)
+	}
+}
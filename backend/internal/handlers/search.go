@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+)
+
+const (
+	// DefaultSearchLimit is the number of results returned per category by default
+	DefaultSearchLimit = 10
+	// MaxSearchLimit caps the number of results returned per category
+	MaxSearchLimit = 50
+	// MinSearchQueryLength is the shortest query accepted by the admin search
+	MinSearchQueryLength = 2
+)
+
+// searchTypes are the categories covered by the admin search, in response order
+var searchTypes = []string{"files", "users", "commands"}
+
+// SearchCategory represents the results for one category of the admin search
+type SearchCategory struct {
+	Count   int         `json:"count"`
+	HasMore bool        `json:"has_more"`
+	Results interface{} `json:"results"`
+}
+
+// AdminSearchHandler searches files, users and commands in parallel (Admin only).
+// ?types=files,users narrows the categories and ?limit caps results per category.
+func AdminSearchHandler(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if len(query) < MinSearchQueryLength {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Search query must be at least " + strconv.Itoa(MinSearchQueryLength) + " characters",
+		})
+		return
+	}
+
+	types, err := parseSearchTypes(c.Query("types"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(DefaultSearchLimit)))
+	if err != nil || limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	results := make([]SearchCategory, len(types))
+	errs := make([]error, len(types))
+	var wg sync.WaitGroup
+	for i, searchType := range types {
+		wg.Add(1)
+		go func(i int, searchType string) {
+			defer wg.Done()
+			results[i], errs[i] = searchCategory(searchType, query, limit)
+		}(i, searchType)
+	}
+	wg.Wait()
+
+	data := make(gin.H, len(types))
+	total := 0
+	for i, searchType := range types {
+		if errs[i] != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to search " + searchType,
+				"details": errs[i].Error(),
+			})
+			return
+		}
+		data[searchType] = results[i]
+		total += results[i].Count
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"query":   query,
+		"data":    data,
+		"total":   total,
+	})
+}
+
+// parseSearchTypes parses a comma-separated list of categories; empty means all
+func parseSearchTypes(value string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return searchTypes, nil
+	}
+
+	requested := make(map[string]bool)
+	for _, searchType := range strings.Split(value, ",") {
+		searchType = strings.TrimSpace(searchType)
+		if searchType == "" {
+			continue
+		}
+		valid := false
+		for _, known := range searchTypes {
+			if searchType == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("unknown search type %s, must be one of %s", searchType, strings.Join(searchTypes, ", "))
+		}
+		requested[searchType] = true
+	}
+
+	if len(requested) == 0 {
+		return searchTypes, nil
+	}
+
+	// Keep the canonical order regardless of how the types were listed
+	types := make([]string, 0, len(requested))
+	for _, known := range searchTypes {
+		if requested[known] {
+			types = append(types, known)
+		}
+	}
+	return types, nil
+}
+
+// searchCategory runs the search for one category, fetching one extra row to detect more results
+func searchCategory(searchType, query string, limit int) (SearchCategory, error) {
+	switch searchType {
+	case "files":
+		files, err := models.NewOptimizedQueryBuilder(db.DB).SearchFilesOptimized(query, nil, limit+1, 0)
+		if err != nil {
+			return SearchCategory{}, err
+		}
+		hasMore := len(files) > limit
+		if hasMore {
+			files = files[:limit]
+		}
+		return SearchCategory{Count: len(files), HasMore: hasMore, Results: files}, nil
+
+	case "users":
+		users, err := models.SearchUsers(db.DB, query, limit+1, 0)
+		if err != nil {
+			return SearchCategory{}, err
+		}
+		hasMore := len(users) > limit
+		if hasMore {
+			users = users[:limit]
+		}
+		return SearchCategory{Count: len(users), HasMore: hasMore, Results: users}, nil
+
+	default: // commands
+		commands, err := models.SearchCommands(db.DB, query, limit+1, 0)
+		if err != nil {
+			return SearchCategory{}, err
+		}
+		hasMore := len(commands) > limit
+		if hasMore {
+			commands = commands[:limit]
+		}
+		return SearchCategory{Count: len(commands), HasMore: hasMore, Results: commands}, nil
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+)
+
+// searchResponse mirrors the admin search response
+type searchResponse struct {
+	Data map[string]struct {
+		Count   int               `json:"count"`
+		HasMore bool              `json:"has_more"`
+		Results []json.RawMessage `json:"results"`
+	} `json:"data"`
+	Total int `json:"total"`
+}
+
+// adminSearch runs an admin search request and decodes the response
+func adminSearch(t *testing.T, query string) (*httptest.ResponseRecorder, searchResponse) {
+	r := gin.New()
+	r.GET("/admin/search", AdminSearchHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/search?"+query, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var response searchResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+	}
+	return w, response
+}
+
+// seedSearchData creates a user, files and commands matching "report"
+func seedSearchData(t *testing.T) {
+	user := &models.User{Username: "reporter", Email: "reporter@example.com", Password: "password123", Role: "user"}
+	other := &models.User{Username: "other", Email: "other@example.com", Password: "password123", Role: "user"}
+	if err := user.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := other.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	createTestFile(t, user.ID, "report-q1.txt", false)
+	createTestFile(t, other.ID, "report-q2.txt", true)
+	createTestFile(t, other.ID, "notes.txt", false)
+
+	for _, command := range []*models.Command{
+		{Command: "cat", Args: `["report-q1.txt"]`, UserID: user.ID},
+		{Command: "ls", Args: `["-la"]`, UserID: other.ID},
+	} {
+		if err := db.DB.Create(command).Error; err != nil {
+			t.Fatalf("Failed to create command: %v", err)
+		}
+	}
+}
+
+func TestAdminSearchHandler_ReturnsEachCategory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	seedSearchData(t)
+
+	w, response := adminSearch(t, "q=report")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+
+	expected := map[string]int{"files": 2, "users": 1, "commands": 1}
+	for category, count := range expected {
+		result, ok := response.Data[category]
+		if !ok {
+			t.Fatalf("Expected %s in results, got %+v", category, response.Data)
+		}
+		if result.Count != count || len(result.Results) != count {
+			t.Errorf("Expected %d %s, got %d", count, category, result.Count)
+		}
+	}
+	if response.Total != 4 {
+		t.Errorf("Expected total 4, got %d", response.Total)
+	}
+
+	var user models.User
+	if err := json.Unmarshal(response.Data["users"].Results[0], &user); err != nil {
+		t.Fatalf("Failed to decode user: %v", err)
+	}
+	if user.Username != "reporter" || user.Password != "" {
+		t.Errorf("Expected reporter without password, got %+v", user)
+	}
+}
+
+func TestAdminSearchHandler_TypesFilterAndLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	seedSearchData(t)
+
+	w, response := adminSearch(t, "q=report&types=files,users&limit=1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, ok := response.Data["commands"]; ok {
+		t.Error("Expected commands to be excluded by the types filter")
+	}
+	if files := response.Data["files"]; files.Count != 1 || !files.HasMore {
+		t.Errorf("Expected 1 file with more available, got %+v", files)
+	}
+	if users := response.Data["users"]; users.Count != 1 || users.HasMore {
+		t.Errorf("Expected 1 user and no more, got %+v", users)
+	}
+}
+
+func TestAdminSearchHandler_InvalidRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+
+	for _, query := range []string{"", "q=r", "q=report&types=files,groups"} {
+		if w, _ := adminSearch(t, query); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %q, got %d", query, w.Code)
+		}
+	}
+}
@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"bytes"
 	"crypto/md5"
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
 	"os"
@@ -14,6 +16,8 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"golangmcp/internal/services"
+	"golangmcp/internal/websocket"
 )
 
 // FileUpload represents a file upload record
@@ -46,6 +50,7 @@ type FileValidationResult struct {
 	Errors      []string `json:"errors"`
 	Warnings    []string `json:"warnings"`
 	FileInfo    FileInfo `json:"file_info"`
+	Sanitized   []byte   `json:"-"` // content to store instead of the upload, e.g. a sanitized SVG
 }
 
 // FileInfo represents file information
@@ -72,7 +77,8 @@ const (
 	AvatarDirSecure    = "./uploads/avatars"
 	ImageDir     = "./uploads/images"
 	DocumentDir  = "./uploads/documents"
-	QuarantineDir = "./uploads/quarantine"
+	QuarantineDir = services.QuarantineDir
+	PreviewDir   = services.PreviewDir
 )
 
 // SecureUploadHandler handles secure file uploads
@@ -83,6 +89,18 @@ func SecureUploadHandler(c *gin.Context) {
 		return
 	}
 
+	// Report body progress to /ws/uploads/:uploadId when the client asked for it
+	uploadID := websocket.TrackUploadProgress(c)
+	defer websocket.FinishUploadProgress(c, uploadID)
+
+	// Parse the form before binding, which would otherwise parse it with gin's limit
+	form, err := parseUploadForm(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	defer form.RemoveAll()
+
 	var req UploadRequest
 	if err := c.ShouldBind(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -90,13 +108,17 @@ func SecureUploadHandler(c *gin.Context) {
 	}
 
 	// Get the uploaded file
-	file, header, err := c.Request.FormFile("file")
+	file, header, err := form.FormFile("file")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
 		return
 	}
 	defer file.Close()
 
+	if !checkUploadFilename(c, header) || !checkUploadMetadata(c, &req.Description, nil) {
+		return
+	}
+
 	// Validate file
 	validation := validateSecureFile(file, header, req.FileType)
 	if !validation.IsValid {
@@ -110,18 +132,24 @@ func SecureUploadHandler(c *gin.Context) {
 
 	// Create appropriate upload directory
 	uploadDir := getUploadDirectory(req.FileType)
-	if err := os.MkdirAll(uploadDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
+	if !ensureUploadDir(c, uploadDir) {
 		return
 	}
 
 	// Generate secure filename
-	filename := generateSecureFilename(header.Filename, userID.(uint))
+	filename := generateSecureFilename(header.Filename)
+	if !ensureUploadDir(c, filepath.Dir(filepath.Join(uploadDir, filename))) {
+		return
+	}
 	filepath := filepath.Join(uploadDir, filename)
 
-	// Save file
-	if err := saveSecureFile(file, filepath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+	// Save file, or its sanitized version
+	var content io.Reader = file
+	if validation.Sanitized != nil {
+		content = bytes.NewReader(validation.Sanitized)
+	}
+	if err := saveSecureFile(content, filepath); err != nil {
+		respondSaveError(c, err, "Failed to save file")
 		return
 	}
 
@@ -138,7 +166,7 @@ func SecureUploadHandler(c *gin.Context) {
 		Filename:     filename,
 		OriginalName: header.Filename,
 		FilePath:     filepath,
-		FileSize:     header.Size,
+		FileSize:     validation.FileInfo.Size,
 		MimeType:     header.Header.Get("Content-Type"),
 		MD5Hash:      validation.FileInfo.MD5Hash,
 		SHA256Hash:   validation.FileInfo.SHA256Hash,
@@ -148,12 +176,82 @@ func SecureUploadHandler(c *gin.Context) {
 		ExpiresAt:    expiresAt,
 	}
 
-	// Save to database (you would need to create a FileUpload model)
-	// For now, we'll just return the file info
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message": "File uploaded successfully",
 		"file":    fileUpload,
 		"url":     fmt.Sprintf("/uploads/%s/%s", req.FileType, filename),
+	}
+
+	// Render a first-page thumbnail of documents in the background, when enabled
+	if req.FileType == "document" && services.GlobalDocumentPreviews.Generate(fileUpload.UserID, filename, filepath, fileUpload.MimeType) {
+		response["preview_url"] = "/upload/preview/" + filename
+	}
+
+	// Save to database (you would need to create a FileUpload model)
+	// For now, we'll just return the file info
+	c.JSON(http.StatusOK, response)
+}
+
+// GetDocumentPreviewHandler serves the thumbnail of a document the current user
+// uploaded. It is 404 until the thumbnail has been rendered, if it failed, or
+// once the document has been quarantined or deleted.
+func GetDocumentPreviewHandler(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Previews are kept per user, so only the uploader's own can be found
+	path, err := services.GlobalDocumentPreviews.PreviewPath(userID.(uint), c.Param("filename"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file name"})
+		return
+	}
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Preview not available"})
+		return
+	}
+
+	// A document moved to quarantine or deleted takes its preview with it
+	if info, err := os.Stat(filepath.Join(DocumentDir, filepath.FromSlash(c.Param("filename")))); err != nil || info.IsDir() {
+		if err := services.GlobalDocumentPreviews.Remove(userID.(uint), c.Param("filename")); err != nil {
+			log.Printf("Warning: Failed to remove preview of %s: %v", c.Param("filename"), err)
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Preview not available"})
+		return
+	}
+
+	c.Header("Content-Type", "image/png")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.File(path)
+}
+
+// GetDocumentPreviewPolicyHandler returns the document preview policy (admin only)
+func GetDocumentPreviewPolicyHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": services.GlobalDocumentPreviews.GetPolicy(),
+	})
+}
+
+// UpdateDocumentPreviewPolicyHandler replaces the document preview policy (admin
+// only). The renderer is configured at startup and can't be changed here.
+func UpdateDocumentPreviewPolicyHandler(c *gin.Context) {
+	var policy services.DocumentPreviewPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.GlobalDocumentPreviews.UpdatePolicy(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Document preview policy updated successfully",
+		"data":    policy,
 	})
 }
 
@@ -179,8 +277,24 @@ func validateSecureFile(file multipart.File, header *multipart.FileHeader, fileT
 		result.Errors = append(result.Errors, fmt.Sprintf("File type %s is not allowed for %s uploads", contentType, fileType))
 	}
 
-	// Read file content for analysis
-	content, err := io.ReadAll(file)
+	// SVGs can carry scripts, so they are rejected or sanitized per GlobalSVGPolicy
+	var content io.Reader = file
+	if isSVGUpload(contentType, header.Filename) {
+		sanitized, err := sanitizeSVGUpload(file, maxSize)
+		if err != nil {
+			result.IsValid = false
+			result.Errors = append(result.Errors, err.Error())
+			return result
+		}
+		result.Sanitized = sanitized
+		content = bytes.NewReader(sanitized)
+	}
+
+	// Stream the content once through the hashes and the content scanner
+	md5Hash := md5.New()
+	sha256Hash := sha256.New()
+	scanner := newContentScanner(MaxContentScanBytes)
+	size, err := io.Copy(io.MultiWriter(md5Hash, sha256Hash, scanner), content)
 	if err != nil {
 		result.IsValid = false
 		result.Errors = append(result.Errors, "Failed to read file content")
@@ -190,19 +304,15 @@ func validateSecureFile(file multipart.File, header *multipart.FileHeader, fileT
 	// Reset file pointer
 	file.Seek(0, 0)
 
-	// Calculate hashes
-	md5Hash := md5.Sum(content)
-	sha256Hash := sha256.Sum256(content)
-
 	// Check for executable content
-	isExecutable := containsExecutableContent(content)
+	isExecutable := scanner.executable
 
 	result.FileInfo = FileInfo{
-		Size:         header.Size,
+		Size:         size,
 		MimeType:     contentType,
 		Extension:    strings.ToLower(filepath.Ext(header.Filename)),
-		MD5Hash:      hex.EncodeToString(md5Hash[:]),
-		SHA256Hash:   hex.EncodeToString(sha256Hash[:]),
+		MD5Hash:      hex.EncodeToString(md5Hash.Sum(nil)),
+		SHA256Hash:   hex.EncodeToString(sha256Hash.Sum(nil)),
 		IsExecutable: isExecutable,
 	}
 
@@ -213,9 +323,12 @@ func validateSecureFile(file multipart.File, header *multipart.FileHeader, fileT
 	}
 
 	// Check for suspicious patterns
-	if containsSuspiciousPatterns(content) {
+	if scanner.suspicious {
 		result.Warnings = append(result.Warnings, "File contains potentially suspicious patterns")
 	}
+	if scanner.Truncated() && header.Size > MaxContentScanBytes {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("Only the first %d bytes were scanned", MaxContentScanBytes))
+	}
 
 	// Check file extension matches MIME type
 	if !isValidMimeTypeExtension(contentType, header.Filename) {
@@ -225,6 +338,54 @@ func validateSecureFile(file multipart.File, header *multipart.FileHeader, fileT
 	return result
 }
 
+// checkUploadFilename applies GlobalFilenamePolicy to an upload, replacing its
+// filename with the normalized one. It responds with 400 and returns false
+// when the name is refused.
+func checkUploadFilename(c *gin.Context, header *multipart.FileHeader) bool {
+	filename, err := services.GlobalFilenamePolicy.Check(header.Filename)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "File name not allowed",
+			"details": err.Error(),
+		})
+		return false
+	}
+	header.Filename = filename
+	return true
+}
+
+// checkUploadMetadata applies GlobalFileMetadata to the description and, when
+// given, the comma-separated tags of an upload form, replacing them with the
+// cleaned values. It responds with 400 and returns false when either is refused.
+func checkUploadMetadata(c *gin.Context, description, tags *string) bool {
+	cleaned, err := services.GlobalFileMetadata.Description(*description)
+	if err == nil && tags != nil {
+		*tags, err = services.GlobalFileMetadata.TagList(*tags)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid file metadata",
+			"details": err.Error(),
+		})
+		return false
+	}
+	*description = cleaned
+	return true
+}
+
+// isSVGUpload checks if an upload is an SVG document by type or extension
+func isSVGUpload(contentType, filename string) bool {
+	return contentType == "image/svg+xml" || strings.ToLower(filepath.Ext(filename)) == ".svg"
+}
+
+// sanitizeSVGUpload applies the SVG policy, returning the sanitized document
+func sanitizeSVGUpload(file io.Reader, maxSize int64) ([]byte, error) {
+	if services.GlobalSVGPolicy.GetPolicy().Mode != services.SVGModeSanitize {
+		return nil, services.ErrSVGRejected
+	}
+	return services.SanitizeSVG(io.LimitReader(file, maxSize))
+}
+
 // getMaxFileSize returns maximum file size for file type
 func getMaxFileSize(fileType string) int64 {
 	switch fileType {
@@ -265,16 +426,13 @@ func getUploadDirectory(fileType string) string {
 	}
 }
 
-// generateSecureFilename generates a secure filename
-func generateSecureFilename(originalName string, userID uint) string {
-	ext := filepath.Ext(originalName)
-	timestamp := time.Now().Unix()
-	hash := md5.Sum([]byte(fmt.Sprintf("%d_%s_%d", userID, originalName, timestamp)))
-	return fmt.Sprintf("file_%d_%d_%s%s", userID, timestamp, hex.EncodeToString(hash[:8]), ext)
+// generateSecureFilename generates a secure filename using the configured naming strategy
+func generateSecureFilename(originalName string) string {
+	return services.GlobalFileNamer.Generate(originalName)
 }
 
 // saveSecureFile saves file securely
-func saveSecureFile(file multipart.File, filepath string) error {
+func saveSecureFile(file io.Reader, filepath string) error {
 	dst, err := os.Create(filepath)
 	if err != nil {
 		return err
@@ -292,50 +450,16 @@ func saveSecureFile(file multipart.File, filepath string) error {
 
 // containsExecutableContent checks for executable content
 func containsExecutableContent(content []byte) bool {
-	// Check for common executable signatures
-	executableSignatures := [][]byte{
-		{0x4D, 0x5A}, // PE executable
-		{0x7F, 0x45, 0x4C, 0x46}, // ELF executable
-		{0xFE, 0xED, 0xFA, 0xCE}, // Mach-O executable
-		{0xCA, 0xFE, 0xBA, 0xBE}, // Java class file
-	}
-
-	for _, sig := range executableSignatures {
-		if len(content) >= len(sig) {
-			for i := 0; i <= len(content)-len(sig); i++ {
-				if bytesEqual(content[i:i+len(sig)], sig) {
-					return true
-				}
-			}
-		}
-	}
-
-	return false
+	scanner := newContentScanner(int64(len(content)))
+	scanner.Write(content)
+	return scanner.executable
 }
 
 // containsSuspiciousPatterns checks for suspicious patterns
 func containsSuspiciousPatterns(content []byte) bool {
-	contentStr := string(content)
-	
-	// Check for script tags
-	suspiciousPatterns := []string{
-		"<script",
-		"javascript:",
-		"vbscript:",
-		"onload=",
-		"onerror=",
-		"eval(",
-		"exec(",
-		"system(",
-	}
-
-	for _, pattern := range suspiciousPatterns {
-		if strings.Contains(strings.ToLower(contentStr), pattern) {
-			return true
-		}
-	}
-
-	return false
+	scanner := newContentScanner(int64(len(content)))
+	scanner.Write(content)
+	return scanner.suspicious
 }
 
 // isValidMimeTypeExtension validates MIME type against file extension
@@ -366,19 +490,6 @@ func isValidMimeTypeExtension(mimeType, filename string) bool {
 	return false
 }
 
-// bytesEqual compares two byte slices
-func bytesEqual(a, b []byte) bool {
-	if len(a) != len(b) {
-		return false
-	}
-	for i := range a {
-		if a[i] != b[i] {
-			return false
-		}
-	}
-	return true
-}
-
 // GetSecureUploadStatsHandler returns secure upload statistics
 func GetSecureUploadStatsHandler(c *gin.Context) {
 	stats := gin.H{
@@ -404,8 +515,11 @@ func GetSecureUploadStatsHandler(c *gin.Context) {
 			"Suspicious pattern detection",
 			"MIME type validation",
 			"Secure filename generation",
+			"Dangerous and double extension denylist",
 			"Hash calculation",
 		},
+		"naming_strategy": services.GlobalFileNamer.GetConfig().Strategy,
+		"svg_mode":        services.GlobalSVGPolicy.GetPolicy().Mode,
 	}
 
 	c.JSON(http.StatusOK, stats)
@@ -429,3 +543,29 @@ func ScanFileHandler(c *gin.Context) {
 		"scan_time":  time.Now(),
 	})
 }
+
+// GetUploadNamingConfigHandler returns the upload naming configuration (admin only)
+func GetUploadNamingConfigHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": services.GlobalFileNamer.GetConfig(),
+	})
+}
+
+// UpdateUploadNamingConfigHandler updates the upload naming configuration (admin only)
+func UpdateUploadNamingConfigHandler(c *gin.Context) {
+	var config services.FileNamingConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.GlobalFileNamer.UpdateConfig(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "strategy must be uuid, hash or date"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Upload naming configuration updated successfully",
+		"data":    config,
+	})
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/services"
+)
+
+// openTestUpload builds a multipart upload and returns its file and header
+func openTestUpload(t *testing.T, filename, contentType, content string) (multipart.File, *multipart.FileHeader) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	partHeader := textproto.MIMEHeader{}
+	partHeader.Set("Content-Disposition", `form-data; name="file"; filename="`+filename+`"`)
+	partHeader.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(partHeader)
+	if err != nil {
+		t.Fatalf("Failed to create part: %v", err)
+	}
+	io.WriteString(part, content)
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("Failed to read form: %v", err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+
+	header := form.File["file"][0]
+	file, err := header.Open()
+	if err != nil {
+		t.Fatalf("Failed to open upload: %v", err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file, header
+}
+
+const maliciousSVGUpload = `<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"><script>alert(1)</script><rect width="1" height="1"/></svg>`
+
+func TestValidateSecureFile_RejectsSVGByDefault(t *testing.T) {
+	file, header := openTestUpload(t, "logo.svg", "image/svg+xml", maliciousSVGUpload)
+
+	result := validateSecureFile(file, header, "image")
+	if result.IsValid {
+		t.Fatal("Expected SVG upload to be rejected")
+	}
+	if len(result.Errors) != 1 || result.Errors[0] != services.ErrSVGRejected.Error() {
+		t.Errorf("Expected SVG rejection error, got %v", result.Errors)
+	}
+}
+
+func TestValidateSecureFile_SanitizesSVGWhenEnabled(t *testing.T) {
+	origPolicy := services.GlobalSVGPolicy
+	t.Cleanup(func() { services.GlobalSVGPolicy = origPolicy })
+	services.GlobalSVGPolicy = services.NewSVGPolicyManager()
+	services.GlobalSVGPolicy.UpdatePolicy(&services.SVGPolicy{Mode: services.SVGModeSanitize})
+
+	file, header := openTestUpload(t, "logo.svg", "image/svg+xml", maliciousSVGUpload)
+
+	result := validateSecureFile(file, header, "image")
+	if !result.IsValid {
+		t.Fatalf("Expected sanitized SVG to be accepted, got %v", result.Errors)
+	}
+	sanitized := string(result.Sanitized)
+	if strings.Contains(sanitized, "script") || strings.Contains(sanitized, "onload") {
+		t.Errorf("Expected scripts to be stripped, got %s", sanitized)
+	}
+	if !strings.Contains(sanitized, `<rect width="1" height="1">`) {
+		t.Errorf("Expected shapes to be kept, got %s", sanitized)
+	}
+	if result.FileInfo.Size != int64(len(result.Sanitized)) {
+		t.Errorf("Expected size of the sanitized content, got %d", result.FileInfo.Size)
+	}
+	for _, warning := range result.Warnings {
+		if strings.Contains(warning, "suspicious") {
+			t.Errorf("Expected no suspicious pattern warning after sanitizing, got %v", result.Warnings)
+		}
+	}
+}
+
+// stubThumbnailer renders every document as the same small image
+type stubThumbnailer struct{}
+
+func (stubThumbnailer) Thumbnail(ctx context.Context, path, mimeType string) ([]byte, error) {
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 4, 4)))
+	return buf.Bytes(), err
+}
+
+func TestSecureUploadHandler_GeneratesDocumentPreview(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	chdirTemp(t)
+
+	origPreviews := services.GlobalDocumentPreviews
+	t.Cleanup(func() { services.GlobalDocumentPreviews = origPreviews })
+	services.GlobalDocumentPreviews = services.NewDocumentPreviewManager(PreviewDir)
+	services.GlobalDocumentPreviews.SetThumbnailer(stubThumbnailer{})
+	policy := services.DefaultDocumentPreviewPolicy()
+	policy.Enabled = true
+	services.GlobalDocumentPreviews.UpdatePolicy(policy)
+
+	asUser := func(userID uint) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			c.Set("user_id", userID)
+			c.Next()
+		}
+	}
+	r := gin.New()
+	r.POST("/upload/:fileType", asUser(1), SecureUploadHandler)
+	r.GET("/upload/preview/*filename", asUser(1), GetDocumentPreviewHandler)
+	r.GET("/other/upload/preview/*filename", asUser(2), GetDocumentPreviewHandler)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("file_type", "document")
+	partHeader := textproto.MIMEHeader{}
+	partHeader.Set("Content-Disposition", `form-data; name="file"; filename="report.pdf"`)
+	partHeader.Set("Content-Type", "application/pdf")
+	part, _ := writer.CreatePart(partHeader)
+	io.WriteString(part, "%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj\n%%EOF\n")
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload/document", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the upload to succeed, got %d: %s", w.Code, w.Body.String())
+	}
+	var response struct {
+		PreviewURL string `json:"preview_url"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if !strings.HasPrefix(response.PreviewURL, "/upload/preview/") {
+		t.Fatalf("Expected a preview URL, got %s", w.Body.String())
+	}
+	services.GlobalDocumentPreviews.Wait()
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, response.PreviewURL, nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("Expected the preview image, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if _, err := png.DecodeConfig(w.Body); err != nil {
+		t.Errorf("Expected a PNG preview: %v", err)
+	}
+
+	// Another user can't see it
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/other"+response.PreviewURL, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for another user's preview, got %d", w.Code)
+	}
+
+	// Nor is it served once the document left the upload directory, e.g. to quarantine
+	filename := strings.TrimPrefix(response.PreviewURL, "/upload/preview/")
+	if err := os.Rename(filepath.Join(DocumentDir, filename), filepath.Join(t.TempDir(), "quarantined.pdf")); err != nil {
+		t.Fatalf("Failed to move the document: %v", err)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, response.PreviewURL, nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for the preview of a quarantined document, got %d", w.Code)
+	}
+	path, _ := services.GlobalDocumentPreviews.PreviewPath(1, filename)
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the preview of a quarantined document to be removed, got %v", err)
+	}
+}
+
+func TestSecureUploadHandler_RejectsLongDescription(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	chdirTemp(t)
+
+	r := gin.New()
+	r.POST("/upload/:fileType", func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	}, SecureUploadHandler)
+
+	upload := func(description string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		writer.WriteField("file_type", "document")
+		writer.WriteField("description", description)
+		partHeader := textproto.MIMEHeader{}
+		partHeader.Set("Content-Disposition", `form-data; name="file"; filename="notes.txt"`)
+		partHeader.Set("Content-Type", "text/plain")
+		part, _ := writer.CreatePart(partHeader)
+		io.WriteString(part, "plain text notes\n")
+		writer.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/upload/document", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	max := services.GlobalFileMetadata.GetPolicy().MaxDescriptionLength
+	if w := upload(strings.Repeat("a", max+1)); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected a description over the limit to be rejected, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := upload(strings.Repeat("a", max)); w.Code == http.StatusBadRequest {
+		t.Errorf("Expected a description at the limit to be accepted, got %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -6,11 +6,24 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
 	"golangmcp/internal/security"
+	"golangmcp/internal/services"
 )
 
-// GetSecurityStatusHandler returns current security status
+// GetSecurityStatusHandler returns which protections are enabled. It is public,
+// so the limits and settings are only served by GetSecurityStatusDetailsHandler.
 func GetSecurityStatusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"security_status": security.GetSecuritySummary(),
+		"timestamp": time.Now(),
+	})
+}
+
+// GetSecurityStatusDetailsHandler returns current security status (admin only)
+func GetSecurityStatusDetailsHandler(c *gin.Context) {
 	status := security.GetSecurityStatus()
 	c.JSON(http.StatusOK, gin.H{
 		"security_status": status,
@@ -57,7 +70,7 @@ func GetRateLimitStatusHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"client_ip": clientIP,
 		"rate_limit": gin.H{
-			"limit_per_minute": security.DefaultSecurityConfig.RateLimitPerMinute,
+			"limit_per_minute": security.GlobalSecurityConfig.GetConfig().RateLimitPerMinute,
 			"window_seconds": 60,
 		},
 		"timestamp": time.Now(),
@@ -86,13 +99,29 @@ func GetSecurityHeadersHandler(c *gin.Context) {
 func UpdateSecurityConfigHandler(c *gin.Context) {
 	var req struct {
 		RateLimitPerMinute *int     `json:"rate_limit_per_minute"`
+		RateLimitWarningThreshold *int `json:"rate_limit_warning_threshold"`
 		MaxRequestSize     *int64   `json:"max_request_size"`
+		RequestTimeout     *int     `json:"request_timeout"` // seconds, 0 disables
+		MaxConcurrentUploads *int   `json:"max_concurrent_uploads"` // 0 = unlimited
+		UploadRetryAfter   *int     `json:"upload_retry_after"` // seconds
+		MultipartMemory    *int64   `json:"multipart_memory"` // bytes
 		EnableCORS         *bool    `json:"enable_cors"`
 		EnableCSRF         *bool    `json:"enable_csrf"`
 		EnableXSSProtection *bool   `json:"enable_xss_protection"`
 		EnableHSTS         *bool    `json:"enable_hsts"`
 		AllowedOrigins     []string `json:"allowed_origins"`
+		AllowedMethods     []string `json:"allowed_methods"`
+		AllowedHeaders     []string `json:"allowed_headers"`
+		AllowCredentials   *bool    `json:"allow_credentials"`
+		CORSMaxAge         *int     `json:"cors_max_age"` // seconds, 0 omits the header
+		CORSOriginPolicies []security.CORSOriginPolicy `json:"cors_origin_policies"`
+		CORSPublicPaths    []string `json:"cors_public_paths"`
+		AllowedRedirectURIs []string `json:"allowed_redirect_uris"`
 		TrustedProxies     []string `json:"trusted_proxies"`
+		AuthMode           *string  `json:"auth_mode"` // header, cookie or both
+		SessionCookieName  *string  `json:"session_cookie_name"`
+		SessionCookieSecure *bool   `json:"session_cookie_secure"`
+		SessionCookieSameSite *string `json:"session_cookie_same_site"`
 	}
 	
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -100,47 +129,534 @@ func UpdateSecurityConfigHandler(c *gin.Context) {
 		return
 	}
 	
-	// Update configuration
-	if req.RateLimitPerMinute != nil {
-		security.DefaultSecurityConfig.RateLimitPerMinute = *req.RateLimitPerMinute
-		// Update rate limiter
-		security.GlobalRateLimiter = security.NewRateLimiter(*req.RateLimitPerMinute, time.Minute)
+	// Update configuration; the rate limiter is swapped along with it
+	config, err := security.GlobalSecurityConfig.UpdateConfig(func(config *security.SecurityConfig) {
+		if req.RateLimitPerMinute != nil {
+			config.RateLimitPerMinute = *req.RateLimitPerMinute
+		}
+		
+		if req.RateLimitWarningThreshold != nil {
+			config.RateLimitWarningThreshold = *req.RateLimitWarningThreshold
+		}
+		
+		if req.MaxRequestSize != nil {
+			config.MaxRequestSize = *req.MaxRequestSize
+		}
+		
+		if req.RequestTimeout != nil {
+			config.RequestTimeout = time.Duration(*req.RequestTimeout) * time.Second
+		}
+		
+		if req.MaxConcurrentUploads != nil {
+			config.MaxConcurrentUploads = *req.MaxConcurrentUploads
+		}
+		
+		if req.UploadRetryAfter != nil {
+			config.UploadRetryAfter = time.Duration(*req.UploadRetryAfter) * time.Second
+		}
+		
+		if req.MultipartMemory != nil {
+			config.MultipartMemory = *req.MultipartMemory
+		}
+		
+		if req.EnableCORS != nil {
+			config.EnableCORS = *req.EnableCORS
+		}
+		
+		if req.EnableCSRF != nil {
+			config.EnableCSRF = *req.EnableCSRF
+		}
+		
+		if req.EnableXSSProtection != nil {
+			config.EnableXSSProtection = *req.EnableXSSProtection
+		}
+		
+		if req.EnableHSTS != nil {
+			config.EnableHSTS = *req.EnableHSTS
+		}
+		
+		if req.AllowedOrigins != nil {
+			config.AllowedOrigins = req.AllowedOrigins
+		}
+		
+		if req.AllowedMethods != nil {
+			config.AllowedMethods = req.AllowedMethods
+		}
+		
+		if req.AllowedHeaders != nil {
+			config.AllowedHeaders = req.AllowedHeaders
+		}
+		
+		if req.AllowCredentials != nil {
+			config.AllowCredentials = *req.AllowCredentials
+		}
+		
+		if req.CORSMaxAge != nil {
+			config.CORSMaxAge = time.Duration(*req.CORSMaxAge) * time.Second
+		}
+		
+		if req.CORSOriginPolicies != nil {
+			config.CORSOriginPolicies = req.CORSOriginPolicies
+		}
+		
+		if req.CORSPublicPaths != nil {
+			config.CORSPublicPaths = req.CORSPublicPaths
+		}
+		
+		if req.AllowedRedirectURIs != nil {
+			config.AllowedRedirectURIs = req.AllowedRedirectURIs
+		}
+		
+		if req.TrustedProxies != nil {
+			config.TrustedProxies = req.TrustedProxies
+		}
+		
+		if req.AuthMode != nil {
+			config.AuthMode = *req.AuthMode
+		}
+		
+		if req.SessionCookieName != nil {
+			config.SessionCookieName = *req.SessionCookieName
+		}
+		
+		if req.SessionCookieSecure != nil {
+			config.SessionCookieSecure = *req.SessionCookieSecure
+		}
+		
+		if req.SessionCookieSameSite != nil {
+			config.SessionCookieSameSite = *req.SessionCookieSameSite
+		}
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 	
-	if req.MaxRequestSize != nil {
-		security.DefaultSecurityConfig.MaxRequestSize = *req.MaxRequestSize
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Security configuration updated successfully",
+		"config": config,
+	})
+}
+
+// GetRateLimitExemptionsHandler returns rate limit exemptions (Admin only)
+func GetRateLimitExemptionsHandler(c *gin.Context) {
+	exemptions := security.GlobalExemptionManager.GetExemptions()
+
+	// Never echo API keys back, only a masked form
+	maskedKeys := make([]string, len(exemptions.APIKeys))
+	for i, key := range exemptions.APIKeys {
+		maskedKeys[i] = maskAPIKey(key)
 	}
-	
-	if req.EnableCORS != nil {
-		security.DefaultSecurityConfig.EnableCORS = *req.EnableCORS
+	exemptions.APIKeys = maskedKeys
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": exemptions,
+	})
+}
+
+// UpdateRateLimitExemptionsHandler replaces rate limit exemptions (Admin only)
+func UpdateRateLimitExemptionsHandler(c *gin.Context) {
+	var exemptions security.RateLimitExemptions
+	if err := c.ShouldBindJSON(&exemptions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	
-	if req.EnableCSRF != nil {
-		security.DefaultSecurityConfig.EnableCSRF = *req.EnableCSRF
+
+	for _, key := range exemptions.APIKeys {
+		if len(key) < 16 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "API keys must be at least 16 characters"})
+			return
+		}
 	}
-	
-	if req.EnableXSSProtection != nil {
-		security.DefaultSecurityConfig.EnableXSSProtection = *req.EnableXSSProtection
+
+	security.GlobalExemptionManager.UpdateExemptions(exemptions)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Rate limit exemptions updated successfully",
+		"roles": exemptions.Roles,
+		"user_ids": exemptions.UserIDs,
+		"api_keys": len(exemptions.APIKeys),
+	})
+}
+
+// GetMaintenanceHandler returns the maintenance mode state (Admin only)
+func GetMaintenanceHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": security.GlobalMaintenanceManager.GetState(),
+	})
+}
+
+// UpdateMaintenanceHandler turns maintenance mode on or off (Admin only)
+func UpdateMaintenanceHandler(c *gin.Context) {
+	// Start from the current state so fields left out are kept
+	state := security.GlobalMaintenanceManager.GetState()
+	if err := c.ShouldBindJSON(&state); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	
-	if req.EnableHSTS != nil {
-		security.DefaultSecurityConfig.EnableHSTS = *req.EnableHSTS
+
+	if state.RetryAfterSeconds < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Retry-After cannot be negative"})
+		return
 	}
-	
-	if req.AllowedOrigins != nil {
-		security.DefaultSecurityConfig.AllowedOrigins = req.AllowedOrigins
+
+	if err := security.GlobalMaintenanceManager.UpdateState(db.DB, &state); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save maintenance state"})
+		return
 	}
-	
-	if req.TrustedProxies != nil {
-		security.DefaultSecurityConfig.TrustedProxies = req.TrustedProxies
+
+	adminID, _ := c.Get("user_id")
+	services.NewAuditLogger().LogMaintenanceToggled(adminID.(uint), state.Enabled, state.Message, c.ClientIP(), c.Request.UserAgent())
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Maintenance mode updated successfully",
+		"data":    state,
+	})
+}
+
+// GetLoginAnomalyConfigHandler returns new device login detection configuration (Admin only)
+func GetLoginAnomalyConfigHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": services.GlobalLoginAnomalyDetector.GetConfig(),
+	})
+}
+
+// UpdateLoginAnomalyConfigHandler replaces new device login detection configuration (Admin only)
+func UpdateLoginAnomalyConfigHandler(c *gin.Context) {
+	var config services.LoginAnomalyConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	
+
+	if err := services.GlobalLoginAnomalyDetector.UpdateConfig(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Security configuration updated successfully",
-		"config": security.DefaultSecurityConfig,
+		"message": "Login anomaly detection configuration updated successfully",
+		"data":    config,
+	})
+}
+
+// GetRegistrationPolicyHandler returns how self-registered accounts are set up (Admin only)
+func GetRegistrationPolicyHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": services.GlobalRegistrationPolicy.GetPolicy(),
+	})
+}
+
+// UpdateRegistrationPolicyHandler replaces the registration policy (Admin only)
+func UpdateRegistrationPolicyHandler(c *gin.Context) {
+	var policy services.RegistrationPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.GlobalRegistrationPolicy.UpdatePolicy(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Registration policy updated successfully",
+		"data":    policy,
+	})
+}
+
+// GetSVGPolicyHandler returns how SVG uploads are handled (Admin only)
+func GetSVGPolicyHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": services.GlobalSVGPolicy.GetPolicy(),
+	})
+}
+
+// UpdateSVGPolicyHandler sets whether SVG uploads are rejected or sanitized (Admin only)
+func UpdateSVGPolicyHandler(c *gin.Context) {
+	var policy services.SVGPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.GlobalSVGPolicy.UpdatePolicy(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "SVG policy updated successfully",
+		"data":    policy,
+	})
+}
+
+// GetFilenamePolicyHandler returns which upload filenames are refused (Admin only)
+func GetFilenamePolicyHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": services.GlobalFilenamePolicy.GetPolicy(),
+	})
+}
+
+// UpdateFilenamePolicyHandler replaces which upload filenames are refused (Admin only)
+func UpdateFilenamePolicyHandler(c *gin.Context) {
+	var policy services.FilenamePolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.GlobalFilenamePolicy.UpdatePolicy(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Filename policy updated successfully",
+		"data":    policy,
+	})
+}
+
+// GetDownloadPolicyHandler returns how uploaded files are served (Admin only)
+func GetDownloadPolicyHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": services.GlobalDownloadPolicy.GetPolicy(),
+	})
+}
+
+// UpdateDownloadPolicyHandler replaces how uploaded files are served (Admin only)
+func UpdateDownloadPolicyHandler(c *gin.Context) {
+	// Start from the current policy so fields left out are kept
+	policy := services.GlobalDownloadPolicy.GetPolicy()
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.GlobalDownloadPolicy.UpdatePolicy(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Download policy updated successfully",
+		"data":    policy,
+	})
+}
+
+// GetCompressionConfigHandler returns response compression configuration (Admin only)
+func GetCompressionConfigHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": security.GlobalCompressionConfig.GetConfig(),
+	})
+}
+
+// UpdateCompressionConfigHandler replaces response compression configuration (Admin only)
+func UpdateCompressionConfigHandler(c *gin.Context) {
+	// Start from the current configuration so fields left out are kept
+	config := security.GlobalCompressionConfig.GetConfig()
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := security.GlobalCompressionConfig.UpdateConfig(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Compression configuration updated successfully",
+		"data":    config,
+	})
+}
+
+// GetAuditSamplingHandler returns failed request audit sampling configuration and counters (Admin only)
+func GetAuditSamplingHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data":  security.GlobalAuditSampler.GetConfig(),
+		"stats": security.GlobalAuditSampler.Stats(),
+	})
+}
+
+// UpdateAuditSamplingHandler replaces failed request audit sampling configuration (Admin only)
+func UpdateAuditSamplingHandler(c *gin.Context) {
+	// Start from the current configuration so fields left out are kept
+	config := security.GlobalAuditSampler.GetConfig()
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := security.GlobalAuditSampler.UpdateConfig(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Audit sampling configuration updated successfully",
+		"data":    config,
+	})
+}
+
+// GetAccessLogConfigHandler returns access log configuration (Admin only)
+func GetAccessLogConfigHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": security.GlobalAccessLogger.GetConfig(),
+	})
+}
+
+// UpdateAccessLogConfigHandler replaces access log configuration (Admin only).
+// The output is set at startup and can't be changed here.
+func UpdateAccessLogConfigHandler(c *gin.Context) {
+	// Start from the current configuration so fields left out are kept
+	config := security.GlobalAccessLogger.GetConfig()
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := security.GlobalAccessLogger.UpdateConfig(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Access log configuration updated successfully",
+		"data":    config,
+	})
+}
+
+// GetAuditAlertPolicyHandler returns the audit alert policy and the stats of its last scan (Admin only)
+func GetAuditAlertPolicyHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data":  services.GlobalAuditAlertScanner.GetPolicy(),
+		"stats": services.GlobalAuditAlertScanner.Stats(),
+	})
+}
+
+// UpdateAuditAlertPolicyHandler replaces the audit alert policy (Admin only)
+func UpdateAuditAlertPolicyHandler(c *gin.Context) {
+	// Start from the current policy so fields left out are kept
+	policy := services.GlobalAuditAlertScanner.GetPolicy()
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.GlobalAuditAlertScanner.UpdatePolicy(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Audit alert policy updated successfully",
+		"data":    policy,
+	})
+}
+
+// GetPasswordPolicyHandler returns the password reuse policy (Admin only)
+func GetPasswordPolicyHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": auth.GlobalPasswordPolicy.GetPolicy(),
+	})
+}
+
+// UpdatePasswordPolicyHandler replaces the password reuse policy (Admin only)
+func UpdatePasswordPolicyHandler(c *gin.Context) {
+	var policy auth.PasswordPolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := auth.GlobalPasswordPolicy.UpdatePolicy(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password policy updated successfully",
+		"data":    policy,
+	})
+}
+
+// GetPasswordHasherHandler returns the password hashing configuration (Admin only)
+func GetPasswordHasherHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": auth.GlobalPasswordHasher.GetConfig(),
+	})
+}
+
+// UpdatePasswordHasherHandler replaces the password hashing configuration (Admin only).
+// Existing passwords are migrated to the new algorithm as users log in.
+func UpdatePasswordHasherHandler(c *gin.Context) {
+	config := auth.GlobalPasswordHasher.GetConfig()
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := auth.GlobalPasswordHasher.UpdateConfig(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Password hasher configuration updated successfully",
+		"data":    config,
 	})
 }
 
+// GetSigningKeysHandler returns the JWT signing keys without their secrets (Admin only)
+func GetSigningKeysHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"data": auth.GlobalSigningKeys.GetConfig(),
+	})
+}
+
+// UpdateSigningKeysHandler replaces the JWT signing keys (Admin only)
+func UpdateSigningKeysHandler(c *gin.Context) {
+	var config auth.SigningKeyConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := auth.GlobalSigningKeys.UpdateConfig(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Signing keys updated successfully",
+		"data":    auth.GlobalSigningKeys.GetConfig(),
+	})
+}
+
+// RotateSigningKeyHandler generates a new current JWT signing key (Admin only)
+func RotateSigningKeyHandler(c *gin.Context) {
+	key, err := auth.GlobalSigningKeys.Rotate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rotate signing key"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Signing key rotated successfully",
+		"data":    key,
+	})
+}
+
+// maskAPIKey hides all but the last four characters of an API key
+func maskAPIKey(key string) string {
+	if len(key) <= 4 {
+		return strings.Repeat("*", len(key))
+	}
+	return strings.Repeat("*", len(key)-4) + key[len(key)-4:]
+}
+
 // GetSecurityLogsHandler returns security logs (Admin only)
 func GetSecurityLogsHandler(c *gin.Context) {
 	// In a real application, you would retrieve logs from a logging system
@@ -238,21 +754,30 @@ func sanitizeTestInput(input string) string {
 	return result
 }
 
-// GetSecurityMetricsHandler returns security metrics
+// GetSecurityMetricsHandler returns the security counters of the current window
+// and the IPs with the most failed requests in it
 func GetSecurityMetricsHandler(c *gin.Context) {
-	// In a real application, you would collect metrics from monitoring systems
+	snapshot := security.GlobalSecurityMetrics.Snapshot()
+
+	topBlockedIPs, err := models.GetTopFailureIPs(db.DB, snapshot.WindowStart, 10)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to aggregate blocked IPs"})
+		return
+	}
+
+	var eventsLast24h int64
+	if err := db.DB.Model(&models.SecurityAuditLog{}).Where("created_at >= ?", time.Now().Add(-24*time.Hour)).Count(&eventsLast24h).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count security events"})
+		return
+	}
+
 	metrics := gin.H{
-		"rate_limit_hits": 150,
-		"csrf_violations": 5,
-		"blocked_requests": 25,
-		"suspicious_activities": 8,
-		"security_events_last_24h": 188,
-		"top_blocked_ips": []gin.H{
-			{"ip": "192.168.1.100", "count": 45},
-			{"ip": "192.168.1.101", "count": 32},
-			{"ip": "192.168.1.102", "count": 28},
-		},
-		"security_score": 85,
+		"rate_limit_hits": snapshot.RateLimitHits,
+		"csrf_violations": snapshot.CSRFViolations,
+		"blocked_requests": snapshot.BlockedRequests,
+		"security_events_last_24h": eventsLast24h,
+		"top_blocked_ips": topBlockedIPs,
+		"window_start": snapshot.WindowStart,
 		"last_updated": time.Now(),
 	}
 	
@@ -261,3 +786,12 @@ func GetSecurityMetricsHandler(c *gin.Context) {
 		"timestamp": time.Now(),
 	})
 }
+
+// ResetSecurityMetricsHandler starts a new security metrics window and returns
+// the counters of the one that ended (Admin only)
+func ResetSecurityMetricsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Security metrics reset successfully",
+		"data":    security.GlobalSecurityMetrics.Reset(),
+	})
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/services"
+)
+
+// SelfTestCheck is one invariant the server verifies about itself. A failed
+// critical check means the server cannot work correctly.
+type SelfTestCheck struct {
+	Name     string
+	Critical bool
+	Run      func() error
+}
+
+// SelfTestResult represents the outcome of one check
+type SelfTestResult struct {
+	Name     string `json:"name"`
+	Critical bool   `json:"critical"`
+	Passed   bool   `json:"passed"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// SelfTestReport represents the outcome of a self-test; it passes when every
+// critical check passes
+type SelfTestReport struct {
+	Passed bool             `json:"passed"`
+	Checks []SelfTestResult `json:"checks"`
+	RanAt  time.Time        `json:"ran_at"`
+}
+
+// CriticalFailures returns the critical checks that failed
+func (r *SelfTestReport) CriticalFailures() []SelfTestResult {
+	failures := []SelfTestResult{}
+	for _, check := range r.Checks {
+		if check.Critical && !check.Passed {
+			failures = append(failures, check)
+		}
+	}
+	return failures
+}
+
+// SelfTestChecks returns the invariants verified at startup and by the admin endpoint
+func SelfTestChecks() []SelfTestCheck {
+	return []SelfTestCheck{
+		{Name: "database", Critical: true, Run: checkDatabase},
+		{Name: "upload_directories", Critical: true, Run: ValidateUploadDirectories},
+		{Name: "jwt_secret", Critical: true, Run: checkJWTSecret},
+		{Name: "command_whitelist", Critical: false, Run: checkCommandWhitelist},
+		{Name: "background_jobs", Critical: true, Run: checkBackgroundJobs},
+	}
+}
+
+// SelfTest runs every self-test check
+func SelfTest() *SelfTestReport {
+	return RunSelfTest(SelfTestChecks())
+}
+
+// RunSelfTest runs the given checks in order; one failing does not stop the rest
+func RunSelfTest(checks []SelfTestCheck) *SelfTestReport {
+	report := &SelfTestReport{
+		Passed: true,
+		Checks: make([]SelfTestResult, 0, len(checks)),
+		RanAt:  time.Now(),
+	}
+
+	for _, check := range checks {
+		start := time.Now()
+		err := check.Run()
+
+		result := SelfTestResult{
+			Name:     check.Name,
+			Critical: check.Critical,
+			Passed:   err == nil,
+			Duration: time.Since(start).String(),
+		}
+		if err != nil {
+			result.Error = err.Error()
+			if check.Critical {
+				report.Passed = false
+			}
+		}
+		report.Checks = append(report.Checks, result)
+	}
+
+	return report
+}
+
+// checkDatabase verifies the database answers and every table has been migrated
+func checkDatabase() error {
+	if db.DB == nil {
+		return errors.New("database is not connected")
+	}
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return err
+	}
+	if err := sqlDB.Ping(); err != nil {
+		return fmt.Errorf("database is unreachable: %v", err)
+	}
+
+	migrator := db.DB.Migrator()
+	for _, model := range db.Models() {
+		if !migrator.HasTable(model) {
+			return fmt.Errorf("table for %T is missing, migrations have not run", model)
+		}
+	}
+	return nil
+}
+
+// checkJWTSecret verifies tokens are signed with a key, and in release mode
+// that it is not the public development key
+func checkJWTSecret() error {
+	if len(auth.JWTSecret()) == 0 {
+		return errors.New("JWT secret is empty")
+	}
+	if gin.Mode() == gin.ReleaseMode && auth.UsingDefaultJWTSecret() {
+		return fmt.Errorf("JWT secret is the development default, set %s", auth.EnvJWTSecret)
+	}
+	return nil
+}
+
+// checkCommandWhitelist verifies commands can be executed; an empty whitelist
+// rejects every command
+func checkCommandWhitelist() error {
+	if db.DB == nil {
+		return errors.New("database is not connected")
+	}
+	var count int64
+	if err := db.DB.Model(&models.CommandWhitelist{}).Where("is_active = ?", true).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("command whitelist is empty, every command will be rejected")
+	}
+	return nil
+}
+
+// checkBackgroundJobs verifies the scheduler is running jobs
+func checkBackgroundJobs() error {
+	if !services.GlobalScheduler.Running() {
+		return errors.New("background job scheduler is not running")
+	}
+	if len(services.GlobalScheduler.Statuses()) == 0 {
+		return errors.New("no background jobs are registered")
+	}
+	return nil
+}
+
+// SelfTestHandler runs the self-test, answering 503 when a critical check fails
+func SelfTestHandler(c *gin.Context) {
+	report := SelfTest()
+
+	status := http.StatusOK
+	if !report.Passed {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, gin.H{"data": report})
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/services"
+)
+
+// setupSelfTest prepares an environment where every self-test check passes
+func setupSelfTest(t *testing.T) {
+	setupTestDB(t)
+	chdirTemp(t)
+
+	if err := db.DB.Create(&models.CommandWhitelist{Command: "ls", IsActive: true}).Error; err != nil {
+		t.Fatalf("Failed to seed whitelist: %v", err)
+	}
+
+	scheduler := services.NewScheduler()
+	scheduler.Register("noop", services.FixedInterval(time.Hour), func() (interface{}, error) { return nil, nil })
+	scheduler.Start()
+
+	origScheduler := services.GlobalScheduler
+	services.GlobalScheduler = scheduler
+	t.Cleanup(func() {
+		scheduler.Stop()
+		services.GlobalScheduler = origScheduler
+	})
+}
+
+// selfTestResult returns the result of the named check
+func selfTestResult(t *testing.T, report *SelfTestReport, name string) SelfTestResult {
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	t.Fatalf("Check %s missing from report", name)
+	return SelfTestResult{}
+}
+
+func TestSelfTest_AllChecksPass(t *testing.T) {
+	setupSelfTest(t)
+
+	report := SelfTest()
+	if !report.Passed {
+		t.Fatalf("Expected self-test to pass, got %+v", report.Checks)
+	}
+	for _, check := range report.Checks {
+		if !check.Passed {
+			t.Errorf("Expected check %s to pass, got %s", check.Name, check.Error)
+		}
+	}
+}
+
+func TestSelfTest_DatabaseNotMigrated(t *testing.T) {
+	setupSelfTest(t)
+	db.DB.Migrator().DropTable(&models.FileAccessLog{})
+
+	report := SelfTest()
+	if report.Passed {
+		t.Error("Expected a missing table to fail the self-test")
+	}
+	if result := selfTestResult(t, report, "database"); result.Passed || !result.Critical {
+		t.Errorf("Expected a critical database failure, got %+v", result)
+	}
+}
+
+func TestSelfTest_UploadDirectoriesUnwritable(t *testing.T) {
+	setupSelfTest(t)
+	blockUploads(t)
+
+	report := SelfTest()
+	if report.Passed {
+		t.Error("Expected unwritable upload directories to fail the self-test")
+	}
+	if result := selfTestResult(t, report, "upload_directories"); result.Passed {
+		t.Errorf("Expected upload_directories to fail, got %+v", result)
+	}
+}
+
+func TestSelfTest_JWTSecret(t *testing.T) {
+	setupSelfTest(t)
+	origSecret := auth.JWTSecret()
+	t.Cleanup(func() { auth.SetJWTSecret(origSecret) })
+
+	auth.SetJWTSecret(nil)
+	if result := selfTestResult(t, SelfTest(), "jwt_secret"); result.Passed {
+		t.Error("Expected an empty JWT secret to fail")
+	}
+
+	// The development key is only rejected in release mode
+	auth.SetJWTSecret([]byte(auth.DefaultJWTSecret))
+	if result := selfTestResult(t, SelfTest(), "jwt_secret"); !result.Passed {
+		t.Errorf("Expected the development key to pass in test mode, got %s", result.Error)
+	}
+
+	origMode := gin.Mode()
+	gin.SetMode(gin.ReleaseMode)
+	defer gin.SetMode(origMode)
+	if result := selfTestResult(t, SelfTest(), "jwt_secret"); result.Passed {
+		t.Error("Expected the development key to fail in release mode")
+	}
+
+	auth.SetJWTSecret([]byte("a-production-secret"))
+	if result := selfTestResult(t, SelfTest(), "jwt_secret"); !result.Passed {
+		t.Errorf("Expected a configured key to pass in release mode, got %s", result.Error)
+	}
+}
+
+func TestSelfTest_EmptyWhitelistIsNotCritical(t *testing.T) {
+	setupSelfTest(t)
+	db.DB.Where("1 = 1").Delete(&models.CommandWhitelist{})
+
+	report := SelfTest()
+	result := selfTestResult(t, report, "command_whitelist")
+	if result.Passed || result.Critical {
+		t.Errorf("Expected a non-critical whitelist failure, got %+v", result)
+	}
+	if !report.Passed {
+		t.Error("Expected a non-critical failure to leave the self-test passing")
+	}
+}
+
+func TestSelfTest_SchedulerNotRunning(t *testing.T) {
+	setupSelfTest(t)
+	services.GlobalScheduler.Stop()
+
+	report := SelfTest()
+	if report.Passed {
+		t.Error("Expected a stopped scheduler to fail the self-test")
+	}
+	if result := selfTestResult(t, report, "background_jobs"); result.Passed {
+		t.Errorf("Expected background_jobs to fail, got %+v", result)
+	}
+	if failures := report.CriticalFailures(); len(failures) != 1 || failures[0].Name != "background_jobs" {
+		t.Errorf("Expected only background_jobs to fail critically, got %+v", failures)
+	}
+}
+
+func TestSelfTestHandler_ReportsFailureAs503(t *testing.T) {
+	setupSelfTest(t)
+	router := gin.New()
+	router.GET("/admin/selftest", SelfTestHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/selftest", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 when every check passes, got %d: %s", w.Code, w.Body.String())
+	}
+
+	services.GlobalScheduler.Stop()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/selftest", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 on a critical failure, got %d", w.Code)
+	}
+
+	var response struct {
+		Data SelfTestReport `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if response.Data.Passed || len(response.Data.Checks) != len(SelfTestChecks()) {
+		t.Errorf("Unexpected report: %+v", response.Data)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"golangmcp/internal/security"
+	"golangmcp/internal/services"
 	"golangmcp/internal/session"
 )
 
@@ -17,9 +19,11 @@ func GetUserSessionsHandler(c *gin.Context) {
 	}
 
 	sessions := session.GlobalSessionManager.GetUserSessions(userID.(uint))
+	config := session.GlobalSessionManager.GetConfig()
 	c.JSON(http.StatusOK, gin.H{
-		"sessions": sessions,
-		"count":    len(sessions),
+		"sessions":     sessions,
+		"count":        len(sessions),
+		"max_sessions": config.MaxSessionsPerUser,
 	})
 }
 
@@ -115,19 +119,86 @@ func InvalidateUserSessionsHandler(c *gin.Context) {
 	})
 }
 
+// AdminInvalidateSessionHandler terminates a single session of any user, e.g.
+// one on a suspicious device, leaving the user's other sessions active (admin only)
+func AdminInvalidateSessionHandler(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	sess, err := session.GlobalSessionManager.GetSession(sessionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if err := session.GlobalSessionManager.InvalidateSession(sessionID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invalidate session"})
+		return
+	}
+
+	adminID, _ := c.Get("user_id")
+	services.NewAuditLogger().LogSessionTerminated(adminID.(uint), sess.UserID, sess.ID, c.ClientIP(), c.Request.UserAgent(), security.RequestID(c))
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session invalidated successfully",
+		"user_id": sess.UserID,
+	})
+}
+
+// GetSessionConfigHandler returns the session configuration (admin only)
+func GetSessionConfigHandler(c *gin.Context) {
+	config := session.GlobalSessionManager.GetConfig()
+	c.JSON(http.StatusOK, gin.H{
+		"data": config,
+	})
+}
+
+// UpdateSessionConfigHandler updates the session configuration (admin only)
+func UpdateSessionConfigHandler(c *gin.Context) {
+	var config session.SessionConfig
+	if err := c.ShouldBindJSON(&config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if config.MaxSessionsPerUser < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_sessions_per_user cannot be negative"})
+		return
+	}
+
+	if config.LimitPolicy == "" {
+		config.LimitPolicy = session.LimitPolicyEvictOldest
+	}
+	if config.LimitPolicy != session.LimitPolicyEvictOldest && config.LimitPolicy != session.LimitPolicyReject {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit_policy must be evict_oldest or reject"})
+		return
+	}
+
+	if err := config.ValidateIPBinding(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if config.IDBytes == 0 {
+		config.IDBytes = session.DefaultSessionIDBytes
+	}
+	if err := config.ValidateIDBytes(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session.GlobalSessionManager.UpdateConfig(&config)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session configuration updated successfully",
+		"data":    config,
+	})
+}
+
 // SessionMiddleware validates session and updates last seen
 func SessionMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Extract token from Authorization header
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.Next()
-			return
-		}
-
-		// Check if token starts with "Bearer "
-		tokenString := authHeader[7:] // Remove "Bearer " prefix
-		if len(authHeader) <= 7 {
+		// Extract token from Authorization header or session cookie
+		tokenString, _ := security.RequestToken(c.Request)
+		if tokenString == "" {
 			c.Next()
 			return
 		}
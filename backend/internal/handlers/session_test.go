@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/session"
+)
+
+func TestAdminInvalidateSessionHandler_TerminatesOneSession(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	sm := setupTestSessionManager(t)
+
+	admin := &models.User{Username: "admin", Email: "admin@example.com", Password: "password123", Role: "admin"}
+	target := &models.User{Username: "target", Email: "target@example.com", Password: "password123", Role: "user"}
+	if err := admin.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create admin: %v", err)
+	}
+	if err := target.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	var sessions []*session.Session
+	for _, userAgent := range []string{"suspicious-device", "laptop"} {
+		token, _, err := auth.GenerateJWT(target, auth.JWTSecret())
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		sess, err := sm.CreateSession(target, token, "192.0.2.1", userAgent)
+		if err != nil {
+			t.Fatalf("Failed to create session: %v", err)
+		}
+		sessions = append(sessions, sess)
+	}
+	suspicious, other := sessions[0], sessions[1]
+
+	r := gin.New()
+	r.DELETE("/admin/sessions/:sessionId", func(c *gin.Context) {
+		c.Set("user_id", admin.ID)
+		c.Next()
+	}, AdminInvalidateSessionHandler)
+	terminate := func(sessionID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/sessions/"+sessionID, nil))
+		return w
+	}
+
+	if w := terminate(suspicious.ID); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := sm.GetSessionByToken(suspicious.Token); err != session.ErrTokenBlacklisted {
+		t.Errorf("Expected the terminated session's token to be blacklisted, got %v", err)
+	}
+	if _, err := sm.GetSessionByToken(other.Token); err != nil {
+		t.Errorf("Expected the user's other session to stay active, got %v", err)
+	}
+	if count := sm.CountUserSessions(target.ID); count != 1 {
+		t.Errorf("Expected 1 remaining session, got %d", count)
+	}
+
+	var entry models.SecurityAuditLog
+	if err := db.DB.Where("event_action = ?", "terminate").First(&entry).Error; err != nil {
+		t.Fatalf("Expected the termination to be audited: %v", err)
+	}
+	if entry.UserID == nil || *entry.UserID != admin.ID || entry.SessionID != suspicious.ID {
+		t.Errorf("Expected the audit entry to name the admin and the session, got %+v", entry)
+	}
+
+	if w := terminate(suspicious.ID); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a session already terminated, got %d", w.Code)
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrStorageUnavailable is returned when an upload directory cannot be created or written to
+var ErrStorageUnavailable = errors.New("upload storage is unavailable")
+
+// StorageUnavailableCode identifies storage failures in error responses, so
+// clients can tell them apart from other server errors
+const StorageUnavailableCode = "storage_unavailable"
+
+// StorageWriteCheckInterval is how long a successful write check is trusted.
+// GetStorageHealth only stats a directory checked within the interval, so
+// frequent health probes don't create files in the upload directories.
+var StorageWriteCheckInterval = time.Minute
+
+// storageWriteChecks records when each upload directory last passed a write check
+var storageWriteChecks = struct {
+	sync.Mutex
+	checkedAt map[string]time.Time
+}{checkedAt: make(map[string]time.Time)}
+
+// UploadDirectories returns every directory uploads are written to
+func UploadDirectories() []string {
+	return []string{UploadDir, FileUploadDir, ImageDir, DocumentDir, QuarantineDir, PreviewDir}
+}
+
+// CheckUploadDirectory creates dir if it is missing and verifies that files can
+// be created in it, which catches read-only volumes and wrong permissions
+func CheckUploadDirectory(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrStorageUnavailable, dir, err)
+	}
+
+	probe, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("%w: %s is not writable: %v", ErrStorageUnavailable, dir, err)
+	}
+	probe.Close()
+	os.Remove(probe.Name())
+	return nil
+}
+
+// ValidateUploadDirectories checks every upload directory, so the server can
+// refuse to start instead of failing each upload
+func ValidateUploadDirectories() error {
+	for _, dir := range UploadDirectories() {
+		if err := CheckUploadDirectory(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetStorageHealth reports the state of each upload directory and whether all
+// are usable, writing a probe file at most once per StorageWriteCheckInterval
+func GetStorageHealth() (map[string]string, bool) {
+	status := make(map[string]string)
+	healthy := true
+	for _, dir := range UploadDirectories() {
+		if err := checkUploadDirectoryPeriodically(dir); err != nil {
+			status[filepath.Clean(dir)] = err.Error()
+			healthy = false
+			continue
+		}
+		status[filepath.Clean(dir)] = "ready"
+	}
+	return status, healthy
+}
+
+// checkUploadDirectoryPeriodically runs CheckUploadDirectory unless dir
+// passed one within StorageWriteCheckInterval and still exists as a directory
+func checkUploadDirectoryPeriodically(dir string) error {
+	dir = filepath.Clean(dir)
+
+	storageWriteChecks.Lock()
+	checkedAt, ok := storageWriteChecks.checkedAt[dir]
+	storageWriteChecks.Unlock()
+	if ok && time.Since(checkedAt) < StorageWriteCheckInterval {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return nil
+		}
+	}
+
+	if err := CheckUploadDirectory(dir); err != nil {
+		storageWriteChecks.Lock()
+		delete(storageWriteChecks.checkedAt, dir)
+		storageWriteChecks.Unlock()
+		return err
+	}
+	storageWriteChecks.Lock()
+	storageWriteChecks.checkedAt[dir] = time.Now()
+	storageWriteChecks.Unlock()
+	return nil
+}
+
+// isStorageError checks if a filesystem error means the storage itself is
+// unusable rather than the request being at fault
+func isStorageError(err error) bool {
+	return errors.Is(err, ErrStorageUnavailable) ||
+		errors.Is(err, fs.ErrPermission) ||
+		errors.Is(err, syscall.EROFS) ||
+		errors.Is(err, syscall.ENOSPC) ||
+		errors.Is(err, syscall.ENOTDIR)
+}
+
+// respondStorageUnavailable responds with 503 and StorageUnavailableCode
+func respondStorageUnavailable(c *gin.Context) {
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error": "Upload storage is unavailable",
+		"code":  StorageUnavailableCode,
+	})
+}
+
+// ensureUploadDir creates an upload directory for a request, responding with
+// 503 if the storage is unavailable
+func ensureUploadDir(c *gin.Context, dir string) bool {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		respondStorageUnavailable(c)
+		return false
+	}
+	return true
+}
+
+// respondSaveError responds to a failed file write, with 503 when the storage
+// is at fault and a 500 carrying message otherwise
+func respondSaveError(c *gin.Context, err error, message string) {
+	if isStorageError(err) {
+		respondStorageUnavailable(c)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+)
+
+// chdirTemp runs the rest of the test in an empty working directory, where
+// the relative upload directories are created
+func chdirTemp(t testing.TB) string {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	return dir
+}
+
+// blockUploads makes the uploads directory unusable by putting a regular file in its place
+func blockUploads(t *testing.T) {
+	if err := os.WriteFile("uploads", []byte("not a directory"), 0644); err != nil {
+		t.Fatalf("Failed to block uploads directory: %v", err)
+	}
+}
+
+func TestCheckUploadDirectory_Unwritable(t *testing.T) {
+	dir := chdirTemp(t)
+
+	if err := CheckUploadDirectory(filepath.Join(dir, "uploads", "files")); err != nil {
+		t.Fatalf("Expected a missing directory to be created, got %v", err)
+	}
+
+	blocked := filepath.Join(dir, "blocked")
+	os.WriteFile(blocked, nil, 0644)
+	if err := CheckUploadDirectory(filepath.Join(blocked, "files")); !errors.Is(err, ErrStorageUnavailable) {
+		t.Errorf("Expected ErrStorageUnavailable for a path under a file, got %v", err)
+	}
+
+	// Permission bits are not enforced for root
+	if os.Geteuid() != 0 {
+		readOnly := filepath.Join(dir, "readonly")
+		os.Mkdir(readOnly, 0555)
+		if err := CheckUploadDirectory(readOnly); !errors.Is(err, ErrStorageUnavailable) {
+			t.Errorf("Expected ErrStorageUnavailable for a read-only directory, got %v", err)
+		}
+	}
+}
+
+func TestValidateUploadDirectories_FailsFast(t *testing.T) {
+	chdirTemp(t)
+
+	if err := ValidateUploadDirectories(); err != nil {
+		t.Fatalf("Expected upload directories to be created, got %v", err)
+	}
+
+	os.RemoveAll("uploads")
+	blockUploads(t)
+	if err := ValidateUploadDirectories(); !errors.Is(err, ErrStorageUnavailable) {
+		t.Errorf("Expected ErrStorageUnavailable, got %v", err)
+	}
+}
+
+func TestUploadFileHandler_StorageUnavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	chdirTemp(t)
+	blockUploads(t)
+
+	owner := &models.User{Username: "owner", Email: "owner@example.com", Password: "password123", Role: "user"}
+	if err := owner.Create(db.DB); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	w := uploadFile(t, owner.ID, "report.txt", "content")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Code string `json:"code"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	if response.Code != StorageUnavailableCode {
+		t.Errorf("Expected code %q, got %q", StorageUnavailableCode, response.Code)
+	}
+}
+
+func TestGetHealthHandler_ReportsStorage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	chdirTemp(t)
+
+	r := gin.New()
+	r.GET("/health", GetHealthHandler)
+	probe := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+		return w
+	}
+
+	if w := probe(); w.Code != http.StatusOK {
+		t.Fatalf("Expected healthy storage to be ready, got %d: %s", w.Code, w.Body.String())
+	}
+
+	os.RemoveAll("uploads")
+	blockUploads(t)
+	if w := probe(); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected unwritable storage to fail the readiness probe, got %d", w.Code)
+	}
+}
+
+func TestGetStorageHealth_WriteChecksPeriodically(t *testing.T) {
+	chdirTemp(t)
+	origInterval := StorageWriteCheckInterval
+	t.Cleanup(func() { StorageWriteCheckInterval = origInterval })
+	StorageWriteCheckInterval = time.Hour
+
+	if _, healthy := GetStorageHealth(); !healthy {
+		t.Fatal("Expected the upload directories to be created and ready")
+	}
+
+	// Writing a probe file updates the directory's modification time
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(FileUploadDir, past, past); err != nil {
+		t.Fatalf("Failed to reset modification time: %v", err)
+	}
+	modTime := func() time.Time {
+		info, err := os.Stat(FileUploadDir)
+		if err != nil {
+			t.Fatalf("Failed to stat upload directory: %v", err)
+		}
+		return info.ModTime()
+	}
+
+	if _, healthy := GetStorageHealth(); !healthy || !modTime().Equal(past) {
+		t.Errorf("Expected a recently checked directory to only be stat'ed, healthy %v", healthy)
+	}
+
+	StorageWriteCheckInterval = 0
+	if _, healthy := GetStorageHealth(); !healthy || modTime().Equal(past) {
+		t.Errorf("Expected the write check to run again once the interval passed, healthy %v", healthy)
+	}
+}
@@ -8,11 +8,12 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 	"golangmcp/internal/db"
 	"golangmcp/internal/models"
+	"golangmcp/internal/security"
+	"golangmcp/internal/services"
 )
 
 const (
@@ -24,6 +25,34 @@ const (
 	UploadDir = "./uploads/avatars"
 )
 
+// parseUploadForm parses a multipart upload, holding at most the configured
+// MultipartMemory in memory so large files spill to the upload temp directory
+// early. The caller removes those with RemoveAll once the upload is handled.
+// Form values stay available to PostForm and binding; files are read from the
+// returned form.
+func parseUploadForm(c *gin.Context) (*services.UploadForm, error) {
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	form, err := services.GlobalUploadTempManager.ReadForm(reader, security.GlobalSecurityConfig.GetConfig().MultipartMemory)
+	if err != nil {
+		return nil, err
+	}
+
+	parseErr := c.Request.ParseForm()
+	c.Request.MultipartForm = &multipart.Form{Value: form.Value}
+	for key, values := range form.Value {
+		c.Request.Form[key] = append(c.Request.Form[key], values...)
+		c.Request.PostForm[key] = append(c.Request.PostForm[key], values...)
+	}
+	if parseErr != nil {
+		form.RemoveAll()
+		return nil, parseErr
+	}
+	return form, nil
+}
+
 // UploadAvatarHandler handles avatar file upload
 func UploadAvatarHandler(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -32,14 +61,25 @@ func UploadAvatarHandler(c *gin.Context) {
 		return
 	}
 
+	form, err := parseUploadForm(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+	defer form.RemoveAll()
+
 	// Get the uploaded file
-	file, header, err := c.Request.FormFile("avatar")
+	file, header, err := form.FormFile("avatar")
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
 		return
 	}
 	defer file.Close()
 
+	if !checkUploadFilename(c, header) {
+		return
+	}
+
 	// Validate file
 	if err := validateAvatarFile(file, header); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -47,19 +87,20 @@ func UploadAvatarHandler(c *gin.Context) {
 	}
 
 	// Create upload directory if it doesn't exist
-	if err := os.MkdirAll(UploadDir, 0755); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create upload directory"})
+	if !ensureUploadDir(c, UploadDir) {
 		return
 	}
 
 	// Generate unique filename
-	ext := filepath.Ext(header.Filename)
-	filename := fmt.Sprintf("avatar_%d_%d%s", userID, time.Now().Unix(), ext)
+	filename := services.GlobalFileNamer.Generate(header.Filename)
+	if !ensureUploadDir(c, filepath.Dir(filepath.Join(UploadDir, filename))) {
+		return
+	}
 	filepath := filepath.Join(UploadDir, filename)
 
 	// Save file
 	if err := saveUploadedFile(file, filepath); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
+		respondSaveError(c, err, "Failed to save file")
 		return
 	}
 
@@ -140,14 +181,15 @@ func DeleteAvatarHandler(c *gin.Context) {
 
 // GetAvatarHandler serves avatar files
 func GetAvatarHandler(c *gin.Context) {
-	filename := c.Param("filename")
+	// Date-partitioned names span subdirectories, e.g. 2006/01/02/<uuid>.png
+	filename := strings.TrimPrefix(c.Param("filename"), "/")
 	if filename == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Filename required"})
 		return
 	}
 
 	// Security check: ensure filename doesn't contain path traversal
-	if strings.Contains(filename, "..") || strings.Contains(filename, "/") || strings.Contains(filename, "\\") {
+	if strings.Contains(filename, "..") || strings.HasPrefix(filename, "/") || strings.Contains(filename, "\\") {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid filename"})
 		return
 	}
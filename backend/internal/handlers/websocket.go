@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/services"
+	"golangmcp/internal/websocket"
+)
+
+// ListWebSocketClientsHandler lists connected WebSocket metrics clients (admin only)
+func ListWebSocketClientsHandler(c *gin.Context) {
+	if websocket.GlobalHub == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "WebSocket hub is not running"})
+		return
+	}
+
+	clients := websocket.GlobalHub.Clients()
+	c.JSON(http.StatusOK, gin.H{
+		"clients": clients,
+		"count":   len(clients),
+	})
+}
+
+// DisconnectWebSocketClientHandler forcibly disconnects a WebSocket client by ID (admin only)
+func DisconnectWebSocketClientHandler(c *gin.Context) {
+	if websocket.GlobalHub == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "WebSocket hub is not running"})
+		return
+	}
+
+	client, err := websocket.GlobalHub.Disconnect(c.Param("clientId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Client not found"})
+		return
+	}
+
+	adminID, _ := c.Get("user_id")
+	services.NewAuditLogger().LogWebSocketDisconnect(adminID.(uint), client.ID, client.UserID, c.ClientIP(), c.Request.UserAgent())
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Client disconnected successfully",
+		"client":  client,
+	})
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/websocket"
+)
+
+// setupTestHub replaces the global WebSocket hub for the duration of a test
+func setupTestHub(t *testing.T) *websocket.Hub {
+	origHub := websocket.GlobalHub
+	hub := websocket.NewHub()
+	go hub.Run()
+	websocket.GlobalHub = hub
+	t.Cleanup(func() {
+		hub.Stop()
+		websocket.GlobalHub = origHub
+	})
+	return hub
+}
+
+func TestWebSocketClientsHandlers_ListAndDisconnect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestDB(t)
+	hub := setupTestHub(t)
+
+	client := &websocket.Client{ID: "client-1", Identity: websocket.Identity{UserID: 5, Username: "viewer", Role: "user"}, Send: make(chan []byte, 1), Hub: hub, ConnectedAt: time.Now()}
+	hub.Register(client)
+	for deadline := time.Now().Add(time.Second); hub.ClientCount() == 0 && time.Now().Before(deadline); {
+		time.Sleep(time.Millisecond)
+	}
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Next()
+	})
+	r.GET("/admin/websocket/clients", ListWebSocketClientsHandler)
+	r.DELETE("/admin/websocket/clients/:clientId", DisconnectWebSocketClientHandler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/websocket/clients", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var list struct {
+		Clients []websocket.ClientInfo `json:"clients"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Clients) != 1 || list.Clients[0].ID != "client-1" || list.Clients[0].UserID != 5 {
+		t.Fatalf("Unexpected client list: %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/websocket/clients/client-1", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if hub.ClientCount() != 0 {
+		t.Error("Expected client to be removed from the hub")
+	}
+
+	var logs []models.SecurityAuditLog
+	db.DB.Where("event_action = ?", "websocket_disconnect").Find(&logs)
+	if len(logs) != 1 {
+		t.Errorf("Expected one websocket_disconnect audit log, got %d", len(logs))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/websocket/clients/client-1", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for unknown client, got %d", w.Code)
+	}
+}
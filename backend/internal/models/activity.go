@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Sources of user activity entries
+const (
+	ActivitySourceAudit   = "audit"   // security audit logs: logins, role changes, ...
+	ActivitySourceFile    = "file"    // file access logs: uploads, downloads, ...
+	ActivitySourceCommand = "command" // command executions
+)
+
+// ActivityEntry represents one event of a user's activity feed
+type ActivityEntry struct {
+	Source     string    `json:"source"`
+	ID         uint      `json:"id"`     // ID within the source table
+	Action     string    `json:"action"` // e.g. auth.login, download or the command run
+	Resource   string    `json:"resource"`
+	ResourceID *uint     `json:"resource_id"`
+	Status     string    `json:"status"`
+	IPAddress  string    `json:"ip_address"`
+	Details    string    `json:"details"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// activityQuery merges the activity sources of one user into a single stream
+// of ActivityEntry columns
+const activityQuery = `
+	SELECT 'audit' AS source, id, event_type || '.' || event_action AS action, resource, resource_id,
+		status, ip_address, details, created_at
+	FROM security_audit_logs WHERE user_id = @user
+	UNION ALL
+	SELECT 'file', id, action, 'file', file_id, status, ip_address, '', created_at
+	FROM file_access_logs WHERE user_id = @user
+	UNION ALL
+	SELECT 'command', id, command, 'command', id,
+		CASE WHEN exit_code = 0 THEN 'success' ELSE 'failure' END, '', args, created_at
+	FROM commands WHERE user_id = @user`
+
+// GetUserActivity returns a page of a user's audit logs, file accesses and
+// command executions merged into one feed, most recent first, and the number
+// of entries across all sources
+func GetUserActivity(db *gorm.DB, userID uint, limit, offset int) ([]ActivityEntry, int64, error) {
+	args := map[string]interface{}{"user": userID, "limit": limit, "offset": offset}
+
+	var total int64
+	if err := db.Raw("SELECT COUNT(*) FROM ("+activityQuery+")", args).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []ActivityEntry
+	err := db.Raw(activityQuery+" ORDER BY created_at DESC, source, id DESC LIMIT @limit OFFSET @offset", args).
+		Scan(&entries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
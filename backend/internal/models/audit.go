@@ -1,7 +1,12 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
 	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -37,8 +42,82 @@ type AuditEvent struct {
 	Severity    string `json:"severity"`
 }
 
-// GetAuditEvents returns predefined audit events
+// AuditSeverities are the valid audit event severities, least severe first
+var AuditSeverities = []string{"low", "medium", "high", "critical"}
+
+// auditEventKeyPattern matches audit event keys such as "invoice_approved"
+var auditEventKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+var (
+	ErrInvalidAuditEventKey = errors.New("audit event key must be lowercase letters, digits and underscores, starting with a letter")
+	ErrAuditEventExists     = errors.New("audit event is already defined")
+)
+
+var (
+	// customAuditEvents holds the events registered with RegisterAuditEvent
+	customAuditEvents = make(map[string]AuditEvent)
+	customAuditMutex  sync.RWMutex
+)
+
+// ValidateAuditEvent checks an event definition before it is registered
+func ValidateAuditEvent(key string, event AuditEvent) error {
+	if !auditEventKeyPattern.MatchString(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidAuditEventKey, key)
+	}
+	if event.Type == "" || event.Action == "" {
+		return fmt.Errorf("audit event %s requires a type and an action", key)
+	}
+	for _, severity := range AuditSeverities {
+		if event.Severity == severity {
+			return nil
+		}
+	}
+	return fmt.Errorf("audit event %s has invalid severity %q, must be low, medium, high or critical", key, event.Severity)
+}
+
+// RegisterAuditEvent adds an event definition to the taxonomy, so it can be
+// logged by key like a predefined one. Predefined events can't be redefined.
+func RegisterAuditEvent(key string, event AuditEvent) error {
+	if err := ValidateAuditEvent(key, event); err != nil {
+		return err
+	}
+	if _, exists := predefinedAuditEvents()[key]; exists {
+		return fmt.Errorf("%w: %s", ErrAuditEventExists, key)
+	}
+
+	customAuditMutex.Lock()
+	defer customAuditMutex.Unlock()
+	customAuditEvents[key] = event
+	return nil
+}
+
+// LookupAuditEvent returns the definition of a predefined or registered event
+func LookupAuditEvent(key string) (AuditEvent, bool) {
+	if event, exists := predefinedAuditEvents()[key]; exists {
+		return event, true
+	}
+
+	customAuditMutex.RLock()
+	defer customAuditMutex.RUnlock()
+	event, exists := customAuditEvents[key]
+	return event, exists
+}
+
+// GetAuditEvents returns the predefined audit events and those registered with
+// RegisterAuditEvent
 func GetAuditEvents() map[string]AuditEvent {
+	events := predefinedAuditEvents()
+
+	customAuditMutex.RLock()
+	defer customAuditMutex.RUnlock()
+	for key, event := range customAuditEvents {
+		events[key] = event
+	}
+	return events
+}
+
+// predefinedAuditEvents returns the audit events built into the application
+func predefinedAuditEvents() map[string]AuditEvent {
 	return map[string]AuditEvent{
 		"login_success": {
 			Type:        "authentication",
@@ -46,6 +125,12 @@ func GetAuditEvents() map[string]AuditEvent {
 			Description: "User successfully logged in",
 			Severity:    "low",
 		},
+		"login_new_device": {
+			Type:        "authentication",
+			Action:      "login_new_device",
+			Description: "User logged in from a new device",
+			Severity:    "medium",
+		},
 		"login_failure": {
 			Type:        "authentication",
 			Action:      "login",
@@ -64,6 +149,18 @@ func GetAuditEvents() map[string]AuditEvent {
 			Description: "New user registered",
 			Severity:    "low",
 		},
+		"onboarding_failed": {
+			Type:        "system",
+			Action:      "onboard",
+			Description: "Onboarding hook of a new user failed",
+			Severity:    "low",
+		},
+		"registration_role_rejected": {
+			Type:        "security",
+			Action:      "register",
+			Description: "Self-registration requesting an elevated role rejected",
+			Severity:    "high",
+		},
 		"password_change": {
 			Type:        "authentication",
 			Action:      "password_change",
@@ -88,6 +185,42 @@ func GetAuditEvents() map[string]AuditEvent {
 			Description: "File deleted",
 			Severity:    "medium",
 		},
+		"file_rename": {
+			Type:        "file_operation",
+			Action:      "rename",
+			Description: "File renamed",
+			Severity:    "medium",
+		},
+		"file_verify": {
+			Type:        "file_operation",
+			Action:      "verify",
+			Description: "File integrity verified and re-scanned",
+			Severity:    "medium",
+		},
+		"malware_detected": {
+			Type:        "security",
+			Action:      "quarantine",
+			Description: "Malware detected in an uploaded file, file quarantined",
+			Severity:    "critical",
+		},
+		"quarantine_release": {
+			Type:        "file_operation",
+			Action:      "quarantine_release",
+			Description: "Quarantined file released",
+			Severity:    "high",
+		},
+		"quarantine_delete": {
+			Type:        "file_operation",
+			Action:      "quarantine_delete",
+			Description: "Quarantined file deleted",
+			Severity:    "medium",
+		},
+		"file_expired": {
+			Type:        "file_operation",
+			Action:      "expire",
+			Description: "File deleted on expiry",
+			Severity:    "low",
+		},
 		"command_execute": {
 			Type:        "command_execution",
 			Action:      "execute",
@@ -112,18 +245,72 @@ func GetAuditEvents() map[string]AuditEvent {
 			Description: "Invalid CSRF token",
 			Severity:    "high",
 		},
+		"data_export": {
+			Type:        "data_access",
+			Action:      "export",
+			Description: "User data exported",
+			Severity:    "medium",
+		},
 		"session_expired": {
 			Type:        "session",
 			Action:      "expire",
 			Description: "Session expired",
 			Severity:    "low",
 		},
+		"session_evicted": {
+			Type:        "session",
+			Action:      "evict",
+			Description: "Session evicted due to per-user session limit",
+			Severity:    "medium",
+		},
+		"session_ip_mismatch": {
+			Type:        "session",
+			Action:      "ip_mismatch",
+			Description: "Session token used from a different IP address",
+			Severity:    "high",
+		},
+		"session_terminated": {
+			Type:        "session",
+			Action:      "terminate",
+			Description: "Session terminated by an admin",
+			Severity:    "medium",
+		},
+		"role_change_logout": {
+			Type:        "session",
+			Action:      "revoke",
+			Description: "Sessions revoked after role change",
+			Severity:    "medium",
+		},
+		"rate_limit_exempted": {
+			Type:        "security",
+			Action:      "rate_limit_exempt",
+			Description: "Rate limit bypassed by exemption",
+			Severity:    "low",
+		},
+		"websocket_disconnected": {
+			Type:        "admin",
+			Action:      "websocket_disconnect",
+			Description: "WebSocket client forcibly disconnected",
+			Severity:    "medium",
+		},
+		"maintenance_toggled": {
+			Type:        "admin",
+			Action:      "maintenance",
+			Description: "Maintenance mode toggled",
+			Severity:    "high",
+		},
 		"admin_action": {
 			Type:        "admin",
 			Action:      "action",
 			Description: "Administrative action performed",
 			Severity:    "medium",
 		},
+		"security_alert": {
+			Type:        "security",
+			Action:      "alert",
+			Description: "Audit alert rule threshold reached",
+			Severity:    "high",
+		},
 		"system_error": {
 			Type:        "system",
 			Action:      "error",
@@ -133,9 +320,29 @@ func GetAuditEvents() map[string]AuditEvent {
 	}
 }
 
-// CreateSecurityAuditLog creates a new security audit log entry
+// CreateSecurityAuditLog creates a new security audit log entry and publishes it
+// to GlobalAuditLogBroadcaster subscribers
 func CreateSecurityAuditLog(db *gorm.DB, log *SecurityAuditLog) error {
-	return db.Create(log).Error
+	if err := db.Create(log).Error; err != nil {
+		return err
+	}
+	GlobalAuditLogBroadcaster.Publish(*log)
+	return nil
+}
+
+// CreateSecurityAuditLogs creates audit log entries in batches of batchSize and
+// publishes them to GlobalAuditLogBroadcaster subscribers
+func CreateSecurityAuditLogs(db *gorm.DB, logs []SecurityAuditLog, batchSize int) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	if err := db.CreateInBatches(logs, batchSize).Error; err != nil {
+		return err
+	}
+	for _, log := range logs {
+		GlobalAuditLogBroadcaster.Publish(log)
+	}
+	return nil
 }
 
 // GetSecurityAuditLogs retrieves security audit logs with filtering
@@ -159,6 +366,9 @@ func GetSecurityAuditLogs(db *gorm.DB, filters map[string]interface{}, limit, of
 	if ipAddress, exists := filters["ip_address"]; exists {
 		query = query.Where("ip_address = ?", ipAddress)
 	}
+	if requestID, exists := filters["request_id"]; exists {
+		query = query.Where("request_id = ?", requestID)
+	}
 	if startDate, exists := filters["start_date"]; exists {
 		query = query.Where("created_at >= ?", startDate)
 	}
@@ -177,6 +387,95 @@ func GetSecurityAuditLogs(db *gorm.DB, filters map[string]interface{}, limit, of
 	return logs, err
 }
 
+// IPCount represents how many audit log entries came from an IP address
+type IPCount struct {
+	IP    string `json:"ip"`
+	Count int64  `json:"count"`
+}
+
+// GetTopFailureIPs returns the IP addresses with the most failed audit events
+// since the given time, most frequent first
+func GetTopFailureIPs(db *gorm.DB, since time.Time, limit int) ([]IPCount, error) {
+	var counts []IPCount
+	err := db.Model(&SecurityAuditLog{}).
+		Select("ip_address AS ip, COUNT(*) AS count").
+		Where("status = ? AND created_at >= ? AND ip_address <> ''", "failure", since).
+		Group("ip_address").
+		Order("count DESC").
+		Limit(limit).
+		Scan(&counts).Error
+	return counts, err
+}
+
+// AuditLogMatch selects audit logs by field values; empty fields match anything
+type AuditLogMatch struct {
+	EventType   string `json:"event_type"`
+	EventAction string `json:"event_action"`
+	Severity    string `json:"severity"`
+	Status      string `json:"status"`
+}
+
+// AuditGroupCount represents how many matching audit log entries share a group key
+type AuditGroupCount struct {
+	Key   string `json:"key" gorm:"column:group_key"`
+	Count int64  `json:"count"`
+}
+
+// auditGroupColumns are the columns audit logs can be counted by
+var auditGroupColumns = map[string]string{
+	"":           "''",
+	"ip_address": "ip_address",
+	"user_id":    "user_id",
+}
+
+// ValidAuditGroupColumn checks if audit logs can be counted by column
+func ValidAuditGroupColumn(column string) bool {
+	_, ok := auditGroupColumns[column]
+	return ok
+}
+
+// CountAuditLogsByGroup counts the audit logs matching match since the given
+// time, grouped by column (ip_address, user_id, or "" for all together), and
+// returns the groups of at least minCount entries, largest first. Entries
+// without a value in column are left out.
+func CountAuditLogsByGroup(db *gorm.DB, match AuditLogMatch, column string, since time.Time, minCount int64) ([]AuditGroupCount, error) {
+	expression, ok := auditGroupColumns[column]
+	if !ok {
+		return nil, fmt.Errorf("audit logs can't be grouped by %q", column)
+	}
+
+	query := db.Model(&SecurityAuditLog{}).Where("created_at >= ?", since)
+	for field, value := range map[string]string{
+		"event_type":   match.EventType,
+		"event_action": match.EventAction,
+		"severity":     match.Severity,
+		"status":       match.Status,
+	} {
+		if value != "" {
+			query = query.Where(field+" = ?", value)
+		}
+	}
+	switch column {
+	case "ip_address":
+		query = query.Where("ip_address <> ''")
+	case "user_id":
+		query = query.Where("user_id IS NOT NULL")
+	}
+
+	// Counting everything together needs no GROUP BY, which rejects constants
+	if column != "" {
+		query = query.Group(expression)
+	}
+
+	var counts []AuditGroupCount
+	err := query.
+		Select(expression+" AS group_key, COUNT(*) AS count").
+		Having("COUNT(*) >= ?", minCount).
+		Order("count DESC").
+		Scan(&counts).Error
+	return counts, err
+}
+
 // GetSecurityAuditStats returns security audit statistics
 func GetSecurityAuditStats(db *gorm.DB) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
@@ -238,7 +537,17 @@ func GetSecurityAuditStats(db *gorm.DB) (map[string]interface{}, error) {
 
 // CleanupOldAuditLogs removes old audit logs
 func CleanupOldAuditLogs(db *gorm.DB, olderThanDays int) error {
-	cutoffDate := time.Now().AddDate(0, 0, -olderThanDays)
-	result := db.Where("created_at < ?", cutoffDate).Delete(&SecurityAuditLog{})
-	return result.Error
+	_, err := DeleteAuditLogsBefore(db, time.Now().AddDate(0, 0, -olderThanDays))
+	return err
+}
+
+// DeleteAuditLogsBefore removes audit logs created before cutoff and returns the
+// number removed. It is the only delete of audit logs the append-only guard allows.
+// Logs younger than MinAuditLogRetentionDays are kept whatever the cutoff.
+func DeleteAuditLogsBefore(db *gorm.DB, cutoff time.Time) (int64, error) {
+	if latest := time.Now().AddDate(0, 0, -MinAuditLogRetentionDays); cutoff.After(latest) {
+		cutoff = latest
+	}
+	result := db.Set(auditRetentionKey, true).Where("created_at < ?", cutoff).Delete(&SecurityAuditLog{})
+	return result.RowsAffected, result.Error
 }
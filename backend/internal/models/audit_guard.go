@@ -0,0 +1,77 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ErrAuditLogImmutable is returned for any update of an audit log, and for any
+// delete outside the retention cleanup
+var ErrAuditLogImmutable = errors.New("audit logs are append-only")
+
+// MinAuditLogRetentionDays is how long audit logs are kept at least. The
+// retention cleanup never removes younger logs, and the database rejects
+// deleting them.
+const MinAuditLogRetentionDays = 30
+
+// auditRetentionKey marks a statement as the retention cleanup, the only
+// delete of audit logs allowed
+const auditRetentionKey = "audit:retention"
+
+// BeforeUpdate rejects every update of an audit log
+func (SecurityAuditLog) BeforeUpdate(tx *gorm.DB) error {
+	return ErrAuditLogImmutable
+}
+
+// BeforeDelete rejects deletes of audit logs not made by DeleteAuditLogsBefore
+func (SecurityAuditLog) BeforeDelete(tx *gorm.DB) error {
+	if retention, _ := tx.Get(auditRetentionKey); retention == true {
+		return nil
+	}
+	return ErrAuditLogImmutable
+}
+
+// auditLogTriggers create triggers rejecting updates of audit logs, and deletes
+// of logs younger than MinAuditLogRetentionDays, for each driver that supports
+// them, so raw SQL and statements skipping hooks are blocked as well. The
+// database can't tell the retention cleanup apart from other deletes, so older
+// logs are left to BeforeDelete.
+var auditLogTriggers = map[string][]string{
+	"sqlite": {
+		`CREATE TRIGGER IF NOT EXISTS security_audit_logs_append_only
+			BEFORE UPDATE ON security_audit_logs
+			BEGIN SELECT RAISE(ABORT, 'audit logs are append-only'); END`,
+		fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS security_audit_logs_retained
+			BEFORE DELETE ON security_audit_logs
+			WHEN julianday(OLD.created_at) > julianday('now', '-%d days')
+			BEGIN SELECT RAISE(ABORT, 'audit logs are append-only'); END`, MinAuditLogRetentionDays),
+	},
+	"postgres": {
+		`CREATE OR REPLACE FUNCTION security_audit_logs_append_only() RETURNS trigger AS $$
+			BEGIN RAISE EXCEPTION 'audit logs are append-only'; END;
+			$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS security_audit_logs_append_only ON security_audit_logs`,
+		`CREATE TRIGGER security_audit_logs_append_only
+			BEFORE UPDATE ON security_audit_logs
+			FOR EACH ROW EXECUTE FUNCTION security_audit_logs_append_only()`,
+		`DROP TRIGGER IF EXISTS security_audit_logs_retained ON security_audit_logs`,
+		fmt.Sprintf(`CREATE TRIGGER security_audit_logs_retained
+			BEFORE DELETE ON security_audit_logs
+			FOR EACH ROW WHEN (OLD.created_at > now() - interval '%d days')
+			EXECUTE FUNCTION security_audit_logs_append_only()`, MinAuditLogRetentionDays),
+	},
+}
+
+// InstallAuditLogGuard creates the database triggers that reject updates of
+// audit logs and deletes of retained ones. Drivers without triggers rely on
+// the model hooks alone.
+func InstallAuditLogGuard(db *gorm.DB) error {
+	for _, statement := range auditLogTriggers[db.Dialector.Name()] {
+		if err := db.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to install audit log guard: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAuditLogGuard_BlocksUpdatesAndDeletes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&User{}, &SecurityAuditLog{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	if err := InstallAuditLogGuard(db); err != nil {
+		t.Fatalf("Failed to install guard: %v", err)
+	}
+	// Installing again, as every startup does, is fine
+	if err := InstallAuditLogGuard(db); err != nil {
+		t.Fatalf("Failed to reinstall guard: %v", err)
+	}
+
+	entry := &SecurityAuditLog{EventType: "auth", EventAction: "login", Severity: "low", Status: "success", CreatedAt: time.Now()}
+	if err := CreateSecurityAuditLog(db, entry); err != nil {
+		t.Fatalf("Failed to create audit log: %v", err)
+	}
+
+	if err := db.Model(entry).Update("status", "failure").Error; !errors.Is(err, ErrAuditLogImmutable) {
+		t.Errorf("Expected an update to be rejected, got %v", err)
+	}
+	entry.Status = "failure"
+	if err := db.Save(entry).Error; !errors.Is(err, ErrAuditLogImmutable) {
+		t.Errorf("Expected a save to be rejected, got %v", err)
+	}
+
+	// The trigger catches what the hooks don't see
+	if err := db.Session(&gorm.Session{SkipHooks: true}).Model(entry).Update("status", "failure").Error; err == nil || !strings.Contains(err.Error(), "append-only") {
+		t.Errorf("Expected an update skipping hooks to be rejected, got %v", err)
+	}
+	if err := db.Exec("UPDATE security_audit_logs SET status = 'failure'").Error; err == nil {
+		t.Error("Expected a raw update to be rejected")
+	}
+
+	if err := db.Delete(entry).Error; !errors.Is(err, ErrAuditLogImmutable) {
+		t.Errorf("Expected a delete to be rejected, got %v", err)
+	}
+
+	var stored SecurityAuditLog
+	if err := db.First(&stored, entry.ID).Error; err != nil || stored.Status != "success" {
+		t.Fatalf("Expected the entry to be unchanged, got %+v: %v", stored, err)
+	}
+
+	// Raw deletes of retained entries are rejected by the database
+	if err := db.Exec("DELETE FROM security_audit_logs").Error; err == nil || !strings.Contains(err.Error(), "append-only") {
+		t.Errorf("Expected a raw delete to be rejected, got %v", err)
+	}
+
+	// Only the retention cleanup deletes, and never entries younger than the minimum
+	old := &SecurityAuditLog{EventType: "auth", EventAction: "login", Severity: "low", Status: "success", CreatedAt: time.Now().AddDate(0, 0, -MinAuditLogRetentionDays-1)}
+	if err := CreateSecurityAuditLog(db, old); err != nil {
+		t.Fatalf("Failed to create audit log: %v", err)
+	}
+	removed, err := DeleteAuditLogsBefore(db, time.Now().Add(time.Hour))
+	if err != nil || removed != 1 {
+		t.Errorf("Expected the retention cleanup to remove the old entry only, got %d: %v", removed, err)
+	}
+	if err := db.First(&stored, entry.ID).Error; err != nil {
+		t.Errorf("Expected the recent entry to be kept: %v", err)
+	}
+}
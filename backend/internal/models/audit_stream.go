@@ -0,0 +1,131 @@
+package models
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultAuditSubscriptionBuffer is the number of pending entries a subscriber may queue
+const DefaultAuditSubscriptionBuffer = 64
+
+// auditSeverityRank orders severities for minimum severity filters
+var auditSeverityRank = map[string]int{"low": 1, "medium": 2, "high": 3, "critical": 4}
+
+// IsValidAuditSeverity checks if a severity is one used by audit logs
+func IsValidAuditSeverity(severity string) bool {
+	_, ok := auditSeverityRank[severity]
+	return ok
+}
+
+// AuditLogFilter selects which audit log entries a subscriber receives; empty fields match everything
+type AuditLogFilter struct {
+	Severities  []string
+	MinSeverity string
+	EventTypes  []string
+}
+
+// Matches checks if an audit log entry passes the filter
+func (f AuditLogFilter) Matches(log *SecurityAuditLog) bool {
+	if len(f.Severities) > 0 && !containsString(f.Severities, log.Severity) {
+		return false
+	}
+	if f.MinSeverity != "" && auditSeverityRank[log.Severity] < auditSeverityRank[f.MinSeverity] {
+		return false
+	}
+	if len(f.EventTypes) > 0 && !containsString(f.EventTypes, log.EventType) {
+		return false
+	}
+	return true
+}
+
+// containsString checks if a slice contains a value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditLogSubscription receives newly created audit log entries
+type AuditLogSubscription struct {
+	C       <-chan SecurityAuditLog
+	ch      chan SecurityAuditLog
+	filter  AuditLogFilter
+	dropped int64
+}
+
+// TakeDropped returns and resets the number of entries dropped because the
+// subscriber fell behind
+func (s *AuditLogSubscription) TakeDropped() int64 {
+	return atomic.SwapInt64(&s.dropped, 0)
+}
+
+// AuditLogBroadcaster fans out newly created audit log entries to subscribers.
+// Publishing never blocks: a subscriber whose buffer is full misses the entry
+// and has it counted as dropped.
+type AuditLogBroadcaster struct {
+	subscribers map[*AuditLogSubscription]struct{}
+	mutex       sync.RWMutex
+}
+
+// NewAuditLogBroadcaster creates a new audit log broadcaster
+func NewAuditLogBroadcaster() *AuditLogBroadcaster {
+	return &AuditLogBroadcaster{
+		subscribers: make(map[*AuditLogSubscription]struct{}),
+	}
+}
+
+// Subscribe registers a subscriber for entries matching filter
+func (b *AuditLogBroadcaster) Subscribe(filter AuditLogFilter, buffer int) *AuditLogSubscription {
+	if buffer <= 0 {
+		buffer = DefaultAuditSubscriptionBuffer
+	}
+
+	ch := make(chan SecurityAuditLog, buffer)
+	sub := &AuditLogSubscription{C: ch, ch: ch, filter: filter}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe removes a subscriber and closes its channel
+func (b *AuditLogBroadcaster) Unsubscribe(sub *AuditLogSubscription) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if _, exists := b.subscribers[sub]; exists {
+		delete(b.subscribers, sub)
+		close(sub.ch)
+	}
+}
+
+// SubscriberCount returns the number of active subscribers
+func (b *AuditLogBroadcaster) SubscriberCount() int {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return len(b.subscribers)
+}
+
+// Publish sends an entry to every matching subscriber without blocking
+func (b *AuditLogBroadcaster) Publish(log SecurityAuditLog) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for sub := range b.subscribers {
+		if !sub.filter.Matches(&log) {
+			continue
+		}
+		select {
+		case sub.ch <- log:
+		default:
+			atomic.AddInt64(&sub.dropped, 1)
+		}
+	}
+}
+
+// GlobalAuditLogBroadcaster receives every entry created by CreateSecurityAuditLog
+var GlobalAuditLogBroadcaster = NewAuditLogBroadcaster()
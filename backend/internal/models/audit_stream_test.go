@@ -0,0 +1,52 @@
+package models
+
+import "testing"
+
+func TestAuditLogBroadcaster_DropsWhenSubscriberFallsBehind(t *testing.T) {
+	broadcaster := NewAuditLogBroadcaster()
+	sub := broadcaster.Subscribe(AuditLogFilter{EventTypes: []string{"security"}}, 1)
+
+	broadcaster.Publish(SecurityAuditLog{ID: 1, EventType: "security", Severity: "high"})
+	broadcaster.Publish(SecurityAuditLog{ID: 2, EventType: "authentication", Severity: "high"})
+	broadcaster.Publish(SecurityAuditLog{ID: 3, EventType: "security", Severity: "low"})
+
+	if log := <-sub.C; log.ID != 1 {
+		t.Errorf("Expected log 1, got %d", log.ID)
+	}
+	if dropped := sub.TakeDropped(); dropped != 1 {
+		t.Errorf("Expected 1 dropped log, got %d", dropped)
+	}
+	if dropped := sub.TakeDropped(); dropped != 0 {
+		t.Errorf("Expected the dropped count to reset, got %d", dropped)
+	}
+
+	broadcaster.Unsubscribe(sub)
+	if _, open := <-sub.C; open {
+		t.Error("Expected the channel to be closed after unsubscribing")
+	}
+	if broadcaster.SubscriberCount() != 0 {
+		t.Errorf("Expected no subscribers, got %d", broadcaster.SubscriberCount())
+	}
+	broadcaster.Publish(SecurityAuditLog{ID: 4, EventType: "security"}) // no panic after unsubscribe
+}
+
+func TestAuditLogFilter_Matches(t *testing.T) {
+	log := &SecurityAuditLog{EventType: "security", Severity: "medium"}
+
+	tests := []struct {
+		filter AuditLogFilter
+		want   bool
+	}{
+		{AuditLogFilter{}, true},
+		{AuditLogFilter{Severities: []string{"low", "medium"}}, true},
+		{AuditLogFilter{Severities: []string{"high"}}, false},
+		{AuditLogFilter{MinSeverity: "medium"}, true},
+		{AuditLogFilter{MinSeverity: "high"}, false},
+		{AuditLogFilter{EventTypes: []string{"authentication"}}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Matches(log); got != tt.want {
+			t.Errorf("Matches(%+v) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+// registerTestAuditEvent registers an event, removing it when the test ends
+func registerTestAuditEvent(t *testing.T, key string, event AuditEvent) error {
+	t.Cleanup(func() {
+		customAuditMutex.Lock()
+		defer customAuditMutex.Unlock()
+		delete(customAuditEvents, key)
+	})
+	return RegisterAuditEvent(key, event)
+}
+
+func TestRegisterAuditEvent(t *testing.T) {
+	event := AuditEvent{Type: "billing", Action: "refund", Description: "Refund issued", Severity: "medium"}
+	if err := registerTestAuditEvent(t, "refund_issued", event); err != nil {
+		t.Fatalf("Failed to register audit event: %v", err)
+	}
+
+	if got, exists := LookupAuditEvent("refund_issued"); !exists || got != event {
+		t.Errorf("Expected the registered event to be found, got %+v", got)
+	}
+	if _, exists := GetAuditEvents()["refund_issued"]; !exists {
+		t.Error("Expected the registered event to be listed with the predefined ones")
+	}
+	if _, exists := GetAuditEvents()["login_success"]; !exists {
+		t.Error("Expected the predefined events to be listed")
+	}
+}
+
+func TestRegisterAuditEvent_RejectsInvalidDefinitions(t *testing.T) {
+	valid := AuditEvent{Type: "billing", Action: "refund", Severity: "low"}
+
+	tests := map[string]struct {
+		key   string
+		event AuditEvent
+	}{
+		"bad key":          {"Refund-Issued", valid},
+		"missing type":     {"refund_issued", AuditEvent{Action: "refund", Severity: "low"}},
+		"bad severity":     {"refund_issued", AuditEvent{Type: "billing", Action: "refund", Severity: "urgent"}},
+		"predefined event": {"login_success", valid},
+	}
+	for name, tt := range tests {
+		if err := registerTestAuditEvent(t, tt.key, tt.event); err == nil {
+			t.Errorf("%s: expected the event to be rejected", name)
+		}
+	}
+
+	if err := registerTestAuditEvent(t, "login_success", valid); !errors.Is(err, ErrAuditEventExists) {
+		t.Errorf("Expected ErrAuditEventExists, got %v", err)
+	}
+}
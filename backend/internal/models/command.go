@@ -1,11 +1,14 @@
 package models
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 	"gorm.io/gorm"
 )
@@ -16,6 +19,8 @@ type Command struct {
 	Command     string    `json:"command" gorm:"not null;index:idx_cmd_command"`
 	Args        string    `json:"args" gorm:"type:text"`
 	Output      string    `json:"output" gorm:"type:text"`
+	OutputSize  int64     `json:"output_size"`      // full output size in bytes, before truncation
+	OutputTruncated bool  `json:"output_truncated"`
 	ExitCode    int       `json:"exit_code" gorm:"index:idx_cmd_exit_code"`
 	UserID      uint      `json:"user_id" gorm:"not null;index:idx_cmd_user_id"`
 	User        User      `json:"user" gorm:"foreignKey:UserID"`
@@ -33,14 +38,15 @@ func (Command) TableName() string {
 
 // CommandWhitelist represents allowed commands
 type CommandWhitelist struct {
-	ID          uint      `json:"id" gorm:"primaryKey"`
-	Command     string    `json:"command" gorm:"not null;uniqueIndex:idx_whitelist_command"`
-	Description string    `json:"description" gorm:"type:text"`
-	AllowedArgs string    `json:"allowed_args" gorm:"type:text"` // JSON array
-	MaxDuration int       `json:"max_duration" gorm:"default:30000"` // 30 seconds default
-	IsActive    bool      `json:"is_active" gorm:"default:true;index:idx_whitelist_active"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Command      string    `json:"command" gorm:"not null;uniqueIndex:idx_whitelist_command"`
+	Description  string    `json:"description" gorm:"type:text"`
+	AllowedArgs  string    `json:"allowed_args" gorm:"type:text"` // JSON array
+	MaxDuration  int       `json:"max_duration" gorm:"default:30000"` // 30 seconds default
+	AllowedRoles string    `json:"allowed_roles" gorm:"type:text"` // JSON array, empty = all roles
+	IsActive     bool      `json:"is_active" gorm:"default:true;index:idx_whitelist_active"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
 // TableName returns the table name for the CommandWhitelist model
@@ -48,11 +54,52 @@ func (CommandWhitelist) TableName() string {
 	return "command_whitelist"
 }
 
+var (
+	ErrCommandNotAllowed = errors.New("command is not allowed")
+	ErrCommandRoleDenied = errors.New("your role is not permitted to run this command")
+)
+
+// DefaultMaxCommandOutput is the default cap on captured command output
+const DefaultMaxCommandOutput = 64 * 1024 // 64KB
+
+// OutputTruncatedMarker is appended to command output cut at the size cap
+const OutputTruncatedMarker = "\n[output truncated]"
+
 // CommandExecutor handles command execution with security
 type CommandExecutor struct {
-	db           *gorm.DB
-	queryBuilder *OptimizedQueryBuilder
-	whitelist    map[string]*CommandWhitelist
+	db             *gorm.DB
+	queryBuilder   *OptimizedQueryBuilder
+	whitelist      map[string]*CommandWhitelist
+	maxOutputBytes int64
+	mutex          sync.RWMutex
+}
+
+// cappedBuffer captures writes up to a byte limit and counts everything written,
+// so a command keeps running (rather than failing on a closed pipe) once the cap is hit
+type cappedBuffer struct {
+	buf   bytes.Buffer
+	limit int64
+	total int64
+}
+
+// Write implements io.Writer
+func (cb *cappedBuffer) Write(p []byte) (int, error) {
+	cb.total += int64(len(p))
+
+	if remaining := cb.limit - int64(cb.buf.Len()); remaining > 0 {
+		if int64(len(p)) > remaining {
+			cb.buf.Write(p[:remaining])
+		} else {
+			cb.buf.Write(p)
+		}
+	}
+
+	return len(p), nil
+}
+
+// Truncated reports whether output was cut at the limit
+func (cb *cappedBuffer) Truncated() bool {
+	return cb.total > cb.limit
 }
 
 // NewCommandExecutor creates a new command executor
@@ -61,6 +108,7 @@ func NewCommandExecutor(db *gorm.DB) *CommandExecutor {
 		db:           db,
 		queryBuilder: NewOptimizedQueryBuilder(db),
 		whitelist:    make(map[string]*CommandWhitelist),
+		maxOutputBytes: DefaultMaxCommandOutput,
 	}
 	
 	// Load whitelist into memory for fast access
@@ -70,10 +118,10 @@ func NewCommandExecutor(db *gorm.DB) *CommandExecutor {
 }
 
 // ExecuteCommand executes a command with security validation
-func (ce *CommandExecutor) ExecuteCommand(ctx context.Context, command string, args []string, userID uint, workingDir string) (*Command, error) {
-	// Validate command against whitelist
-	if !ce.isCommandAllowed(command, args) {
-		return nil, fmt.Errorf("command '%s' is not allowed", command)
+func (ce *CommandExecutor) ExecuteCommand(ctx context.Context, command string, args []string, userID uint, role string, workingDir string) (*Command, error) {
+	// Validate command against whitelist and the requester's role
+	if err := ce.isCommandAllowed(command, args, role); err != nil {
+		return nil, fmt.Errorf("command '%s': %w", command, err)
 	}
 
 	// Create command record
@@ -89,12 +137,21 @@ func (ce *CommandExecutor) ExecuteCommand(ctx context.Context, command string, a
 	startTime := time.Now()
 	cmd := exec.CommandContext(ctx, command, args...)
 	cmd.Dir = workingDir
-	
-	output, err := cmd.Output()
+
+	// Stream stdout into a capped buffer instead of reading it all into memory
+	output := &cappedBuffer{limit: ce.GetMaxOutputBytes()}
+	cmd.Stdout = output
+
+	err := cmd.Run()
 	endTime := time.Now()
 	
 	cmdRecord.Duration = endTime.Sub(startTime).Milliseconds()
-	cmdRecord.Output = string(output)
+	cmdRecord.Output = output.buf.String()
+	cmdRecord.OutputSize = output.total
+	if output.Truncated() {
+		cmdRecord.OutputTruncated = true
+		cmdRecord.Output += OutputTruncatedMarker
+	}
 	
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
@@ -115,18 +172,43 @@ func (ce *CommandExecutor) ExecuteCommand(ctx context.Context, command string, a
 	return cmdRecord, nil
 }
 
-// isCommandAllowed checks if a command is allowed
-func (ce *CommandExecutor) isCommandAllowed(command string, args []string) bool {
+// GetMaxOutputBytes returns the cap on captured command output
+func (ce *CommandExecutor) GetMaxOutputBytes() int64 {
+	ce.mutex.RLock()
+	defer ce.mutex.RUnlock()
+	return ce.maxOutputBytes
+}
+
+// SetMaxOutputBytes sets the cap on captured command output
+func (ce *CommandExecutor) SetMaxOutputBytes(limit int64) error {
+	if limit <= 0 {
+		return fmt.Errorf("output limit must be positive")
+	}
+
+	ce.mutex.Lock()
+	defer ce.mutex.Unlock()
+	ce.maxOutputBytes = limit
+	return nil
+}
+
+// isCommandAllowed checks if a command and its args are whitelisted for a role
+func (ce *CommandExecutor) isCommandAllowed(command string, args []string, role string) error {
+	ce.mutex.RLock()
 	whitelistEntry, exists := ce.whitelist[command]
+	ce.mutex.RUnlock()
 	if !exists || !whitelistEntry.IsActive {
-		return false
+		return ErrCommandNotAllowed
+	}
+
+	if !whitelistEntry.AllowsRole(role) {
+		return ErrCommandRoleDenied
 	}
 
 	// Check if args are allowed (if specified)
 	if whitelistEntry.AllowedArgs != "" {
 		var allowedArgs []string
 		if err := json.Unmarshal([]byte(whitelistEntry.AllowedArgs), &allowedArgs); err != nil {
-			return false
+			return ErrCommandNotAllowed
 		}
 		
 		for _, arg := range args {
@@ -138,12 +220,37 @@ func (ce *CommandExecutor) isCommandAllowed(command string, args []string) bool
 				}
 			}
 			if !allowed {
-				return false
+				return ErrCommandNotAllowed
 			}
 		}
 	}
 
-	return true
+	return nil
+}
+
+// AllowsRole checks if a role may run the command. Admins may run every
+// whitelisted command; an empty role list allows all roles.
+func (cw *CommandWhitelist) AllowsRole(role string) bool {
+	if role == "admin" || cw.AllowedRoles == "" {
+		return true
+	}
+
+	var roles []string
+	if err := json.Unmarshal([]byte(cw.AllowedRoles), &roles); err != nil {
+		return false
+	}
+
+	if len(roles) == 0 {
+		return true
+	}
+
+	for _, allowedRole := range roles {
+		if allowedRole == role {
+			return true
+		}
+	}
+
+	return false
 }
 
 // loadWhitelist loads command whitelist into memory
@@ -153,35 +260,74 @@ func (ce *CommandExecutor) loadWhitelist() error {
 		return err
 	}
 
-	ce.whitelist = make(map[string]*CommandWhitelist)
+	// Build the new set first so readers never see a partially filled map
+	entries := make(map[string]*CommandWhitelist, len(whitelist))
 	for i := range whitelist {
-		ce.whitelist[whitelist[i].Command] = &whitelist[i]
+		entries[whitelist[i].Command] = &whitelist[i]
 	}
 
+	ce.mutex.Lock()
+	ce.whitelist = entries
+	ce.mutex.Unlock()
+
 	return nil
 }
 
-// GetCommandHistory retrieves command history with optimized query
-func (ce *CommandExecutor) GetCommandHistory(userID *uint, limit, offset int) ([]Command, error) {
+// CommandHistoryFilter narrows command history queries; zero values are ignored
+type CommandHistoryFilter struct {
+	UserID    *uint
+	Command   string // exact command name
+	ExitCode  *int
+	StartDate *time.Time
+	EndDate   *time.Time
+	Search    string // text contained in the arguments or output
+}
+
+// GetCommandHistory retrieves a page of command history matching the filter
+// together with the total number of matching commands. The user, command, exit
+// code and date filters are served by the commands table indexes.
+func (ce *CommandExecutor) GetCommandHistory(filter CommandHistoryFilter, limit, offset int) ([]Command, int64, error) {
+	query := ce.db.Model(&Command{})
+
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.Command != "" {
+		query = query.Where("command = ?", filter.Command)
+	}
+	if filter.ExitCode != nil {
+		query = query.Where("exit_code = ?", *filter.ExitCode)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("created_at >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("created_at <= ?", *filter.EndDate)
+	}
+	if filter.Search != "" {
+		searchPattern := "%" + filter.Search + "%"
+		query = query.Where("args LIKE ? OR output LIKE ?", searchPattern, searchPattern)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
 	var commands []Command
-	query := ce.db.Select("id, command, args, output, exit_code, user_id, working_dir, duration, created_at").
+	pageQuery := query.Select("id, command, args, output, output_size, output_truncated, exit_code, user_id, working_dir, duration, created_at").
 		Preload("User", func(db *gorm.DB) *gorm.DB {
 			return db.Select("id, username, email, role")
 		})
-
-	if userID != nil {
-		query = query.Where("user_id = ?", *userID)
-	}
-
 	if limit > 0 {
-		query = query.Limit(limit)
+		pageQuery = pageQuery.Limit(limit)
 	}
 	if offset > 0 {
-		query = query.Offset(offset)
+		pageQuery = pageQuery.Offset(offset)
 	}
 
-	err := query.Order("created_at DESC").Find(&commands).Error
-	return commands, err
+	err := pageQuery.Order("created_at DESC").Find(&commands).Error
+	return commands, total, err
 }
 
 // GetCommandStats retrieves command execution statistics
@@ -230,18 +376,26 @@ func (ce *CommandExecutor) GetCommandStats() (map[string]interface{}, error) {
 }
 
 // AddToWhitelist adds a command to the whitelist
-func (ce *CommandExecutor) AddToWhitelist(command string, description string, allowedArgs []string, maxDuration int) error {
+func (ce *CommandExecutor) AddToWhitelist(command string, description string, allowedArgs []string, maxDuration int, allowedRoles []string) error {
 	argsJSON, err := json.Marshal(allowedArgs)
 	if err != nil {
 		return err
 	}
 
+	var rolesJSON []byte
+	if len(allowedRoles) > 0 {
+		if rolesJSON, err = json.Marshal(allowedRoles); err != nil {
+			return err
+		}
+	}
+
 	whitelistEntry := &CommandWhitelist{
-		Command:     command,
-		Description: description,
-		AllowedArgs: string(argsJSON),
-		MaxDuration: maxDuration,
-		IsActive:    true,
+		Command:      command,
+		Description:  description,
+		AllowedArgs:  string(argsJSON),
+		MaxDuration:  maxDuration,
+		AllowedRoles: string(rolesJSON),
+		IsActive:     true,
 	}
 
 	if err := ce.db.Create(whitelistEntry).Error; err != nil {
@@ -263,24 +417,151 @@ func (ce *CommandExecutor) RemoveFromWhitelist(command string) error {
 	return ce.loadWhitelist()
 }
 
+// WhitelistEntry represents a command to import into the whitelist
+type WhitelistEntry struct {
+	Command      string   `json:"command"`
+	Description  string   `json:"description"`
+	AllowedArgs  []string `json:"allowed_args"`
+	MaxDuration  int      `json:"max_duration"`  // milliseconds, 0 = 30 seconds
+	AllowedRoles []string `json:"allowed_roles"` // empty = all roles
+}
+
+// WhitelistImportResult represents the outcome of a whitelist import
+type WhitelistImportResult struct {
+	Created     []string `json:"created"`
+	Updated     []string `json:"updated"`
+	Deactivated int64    `json:"deactivated"` // entries turned off by reset
+}
+
+// MaxWhitelistDuration is the longest max duration an imported command may have
+const MaxWhitelistDuration = 5 * 60 * 1000 // 5 minutes
+
+// validateWhitelistEntries checks a whitelist import before anything is written
+func validateWhitelistEntries(entries []WhitelistEntry) error {
+	if len(entries) == 0 {
+		return errors.New("no commands to import")
+	}
+
+	seen := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		if entry.Command == "" || strings.ContainsAny(entry.Command, " \t/\\") {
+			return fmt.Errorf("invalid command name %q", entry.Command)
+		}
+		if seen[entry.Command] {
+			return fmt.Errorf("command %q is listed more than once", entry.Command)
+		}
+		seen[entry.Command] = true
+
+		if entry.MaxDuration < 0 || entry.MaxDuration > MaxWhitelistDuration {
+			return fmt.Errorf("max duration of %q must be between 0 and %d ms", entry.Command, MaxWhitelistDuration)
+		}
+		for _, role := range entry.AllowedRoles {
+			if role == "" {
+				return fmt.Errorf("command %q has an empty role", entry.Command)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ImportWhitelist creates or updates whitelist entries in one transaction.
+// Existing entries with the same command are overwritten and reactivated.
+// With reset, every current entry is deactivated first, so only the imported
+// set stays allowed. Nothing is written if any entry is invalid.
+func (ce *CommandExecutor) ImportWhitelist(entries []WhitelistEntry, reset bool) (*WhitelistImportResult, error) {
+	if err := validateWhitelistEntries(entries); err != nil {
+		return nil, err
+	}
+
+	result := &WhitelistImportResult{Created: []string{}, Updated: []string{}}
+	err := ce.db.Transaction(func(tx *gorm.DB) error {
+		if reset {
+			deactivated := tx.Model(&CommandWhitelist{}).Where("is_active = ?", true).Update("is_active", false)
+			if deactivated.Error != nil {
+				return deactivated.Error
+			}
+			result.Deactivated = deactivated.RowsAffected
+		}
+
+		for _, entry := range entries {
+			argsJSON, err := json.Marshal(entry.AllowedArgs)
+			if err != nil {
+				return err
+			}
+			var rolesJSON []byte
+			if len(entry.AllowedRoles) > 0 {
+				if rolesJSON, err = json.Marshal(entry.AllowedRoles); err != nil {
+					return err
+				}
+			}
+			maxDuration := entry.MaxDuration
+			if maxDuration == 0 {
+				maxDuration = 30000
+			}
+
+			var existing CommandWhitelist
+			err = tx.Where("command = ?", entry.Command).First(&existing).Error
+			if err == gorm.ErrRecordNotFound {
+				if err := tx.Create(&CommandWhitelist{
+					Command:      entry.Command,
+					Description:  entry.Description,
+					AllowedArgs:  string(argsJSON),
+					MaxDuration:  maxDuration,
+					AllowedRoles: string(rolesJSON),
+					IsActive:     true,
+				}).Error; err != nil {
+					return err
+				}
+				result.Created = append(result.Created, entry.Command)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			// Map update, so false/empty values are written too
+			if err := tx.Model(&existing).Updates(map[string]interface{}{
+				"description":   entry.Description,
+				"allowed_args":  string(argsJSON),
+				"max_duration":  maxDuration,
+				"allowed_roles": string(rolesJSON),
+				"is_active":     true,
+			}).Error; err != nil {
+				return err
+			}
+			result.Updated = append(result.Updated, entry.Command)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Reload whitelist
+	return result, ce.loadWhitelist()
+}
+
 // InitializeDefaultWhitelist creates default allowed commands
 func (ce *CommandExecutor) InitializeDefaultWhitelist() error {
 	defaultCommands := []struct {
-		command     string
-		description string
-		allowedArgs []string
-		maxDuration int
+		command      string
+		description  string
+		allowedArgs  []string
+		maxDuration  int
+		allowedRoles []string // nil = all roles
 	}{
-		{"ls", "List directory contents", []string{"-l", "-a", "-h", "-la", "-lh"}, 5000},
-		{"pwd", "Print working directory", []string{}, 1000},
-		{"whoami", "Print current user", []string{}, 1000},
-		{"date", "Print current date", []string{}, 1000},
-		{"echo", "Print text", []string{}, 2000},
-		{"cat", "Display file contents", []string{}, 5000},
-		{"head", "Display first lines of file", []string{"-n", "-c"}, 5000},
-		{"tail", "Display last lines of file", []string{"-n", "-c", "-f"}, 5000},
-		{"grep", "Search text in files", []string{"-i", "-n", "-r", "-v"}, 10000},
-		{"find", "Find files", []string{"-name", "-type", "-size", "-mtime"}, 15000},
+		{"ls", "List directory contents", []string{"-l", "-a", "-h", "-la", "-lh"}, 5000, nil},
+		{"pwd", "Print working directory", []string{}, 1000, nil},
+		{"whoami", "Print current user", []string{}, 1000, nil},
+		{"date", "Print current date", []string{}, 1000, nil},
+		{"echo", "Print text", []string{}, 2000, nil},
+		{"cat", "Display file contents", []string{}, 5000, []string{"admin", "moderator"}},
+		{"head", "Display first lines of file", []string{"-n", "-c"}, 5000, []string{"admin", "moderator"}},
+		{"tail", "Display last lines of file", []string{"-n", "-c", "-f"}, 5000, []string{"admin", "moderator"}},
+		{"grep", "Search text in files", []string{"-i", "-n", "-r", "-v"}, 10000, []string{"admin", "moderator"}},
+		{"find", "Find files", []string{"-name", "-type", "-size", "-mtime"}, 15000, []string{"admin"}},
 	}
 
 	for _, cmd := range defaultCommands {
@@ -288,7 +569,7 @@ func (ce *CommandExecutor) InitializeDefaultWhitelist() error {
 		var count int64
 		ce.db.Model(&CommandWhitelist{}).Where("command = ?", cmd.command).Count(&count)
 		if count == 0 {
-			if err := ce.AddToWhitelist(cmd.command, cmd.description, cmd.allowedArgs, cmd.maxDuration); err != nil {
+			if err := ce.AddToWhitelist(cmd.command, cmd.description, cmd.allowedArgs, cmd.maxDuration, cmd.allowedRoles); err != nil {
 				return err
 			}
 		}
@@ -296,3 +577,29 @@ func (ce *CommandExecutor) InitializeDefaultWhitelist() error {
 
 	return nil
 }
+
+// DeleteCommandsBefore removes command history created before cutoff and returns the number removed
+func DeleteCommandsBefore(db *gorm.DB, cutoff time.Time) (int64, error) {
+	result := db.Where("created_at < ?", cutoff).Delete(&Command{})
+	return result.RowsAffected, result.Error
+}
+
+// SearchCommands searches command history by command name or arguments
+func SearchCommands(db *gorm.DB, query string, limit, offset int) ([]Command, error) {
+	var commands []Command
+	dbQuery := db.Select("id, command, args, output_size, output_truncated, exit_code, user_id, working_dir, duration, created_at").
+		Preload("User", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, username, email, role")
+		}).
+		Where("command LIKE ? OR args LIKE ?", "%"+query+"%", "%"+query+"%")
+
+	if limit > 0 {
+		dbQuery = dbQuery.Limit(limit)
+	}
+	if offset > 0 {
+		dbQuery = dbQuery.Offset(offset)
+	}
+
+	err := dbQuery.Order("created_at DESC").Find(&commands).Error
+	return commands, err
+}
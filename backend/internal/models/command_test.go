@@ -0,0 +1,321 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupCommandTestExecutor creates a command executor backed by an in-memory database
+func setupCommandTestExecutor(t *testing.T) *CommandExecutor {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(&User{}, &Command{}, &CommandWhitelist{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	return NewCommandExecutor(db)
+}
+
+func TestExecuteCommand_TruncatesOutputOverCap(t *testing.T) {
+	executor := setupCommandTestExecutor(t)
+	if err := executor.AddToWhitelist("seq", "Print numbers", []string{"1", "100000"}, 30000, nil); err != nil {
+		t.Fatalf("Failed to whitelist command: %v", err)
+	}
+	executor.SetMaxOutputBytes(1024)
+
+	cmd, err := executor.ExecuteCommand(context.Background(), "seq", []string{"1", "100000"}, 1, "user", "")
+	if err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	if !cmd.OutputTruncated {
+		t.Error("Expected output to be marked truncated")
+	}
+
+	if !strings.HasSuffix(cmd.Output, OutputTruncatedMarker) {
+		t.Errorf("Expected output to end with truncation marker, got %q", cmd.Output[len(cmd.Output)-40:])
+	}
+
+	if len(cmd.Output) != 1024+len(OutputTruncatedMarker) {
+		t.Errorf("Expected output capped at 1024 bytes plus marker, got %d", len(cmd.Output))
+	}
+
+	// seq 1 100000 prints 588895 bytes
+	if cmd.OutputSize != 588895 {
+		t.Errorf("Expected full output size 588895, got %d", cmd.OutputSize)
+	}
+
+	if cmd.ExitCode != 0 {
+		t.Errorf("Expected command to complete normally, got exit code %d", cmd.ExitCode)
+	}
+}
+
+func TestGetCommandHistory_ReportsTruncation(t *testing.T) {
+	executor := setupCommandTestExecutor(t)
+	if err := executor.AddToWhitelist("seq", "Print numbers", []string{"1", "100000"}, 30000, nil); err != nil {
+		t.Fatalf("Failed to whitelist command: %v", err)
+	}
+	executor.SetMaxOutputBytes(1024)
+
+	if _, err := executor.ExecuteCommand(context.Background(), "seq", []string{"1", "100000"}, 1, "user", ""); err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	commands, _, err := executor.GetCommandHistory(CommandHistoryFilter{}, 10, 0)
+	if err != nil || len(commands) != 1 {
+		t.Fatalf("Expected one history entry, got %d (%v)", len(commands), err)
+	}
+	if !commands[0].OutputTruncated || commands[0].OutputSize != 588895 {
+		t.Errorf("Expected truncated history entry of size 588895, got truncated=%v size=%d", commands[0].OutputTruncated, commands[0].OutputSize)
+	}
+
+	// Filtered history goes through the same column list
+	filtered, _, err := executor.GetCommandHistory(CommandHistoryFilter{Command: "seq", Search: "100000"}, 10, 0)
+	if err != nil || len(filtered) != 1 || !filtered[0].OutputTruncated || filtered[0].OutputSize != 588895 {
+		t.Errorf("Expected filtered history to report truncation, got %+v (%v)", filtered, err)
+	}
+
+	found, err := SearchCommands(executor.db, "seq", 10, 0)
+	if err != nil || len(found) != 1 {
+		t.Fatalf("Expected one search result, got %d (%v)", len(found), err)
+	}
+	if !found[0].OutputTruncated || found[0].OutputSize != 588895 {
+		t.Errorf("Expected truncated search result of size 588895, got truncated=%v size=%d", found[0].OutputTruncated, found[0].OutputSize)
+	}
+}
+
+func TestExecuteCommand_OutputUnderCapNotTruncated(t *testing.T) {
+	executor := setupCommandTestExecutor(t)
+	if err := executor.AddToWhitelist("echo", "Print text", []string{"hello"}, 30000, nil); err != nil {
+		t.Fatalf("Failed to whitelist command: %v", err)
+	}
+
+	cmd, err := executor.ExecuteCommand(context.Background(), "echo", []string{"hello"}, 1, "user", "")
+	if err != nil {
+		t.Fatalf("Failed to execute command: %v", err)
+	}
+
+	if cmd.OutputTruncated || cmd.Output != "hello\n" || cmd.OutputSize != 6 {
+		t.Errorf("Unexpected result: truncated=%v output=%q size=%d", cmd.OutputTruncated, cmd.Output, cmd.OutputSize)
+	}
+}
+
+func TestExecuteCommand_RoleRestrictedCommand(t *testing.T) {
+	executor := setupCommandTestExecutor(t)
+	if err := executor.AddToWhitelist("pwd", "Print working directory", []string{}, 1000, []string{"admin"}); err != nil {
+		t.Fatalf("Failed to whitelist command: %v", err)
+	}
+
+	if _, err := executor.ExecuteCommand(context.Background(), "pwd", nil, 2, "user", ""); !errors.Is(err, ErrCommandRoleDenied) {
+		t.Errorf("Expected ErrCommandRoleDenied for user, got %v", err)
+	}
+
+	if _, err := executor.ExecuteCommand(context.Background(), "pwd", nil, 1, "admin", ""); err != nil {
+		t.Errorf("Expected admin to run the command, got %v", err)
+	}
+
+	if _, err := executor.ExecuteCommand(context.Background(), "whoami", nil, 1, "admin", ""); !errors.Is(err, ErrCommandNotAllowed) {
+		t.Errorf("Expected ErrCommandNotAllowed for non-whitelisted command, got %v", err)
+	}
+}
+
+func TestCommandExecutor_WhitelistReloadDuringChecks(t *testing.T) {
+	executor := setupCommandTestExecutor(t)
+	if err := executor.AddToWhitelist("echo", "Print text", nil, 1000, nil); err != nil {
+		t.Fatalf("Failed to whitelist command: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if err := executor.isCommandAllowed("echo", nil, "user"); err != nil {
+					t.Errorf("Expected echo to stay allowed during reloads, got %v", err)
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 20; i++ {
+		if _, err := executor.ImportWhitelist([]WhitelistEntry{{Command: "echo", Description: "Print text"}}, false); err != nil {
+			t.Errorf("Failed to import whitelist: %v", err)
+			break
+		}
+	}
+	close(done)
+	wg.Wait()
+}
+
+func TestCommandWhitelist_AllowsRole(t *testing.T) {
+	open := &CommandWhitelist{}
+	if !open.AllowsRole("guest") {
+		t.Error("Expected entry without roles to allow every role")
+	}
+
+	restricted := &CommandWhitelist{AllowedRoles: `["moderator"]`}
+	if !restricted.AllowsRole("moderator") || !restricted.AllowsRole("admin") {
+		t.Error("Expected moderator and admin to be allowed")
+	}
+	if restricted.AllowsRole("user") {
+		t.Error("Expected user to be denied")
+	}
+}
+
+func TestImportWhitelist_MergesCustomSet(t *testing.T) {
+	executor := setupCommandTestExecutor(t)
+	if err := executor.InitializeDefaultWhitelist(); err != nil {
+		t.Fatalf("Failed to initialize whitelist: %v", err)
+	}
+
+	result, err := executor.ImportWhitelist([]WhitelistEntry{
+		{Command: "uptime", Description: "Show uptime", MaxDuration: 2000},
+		{Command: "ls", Description: "List files", AllowedArgs: []string{"-l"}, AllowedRoles: []string{"admin"}},
+	}, false)
+	if err != nil {
+		t.Fatalf("Failed to import whitelist: %v", err)
+	}
+
+	if len(result.Created) != 1 || result.Created[0] != "uptime" {
+		t.Errorf("Expected uptime to be created, got %v", result.Created)
+	}
+	if len(result.Updated) != 1 || result.Updated[0] != "ls" {
+		t.Errorf("Expected ls to be updated, got %v", result.Updated)
+	}
+	if err := executor.isCommandAllowed("uptime", nil, "user"); err != nil {
+		t.Errorf("Expected imported command to be allowed, got %v", err)
+	}
+	if err := executor.isCommandAllowed("ls", []string{"-l"}, "user"); !errors.Is(err, ErrCommandRoleDenied) {
+		t.Errorf("Expected ls to be restricted to admins, got %v", err)
+	}
+	if err := executor.isCommandAllowed("pwd", nil, "user"); err != nil {
+		t.Errorf("Expected untouched default command to stay allowed, got %v", err)
+	}
+}
+
+func TestImportWhitelist_ResetDeactivatesExisting(t *testing.T) {
+	executor := setupCommandTestExecutor(t)
+	if err := executor.InitializeDefaultWhitelist(); err != nil {
+		t.Fatalf("Failed to initialize whitelist: %v", err)
+	}
+
+	result, err := executor.ImportWhitelist([]WhitelistEntry{{Command: "pwd"}}, true)
+	if err != nil {
+		t.Fatalf("Failed to import whitelist: %v", err)
+	}
+
+	if result.Deactivated != 10 {
+		t.Errorf("Expected 10 entries deactivated, got %d", result.Deactivated)
+	}
+	if err := executor.isCommandAllowed("pwd", nil, "user"); err != nil {
+		t.Errorf("Expected imported command to be reactivated, got %v", err)
+	}
+	if err := executor.isCommandAllowed("date", nil, "user"); !errors.Is(err, ErrCommandNotAllowed) {
+		t.Errorf("Expected command missing from the import to be disabled, got %v", err)
+	}
+}
+
+func TestImportWhitelist_InvalidSetWritesNothing(t *testing.T) {
+	executor := setupCommandTestExecutor(t)
+
+	invalid := [][]WhitelistEntry{
+		{},
+		{{Command: "uptime"}, {Command: "uptime"}},
+		{{Command: "uptime"}, {Command: "/bin/sh"}},
+		{{Command: "uptime"}, {Command: "sleep", MaxDuration: MaxWhitelistDuration + 1}},
+	}
+	for _, entries := range invalid {
+		if _, err := executor.ImportWhitelist(entries, true); err == nil {
+			t.Errorf("Expected import of %+v to fail", entries)
+		}
+	}
+
+	var count int64
+	executor.db.Model(&CommandWhitelist{}).Where("command = ?", "uptime").Count(&count)
+	if count != 0 {
+		t.Errorf("Expected nothing written by failed imports, got %d entries", count)
+	}
+}
+
+func TestGetCommandHistory_Filters(t *testing.T) {
+	executor := setupCommandTestExecutor(t)
+
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	seed := []Command{
+		{Command: "ls", Args: `["-la","/var/log"]`, Output: "syslog", ExitCode: 0, UserID: 1, CreatedAt: day},
+		{Command: "ls", Args: `["/missing"]`, Output: "No such file or directory", ExitCode: 2, UserID: 2, CreatedAt: day.Add(24 * time.Hour)},
+		{Command: "cat", Args: `["/etc/hosts"]`, Output: "127.0.0.1 localhost", ExitCode: 0, UserID: 1, CreatedAt: day.Add(48 * time.Hour)},
+		{Command: "grep", Args: `["error","/var/log/app.log"]`, Output: "", ExitCode: 1, UserID: 2, CreatedAt: day.Add(72 * time.Hour)},
+	}
+	for i := range seed {
+		if err := executor.db.Create(&seed[i]).Error; err != nil {
+			t.Fatalf("Failed to seed command: %v", err)
+		}
+	}
+
+	userID := uint(2)
+	exitCode := 0
+	start := day.Add(24 * time.Hour)
+	end := day.Add(48 * time.Hour)
+	tests := []struct {
+		name   string
+		filter CommandHistoryFilter
+		want   []string // commands, newest first
+	}{
+		{"no filter", CommandHistoryFilter{}, []string{"grep", "cat", "ls", "ls"}},
+		{"user", CommandHistoryFilter{UserID: &userID}, []string{"grep", "ls"}},
+		{"command", CommandHistoryFilter{Command: "ls"}, []string{"ls", "ls"}},
+		{"exit code", CommandHistoryFilter{ExitCode: &exitCode}, []string{"cat", "ls"}},
+		{"date range", CommandHistoryFilter{StartDate: &start, EndDate: &end}, []string{"cat", "ls"}},
+		{"search args", CommandHistoryFilter{Search: "/var/log"}, []string{"grep", "ls"}},
+		{"search output", CommandHistoryFilter{Search: "localhost"}, []string{"cat"}},
+		{"combined", CommandHistoryFilter{Command: "ls", Search: "missing"}, []string{"ls"}},
+		{"no match", CommandHistoryFilter{Command: "rm"}, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			commands, total, err := executor.GetCommandHistory(tt.filter, 50, 0)
+			if err != nil {
+				t.Fatalf("Failed to get command history: %v", err)
+			}
+			if total != int64(len(tt.want)) {
+				t.Errorf("Expected total %d, got %d", len(tt.want), total)
+			}
+			got := make([]string, len(commands))
+			for i, cmd := range commands {
+				got[i] = cmd.Command
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	// The total counts every match, not just the page
+	commands, total, err := executor.GetCommandHistory(CommandHistoryFilter{}, 1, 1)
+	if err != nil || len(commands) != 1 || total != 4 {
+		t.Errorf("Expected a page of 1 out of 4, got %d of %d (%v)", len(commands), total, err)
+	}
+	if commands[0].Command != "cat" {
+		t.Errorf("Expected the second newest command, got %s", commands[0].Command)
+	}
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
 	"gorm.io/gorm"
 )
@@ -14,17 +16,37 @@ type File struct {
 	MimeType    string    `json:"mime_type" gorm:"not null"`
 	Size        int64     `json:"size" gorm:"not null"`
 	Path        string    `json:"path" gorm:"not null"`
-	Hash        string    `json:"hash" gorm:"uniqueIndex;not null"`
-	UserID      uint      `json:"user_id" gorm:"not null"`
+	Hash        string    `json:"hash" gorm:"uniqueIndex:idx_files_live_hash_user,priority:1,where:deleted_at IS NULL;not null"` // unique per owner among files not deleted
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex:idx_files_live_hash_user,priority:2,where:deleted_at IS NULL;not null"`
 	User        User      `json:"user" gorm:"foreignKey:UserID"`
 	IsPublic    bool      `json:"is_public" gorm:"default:false"`
 	Description string    `json:"description" gorm:"type:text"`
 	Tags        string    `json:"tags" gorm:"type:text"` // JSON array as string
+	IsScanned   bool      `json:"is_scanned" gorm:"default:false;index"` // set by the file scan job
+	IsSafe      bool      `json:"is_safe" gorm:"default:false"`          // only safe files can be downloaded
+	ScanResult  string    `json:"scan_result,omitempty"`                 // threat found by the scanner
+	ScanAttempts int      `json:"scan_attempts,omitempty"`               // failed scans so far
+	ScanError   string    `json:"scan_error,omitempty"`                  // error of the last failed scan
+	NextScanAt  *time.Time `json:"-" gorm:"index"`                       // failed scans are retried after a backoff
+	QuarantinedFrom string `json:"-"`                                   // path before the file was quarantined
+	QuarantinedAt *time.Time `json:"quarantined_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" gorm:"index"` // deleted by the file expiry job, nil = kept
+	IntegrityStatus string `json:"integrity_status,omitempty"` // result of the last verification, see FileIntegrityVerified
+	VerifiedAt  *time.Time `json:"verified_at,omitempty"`
+	Score       int       `json:"score,omitempty" gorm:"->;-:migration"` // search relevance, only set by searches
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index"`
 }
 
+// Results of verifying a file's content against its stored hash
+const (
+	FileIntegrityVerified = "verified" // content matches the hash computed on upload
+	FileIntegrityMismatch = "mismatch" // content changed since upload
+	FileIntegrityMissing  = "missing"  // content is gone from disk
+	FileIntegrityUnknown  = "unknown"  // stored hash isn't an md5 hash, content can't be compared
+)
+
 // FileMetadata represents additional file metadata
 type FileMetadata struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
@@ -38,17 +60,27 @@ type FileMetadata struct {
 
 // FileAccessLog represents file access logging
 type FileAccessLog struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	FileID    uint      `json:"file_id" gorm:"not null"`
-	File      File      `json:"file" gorm:"foreignKey:FileID"`
-	UserID    uint      `json:"user_id" gorm:"not null"`
-	User      User      `json:"user" gorm:"foreignKey:UserID"`
-	Action    string    `json:"action" gorm:"not null"` // upload, download, delete, view
-	IPAddress string    `json:"ip_address"`
-	UserAgent string    `json:"user_agent"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	FileID      uint      `json:"file_id" gorm:"not null"`
+	File        File      `json:"file" gorm:"foreignKey:FileID"`
+	UserID      uint      `json:"user_id" gorm:"not null"`
+	User        User      `json:"user" gorm:"foreignKey:UserID"`
+	Action      string    `json:"action" gorm:"not null"` // upload, download, delete, view, update, rename, verify
+	IPAddress   string    `json:"ip_address"`
+	UserAgent   string    `json:"user_agent"`
+	RequestID   string    `json:"request_id" gorm:"index"` // joins with the security audit logs of the same request
+	Status      string    `json:"status" gorm:"not null;default:success;index"` // success, not_found or denied
+	BytesServed int64     `json:"bytes_served"`
+	CreatedAt   time.Time `json:"created_at"`
 }
 
+// File access outcomes recorded in FileAccessLog.Status
+const (
+	FileAccessSuccess  = "success"
+	FileAccessNotFound = "not_found" // no such file record, or its content is missing from disk
+	FileAccessDenied   = "denied"    // not permitted, or blocked by the malware scan
+)
+
 // FileStats represents file statistics
 type FileStats struct {
 	TotalFiles    int64   `json:"total_files"`
@@ -73,6 +105,16 @@ func GetFileByID(db *gorm.DB, id uint) (*File, error) {
 	return &file, err
 }
 
+// GetFilesByIDs retrieves the files matching a set of IDs in a single query
+func GetFilesByIDs(db *gorm.DB, ids []uint) ([]File, error) {
+	var files []File
+	if len(ids) == 0 {
+		return files, nil
+	}
+	err := db.Preload("User").Where("id IN ?", ids).Find(&files).Error
+	return files, err
+}
+
 // GetFileByHash retrieves a file by hash
 func GetFileByHash(db *gorm.DB, hash string) (*File, error) {
 	var file File
@@ -80,6 +122,13 @@ func GetFileByHash(db *gorm.DB, hash string) (*File, error) {
 	return &file, err
 }
 
+// GetUserFileByHash retrieves a user's own file by hash
+func GetUserFileByHash(db *gorm.DB, userID uint, hash string) (*File, error) {
+	var file File
+	err := db.Preload("User").Where("hash = ? AND user_id = ?", hash, userID).First(&file).Error
+	return &file, err
+}
+
 // GetFilesByUser retrieves all files for a specific user
 func GetFilesByUser(db *gorm.DB, userID uint, limit, offset int) ([]File, error) {
 	var files []File
@@ -128,10 +177,48 @@ func GetFilesByType(db *gorm.DB, fileType string, limit, offset int) ([]File, er
 	return files, err
 }
 
-// SearchFiles searches files by filename or description
+// Relevance of a search match, from best to worst: a name match beats a
+// description match, and an exact match beats a prefix beats a substring.
+// Names match exactly with or without their extension.
+const (
+	ScoreExactName            = 6
+	ScorePrefixName           = 5
+	ScoreSubstringName        = 4
+	ScoreExactDescription     = 3
+	ScorePrefixDescription    = 2
+	ScoreSubstringDescription = 1
+)
+
+// fileSearchScore returns the SQL expression scoring a file against a search
+// query, selected as score, with its arguments
+func fileSearchScore(query string) (string, []interface{}) {
+	q := strings.ToLower(query)
+	expr := fmt.Sprintf(`(CASE
+		WHEN LOWER(original_name) = ? OR LOWER(original_name) LIKE ? OR LOWER(filename) = ? THEN %d
+		WHEN LOWER(original_name) LIKE ? OR LOWER(filename) LIKE ? THEN %d
+		WHEN LOWER(original_name) LIKE ? OR LOWER(filename) LIKE ? THEN %d
+		WHEN LOWER(description) = ? THEN %d
+		WHEN LOWER(description) LIKE ? THEN %d
+		ELSE %d END) AS score`,
+		ScoreExactName, ScorePrefixName, ScoreSubstringName,
+		ScoreExactDescription, ScorePrefixDescription, ScoreSubstringDescription)
+	args := []interface{}{
+		q, q + ".%", q,
+		q + "%", q + "%",
+		"%" + q + "%", "%" + q + "%",
+		q,
+		q + "%",
+	}
+	return expr, args
+}
+
+// SearchFiles searches files by filename or description, best matches first
+// and the most recent among equally relevant ones
 func SearchFiles(db *gorm.DB, query string, userID *uint, limit, offset int) ([]File, error) {
 	var files []File
-	dbQuery := db.Preload("User").Where("filename LIKE ? OR original_name LIKE ? OR description LIKE ?", 
+	score, scoreArgs := fileSearchScore(query)
+	dbQuery := db.Preload("User").Select("files.*, "+score, scoreArgs...).
+		Where("filename LIKE ? OR original_name LIKE ? OR description LIKE ?", 
 		"%"+query+"%", "%"+query+"%", "%"+query+"%")
 	
 	if userID != nil {
@@ -145,7 +232,7 @@ func SearchFiles(db *gorm.DB, query string, userID *uint, limit, offset int) ([]
 		dbQuery = dbQuery.Offset(offset)
 	}
 	
-	err := dbQuery.Order("created_at DESC").Find(&files).Error
+	err := dbQuery.Order("score DESC, created_at DESC").Find(&files).Error
 	return files, err
 }
 
@@ -154,6 +241,20 @@ func UpdateFile(db *gorm.DB, file *File) error {
 	return db.Save(file).Error
 }
 
+// UpdateFileMetadata updates the given mutable metadata columns of a file.
+// Only description, tags and is_public may be changed.
+func UpdateFileMetadata(db *gorm.DB, file *File, updates map[string]interface{}) error {
+	for column := range updates {
+		switch column {
+		case "original_name", "description", "tags", "is_public":
+		default:
+			return fmt.Errorf("file column %q is not mutable", column)
+		}
+	}
+
+	return db.Model(file).Updates(updates).Error
+}
+
 // DeleteFile soft deletes a file
 func DeleteFile(db *gorm.DB, id uint) error {
 	return db.Delete(&File{}, id).Error
@@ -230,3 +331,103 @@ func GetFileAccessLogs(db *gorm.DB, fileID uint, limit, offset int) ([]FileAcces
 	err := query.Order("created_at DESC").Find(&logs).Error
 	return logs, err
 }
+
+// DeleteFileAccessLogsBefore removes file access logs created before cutoff and returns the number removed
+func DeleteFileAccessLogsBefore(db *gorm.DB, cutoff time.Time) (int64, error) {
+	result := db.Where("created_at < ?", cutoff).Delete(&FileAccessLog{})
+	return result.RowsAffected, result.Error
+}
+
+// GetUnscannedFiles retrieves files the scanner has not inspected yet and that
+// are due for a scan at now. Files with fewer failed scans come first, oldest
+// first, so files failing to scan don't hold up new uploads.
+func GetUnscannedFiles(db *gorm.DB, now time.Time, limit int) ([]File, error) {
+	var files []File
+	err := db.Where("is_scanned = ? AND (next_scan_at IS NULL OR next_scan_at <= ?)", false, now).
+		Order("scan_attempts").Order("id").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+// RecordFileScanFailure records a failed scan and when the file is due for the next one
+func RecordFileScanFailure(db *gorm.DB, id uint, scanErr string, nextScanAt time.Time) error {
+	return db.Model(&File{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"scan_attempts": gorm.Expr("scan_attempts + 1"),
+		"scan_error":    scanErr,
+		"next_scan_at":  &nextScanAt,
+	}).Error
+}
+
+// GetExpiredFiles retrieves files whose expiry has passed, earliest first
+func GetExpiredFiles(db *gorm.DB, now time.Time, limit int) ([]File, error) {
+	var files []File
+	err := db.Where("expires_at IS NOT NULL AND expires_at <= ?", now).Order("expires_at").Limit(limit).Find(&files).Error
+	return files, err
+}
+
+// MarkFileSafe records a clean scan result, making the file downloadable
+func MarkFileSafe(db *gorm.DB, id uint) error {
+	return db.Model(&File{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"is_scanned":   true,
+		"is_safe":      true,
+		"scan_result":  "",
+		"scan_error":   "",
+		"next_scan_at": nil,
+	}).Error
+}
+
+// RecordFileIntegrity records the result of verifying a file's content against
+// its hash. Files whose content changed or is gone are no longer safe to serve;
+// an unknown result leaves the file as it is.
+func RecordFileIntegrity(db *gorm.DB, id uint, status string, verifiedAt time.Time) error {
+	updates := map[string]interface{}{
+		"integrity_status": status,
+		"verified_at":      &verifiedAt,
+	}
+	if status == FileIntegrityMismatch || status == FileIntegrityMissing {
+		updates["is_safe"] = false
+	}
+	return db.Model(&File{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// QuarantineFile records a detection after the file content was moved from its upload path to path
+func QuarantineFile(db *gorm.DB, id uint, threat, uploadPath, path string) error {
+	now := time.Now()
+	return db.Model(&File{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"is_scanned":       true,
+		"is_safe":          false,
+		"scan_result":      threat,
+		"quarantined_from": uploadPath,
+		"quarantined_at":   &now,
+		"path":             path,
+	}).Error
+}
+
+// ReleaseQuarantinedFile marks a quarantined file safe after its content was moved back to path.
+// The scan result is kept as a record of the overridden detection.
+func ReleaseQuarantinedFile(db *gorm.DB, id uint, path string) error {
+	return db.Model(&File{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"is_safe":          true,
+		"quarantined_from": "",
+		"quarantined_at":   nil,
+		"path":             path,
+	}).Error
+}
+
+// GetQuarantinedFiles retrieves quarantined files, most recent detection first
+func GetQuarantinedFiles(db *gorm.DB, limit, offset int) ([]File, int64, error) {
+	var files []File
+	var total int64
+	query := db.Model(&File{}).Where("quarantined_at IS NOT NULL")
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("User").Order("quarantined_at DESC").Limit(limit).Offset(offset).Find(&files).Error
+	return files, total, err
+}
+
+// PurgeDeletedFilesBefore permanently removes files soft-deleted before cutoff and returns the number removed
+func PurgeDeletedFilesBefore(db *gorm.DB, cutoff time.Time) (int64, error) {
+	result := db.Unscoped().Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Delete(&File{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// FileDedupGroup represents files sharing the same content hash
+type FileDedupGroup struct {
+	Hash           string `json:"hash"`
+	Files          int64  `json:"files"`           // file records with this hash
+	Users          int64  `json:"users"`           // distinct owners
+	PhysicalCopies int64  `json:"physical_copies"` // distinct paths on disk
+	MinSize        int64  `json:"min_size"`
+	MaxSize        int64  `json:"max_size"`
+	LogicalBytes   int64  `json:"logical_bytes"`
+	Anomaly        bool   `json:"anomaly"` // sizes differ, so the content can't really be the same
+}
+
+// FileDedupReport represents how much storage identical content takes. Logical
+// bytes count every file record, physical bytes every distinct path, and unique
+// bytes every distinct hash once. Savings are what sharing paths already saves;
+// potential savings what storing each hash once would save on top of that.
+type FileDedupReport struct {
+	Files            int64            `json:"files"`
+	UniqueHashes     int64            `json:"unique_hashes"`
+	LogicalBytes     int64            `json:"logical_bytes"`
+	PhysicalBytes    int64            `json:"physical_bytes"`
+	UniqueBytes      int64            `json:"unique_bytes"`
+	SavedBytes       int64            `json:"saved_bytes"`
+	PotentialSavings int64            `json:"potential_savings_bytes"`
+	DuplicateGroups  int64            `json:"duplicate_groups"`
+	Groups           []FileDedupGroup `json:"groups"` // the largest groups, by logical bytes
+	Anomalies        int64            `json:"anomalies"`
+}
+
+// GetFileDedupReport computes the deduplication report of all files with
+// grouped hash queries, listing at most limit duplicate groups
+func GetFileDedupReport(db *gorm.DB, limit int) (*FileDedupReport, error) {
+	report := &FileDedupReport{Groups: make([]FileDedupGroup, 0)}
+
+	var totals struct {
+		Files        int64
+		LogicalBytes int64
+	}
+	if err := db.Model(&File{}).Select("COUNT(*) AS files, COALESCE(SUM(size), 0) AS logical_bytes").Scan(&totals).Error; err != nil {
+		return nil, err
+	}
+	report.Files, report.LogicalBytes = totals.Files, totals.LogicalBytes
+
+	// A path or hash counts once, with the size of its largest record
+	byPath := db.Model(&File{}).Select("MAX(size) AS size").Group("path")
+	if err := db.Table("(?) AS paths", byPath).Select("COALESCE(SUM(size), 0)").Scan(&report.PhysicalBytes).Error; err != nil {
+		return nil, err
+	}
+	var unique struct {
+		Hashes int64
+		Bytes  int64
+	}
+	byHash := db.Model(&File{}).Select("MAX(size) AS size").Group("hash")
+	if err := db.Table("(?) AS hashes", byHash).Select("COUNT(*) AS hashes, COALESCE(SUM(size), 0) AS bytes").Scan(&unique).Error; err != nil {
+		return nil, err
+	}
+	report.UniqueHashes, report.UniqueBytes = unique.Hashes, unique.Bytes
+
+	// Hashes held by more than one file
+	duplicates := func() *gorm.DB {
+		return db.Model(&File{}).Group("hash").Having("COUNT(*) > 1")
+	}
+	var groups struct {
+		DuplicateGroups int64
+		Anomalies       int64
+	}
+	err := db.Table("(?) AS duplicates", duplicates().Select("MIN(size) AS min_size, MAX(size) AS max_size")).
+		Select("COUNT(*) AS duplicate_groups, COALESCE(SUM(CASE WHEN min_size <> max_size THEN 1 ELSE 0 END), 0) AS anomalies").
+		Scan(&groups).Error
+	if err != nil {
+		return nil, err
+	}
+	report.DuplicateGroups, report.Anomalies = groups.DuplicateGroups, groups.Anomalies
+
+	err = duplicates().Select(`hash, COUNT(*) AS files, COUNT(DISTINCT user_id) AS users,
+		COUNT(DISTINCT path) AS physical_copies, MIN(size) AS min_size, MAX(size) AS max_size,
+		SUM(size) AS logical_bytes`).
+		Order("logical_bytes DESC, hash").Limit(limit).Scan(&report.Groups).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range report.Groups {
+		report.Groups[i].Anomaly = report.Groups[i].MinSize != report.Groups[i].MaxSize
+	}
+
+	report.SavedBytes = report.LogicalBytes - report.PhysicalBytes
+	report.PotentialSavings = report.PhysicalBytes - report.UniqueBytes
+	if report.PotentialSavings < 0 {
+		report.PotentialSavings = 0
+	}
+	return report, nil
+}
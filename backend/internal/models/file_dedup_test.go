@@ -0,0 +1,90 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestGetFileDedupReport(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&User{}, &File{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	files := []struct {
+		hash   string
+		path   string
+		size   int64
+		userID uint
+	}{
+		// Three users uploaded the same content; two of the records share a copy
+		{"report", "uploads/files/a", 100, 1},
+		{"report", "uploads/files/b", 100, 2},
+		{"report", "uploads/files/b", 100, 3},
+		// Two separate copies
+		{"photo", "uploads/files/c", 40, 1},
+		{"photo", "uploads/files/d", 40, 2},
+		// The same hash with different sizes
+		{"broken", "uploads/files/e", 10, 1},
+		{"broken", "uploads/files/f", 12, 2},
+		// Unique content
+		{"notes", "uploads/files/g", 7, 1},
+	}
+	for i, f := range files {
+		name := fmt.Sprintf("file%d.txt", i)
+		file := &File{
+			Filename: name, OriginalName: name, FileType: "txt", MimeType: "text/plain",
+			Size: f.size, Path: f.path, Hash: f.hash, UserID: f.userID,
+		}
+		if err := CreateFile(db, file); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+
+	report, err := GetFileDedupReport(db, 2)
+	if err != nil {
+		t.Fatalf("Failed to compute report: %v", err)
+	}
+
+	want := FileDedupReport{
+		Files:            8,
+		UniqueHashes:     4,
+		LogicalBytes:     409,
+		PhysicalBytes:    309,
+		UniqueBytes:      159,
+		SavedBytes:       100,
+		PotentialSavings: 150,
+		DuplicateGroups:  3,
+		Anomalies:        1,
+	}
+	got := *report
+	got.Groups = nil
+	if fmt.Sprintf("%+v", got) != fmt.Sprintf("%+v", want) {
+		t.Errorf("Expected totals %+v, got %+v", want, got)
+	}
+
+	// Limited to the two largest groups
+	if len(report.Groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %+v", report.Groups)
+	}
+	if group := report.Groups[0]; group.Hash != "report" || group.Files != 3 || group.Users != 3 || group.PhysicalCopies != 2 || group.LogicalBytes != 300 {
+		t.Errorf("Unexpected largest group: %+v", group)
+	}
+	if group := report.Groups[1]; group.Hash != "photo" || group.Anomaly {
+		t.Errorf("Unexpected second group: %+v", group)
+	}
+
+	report, err = GetFileDedupReport(db, 10)
+	if err != nil {
+		t.Fatalf("Failed to compute report: %v", err)
+	}
+	if group := report.Groups[2]; group.Hash != "broken" || !group.Anomaly || group.MinSize != 10 || group.MaxSize != 12 {
+		t.Errorf("Expected the differing sizes to be flagged, got %+v", group)
+	}
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupFileSearchTestDB creates an in-memory database holding files of one user
+// to search, the most recent last
+func setupFileSearchTestDB(t *testing.T, files []File) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&User{}, &File{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	created := time.Now().Add(-time.Hour)
+	for i := range files {
+		file := &files[i]
+		file.Filename = file.OriginalName
+		file.FileType, file.MimeType, file.Path = "txt", "text/plain", "uploads/files/"+file.OriginalName
+		file.Hash, file.UserID = file.OriginalName, 1
+		file.CreatedAt = created.Add(time.Duration(i) * time.Minute)
+		if err := CreateFile(db, file); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	return db
+}
+
+func TestSearchFiles_RanksByRelevance(t *testing.T) {
+	// Created worst match first, so recency alone would reverse the ranking
+	db := setupFileSearchTestDB(t, []File{
+		{OriginalName: "notes.txt", Description: "includes the budget"},
+		{OriginalName: "minutes.txt", Description: "budget review"},
+		{OriginalName: "q3.txt", Description: "Budget"},
+		{OriginalName: "old-budget.csv"},
+		{OriginalName: "budget-2024.csv"},
+		{OriginalName: "Budget.csv"},
+	})
+
+	want := []struct {
+		name  string
+		score int
+	}{
+		{"Budget.csv", ScoreExactName},
+		{"budget-2024.csv", ScorePrefixName},
+		{"old-budget.csv", ScoreSubstringName},
+		{"q3.txt", ScoreExactDescription},
+		{"minutes.txt", ScorePrefixDescription},
+		{"notes.txt", ScoreSubstringDescription},
+	}
+
+	userID := uint(1)
+	searches := map[string]func() ([]File, error){
+		"SearchFiles": func() ([]File, error) {
+			return SearchFiles(db, "budget", &userID, 20, 0)
+		},
+		"SearchFilesOptimized": func() ([]File, error) {
+			return NewOptimizedQueryBuilder(db).SearchFilesOptimized("budget", nil, 20, 0)
+		},
+	}
+
+	for name, search := range searches {
+		t.Run(name, func(t *testing.T) {
+			files, err := search()
+			if err != nil {
+				t.Fatalf("Failed to search files: %v", err)
+			}
+			if len(files) != len(want) {
+				t.Fatalf("Expected %d results, got %d", len(want), len(files))
+			}
+			for i, w := range want {
+				if files[i].OriginalName != w.name || files[i].Score != w.score {
+					t.Errorf("Expected result %d to be %s with score %d, got %s with score %d",
+						i, w.name, w.score, files[i].OriginalName, files[i].Score)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchFiles_RecencyBreaksTies(t *testing.T) {
+	db := setupFileSearchTestDB(t, []File{
+		{OriginalName: "report-old.txt"},
+		{OriginalName: "report-new.txt"},
+		{OriginalName: "report.txt"},
+	})
+
+	files, err := SearchFiles(db, "report", nil, 2, 1)
+	if err != nil {
+		t.Fatalf("Failed to search files: %v", err)
+	}
+
+	// The exact match is on the first page; equally relevant prefixes follow newest first
+	if len(files) != 2 || files[0].OriginalName != "report-new.txt" || files[1].OriginalName != "report-old.txt" {
+		t.Errorf("Expected the prefix matches newest first, got %+v", files)
+	}
+}
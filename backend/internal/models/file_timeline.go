@@ -0,0 +1,132 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Granularities of a file upload timeline
+const (
+	TimelineDay   = "day"
+	TimelineWeek  = "week" // weeks start on Monday
+	TimelineMonth = "month"
+)
+
+// MaxTimelineBuckets is the most buckets a timeline may span
+const MaxTimelineBuckets = 400
+
+var (
+	ErrInvalidTimelineGranularity = errors.New("granularity must be day, week or month")
+	ErrInvalidTimelineRange       = errors.New("end date must not be before start date")
+	ErrTimelineRangeTooLarge      = errors.New("date range spans too many buckets")
+)
+
+// timelineBucketExpressions are the SQLite expressions giving the first day of
+// a file's bucket, in UTC
+var timelineBucketExpressions = map[string]string{
+	TimelineDay:   "strftime('%Y-%m-%d', created_at)",
+	TimelineWeek:  "strftime('%Y-%m-%d', created_at, 'weekday 0', '-6 days')",
+	TimelineMonth: "strftime('%Y-%m-01', created_at)",
+}
+
+// FileTimelineFilter represents the files counted in a timeline
+type FileTimelineFilter struct {
+	Granularity string
+	Start       time.Time
+	End         time.Time
+	UserID      *uint
+	FileType    string
+}
+
+// FileTimelineBucket represents the uploads of one period
+type FileTimelineBucket struct {
+	Start      time.Time `json:"start"`
+	Count      int64     `json:"count"`
+	TotalBytes int64     `json:"total_bytes"`
+}
+
+// IsValidTimelineGranularity checks if a timeline granularity is supported
+func IsValidTimelineGranularity(granularity string) bool {
+	_, exists := timelineBucketExpressions[granularity]
+	return exists
+}
+
+// TimelineBucketStart returns the start of the bucket holding t, in UTC
+func TimelineBucketStart(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case TimelineWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case TimelineMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// nextTimelineBucket returns the start of the bucket after the one starting at start
+func nextTimelineBucket(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case TimelineWeek:
+		return start.AddDate(0, 0, 7)
+	case TimelineMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// GetFileTimeline counts the files uploaded between filter.Start and filter.End
+// and their total size per bucket, grouped in SQL. Every bucket of the range is
+// returned in order, periods without uploads with zero counts.
+func GetFileTimeline(db *gorm.DB, filter FileTimelineFilter) ([]FileTimelineBucket, error) {
+	expression, exists := timelineBucketExpressions[filter.Granularity]
+	if !exists {
+		return nil, ErrInvalidTimelineGranularity
+	}
+	if filter.End.Before(filter.Start) {
+		return nil, ErrInvalidTimelineRange
+	}
+
+	// Lay out the zero-filled buckets first, so huge ranges never hit the database
+	var buckets []FileTimelineBucket
+	index := make(map[string]int)
+	last := TimelineBucketStart(filter.End, filter.Granularity)
+	for start := TimelineBucketStart(filter.Start, filter.Granularity); !start.After(last); start = nextTimelineBucket(start, filter.Granularity) {
+		if len(buckets) == MaxTimelineBuckets {
+			return nil, ErrTimelineRangeTooLarge
+		}
+		index[start.Format("2006-01-02")] = len(buckets)
+		buckets = append(buckets, FileTimelineBucket{Start: start})
+	}
+
+	query := db.Model(&File{}).
+		Select(expression+" AS bucket, COUNT(*) AS count, COALESCE(SUM(size), 0) AS total_bytes").
+		Where("created_at >= ? AND created_at <= ?", filter.Start, filter.End)
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.FileType != "" {
+		query = query.Where("file_type = ?", filter.FileType)
+	}
+
+	var rows []struct {
+		Bucket     string
+		Count      int64
+		TotalBytes int64
+	}
+	if err := query.Group("bucket").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		if i, exists := index[row.Bucket]; exists {
+			buckets[i].Count = row.Count
+			buckets[i].TotalBytes = row.TotalBytes
+		}
+	}
+	return buckets, nil
+}
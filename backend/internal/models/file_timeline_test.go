@@ -0,0 +1,157 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// timelineFile describes a file created for timeline tests
+type timelineFile struct {
+	created  string // RFC3339
+	size     int64
+	userID   uint
+	fileType string
+}
+
+// setupFileTimelineTestDB creates an in-memory database holding the given files
+func setupFileTimelineTestDB(t *testing.T, files []timelineFile) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := db.AutoMigrate(&User{}, &File{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	for i, f := range files {
+		created, err := time.Parse(time.RFC3339, f.created)
+		if err != nil {
+			t.Fatalf("Invalid creation time %q: %v", f.created, err)
+		}
+		name := fmt.Sprintf("file%d.%s", i, f.fileType)
+		file := &File{
+			Filename: name, OriginalName: name, FileType: f.fileType, MimeType: "text/plain",
+			Size: f.size, Path: "uploads/files/" + name, Hash: name, UserID: f.userID,
+		}
+		file.CreatedAt = created
+		if err := CreateFile(db, file); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	return db
+}
+
+// date parses a UTC date
+func date(value string) time.Time {
+	t, _ := time.Parse("2006-01-02", value)
+	return t
+}
+
+func TestGetFileTimeline_Bucketing(t *testing.T) {
+	db := setupFileTimelineTestDB(t, []timelineFile{
+		{"2024-03-04T08:00:00Z", 100, 1, "txt"}, // Monday
+		{"2024-03-04T23:59:00Z", 50, 2, "csv"},
+		{"2024-03-06T12:00:00Z", 10, 1, "txt"}, // Wednesday, same week
+		{"2024-03-11T00:00:00Z", 7, 1, "txt"},  // next Monday
+		{"2024-04-30T12:00:00Z", 1, 1, "txt"},
+		{"2024-02-28T12:00:00Z", 1000, 1, "txt"}, // before the range
+	})
+	start, end := date("2024-03-04"), date("2024-04-30").Add(24*time.Hour-time.Nanosecond)
+
+	type want struct {
+		start string
+		count int64
+		bytes int64
+	}
+	tests := []struct {
+		granularity string
+		filter      func(*FileTimelineFilter)
+		buckets     int
+		nonZero     []want
+	}{
+		{TimelineDay, nil, 58, []want{
+			{"2024-03-04", 2, 150}, {"2024-03-06", 1, 10}, {"2024-03-11", 1, 7}, {"2024-04-30", 1, 1},
+		}},
+		{TimelineWeek, nil, 9, []want{
+			{"2024-03-04", 3, 160}, {"2024-03-11", 1, 7}, {"2024-04-29", 1, 1},
+		}},
+		{TimelineMonth, nil, 2, []want{
+			{"2024-03-01", 4, 167}, {"2024-04-01", 1, 1},
+		}},
+		{TimelineMonth, func(f *FileTimelineFilter) { id := uint(2); f.UserID = &id }, 2, []want{
+			{"2024-03-01", 1, 50},
+		}},
+		{TimelineWeek, func(f *FileTimelineFilter) { f.FileType = "txt" }, 9, []want{
+			{"2024-03-04", 2, 110}, {"2024-03-11", 1, 7}, {"2024-04-29", 1, 1},
+		}},
+	}
+
+	for _, tt := range tests {
+		filter := FileTimelineFilter{Granularity: tt.granularity, Start: start, End: end}
+		if tt.filter != nil {
+			tt.filter(&filter)
+		}
+		buckets, err := GetFileTimeline(db, filter)
+		if err != nil {
+			t.Fatalf("%s: failed to get timeline: %v", tt.granularity, err)
+		}
+		if len(buckets) != tt.buckets {
+			t.Fatalf("%s: expected %d buckets, got %d", tt.granularity, tt.buckets, len(buckets))
+		}
+
+		expected := make(map[string]want)
+		for _, w := range tt.nonZero {
+			expected[w.start] = w
+		}
+		for i, bucket := range buckets {
+			if i > 0 && !bucket.Start.After(buckets[i-1].Start) {
+				t.Errorf("%s: buckets out of order at %d", tt.granularity, i)
+			}
+			w := expected[bucket.Start.Format("2006-01-02")]
+			if bucket.Count != w.count || bucket.TotalBytes != w.bytes {
+				t.Errorf("%s: bucket %s has %d files and %d bytes, want %d and %d",
+					tt.granularity, bucket.Start.Format("2006-01-02"), bucket.Count, bucket.TotalBytes, w.count, w.bytes)
+			}
+			delete(expected, bucket.Start.Format("2006-01-02"))
+		}
+		if len(expected) != 0 {
+			t.Errorf("%s: missing buckets %v", tt.granularity, expected)
+		}
+	}
+}
+
+func TestGetFileTimeline_InvalidFilters(t *testing.T) {
+	db := setupFileTimelineTestDB(t, nil)
+
+	tests := []struct {
+		filter FileTimelineFilter
+		want   error
+	}{
+		{FileTimelineFilter{Granularity: "hour", Start: date("2024-01-01"), End: date("2024-01-02")}, ErrInvalidTimelineGranularity},
+		{FileTimelineFilter{Granularity: TimelineDay, Start: date("2024-01-02"), End: date("2024-01-01")}, ErrInvalidTimelineRange},
+		{FileTimelineFilter{Granularity: TimelineDay, Start: date("2020-01-01"), End: date("2024-01-01")}, ErrTimelineRangeTooLarge},
+	}
+	for _, tt := range tests {
+		if _, err := GetFileTimeline(db, tt.filter); err != tt.want {
+			t.Errorf("Expected %v, got %v", tt.want, err)
+		}
+	}
+}
+
+func TestTimelineBucketStart(t *testing.T) {
+	moment := time.Date(2024, 3, 10, 15, 30, 0, 0, time.UTC) // Sunday
+	tests := map[string]string{
+		TimelineDay:   "2024-03-10",
+		TimelineWeek:  "2024-03-04",
+		TimelineMonth: "2024-03-01",
+	}
+	for granularity, want := range tests {
+		if got := TimelineBucketStart(moment, granularity).Format("2006-01-02"); got != want {
+			t.Errorf("%s: expected %s, got %s", granularity, want, got)
+		}
+	}
+}
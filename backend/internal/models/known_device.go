@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// KnownDevice represents a device fingerprint a user has logged in from
+type KnownDevice struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_known_device_user_fingerprint"`
+	Fingerprint string    `json:"fingerprint" gorm:"not null;size:64;uniqueIndex:idx_known_device_user_fingerprint"`
+	IPAddress   string    `json:"ip_address"`
+	UserAgent   string    `json:"user_agent"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+// TableName returns the table name for the KnownDevice model
+func (KnownDevice) TableName() string {
+	return "known_devices"
+}
+
+// GetKnownDevice retrieves a user's device by fingerprint
+func GetKnownDevice(db *gorm.DB, userID uint, fingerprint string) (*KnownDevice, error) {
+	var device KnownDevice
+	err := db.Where("user_id = ? AND fingerprint = ?", userID, fingerprint).First(&device).Error
+	return &device, err
+}
+
+// CountKnownDevices returns the number of devices known for a user
+func CountKnownDevices(db *gorm.DB, userID uint) (int64, error) {
+	var count int64
+	err := db.Model(&KnownDevice{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// PurgeKnownDevices removes all but a user's keep most recently seen devices
+func PurgeKnownDevices(db *gorm.DB, userID uint, keep int) error {
+	kept := db.Model(&KnownDevice{}).Select("id").Where("user_id = ?", userID).Order("last_seen DESC, id DESC").Limit(keep)
+	return db.Where("user_id = ? AND id NOT IN (?)", userID, kept).Delete(&KnownDevice{}).Error
+}
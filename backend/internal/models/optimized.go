@@ -119,10 +119,11 @@ func (qb *OptimizedQueryBuilder) GetFilesWithOptimizedQuery(limit, offset int, f
 	return files, err
 }
 
-// SearchFilesOptimized performs optimized file search
+// SearchFilesOptimized performs optimized file search, ranked like SearchFiles
 func (qb *OptimizedQueryBuilder) SearchFilesOptimized(query string, userID *uint, limit, offset int) ([]File, error) {
 	var files []File
-	dbQuery := qb.db.Select("id, filename, original_name, file_type, mime_type, size, user_id, is_public, created_at, updated_at").
+	score, scoreArgs := fileSearchScore(query)
+	dbQuery := qb.db.Select("id, filename, original_name, file_type, mime_type, size, user_id, is_public, created_at, updated_at, "+score, scoreArgs...).
 		Where("filename LIKE ? OR original_name LIKE ? OR description LIKE ?", 
 			"%"+query+"%", "%"+query+"%", "%"+query+"%")
 	
@@ -137,7 +138,7 @@ func (qb *OptimizedQueryBuilder) SearchFilesOptimized(query string, userID *uint
 		dbQuery = dbQuery.Offset(offset)
 	}
 	
-	err := dbQuery.Order("created_at DESC").Find(&files).Error
+	err := dbQuery.Order("score DESC, created_at DESC").Find(&files).Error
 	return files, err
 }
 
@@ -212,7 +213,7 @@ func (qb *OptimizedQueryBuilder) GetFileStatsOptimized() (*FileStats, error) {
 // GetFileAccessLogsOptimized retrieves file access logs with optimized query
 func (qb *OptimizedQueryBuilder) GetFileAccessLogsOptimized(fileID uint, limit, offset int) ([]FileAccessLog, error) {
 	var logs []FileAccessLog
-	query := qb.db.Select("id, file_id, user_id, action, ip_address, user_agent, created_at").
+	query := qb.db.Select("id, file_id, user_id, action, ip_address, user_agent, request_id, status, bytes_served, created_at").
 		Where("file_id = ?", fileID)
 	
 	if limit > 0 {
@@ -226,6 +227,79 @@ func (qb *OptimizedQueryBuilder) GetFileAccessLogsOptimized(fileID uint, limit,
 	return logs, err
 }
 
+// FileAccessLogFilter narrows file access log queries; zero values are ignored
+type FileAccessLogFilter struct {
+	Action    string
+	RequestID string
+	Status    string
+	StartDate *time.Time
+	EndDate   *time.Time
+}
+
+// FileAccessActions lists the actions recorded in file access logs
+var FileAccessActions = []string{"upload", "download", "view", "delete", "update", "rename", "verify"}
+
+// IsValidFileAccessAction checks if an action is one recorded in file access logs
+func IsValidFileAccessAction(action string) bool {
+	for _, valid := range FileAccessActions {
+		if action == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidFileAccessStatus checks if a status is one recorded in file access logs
+func IsValidFileAccessStatus(status string) bool {
+	switch status {
+	case FileAccessSuccess, FileAccessNotFound, FileAccessDenied:
+		return true
+	}
+	return false
+}
+
+// GetFileAccessLogsFiltered retrieves a page of file access logs matching the filter
+// together with the total number of matching logs
+func (qb *OptimizedQueryBuilder) GetFileAccessLogsFiltered(fileID uint, filter FileAccessLogFilter, limit, offset int) ([]FileAccessLog, int64, error) {
+	query := qb.db.Model(&FileAccessLog{}).Where("file_id = ?", fileID)
+	
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.StartDate != nil {
+		query = query.Where("created_at >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("created_at <= ?", *filter.EndDate)
+	}
+	
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	var logs []FileAccessLog
+	pageQuery := query.Preload("User", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "username")
+	}).
+		Select("id, file_id, user_id, action, ip_address, user_agent, request_id, status, bytes_served, created_at")
+	if limit > 0 {
+		pageQuery = pageQuery.Limit(limit)
+	}
+	if offset > 0 {
+		pageQuery = pageQuery.Offset(offset)
+	}
+	
+	err := pageQuery.Order("created_at DESC").Find(&logs).Error
+	return logs, total, err
+}
+
 // BatchInsertFiles performs batch insert for better performance
 func (qb *OptimizedQueryBuilder) BatchInsertFiles(files []File) error {
 	if len(files) == 0 {
@@ -1,8 +1,14 @@
 package models
 
 import (
-	"gorm.io/gorm"
+	"errors"
+	"fmt"
 	"log"
+	"regexp"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
 )
 
 // DatabaseOptimizer handles database optimization tasks
@@ -127,80 +133,223 @@ func (do *DatabaseOptimizer) AnalyzeTables() error {
 	return nil
 }
 
-// GetQueryPerformanceStats returns query performance statistics
-func (do *DatabaseOptimizer) GetQueryPerformanceStats() (map[string]interface{}, error) {
-	stats := make(map[string]interface{})
-	
-	// Get table sizes
-	var tableSizes []struct {
-		Name string
-		Size int64
+// GetQueryPerformanceStats returns query performance statistics of the tables
+// configured in GlobalQueryStatsCollector, cached briefly
+func (do *DatabaseOptimizer) GetQueryPerformanceStats() *QueryPerformanceStats {
+	return GlobalQueryStatsCollector.Collect(do.db)
+}
+
+var (
+	ErrInvalidQueryStatsConfig = errors.New("stats need at least one table, valid table names, a cache TTL of at least 0 and a concurrency between 1 and 16")
+	ErrUnsupportedDriver       = errors.New("not supported by this database driver")
+)
+
+// tableNamePattern matches the table names stats may be collected for
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// maxQueryStatsConcurrency bounds how many tables are inspected at once
+const maxQueryStatsConcurrency = 16
+
+// QueryStatsConfig represents which tables GetQueryPerformanceStats inspects
+// and how its results are cached
+type QueryStatsConfig struct {
+	Tables          []string `json:"tables"`
+	CacheTTLSeconds int      `json:"cache_ttl_seconds"` // 0 disables caching
+	Concurrency     int      `json:"concurrency"`       // tables inspected at once
+}
+
+// DefaultQueryStatsConfig returns default query stats configuration
+func DefaultQueryStatsConfig() *QueryStatsConfig {
+	return &QueryStatsConfig{
+		Tables:          []string{"users", "files", "file_access_logs"},
+		CacheTTLSeconds: 30,
+		Concurrency:     2,
 	}
-	err := do.db.Raw(`
-		SELECT name, 
-		       (SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=main.name) as size
-		FROM sqlite_master 
-		WHERE type='table' AND name IN ('users', 'files', 'file_access_logs')
-	`).Scan(&tableSizes).Error
-	
-	if err != nil {
-		return nil, err
+}
+
+// Validate checks the configuration for invalid values
+func (qc *QueryStatsConfig) Validate() error {
+	if len(qc.Tables) == 0 || qc.CacheTTLSeconds < 0 || qc.Concurrency < 1 || qc.Concurrency > maxQueryStatsConcurrency {
+		return ErrInvalidQueryStatsConfig
 	}
-	
-	stats["table_sizes"] = tableSizes
-	
-	// Get index information
-	var indexInfo []struct {
-		TableName string
-		IndexName string
-		Unique    bool
+	for _, table := range qc.Tables {
+		if !tableNamePattern.MatchString(table) {
+			return ErrInvalidQueryStatsConfig
+		}
 	}
-	err = do.db.Raw(`
-		SELECT name as table_name, 
-		       sql as index_name,
-		       CASE WHEN sql LIKE '%UNIQUE%' THEN 1 ELSE 0 END as unique
-		FROM sqlite_master 
-		WHERE type='index' AND name LIKE 'idx_%'
-	`).Scan(&indexInfo).Error
-	
-	if err != nil {
-		return nil, err
+	return nil
+}
+
+// IndexStats describes an index of a table
+type IndexStats struct {
+	Name   string `json:"name"`
+	Unique bool   `json:"unique"`
+}
+
+// TableStats represents the statistics of a table. A stat that could not be
+// collected is left out and its error recorded, keyed by stat.
+type TableStats struct {
+	Name    string            `json:"name"`
+	Rows    *int64            `json:"rows,omitempty"`
+	Indexes []IndexStats      `json:"indexes,omitempty"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// QueryPerformanceStats represents the statistics of the inspected tables.
+// Partial is set when some stats failed, see TableStats.Errors.
+type QueryPerformanceStats struct {
+	Driver      string       `json:"driver"`
+	Tables      []TableStats `json:"tables"`
+	Partial     bool         `json:"partial"`
+	CollectedAt time.Time    `json:"collected_at"`
+}
+
+// QueryStatsCollector collects table statistics with queries suited to the
+// database driver, caching the result for the configured TTL so dashboards
+// polling it don't re-run them
+type QueryStatsCollector struct {
+	config   *QueryStatsConfig
+	cached   *QueryPerformanceStats
+	cachedDB *gorm.DB
+	mutex    sync.RWMutex
+}
+
+// NewQueryStatsCollector creates a new query stats collector
+func NewQueryStatsCollector() *QueryStatsCollector {
+	return &QueryStatsCollector{
+		config: DefaultQueryStatsConfig(),
 	}
-	
-	stats["indexes"] = indexInfo
-	
-	return stats, nil
 }
 
-// CleanupOldData removes old data to improve performance
-func (do *DatabaseOptimizer) CleanupOldData() error {
-	log.Println("Cleaning up old data...")
-	
-	// Remove old file access logs (older than 90 days)
-	result := do.db.Exec(`
-		DELETE FROM file_access_logs 
-		WHERE created_at < datetime('now', '-90 days')
-	`)
-	
-	if result.Error != nil {
-		log.Printf("Warning: Failed to cleanup old file access logs: %v", result.Error)
+// GetConfig returns a copy of the current configuration
+func (qs *QueryStatsCollector) GetConfig() QueryStatsConfig {
+	qs.mutex.RLock()
+	defer qs.mutex.RUnlock()
+
+	config := *qs.config
+	config.Tables = append([]string(nil), qs.config.Tables...)
+	return config
+}
+
+// UpdateConfig validates and replaces the configuration, dropping cached stats
+func (qs *QueryStatsCollector) UpdateConfig(config *QueryStatsConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	stored := *config
+	stored.Tables = append([]string(nil), config.Tables...)
+
+	qs.mutex.Lock()
+	defer qs.mutex.Unlock()
+	qs.config = &stored
+	qs.cached = nil
+	return nil
+}
+
+// Collect returns the statistics of the configured tables, from the cache when
+// they were collected from the same database within the TTL
+func (qs *QueryStatsCollector) Collect(db *gorm.DB) *QueryPerformanceStats {
+	config := qs.GetConfig()
+	ttl := time.Duration(config.CacheTTLSeconds) * time.Second
+
+	qs.mutex.RLock()
+	cached, cachedDB := qs.cached, qs.cachedDB
+	qs.mutex.RUnlock()
+	if cached != nil && cachedDB == db && time.Since(cached.CollectedAt) < ttl {
+		return cached
+	}
+
+	stats := &QueryPerformanceStats{
+		Driver: db.Dialector.Name(),
+		Tables: make([]TableStats, len(config.Tables)),
+	}
+
+	// Each table is inspected by one goroutine, at most Concurrency at a time
+	semaphore := make(chan struct{}, config.Concurrency)
+	var wg sync.WaitGroup
+	for i, table := range config.Tables {
+		wg.Add(1)
+		go func(i int, table string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			stats.Tables[i] = collectTableStats(db, table)
+		}(i, table)
+	}
+	wg.Wait()
+
+	for _, table := range stats.Tables {
+		if len(table.Errors) > 0 {
+			stats.Partial = true
+		}
+	}
+	stats.CollectedAt = time.Now()
+
+	if ttl > 0 {
+		qs.mutex.Lock()
+		qs.cached, qs.cachedDB = stats, db
+		qs.mutex.Unlock()
+	}
+	return stats
+}
+
+// collectTableStats collects the row count and indexes of a table, recording
+// the error of each stat that fails instead of giving up on the table
+func collectTableStats(db *gorm.DB, table string) TableStats {
+	stats := TableStats{Name: table}
+	fail := func(stat string, err error) {
+		if stats.Errors == nil {
+			stats.Errors = make(map[string]string)
+		}
+		stats.Errors[stat] = err.Error()
+	}
+
+	if !db.Migrator().HasTable(table) {
+		fail("table", errors.New("table does not exist"))
+		return stats
+	}
+
+	var rows int64
+	if err := db.Table(table).Count(&rows).Error; err != nil {
+		fail("rows", err)
 	} else {
-		log.Printf("Cleaned up %d old file access log entries", result.RowsAffected)
+		stats.Rows = &rows
 	}
-	
-	// Remove soft-deleted files older than 30 days
-	result = do.db.Exec(`
-		DELETE FROM files 
-		WHERE deleted_at IS NOT NULL 
-		AND deleted_at < datetime('now', '-30 days')
-	`)
-	
-	if result.Error != nil {
-		log.Printf("Warning: Failed to cleanup old deleted files: %v", result.Error)
+
+	if indexes, err := tableIndexes(db, table); err != nil {
+		fail("indexes", err)
 	} else {
-		log.Printf("Cleaned up %d old deleted files", result.RowsAffected)
+		stats.Indexes = indexes
 	}
-	
-	log.Println("Data cleanup completed")
-	return nil
+
+	return stats
 }
+
+// tableIndexes lists the indexes of a table with the catalog query of the
+// database driver
+func tableIndexes(db *gorm.DB, table string) ([]IndexStats, error) {
+	var query string
+	switch db.Dialector.Name() {
+	case "sqlite":
+		query = `SELECT name, "unique" AS "unique" FROM pragma_index_list(?) ORDER BY name`
+	case "postgres":
+		query = `SELECT indexname AS name, indexdef LIKE 'CREATE UNIQUE%' AS "unique"
+			FROM pg_indexes WHERE schemaname = current_schema() AND tablename = ? ORDER BY indexname`
+	case "mysql":
+		query = "SELECT index_name AS name, MIN(non_unique) = 0 AS `unique`" +
+			" FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ?" +
+			" GROUP BY index_name ORDER BY index_name"
+	default:
+		return nil, fmt.Errorf("listing indexes is %w", ErrUnsupportedDriver)
+	}
+
+	indexes := make([]IndexStats, 0)
+	if err := db.Raw(query, table).Scan(&indexes).Error; err != nil {
+		return nil, err
+	}
+	return indexes, nil
+}
+
+// GlobalQueryStatsCollector collects the database performance stats of the application
+var GlobalQueryStatsCollector = NewQueryStatsCollector()
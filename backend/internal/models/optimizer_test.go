@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestQueryStatsCollector_CollectsAndCaches(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(&User{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	user := &User{Username: "testuser", Email: "test@example.com", Password: "password123", Role: "user"}
+	if err := user.Create(db); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	qs := NewQueryStatsCollector()
+	if err := qs.UpdateConfig(&QueryStatsConfig{Tables: []string{"users", "missing_table"}, CacheTTLSeconds: 60, Concurrency: 2}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	stats := qs.Collect(db)
+	if stats.Driver != "sqlite" || len(stats.Tables) != 2 {
+		t.Fatalf("Unexpected stats: %+v", stats)
+	}
+	users := stats.Tables[0]
+	if users.Rows == nil || *users.Rows != 1 || len(users.Indexes) == 0 || len(users.Errors) != 0 {
+		t.Errorf("Expected the users table's rows and indexes, got %+v", users)
+	}
+	if missing := stats.Tables[1]; missing.Errors["table"] == "" {
+		t.Errorf("Expected the missing table to record an error, got %+v", missing)
+	}
+	if !stats.Partial {
+		t.Error("Expected stats with a failed table to be partial")
+	}
+
+	if qs.Collect(db) != stats {
+		t.Error("Expected stats within the TTL to come from the cache")
+	}
+	if err := qs.UpdateConfig(&QueryStatsConfig{Tables: []string{"users"}, CacheTTLSeconds: 60, Concurrency: 1}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	if refreshed := qs.Collect(db); refreshed == stats || refreshed.Partial || len(refreshed.Tables) != 1 {
+		t.Errorf("Expected updating the config to drop cached stats, got %+v", refreshed)
+	}
+}
+
+func TestQueryStatsConfig_Validate(t *testing.T) {
+	for _, config := range []QueryStatsConfig{
+		{Tables: nil, Concurrency: 1},
+		{Tables: []string{"users; DROP TABLE users"}, Concurrency: 1},
+		{Tables: []string{"users"}, Concurrency: 0},
+		{Tables: []string{"users"}, Concurrency: 17},
+		{Tables: []string{"users"}, CacheTTLSeconds: -1, Concurrency: 1},
+	} {
+		if err := config.Validate(); err != ErrInvalidQueryStatsConfig {
+			t.Errorf("Expected %+v to be rejected, got %v", config, err)
+		}
+	}
+}
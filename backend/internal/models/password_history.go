@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PasswordHistory represents a password hash a user previously had
+type PasswordHistory struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	UserID       uint      `json:"user_id" gorm:"not null;index:idx_password_history_user"`
+	PasswordHash string    `json:"-" gorm:"not null;size:255"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName returns the table name for the PasswordHistory model
+func (PasswordHistory) TableName() string {
+	return "password_history"
+}
+
+// GetPasswordHistory retrieves a user's most recent previous password hashes, newest first
+func GetPasswordHistory(db *gorm.DB, userID uint, limit int) ([]PasswordHistory, error) {
+	var history []PasswordHistory
+	err := db.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(limit).Find(&history).Error
+	return history, err
+}
+
+// AddPasswordHistory records a previous password hash and purges all but the newest keep entries
+func AddPasswordHistory(db *gorm.DB, userID uint, passwordHash string, keep int) error {
+	if keep > 0 {
+		if err := db.Create(&PasswordHistory{UserID: userID, PasswordHash: passwordHash}).Error; err != nil {
+			return err
+		}
+	}
+
+	return PurgePasswordHistory(db, userID, keep)
+}
+
+// PurgePasswordHistory removes all but a user's newest keep password hashes
+func PurgePasswordHistory(db *gorm.DB, userID uint, keep int) error {
+	kept := db.Model(&PasswordHistory{}).Select("id").Where("user_id = ?", userID).Order("created_at DESC, id DESC").Limit(keep)
+	return db.Where("user_id = ? AND id NOT IN (?)", userID, kept).Delete(&PasswordHistory{}).Error
+}
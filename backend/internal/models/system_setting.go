@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SystemSetting represents a persisted system-wide setting
+type SystemSetting struct {
+	Key       string    `json:"key" gorm:"primaryKey;size:100"`
+	Value     string    `json:"value" gorm:"type:text"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName returns the table name for the SystemSetting model
+func (SystemSetting) TableName() string {
+	return "system_settings"
+}
+
+// GetSystemSetting retrieves a setting's value
+func GetSystemSetting(db *gorm.DB, key string) (string, error) {
+	var setting SystemSetting
+	err := db.Where("key = ?", key).First(&setting).Error
+	return setting.Value, err
+}
+
+// SetSystemSetting creates or replaces a setting's value
+func SetSystemSetting(db *gorm.DB, key, value string) error {
+	setting := &SystemSetting{Key: key, Value: value, UpdatedAt: time.Now()}
+	return db.Clauses(clause.OnConflict{UpdateAll: true}).Create(setting).Error
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TableVersions tracks a version counter and last write time per table so
+// responses derived from a table can be revalidated cheaply
+type TableVersions struct {
+	versions     map[string]uint64
+	lastModified map[string]time.Time
+	startedAt    time.Time
+	mutex        sync.RWMutex
+}
+
+// NewTableVersions creates a new table version tracker
+func NewTableVersions() *TableVersions {
+	return &TableVersions{
+		versions:     make(map[string]uint64),
+		lastModified: make(map[string]time.Time),
+		startedAt:    time.Now(),
+	}
+}
+
+// Bump records a write to a table
+func (tv *TableVersions) Bump(table string) {
+	tv.mutex.Lock()
+	defer tv.mutex.Unlock()
+	tv.versions[table]++
+	tv.lastModified[table] = time.Now()
+}
+
+// Version returns a table's version counter
+func (tv *TableVersions) Version(table string) uint64 {
+	tv.mutex.RLock()
+	defer tv.mutex.RUnlock()
+	return tv.versions[table]
+}
+
+// LastModified returns the time of a table's last write. Tables not written
+// since startup report the startup time, since earlier writes are unknown.
+func (tv *TableVersions) LastModified(table string) time.Time {
+	tv.mutex.RLock()
+	defer tv.mutex.RUnlock()
+	if modified, exists := tv.lastModified[table]; exists {
+		return modified
+	}
+	return tv.startedAt
+}
+
+// GlobalTableVersions tracks writes made through databases with RegisterTableVersionCallbacks
+var GlobalTableVersions = NewTableVersions()
+
+// RegisterTableVersionCallbacks bumps GlobalTableVersions after every successful
+// create, update and delete made through db
+func RegisterTableVersionCallbacks(db *gorm.DB) error {
+	bump := func(tx *gorm.DB) {
+		if tx.Error == nil && tx.Statement.Table != "" && tx.Statement.RowsAffected > 0 {
+			GlobalTableVersions.Bump(tx.Statement.Table)
+		}
+	}
+
+	if err := db.Callback().Create().After("gorm:create").Register("table_version:create", bump); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register("table_version:update", bump); err != nil {
+		return err
+	}
+	return db.Callback().Delete().After("gorm:delete").Register("table_version:delete", bump)
+}
@@ -66,3 +66,20 @@ func Count(db *gorm.DB) (int64, error) {
 	err := db.Model(&User{}).Count(&count).Error
 	return count, err
 }
+
+// SearchUsers searches users by username or email
+func SearchUsers(db *gorm.DB, query string, limit, offset int) ([]User, error) {
+	var users []User
+	dbQuery := db.Select("id, username, email, role, avatar, created_at, updated_at").
+		Where("username LIKE ? OR email LIKE ?", "%"+query+"%", "%"+query+"%")
+
+	if limit > 0 {
+		dbQuery = dbQuery.Limit(limit)
+	}
+	if offset > 0 {
+		dbQuery = dbQuery.Offset(offset)
+	}
+
+	err := dbQuery.Order("username ASC").Find(&users).Error
+	return users, err
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"regexp"
 	"strings"
+	"unicode"
 )
 
 // Validation errors
@@ -12,11 +13,35 @@ var (
 	ErrInvalidEmail    = errors.New("invalid email format")
 	ErrInvalidPassword = errors.New("password must be at least 8 characters")
 	ErrInvalidRole     = errors.New("invalid role")
+
+	ErrInvalidFileName = errors.New("file name must be 1-255 characters without path separators or control characters")
+)
+
+// File metadata limits. Description and tag limits are the defaults of
+// services.FileMetadataPolicy, which can be configured.
+const (
+	MaxFileDescriptionLength = 1000
+	MaxFileTags              = 20
+	MaxFileTagLength         = 50
+	MaxFileNameLength        = 255
 )
 
 // ValidRoles defines the allowed user roles
 var ValidRoles = []string{"admin", "user", "moderator"}
 
+// ElevatedRoles can only be assigned by an admin, never requested at registration
+var ElevatedRoles = []string{"admin", "moderator"}
+
+// IsElevatedRole checks if role is one of ElevatedRoles
+func IsElevatedRole(role string) bool {
+	for _, elevated := range ElevatedRoles {
+		if role == elevated {
+			return true
+		}
+	}
+	return false
+}
+
 // ValidateUser validates a user struct
 func ValidateUser(u *User) error {
 	if err := ValidateUsername(u.Username); err != nil {
@@ -93,6 +118,22 @@ func ValidateRole(role string) error {
 	return ErrInvalidRole
 }
 
+// ValidateFileName validates a file's display name. It is only shown to users
+// and used in Content-Disposition, so it must be a single path element.
+func ValidateFileName(name string) error {
+	if name == "" || name == "." || name == ".." || len([]rune(name)) > MaxFileNameLength {
+		return ErrInvalidFileName
+	}
+
+	for _, r := range name {
+		if unicode.IsControl(r) || r == '/' || r == '\\' {
+			return ErrInvalidFileName
+		}
+	}
+
+	return nil
+}
+
 // SanitizeUser sanitizes user input
 func SanitizeUser(u *User) {
 	u.Username = strings.TrimSpace(u.Username)
@@ -0,0 +1,189 @@
+package security
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrInvalidAccessLogSampling = errors.New("access log sampling needs a /path or /prefix/* path and a rate of at least 1")
+
+// AccessLogSampling logs 1 in Rate successful requests to paths matching Path,
+// for high-traffic routes such as health checks. Failed requests are always logged.
+type AccessLogSampling struct {
+	Path string `json:"path"` // "/prefix/*" matches below prefix
+	Rate int    `json:"rate"`
+}
+
+// matches checks if a request path matches the sampling path
+func (as *AccessLogSampling) matches(path string) bool {
+	return as.Path == path || (strings.HasSuffix(as.Path, "/*") && strings.HasPrefix(path, strings.TrimSuffix(as.Path, "*")))
+}
+
+// AccessLogConfig represents which requests AccessLogMiddleware logs
+type AccessLogConfig struct {
+	Enabled  bool                `json:"enabled"`
+	Sampling []AccessLogSampling `json:"sampling"` // first match wins, unmatched paths are logged in full
+}
+
+// DefaultAccessLogConfig returns default access log configuration
+func DefaultAccessLogConfig() *AccessLogConfig {
+	return &AccessLogConfig{
+		Enabled:  true,
+		Sampling: []AccessLogSampling{{Path: "/health", Rate: 100}},
+	}
+}
+
+// Validate checks the configuration for invalid values
+func (ac *AccessLogConfig) Validate() error {
+	for _, sampling := range ac.Sampling {
+		if sampling.Rate < 1 || !strings.HasPrefix(sampling.Path, "/") || strings.Contains(strings.TrimSuffix(sampling.Path, "/*"), "*") {
+			return ErrInvalidAccessLogSampling
+		}
+	}
+	return nil
+}
+
+// AccessLogEntry is one line of the access log
+type AccessLogEntry struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"` // without the query, which may carry tokens
+	Status    int       `json:"status"`
+	LatencyMS float64   `json:"latency_ms"`
+	Bytes     int       `json:"bytes"`
+	UserID    uint      `json:"user_id,omitempty"` // 0 for unauthenticated requests
+	RequestID string    `json:"request_id"`
+	ClientIP  string    `json:"client_ip"`
+}
+
+// AccessLogger writes one JSON line per request, replacing gin's text logger
+// so the access log can be fed to log aggregation
+type AccessLogger struct {
+	config *AccessLogConfig
+	counts []uint64 // requests seen per sampling rule, reset with the config
+	writer io.Writer
+	mutex  sync.RWMutex
+	write  sync.Mutex // keeps concurrent lines from interleaving
+}
+
+// NewAccessLogger creates an access logger writing to w
+func NewAccessLogger(w io.Writer) *AccessLogger {
+	config := DefaultAccessLogConfig()
+	return &AccessLogger{
+		config: config,
+		counts: make([]uint64, len(config.Sampling)),
+		writer: w,
+	}
+}
+
+// GetConfig returns a copy of the current access log configuration
+func (al *AccessLogger) GetConfig() AccessLogConfig {
+	al.mutex.RLock()
+	defer al.mutex.RUnlock()
+
+	config := *al.config
+	config.Sampling = append([]AccessLogSampling(nil), al.config.Sampling...)
+	return config
+}
+
+// UpdateConfig replaces the access log configuration
+func (al *AccessLogger) UpdateConfig(config *AccessLogConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	stored := *config
+	stored.Sampling = append([]AccessLogSampling(nil), config.Sampling...)
+
+	al.mutex.Lock()
+	defer al.mutex.Unlock()
+	al.config = &stored
+	al.counts = make([]uint64, len(stored.Sampling))
+	return nil
+}
+
+// SetWriter sets where access log lines are written
+func (al *AccessLogger) SetWriter(w io.Writer) {
+	al.write.Lock()
+	defer al.write.Unlock()
+	al.writer = w
+}
+
+// shouldLog applies the configuration to a finished request
+func (al *AccessLogger) shouldLog(path string, status int) bool {
+	al.mutex.RLock()
+	defer al.mutex.RUnlock()
+
+	if !al.config.Enabled {
+		return false
+	}
+	if status >= 400 {
+		return true
+	}
+	for i := range al.config.Sampling {
+		if al.config.Sampling[i].matches(path) {
+			count := atomic.AddUint64(&al.counts[i], 1)
+			return (count-1)%uint64(al.config.Sampling[i].Rate) == 0
+		}
+	}
+	return true
+}
+
+// Log writes an entry if the configuration selects it
+func (al *AccessLogger) Log(entry *AccessLogEntry) {
+	if !al.shouldLog(entry.Path, entry.Status) {
+		return
+	}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	al.write.Lock()
+	defer al.write.Unlock()
+	if _, err := al.writer.Write(line); err != nil {
+		log.Printf("Warning: Failed to write access log: %v", err)
+	}
+}
+
+// AccessLogMiddleware logs every request to GlobalAccessLogger once it has been
+// handled. Mount it right after RequestIDMiddleware, so it sees the request ID
+// and the status RecoveryMiddleware sets after a panic.
+func AccessLogMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		entry := &AccessLogEntry{
+			Time:      start.UTC(),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.Path,
+			Status:    c.Writer.Status(),
+			LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			Bytes:     c.Writer.Size(),
+			RequestID: RequestID(c),
+			ClientIP:  c.ClientIP(),
+		}
+		if entry.Bytes < 0 {
+			entry.Bytes = 0
+		}
+		if userID, ok := c.Get("user_id"); ok {
+			entry.UserID, _ = userID.(uint)
+		}
+		GlobalAccessLogger.Log(entry)
+	}
+}
+
+// GlobalAccessLogger holds the access log written by AccessLogMiddleware
+var GlobalAccessLogger = NewAccessLogger(os.Stdout)
@@ -0,0 +1,89 @@
+package security
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newAccessLogRouter returns a router logging to a buffer with the given sampling
+func newAccessLogRouter(t *testing.T, sampling []AccessLogSampling) (*gin.Engine, *bytes.Buffer) {
+	gin.SetMode(gin.TestMode)
+
+	origLogger := GlobalAccessLogger
+	t.Cleanup(func() { GlobalAccessLogger = origLogger })
+	var buf bytes.Buffer
+	GlobalAccessLogger = NewAccessLogger(&buf)
+	if err := GlobalAccessLogger.UpdateConfig(&AccessLogConfig{Enabled: true, Sampling: sampling}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(RequestIDMiddleware())
+	r.Use(AccessLogMiddleware())
+	r.GET("/files", func(c *gin.Context) {
+		c.Set("user_id", uint(42))
+		c.String(http.StatusOK, "hello")
+	})
+	r.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/broken", func(c *gin.Context) { c.Status(http.StatusInternalServerError) })
+	return r, &buf
+}
+
+func TestAccessLogMiddleware_WritesJSONLine(t *testing.T) {
+	r, buf := newAccessLogRouter(t, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/files?token=secret", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	req.RemoteAddr = "192.0.2.10:4000"
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected one JSON line, got %q: %v", buf.String(), err)
+	}
+	want := map[string]interface{}{
+		"method":     "GET",
+		"path":       "/files",
+		"status":     float64(200),
+		"bytes":      float64(5),
+		"user_id":    float64(42),
+		"request_id": "req-123",
+		"client_ip":  "192.0.2.10",
+	}
+	for field, value := range want {
+		if entry[field] != value {
+			t.Errorf("Expected %s=%v, got %v", field, value, entry[field])
+		}
+	}
+	if _, ok := entry["latency_ms"].(float64); !ok {
+		t.Errorf("Expected a latency, got %v", entry["latency_ms"])
+	}
+	if strings.Contains(buf.String(), "secret") {
+		t.Errorf("Expected the query to be left out, got %s", buf.String())
+	}
+}
+
+func TestAccessLogMiddleware_SamplesHighTrafficPaths(t *testing.T) {
+	r, buf := newAccessLogRouter(t, []AccessLogSampling{{Path: "/health", Rate: 5}})
+
+	for i := 0; i < 10; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/broken", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/files", nil))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected 2 sampled health checks and 2 other requests, got %d lines:\n%s", len(lines), buf.String())
+	}
+
+	if err := GlobalAccessLogger.UpdateConfig(&AccessLogConfig{Sampling: []AccessLogSampling{{Path: "health", Rate: 5}}}); err != ErrInvalidAccessLogSampling {
+		t.Errorf("Expected an invalid sampling path to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package security
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxSampledClients bounds the per-IP sampling state; once reached, clients
+// whose window ended are forgotten
+const maxSampledClients = 10000
+
+var (
+	ErrInvalidSamplingRate   = errors.New("sampling rate must be at least 1")
+	ErrInvalidSamplingWindow = errors.New("sampling window must be at least 1 second")
+	ErrInvalidCriticalPath   = errors.New("critical paths must be /path or /prefix/*")
+)
+
+// AuditSamplingConfig represents how AuditLogMiddleware samples failed requests.
+// The first failure of each IP in a window is always logged, then 1 in Rate.
+type AuditSamplingConfig struct {
+	Enabled       bool     `json:"enabled"`
+	Rate          int      `json:"rate"`           // 1 logs every failure
+	WindowSeconds int      `json:"window_seconds"` // how long an IP's sampling lasts before it is logged in full again
+	CriticalPaths []string `json:"critical_paths"` // never sampled out, "/prefix/*" matches below prefix
+}
+
+// DefaultAuditSamplingConfig returns default audit sampling configuration.
+// Failures on authentication and admin routes are always logged.
+func DefaultAuditSamplingConfig() *AuditSamplingConfig {
+	return &AuditSamplingConfig{
+		Enabled:       true,
+		Rate:          10,
+		WindowSeconds: 60,
+		CriticalPaths: []string{"/login", "/admin/*"},
+	}
+}
+
+// Validate checks the configuration for invalid values
+func (ac *AuditSamplingConfig) Validate() error {
+	if ac.Rate < 1 {
+		return ErrInvalidSamplingRate
+	}
+	if ac.WindowSeconds < 1 {
+		return ErrInvalidSamplingWindow
+	}
+	for _, path := range ac.CriticalPaths {
+		if !strings.HasPrefix(path, "/") || strings.Contains(strings.TrimSuffix(path, "/*"), "*") {
+			return ErrInvalidCriticalPath
+		}
+	}
+	return nil
+}
+
+// isCritical checks if a request path is on the critical list
+func (ac *AuditSamplingConfig) isCritical(path string) bool {
+	for _, critical := range ac.CriticalPaths {
+		if critical == path || (strings.HasSuffix(critical, "/*") && strings.HasPrefix(path, strings.TrimSuffix(critical, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+// AuditSamplingStats represents how many failed requests were logged or sampled out
+type AuditSamplingStats struct {
+	Logged     int64 `json:"logged"`
+	SampledOut int64 `json:"sampled_out"`
+	Clients    int   `json:"clients"` // IPs with an open sampling window
+}
+
+// sampleWindow counts the failures of one IP in its current window
+type sampleWindow struct {
+	start time.Time
+	count int
+}
+
+// AuditSampler decides which failed requests AuditLogMiddleware logs, so an
+// attack flooding 401, 403 or 429 responses can't flood the log with it
+type AuditSampler struct {
+	config  *AuditSamplingConfig
+	windows map[string]*sampleWindow
+	mutex   sync.Mutex
+
+	logged     int64
+	sampledOut int64
+}
+
+// NewAuditSampler creates a new audit sampler
+func NewAuditSampler() *AuditSampler {
+	return &AuditSampler{
+		config:  DefaultAuditSamplingConfig(),
+		windows: make(map[string]*sampleWindow),
+	}
+}
+
+// GetConfig returns a copy of the current sampling configuration
+func (as *AuditSampler) GetConfig() AuditSamplingConfig {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	config := *as.config
+	config.CriticalPaths = append([]string(nil), as.config.CriticalPaths...)
+	return config
+}
+
+// UpdateConfig replaces the sampling configuration and starts every IP afresh
+func (as *AuditSampler) UpdateConfig(config *AuditSamplingConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	stored := *config
+	stored.CriticalPaths = append([]string(nil), config.CriticalPaths...)
+
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+	as.config = &stored
+	as.windows = make(map[string]*sampleWindow)
+	return nil
+}
+
+// Sample reports whether a failed request from clientIP to path should be logged
+func (as *AuditSampler) Sample(clientIP, path string) bool {
+	logged := as.sample(clientIP, path, time.Now())
+	if logged {
+		atomic.AddInt64(&as.logged, 1)
+	} else {
+		atomic.AddInt64(&as.sampledOut, 1)
+	}
+	return logged
+}
+
+// sample applies the configuration to one failure at now
+func (as *AuditSampler) sample(clientIP, path string, now time.Time) bool {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+
+	if !as.config.Enabled || as.config.Rate == 1 || as.config.isCritical(path) {
+		return true
+	}
+
+	windowLength := time.Duration(as.config.WindowSeconds) * time.Second
+	window, exists := as.windows[clientIP]
+	if !exists || now.Sub(window.start) >= windowLength {
+		if !exists && len(as.windows) >= maxSampledClients {
+			as.pruneLocked(now, windowLength)
+		}
+		as.windows[clientIP] = &sampleWindow{start: now, count: 1}
+		return true
+	}
+
+	window.count++
+	return (window.count-1)%as.config.Rate == 0
+}
+
+// pruneLocked forgets IPs whose window has ended
+func (as *AuditSampler) pruneLocked(now time.Time, windowLength time.Duration) {
+	for ip, window := range as.windows {
+		if now.Sub(window.start) >= windowLength {
+			delete(as.windows, ip)
+		}
+	}
+}
+
+// Stats returns the sampling counters
+func (as *AuditSampler) Stats() AuditSamplingStats {
+	as.mutex.Lock()
+	clients := len(as.windows)
+	as.mutex.Unlock()
+
+	return AuditSamplingStats{
+		Logged:     atomic.LoadInt64(&as.logged),
+		SampledOut: atomic.LoadInt64(&as.sampledOut),
+		Clients:    clients,
+	}
+}
+
+// GlobalAuditSampler holds the sampling applied by AuditLogMiddleware
+var GlobalAuditSampler = NewAuditSampler()
@@ -0,0 +1,111 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupTestAuditSampler replaces the global audit sampler for the duration of a test
+func setupTestAuditSampler(t *testing.T, config *AuditSamplingConfig) *AuditSampler {
+	orig := GlobalAuditSampler
+	t.Cleanup(func() { GlobalAuditSampler = orig })
+	GlobalAuditSampler = NewAuditSampler()
+	if err := GlobalAuditSampler.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to update sampling config: %v", err)
+	}
+	return GlobalAuditSampler
+}
+
+func TestAuditLogMiddleware_SamplesBursts(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setupTestSecurityMetrics(t)
+	sampler := setupTestAuditSampler(t, &AuditSamplingConfig{
+		Enabled:       true,
+		Rate:          10,
+		WindowSeconds: 60,
+		CriticalPaths: []string{"/admin/*"},
+	})
+
+	r := gin.New()
+	r.Use(AuditLogMiddleware())
+	r.GET("/denied", func(c *gin.Context) { c.Status(http.StatusForbidden) })
+	r.GET("/admin/denied", func(c *gin.Context) { c.Status(http.StatusForbidden) })
+
+	serve := func(path, remoteAddr string) {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	// A burst of 100 failures from one IP logs the first, then 1 in 10
+	for i := 0; i < 100; i++ {
+		serve("/denied", "10.0.0.1:1234")
+	}
+	stats := sampler.Stats()
+	if stats.Logged != 10 || stats.SampledOut != 90 {
+		t.Errorf("Expected 10 logged and 90 sampled out, got %+v", stats)
+	}
+	if blocked := GlobalSecurityMetrics.Snapshot().BlockedRequests; blocked != 100 {
+		t.Errorf("Expected every failure to be counted, got %d", blocked)
+	}
+
+	// Another IP's first failure is logged
+	serve("/denied", "10.0.0.2:1234")
+	if stats := sampler.Stats(); stats.Logged != 11 {
+		t.Errorf("Expected the first failure of a new IP to be logged, got %+v", stats)
+	}
+
+	// Critical paths are never sampled out
+	for i := 0; i < 20; i++ {
+		serve("/admin/denied", "10.0.0.1:1234")
+	}
+	if stats := sampler.Stats(); stats.Logged != 31 || stats.SampledOut != 90 {
+		t.Errorf("Expected every critical failure to be logged, got %+v", stats)
+	}
+}
+
+func TestAuditSampler_WindowAndDisabled(t *testing.T) {
+	sampler := NewAuditSampler()
+	if err := sampler.UpdateConfig(&AuditSamplingConfig{Enabled: true, Rate: 5, WindowSeconds: 60}); err != nil {
+		t.Fatalf("Failed to update sampling config: %v", err)
+	}
+
+	now := time.Now()
+	if !sampler.sample("10.0.0.1", "/denied", now) || sampler.sample("10.0.0.1", "/denied", now) {
+		t.Fatal("Expected only the first failure of the window to be logged")
+	}
+	if !sampler.sample("10.0.0.1", "/denied", now.Add(time.Minute)) {
+		t.Error("Expected the first failure of a new window to be logged")
+	}
+
+	if err := sampler.UpdateConfig(&AuditSamplingConfig{Enabled: false, Rate: 5, WindowSeconds: 60}); err != nil {
+		t.Fatalf("Failed to update sampling config: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if !sampler.sample("10.0.0.1", "/denied", now) {
+			t.Fatal("Expected every failure to be logged with sampling disabled")
+		}
+	}
+}
+
+func TestAuditSamplingConfig_Validate(t *testing.T) {
+	invalid := []AuditSamplingConfig{
+		{Rate: 0, WindowSeconds: 60},
+		{Rate: 10, WindowSeconds: 0},
+		{Rate: 10, WindowSeconds: 60, CriticalPaths: []string{"admin"}},
+		{Rate: 10, WindowSeconds: 60, CriticalPaths: []string{"/admin/*/users"}},
+	}
+	for _, config := range invalid {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected %+v to be rejected", config)
+		}
+	}
+
+	if err := DefaultAuditSamplingConfig().Validate(); err != nil {
+		t.Errorf("Expected the default config to be valid: %v", err)
+	}
+}
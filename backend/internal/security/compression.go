@@ -0,0 +1,286 @@
+package security
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	ErrInvalidCompressionLevel   = errors.New("compression level must be between 1 and 9, or -1 for the default")
+	ErrInvalidCompressionMinSize = errors.New("compression min size cannot be negative")
+)
+
+// CompressionConfig represents response compression configuration
+type CompressionConfig struct {
+	Enabled      bool     `json:"enabled"`
+	MinSize      int      `json:"min_size"`      // responses smaller than this are sent as is
+	Level        int      `json:"level"`         // 1 (fastest) to 9 (smallest), -1 for the default
+	ContentTypes []string `json:"content_types"` // compressible types, "text/*" matches any subtype
+}
+
+// DefaultCompressionConfig returns default compression configuration. Images,
+// archives and other already compressed formats are left out.
+func DefaultCompressionConfig() *CompressionConfig {
+	return &CompressionConfig{
+		Enabled: true,
+		MinSize: 1024, // 1KB
+		Level:   gzip.DefaultCompression,
+		ContentTypes: []string{
+			"application/json",
+			"application/javascript",
+			"application/xml",
+			"image/svg+xml",
+			"text/*",
+		},
+	}
+}
+
+// Validate checks the configuration for invalid values
+func (cc *CompressionConfig) Validate() error {
+	if cc.Level != gzip.DefaultCompression && (cc.Level < gzip.BestSpeed || cc.Level > gzip.BestCompression) {
+		return ErrInvalidCompressionLevel
+	}
+	if cc.MinSize < 0 {
+		return ErrInvalidCompressionMinSize
+	}
+	return nil
+}
+
+// allowsContentType checks if a Content-Type is on the compressible list
+func (cc *CompressionConfig) allowsContentType(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if mediaType == "" {
+		return false
+	}
+
+	for _, allowed := range cc.ContentTypes {
+		allowed = strings.ToLower(allowed)
+		if allowed == mediaType {
+			return true
+		}
+		if strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// CompressionManager manages response compression configuration
+type CompressionManager struct {
+	config *CompressionConfig
+	mutex  sync.RWMutex
+}
+
+// NewCompressionManager creates a new compression manager
+func NewCompressionManager() *CompressionManager {
+	return &CompressionManager{
+		config: DefaultCompressionConfig(),
+	}
+}
+
+// GetConfig returns a copy of the current compression configuration
+func (cm *CompressionManager) GetConfig() CompressionConfig {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+
+	config := *cm.config
+	config.ContentTypes = append([]string(nil), cm.config.ContentTypes...)
+	return config
+}
+
+// UpdateConfig replaces the compression configuration
+func (cm *CompressionManager) UpdateConfig(config *CompressionConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	cm.mutex.Lock()
+	defer cm.mutex.Unlock()
+	cm.config = config
+	return nil
+}
+
+// GlobalCompressionConfig holds the configuration used by CompressionMiddleware
+var GlobalCompressionConfig = NewCompressionManager()
+
+// CompressionMiddleware compresses responses with gzip or deflate when the client
+// accepts it, the body reaches the minimum size and its type is compressible
+func CompressionMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		config := GlobalCompressionConfig.GetConfig()
+		if !config.Enabled || c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		c.Header("Vary", "Accept-Encoding")
+		writer := &compressWriter{ResponseWriter: c.Writer, config: config, encoding: encoding}
+		c.Writer = writer
+		defer writer.finish()
+
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks gzip or deflate from an Accept-Encoding header, by
+// quality and then preferring gzip. It returns "" if neither is acceptable.
+func negotiateEncoding(acceptEncoding string) string {
+	best, bestQuality := "", 0.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		name := strings.ToLower(strings.TrimSpace(fields[0]))
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+
+		if name == "*" {
+			name = "gzip"
+		}
+		if (name != "gzip" && name != "deflate") || quality <= 0 {
+			continue
+		}
+		if quality > bestQuality || (quality == bestQuality && name == "gzip") {
+			best, bestQuality = name, quality
+		}
+	}
+	return best
+}
+
+// compressWriter buffers the start of a response until it knows whether to
+// compress it: once MinSize bytes are written, or when the handler finishes
+type compressWriter struct {
+	gin.ResponseWriter
+	config     CompressionConfig
+	encoding   string
+	buffer     bytes.Buffer
+	decided    bool
+	compressor io.WriteCloser // nil when the response is sent as is
+}
+
+// Write buffers or compresses response data
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buffer.Write(data)
+		if w.buffer.Len() < w.config.MinSize {
+			return len(data), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+
+	if w.compressor != nil {
+		return w.compressor.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString buffers or compresses response data
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written reports whether the handler has written a response, including buffered data
+func (w *compressWriter) Written() bool {
+	return w.buffer.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// Flush sends buffered data, deciding on compression early if needed
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide()
+	}
+	if flusher, ok := w.compressor.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide chooses whether to compress and writes out the buffered data
+func (w *compressWriter) decide() error {
+	w.decided = true
+	header := w.Header()
+
+	if header.Get("Content-Type") == "" && w.buffer.Len() > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buffer.Bytes()))
+	}
+
+	if w.shouldCompress() {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+
+		compressor, err := newCompressor(w.encoding, w.ResponseWriter, w.config.Level)
+		if err != nil {
+			return err
+		}
+		w.compressor = compressor
+		_, err = w.compressor.Write(w.buffer.Bytes())
+		w.buffer.Reset()
+		return err
+	}
+
+	if w.buffer.Len() == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buffer.Bytes())
+	w.buffer.Reset()
+	return err
+}
+
+// newCompressor creates a gzip or deflate writer
+func newCompressor(encoding string, out io.Writer, level int) (io.WriteCloser, error) {
+	if encoding == "gzip" {
+		return gzip.NewWriterLevel(out, level)
+	}
+	return flate.NewWriter(out, level)
+}
+
+// shouldCompress checks if the response qualifies for compression
+func (w *compressWriter) shouldCompress() bool {
+	header := w.Header()
+	status := w.Status()
+
+	switch {
+	case w.ResponseWriter.Written(): // headers already sent
+		return false
+	case w.buffer.Len() < w.config.MinSize || w.buffer.Len() == 0:
+		return false
+	case status == http.StatusNoContent || status == http.StatusNotModified || status == http.StatusPartialContent:
+		return false
+	case header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "":
+		return false
+	}
+	return w.config.allowsContentType(header.Get("Content-Type"))
+}
+
+// finish writes out anything still buffered and completes the compressed stream
+func (w *compressWriter) finish() {
+	if !w.decided {
+		w.decide()
+	}
+	if w.compressor != nil {
+		w.compressor.Close()
+	}
+}
@@ -0,0 +1,153 @@
+package security
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newCompressionRouter returns a router behind CompressionMiddleware using the given configuration
+func newCompressionRouter(t *testing.T, config *CompressionConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	origConfig := GlobalCompressionConfig
+	t.Cleanup(func() { GlobalCompressionConfig = origConfig })
+	GlobalCompressionConfig = NewCompressionManager()
+	if err := GlobalCompressionConfig.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to set compression config: %v", err)
+	}
+
+	items := make([]gin.H, 200)
+	for i := range items {
+		items[i] = gin.H{"id": i, "name": "file.txt", "description": "a file in a long list"}
+	}
+
+	r := gin.New()
+	r.Use(CompressionMiddleware())
+	r.GET("/large", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"data": items}) })
+	r.GET("/small", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
+	r.GET("/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", []byte(strings.Repeat("x", 4096)))
+	})
+	r.GET("/empty", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return r
+}
+
+// getWithEncoding sends a GET request with the given Accept-Encoding header
+func getWithEncoding(r *gin.Engine, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCompressionMiddleware_GzipsLargeJSON(t *testing.T) {
+	r := newCompressionRouter(t, DefaultCompressionConfig())
+
+	w := getWithEncoding(r, "/large", "gzip, deflate")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+	if !strings.Contains(w.Header().Get("Vary"), "Accept-Encoding") {
+		t.Error("Expected Vary: Accept-Encoding")
+	}
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		t.Errorf("Expected JSON content type, got %q", w.Header().Get("Content-Type"))
+	}
+
+	compressedSize := w.Body.Len()
+	reader, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("Failed to open gzip body: %v", err)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("Failed to read gzip body: %v", err)
+	}
+
+	var response struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		t.Fatalf("Failed to decode decompressed body: %v", err)
+	}
+	if len(response.Data) != 200 {
+		t.Errorf("Expected 200 items, got %d", len(response.Data))
+	}
+	if compressedSize >= len(body) {
+		t.Errorf("Expected compressed size %d to be below %d", compressedSize, len(body))
+	}
+}
+
+func TestCompressionMiddleware_Deflate(t *testing.T) {
+	r := newCompressionRouter(t, DefaultCompressionConfig())
+
+	w := getWithEncoding(r, "/large", "gzip;q=0.5, deflate")
+	if w.Header().Get("Content-Encoding") != "deflate" {
+		t.Fatalf("Expected deflate encoding, got %q", w.Header().Get("Content-Encoding"))
+	}
+	body, err := io.ReadAll(flate.NewReader(w.Body))
+	if err != nil || !json.Valid(body) {
+		t.Errorf("Expected a valid deflated JSON body, got error %v", err)
+	}
+}
+
+func TestCompressionMiddleware_SkipsIneligibleResponses(t *testing.T) {
+	disabled := DefaultCompressionConfig()
+	disabled.Enabled = false
+
+	tests := []struct {
+		name           string
+		config         *CompressionConfig
+		path           string
+		acceptEncoding string
+	}{
+		{"not requested", DefaultCompressionConfig(), "/large", ""},
+		{"unsupported encoding", DefaultCompressionConfig(), "/large", "br"},
+		{"gzip refused", DefaultCompressionConfig(), "/large", "gzip;q=0"},
+		{"below minimum size", DefaultCompressionConfig(), "/small", "gzip"},
+		{"image", DefaultCompressionConfig(), "/image", "gzip"},
+		{"no content", DefaultCompressionConfig(), "/empty", "gzip"},
+		{"disabled", disabled, "/large", "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newCompressionRouter(t, tt.config)
+			w := getWithEncoding(r, tt.path, tt.acceptEncoding)
+			if encoding := w.Header().Get("Content-Encoding"); encoding != "" {
+				t.Errorf("Expected no content encoding, got %q", encoding)
+			}
+			if tt.path == "/large" && !json.Valid(w.Body.Bytes()) {
+				t.Error("Expected the plain JSON body")
+			}
+		})
+	}
+}
+
+func TestCompressionConfig_Validate(t *testing.T) {
+	config := DefaultCompressionConfig()
+	config.Level = 10
+	if err := config.Validate(); err != ErrInvalidCompressionLevel {
+		t.Errorf("Expected ErrInvalidCompressionLevel, got %v", err)
+	}
+
+	config = DefaultCompressionConfig()
+	config.MinSize = -1
+	if err := config.Validate(); err != ErrInvalidCompressionMinSize {
+		t.Errorf("Expected ErrInvalidCompressionMinSize, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package security
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// SecurityConfigManager guards the live security configuration, which admins
+// can change while middlewares read it on every request. The rate and upload
+// limiters are swapped together with the config so both always agree on the limit.
+type SecurityConfigManager struct {
+	config        SecurityConfig
+	rateLimiter   *RateLimiter
+	uploadLimiter *UploadLimiter
+	cors          *CORSPolicy
+	redirects     *RedirectPolicy
+	mutex         sync.RWMutex
+}
+
+// NewSecurityConfigManager creates a new security config manager. The config
+// must be valid; origin patterns and redirect URIs that fail to compile are ignored.
+func NewSecurityConfigManager(config SecurityConfig) *SecurityConfigManager {
+	cors, _ := compileCORSPolicy(&config)
+	redirects, err := compileRedirectPolicy(&config)
+	if err != nil {
+		redirects = &RedirectPolicy{}
+	}
+	return &SecurityConfigManager{
+		config:        cloneSecurityConfig(config),
+		rateLimiter:   NewRateLimiter(config.RateLimitPerMinute, time.Minute),
+		uploadLimiter: NewUploadLimiter(config.MaxConcurrentUploads),
+		cors:          cors,
+		redirects:     redirects,
+	}
+}
+
+// GetConfig returns a copy of the current security configuration
+func (sm *SecurityConfigManager) GetConfig() SecurityConfig {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return cloneSecurityConfig(sm.config)
+}
+
+// GetRateLimiter returns the rate limiter matching the current configuration
+func (sm *SecurityConfigManager) GetRateLimiter() *RateLimiter {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return sm.rateLimiter
+}
+
+// GetUploadLimiter returns the upload limiter matching the current configuration
+func (sm *SecurityConfigManager) GetUploadLimiter() *UploadLimiter {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return sm.uploadLimiter
+}
+
+// GetCORSPolicy returns the CORS policy compiled from the current configuration
+func (sm *SecurityConfigManager) GetCORSPolicy() *CORSPolicy {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return sm.cors
+}
+
+// GetRedirectPolicy returns the redirect allowlist compiled from the current configuration
+func (sm *SecurityConfigManager) GetRedirectPolicy() *RedirectPolicy {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return sm.redirects
+}
+
+// UpdateConfig applies update to a copy of the current configuration and swaps
+// it in if valid. Concurrent updates are serialized, so none is lost. A new rate
+// or upload limiter is created only when its limit changes, keeping existing
+// counters otherwise; uploads in progress keep the slots of the old one.
+func (sm *SecurityConfigManager) UpdateConfig(update func(*SecurityConfig)) (SecurityConfig, error) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	config := cloneSecurityConfig(sm.config)
+	update(&config)
+	if err := config.Validate(); err != nil {
+		return SecurityConfig{}, err
+	}
+	cors, err := compileCORSPolicy(&config)
+	if err != nil {
+		return SecurityConfig{}, err
+	}
+	redirects, err := compileRedirectPolicy(&config)
+	if err != nil {
+		return SecurityConfig{}, err
+	}
+
+	sm.cors = cors
+	sm.redirects = redirects
+	if config.RateLimitPerMinute != sm.config.RateLimitPerMinute {
+		sm.rateLimiter = NewRateLimiter(config.RateLimitPerMinute, time.Minute)
+	}
+	if config.MaxConcurrentUploads != sm.config.MaxConcurrentUploads {
+		sm.uploadLimiter = NewUploadLimiter(config.MaxConcurrentUploads)
+	}
+	sm.config = config
+	return cloneSecurityConfig(config), nil
+}
+
+// Validate validates the security configuration
+func (sc *SecurityConfig) Validate() error {
+	if sc.RateLimitPerMinute <= 0 {
+		return errors.New("rate limit per minute must be positive")
+	}
+	if sc.RateLimitWarningThreshold < 0 {
+		return errors.New("rate limit warning threshold cannot be negative")
+	}
+	if sc.MaxRequestSize <= 0 {
+		return errors.New("max request size must be positive")
+	}
+	if sc.RequestTimeout < 0 {
+		return errors.New("request timeout cannot be negative")
+	}
+	if sc.MaxConcurrentUploads < 0 {
+		return errors.New("max concurrent uploads cannot be negative")
+	}
+	if sc.UploadRetryAfter < time.Second {
+		return errors.New("upload retry after must be at least 1 second")
+	}
+	if sc.MultipartMemory <= 0 {
+		return errors.New("multipart memory must be positive")
+	}
+	if err := sc.ValidateCORS(); err != nil {
+		return err
+	}
+	if err := sc.validateRedirects(); err != nil {
+		return err
+	}
+	return sc.validateAuthMode()
+}
+
+// cloneSecurityConfig copies a config so callers can't modify the shared slices
+func cloneSecurityConfig(config SecurityConfig) SecurityConfig {
+	config.AllowedOrigins = append([]string(nil), config.AllowedOrigins...)
+	config.AllowedMethods = append([]string(nil), config.AllowedMethods...)
+	config.AllowedHeaders = append([]string(nil), config.AllowedHeaders...)
+	config.CORSOriginPolicies = append([]CORSOriginPolicy(nil), config.CORSOriginPolicies...)
+	config.CORSPublicPaths = append([]string(nil), config.CORSPublicPaths...)
+	config.TrustedProxies = append([]string(nil), config.TrustedProxies...)
+	config.AllowedRedirectURIs = append([]string(nil), config.AllowedRedirectURIs...)
+	return config
+}
+
+// GlobalSecurityConfig holds the live security configuration, starting from DefaultSecurityConfig
+var GlobalSecurityConfig = NewSecurityConfigManager(DefaultSecurityConfig)
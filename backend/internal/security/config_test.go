@@ -0,0 +1,106 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSecurityConfigManager_ConcurrentUpdates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	origConfig := GlobalSecurityConfig
+	t.Cleanup(func() { GlobalSecurityConfig = origConfig })
+	GlobalSecurityConfig = NewSecurityConfigManager(DefaultSecurityConfig)
+
+	r := gin.New()
+	r.Use(CORSMiddleware())
+	r.Use(RateLimitMiddleware())
+	r.Use(ConfiguredRequestSizeMiddleware())
+	r.POST("/echo", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_, err := GlobalSecurityConfig.UpdateConfig(func(config *SecurityConfig) {
+					config.RateLimitPerMinute = 1000 + i*100 + j
+					config.MaxRequestSize = int64(1024 + j)
+					config.AllowedOrigins = append(config.AllowedOrigins, "http://example.com")
+				})
+				if err != nil {
+					t.Errorf("Failed to update config: %v", err)
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("body"))
+				req.Header.Set("Origin", "http://localhost:3000")
+				w := httptest.NewRecorder()
+				r.ServeHTTP(w, req)
+				if w.Code != http.StatusOK {
+					t.Errorf("Expected status 200, got %d", w.Code)
+				}
+				GetSecurityStatus()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Every update is applied: none lost to a concurrent read-modify-write
+	config := GlobalSecurityConfig.GetConfig()
+	if got := len(config.AllowedOrigins) - len(DefaultSecurityConfig.AllowedOrigins); got != 200 {
+		t.Errorf("Expected 200 appended origins, got %d", got)
+	}
+}
+
+func TestSecurityConfigManager_SwapsRateLimiterWithConfig(t *testing.T) {
+	manager := NewSecurityConfigManager(DefaultSecurityConfig)
+	limiter := manager.GetRateLimiter()
+
+	if _, err := manager.UpdateConfig(func(config *SecurityConfig) { config.EnableHSTS = false }); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	if manager.GetRateLimiter() != limiter {
+		t.Error("Expected rate limiter to be kept when the limit is unchanged")
+	}
+
+	if _, err := manager.UpdateConfig(func(config *SecurityConfig) { config.RateLimitPerMinute = 1 }); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	newLimiter := manager.GetRateLimiter()
+	if newLimiter == limiter || !newLimiter.Allow("10.0.0.1") || newLimiter.Allow("10.0.0.1") {
+		t.Error("Expected a new rate limiter allowing one request per minute")
+	}
+
+	if _, err := manager.UpdateConfig(func(config *SecurityConfig) { config.RateLimitPerMinute = 0 }); err == nil {
+		t.Error("Expected a zero rate limit to be rejected")
+	}
+	if manager.GetConfig().RateLimitPerMinute != 1 || manager.GetRateLimiter() != newLimiter {
+		t.Error("Expected a rejected update to leave the config unchanged")
+	}
+}
+
+func TestSecurityConfigManager_GetConfigReturnsCopy(t *testing.T) {
+	manager := NewSecurityConfigManager(DefaultSecurityConfig)
+
+	config := manager.GetConfig()
+	config.AllowedOrigins[0] = "http://evil.example.com"
+
+	if manager.GetConfig().AllowedOrigins[0] == "http://evil.example.com" {
+		t.Error("Expected modifying a returned config to leave the live config untouched")
+	}
+}
@@ -0,0 +1,201 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CORSPublicMethods are the only methods allowed cross-origin on public paths
+var CORSPublicMethods = []string{"GET", "HEAD", "OPTIONS"}
+
+// CORSOriginPolicy grants origins matching Origin their own CORS policy, so
+// trusted origins can get credentials while others listed in AllowedOrigins don't
+type CORSOriginPolicy struct {
+	Origin           string `json:"origin"` // exact origin, or https://*.example.com for its subdomains
+	AllowCredentials bool   `json:"allow_credentials"`
+	MaxAgeSeconds    int    `json:"max_age_seconds"` // 0 uses CORSMaxAge
+}
+
+// CORSGrant represents the CORS headers granted to a request origin
+type CORSGrant struct {
+	AllowOrigin      string // "" grants nothing
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// originPattern is a compiled CORSOriginPolicy origin
+type originPattern struct {
+	scheme     string
+	host       string // exact host, or the domain whose subdomains match
+	port       string
+	subdomains bool
+}
+
+// compiledOriginPolicy is a CORSOriginPolicy ready for matching
+type compiledOriginPolicy struct {
+	pattern     originPattern
+	credentials bool
+	maxAge      time.Duration
+}
+
+// CORSPolicy is the CORS part of the security configuration compiled for
+// matching request origins, rebuilt whenever the configuration changes
+type CORSPolicy struct {
+	policies       []compiledOriginPolicy
+	allowed        map[string]bool
+	wildcard       bool
+	credentials    bool
+	maxAge         time.Duration
+	publicPaths    map[string]bool
+	publicPrefixes []string // from "/prefix/*" paths, kept with the trailing slash
+}
+
+// compileOriginPattern parses an origin policy pattern
+func compileOriginPattern(origin string) (originPattern, error) {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" ||
+		(u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.User != nil {
+		return originPattern{}, fmt.Errorf("invalid origin pattern %q, use scheme://host[:port] or scheme://*.domain[:port]", origin)
+	}
+
+	pattern := originPattern{scheme: u.Scheme, host: u.Hostname(), port: u.Port()}
+	if strings.HasPrefix(pattern.host, "*.") {
+		pattern.host, pattern.subdomains = strings.TrimPrefix(pattern.host, "*."), true
+	}
+	if pattern.host == "" || strings.Contains(pattern.host, "*") {
+		return originPattern{}, fmt.Errorf("invalid origin pattern %q, only a leading *. wildcard is supported", origin)
+	}
+	return pattern, nil
+}
+
+// matches reports whether a request origin matches the pattern
+func (op originPattern) matches(origin string) bool {
+	u, err := url.Parse(strings.ToLower(origin))
+	if err != nil || u.Scheme != op.scheme || u.Port() != op.port {
+		return false
+	}
+	if op.subdomains {
+		return strings.HasSuffix(u.Hostname(), "."+op.host)
+	}
+	return u.Hostname() == op.host
+}
+
+// compileCORSPolicy compiles the CORS part of a configuration
+func compileCORSPolicy(sc *SecurityConfig) (*CORSPolicy, error) {
+	policy := &CORSPolicy{
+		allowed:     make(map[string]bool),
+		credentials: sc.AllowCredentials,
+		maxAge:      sc.CORSMaxAge,
+		publicPaths: make(map[string]bool),
+	}
+	for _, path := range sc.CORSPublicPaths {
+		if strings.HasSuffix(path, "/*") {
+			policy.publicPrefixes = append(policy.publicPrefixes, strings.TrimSuffix(path, "*"))
+		} else {
+			policy.publicPaths[path] = true
+		}
+	}
+	for _, origin := range sc.AllowedOrigins {
+		if origin == "*" {
+			policy.wildcard = true
+		}
+		policy.allowed[origin] = true
+	}
+
+	for _, originPolicy := range sc.CORSOriginPolicies {
+		pattern, err := compileOriginPattern(originPolicy.Origin)
+		if err != nil {
+			return nil, err
+		}
+		maxAge := sc.CORSMaxAge
+		if originPolicy.MaxAgeSeconds > 0 {
+			maxAge = time.Duration(originPolicy.MaxAgeSeconds) * time.Second
+		}
+		policy.policies = append(policy.policies, compiledOriginPolicy{
+			pattern:     pattern,
+			credentials: originPolicy.AllowCredentials,
+			maxAge:      maxAge,
+		})
+	}
+	return policy, nil
+}
+
+// Grant returns the CORS headers for a request origin: those of the first
+// matching origin policy, else the global ones for a listed origin, else a
+// non-credentialed wildcard if any origin is allowed
+func (cp *CORSPolicy) Grant(origin string) CORSGrant {
+	if origin != "" {
+		for _, policy := range cp.policies {
+			if policy.pattern.matches(origin) {
+				return CORSGrant{AllowOrigin: origin, AllowCredentials: policy.credentials, MaxAge: policy.maxAge}
+			}
+		}
+		if cp.allowed[origin] {
+			return CORSGrant{AllowOrigin: origin, AllowCredentials: cp.credentials, MaxAge: cp.maxAge}
+		}
+	}
+	if cp.wildcard {
+		return CORSGrant{AllowOrigin: "*", MaxAge: cp.maxAge}
+	}
+	return CORSGrant{MaxAge: cp.maxAge}
+}
+
+// IsPublicPath reports whether a request path is one of the CORS public paths
+func (cp *CORSPolicy) IsPublicPath(path string) bool {
+	if cp.publicPaths[path] {
+		return true
+	}
+	for _, prefix := range cp.publicPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateCORS validates the CORS part of the security configuration
+func (sc *SecurityConfig) ValidateCORS() error {
+	for _, origin := range sc.AllowedOrigins {
+		if origin == "" {
+			return errors.New("allowed origins cannot contain an empty origin")
+		}
+		// Browsers refuse credentials with a wildcard origin, and reflecting any
+		// origin instead would let every site make authenticated requests
+		if origin == "*" && sc.AllowCredentials {
+			return errors.New("credentials cannot be allowed together with a wildcard origin")
+		}
+	}
+	if len(sc.AllowedMethods) == 0 {
+		return errors.New("at least one allowed method is required")
+	}
+	for _, method := range sc.AllowedMethods {
+		if method == "" || strings.ContainsAny(method, " ,") {
+			return fmt.Errorf("invalid allowed method %q", method)
+		}
+	}
+	for _, header := range sc.AllowedHeaders {
+		if header == "" || strings.ContainsAny(header, " ,") {
+			return fmt.Errorf("invalid allowed header %q", header)
+		}
+	}
+	if sc.CORSMaxAge < 0 {
+		return errors.New("CORS max age cannot be negative")
+	}
+	for _, policy := range sc.CORSOriginPolicies {
+		if _, err := compileOriginPattern(policy.Origin); err != nil {
+			return err
+		}
+		if policy.MaxAgeSeconds < 0 {
+			return fmt.Errorf("CORS max age of origin %q cannot be negative", policy.Origin)
+		}
+	}
+	for _, path := range sc.CORSPublicPaths {
+		if !strings.HasPrefix(path, "/") || strings.Contains(strings.TrimSuffix(path, "/*"), "*") {
+			return fmt.Errorf("invalid CORS public path %q, use /path or /prefix/*", path)
+		}
+	}
+	return nil
+}
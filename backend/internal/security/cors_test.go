@@ -0,0 +1,223 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newCORSRouter returns a router behind CORSMiddleware with the given origins and credentials setting
+func newCORSRouter(t *testing.T, origins []string, allowCredentials bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	origConfig := GlobalSecurityConfig
+	t.Cleanup(func() { GlobalSecurityConfig = origConfig })
+	GlobalSecurityConfig = NewSecurityConfigManager(DefaultSecurityConfig)
+	if _, err := GlobalSecurityConfig.UpdateConfig(func(config *SecurityConfig) {
+		config.AllowedOrigins = origins
+		config.AllowedMethods = []string{"GET", "POST"}
+		config.AllowCredentials = allowCredentials
+	}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(CORSMiddleware())
+	r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func corsRequest(r *gin.Engine, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set("Origin", origin)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORSMiddleware_Credentialed(t *testing.T) {
+	r := newCORSRouter(t, []string{"https://app.example.com"}, true)
+
+	w := corsRequest(r, "https://app.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Expected allowed origin to be reflected, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Expected credentials to be allowed, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, POST" {
+		t.Errorf("Expected configured methods, got %q", got)
+	}
+
+	w = corsRequest(r, "https://evil.example.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("Expected no CORS grant for an unlisted origin, got %v", w.Header())
+	}
+}
+
+func TestCORSMiddleware_NonCredentialed(t *testing.T) {
+	r := newCORSRouter(t, []string{"*"}, false)
+
+	w := corsRequest(r, "https://any.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("Expected wildcard origin, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Expected no credentials header, got %q", got)
+	}
+
+	r = newCORSRouter(t, []string{"https://app.example.com"}, false)
+	w = corsRequest(r, "https://app.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("Expected allowed origin to be reflected, got %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("Expected no credentials header, got %q", got)
+	}
+}
+
+func TestSecurityConfig_RejectsCredentialsWithWildcard(t *testing.T) {
+	manager := NewSecurityConfigManager(DefaultSecurityConfig)
+
+	if _, err := manager.UpdateConfig(func(config *SecurityConfig) {
+		config.AllowedOrigins = []string{"http://localhost:3000", "*"}
+		config.AllowCredentials = true
+	}); err == nil {
+		t.Error("Expected credentials with a wildcard origin to be rejected")
+	}
+
+	if _, err := manager.UpdateConfig(func(config *SecurityConfig) { config.AllowedMethods = nil }); err == nil {
+		t.Error("Expected an empty method list to be rejected")
+	}
+}
+
+func TestCORSMiddleware_PerOriginPolicies(t *testing.T) {
+	r := newCORSRouter(t, []string{"https://partner.example.org"}, false)
+	if _, err := GlobalSecurityConfig.UpdateConfig(func(config *SecurityConfig) {
+		config.CORSMaxAge = 10 * time.Minute
+		config.CORSOriginPolicies = []CORSOriginPolicy{
+			{Origin: "https://app.example.com", AllowCredentials: true, MaxAgeSeconds: 3600},
+			{Origin: "https://*.tools.example.com", AllowCredentials: true},
+			{Origin: "http://localhost:3000"},
+		}
+	}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	tests := []struct {
+		origin          string
+		wantOrigin      string
+		wantCredentials string
+		wantMaxAge      string
+	}{
+		{"https://app.example.com", "https://app.example.com", "true", "3600"},
+		{"https://ci.tools.example.com", "https://ci.tools.example.com", "true", "600"},
+		{"https://tools.example.com", "", "", "600"},   // the pattern only covers subdomains
+		{"http://ci.tools.example.com", "", "", "600"}, // nor other schemes
+		{"http://localhost:3000", "http://localhost:3000", "", "600"},
+		{"http://localhost:3001", "", "", "600"},
+		{"https://partner.example.org", "https://partner.example.org", "", "600"}, // global AllowedOrigins
+		{"https://evil.example.com", "", "", "600"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.origin, func(t *testing.T) {
+			w := corsRequest(r, tt.origin)
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Expected allowed origin %q, got %q", tt.wantOrigin, got)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCredentials {
+				t.Errorf("Expected credentials %q, got %q", tt.wantCredentials, got)
+			}
+			if got := w.Header().Get("Access-Control-Max-Age"); got != tt.wantMaxAge {
+				t.Errorf("Expected max age %q, got %q", tt.wantMaxAge, got)
+			}
+		})
+	}
+
+	// A zero max age leaves preflight caching to the browser
+	GlobalSecurityConfig.UpdateConfig(func(config *SecurityConfig) { config.CORSMaxAge = 0 })
+	if got := corsRequest(r, "http://localhost:3000").Header().Get("Access-Control-Max-Age"); got != "" {
+		t.Errorf("Expected no max age header, got %q", got)
+	}
+}
+
+func TestSecurityConfig_RejectsInvalidOriginPolicies(t *testing.T) {
+	manager := NewSecurityConfigManager(DefaultSecurityConfig)
+
+	for _, origin := range []string{"*", "app.example.com", "ftp://app.example.com", "https://app.*.example.com", "https://app.example.com/path"} {
+		if _, err := manager.UpdateConfig(func(config *SecurityConfig) {
+			config.CORSOriginPolicies = []CORSOriginPolicy{{Origin: origin}}
+		}); err == nil {
+			t.Errorf("Expected origin pattern %q to be rejected", origin)
+		}
+	}
+
+	if _, err := manager.UpdateConfig(func(config *SecurityConfig) { config.CORSMaxAge = -time.Second }); err == nil {
+		t.Error("Expected a negative max age to be rejected")
+	}
+}
+
+func TestCORSMiddleware_PublicPaths(t *testing.T) {
+	r := newCORSRouter(t, []string{"https://app.example.com"}, true)
+	if _, err := GlobalSecurityConfig.UpdateConfig(func(config *SecurityConfig) {
+		config.CORSPublicPaths = []string{"/health", "/uploads/avatars/*"}
+	}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	r.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/uploads/avatars/*filename", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		method string
+		path   string
+		origin string
+		public bool
+	}{
+		{http.MethodGet, "/health", "https://any.example.com", true},
+		{http.MethodOptions, "/health", "https://any.example.com", true},
+		{http.MethodGet, "/uploads/avatars/1.png", "https://any.example.com", true},
+		{http.MethodGet, "/health/", "https://any.example.com", false},
+		{http.MethodGet, "/ping", "https://any.example.com", false},
+		{http.MethodOptions, "/ping", "https://any.example.com", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		req.Header.Set("Origin", tt.origin)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		allowOrigin := w.Header().Get("Access-Control-Allow-Origin")
+		if tt.public {
+			if allowOrigin != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+				t.Errorf("%s %s: expected a wildcard grant without credentials, got %v", tt.method, tt.path, w.Header())
+			}
+			if got := w.Header().Get("Access-Control-Allow-Methods"); got != "GET, HEAD, OPTIONS" {
+				t.Errorf("%s %s: expected only safe methods, got %q", tt.method, tt.path, got)
+			}
+		} else if allowOrigin != "" {
+			t.Errorf("%s %s: expected no CORS grant for an unlisted origin, got %q", tt.method, tt.path, allowOrigin)
+		}
+	}
+
+	// Allowed origins keep their credentialed grant on restricted paths
+	w := corsRequest(r, "https://app.example.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" || w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("Expected the strict policy on restricted paths, got %v", w.Header())
+	}
+}
+
+func TestSecurityConfig_RejectsInvalidPublicPaths(t *testing.T) {
+	manager := NewSecurityConfigManager(DefaultSecurityConfig)
+
+	for _, path := range []string{"health", "*", "/uploads/*/avatars", "/uploads*"} {
+		if _, err := manager.UpdateConfig(func(config *SecurityConfig) {
+			config.CORSPublicPaths = []string{path}
+		}); err == nil {
+			t.Errorf("Expected public path %q to be rejected", path)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package security
+
+import (
+	"crypto/subtle"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/authorization"
+	"golangmcp/internal/session"
+)
+
+// APIKeyHeader is the header carrying service API keys
+const APIKeyHeader = "X-API-Key"
+
+// RateLimitBypassHeader carries the rate limit bypass token of load tests
+const RateLimitBypassHeader = "X-RateLimit-Bypass"
+
+// RateLimitExemptions lists callers that bypass the global rate limiter
+type RateLimitExemptions struct {
+	Roles   []string `json:"roles"`
+	UserIDs []uint   `json:"user_ids"`
+	APIKeys []string `json:"api_keys"`
+}
+
+// Exemption describes why a rate-limited request was let through
+type Exemption struct {
+	Reason   string // role, user_id, api_key, bypass_token
+	UserID   *uint
+	Username string
+	Role     string
+}
+
+// ExemptionManager manages rate limit exemptions
+type ExemptionManager struct {
+	exemptions  RateLimitExemptions
+	bypassToken string // empty disables RateLimitBypassHeader
+	onExempt    func(*gin.Context, *Exemption)
+	mutex       sync.RWMutex
+}
+
+// NewExemptionManager creates a new exemption manager
+func NewExemptionManager() *ExemptionManager {
+	return &ExemptionManager{
+		exemptions: RateLimitExemptions{
+			Roles: []string{"admin"},
+		},
+	}
+}
+
+// GetExemptions returns the current exemptions
+func (em *ExemptionManager) GetExemptions() RateLimitExemptions {
+	em.mutex.RLock()
+	defer em.mutex.RUnlock()
+	return em.exemptions
+}
+
+// UpdateExemptions replaces the current exemptions
+func (em *ExemptionManager) UpdateExemptions(exemptions RateLimitExemptions) {
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	em.exemptions = exemptions
+}
+
+// SetBypassToken sets the token which, sent in RateLimitBypassHeader, exempts
+// any request from rate limiting, so load tests don't need limits disabled
+// globally. An empty token disables the bypass. It is kept out of
+// RateLimitExemptions so the admin API never reveals it.
+func (em *ExemptionManager) SetBypassToken(token string) {
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	em.bypassToken = token
+}
+
+// SetExemptionHandler registers a callback invoked whenever an exemption lets
+// through a request that would otherwise have been rate limited
+func (em *ExemptionManager) SetExemptionHandler(handler func(*gin.Context, *Exemption)) {
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	em.onExempt = handler
+}
+
+// Check authenticates the request and returns the exemption that applies to it, if any.
+// Only verified credentials are considered: the bypass token, a configured API
+// key or a JWT passing the checks of AuthMiddleware, i.e. valid, unrevoked and
+// used from the address its session is bound to. Narrowly scoped tokens are
+// never exempt.
+func (em *ExemptionManager) Check(c *gin.Context) *Exemption {
+	exemptions := em.GetExemptions()
+
+	em.mutex.RLock()
+	bypassToken := em.bypassToken
+	em.mutex.RUnlock()
+	if token := c.GetHeader(RateLimitBypassHeader); token != "" && bypassToken != "" {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(bypassToken)) == 1 {
+			return &Exemption{Reason: "bypass_token"}
+		}
+	}
+
+	if apiKey := c.GetHeader(APIKeyHeader); apiKey != "" {
+		for _, key := range exemptions.APIKeys {
+			if subtle.ConstantTimeCompare([]byte(apiKey), []byte(key)) == 1 {
+				return &Exemption{Reason: "api_key"}
+			}
+		}
+	}
+
+	tokenString, _ := RequestToken(c.Request)
+	if tokenString == "" {
+		return nil
+	}
+
+	claims, err := auth.ValidateJWT(tokenString, auth.JWTSecret())
+	if err != nil || !authorization.ScopesAllow(claims.Scopes, "*") {
+		return nil
+	}
+	if session.GlobalSessionManager.IsTokenRevoked(tokenString, claims) {
+		return nil
+	}
+	if err := session.GlobalSessionManager.CheckSessionIP(tokenString, claims, c.ClientIP(), c.Request.UserAgent()); err != nil {
+		return nil
+	}
+
+	exemption := &Exemption{
+		UserID:   &claims.UserID,
+		Username: claims.Username,
+		Role:     claims.Role,
+	}
+
+	for _, userID := range exemptions.UserIDs {
+		if userID == claims.UserID {
+			exemption.Reason = "user_id"
+			return exemption
+		}
+	}
+
+	for _, role := range exemptions.Roles {
+		if role == claims.Role {
+			exemption.Reason = "role"
+			return exemption
+		}
+	}
+
+	return nil
+}
+
+// notify invokes the exemption handler, if any
+func (em *ExemptionManager) notify(c *gin.Context, exemption *Exemption) {
+	em.mutex.RLock()
+	onExempt := em.onExempt
+	em.mutex.RUnlock()
+
+	if onExempt != nil {
+		onExempt(c, exemption)
+	}
+}
+
+// GlobalExemptionManager holds the rate limit exemptions used by RateLimitMiddleware
+var GlobalExemptionManager = NewExemptionManager()
@@ -0,0 +1,182 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/models"
+	"golangmcp/internal/session"
+)
+
+// newRateLimitedRouter returns a router allowing one request per minute per client
+func newRateLimitedRouter(t *testing.T, exemptions RateLimitExemptions) (*gin.Engine, *[]*Exemption) {
+	gin.SetMode(gin.TestMode)
+
+	origConfig, origManager := GlobalSecurityConfig, GlobalExemptionManager
+	t.Cleanup(func() {
+		GlobalSecurityConfig, GlobalExemptionManager = origConfig, origManager
+	})
+
+	config := DefaultSecurityConfig
+	config.RateLimitPerMinute = 1
+	GlobalSecurityConfig = NewSecurityConfigManager(config)
+	GlobalExemptionManager = NewExemptionManager()
+	GlobalExemptionManager.UpdateExemptions(exemptions)
+
+	var used []*Exemption
+	GlobalExemptionManager.SetExemptionHandler(func(c *gin.Context, e *Exemption) {
+		used = append(used, e)
+	})
+
+	r := gin.New()
+	r.Use(RateLimitMiddleware())
+	r.GET("/ping", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	return r, &used
+}
+
+// doRequests sends n requests with the given headers and returns the last status code
+func doRequests(r *gin.Engine, n int, headers map[string]string) int {
+	code := 0
+	for i := 0; i < n; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		code = w.Code
+	}
+	return code
+}
+
+func bearer(t *testing.T, user *models.User) map[string]string {
+	token, _, err := auth.GenerateJWT(user, []byte("my_secret_key"))
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	return map[string]string{"Authorization": "Bearer " + token}
+}
+
+func TestRateLimitMiddleware_NonExemptIsLimited(t *testing.T) {
+	r, used := newRateLimitedRouter(t, RateLimitExemptions{Roles: []string{"admin"}})
+
+	user := &models.User{ID: 2, Username: "user", Role: "user"}
+	if code := doRequests(r, 3, bearer(t, user)); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", code)
+	}
+
+	if len(*used) != 0 {
+		t.Errorf("Expected no exemptions used, got %d", len(*used))
+	}
+}
+
+func TestRateLimitMiddleware_ExemptRole(t *testing.T) {
+	r, used := newRateLimitedRouter(t, RateLimitExemptions{Roles: []string{"admin"}})
+
+	admin := &models.User{ID: 1, Username: "admin", Role: "admin"}
+	if code := doRequests(r, 3, bearer(t, admin)); code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", code)
+	}
+
+	// The first request was within the limit, so only the following two used the exemption
+	if len(*used) != 2 || (*used)[0].Reason != "role" {
+		t.Errorf("Expected 2 role exemptions, got %+v", *used)
+	}
+}
+
+func TestRateLimitMiddleware_ExemptUserID(t *testing.T) {
+	r, used := newRateLimitedRouter(t, RateLimitExemptions{UserIDs: []uint{9}})
+
+	user := &models.User{ID: 9, Username: "service", Role: "user"}
+	if code := doRequests(r, 3, bearer(t, user)); code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", code)
+	}
+
+	if len(*used) == 0 || (*used)[0].Reason != "user_id" || *(*used)[0].UserID != 9 {
+		t.Errorf("Expected user_id exemption, got %+v", *used)
+	}
+}
+
+func TestRateLimitMiddleware_ExemptAPIKey(t *testing.T) {
+	r, _ := newRateLimitedRouter(t, RateLimitExemptions{APIKeys: []string{"service-key-0123456789"}})
+
+	if code := doRequests(r, 3, map[string]string{APIKeyHeader: "service-key-0123456789"}); code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", code)
+	}
+
+	if code := doRequests(r, 1, map[string]string{APIKeyHeader: "wrong-key"}); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for unknown API key, got %d", code)
+	}
+}
+
+func TestRateLimitMiddleware_ForgedTokenNotExempt(t *testing.T) {
+	r, _ := newRateLimitedRouter(t, RateLimitExemptions{Roles: []string{"admin"}})
+
+	admin := &models.User{ID: 1, Username: "admin", Role: "admin"}
+	token, _, _ := auth.GenerateJWT(admin, []byte("wrong_secret"))
+
+	if code := doRequests(r, 3, map[string]string{"Authorization": "Bearer " + token}); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for unverified token, got %d", code)
+	}
+}
+
+func TestRateLimitMiddleware_RejectedTokensNotExempt(t *testing.T) {
+	r, _ := newRateLimitedRouter(t, RateLimitExemptions{Roles: []string{"admin"}})
+	admin := &models.User{ID: 1, Username: "admin", Role: "admin"}
+
+	revoked := bearer(t, admin)
+	session.GlobalSessionManager.BlacklistToken(strings.TrimPrefix(revoked["Authorization"], "Bearer "))
+	if code := doRequests(r, 3, revoked); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for a revoked token, got %d", code)
+	}
+
+	scoped, _, err := auth.GenerateScopedJWT(admin, []string{"profile.read"}, time.Hour, []byte("my_secret_key"))
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	if code := doRequests(r, 3, map[string]string{"Authorization": "Bearer " + scoped}); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for a narrowly scoped token, got %d", code)
+	}
+
+	// With IP binding on, a token must come with its session
+	previous := session.GlobalSessionManager.GetConfig()
+	t.Cleanup(func() { session.GlobalSessionManager.UpdateConfig(&previous) })
+	session.GlobalSessionManager.UpdateConfig(&session.SessionConfig{BindToIP: true})
+	if code := doRequests(r, 3, bearer(t, admin)); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for a token without its bound session, got %d", code)
+	}
+}
+
+func TestRateLimitMiddleware_BypassToken(t *testing.T) {
+	r, used := newRateLimitedRouter(t, RateLimitExemptions{})
+	bypass := map[string]string{RateLimitBypassHeader: "load-test-token-0123456789abcdef"}
+
+	// Without a configured token the header is ignored
+	if code := doRequests(r, 2, bypass); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 with the bypass off, got %d", code)
+	}
+
+	GlobalExemptionManager.SetBypassToken("load-test-token-0123456789abcdef")
+	if code := doRequests(r, 3, bypass); code != http.StatusOK {
+		t.Errorf("Expected status 200 with the bypass token, got %d", code)
+	}
+	if len(*used) != 3 || (*used)[0].Reason != "bypass_token" {
+		t.Errorf("Expected every bypassed request to be reported, got %+v", *used)
+	}
+	if code := doRequests(r, 1, map[string]string{RateLimitBypassHeader: "wrong-token"}); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 for a wrong bypass token, got %d", code)
+	}
+
+	GlobalExemptionManager.SetBypassToken("")
+	if code := doRequests(r, 1, bypass); code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429 once the bypass is turned off, got %d", code)
+	}
+}
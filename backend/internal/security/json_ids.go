@@ -0,0 +1,180 @@
+package security
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderIDFormat lets a client choose how IDs are serialized, overriding the
+// server default: "string" or "number"
+const HeaderIDFormat = "X-ID-Format"
+
+// Values of HeaderIDFormat
+const (
+	IDFormatString = "string"
+	IDFormatNumber = "number"
+)
+
+// StringIDMiddleware serializes the IDs of JSON responses as strings, so
+// JavaScript clients don't lose precision on IDs above 2^53. An ID is the value
+// of an "id" or "*_id" key, or an element of an "ids" or "*_ids" array. With
+// stringIDs false, responses are unchanged unless the client asks for strings
+// with X-ID-Format; existing clients keep receiving numbers.
+func StringIDMiddleware(stringIDs bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", HeaderIDFormat)
+		enabled := stringIDs
+		switch c.GetHeader(HeaderIDFormat) {
+		case IDFormatString:
+			enabled = true
+		case IDFormatNumber:
+			enabled = false
+		}
+		if !enabled || c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+
+		writer := &stringIDWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		// Also runs on a panic, so the error response RecoveryMiddleware writes
+		// next goes straight to the client
+		defer func() {
+			writer.finish()
+			c.Writer = writer.ResponseWriter
+		}()
+
+		c.Next()
+	}
+}
+
+// stringIDWriter buffers JSON responses so their IDs can be rewritten once the
+// handler finishes; any other response is passed through as written
+type stringIDWriter struct {
+	gin.ResponseWriter
+	buffer    bytes.Buffer
+	decided   bool
+	buffering bool
+}
+
+// Write buffers JSON data and passes everything else through
+func (w *stringIDWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.decided = true
+		w.buffering = isJSONContentType(w.Header().Get("Content-Type"))
+	}
+	if w.buffering {
+		return w.buffer.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WriteString buffers JSON data and passes everything else through
+func (w *stringIDWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written reports whether the handler has written a response, including buffered data
+func (w *stringIDWriter) Written() bool {
+	return w.buffer.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// Flush gives up on rewriting a response that is being streamed
+func (w *stringIDWriter) Flush() {
+	w.writeBuffered(w.buffer.Bytes())
+	w.buffering = false
+	w.ResponseWriter.Flush()
+}
+
+// finish rewrites and writes out a buffered JSON response. Anything written
+// afterwards is passed through.
+func (w *stringIDWriter) finish() {
+	buffering := w.buffering
+	w.decided, w.buffering = true, false
+	if !buffering || w.buffer.Len() == 0 {
+		return
+	}
+
+	body := w.buffer.Bytes()
+	if rewritten, err := stringifyJSONIDs(body); err == nil {
+		body = rewritten
+	}
+	w.writeBuffered(body)
+}
+
+// writeBuffered writes data through and empties the buffer
+func (w *stringIDWriter) writeBuffered(data []byte) {
+	if len(data) > 0 {
+		w.Header().Del("Content-Length")
+		w.ResponseWriter.Write(data)
+	}
+	w.buffer.Reset()
+}
+
+// isJSONContentType checks if a Content-Type is JSON
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// stringifyJSONIDs rewrites the integer IDs of a JSON document as strings. Numbers
+// are decoded exactly, so IDs too large for a float64 keep every digit.
+func stringifyJSONIDs(body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return json.Marshal(stringifyIDs(document))
+}
+
+// stringifyIDs walks a decoded JSON value, converting the IDs it finds
+func stringifyIDs(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if isIDKey(key) {
+				v[key] = idToString(item)
+			} else {
+				v[key] = stringifyIDs(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = stringifyIDs(item)
+		}
+	}
+	return value
+}
+
+// isIDKey checks if a key holds an ID or a list of IDs
+func isIDKey(key string) bool {
+	return key == "id" || key == "ids" || strings.HasSuffix(key, "_id") || strings.HasSuffix(key, "_ids")
+}
+
+// idToString converts an integer, or a list of them, to strings; anything else
+// is left as is
+func idToString(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if _, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return v.String()
+		}
+		if _, err := strconv.ParseInt(v.String(), 10, 64); err == nil {
+			return v.String()
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = idToString(item)
+		}
+	}
+	return value
+}
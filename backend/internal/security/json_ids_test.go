@@ -0,0 +1,108 @@
+package security
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newStringIDRouter returns a router serving a file and a user list behind StringIDMiddleware
+func newStringIDRouter(stringIDs bool) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(StringIDMiddleware(stringIDs))
+	r.GET("/file", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"data": gin.H{
+				"id":         uint64(1<<62 + 1), // loses precision as a float64
+				"user_id":    uint(7),
+				"filename":   "report.pdf",
+				"size":       1024,
+				"request_id": "abc-123",
+				"tag_ids":    []uint{1, 2},
+			},
+			"results": []gin.H{{"id": 3, "status": "ok"}},
+		})
+	})
+	r.GET("/text", func(c *gin.Context) { c.String(http.StatusOK, `{"id": 1}`) })
+	return r
+}
+
+func getWithIDFormat(r *gin.Engine, path, format string) map[string]interface{} {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if format != "" {
+		req.Header.Set(HeaderIDFormat, format)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	return body
+}
+
+func TestStringIDMiddleware_Enabled(t *testing.T) {
+	body := getWithIDFormat(newStringIDRouter(true), "/file", "")
+	data := body["data"].(map[string]interface{})
+
+	if data["id"] != "4611686018427387905" || data["user_id"] != "7" {
+		t.Errorf("Expected exact string IDs, got id=%#v user_id=%#v", data["id"], data["user_id"])
+	}
+	if ids, _ := data["tag_ids"].([]interface{}); len(ids) != 2 || ids[0] != "1" || ids[1] != "2" {
+		t.Errorf("Expected ID lists as strings, got %#v", data["tag_ids"])
+	}
+	if data["size"] != float64(1024) || data["request_id"] != "abc-123" {
+		t.Errorf("Expected other fields to be unchanged, got size=%#v request_id=%#v", data["size"], data["request_id"])
+	}
+	if result := body["results"].([]interface{})[0].(map[string]interface{}); result["id"] != "3" {
+		t.Errorf("Expected IDs in arrays of objects as strings, got %#v", result["id"])
+	}
+
+	// Clients not yet migrated can still ask for numbers
+	data = getWithIDFormat(newStringIDRouter(true), "/file", IDFormatNumber)["data"].(map[string]interface{})
+	if _, ok := data["id"].(float64); !ok {
+		t.Errorf("Expected a numeric ID when asked for numbers, got %#v", data["id"])
+	}
+}
+
+func TestStringIDMiddleware_DisabledByDefault(t *testing.T) {
+	r := newStringIDRouter(false)
+
+	data := getWithIDFormat(r, "/file", "")["data"].(map[string]interface{})
+	if _, ok := data["user_id"].(float64); !ok {
+		t.Errorf("Expected numeric IDs by default, got %#v", data["user_id"])
+	}
+
+	data = getWithIDFormat(r, "/file", IDFormatString)["data"].(map[string]interface{})
+	if data["user_id"] != "7" {
+		t.Errorf("Expected string IDs when the client asks for them, got %#v", data["user_id"])
+	}
+
+	// Only JSON responses are rewritten
+	if body := getWithIDFormat(r, "/text", IDFormatString); body["id"] != float64(1) {
+		t.Errorf("Expected a non-JSON response to be left alone, got %#v", body["id"])
+	}
+}
+
+func TestStringIDMiddleware_PanicBehindRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	// Ordered as in main.go
+	r.Use(RecoveryMiddleware(nil))
+	r.Use(StringIDMiddleware(true))
+	r.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected a JSON error body, got %q", w.Body.String())
+	}
+	if w.Code != http.StatusInternalServerError || body["code"] != InternalErrorCode {
+		t.Errorf("Expected the recovery error response, got %d: %s", w.Code, w.Body.String())
+	}
+}
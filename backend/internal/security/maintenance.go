@@ -0,0 +1,148 @@
+package security
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/authorization"
+	"golangmcp/internal/models"
+	"golangmcp/internal/session"
+	"gorm.io/gorm"
+)
+
+// maintenanceSettingKey is the system setting holding the persisted maintenance state
+const maintenanceSettingKey = "maintenance_mode"
+
+// MaintenanceState represents maintenance mode configuration
+type MaintenanceState struct {
+	Enabled           bool      `json:"enabled"`
+	Message           string    `json:"message"`
+	RetryAfterSeconds int       `json:"retry_after_seconds"`
+	AllowedPaths      []string  `json:"allowed_paths"` // reachable by everyone, prefix match
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// DefaultMaintenanceState returns default maintenance state
+func DefaultMaintenanceState() *MaintenanceState {
+	return &MaintenanceState{
+		Enabled:           false,
+		Message:           "The service is down for maintenance",
+		RetryAfterSeconds: 300,
+		AllowedPaths:      []string{"/health", "/login", "/admin/maintenance"},
+	}
+}
+
+// MaintenanceManager manages maintenance mode. The state is persisted as a
+// system setting so it survives restarts.
+type MaintenanceManager struct {
+	state *MaintenanceState
+	mutex sync.RWMutex
+}
+
+// NewMaintenanceManager creates a new maintenance manager
+func NewMaintenanceManager() *MaintenanceManager {
+	return &MaintenanceManager{
+		state: DefaultMaintenanceState(),
+	}
+}
+
+// GetState returns the current maintenance state
+func (mm *MaintenanceManager) GetState() MaintenanceState {
+	mm.mutex.RLock()
+	defer mm.mutex.RUnlock()
+	return *mm.state
+}
+
+// UpdateState persists and applies a new maintenance state
+func (mm *MaintenanceManager) UpdateState(database *gorm.DB, state *MaintenanceState) error {
+	state.UpdatedAt = time.Now()
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	if err := models.SetSystemSetting(database, maintenanceSettingKey, string(data)); err != nil {
+		return err
+	}
+
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+	mm.state = state
+	return nil
+}
+
+// LoadState restores the persisted maintenance state, if any
+func (mm *MaintenanceManager) LoadState(database *gorm.DB) error {
+	value, err := models.GetSystemSetting(database, maintenanceSettingKey)
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	state := DefaultMaintenanceState()
+	if err := json.Unmarshal([]byte(value), state); err != nil {
+		return err
+	}
+
+	mm.mutex.Lock()
+	defer mm.mutex.Unlock()
+	mm.state = state
+	return nil
+}
+
+// GlobalMaintenanceManager holds the maintenance state used by MaintenanceMiddleware
+var GlobalMaintenanceManager = NewMaintenanceManager()
+
+// MaintenanceMiddleware rejects requests with 503 while maintenance mode is on.
+// Allowed paths (health checks, login) and requests from admins still go through.
+func MaintenanceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := GlobalMaintenanceManager.GetState()
+		if !state.Enabled || isMaintenancePathAllowed(c.Request.URL.Path, state.AllowedPaths) || isAdminRequest(c) {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", strconv.Itoa(state.RetryAfterSeconds))
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":       state.Message,
+			"maintenance": true,
+			"retry_after": state.RetryAfterSeconds,
+		})
+		c.Abort()
+	}
+}
+
+// isMaintenancePathAllowed checks if a path stays reachable during maintenance
+func isMaintenancePathAllowed(path string, allowedPaths []string) bool {
+	for _, allowed := range allowedPaths {
+		if path == allowed || strings.HasPrefix(path, strings.TrimSuffix(allowed, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// isAdminRequest checks if the request carries a valid, unrevoked admin token
+// whose scopes, if any, grant admin.security
+func isAdminRequest(c *gin.Context) bool {
+	tokenString, _ := RequestToken(c.Request)
+	if tokenString == "" {
+		return false
+	}
+
+	claims, err := auth.ValidateJWT(tokenString, auth.JWTSecret())
+	if err != nil || claims.Role != "admin" || !authorization.HasScopedPermission(claims.Role, claims.Scopes, "admin.security") {
+		return false
+	}
+
+	return !session.GlobalSessionManager.IsTokenRevoked(tokenString, claims)
+}
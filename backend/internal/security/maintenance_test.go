@@ -0,0 +1,125 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newMaintenanceRouter enables maintenance mode and returns a router behind MaintenanceMiddleware
+func newMaintenanceRouter(t *testing.T) (*gin.Engine, *gorm.DB) {
+	gin.SetMode(gin.TestMode)
+
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+	if err := database.AutoMigrate(&models.SystemSetting{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	origManager := GlobalMaintenanceManager
+	t.Cleanup(func() { GlobalMaintenanceManager = origManager })
+	GlobalMaintenanceManager = NewMaintenanceManager()
+
+	state := DefaultMaintenanceState()
+	state.Enabled = true
+	state.RetryAfterSeconds = 120
+	if err := GlobalMaintenanceManager.UpdateState(database, state); err != nil {
+		t.Fatalf("Failed to enable maintenance mode: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(MaintenanceMiddleware())
+	for _, path := range []string{"/health", "/api/files", "/admin/users"} {
+		r.GET(path, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+	return r, database
+}
+
+// getWithRole sends a GET request, authenticated with a token for the given role if any
+func getWithRole(t *testing.T, r *gin.Engine, path, role string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if role != "" {
+		token, _, err := auth.GenerateJWT(&models.User{ID: 1, Username: role, Role: role}, []byte("my_secret_key"))
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestMaintenanceMiddleware_RejectsNonAdmins(t *testing.T) {
+	r, _ := newMaintenanceRouter(t)
+
+	for _, role := range []string{"", "user"} {
+		w := getWithRole(t, r, "/api/files", role)
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503 for role %q, got %d", role, w.Code)
+		}
+		if w.Header().Get("Retry-After") != "120" {
+			t.Errorf("Expected Retry-After 120, got %q", w.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestMaintenanceMiddleware_AllowsAdminsAndHealthChecks(t *testing.T) {
+	r, _ := newMaintenanceRouter(t)
+
+	if w := getWithRole(t, r, "/health", ""); w.Code != http.StatusOK {
+		t.Errorf("Expected health check to pass, got %d", w.Code)
+	}
+	for _, path := range []string{"/api/files", "/admin/users"} {
+		if w := getWithRole(t, r, path, "admin"); w.Code != http.StatusOK {
+			t.Errorf("Expected admin to reach %s, got %d", path, w.Code)
+		}
+	}
+}
+
+func TestMaintenanceMiddleware_RejectsNarrowlyScopedAdminTokens(t *testing.T) {
+	r, _ := newMaintenanceRouter(t)
+	admin := &models.User{ID: 1, Username: "admin", Role: "admin"}
+
+	for scope, want := range map[string]int{
+		"profile.read":   http.StatusServiceUnavailable,
+		"admin.security": http.StatusOK,
+		"*":              http.StatusOK,
+	} {
+		token, _, err := auth.GenerateScopedJWT(admin, []string{scope}, time.Hour, []byte("my_secret_key"))
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/files", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("Expected status %d for an admin token scoped to %s, got %d", want, scope, w.Code)
+		}
+	}
+}
+
+func TestMaintenanceManager_SurvivesRestart(t *testing.T) {
+	_, database := newMaintenanceRouter(t)
+
+	restarted := NewMaintenanceManager()
+	if restarted.GetState().Enabled {
+		t.Fatal("Expected a new manager to start disabled")
+	}
+	if err := restarted.LoadState(database); err != nil {
+		t.Fatalf("Failed to load state: %v", err)
+	}
+	if state := restarted.GetState(); !state.Enabled || state.RetryAfterSeconds != 120 {
+		t.Errorf("Expected persisted maintenance state, got %+v", state)
+	}
+}
@@ -0,0 +1,81 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setupTestSecurityMetrics replaces the global security metrics for the duration of a test
+func setupTestSecurityMetrics(t *testing.T) *SecurityMetrics {
+	orig := GlobalSecurityMetrics
+	t.Cleanup(func() { GlobalSecurityMetrics = orig })
+	GlobalSecurityMetrics = NewSecurityMetrics()
+	return GlobalSecurityMetrics
+}
+
+func TestSecurityMetrics_CountEvents(t *testing.T) {
+	metrics := setupTestSecurityMetrics(t)
+	r := newRateLimitRouter(t, 1, 0)
+	r.Use(AuditLogMiddleware(), CSRFMiddleware())
+	r.POST("/submit", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/denied", func(c *gin.Context) { c.Status(http.StatusForbidden) })
+
+	serve := func(method, path, remoteAddr string, header map[string]string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remoteAddr
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Second request from the same client trips the rate limiter
+	serve(http.MethodGet, "/ping", "10.0.0.1:1234", nil)
+	if code := serve(http.MethodGet, "/ping", "10.0.0.1:1234", nil); code != http.StatusTooManyRequests {
+		t.Fatalf("Expected rate limited request, got %d", code)
+	}
+	if got := metrics.Snapshot().RateLimitHits; got != 1 {
+		t.Errorf("Expected 1 rate limit hit, got %d", got)
+	}
+
+	// Missing and invalid CSRF tokens are both violations, and both are blocked
+	serve(http.MethodPost, "/submit", "10.0.0.2:1234", nil)
+	serve(http.MethodPost, "/submit", "10.0.0.3:1234", map[string]string{"X-CSRF-Token": "bogus"})
+	if got := metrics.Snapshot().CSRFViolations; got != 2 {
+		t.Errorf("Expected 2 CSRF violations, got %d", got)
+	}
+	if got := metrics.Snapshot().BlockedRequests; got != 2 {
+		t.Errorf("Expected 2 blocked requests, got %d", got)
+	}
+
+	serve(http.MethodGet, "/denied", "10.0.0.4:1234", nil)
+	if got := metrics.Snapshot().BlockedRequests; got != 3 {
+		t.Errorf("Expected 3 blocked requests, got %d", got)
+	}
+}
+
+func TestSecurityMetrics_Reset(t *testing.T) {
+	metrics := NewSecurityMetrics()
+	metrics.IncRateLimitHits()
+	metrics.IncCSRFViolations()
+	metrics.IncBlockedRequests()
+	before := metrics.Snapshot()
+
+	previous := metrics.Reset()
+	if previous.RateLimitHits != 1 || previous.CSRFViolations != 1 || previous.BlockedRequests != 1 {
+		t.Errorf("Expected reset to return the ended window, got %+v", previous)
+	}
+
+	after := metrics.Snapshot()
+	if after.RateLimitHits != 0 || after.CSRFViolations != 0 || after.BlockedRequests != 0 {
+		t.Errorf("Expected counters to be zero after reset, got %+v", after)
+	}
+	if !after.WindowStart.After(before.WindowStart) {
+		t.Error("Expected reset to start a new window")
+	}
+}
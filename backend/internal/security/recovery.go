@@ -0,0 +1,92 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InternalErrorCode identifies the response to a request whose handler panicked
+const InternalErrorCode = "internal_error"
+
+// PanicReport describes a panic recovered while handling a request
+type PanicReport struct {
+	RequestID string
+	Method    string
+	Path      string
+	ClientIP  string
+	UserAgent string
+	Value     string // the panic value, formatted
+	Stack     []byte // only ever logged, never sent to the client
+}
+
+// RecoveryMiddleware recovers from panics in later handlers. The panic and its
+// stack are logged with the request ID, onPanic is called (e.g. to audit it),
+// and the client receives a coded 500 carrying the request ID and nothing about
+// the failure itself. Mount it after RequestIDMiddleware, in place of gin.Recovery.
+func RecoveryMiddleware(onPanic func(*PanicReport)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// Let net/http abort the connection as it intends to
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			report := &PanicReport{
+				RequestID: RequestID(c),
+				Method:    c.Request.Method,
+				Path:      c.Request.URL.Path,
+				ClientIP:  c.ClientIP(),
+				UserAgent: c.Request.UserAgent(),
+				Value:     fmt.Sprint(recovered),
+				Stack:     debug.Stack(),
+			}
+			log.Printf("[PANIC] request %s %s %s: %s\n%s", report.RequestID, report.Method, report.Path, report.Value, report.Stack)
+			if onPanic != nil {
+				onPanic(report)
+			}
+
+			// Nothing can be sent to a client that went away, and a response
+			// already under way can only be cut short
+			if isBrokenConnection(recovered) || c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":      "Internal server error",
+				"code":       InternalErrorCode,
+				"request_id": report.RequestID,
+			})
+		}()
+		c.Next()
+	}
+}
+
+// isBrokenConnection checks if a panic was caused by the client closing the connection
+func isBrokenConnection(recovered interface{}) bool {
+	err, ok := recovered.(error)
+	if !ok {
+		return false
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		return false
+	}
+	var syscallErr *os.SyscallError
+	if !errors.As(opErr, &syscallErr) {
+		return false
+	}
+	message := strings.ToLower(syscallErr.Error())
+	return strings.Contains(message, "broken pipe") || strings.Contains(message, "connection reset by peer")
+}
@@ -0,0 +1,59 @@
+package security
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecoveryMiddleware_ReturnsSafeCodedError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var reports []*PanicReport
+	r := gin.New()
+	r.Use(RequestIDMiddleware(), RecoveryMiddleware(func(p *PanicReport) {
+		reports = append(reports, p)
+	}))
+	r.GET("/boom", func(c *gin.Context) {
+		var user map[string]string
+		user["secret"] = "password" // panics: assignment to entry in nil map
+	})
+	r.GET("/ok", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected status 500, got %d", w.Code)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Expected a JSON error, got %q", w.Body.String())
+	}
+	if body["code"] != InternalErrorCode || body["request_id"] != "req-123" {
+		t.Errorf("Expected a coded error carrying the request ID, got %v", body)
+	}
+	if strings.Contains(w.Body.String(), "nil map") || strings.Contains(w.Body.String(), "goroutine") {
+		t.Errorf("Expected nothing about the panic in the response, got %q", w.Body.String())
+	}
+
+	if len(reports) != 1 {
+		t.Fatalf("Expected the panic to be reported once, got %d", len(reports))
+	}
+	if report := reports[0]; report.RequestID != "req-123" || report.Path != "/boom" || !strings.Contains(report.Value, "nil map") || len(report.Stack) == 0 {
+		t.Errorf("Expected a full panic report, got %+v", report)
+	}
+
+	// The server keeps serving
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ok", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected later requests to succeed, got %d", w.Code)
+	}
+}
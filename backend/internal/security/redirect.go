@@ -0,0 +1,111 @@
+package security
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var ErrRedirectNotAllowed = errors.New("redirect target is not allowed")
+
+// redirectPattern is a compiled AllowedRedirectURIs entry
+type redirectPattern struct {
+	origin originPattern
+	path   string // targets must be at or below it, "" = anywhere on the origin
+}
+
+// RedirectPolicy is the redirect allowlist of the security configuration
+// compiled for matching redirect targets
+type RedirectPolicy struct {
+	patterns []redirectPattern
+}
+
+// compileRedirectPattern parses an allowed redirect URI: an origin as accepted
+// for CORS origin policies, optionally followed by a path
+func compileRedirectPattern(uri string) (redirectPattern, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.RawQuery != "" || u.Fragment != "" {
+		return redirectPattern{}, fmt.Errorf("invalid redirect URI %q, use scheme://host[:port][/path]", uri)
+	}
+	origin, err := compileOriginPattern(u.Scheme + "://" + u.Host)
+	if err != nil || u.User != nil {
+		return redirectPattern{}, fmt.Errorf("invalid redirect URI %q, use scheme://host[:port][/path]", uri)
+	}
+	return redirectPattern{origin: origin, path: strings.TrimSuffix(u.Path, "/")}, nil
+}
+
+// matches reports whether a parsed absolute redirect target matches the pattern
+func (rp redirectPattern) matches(target *url.URL) bool {
+	if target.User != nil || !rp.origin.matches(target.Scheme+"://"+target.Host) {
+		return false
+	}
+	return rp.path == "" || target.Path == rp.path || strings.HasPrefix(target.Path, rp.path+"/")
+}
+
+// compileRedirectPolicy compiles the redirect part of a configuration
+func compileRedirectPolicy(sc *SecurityConfig) (*RedirectPolicy, error) {
+	policy := &RedirectPolicy{}
+	for _, uri := range sc.AllowedRedirectURIs {
+		pattern, err := compileRedirectPattern(uri)
+		if err != nil {
+			return nil, err
+		}
+		policy.patterns = append(policy.patterns, pattern)
+	}
+	return policy, nil
+}
+
+// Validate checks a redirect target and returns it if allowed: a path on this
+// server, or an absolute URL matching an allowed redirect URI. Anything else,
+// including protocol-relative URLs such as //evil.example.com, is rejected so
+// the redirect can't send users to another site.
+func (rp *RedirectPolicy) Validate(target string) (string, error) {
+	// Browsers treat backslashes as slashes and ignore tabs and newlines in URLs
+	if target == "" || strings.ContainsAny(target, "\\\t\r\n") {
+		return "", ErrRedirectNotAllowed
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", ErrRedirectNotAllowed
+	}
+
+	if u.Scheme == "" && u.Host == "" {
+		if !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") {
+			return "", ErrRedirectNotAllowed
+		}
+		return target, nil
+	}
+	for _, pattern := range rp.patterns {
+		if pattern.matches(u) {
+			return target, nil
+		}
+	}
+	return "", ErrRedirectNotAllowed
+}
+
+// validateRedirects validates the redirect part of the security configuration
+func (sc *SecurityConfig) validateRedirects() error {
+	_, err := compileRedirectPolicy(sc)
+	return err
+}
+
+// ValidateRedirect checks a redirect target against the live configuration,
+// see RedirectPolicy.Validate
+func ValidateRedirect(target string) (string, error) {
+	return GlobalSecurityConfig.GetRedirectPolicy().Validate(target)
+}
+
+// Redirect redirects to target if it is allowed, else to fallback, a path on
+// this server. Flows redirecting to a client-supplied URL, such as after a login
+// or a verification, must redirect through it.
+func Redirect(c *gin.Context, target, fallback string) {
+	if allowed, err := ValidateRedirect(target); err == nil {
+		c.Redirect(http.StatusFound, allowed)
+		return
+	}
+	c.Redirect(http.StatusFound, fallback)
+}
@@ -0,0 +1,93 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRedirectPolicy_Validate(t *testing.T) {
+	policy, err := compileRedirectPolicy(&SecurityConfig{
+		AllowedRedirectURIs: []string{"https://app.example.com/auth", "https://*.example.org"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to compile redirect policy: %v", err)
+	}
+
+	for target, wantAllowed := range map[string]bool{
+		"/dashboard?tab=files":                    true,
+		"https://app.example.com/auth":            true,
+		"https://app.example.com/auth/callback":   true,
+		"https://team.example.org/welcome":        true,
+		"https://app.example.com/authx":           false, // not below /auth
+		"https://app.example.com/other":           false,
+		"http://app.example.com/auth":             false, // scheme differs
+		"https://app.example.com:8443/auth":       false,
+		"https://app.example.com.evil.com/auth":   false,
+		"https://app.example.com@evil.com/auth":   false,
+		"https://user@app.example.com/auth":       false,
+		"https://example.org/":                    false, // only its subdomains
+		"https://evil.com/":                       false,
+		"//evil.com/":                             false,
+		"/\\evil.com/":                            false,
+		"\\\\evil.com":                            false,
+		"https:evil.com":                          false,
+		"javascript:alert(1)":                     false,
+		"dashboard":                               false,
+		"":                                        false,
+		"https://app.example.com/auth\t@evil.com": false,
+	} {
+		allowed, err := policy.Validate(target)
+		if gotAllowed := err == nil; gotAllowed != wantAllowed {
+			t.Errorf("Validate(%q): expected allowed=%v, got %v", target, wantAllowed, err)
+		}
+		if err == nil && allowed != target {
+			t.Errorf("Validate(%q): expected the target back, got %q", target, allowed)
+		}
+		if err != nil && err != ErrRedirectNotAllowed {
+			t.Errorf("Validate(%q): unexpected error %v", target, err)
+		}
+	}
+}
+
+func TestSecurityConfig_RejectsInvalidRedirectURIs(t *testing.T) {
+	for _, uri := range []string{"app.example.com", "ftp://app.example.com", "https://*evil.com", "https://app.example.com/?next=1"} {
+		config := DefaultSecurityConfig
+		config.AllowedRedirectURIs = []string{uri}
+		if err := config.Validate(); err == nil {
+			t.Errorf("Expected redirect URI %q to be rejected", uri)
+		}
+	}
+}
+
+func TestRedirect_FallsBackForDisallowedTargets(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	origConfig := GlobalSecurityConfig
+	t.Cleanup(func() { GlobalSecurityConfig = origConfig })
+	GlobalSecurityConfig = NewSecurityConfigManager(DefaultSecurityConfig)
+	if _, err := GlobalSecurityConfig.UpdateConfig(func(config *SecurityConfig) {
+		config.AllowedRedirectURIs = []string{"https://app.example.com"}
+	}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	r := gin.New()
+	r.GET("/verified", func(c *gin.Context) {
+		Redirect(c, c.Query("next"), "/")
+	})
+
+	for next, want := range map[string]string{
+		"https://app.example.com/home": "https://app.example.com/home",
+		"https://evil.example.com/":    "/",
+		"//evil.example.com":           "/",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/verified?next="+url.QueryEscape(next), nil))
+		if w.Code != http.StatusFound || w.Header().Get("Location") != want {
+			t.Errorf("Redirect to %q: expected 302 to %q, got %d to %q", next, want, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
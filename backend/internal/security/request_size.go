@@ -0,0 +1,162 @@
+package security
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"reflect"
+	"runtime"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MultipartOverhead is the room left for form fields and part headers when an
+// upload route's limit is derived from its maximum file size
+const MultipartOverhead = 1 * 1024 * 1024 // 1MB
+
+// limitedBody enforces the request size limit on the bytes actually read, since
+// Content-Length can be understated or omitted (chunked transfer). The limit may be
+// changed until the first read, which lets route middleware override the global default.
+type limitedBody struct {
+	io.ReadCloser
+	writer   http.ResponseWriter
+	limit    int64
+	reader   io.ReadCloser // http.MaxBytesReader, created on first read
+	exceeded bool
+}
+
+// Read reads from the body, failing with *http.MaxBytesError past the limit
+func (lb *limitedBody) Read(p []byte) (int, error) {
+	if lb.reader == nil {
+		lb.reader = http.MaxBytesReader(lb.writer, lb.ReadCloser, lb.limit)
+	}
+
+	n, err := lb.reader.Read(p)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		lb.exceeded = true
+	}
+	return n, err
+}
+
+// Close closes the body
+func (lb *limitedBody) Close() error {
+	if lb.reader != nil {
+		return lb.reader.Close()
+	}
+	return lb.ReadCloser.Close()
+}
+
+// sizeLimitWriter reports 413 instead of the handler's error status when the
+// handler failed because the request body exceeded its limit
+type sizeLimitWriter struct {
+	gin.ResponseWriter
+	body *limitedBody
+}
+
+// WriteHeader writes the status code, replacing errors caused by an oversized body
+func (w *sizeLimitWriter) WriteHeader(code int) {
+	if w.body.exceeded && code >= http.StatusBadRequest {
+		code = http.StatusRequestEntityTooLarge
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// maxBodySizeHandlerName identifies MaxBodySize in a route's handler chain
+var maxBodySizeHandlerName = runtime.FuncForPC(reflect.ValueOf(MaxBodySize(0)).Pointer()).Name()
+
+// RequestSizeMiddleware limits request size to maxSize bytes, unless the route
+// sets its own limit with MaxBodySize. Both the declared Content-Length and the
+// bytes actually read are enforced; overflowing either results in 413.
+func RequestSizeMiddleware(maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limitRequestSize(c, maxSize)
+	}
+}
+
+// ConfiguredRequestSizeMiddleware works like RequestSizeMiddleware, using the
+// MaxRequestSize of GlobalSecurityConfig at the time of each request
+func ConfiguredRequestSizeMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limitRequestSize(c, GlobalSecurityConfig.GetConfig().MaxRequestSize)
+	}
+}
+
+// limitRequestSize enforces maxSize on the request and runs the remaining handlers
+func limitRequestSize(c *gin.Context, maxSize int64) {
+	// Leave the Content-Length check to the route's own limit, if any
+	if !hasRouteSizeLimit(c) && !checkContentLength(c, maxSize) {
+		return
+	}
+
+	body := limitBody(c, maxSize)
+	c.Next()
+	rejectIfExceeded(c, body)
+}
+
+// MaxBodySize overrides the request size limit for a single route, e.g. a larger
+// limit for uploads or a smaller one for JSON endpoints. Mount it before the handler.
+func MaxBodySize(maxSize int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !checkContentLength(c, maxSize) {
+			return
+		}
+
+		// Adjust the body installed by RequestSizeMiddleware, or install one
+		if body, ok := c.Request.Body.(*limitedBody); ok {
+			body.limit = maxSize
+			return
+		}
+
+		body := limitBody(c, maxSize)
+		c.Next()
+		rejectIfExceeded(c, body)
+	}
+}
+
+// hasRouteSizeLimit checks if the matched route mounts MaxBodySize
+func hasRouteSizeLimit(c *gin.Context) bool {
+	for _, name := range c.HandlerNames() {
+		if name == maxBodySizeHandlerName {
+			return true
+		}
+	}
+	return false
+}
+
+// checkContentLength rejects requests declaring a body larger than maxSize
+func checkContentLength(c *gin.Context, maxSize int64) bool {
+	if c.Request.ContentLength > maxSize {
+		rejectTooLarge(c, maxSize)
+		return false
+	}
+	return true
+}
+
+// limitBody wraps the request body so reads fail past maxSize bytes
+func limitBody(c *gin.Context, maxSize int64) *limitedBody {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return nil
+	}
+
+	body := &limitedBody{ReadCloser: c.Request.Body, writer: c.Writer, limit: maxSize}
+	c.Request.Body = body
+	c.Writer = &sizeLimitWriter{ResponseWriter: c.Writer, body: body}
+	return body
+}
+
+// rejectIfExceeded responds with 413 if the handler hit the body limit without responding
+func rejectIfExceeded(c *gin.Context, body *limitedBody) {
+	if body != nil && body.exceeded && !c.Writer.Written() {
+		rejectTooLarge(c, body.limit)
+	}
+}
+
+// rejectTooLarge aborts the request with 413 Request Entity Too Large
+func rejectTooLarge(c *gin.Context, maxSize int64) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":    "Request too large",
+		"max_size": maxSize,
+	})
+	c.Abort()
+}
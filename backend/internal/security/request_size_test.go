@@ -0,0 +1,166 @@
+package security
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newSizeLimitedRouter returns a router with a 10 byte global limit and a 100 byte upload route
+func newSizeLimitedRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	readAll := func(c *gin.Context) {
+		data, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"read": len(data)})
+	}
+
+	r := gin.New()
+	r.Use(RequestSizeMiddleware(10))
+	r.POST("/json", readAll)
+	r.POST("/upload", MaxBodySize(100), readAll)
+	return r
+}
+
+// postBody sends a body, optionally hiding its length so only the body reader can enforce the limit
+func postBody(r *gin.Engine, path string, size int, hideLength bool) *httptest.ResponseRecorder {
+	var body io.Reader = bytes.NewBufferString(strings.Repeat("a", size))
+	if hideLength {
+		body = io.MultiReader(body) // not a known type, so ContentLength stays unset
+	}
+
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	if hideLength {
+		req.ContentLength = -1
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestRequestSize_ContentLengthEnforced(t *testing.T) {
+	r := newSizeLimitedRouter()
+
+	tests := []struct {
+		path     string
+		size     int
+		expected int
+	}{
+		{"/json", 10, http.StatusOK},
+		{"/json", 11, http.StatusRequestEntityTooLarge},
+		{"/upload", 50, http.StatusOK}, // route limit overrides the global one
+		{"/upload", 101, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		if w := postBody(r, tt.path, tt.size, false); w.Code != tt.expected {
+			t.Errorf("%s with %d bytes: expected status %d, got %d", tt.path, tt.size, tt.expected, w.Code)
+		}
+	}
+}
+
+func TestRequestSize_BodyReaderEnforced(t *testing.T) {
+	r := newSizeLimitedRouter()
+
+	if w := postBody(r, "/json", 10, true); w.Code != http.StatusOK {
+		t.Errorf("Expected body at the limit to be accepted, got %d", w.Code)
+	}
+
+	// The handler reports a read error, which becomes 413
+	w := postBody(r, "/json", 11, true)
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "request body too large") {
+		t.Errorf("Expected oversized body to be rejected with 413, got %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := postBody(r, "/upload", 100, true); w.Code != http.StatusOK {
+		t.Errorf("Expected upload at the route limit to be accepted, got %d", w.Code)
+	}
+	if w := postBody(r, "/upload", 101, true); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected oversized upload to be rejected with 413, got %d", w.Code)
+	}
+}
+
+func TestRequestSize_LyingContentLength(t *testing.T) {
+	r := newSizeLimitedRouter()
+
+	// Declares 5 bytes but sends 50
+	req := httptest.NewRequest(http.MethodPost, "/json", bytes.NewBufferString(strings.Repeat("a", 50)))
+	req.ContentLength = 5
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code == http.StatusOK {
+		t.Errorf("Expected lying Content-Length to be caught by the body reader, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRequestSize_TooLargeWhenHandlerDoesNotRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(RequestSizeMiddleware(10))
+	r.POST("/ignore", func(c *gin.Context) {
+		io.ReadAll(c.Request.Body)
+	})
+
+	if w := postBody(r, "/ignore", 20, true); w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected status 413, got %d", w.Code)
+	}
+}
+
+func TestRequestSize_ChunkedBodyWithoutContentLength(t *testing.T) {
+	r := newSizeLimitedRouter()
+	r.POST("/encoding", func(c *gin.Context) {
+		c.String(http.StatusOK, "%d %v", c.Request.ContentLength, c.Request.TransferEncoding)
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// A pipe has no known length, so the client sends it chunked without Content-Length
+	send := func(size int) *http.Response {
+		pr, pw := io.Pipe()
+		go func() {
+			pw.Write([]byte(strings.Repeat("a", size)))
+			pw.Close()
+		}()
+
+		req, _ := http.NewRequest(http.MethodPost, server.URL+"/json", pr)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Confirm the server sees no Content-Length
+	pr, pw := io.Pipe()
+	go func() {
+		pw.Write([]byte("a"))
+		pw.Close()
+	}()
+	resp, err := http.Post(server.URL+"/encoding", "text/plain", pr)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	encoding, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(encoding) != "-1 [chunked]" {
+		t.Fatalf("Expected chunked request without Content-Length, got %q", encoding)
+	}
+
+	if resp := send(10); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected chunked body at the limit to be accepted, got %d", resp.StatusCode)
+	}
+	if resp := send(1000); resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected chunked oversized body to be rejected with 413, got %d", resp.StatusCode)
+	}
+}
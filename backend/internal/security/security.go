@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -23,13 +24,29 @@ type RateLimiter struct {
 // SecurityConfig represents security configuration
 type SecurityConfig struct {
 	RateLimitPerMinute int
+	RateLimitWarningThreshold int // warn when fewer requests remain, 0 disables
 	MaxRequestSize     int64
+	RequestTimeout     time.Duration // per request deadline, 0 disables; routes override it with RequestTimeout
+	MaxConcurrentUploads int         // uploads processed at once, 0 = unlimited
+	UploadRetryAfter   time.Duration // Retry-After sent while uploads are at capacity
+	MultipartMemory    int64         // bytes of a multipart upload held in memory, the rest spills to temp files
 	EnableCORS         bool
 	EnableCSRF         bool
 	EnableXSSProtection bool
 	EnableHSTS         bool
-	AllowedOrigins     []string
+	AllowedOrigins     []string // "*" allows any origin, but never with credentials
+	AllowedMethods     []string
+	AllowedHeaders     []string
+	AllowCredentials   bool
+	CORSMaxAge         time.Duration // how long browsers may cache preflights, 0 omits the header
+	CORSOriginPolicies []CORSOriginPolicy // checked before AllowedOrigins, first match wins
+	CORSPublicPaths    []string // readable from any origin without credentials; "/prefix/*" matches below prefix
+	AllowedRedirectURIs []string // absolute redirect targets allowed at or below these, see RedirectPolicy
 	TrustedProxies     []string
+	AuthMode           string // header, cookie or both, see AuthModeHeader
+	SessionCookieName  string
+	SessionCookieSecure bool
+	SessionCookieSameSite string // strict, lax or none
 }
 
 // SecurityHeaders represents security headers
@@ -50,16 +67,32 @@ type CSRFProtection struct {
 }
 
 var (
-	// Default security configuration
+	// Default security configuration, the starting point of GlobalSecurityConfig.
+	// Read the live configuration through GlobalSecurityConfig.GetConfig.
 	DefaultSecurityConfig = SecurityConfig{
 		RateLimitPerMinute: 120,
-		MaxRequestSize:     10 * 1024 * 1024, // 10MB
+		RateLimitWarningThreshold: 10,
+		MaxRequestSize:     1 * 1024 * 1024, // 1MB, upload routes raise it with MaxBodySize
+		RequestTimeout:     30 * time.Second,
+		MaxConcurrentUploads: 10,
+		UploadRetryAfter:   5 * time.Second,
+		MultipartMemory:    1 * 1024 * 1024, // 1MB
 		EnableCORS:         true,
 		EnableCSRF:         true,
 		EnableXSSProtection: true,
 		EnableHSTS:         true,
 		AllowedOrigins:     []string{"http://localhost:3000", "http://localhost:8080"},
+		AllowedMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-CSRF-Token", HeaderIDFormat},
+		AllowCredentials:   true,
+		CORSMaxAge:         24 * time.Hour,
+		CORSPublicPaths:    []string{"/", "/api", "/health", "/uploads/avatars/*"},
+		AllowedRedirectURIs: []string{"http://localhost:3000", "http://localhost:8080"},
 		TrustedProxies:     []string{"127.0.0.1", "::1"},
+		AuthMode:           AuthModeHeader,
+		SessionCookieName:  DefaultSessionCookieName,
+		SessionCookieSecure: true,
+		SessionCookieSameSite: "strict",
 	}
 
 	// Default security headers
@@ -74,7 +107,6 @@ var (
 	}
 
 	// Global instances
-	GlobalRateLimiter = NewRateLimiter(DefaultSecurityConfig.RateLimitPerMinute, time.Minute)
 	GlobalCSRFProtection = NewCSRFProtection()
 )
 
@@ -99,7 +131,18 @@ func RateLimitMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		clientIP := c.ClientIP()
 		
-		if !GlobalRateLimiter.Allow(clientIP) {
+		allowed, remaining := GlobalSecurityConfig.GetRateLimiter().Check(clientIP)
+		if !allowed {
+			// Exemptions are only evaluated once the limit is hit, and only
+			// for callers presenting valid credentials
+			if exemption := GlobalExemptionManager.Check(c); exemption != nil {
+				c.Set("rate_limit_exempt", exemption.Reason)
+				GlobalExemptionManager.notify(c, exemption)
+				c.Next()
+				return
+			}
+
+			GlobalSecurityMetrics.IncRateLimitHits()
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": "Rate limit exceeded",
 				"retry_after": 60,
@@ -108,12 +151,25 @@ func RateLimitMiddleware() gin.HandlerFunc {
 			return
 		}
 		
+		// Let well-behaved clients slow down before they get blocked
+		if threshold := GlobalSecurityConfig.GetConfig().RateLimitWarningThreshold; remaining < threshold {
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			c.Header("X-RateLimit-Warning", fmt.Sprintf("Approaching rate limit: %d requests remaining in the current window", remaining))
+		}
+		
 		c.Next()
 	}
 }
 
 // Allow checks if a request is allowed based on rate limiting
 func (rl *RateLimiter) Allow(clientIP string) bool {
+	allowed, _ := rl.Check(clientIP)
+	return allowed
+}
+
+// Check records a request if it is allowed and returns how many requests the
+// client has left in the current window
+func (rl *RateLimiter) Check(clientIP string) (bool, int) {
 	rl.mutex.Lock()
 	defer rl.mutex.Unlock()
 
@@ -133,12 +189,12 @@ func (rl *RateLimiter) Allow(clientIP string) bool {
 
 	// Check if limit exceeded
 	if len(rl.requests[clientIP]) >= rl.limit {
-		return false
+		return false, 0
 	}
 
 	// Add current request
 	rl.requests[clientIP] = append(rl.requests[clientIP], now)
-	return true
+	return true, rl.limit - len(rl.requests[clientIP])
 }
 
 // SecurityHeadersMiddleware adds security headers
@@ -165,25 +221,35 @@ func SecurityHeadersMiddleware() gin.HandlerFunc {
 // CORSMiddleware implements CORS
 func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		config := GlobalSecurityConfig.GetConfig()
 		origin := c.Request.Header.Get("Origin")
+		policy := GlobalSecurityConfig.GetCORSPolicy()
+		grant := policy.Grant(origin)
+		methods := config.AllowedMethods
 		
-		// Check if origin is allowed
-		allowed := false
-		for _, allowedOrigin := range DefaultSecurityConfig.AllowedOrigins {
-			if origin == allowedOrigin {
-				allowed = true
-				break
-			}
+		// Public paths are readable from any origin, but never with credentials
+		// or unsafe methods, so the strict policy still guards everything else
+		if policy.IsPublicPath(c.Request.URL.Path) {
+			grant = CORSGrant{AllowOrigin: "*", MaxAge: policy.maxAge}
+			methods = CORSPublicMethods
 		}
 		
-		if allowed {
-			c.Header("Access-Control-Allow-Origin", origin)
+		// A listed origin is reflected, so the response varies with it. Credentials
+		// are only ever allowed together with a reflected origin, Validate rejects
+		// them alongside a wildcard.
+		c.Header("Vary", "Origin")
+		if grant.AllowOrigin != "" {
+			c.Header("Access-Control-Allow-Origin", grant.AllowOrigin)
+			if grant.AllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
 		}
 		
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Accept, Authorization, X-CSRF-Token")
-		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Max-Age", "86400")
+		c.Header("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+		c.Header("Access-Control-Allow-Headers", strings.Join(config.AllowedHeaders, ", "))
+		if grant.MaxAge > 0 {
+			c.Header("Access-Control-Max-Age", strconv.Itoa(int(grant.MaxAge.Seconds())))
+		}
 		
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(http.StatusNoContent)
@@ -203,13 +269,25 @@ func CSRFMiddleware() gin.HandlerFunc {
 			return
 		}
 		
-		// Get CSRF token from header or form
+		// Browsers attach the session cookie to cross-site requests too, so
+		// cookie-authenticated requests need a CSRF token even when it is disabled
+		if !GlobalSecurityConfig.GetConfig().EnableCSRF {
+			if _, fromCookie := RequestToken(c.Request); !fromCookie {
+				c.Next()
+				return
+			}
+		}
+		
+		// Get CSRF token from header or form. Multipart bodies are left to the
+		// upload handlers, which parse them within the configured memory and temp
+		// directory, so uploads must send the header.
 		token := c.GetHeader("X-CSRF-Token")
-		if token == "" {
+		if token == "" && !strings.HasPrefix(c.ContentType(), "multipart/") {
 			token = c.PostForm("csrf_token")
 		}
 		
 		if token == "" {
+			GlobalSecurityMetrics.IncCSRFViolations()
 			c.JSON(http.StatusForbidden, gin.H{
 				"error": "CSRF token missing",
 			})
@@ -219,6 +297,7 @@ func CSRFMiddleware() gin.HandlerFunc {
 		
 		// Validate CSRF token
 		if !GlobalCSRFProtection.ValidateToken(c.ClientIP(), token) {
+			GlobalSecurityMetrics.IncCSRFViolations()
 			c.JSON(http.StatusForbidden, gin.H{
 				"error": "Invalid CSRF token",
 			})
@@ -269,22 +348,6 @@ func (csrf *CSRFProtection) ValidateToken(clientIP, token string) bool {
 	return exists && storedToken == token
 }
 
-// RequestSizeMiddleware limits request size
-func RequestSizeMiddleware(maxSize int64) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		if c.Request.ContentLength > maxSize {
-			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
-				"error": "Request too large",
-				"max_size": maxSize,
-			})
-			c.Abort()
-			return
-		}
-		
-		c.Next()
-	}
-}
-
 // IPWhitelistMiddleware implements IP whitelisting
 func IPWhitelistMiddleware(allowedIPs []string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -340,9 +403,13 @@ func AuditLogMiddleware() gin.HandlerFunc {
 		path := c.Request.URL.Path
 		status := c.Writer.Status()
 		
-		// Log suspicious activities
+		// Log suspicious activities; every one is counted, but only a sample of
+		// each IP's failures is logged, see GlobalAuditSampler
 		if status == http.StatusForbidden || status == http.StatusUnauthorized || status == http.StatusTooManyRequests {
-			logSecurityEvent(clientIP, userAgent, method, path, status, duration)
+			GlobalSecurityMetrics.IncBlockedRequests()
+			if GlobalAuditSampler.Sample(clientIP, path) {
+				logSecurityEvent(clientIP, userAgent, method, path, status, duration)
+			}
 		}
 	}
 }
@@ -376,26 +443,62 @@ func logSecurityEvent(clientIP, userAgent, method, path string, status int, dura
 	fmt.Printf("[SECURITY] %s - %s %s %s %d %v\n", clientIP, method, path, userAgent, status, duration)
 }
 
+// GetSecuritySummary returns which protections are on, without the limits and
+// settings GetSecurityStatus reports, for unauthenticated callers
+func GetSecuritySummary() map[string]interface{} {
+	config := GlobalSecurityConfig.GetConfig()
+	return map[string]interface{}{
+		"rate_limiting": true,
+		"cors": config.EnableCORS,
+		"security_headers": config.EnableXSSProtection || config.EnableHSTS,
+	}
+}
+
 // GetSecurityStatus returns current security status
 func GetSecurityStatus() map[string]interface{} {
+	config := GlobalSecurityConfig.GetConfig()
 	return map[string]interface{}{
 		"rate_limiting": map[string]interface{}{
 			"enabled": true,
-			"limit_per_minute": DefaultSecurityConfig.RateLimitPerMinute,
+			"limit_per_minute": config.RateLimitPerMinute,
+			"warning_threshold": config.RateLimitWarningThreshold,
 		},
 		"cors": map[string]interface{}{
-			"enabled": DefaultSecurityConfig.EnableCORS,
-			"allowed_origins": DefaultSecurityConfig.AllowedOrigins,
+			"enabled": config.EnableCORS,
+			"allowed_origins": config.AllowedOrigins,
+			"allowed_methods": config.AllowedMethods,
+			"allowed_headers": config.AllowedHeaders,
+			"allow_credentials": config.AllowCredentials,
+			"max_age_seconds": int(config.CORSMaxAge.Seconds()),
+			"origin_policies": config.CORSOriginPolicies,
+			"public_paths": config.CORSPublicPaths,
 		},
 		"csrf": map[string]interface{}{
-			"enabled": DefaultSecurityConfig.EnableCSRF,
+			"enabled": config.EnableCSRF,
+		},
+		"audit_sampling": map[string]interface{}{
+			"config": GlobalAuditSampler.GetConfig(),
+			"stats": GlobalAuditSampler.Stats(),
+		},
+		"authentication": map[string]interface{}{
+			"mode": config.AuthMode,
+			"session_cookie": config.SessionCookieName,
+			"cookie_secure": config.SessionCookieSecure,
+			"cookie_same_site": config.SessionCookieSameSite,
 		},
 		"headers": map[string]interface{}{
-			"xss_protection": DefaultSecurityConfig.EnableXSSProtection,
-			"hsts": DefaultSecurityConfig.EnableHSTS,
+			"xss_protection": config.EnableXSSProtection,
+			"hsts": config.EnableHSTS,
 		},
 		"request_limits": map[string]interface{}{
-			"max_size_mb": DefaultSecurityConfig.MaxRequestSize / (1024 * 1024),
+			"max_size_mb": config.MaxRequestSize / (1024 * 1024),
+			"timeout": config.RequestTimeout.String(),
+		},
+		"uploads": map[string]interface{}{
+			"max_concurrent": config.MaxConcurrentUploads,
+			"in_flight": GlobalSecurityConfig.GetUploadLimiter().InFlight(),
+			"retry_after_seconds": int(config.UploadRetryAfter.Seconds()),
+			"multipart_memory": config.MultipartMemory,
 		},
 	}
 }
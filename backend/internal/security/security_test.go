@@ -0,0 +1,100 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRateLimitRouter returns a router behind RateLimitMiddleware with the given limit and warning threshold
+func newRateLimitRouter(t *testing.T, limit, threshold int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	origConfig := GlobalSecurityConfig
+	t.Cleanup(func() { GlobalSecurityConfig = origConfig })
+	GlobalSecurityConfig = NewSecurityConfigManager(DefaultSecurityConfig)
+	if _, err := GlobalSecurityConfig.UpdateConfig(func(config *SecurityConfig) {
+		config.RateLimitPerMinute = limit
+		config.RateLimitWarningThreshold = threshold
+	}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(RateLimitMiddleware())
+	r.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestRateLimitMiddleware_WarnsNearLimit(t *testing.T) {
+	r := newRateLimitRouter(t, 5, 2)
+
+	// Requests 1-3 leave 4, 3 and 2 remaining; 4 and 5 leave 1 and 0
+	for i := 1; i <= 6; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		warning := w.Header().Get("X-RateLimit-Warning")
+		switch {
+		case i <= 3:
+			if w.Code != http.StatusOK || warning != "" {
+				t.Errorf("Request %d: expected 200 without warning, got %d and %q", i, w.Code, warning)
+			}
+		case i <= 5:
+			if w.Code != http.StatusOK || warning == "" {
+				t.Errorf("Request %d: expected 200 with a warning, got %d and %q", i, w.Code, warning)
+			}
+			if remaining := w.Header().Get("X-RateLimit-Remaining"); remaining != strconv.Itoa(5-i) {
+				t.Errorf("Request %d: expected %d remaining, got %q", i, 5-i, remaining)
+			}
+		default:
+			if w.Code != http.StatusTooManyRequests {
+				t.Errorf("Request %d: expected 429, got %d", i, w.Code)
+			}
+		}
+	}
+}
+
+func TestRateLimitMiddleware_WarningDisabled(t *testing.T) {
+	r := newRateLimitRouter(t, 2, 0)
+
+	for i := 1; i <= 2; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if warning := w.Header().Get("X-RateLimit-Warning"); warning != "" {
+			t.Errorf("Request %d: expected no warning with threshold 0, got %q", i, warning)
+		}
+	}
+}
+
+func TestSecurityConfig_RejectsNegativeWarningThreshold(t *testing.T) {
+	config := DefaultSecurityConfig
+	config.RateLimitWarningThreshold = -1
+	if err := config.Validate(); err == nil {
+		t.Error("Expected a negative warning threshold to be rejected")
+	}
+}
+
+func TestGetSecuritySummary_OmitsDetails(t *testing.T) {
+	summary := GetSecuritySummary()
+	for _, key := range []string{"rate_limiting", "cors", "security_headers"} {
+		if _, ok := summary[key].(bool); !ok {
+			t.Errorf("Expected %q to be reported as on or off, got %v", key, summary[key])
+		}
+	}
+
+	details := GetSecurityStatus()
+	for _, key := range []string{"request_limits", "uploads", "authentication", "csrf"} {
+		if _, ok := details[key]; !ok {
+			t.Errorf("Expected the detailed status to report %q", key)
+		}
+		if _, ok := summary[key]; ok {
+			t.Errorf("Expected the summary not to expose %q", key)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package security
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Authentication modes, deciding how a login hands the session token to the client
+const (
+	AuthModeHeader = "header" // returned in the body, sent back as a bearer token
+	AuthModeCookie = "cookie" // set as an HttpOnly cookie only, never exposed to scripts
+	AuthModeBoth   = "both"   // both of the above
+)
+
+// DefaultSessionCookieName is the name of the session cookie
+const DefaultSessionCookieName = "session_token"
+
+// validateAuthMode validates the authentication mode and session cookie settings
+func (sc *SecurityConfig) validateAuthMode() error {
+	switch sc.AuthMode {
+	case AuthModeHeader, AuthModeCookie, AuthModeBoth:
+	default:
+		return errors.New("auth mode must be header, cookie or both")
+	}
+	if sc.SessionCookieName == "" || strings.ContainsAny(sc.SessionCookieName, " \t;,=\"") {
+		return errors.New("session cookie name must be a non-empty token")
+	}
+	switch sc.SessionCookieSameSite {
+	case "strict", "lax":
+	case "none":
+		// Browsers drop SameSite=None cookies that are not Secure
+		if !sc.SessionCookieSecure {
+			return errors.New("session cookie with SameSite none must be secure")
+		}
+	default:
+		return errors.New("session cookie SameSite must be strict, lax or none")
+	}
+	return nil
+}
+
+// cookiesEnabled reports whether sessions may be carried by the session cookie
+func (sc *SecurityConfig) cookiesEnabled() bool {
+	return sc.AuthMode == AuthModeCookie || sc.AuthMode == AuthModeBoth
+}
+
+// sameSite converts the configured SameSite value
+func (sc *SecurityConfig) sameSite() http.SameSite {
+	switch sc.SessionCookieSameSite {
+	case "none":
+		return http.SameSiteNoneMode
+	case "lax":
+		return http.SameSiteLaxMode
+	default:
+		return http.SameSiteStrictMode
+	}
+}
+
+// SessionCookieToken returns the token carried by the session cookie, or "" if
+// there is none or cookie sessions are disabled
+func SessionCookieToken(r *http.Request) string {
+	config := GlobalSecurityConfig.GetConfig()
+	if !config.cookiesEnabled() {
+		return ""
+	}
+
+	cookie, err := r.Cookie(config.SessionCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// RequestToken returns the session token of a request: the bearer token of the
+// Authorization header or, without one, the session cookie. fromCookie reports
+// where it came from; an Authorization header that is not a bearer token yields "".
+func RequestToken(r *http.Request) (token string, fromCookie bool) {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			return "", false
+		}
+		return strings.TrimPrefix(authHeader, "Bearer "), false
+	}
+
+	if token := SessionCookieToken(r); token != "" {
+		return token, true
+	}
+	return "", false
+}
+
+// SetSessionCookie issues the session token as an HttpOnly cookie expiring with it,
+// if the auth mode uses cookies
+func SetSessionCookie(w http.ResponseWriter, token string, expiresAt time.Time) {
+	config := GlobalSecurityConfig.GetConfig()
+	if !config.cookiesEnabled() {
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     config.SessionCookieName,
+		Value:    token,
+		Path:     "/",
+		Expires:  expiresAt,
+		MaxAge:   int(time.Until(expiresAt).Seconds()),
+		HttpOnly: true,
+		Secure:   config.SessionCookieSecure,
+		SameSite: config.sameSite(),
+	})
+}
+
+// ClearSessionCookie tells the browser to drop the session cookie
+func ClearSessionCookie(w http.ResponseWriter) {
+	config := GlobalSecurityConfig.GetConfig()
+	http.SetCookie(w, &http.Cookie{
+		Name:     config.SessionCookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   config.SessionCookieSecure,
+		SameSite: config.sameSite(),
+	})
+}
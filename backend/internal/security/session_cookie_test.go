@@ -0,0 +1,100 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newCSRFRouter returns a router behind CSRFMiddleware using the given auth mode, with CSRF disabled
+func newCSRFRouter(t *testing.T, authMode string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	origConfig := GlobalSecurityConfig
+	t.Cleanup(func() { GlobalSecurityConfig = origConfig })
+	GlobalSecurityConfig = NewSecurityConfigManager(DefaultSecurityConfig)
+	if _, err := GlobalSecurityConfig.UpdateConfig(func(config *SecurityConfig) {
+		config.EnableCSRF = false
+		config.AuthMode = authMode
+	}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	r := gin.New()
+	r.Use(CSRFMiddleware())
+	r.POST("/files", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestCSRFMiddleware_EnforcedForCookieAuth(t *testing.T) {
+	r := newCSRFRouter(t, AuthModeCookie)
+
+	post := func(configure func(*http.Request)) int {
+		req := httptest.NewRequest(http.MethodPost, "/files", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		configure(req)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Bearer tokens are never sent by the browser on its own, so disabling CSRF applies
+	if code := post(func(req *http.Request) { req.Header.Set("Authorization", "Bearer token") }); code != http.StatusOK {
+		t.Errorf("Expected a bearer request to skip CSRF when disabled, got %d", code)
+	}
+
+	withCookie := func(req *http.Request) {
+		req.AddCookie(&http.Cookie{Name: DefaultSessionCookieName, Value: "token"})
+	}
+	if code := post(withCookie); code != http.StatusForbidden {
+		t.Errorf("Expected a cookie request without a CSRF token to be rejected, got %d", code)
+	}
+
+	csrfToken := GlobalCSRFProtection.GenerateToken("192.0.2.1")
+	if code := post(func(req *http.Request) {
+		withCookie(req)
+		req.Header.Set("X-CSRF-Token", csrfToken)
+	}); code != http.StatusOK {
+		t.Errorf("Expected a cookie request with a CSRF token to pass, got %d", code)
+	}
+}
+
+func TestCSRFMiddleware_IgnoresCookieInHeaderMode(t *testing.T) {
+	r := newCSRFRouter(t, AuthModeHeader)
+
+	req := httptest.NewRequest(http.MethodPost, "/files", nil)
+	req.AddCookie(&http.Cookie{Name: DefaultSessionCookieName, Value: "token"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected the cookie to be ignored in header mode, got %d", w.Code)
+	}
+}
+
+func TestSecurityConfig_ValidatesSessionCookie(t *testing.T) {
+	manager := NewSecurityConfigManager(DefaultSecurityConfig)
+
+	for _, update := range []func(*SecurityConfig){
+		func(c *SecurityConfig) { c.AuthMode = "session" },
+		func(c *SecurityConfig) { c.SessionCookieName = "" },
+		func(c *SecurityConfig) { c.SessionCookieName = "session token" },
+		func(c *SecurityConfig) { c.SessionCookieSameSite = "always" },
+		func(c *SecurityConfig) {
+			c.SessionCookieSameSite = "none"
+			c.SessionCookieSecure = false
+		},
+	} {
+		if _, err := manager.UpdateConfig(update); err == nil {
+			t.Errorf("Expected update to be rejected, got %+v", manager.GetConfig())
+		}
+	}
+
+	if _, err := manager.UpdateConfig(func(c *SecurityConfig) {
+		c.AuthMode = AuthModeBoth
+		c.SessionCookieSameSite = "none"
+	}); err != nil {
+		t.Errorf("Expected a secure SameSite none cookie to be accepted, got %v", err)
+	}
+}
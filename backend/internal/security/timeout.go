@@ -0,0 +1,85 @@
+package security
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// timeoutWriter reports 504 instead of the handler's error status when the
+// handler failed because the request deadline passed
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx context.Context
+}
+
+// WriteHeader writes the status code, replacing errors caused by the deadline
+func (w *timeoutWriter) WriteHeader(code int) {
+	if w.ctx.Err() == context.DeadlineExceeded && code >= http.StatusBadRequest {
+		code = http.StatusGatewayTimeout
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// requestTimeoutHandlerName identifies RequestTimeout in a route's handler chain
+var requestTimeoutHandlerName = runtime.FuncForPC(reflect.ValueOf(RequestTimeout(0)).Pointer()).Name()
+
+// ConfiguredTimeoutMiddleware cancels the request context after the
+// RequestTimeout of GlobalSecurityConfig, unless the route sets its own
+// timeout with RequestTimeout. Handlers must pass c.Request.Context() to
+// blocking calls (database, commands, metrics collection) for it to take effect.
+func ConfiguredTimeoutMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if hasRouteTimeout(c) {
+			c.Next()
+			return
+		}
+		applyTimeout(c, GlobalSecurityConfig.GetConfig().RequestTimeout)
+	}
+}
+
+// RequestTimeout overrides the request timeout for a single route. A timeout of
+// 0 disables it, which long-lived WebSocket and SSE routes rely on.
+func RequestTimeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		applyTimeout(c, timeout)
+	}
+}
+
+// hasRouteTimeout checks if the matched route mounts RequestTimeout
+func hasRouteTimeout(c *gin.Context) bool {
+	for _, name := range c.HandlerNames() {
+		if name == requestTimeoutHandlerName {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTimeout runs the remaining handlers with a deadline on the request
+// context and responds with 504 if it passed before they responded
+func applyTimeout(c *gin.Context, timeout time.Duration) {
+	if timeout <= 0 {
+		c.Next()
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	c.Request = c.Request.WithContext(ctx)
+	c.Writer = &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx}
+
+	c.Next()
+
+	if ctx.Err() == context.DeadlineExceeded && !c.Writer.Written() {
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error":   "Request timed out",
+			"timeout": timeout.String(),
+		})
+		c.Abort()
+	}
+}
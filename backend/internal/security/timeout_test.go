@@ -0,0 +1,70 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newTimeoutRouter returns a router with a 20ms global request timeout
+func newTimeoutRouter(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+
+	origConfig := GlobalSecurityConfig
+	t.Cleanup(func() { GlobalSecurityConfig = origConfig })
+	GlobalSecurityConfig = NewSecurityConfigManager(DefaultSecurityConfig)
+	if _, err := GlobalSecurityConfig.UpdateConfig(func(config *SecurityConfig) {
+		config.RequestTimeout = 20 * time.Millisecond
+	}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	// slow waits 200ms unless the request context is cancelled first
+	slow := func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+			c.JSON(http.StatusInternalServerError, gin.H{"error": c.Request.Context().Err().Error()})
+		case <-time.After(200 * time.Millisecond):
+			c.Status(http.StatusOK)
+		}
+	}
+
+	r := gin.New()
+	r.Use(ConfiguredTimeoutMiddleware())
+	r.GET("/fast", func(c *gin.Context) { c.Status(http.StatusOK) })
+	r.GET("/slow", slow)
+	r.GET("/slow-silent", func(c *gin.Context) { <-c.Request.Context().Done() })
+	r.GET("/stream", RequestTimeout(0), slow)
+	r.GET("/long", RequestTimeout(time.Second), slow)
+	return r
+}
+
+func TestRequestTimeout_SlowHandler(t *testing.T) {
+	r := newTimeoutRouter(t)
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/fast", http.StatusOK},
+		{"/slow", http.StatusGatewayTimeout},        // handler error replaced by 504
+		{"/slow-silent", http.StatusGatewayTimeout}, // no response written, middleware responds
+		{"/stream", http.StatusOK},                  // exempt route is never cancelled
+		{"/long", http.StatusOK},                    // route timeout overrides the global one
+	}
+
+	for _, tt := range tests {
+		start := time.Now()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.expected {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.expected, w.Code)
+		}
+		if tt.expected == http.StatusGatewayTimeout && time.Since(start) > 150*time.Millisecond {
+			t.Errorf("%s: expected the request to be cancelled at the deadline, took %v", tt.path, time.Since(start))
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package security
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tlsVersions maps the accepted minimum TLS versions; older ones are insecure
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig represents how the server serves HTTPS
+type TLSConfig struct {
+	Enabled    bool
+	CertFile   string
+	KeyFile    string
+	MinVersion string // 1.2 or 1.3
+	Addr       string // HTTPS listen address
+	HTTPAddr   string // plain HTTP listen address, redirecting to HTTPS when enabled
+}
+
+// DefaultTLSConfig returns default TLS configuration, serving plain HTTP only
+func DefaultTLSConfig() *TLSConfig {
+	return &TLSConfig{
+		MinVersion: "1.2",
+		Addr:       ":8443",
+		HTTPAddr:   ":8080",
+	}
+}
+
+// Validate checks the minimum version and, when TLS is enabled, that the
+// certificate and key load, so a bad setup fails at startup
+func (tc *TLSConfig) Validate() error {
+	if _, ok := tlsVersions[tc.MinVersion]; !ok {
+		return fmt.Errorf("minimum TLS version must be 1.2 or 1.3, got %q", tc.MinVersion)
+	}
+	if !tc.Enabled {
+		return nil
+	}
+	if tc.CertFile == "" || tc.KeyFile == "" {
+		return errors.New("TLS requires both a certificate and a key file")
+	}
+	if _, err := tls.LoadX509KeyPair(tc.CertFile, tc.KeyFile); err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	if tc.Addr == "" || tc.HTTPAddr == "" {
+		return errors.New("TLS and HTTP listen addresses are required")
+	}
+	return nil
+}
+
+// ServerTLSConfig returns the crypto/tls configuration for the HTTPS server
+func (tc *TLSConfig) ServerTLSConfig() *tls.Config {
+	return &tls.Config{MinVersion: tlsVersions[tc.MinVersion]}
+}
+
+// HTTPSRedirectMiddleware redirects plain HTTP requests to the same URL on the
+// HTTPS listen address. Safe methods are moved permanently; others use 308 so
+// clients repeat them with their body rather than switching to GET.
+func HTTPSRedirectMiddleware(httpsAddr string) gin.HandlerFunc {
+	_, httpsPort, _ := net.SplitHostPort(httpsAddr)
+
+	return func(c *gin.Context) {
+		if c.Request.TLS != nil {
+			c.Next()
+			return
+		}
+
+		host := c.Request.Host
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		} else {
+			host = strings.Trim(host, "[]")
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		} else if strings.Contains(host, ":") {
+			host = "[" + host + "]"
+		}
+
+		status := http.StatusPermanentRedirect
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead {
+			status = http.StatusMovedPermanently
+		}
+		c.Redirect(status, "https://"+host+c.Request.URL.RequestURI())
+		c.Abort()
+	}
+}
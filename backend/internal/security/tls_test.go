@@ -0,0 +1,139 @@
+package security
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRedirectRouter(httpsAddr string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(HTTPSRedirectMiddleware(httpsAddr))
+	r.Use(SecurityHeadersMiddleware())
+	r.Any("/api/files", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return r
+}
+
+func TestHTTPSRedirectMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		httpsAddr    string
+		method       string
+		host         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{"get keeps path and query", ":8443", http.MethodGet, "example.com:8080", http.StatusMovedPermanently, "https://example.com:8443/api/files?limit=5"},
+		{"post keeps method", ":8443", http.MethodPost, "example.com:8080", http.StatusPermanentRedirect, "https://example.com:8443/api/files?limit=5"},
+		{"default port omitted", ":443", http.MethodGet, "example.com", http.StatusMovedPermanently, "https://example.com/api/files?limit=5"},
+		{"ipv6 host", ":8443", http.MethodGet, "[::1]:8080", http.StatusMovedPermanently, "https://[::1]:8443/api/files?limit=5"},
+		{"ipv6 host default port", ":443", http.MethodGet, "[::1]", http.StatusMovedPermanently, "https://[::1]/api/files?limit=5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newRedirectRouter(tt.httpsAddr)
+			req := httptest.NewRequest(tt.method, "/api/files?limit=5", nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if location := w.Header().Get("Location"); location != tt.wantLocation {
+				t.Errorf("Expected redirect to %q, got %q", tt.wantLocation, location)
+			}
+			if w.Header().Get("Strict-Transport-Security") != "" {
+				t.Error("Expected no HSTS header over plain HTTP")
+			}
+		})
+	}
+}
+
+func TestHTTPSRedirectMiddleware_ServesTLSWithHSTS(t *testing.T) {
+	r := newRedirectRouter(":8443")
+	req := httptest.NewRequest(http.MethodGet, "https://example.com:8443/api/files", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected a TLS request to be served, got %d", w.Code)
+	}
+	if w.Header().Get("Strict-Transport-Security") != DefaultSecurityHeaders.StrictTransportSecurity {
+		t.Errorf("Expected HSTS over TLS, got %q", w.Header().Get("Strict-Transport-Security"))
+	}
+}
+
+// writeTestCertificate writes a self-signed certificate and its key to a temp directory
+func writeTestCertificate(t *testing.T) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+
+	dir := t.TempDir()
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func TestTLSConfig_Validate(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t)
+
+	config := DefaultTLSConfig()
+	if err := config.Validate(); err != nil || config.Enabled {
+		t.Fatalf("Expected plain HTTP without a certificate, got %+v, %v", config, err)
+	}
+
+	config.Enabled = true
+	config.CertFile = certFile
+	if err := config.Validate(); err == nil {
+		t.Error("Expected a certificate without a key to be rejected")
+	}
+
+	config.KeyFile = certFile
+	if err := config.Validate(); err == nil {
+		t.Error("Expected an unloadable key to be rejected")
+	}
+
+	config.KeyFile = keyFile
+	config.MinVersion = "1.0"
+	if err := config.Validate(); err == nil {
+		t.Error("Expected TLS 1.0 to be rejected as a minimum version")
+	}
+
+	config.MinVersion = "1.3"
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Failed to validate TLS config: %v", err)
+	}
+	if config.ServerTLSConfig().MinVersion != tls.VersionTLS13 {
+		t.Errorf("Expected a 1.3 minimum, got %+v", config)
+	}
+}
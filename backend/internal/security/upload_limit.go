@@ -0,0 +1,77 @@
+package security
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UploadCapacityCode identifies responses rejected because too many uploads are
+// in progress, so clients can retry after the Retry-After delay
+const UploadCapacityCode = "upload_capacity_exceeded"
+
+// UploadLimiter bounds the number of uploads processed at the same time, so
+// simultaneous large uploads can't exhaust disk IO and memory
+type UploadLimiter struct {
+	slots chan struct{} // nil = unlimited
+}
+
+// NewUploadLimiter creates an upload limiter allowing max concurrent uploads, 0 = unlimited
+func NewUploadLimiter(max int) *UploadLimiter {
+	if max <= 0 {
+		return &UploadLimiter{}
+	}
+	return &UploadLimiter{slots: make(chan struct{}, max)}
+}
+
+// TryAcquire takes a slot without waiting, reporting false when all are taken.
+// Every acquired slot must be released.
+func (ul *UploadLimiter) TryAcquire() bool {
+	if ul.slots == nil {
+		return true
+	}
+	select {
+	case ul.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Release frees a slot taken with TryAcquire
+func (ul *UploadLimiter) Release() {
+	if ul.slots == nil {
+		return
+	}
+	<-ul.slots
+}
+
+// InFlight returns the number of uploads holding a slot
+func (ul *UploadLimiter) InFlight() int {
+	return len(ul.slots)
+}
+
+// UploadConcurrencyLimit rejects uploads with 503 and Retry-After while
+// MaxConcurrentUploads of GlobalSecurityConfig are in progress. Mount it on
+// upload routes after authentication, so anonymous requests never hold a slot.
+func UploadConcurrencyLimit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// The limiter is captured so a slot is released to the limiter it was
+		// taken from, even if the limit changes during the upload
+		limiter := GlobalSecurityConfig.GetUploadLimiter()
+		if !limiter.TryAcquire() {
+			retryAfter := int(GlobalSecurityConfig.GetConfig().UploadRetryAfter.Seconds())
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+				"error":       "Too many uploads in progress, please retry later",
+				"code":        UploadCapacityCode,
+				"retry_after": retryAfter,
+			})
+			return
+		}
+		defer limiter.Release()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,117 @@
+package security
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newUploadLimitRouter returns a router allowing max concurrent uploads, whose
+// /upload handler holds its slot until release is closed
+func newUploadLimitRouter(t *testing.T, max int, release chan struct{}) (*gin.Engine, *sync.WaitGroup) {
+	gin.SetMode(gin.TestMode)
+
+	origConfig := GlobalSecurityConfig
+	t.Cleanup(func() { GlobalSecurityConfig = origConfig })
+	GlobalSecurityConfig = NewSecurityConfigManager(DefaultSecurityConfig)
+	if _, err := GlobalSecurityConfig.UpdateConfig(func(config *SecurityConfig) {
+		config.MaxConcurrentUploads = max
+		config.UploadRetryAfter = 7 * time.Second
+	}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	var started sync.WaitGroup
+	r := gin.New()
+	r.POST("/upload", UploadConcurrencyLimit(), func(c *gin.Context) {
+		started.Done()
+		<-release
+		c.Status(http.StatusCreated)
+	})
+	return r, &started
+}
+
+func TestUploadConcurrencyLimit_RejectsBeyondCap(t *testing.T) {
+	release := make(chan struct{})
+	r, started := newUploadLimitRouter(t, 2, release)
+
+	// Fill every slot with an upload in progress
+	var finished sync.WaitGroup
+	codes := make([]int, 2)
+	started.Add(2)
+	finished.Add(2)
+	for i := range codes {
+		go func(i int) {
+			defer finished.Done()
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/upload", nil))
+			codes[i] = w.Code
+		}(i)
+	}
+	started.Wait()
+
+	if inFlight := GlobalSecurityConfig.GetUploadLimiter().InFlight(); inFlight != 2 {
+		t.Errorf("Expected 2 uploads in flight, got %d", inFlight)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/upload", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503 beyond the cap, got %d", w.Code)
+	}
+	if retryAfter := w.Header().Get("Retry-After"); retryAfter != "7" {
+		t.Errorf("Expected Retry-After 7, got %q", retryAfter)
+	}
+
+	close(release)
+	finished.Wait()
+	for i, code := range codes {
+		if code != http.StatusCreated {
+			t.Errorf("Expected upload %d within the cap to succeed, got %d", i, code)
+		}
+	}
+
+	// Finished uploads free their slots
+	started.Add(1)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/upload", nil))
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected an upload after the others finished to succeed, got %d", w.Code)
+	}
+	if inFlight := GlobalSecurityConfig.GetUploadLimiter().InFlight(); inFlight != 0 {
+		t.Errorf("Expected every slot to be released, got %d in flight", inFlight)
+	}
+}
+
+func TestUploadConcurrencyLimit_ZeroIsUnlimited(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	r, started := newUploadLimitRouter(t, 0, release)
+
+	started.Add(5)
+	for i := 0; i < 5; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/upload", nil))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected unlimited uploads to succeed, got %d", w.Code)
+		}
+	}
+}
+
+func TestSecurityConfig_ValidatesUploadLimits(t *testing.T) {
+	config := DefaultSecurityConfig
+	config.MaxConcurrentUploads = -1
+	if err := config.Validate(); err == nil {
+		t.Error("Expected a negative upload limit to be rejected")
+	}
+
+	config = DefaultSecurityConfig
+	config.UploadRetryAfter = 0
+	if err := config.Validate(); err == nil {
+		t.Error("Expected a retry after below 1 second to be rejected")
+	}
+}
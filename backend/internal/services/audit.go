@@ -32,9 +32,18 @@ func (al *AuditLogger) LogEvent(eventKey string, userID *uint, resource string,
 	event, exists := al.events[eventKey]
 	al.mutex.RUnlock()
 	
+	// Events registered after the logger was created
+	if !exists {
+		event, exists = models.LookupAuditEvent(eventKey)
+	}
 	if !exists {
 		return fmt.Errorf("unknown audit event: %s", eventKey)
 	}
+
+	// Drop events below the configured severity floor
+	if !GlobalAuditManager.ShouldLog(event.Severity) {
+		return nil
+	}
 	
 	// Convert details to JSON string
 	var detailsStr string
@@ -59,7 +68,12 @@ func (al *AuditLogger) LogEvent(eventKey string, userID *uint, resource string,
 		Status:      status,
 		CreatedAt:   time.Now(),
 	}
-	
+
+	// Written in the background once GlobalAuditWriter runs, so requests don't
+	// wait on the database
+	if err := GlobalAuditWriter.Enqueue(auditLog); err != ErrAuditWriterStopped {
+		return err
+	}
 	return models.CreateSecurityAuditLog(al.db, auditLog)
 }
 
@@ -68,11 +82,38 @@ func (al *AuditLogger) LogLoginSuccess(userID uint, ipAddress, userAgent, reques
 	return al.LogEvent("login_success", &userID, "user", &userID, ipAddress, userAgent, requestID, sessionID, nil, "success")
 }
 
+// LogNewDeviceLogin logs a login from a device the user has not used before
+func (al *AuditLogger) LogNewDeviceLogin(userID uint, fingerprint, ipAddress, userAgent string) error {
+	details := map[string]interface{}{
+		"fingerprint": fingerprint,
+	}
+	return al.LogEvent("login_new_device", &userID, "user", &userID, ipAddress, userAgent, "", "", details, "success")
+}
+
 // LogLoginFailure logs a failed login attempt
 func (al *AuditLogger) LogLoginFailure(username, ipAddress, userAgent, requestID string, details interface{}) error {
 	return al.LogEvent("login_failure", nil, "user", nil, ipAddress, userAgent, requestID, "", details, "failure")
 }
 
+// LogRegistrationRoleRejected logs a self-registration rejected for requesting an elevated role
+func (al *AuditLogger) LogRegistrationRoleRejected(username, email, role, ipAddress, userAgent, requestID string) error {
+	details := map[string]interface{}{
+		"username":       username,
+		"email":          email,
+		"requested_role": role,
+	}
+	return al.LogEvent("registration_role_rejected", nil, "user", nil, ipAddress, userAgent, requestID, "", details, "failure")
+}
+
+// LogOnboardingFailed logs an onboarding hook failing for a newly registered user
+func (al *AuditLogger) LogOnboardingFailed(userID uint, hook string, err error) error {
+	details := map[string]interface{}{
+		"hook":  hook,
+		"error": err.Error(),
+	}
+	return al.LogEvent("onboarding_failed", &userID, "user", &userID, "", "", "", "", details, "failure")
+}
+
 // LogLogout logs a logout event
 func (al *AuditLogger) LogLogout(userID uint, ipAddress, userAgent, requestID, sessionID string) error {
 	return al.LogEvent("logout", &userID, "user", &userID, ipAddress, userAgent, requestID, sessionID, nil, "success")
@@ -89,6 +130,76 @@ func (al *AuditLogger) LogFileOperation(operation string, userID uint, fileID ui
 	return al.LogEvent(eventKey, &userID, "file", &fileID, ipAddress, userAgent, requestID, "", details, status)
 }
 
+// LogFileRename logs a change of a file's display name
+func (al *AuditLogger) LogFileRename(userID uint, fileID uint, oldName, newName, ipAddress, userAgent, requestID string) error {
+	details := map[string]interface{}{
+		"file_id":  fileID,
+		"old_name": oldName,
+		"new_name": newName,
+	}
+	return al.LogEvent("file_rename", &userID, "file", &fileID, ipAddress, userAgent, requestID, "", details, "success")
+}
+
+// LogFileVerification logs an on-demand integrity check and re-scan of a file.
+// It fails unless the content matches its hash and scanned clean.
+func (al *AuditLogger) LogFileVerification(userID uint, v *FileVerification, ipAddress, userAgent, requestID string) error {
+	details := map[string]interface{}{
+		"file_id":   v.FileID,
+		"integrity": v.Integrity,
+		"safe":      v.Safe,
+	}
+	if v.Scan != nil && v.Scan.Infected {
+		details["threat"] = v.Scan.Threat
+	}
+	if v.ScanError != "" {
+		details["scan_error"] = v.ScanError
+	}
+
+	status := "success"
+	if v.Integrity != models.FileIntegrityVerified || v.Scan == nil || v.Scan.Infected {
+		status = "failure"
+	}
+	return al.LogEvent("file_verify", &userID, "file", &v.FileID, ipAddress, userAgent, requestID, "", details, status)
+}
+
+// LogMalwareDetected logs a file the scanner found infected and moved to quarantine
+func (al *AuditLogger) LogMalwareDetected(userID uint, fileID uint, threat, quarantinePath string) error {
+	details := map[string]interface{}{
+		"file_id":         fileID,
+		"threat":          threat,
+		"quarantine_path": quarantinePath,
+	}
+	return al.LogEvent("malware_detected", &userID, "file", &fileID, "", "", "", "", details, "failure")
+}
+
+// LogFileExpired logs a file deleted by the expiry job
+func (al *AuditLogger) LogFileExpired(userID uint, fileID uint, filename string, expiresAt time.Time) error {
+	details := map[string]interface{}{
+		"file_id":    fileID,
+		"filename":   filename,
+		"expires_at": expiresAt,
+	}
+	return al.LogEvent("file_expired", &userID, "file", &fileID, "", "", "", "", details, "success")
+}
+
+// LogQuarantineRelease logs an admin releasing a quarantined file despite its detection
+func (al *AuditLogger) LogQuarantineRelease(adminID uint, fileID uint, threat, ipAddress, userAgent string) error {
+	details := map[string]interface{}{
+		"file_id": fileID,
+		"threat":  threat,
+	}
+	return al.LogEvent("quarantine_release", &adminID, "file", &fileID, ipAddress, userAgent, "", "", details, "success")
+}
+
+// LogQuarantineDelete logs an admin deleting a quarantined file
+func (al *AuditLogger) LogQuarantineDelete(adminID uint, fileID uint, threat, ipAddress, userAgent string) error {
+	details := map[string]interface{}{
+		"file_id": fileID,
+		"threat":  threat,
+	}
+	return al.LogEvent("quarantine_delete", &adminID, "file", &fileID, ipAddress, userAgent, "", "", details, "success")
+}
+
 // LogCommandExecution logs a command execution
 func (al *AuditLogger) LogCommandExecution(userID uint, command string, args []string, exitCode int, ipAddress, userAgent, requestID string) error {
 	details := map[string]interface{}{
@@ -134,11 +245,90 @@ func (al *AuditLogger) LogSessionExpired(userID uint, sessionID, ipAddress, user
 	return al.LogEvent("session_expired", &userID, "session", nil, ipAddress, userAgent, "", sessionID, nil, "success")
 }
 
+// LogSessionEvicted logs a session evicted because the user exceeded the session limit
+func (al *AuditLogger) LogSessionEvicted(userID uint, sessionID, ipAddress, userAgent string) error {
+	return al.LogEvent("session_evicted", &userID, "session", nil, ipAddress, userAgent, "", sessionID, nil, "success")
+}
+
+// LogDataExport logs an export of a user's data, by the user or by an admin
+func (al *AuditLogger) LogDataExport(actorID, userID uint, format, ipAddress, userAgent, requestID string) error {
+	details := map[string]interface{}{
+		"user_id": userID,
+		"format":  format,
+	}
+	return al.LogEvent("data_export", &actorID, "user", &userID, ipAddress, userAgent, requestID, "", details, "success")
+}
+
+// LogSessionIPMismatch logs a session token rejected because it was used from
+// another address than the session is bound to
+func (al *AuditLogger) LogSessionIPMismatch(userID uint, sessionID, boundIP, ipAddress, userAgent string) error {
+	details := map[string]interface{}{
+		"bound_ip": boundIP,
+	}
+	return al.LogEvent("session_ip_mismatch", &userID, "session", nil, ipAddress, userAgent, "", sessionID, details, "failure")
+}
+
+// LogSessionTerminated logs an admin terminating one of a user's sessions
+func (al *AuditLogger) LogSessionTerminated(adminID, userID uint, sessionID, ipAddress, userAgent, requestID string) error {
+	details := map[string]interface{}{
+		"user_id": userID,
+	}
+	return al.LogEvent("session_terminated", &adminID, "session", nil, ipAddress, userAgent, requestID, sessionID, details, "success")
+}
+
+// LogRoleChangeLogout logs the revocation of a user's sessions after an admin changed their role
+func (al *AuditLogger) LogRoleChangeLogout(adminID, userID uint, oldRole, newRole string, revokedSessions int, ipAddress, userAgent string) error {
+	details := map[string]interface{}{
+		"user_id":          userID,
+		"old_role":         oldRole,
+		"new_role":         newRole,
+		"revoked_sessions": revokedSessions,
+	}
+	return al.LogEvent("role_change_logout", &adminID, "user", &userID, ipAddress, userAgent, "", "", details, "success")
+}
+
+// LogRateLimitExempted logs a request that bypassed the rate limiter through an exemption
+func (al *AuditLogger) LogRateLimitExempted(userID *uint, reason, resource, ipAddress, userAgent string) error {
+	details := map[string]interface{}{
+		"reason": reason,
+	}
+	return al.LogEvent("rate_limit_exempted", userID, resource, nil, ipAddress, userAgent, "", "", details, "success")
+}
+
+// LogWebSocketDisconnect logs an admin forcibly disconnecting a WebSocket client
+func (al *AuditLogger) LogWebSocketDisconnect(adminID uint, clientID string, clientUserID uint, ipAddress, userAgent string) error {
+	details := map[string]interface{}{
+		"client_id": clientID,
+		"user_id":   clientUserID,
+	}
+	return al.LogEvent("websocket_disconnected", &adminID, "websocket", nil, ipAddress, userAgent, "", "", details, "success")
+}
+
+// LogMaintenanceToggled logs an admin turning maintenance mode on or off
+func (al *AuditLogger) LogMaintenanceToggled(adminID uint, enabled bool, message, ipAddress, userAgent string) error {
+	details := map[string]interface{}{
+		"enabled": enabled,
+		"message": message,
+	}
+	return al.LogEvent("maintenance_toggled", &adminID, "system", nil, ipAddress, userAgent, "", "", details, "success")
+}
+
 // LogAdminAction logs an administrative action
 func (al *AuditLogger) LogAdminAction(userID uint, action, resource string, resourceID *uint, details interface{}, ipAddress, userAgent, requestID string) error {
 	return al.LogEvent("admin_action", &userID, resource, resourceID, ipAddress, userAgent, requestID, "", details, "success")
 }
 
+// LogSecurityAlert logs an alert raised by the audit alert scanner
+func (al *AuditLogger) LogSecurityAlert(alert *AuditAlert) error {
+	details := map[string]interface{}{
+		"rule":   alert.Rule,
+		"group":  alert.Group,
+		"count":  alert.Count,
+		"window": alert.Window,
+	}
+	return al.LogEvent("security_alert", nil, "audit", nil, "", "", "", "", details, "success")
+}
+
 // LogSystemError logs a system error
 func (al *AuditLogger) LogSystemError(errorType, resource string, details interface{}, ipAddress, userAgent, requestID string) error {
 	return al.LogEvent("system_error", nil, resource, nil, ipAddress, userAgent, requestID, "", details, "error")
@@ -216,28 +406,41 @@ func (am *AuditMiddleware) LogRequest(method, path string, userID *uint, ipAddre
 // AuditConfig represents audit logging configuration
 type AuditConfig struct {
 	Enabled           bool          `json:"enabled"`
-	RetentionDays     int           `json:"retention_days"`
-	LogLevel          string        `json:"log_level"`
-	CleanupInterval   time.Duration `json:"cleanup_interval"`
+	LogLevel          string        `json:"log_level"` // severity floor: low, medium or high
 	MaxLogSize        int64         `json:"max_log_size"`
 	CompressOldLogs   bool          `json:"compress_old_logs"`
 }
 
+// auditSeverityRank orders audit event severities from least to most severe
+var auditSeverityRank = map[string]int{
+	"low":      0,
+	"medium":   1,
+	"high":     2,
+	"critical": 3,
+}
+
 // DefaultAuditConfig returns default audit configuration
 func DefaultAuditConfig() *AuditConfig {
 	return &AuditConfig{
 		Enabled:         true,
-		RetentionDays:   90,
 		LogLevel:        "medium",
-		CleanupInterval: 24 * time.Hour,
 		MaxLogSize:      100 * 1024 * 1024, // 100MB
 		CompressOldLogs: true,
 	}
 }
 
+// Validate checks the severity floor. It cannot be raised above "high", so
+// high and critical events are always logged.
+func (ac *AuditConfig) Validate() error {
+	rank, exists := auditSeverityRank[ac.LogLevel]
+	if !exists || rank > auditSeverityRank["high"] {
+		return fmt.Errorf("log level must be one of low, medium or high")
+	}
+	return nil
+}
+
 // AuditManager manages audit logging for the entire application
 type AuditManager struct {
-	logger *AuditLogger
 	config *AuditConfig
 	mutex  sync.RWMutex
 }
@@ -245,26 +448,27 @@ type AuditManager struct {
 // NewAuditManager creates a new audit manager
 func NewAuditManager() *AuditManager {
 	manager := &AuditManager{
-		logger: NewAuditLogger(),
 		config: DefaultAuditConfig(),
 	}
 	
-	// Start cleanup goroutine
-	go manager.startCleanup()
-	
 	return manager
 }
 
-// GetLogger returns the audit logger
+// GetLogger returns an audit logger bound to the current database
 func (am *AuditManager) GetLogger() *AuditLogger {
-	return am.logger
+	return NewAuditLogger()
 }
 
 // UpdateConfig updates audit configuration
-func (am *AuditManager) UpdateConfig(config *AuditConfig) {
+func (am *AuditManager) UpdateConfig(config *AuditConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
 	am.mutex.Lock()
 	defer am.mutex.Unlock()
 	am.config = config
+	return nil
 }
 
 // GetConfig returns current audit configuration
@@ -274,14 +478,18 @@ func (am *AuditManager) GetConfig() *AuditConfig {
 	return am.config
 }
 
-// startCleanup starts a goroutine to clean up old audit logs
-func (am *AuditManager) startCleanup() {
-	ticker := time.NewTicker(am.config.CleanupInterval)
-	defer ticker.Stop()
-	
-	for range ticker.C {
-		if am.config.Enabled {
-			am.logger.CleanupOldLogs(am.config.RetentionDays)
-		}
+// ShouldLog reports whether an event of the given severity reaches the
+// configured floor. Unknown severities are logged.
+func (am *AuditManager) ShouldLog(severity string) bool {
+	rank, exists := auditSeverityRank[severity]
+	if !exists {
+		return true
 	}
+
+	am.mutex.RLock()
+	defer am.mutex.RUnlock()
+	return rank >= auditSeverityRank[am.config.LogLevel]
 }
+
+// GlobalAuditManager holds the audit configuration applied by every AuditLogger
+var GlobalAuditManager = NewAuditManager()
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidAuditAlertPolicy = errors.New("audit alert interval must be at least 10 seconds and window at least 1 minute")
+	ErrInvalidAuditAlertRule   = errors.New("audit alert rules need a unique name, a threshold of at least 1 and a group by of ip_address, user_id or none")
+	ErrAlertScanRunning        = errors.New("audit alert scan is already running")
+)
+
+// AuditAlertRule raises an alert when at least Threshold audit logs matching
+// Match were written within the scan window, counted per GroupBy value
+type AuditAlertRule struct {
+	Name      string               `json:"name"`
+	Match     models.AuditLogMatch `json:"match"`
+	GroupBy   string               `json:"group_by"` // ip_address, user_id, or "" to count all together
+	Threshold int64                `json:"threshold"`
+}
+
+// AuditAlertPolicy represents how and how often recent audit logs are scanned for alerts
+type AuditAlertPolicy struct {
+	Enabled         bool             `json:"enabled"`
+	IntervalSeconds int              `json:"interval_seconds"`
+	WindowMinutes   int              `json:"window_minutes"` // how far back each scan looks
+	Rules           []AuditAlertRule `json:"rules"`
+}
+
+// DefaultAuditAlertPolicy returns default audit alert policy: brute forcing
+// from one address, repeated denials of one user, and any malware detection
+func DefaultAuditAlertPolicy() *AuditAlertPolicy {
+	return &AuditAlertPolicy{
+		Enabled:         true,
+		IntervalSeconds: 60,
+		WindowMinutes:   15,
+		Rules: []AuditAlertRule{
+			{
+				Name:      "login_failures",
+				Match:     models.AuditLogMatch{EventType: "authentication", EventAction: "login", Status: "failure"},
+				GroupBy:   "ip_address",
+				Threshold: 10,
+			},
+			{
+				Name:      "permission_denials",
+				Match:     models.AuditLogMatch{EventType: "authorization", EventAction: "deny"},
+				GroupBy:   "user_id",
+				Threshold: 20,
+			},
+			{
+				Name:      "malware_detected",
+				Match:     models.AuditLogMatch{EventType: "security", EventAction: "quarantine"},
+				Threshold: 1,
+			},
+		},
+	}
+}
+
+// Validate checks the policy for invalid values
+func (ap *AuditAlertPolicy) Validate() error {
+	if ap.IntervalSeconds < 10 || ap.WindowMinutes < 1 {
+		return ErrInvalidAuditAlertPolicy
+	}
+	names := make(map[string]bool, len(ap.Rules))
+	for _, rule := range ap.Rules {
+		if rule.Name == "" || names[rule.Name] || rule.Threshold < 1 || !models.ValidAuditGroupColumn(rule.GroupBy) {
+			return fmt.Errorf("%w: %q", ErrInvalidAuditAlertRule, rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return nil
+}
+
+// AuditAlert represents a rule whose threshold was reached
+type AuditAlert struct {
+	Rule   string    `json:"rule"`
+	Group  string    `json:"group,omitempty"` // the IP address or user ID counted
+	Count  int64     `json:"count"`
+	Window string    `json:"window"`
+	Time   time.Time `json:"time"`
+}
+
+// AuditAlertScanStats represents the result of the last scan and how many were skipped
+type AuditAlertScanStats struct {
+	LastScan     *time.Time   `json:"last_scan,omitempty"`
+	LastDuration string       `json:"last_duration,omitempty"`
+	LastError    string       `json:"last_error,omitempty"`
+	LastAlerts   []AuditAlert `json:"last_alerts"`
+	Suppressed   int          `json:"suppressed"` // alerts of the last scan already raised within the window
+	Scans        int64        `json:"scans"`
+	Skipped      int64        `json:"skipped"` // scans not started because one was still running
+}
+
+// AuditAlertScanner scans recent audit logs against the alert rules. A scan
+// is skipped while the previous one is still running, so slow scans under load
+// don't pile up. An alert is raised once per rule and group within the window.
+type AuditAlertScanner struct {
+	policy   *AuditAlertPolicy
+	onAlert  func(*AuditAlert)
+	raised   map[string]time.Time // rule and group -> when it was last raised
+	stats    AuditAlertScanStats
+	scanning atomic.Bool
+	skipped  atomic.Int64
+	mutex    sync.RWMutex
+}
+
+// NewAuditAlertScanner creates a new audit alert scanner
+func NewAuditAlertScanner() *AuditAlertScanner {
+	return &AuditAlertScanner{
+		policy: DefaultAuditAlertPolicy(),
+		raised: make(map[string]time.Time),
+		stats:  AuditAlertScanStats{LastAlerts: []AuditAlert{}},
+	}
+}
+
+// GetPolicy returns a copy of the current audit alert policy
+func (as *AuditAlertScanner) GetPolicy() AuditAlertPolicy {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+
+	policy := *as.policy
+	policy.Rules = append([]AuditAlertRule(nil), as.policy.Rules...)
+	return policy
+}
+
+// UpdatePolicy validates and replaces the audit alert policy
+func (as *AuditAlertScanner) UpdatePolicy(policy *AuditAlertPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	stored := *policy
+	stored.Rules = append([]AuditAlertRule(nil), policy.Rules...)
+
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+	as.policy = &stored
+	return nil
+}
+
+// SetAlertHandler registers a callback invoked for every alert raised
+func (as *AuditAlertScanner) SetAlertHandler(handler func(*AuditAlert)) {
+	as.mutex.Lock()
+	defer as.mutex.Unlock()
+	as.onAlert = handler
+}
+
+// Stats returns the stats of the last scan
+func (as *AuditAlertScanner) Stats() AuditAlertScanStats {
+	as.mutex.RLock()
+	defer as.mutex.RUnlock()
+
+	stats := as.stats
+	stats.LastAlerts = append([]AuditAlert{}, as.stats.LastAlerts...)
+	stats.Skipped = as.skipped.Load()
+	return stats
+}
+
+// Scan evaluates every rule over the audit logs of the window ending at now
+// and raises the alerts not raised yet. It returns ErrAlertScanRunning without
+// scanning while another scan is in progress.
+func (as *AuditAlertScanner) Scan(database *gorm.DB, now time.Time) (*AuditAlertScanStats, error) {
+	if !as.scanning.CompareAndSwap(false, true) {
+		as.skipped.Add(1)
+		return nil, ErrAlertScanRunning
+	}
+	defer as.scanning.Store(false)
+
+	policy := as.GetPolicy()
+	window := time.Duration(policy.WindowMinutes) * time.Minute
+	start := time.Now()
+
+	var alerts []AuditAlert
+	var scanErr error
+	for _, rule := range policy.Rules {
+		counts, err := models.CountAuditLogsByGroup(database, rule.Match, rule.GroupBy, now.Add(-window), rule.Threshold)
+		if err != nil {
+			scanErr = fmt.Errorf("rule %s: %w", rule.Name, err)
+			break
+		}
+		for _, count := range counts {
+			alerts = append(alerts, AuditAlert{Rule: rule.Name, Group: count.Key, Count: count.Count, Window: window.String(), Time: now})
+		}
+	}
+
+	as.mutex.Lock()
+	raised := make([]AuditAlert, 0, len(alerts))
+	suppressed := 0
+	for _, alert := range alerts {
+		key := alert.Rule + "\x00" + alert.Group
+		if last, exists := as.raised[key]; exists && now.Sub(last) < window {
+			suppressed++
+			continue
+		}
+		as.raised[key] = now
+		raised = append(raised, alert)
+	}
+	for key, last := range as.raised {
+		if now.Sub(last) >= window {
+			delete(as.raised, key)
+		}
+	}
+
+	as.stats.LastScan = &start
+	as.stats.LastDuration = time.Since(start).String()
+	as.stats.LastAlerts = raised
+	as.stats.Suppressed = suppressed
+	as.stats.Scans++
+	as.stats.LastError = ""
+	if scanErr != nil {
+		as.stats.LastError = scanErr.Error()
+	}
+	onAlert := as.onAlert
+	as.mutex.Unlock()
+
+	if onAlert != nil {
+		for i := range raised {
+			onAlert(&raised[i])
+		}
+	}
+
+	stats := as.Stats()
+	return &stats, scanErr
+}
+
+// Register schedules scans against the application database at the interval
+// the current policy sets; scans are skipped while the policy is disabled
+func (as *AuditAlertScanner) Register(scheduler *Scheduler) error {
+	interval := func() time.Duration {
+		return time.Duration(as.GetPolicy().IntervalSeconds) * time.Second
+	}
+	return scheduler.Register("audit_alert_scan", interval, func() (interface{}, error) {
+		if !as.GetPolicy().Enabled {
+			return nil, nil
+		}
+		stats, err := as.Scan(db.DB, time.Now())
+		if err == ErrAlertScanRunning {
+			return nil, nil // counted as skipped
+		}
+		return stats, err
+	})
+}
+
+// GlobalAuditAlertScanner scans the application's audit logs for alerts
+var GlobalAuditAlertScanner = NewAuditAlertScanner()
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"golangmcp/internal/models"
+	"gorm.io/gorm"
+)
+
+// createAuditLogs writes count copies of an audit log entry
+func createAuditLogs(t *testing.T, database *gorm.DB, entry models.SecurityAuditLog, count int) {
+	for i := 0; i < count; i++ {
+		log := entry
+		if err := database.Create(&log).Error; err != nil {
+			t.Fatalf("Failed to create audit log: %v", err)
+		}
+	}
+}
+
+func TestAuditAlertScanner_EvaluatesRules(t *testing.T) {
+	database := setupRetentionTestDB(t)
+	now := time.Now()
+	userID := uint(7)
+
+	failedLogin := models.SecurityAuditLog{EventType: "authentication", EventAction: "login", Status: "failure", Severity: "medium", CreatedAt: now.Add(-time.Minute)}
+	attacker, other := failedLogin, failedLogin
+	attacker.IPAddress, other.IPAddress = "203.0.113.9", "198.51.100.1"
+	createAuditLogs(t, database, attacker, 5)
+	createAuditLogs(t, database, other, 2)
+	old := attacker
+	old.CreatedAt = now.Add(-time.Hour) // outside the window
+	createAuditLogs(t, database, old, 5)
+	createAuditLogs(t, database, models.SecurityAuditLog{EventType: "authorization", EventAction: "deny", Status: "failure", Severity: "high", UserID: &userID, CreatedAt: now.Add(-time.Minute)}, 3)
+
+	as := NewAuditAlertScanner()
+	err := as.UpdatePolicy(&AuditAlertPolicy{
+		Enabled:         true,
+		IntervalSeconds: 60,
+		WindowMinutes:   15,
+		Rules: []AuditAlertRule{
+			{Name: "login_failures", Match: models.AuditLogMatch{EventType: "authentication", Status: "failure"}, GroupBy: "ip_address", Threshold: 5},
+			{Name: "denials", Match: models.AuditLogMatch{EventAction: "deny"}, GroupBy: "user_id", Threshold: 3},
+			{Name: "any_failure", Match: models.AuditLogMatch{Status: "failure"}, Threshold: 10},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+
+	var handled []AuditAlert
+	as.SetAlertHandler(func(a *AuditAlert) { handled = append(handled, *a) })
+
+	stats, err := as.Scan(database, now)
+	if err != nil {
+		t.Fatalf("Failed to scan: %v", err)
+	}
+	want := map[string]int64{
+		"login_failures/203.0.113.9": 5,
+		"denials/7":                  3,
+		"any_failure/":               10,
+	}
+	if len(stats.LastAlerts) != len(want) || len(handled) != len(want) {
+		t.Fatalf("Expected %d alerts, got %+v", len(want), stats.LastAlerts)
+	}
+	for _, alert := range stats.LastAlerts {
+		if count, ok := want[alert.Rule+"/"+alert.Group]; !ok || count != alert.Count {
+			t.Errorf("Unexpected alert %+v", alert)
+		}
+	}
+	if stats.Scans != 1 || stats.LastScan == nil {
+		t.Errorf("Expected the scan to be recorded, got %+v", stats)
+	}
+
+	// The same alerts aren't raised again within the window
+	stats, _ = as.Scan(database, now.Add(time.Minute))
+	if len(stats.LastAlerts) != 0 || stats.Suppressed != 3 {
+		t.Errorf("Expected the alerts to be suppressed, got %+v", stats)
+	}
+}
+
+func TestAuditAlertScanner_SkipsOverlappingScans(t *testing.T) {
+	database := setupRetentionTestDB(t)
+	createAuditLogs(t, database, models.SecurityAuditLog{EventType: "security", EventAction: "quarantine", Status: "success", Severity: "critical", CreatedAt: time.Now()}, 1)
+
+	as := NewAuditAlertScanner()
+	started, release := make(chan struct{}), make(chan struct{})
+	as.SetAlertHandler(func(a *AuditAlert) {
+		close(started)
+		<-release // hold the first scan open
+	})
+
+	done := make(chan error)
+	go func() {
+		_, err := as.Scan(database, time.Now())
+		done <- err
+	}()
+	<-started
+
+	if _, err := as.Scan(database, time.Now()); err != ErrAlertScanRunning {
+		t.Errorf("Expected a scan to be skipped while one is running, got %v", err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Expected the first scan to succeed: %v", err)
+	}
+
+	stats := as.Stats()
+	if stats.Scans != 1 || stats.Skipped != 1 {
+		t.Errorf("Expected one scan and one skipped, got %+v", stats)
+	}
+	if _, err := as.Scan(database, time.Now()); err != nil {
+		t.Errorf("Expected scans to run again once the previous one finished, got %v", err)
+	}
+}
+
+func TestAuditAlertPolicy_Validate(t *testing.T) {
+	for name, rule := range map[string]AuditAlertRule{
+		"no name":      {Threshold: 1},
+		"no threshold": {Name: "r"},
+		"bad group":    {Name: "r", Threshold: 1, GroupBy: "user_agent"},
+	} {
+		policy := DefaultAuditAlertPolicy()
+		policy.Rules = []AuditAlertRule{rule}
+		if err := policy.Validate(); err == nil {
+			t.Errorf("Expected a rule with %s to be rejected", name)
+		}
+	}
+
+	policy := DefaultAuditAlertPolicy()
+	policy.IntervalSeconds = 1
+	if err := policy.Validate(); err != ErrInvalidAuditAlertPolicy {
+		t.Errorf("Expected a too short interval to be rejected, got %v", err)
+	}
+}
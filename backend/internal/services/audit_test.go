@@ -0,0 +1,102 @@
+package services
+
+import (
+	"testing"
+
+	"golangmcp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupAuditTestLogger creates an audit logger backed by an in-memory database
+func setupAuditTestLogger(t *testing.T) (*AuditLogger, *gorm.DB) {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	if err := database.AutoMigrate(&models.SecurityAuditLog{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	return &AuditLogger{db: database, events: models.GetAuditEvents()}, database
+}
+
+func TestAuditLogger_SkipsEventsBelowSeverityFloor(t *testing.T) {
+	orig := GlobalAuditManager
+	t.Cleanup(func() { GlobalAuditManager = orig })
+	GlobalAuditManager = NewAuditManager()
+
+	config := DefaultAuditConfig()
+	config.LogLevel = "high"
+	if err := GlobalAuditManager.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	logger, database := setupAuditTestLogger(t)
+	userID := uint(1)
+
+	logger.LogLoginSuccess(userID, "127.0.0.1", "test-agent", "", "")                    // low
+	logger.LogLoginFailure("testuser", "127.0.0.1", "test-agent", "", nil)               // medium
+	logger.LogCommandExecution(userID, "ls", nil, 0, "127.0.0.1", "test-agent", "")      // high
+	logger.LogPermissionDenied(&userID, "file", "delete", "127.0.0.1", "test-agent", "") // high
+
+	var severities []string
+	database.Model(&models.SecurityAuditLog{}).Pluck("severity", &severities)
+	if len(severities) != 2 {
+		t.Fatalf("Expected only the 2 high severity events to be persisted, got %v", severities)
+	}
+	for _, severity := range severities {
+		if severity != "high" {
+			t.Errorf("Expected no events below the floor, got %q", severity)
+		}
+	}
+}
+
+func TestAuditManager_UpdateConfigRejectsInvalidFloor(t *testing.T) {
+	manager := NewAuditManager()
+
+	for _, level := range []string{"", "verbose", "critical"} {
+		config := DefaultAuditConfig()
+		config.LogLevel = level
+		if err := manager.UpdateConfig(config); err == nil {
+			t.Errorf("Expected log level %q to be rejected", level)
+		}
+	}
+
+	if !manager.ShouldLog("critical") || !manager.ShouldLog("high") {
+		t.Error("Expected high and critical events to always be logged")
+	}
+}
+
+func TestAuditLogger_LogsRegisteredEvent(t *testing.T) {
+	logger, database := setupAuditTestLogger(t)
+
+	if err := logger.LogEvent("invoice_approved", nil, "invoice", nil, "127.0.0.1", "test-agent", "", "", nil, "success"); err == nil {
+		t.Fatal("Expected an unregistered event to be rejected")
+	}
+
+	// Registered after the logger was created
+	err := models.RegisterAuditEvent("invoice_approved", models.AuditEvent{
+		Type:        "billing",
+		Action:      "approve",
+		Description: "Invoice approved for payment",
+		Severity:    "high",
+	})
+	if err != nil {
+		t.Fatalf("Failed to register audit event: %v", err)
+	}
+
+	userID := uint(1)
+	if err := logger.LogEvent("invoice_approved", &userID, "invoice", nil, "127.0.0.1", "test-agent", "", "", map[string]int{"amount": 100}, "success"); err != nil {
+		t.Fatalf("Failed to log registered event: %v", err)
+	}
+
+	var log models.SecurityAuditLog
+	if err := database.First(&log).Error; err != nil {
+		t.Fatalf("Expected the event to be persisted: %v", err)
+	}
+	if log.EventType != "billing" || log.EventAction != "approve" || log.Severity != "high" {
+		t.Errorf("Expected the registered definition to be applied, got %+v", log)
+	}
+}
@@ -0,0 +1,180 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golangmcp/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAuditWriterStopped = errors.New("audit writer is not running")
+	ErrAuditBufferFull    = errors.New("audit log buffer is full, entry dropped")
+)
+
+// AuditWriterConfig represents how buffered audit logs are written
+type AuditWriterConfig struct {
+	BufferSize    int           `json:"buffer_size"`    // entries waiting to be written; overflow is dropped
+	BatchSize     int           `json:"batch_size"`     // entries written per insert
+	FlushInterval time.Duration `json:"flush_interval"` // longest an entry waits for a full batch
+}
+
+// DefaultAuditWriterConfig returns default audit writer configuration
+func DefaultAuditWriterConfig() AuditWriterConfig {
+	return AuditWriterConfig{
+		BufferSize:    4096,
+		BatchSize:     100,
+		FlushInterval: time.Second,
+	}
+}
+
+// AuditWriterStats represents the activity of the audit writer
+type AuditWriterStats struct {
+	Running bool  `json:"running"`
+	Queued  int   `json:"queued"`
+	Written int64 `json:"written"`
+	Dropped int64 `json:"dropped"` // refused because the buffer was full
+	Failed  int64 `json:"failed"`  // lost to database errors
+}
+
+// AuditWriter writes audit logs off the request path. Entries are buffered and
+// inserted in batches by a single worker; when the buffer is full they are
+// dropped and counted rather than blocking the request.
+type AuditWriter struct {
+	config  AuditWriterConfig
+	entries chan models.SecurityAuditLog
+	done    chan struct{}
+	running bool
+	mutex   sync.RWMutex
+
+	written int64
+	dropped int64
+	failed  int64
+}
+
+// NewAuditWriter creates a new audit writer, which buffers nothing until started
+func NewAuditWriter(config AuditWriterConfig) *AuditWriter {
+	if config.BufferSize < 1 {
+		config.BufferSize = 1
+	}
+	if config.BatchSize < 1 {
+		config.BatchSize = 1
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	return &AuditWriter{config: config}
+}
+
+// Start starts the worker writing buffered entries to database
+func (aw *AuditWriter) Start(database *gorm.DB) {
+	aw.mutex.Lock()
+	defer aw.mutex.Unlock()
+
+	if aw.running {
+		return
+	}
+	aw.running = true
+	aw.entries = make(chan models.SecurityAuditLog, aw.config.BufferSize)
+	aw.done = make(chan struct{})
+	go aw.run(database, aw.entries, aw.done)
+}
+
+// Close stops accepting entries and returns once every buffered entry has been
+// written. Call it on shutdown so no accepted entry is lost.
+func (aw *AuditWriter) Close() {
+	aw.mutex.Lock()
+	if !aw.running {
+		aw.mutex.Unlock()
+		return
+	}
+	aw.running = false
+	close(aw.entries)
+	done := aw.done
+	aw.mutex.Unlock()
+
+	<-done
+}
+
+// Enqueue buffers an entry without waiting. It returns ErrAuditWriterStopped
+// when the writer is not running, so the caller can write the entry itself,
+// and ErrAuditBufferFull when the entry was dropped.
+func (aw *AuditWriter) Enqueue(entry *models.SecurityAuditLog) error {
+	aw.mutex.RLock()
+	defer aw.mutex.RUnlock()
+
+	if !aw.running {
+		return ErrAuditWriterStopped
+	}
+	select {
+	case aw.entries <- *entry:
+		return nil
+	default:
+		atomic.AddInt64(&aw.dropped, 1)
+		return ErrAuditBufferFull
+	}
+}
+
+// Stats returns the activity of the writer
+func (aw *AuditWriter) Stats() AuditWriterStats {
+	aw.mutex.RLock()
+	defer aw.mutex.RUnlock()
+
+	return AuditWriterStats{
+		Running: aw.running,
+		Queued:  len(aw.entries),
+		Written: atomic.LoadInt64(&aw.written),
+		Dropped: atomic.LoadInt64(&aw.dropped),
+		Failed:  atomic.LoadInt64(&aw.failed),
+	}
+}
+
+// run collects entries into batches, writing a batch when it is full, when the
+// flush interval passes, and when entries is closed
+func (aw *AuditWriter) run(database *gorm.DB, entries <-chan models.SecurityAuditLog, done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(aw.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.SecurityAuditLog, 0, aw.config.BatchSize)
+	for {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				aw.flush(database, batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= aw.config.BatchSize {
+				aw.flush(database, batch)
+				batch = make([]models.SecurityAuditLog, 0, aw.config.BatchSize)
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				aw.flush(database, batch)
+				batch = make([]models.SecurityAuditLog, 0, aw.config.BatchSize)
+			}
+		}
+	}
+}
+
+// flush writes a batch, counting it as written or failed
+func (aw *AuditWriter) flush(database *gorm.DB, batch []models.SecurityAuditLog) {
+	if len(batch) == 0 {
+		return
+	}
+	if err := models.CreateSecurityAuditLogs(database, batch, aw.config.BatchSize); err != nil {
+		atomic.AddInt64(&aw.failed, int64(len(batch)))
+		log.Printf("Warning: Failed to write %d audit logs: %v", len(batch), err)
+		return
+	}
+	atomic.AddInt64(&aw.written, int64(len(batch)))
+}
+
+// GlobalAuditWriter writes the entries of every AuditLogger once started
+var GlobalAuditWriter = NewAuditWriter(DefaultAuditWriterConfig())
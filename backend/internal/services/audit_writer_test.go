@@ -0,0 +1,138 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"golangmcp/internal/models"
+	"gorm.io/gorm"
+)
+
+// countAuditLogs returns the number of audit log entries in database
+func countAuditLogs(t *testing.T, database *gorm.DB) int64 {
+	var count int64
+	if err := database.Model(&models.SecurityAuditLog{}).Count(&count).Error; err != nil {
+		t.Fatalf("Failed to count audit logs: %v", err)
+	}
+	return count
+}
+
+func TestAuditWriter_WritesFullBatchesAndFlushesOnClose(t *testing.T) {
+	_, database := setupAuditTestLogger(t)
+
+	writer := NewAuditWriter(AuditWriterConfig{BufferSize: 100, BatchSize: 3, FlushInterval: time.Hour})
+	writer.Start(database)
+
+	for i := 0; i < 4; i++ {
+		if err := writer.Enqueue(&models.SecurityAuditLog{EventType: "test", EventAction: "batch", Severity: "low"}); err != nil {
+			t.Fatalf("Failed to enqueue entry %d: %v", i, err)
+		}
+	}
+
+	// The full batch is written right away, the partial one waits
+	deadline := time.Now().Add(2 * time.Second)
+	for writer.Stats().Written < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if count := countAuditLogs(t, database); count != 3 {
+		t.Fatalf("Expected the first batch of 3 to be written, got %d", count)
+	}
+
+	writer.Close()
+	if count := countAuditLogs(t, database); count != 4 {
+		t.Errorf("Expected Close to flush the partial batch, got %d entries", count)
+	}
+	if stats := writer.Stats(); stats.Running || stats.Written != 4 || stats.Dropped != 0 {
+		t.Errorf("Unexpected stats after close: %+v", stats)
+	}
+
+	if err := writer.Enqueue(&models.SecurityAuditLog{}); err != ErrAuditWriterStopped {
+		t.Errorf("Expected ErrAuditWriterStopped after close, got %v", err)
+	}
+}
+
+func TestAuditWriter_FlushesPartialBatchOnInterval(t *testing.T) {
+	_, database := setupAuditTestLogger(t)
+
+	writer := NewAuditWriter(AuditWriterConfig{BufferSize: 100, BatchSize: 50, FlushInterval: 20 * time.Millisecond})
+	writer.Start(database)
+	defer writer.Close()
+
+	writer.Enqueue(&models.SecurityAuditLog{EventType: "test", EventAction: "interval", Severity: "low"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for writer.Stats().Written < 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if count := countAuditLogs(t, database); count != 1 {
+		t.Errorf("Expected the entry to be written after the flush interval, got %d", count)
+	}
+}
+
+func TestAuditWriter_DropsOverflowWithoutBlocking(t *testing.T) {
+	_, database := setupAuditTestLogger(t)
+
+	// Hold the worker inside its first write so the buffer fills up
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	database.Callback().Create().Before("gorm:create").Register("test:block", func(*gorm.DB) {
+		select {
+		case entered <- struct{}{}:
+			<-release
+		default:
+		}
+	})
+
+	writer := NewAuditWriter(AuditWriterConfig{BufferSize: 2, BatchSize: 1, FlushInterval: time.Hour})
+	writer.Start(database)
+
+	entry := &models.SecurityAuditLog{EventType: "test", EventAction: "overflow", Severity: "low"}
+	if err := writer.Enqueue(entry); err != nil {
+		t.Fatalf("Failed to enqueue: %v", err)
+	}
+	<-entered
+
+	start := time.Now()
+	var dropped int
+	for i := 0; i < 5; i++ {
+		if err := writer.Enqueue(entry); err == ErrAuditBufferFull {
+			dropped++
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Expected enqueueing to never block, took %v", elapsed)
+	}
+	if dropped != 3 {
+		t.Errorf("Expected 3 of 5 entries to overflow a buffer of 2, got %d", dropped)
+	}
+	if stats := writer.Stats(); stats.Dropped != 3 || stats.Queued != 2 {
+		t.Errorf("Expected 3 dropped and 2 queued, got %+v", stats)
+	}
+
+	close(release)
+	writer.Close()
+	if count := countAuditLogs(t, database); count != 3 {
+		t.Errorf("Expected every accepted entry to be written, got %d", count)
+	}
+}
+
+func TestAuditLogger_UsesRunningAuditWriter(t *testing.T) {
+	logger, database := setupAuditTestLogger(t)
+
+	orig := GlobalAuditWriter
+	t.Cleanup(func() { GlobalAuditWriter = orig })
+	GlobalAuditWriter = NewAuditWriter(AuditWriterConfig{BufferSize: 10, BatchSize: 10, FlushInterval: time.Hour})
+	GlobalAuditWriter.Start(database)
+
+	if err := logger.LogLoginFailure("testuser", "127.0.0.1", "test-agent", "", nil); err != nil {
+		t.Fatalf("Failed to log event: %v", err)
+	}
+	if count := countAuditLogs(t, database); count != 0 {
+		t.Errorf("Expected the entry to be buffered, found %d written", count)
+	}
+
+	GlobalAuditWriter.Close()
+	if count := countAuditLogs(t, database); count != 1 {
+		t.Errorf("Expected the buffered entry to be written on close, got %d", count)
+	}
+}
@@ -2,7 +2,9 @@ package services
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 )
@@ -33,9 +35,7 @@ func NewCacheService(defaultTTL time.Duration) *CacheService {
 		ttl:   defaultTTL,
 	}
 	
-	// Start cleanup goroutine
-	go cache.startCleanup()
-	
+	// Expired items are removed on access, and periodically by CleanupExpired
 	return cache
 }
 
@@ -77,6 +77,21 @@ func (cs *CacheService) Delete(key string) {
 	delete(cs.items, key)
 }
 
+// DeletePrefix removes every value whose key starts with prefix and returns how many were removed
+func (cs *CacheService) DeletePrefix(prefix string) int {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+
+	removed := 0
+	for key := range cs.items {
+		if strings.HasPrefix(key, prefix) {
+			delete(cs.items, key)
+			removed++
+		}
+	}
+	return removed
+}
+
 // Clear removes all items from the cache
 func (cs *CacheService) Clear() {
 	cs.mutex.Lock()
@@ -107,26 +122,19 @@ func (cs *CacheService) GetStats() map[string]interface{} {
 	}
 }
 
-// startCleanup starts a goroutine to clean up expired items
-func (cs *CacheService) startCleanup() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-	
-	for range ticker.C {
-		cs.cleanupExpired()
-	}
-}
-
-// cleanupExpired removes expired items from the cache
-func (cs *CacheService) cleanupExpired() {
+// CleanupExpired removes expired items from the cache and returns how many were removed
+func (cs *CacheService) CleanupExpired() int {
 	cs.mutex.Lock()
 	defer cs.mutex.Unlock()
 	
+	removed := 0
 	for key, item := range cs.items {
 		if item.IsExpired() {
 			delete(cs.items, key)
+			removed++
 		}
 	}
+	return removed
 }
 
 // CacheableFunc represents a function that can be cached
@@ -279,6 +287,18 @@ func (cm *CacheManager) GetStats() map[string]interface{} {
 	return stats
 }
 
+// CleanupExpired removes expired items from all caches and returns how many were removed
+func (cm *CacheManager) CleanupExpired() int {
+	cm.mutex.RLock()
+	defer cm.mutex.RUnlock()
+	
+	removed := 0
+	for _, cache := range cm.caches {
+		removed += cache.CleanupExpired()
+	}
+	return removed
+}
+
 // ClearAll clears all caches
 func (cm *CacheManager) ClearAll() {
 	cm.mutex.Lock()
@@ -288,3 +308,72 @@ func (cm *CacheManager) ClearAll() {
 		cache.Clear()
 	}
 }
+
+// Cached endpoints with a configurable TTL
+const (
+	CacheEndpointUsers = "users"
+	CacheEndpointFiles = "files"
+)
+
+// MaxCacheTTL is the longest TTL an endpoint can be configured with
+const MaxCacheTTL = 24 * time.Hour
+
+var (
+	ErrUnknownCacheEndpoint = errors.New("unknown cache endpoint")
+	ErrInvalidCacheTTL      = errors.New("cache ttl must be between 1s and 24h")
+)
+
+// DefaultCacheTTLs returns the default TTL of each cached endpoint
+func DefaultCacheTTLs() map[string]time.Duration {
+	return map[string]time.Duration{
+		CacheEndpointUsers: 5 * time.Minute,
+		CacheEndpointFiles: 2 * time.Minute,
+	}
+}
+
+// CacheTTLManager manages how long each cached endpoint keeps its responses
+type CacheTTLManager struct {
+	ttls  map[string]time.Duration
+	mutex sync.RWMutex
+}
+
+// NewCacheTTLManager creates a new cache TTL manager with the default TTLs
+func NewCacheTTLManager() *CacheTTLManager {
+	return &CacheTTLManager{
+		ttls: DefaultCacheTTLs(),
+	}
+}
+
+// GetTTL returns the TTL of an endpoint
+func (tm *CacheTTLManager) GetTTL(endpoint string) time.Duration {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+	return tm.ttls[endpoint]
+}
+
+// SetTTL sets the TTL of a known endpoint
+func (tm *CacheTTLManager) SetTTL(endpoint string, ttl time.Duration) error {
+	tm.mutex.Lock()
+	defer tm.mutex.Unlock()
+
+	if _, exists := tm.ttls[endpoint]; !exists {
+		return ErrUnknownCacheEndpoint
+	}
+	if ttl < time.Second || ttl > MaxCacheTTL {
+		return ErrInvalidCacheTTL
+	}
+	tm.ttls[endpoint] = ttl
+	return nil
+}
+
+// GetAllTTLs returns the TTL of every endpoint, formatted as durations
+func (tm *CacheTTLManager) GetAllTTLs() map[string]string {
+	tm.mutex.RLock()
+	defer tm.mutex.RUnlock()
+
+	ttls := make(map[string]string, len(tm.ttls))
+	for endpoint, ttl := range tm.ttls {
+		ttls[endpoint] = ttl.String()
+	}
+	return ttls
+}
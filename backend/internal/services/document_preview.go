@@ -0,0 +1,272 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // thumbnails may be rendered as any of these formats
+	_ "image/jpeg"
+	"image/png"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PreviewDir holds the first-page thumbnails of uploaded documents, one
+// directory per user
+const PreviewDir = "./uploads/previews"
+
+// maxPreviewDimension bounds the size of a rendered thumbnail, so a renderer
+// can't hand back a decompression bomb
+const maxPreviewDimension = 4096
+
+var (
+	ErrInvalidDocumentPreviewPolicy = errors.New("document preview timeout and max bytes must be positive")
+	ErrInvalidPreviewName           = errors.New("invalid preview file name")
+	ErrInvalidThumbnail             = errors.New("thumbnail is not a valid image")
+	ErrDocumentInfected             = errors.New("document is infected")
+)
+
+// DocumentThumbnailer renders the first page of a document as an image.
+// Implementations wrap a renderer; none is configured by default.
+type DocumentThumbnailer interface {
+	Thumbnail(ctx context.Context, path, mimeType string) ([]byte, error)
+}
+
+// CommandThumbnailer renders thumbnails with an external program. Its arguments
+// may contain {input}, replaced by the document path, and {output}, replaced by
+// the path the program must write a PNG, JPEG or GIF image to, e.g.
+// convert {input}[0] -thumbnail 256x256 png:{output}. Only uploads the scanner
+// found clean are rendered, but the program should still run sandboxed.
+type CommandThumbnailer struct {
+	Command string
+	Args    []string
+}
+
+// Thumbnail runs the command and reads the image it wrote
+func (ct *CommandThumbnailer) Thumbnail(ctx context.Context, path, mimeType string) ([]byte, error) {
+	output, err := os.CreateTemp("", "preview-*")
+	if err != nil {
+		return nil, err
+	}
+	output.Close()
+	defer os.Remove(output.Name())
+
+	args := make([]string, len(ct.Args))
+	for i, arg := range ct.Args {
+		arg = strings.ReplaceAll(arg, "{input}", path)
+		args[i] = strings.ReplaceAll(arg, "{output}", output.Name())
+	}
+	if out, err := exec.CommandContext(ctx, ct.Command, args...).CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("%s: %v: %s", ct.Command, err, bytes.TrimSpace(out))
+	}
+	return os.ReadFile(output.Name())
+}
+
+// DocumentPreviewPolicy represents which uploaded documents get a thumbnail
+type DocumentPreviewPolicy struct {
+	Enabled        bool     `json:"enabled"`
+	MimeTypes      []string `json:"mime_types"`
+	TimeoutSeconds int      `json:"timeout_seconds"` // per document
+	MaxBytes       int64    `json:"max_bytes"`       // of a rendered thumbnail
+}
+
+// DefaultDocumentPreviewPolicy returns default document preview policy. Previews
+// are disabled until a thumbnailer is configured.
+func DefaultDocumentPreviewPolicy() *DocumentPreviewPolicy {
+	return &DocumentPreviewPolicy{
+		Enabled:        false,
+		MimeTypes:      []string{"application/pdf"},
+		TimeoutSeconds: 30,
+		MaxBytes:       2 * 1024 * 1024, // 2MB
+	}
+}
+
+// Validate checks the policy for invalid values
+func (dp *DocumentPreviewPolicy) Validate() error {
+	if dp.TimeoutSeconds < 1 || dp.MaxBytes <= 0 {
+		return ErrInvalidDocumentPreviewPolicy
+	}
+	return nil
+}
+
+// previews checks if documents of mimeType get a thumbnail
+func (dp *DocumentPreviewPolicy) previews(mimeType string) bool {
+	for _, allowed := range dp.MimeTypes {
+		if strings.EqualFold(allowed, mimeType) {
+			return true
+		}
+	}
+	return false
+}
+
+// DocumentPreviewManager renders thumbnails of uploaded documents in the
+// background and stores them as PNG under its directory, keyed by the owner
+// and stored file name of the document. Documents are scanned before they are
+// rendered; infected ones and those that fail to scan get no preview.
+type DocumentPreviewManager struct {
+	policy      *DocumentPreviewPolicy
+	thumbnailer DocumentThumbnailer
+	scanner     FileScanner
+	dir         string
+	running     sync.WaitGroup
+	mutex       sync.RWMutex
+}
+
+// NewDocumentPreviewManager creates a preview manager storing thumbnails in dir
+func NewDocumentPreviewManager(dir string) *DocumentPreviewManager {
+	return &DocumentPreviewManager{
+		policy:  DefaultDocumentPreviewPolicy(),
+		scanner: GlobalFileScanManager,
+		dir:     dir,
+	}
+}
+
+// SetThumbnailer sets the renderer of thumbnails, e.g. a CommandThumbnailer
+func (dm *DocumentPreviewManager) SetThumbnailer(thumbnailer DocumentThumbnailer) {
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+	dm.thumbnailer = thumbnailer
+}
+
+// SetScanner replaces the scanner documents are checked with before rendering,
+// GlobalFileScanManager by default
+func (dm *DocumentPreviewManager) SetScanner(scanner FileScanner) {
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+	dm.scanner = scanner
+}
+
+// GetPolicy returns a copy of the current document preview policy
+func (dm *DocumentPreviewManager) GetPolicy() DocumentPreviewPolicy {
+	dm.mutex.RLock()
+	defer dm.mutex.RUnlock()
+
+	policy := *dm.policy
+	policy.MimeTypes = append([]string(nil), dm.policy.MimeTypes...)
+	return policy
+}
+
+// UpdatePolicy validates and replaces the document preview policy
+func (dm *DocumentPreviewManager) UpdatePolicy(policy *DocumentPreviewPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	stored := *policy
+	stored.MimeTypes = append([]string(nil), policy.MimeTypes...)
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+	dm.policy = &stored
+	return nil
+}
+
+// Generate starts rendering the thumbnail of a document uploaded by userID and
+// stored as filename at path. It reports whether a preview will be generated;
+// failures are only logged, they never fail the upload.
+func (dm *DocumentPreviewManager) Generate(userID uint, filename, path, mimeType string) bool {
+	dm.mutex.RLock()
+	policy, thumbnailer, scanner := *dm.policy, dm.thumbnailer, dm.scanner
+	dm.mutex.RUnlock()
+
+	if !policy.Enabled || thumbnailer == nil || !policy.previews(mimeType) {
+		return false
+	}
+	previewPath, err := dm.PreviewPath(userID, filename)
+	if err != nil {
+		return false
+	}
+
+	dm.running.Add(1)
+	go func() {
+		defer dm.running.Done()
+		if err := dm.render(thumbnailer, scanner, &policy, path, mimeType, previewPath); err != nil {
+			log.Printf("Warning: Failed to generate preview of %s: %v", path, err)
+		}
+	}()
+	return true
+}
+
+// render scans the document, then renders one thumbnail and stores it
+// re-encoded as PNG, so whatever else the renderer wrote is never served
+func (dm *DocumentPreviewManager) render(thumbnailer DocumentThumbnailer, scanner FileScanner, policy *DocumentPreviewPolicy, path, mimeType, previewPath string) error {
+	result, err := scanner.Scan(path)
+	if err != nil {
+		return fmt.Errorf("scan failed: %w", err)
+	}
+	if result.Infected {
+		return fmt.Errorf("%w: %s", ErrDocumentInfected, result.Threat)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(policy.TimeoutSeconds)*time.Second)
+	defer cancel()
+
+	data, err := thumbnailer.Thumbnail(ctx, path, mimeType)
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > policy.MaxBytes {
+		return fmt.Errorf("thumbnail of %d bytes exceeds the limit of %d", len(data), policy.MaxBytes)
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width > maxPreviewDimension || config.Height > maxPreviewDimension {
+		return ErrInvalidThumbnail
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return ErrInvalidThumbnail
+	}
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, img); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(previewPath), 0755); err != nil {
+		return err
+	}
+	// Write then rename, so a preview is never served half written
+	tmp := previewPath + ".tmp"
+	if err := os.WriteFile(tmp, encoded.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, previewPath)
+}
+
+// PreviewPath returns where the thumbnail of a document uploaded by userID and
+// stored as filename is kept. Names leaving the user's directory are rejected.
+func (dm *DocumentPreviewManager) PreviewPath(userID uint, filename string) (string, error) {
+	name := filepath.Clean("/" + filepath.ToSlash(filename))
+	if name == "/" || strings.Contains(filename, "\\") || strings.Contains(filename, "..") {
+		return "", ErrInvalidPreviewName
+	}
+	return filepath.Join(dm.dir, strconv.FormatUint(uint64(userID), 10), filepath.FromSlash(name)+".png"), nil
+}
+
+// Remove removes the thumbnail of a document, e.g. one that has been
+// quarantined or deleted. A missing thumbnail is not an error.
+func (dm *DocumentPreviewManager) Remove(userID uint, filename string) error {
+	path, err := dm.PreviewPath(userID, filename)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Wait blocks until every started thumbnail has been rendered or failed
+func (dm *DocumentPreviewManager) Wait() {
+	dm.running.Wait()
+}
+
+// GlobalDocumentPreviews renders thumbnails of documents uploaded to the application into PreviewDir
+var GlobalDocumentPreviews = NewDocumentPreviewManager(PreviewDir)
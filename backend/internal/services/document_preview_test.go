@@ -0,0 +1,157 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fakeThumbnailer returns a fixed thumbnail and records the documents rendered
+type fakeThumbnailer struct {
+	data     []byte
+	err      error
+	rendered []string
+}
+
+func (ft *fakeThumbnailer) Thumbnail(ctx context.Context, path, mimeType string) ([]byte, error) {
+	ft.rendered = append(ft.rendered, path)
+	return ft.data, ft.err
+}
+
+// writeDocument writes a document to render under dir, returning its path
+func writeDocument(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create document directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write document: %v", err)
+	}
+	return path
+}
+
+// testThumbnail encodes a small PNG image
+func testThumbnail(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 10))); err != nil {
+		t.Fatalf("Failed to encode thumbnail: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestDocumentPreviewManager_StoresThumbnail(t *testing.T) {
+	dm := NewDocumentPreviewManager(t.TempDir())
+	thumbnailer := &fakeThumbnailer{data: testThumbnail(t)}
+	dm.SetThumbnailer(thumbnailer)
+	document := writeDocument(t, t.TempDir(), "2024/report.pdf", "%PDF-1.4")
+
+	// Disabled by default
+	if dm.Generate(1, "2024/report.pdf", document, "application/pdf") {
+		t.Fatal("Expected previews to be disabled by default")
+	}
+
+	policy := DefaultDocumentPreviewPolicy()
+	policy.Enabled = true
+	if err := dm.UpdatePolicy(policy); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+	if dm.Generate(1, "notes.txt", "/uploads/notes.txt", "text/plain") {
+		t.Error("Expected documents of other types to be skipped")
+	}
+	if !dm.Generate(1, "2024/report.pdf", document, "application/pdf") {
+		t.Fatal("Expected a preview to be generated")
+	}
+	dm.Wait()
+
+	path, err := dm.PreviewPath(1, "2024/report.pdf")
+	if err != nil {
+		t.Fatalf("Failed to get preview path: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Expected the preview to be stored: %v", err)
+	}
+	defer f.Close()
+	if config, err := png.DecodeConfig(f); err != nil || config.Width != 8 || config.Height != 10 {
+		t.Errorf("Expected the thumbnail as PNG, got %+v: %v", config, err)
+	}
+	if len(thumbnailer.rendered) != 1 {
+		t.Errorf("Expected one document to be rendered, got %v", thumbnailer.rendered)
+	}
+
+	if err := dm.Remove(1, "2024/report.pdf"); err != nil {
+		t.Fatalf("Failed to remove preview: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected the preview to be removed, got %v", err)
+	}
+}
+
+func TestDocumentPreviewManager_SkipsInfectedDocuments(t *testing.T) {
+	dm := NewDocumentPreviewManager(t.TempDir())
+	dm.SetScanner(NewSignatureScanner())
+	thumbnailer := &fakeThumbnailer{data: testThumbnail(t)}
+	dm.SetThumbnailer(thumbnailer)
+	policy := DefaultDocumentPreviewPolicy()
+	policy.Enabled = true
+	dm.UpdatePolicy(policy)
+
+	documents := t.TempDir()
+	for name, path := range map[string]string{
+		"infected.pdf": writeDocument(t, documents, "infected.pdf", "%PDF-1.4 "+string(eicarSignature)),
+		"missing.pdf":  filepath.Join(documents, "missing.pdf"), // fails to scan
+	} {
+		dm.Generate(1, name, path, "application/pdf")
+		dm.Wait()
+
+		preview, _ := dm.PreviewPath(1, name)
+		if _, err := os.Stat(preview); !os.IsNotExist(err) {
+			t.Errorf("Expected no preview for %s, got %v", name, err)
+		}
+	}
+	if len(thumbnailer.rendered) != 0 {
+		t.Errorf("Expected unscanned documents not to be rendered, got %v", thumbnailer.rendered)
+	}
+}
+
+func TestDocumentPreviewManager_RejectsBadThumbnails(t *testing.T) {
+	dir := t.TempDir()
+	dm := NewDocumentPreviewManager(dir)
+	policy := DefaultDocumentPreviewPolicy()
+	policy.Enabled = true
+	dm.UpdatePolicy(policy)
+	documents := t.TempDir()
+
+	for name, thumbnailer := range map[string]*fakeThumbnailer{
+		"not-an-image.pdf": {data: []byte("<html><script>alert(1)</script></html>")},
+		"failed.pdf":       {err: errors.New("renderer crashed")},
+	} {
+		dm.SetThumbnailer(thumbnailer)
+		dm.Generate(1, name, writeDocument(t, documents, name, "%PDF-1.4"), "application/pdf")
+		dm.Wait()
+
+		path, _ := dm.PreviewPath(1, name)
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected no preview for %s, got %v", name, err)
+		}
+	}
+}
+
+func TestDocumentPreviewManager_PreviewPathStaysInUserDirectory(t *testing.T) {
+	dm := NewDocumentPreviewManager("/previews")
+
+	path, err := dm.PreviewPath(7, "/2024/report.pdf")
+	if err != nil || path != filepath.Join("/previews", "7", "2024", "report.pdf.png") {
+		t.Errorf("Unexpected preview path %q: %v", path, err)
+	}
+	for _, name := range []string{"", "/", "../8/report.pdf", "2024/../../8/report.pdf", "..\\8\\report.pdf"} {
+		if _, err := dm.PreviewPath(7, name); err != ErrInvalidPreviewName {
+			t.Errorf("Expected %q to be rejected, got %v", name, err)
+		}
+	}
+}
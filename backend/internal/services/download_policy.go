@@ -0,0 +1,212 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DispositionAttachment makes browsers save the file instead of rendering it
+	DispositionAttachment = "attachment"
+	// DispositionInline lets browsers render the file
+	DispositionInline = "inline"
+)
+
+var (
+	ErrInvalidDisposition   = errors.New("disposition must be inline or attachment")
+	ErrInvalidDownloadLimit = errors.New("download rate and bandwidth limits cannot be negative")
+)
+
+// DownloadPolicy represents how user uploaded files are served
+type DownloadPolicy struct {
+	DefaultDisposition      string   `json:"default_disposition"`        // used when a request doesn't ask for one
+	AllowInline             bool     `json:"allow_inline"`               // false forces attachment for every request
+	InlineContentTypes      []string `json:"inline_content_types"`       // types that may be rendered inline
+	DownloadsPerMinute      int      `json:"downloads_per_minute"`       // per user, or per IP when anonymous; 0 = unlimited
+	BandwidthBytesPerSecond int64    `json:"bandwidth_bytes_per_second"` // per download, 0 = unlimited
+	DetectContentType       bool     `json:"detect_content_type"`        // re-detect the stored type from the content on serve
+}
+
+// DefaultDownloadPolicy returns default download policy. Only types browsers
+// can't execute script from are rendered inline; HTML and SVG never are.
+func DefaultDownloadPolicy() *DownloadPolicy {
+	return &DownloadPolicy{
+		DefaultDisposition: DispositionAttachment,
+		AllowInline:        true,
+		InlineContentTypes: []string{
+			"image/png",
+			"image/jpeg",
+			"image/gif",
+			"image/webp",
+			"text/plain",
+		},
+		DownloadsPerMinute: 60,
+		DetectContentType:  true,
+	}
+}
+
+// Validate checks the policy for invalid values
+func (dp *DownloadPolicy) Validate() error {
+	if !IsValidDisposition(dp.DefaultDisposition) {
+		return ErrInvalidDisposition
+	}
+	if dp.DownloadsPerMinute < 0 || dp.BandwidthBytesPerSecond < 0 {
+		return ErrInvalidDownloadLimit
+	}
+	return nil
+}
+
+// IsValidDisposition checks if a disposition is inline or attachment
+func IsValidDisposition(disposition string) bool {
+	return disposition == DispositionInline || disposition == DispositionAttachment
+}
+
+// ResolveDisposition returns the disposition to serve a file of contentType with.
+// An empty request uses the default; inline is downgraded to attachment unless
+// the policy allows it for the content type.
+func (dp *DownloadPolicy) ResolveDisposition(requested, contentType string) string {
+	disposition := requested
+	if disposition == "" {
+		disposition = dp.DefaultDisposition
+	}
+	if disposition == DispositionInline && (!dp.AllowInline || !dp.allowsInlineType(contentType)) {
+		return DispositionAttachment
+	}
+	return disposition
+}
+
+// genericContentType is the type that says nothing about the content
+const genericContentType = "application/octet-stream"
+
+// ResolveContentType returns the content type to serve a file with. The stored
+// type comes from the uploading client and may be wrong, so when the policy asks
+// for it the type is re-detected from head, the first bytes of the content. The
+// detected type replaces a missing or generic stored type, and one whose top-level
+// type the content contradicts (e.g. image/png for a PDF). Detection that only
+// finds text or binary data never overrides a stored type, which is usually the
+// more specific one (e.g. text/csv).
+func (dp *DownloadPolicy) ResolveContentType(stored string, head []byte) string {
+	stored = strings.TrimSpace(stored)
+	if !dp.DetectContentType || len(head) == 0 {
+		if stored == "" {
+			return genericContentType
+		}
+		return stored
+	}
+
+	detected := http.DetectContentType(head)
+	storedType, detectedType := mediaType(stored), mediaType(detected)
+	switch {
+	case storedType == "" || storedType == genericContentType:
+		return detected
+	case detectedType == genericContentType || detectedType == "text/plain":
+		return stored
+	case strings.Split(storedType, "/")[0] == strings.Split(detectedType, "/")[0]:
+		return stored
+	}
+	return detected
+}
+
+// mediaType returns the lower-cased media type of a content type, without parameters
+func mediaType(contentType string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+}
+
+// allowsInlineType checks if a content type may be rendered inline
+func (dp *DownloadPolicy) allowsInlineType(contentType string) bool {
+	media := mediaType(contentType)
+	for _, allowed := range dp.InlineContentTypes {
+		if strings.ToLower(allowed) == media {
+			return true
+		}
+	}
+	return false
+}
+
+// DownloadPolicyManager manages the download policy. The download rate limiter
+// is swapped together with the policy so both always agree on the limit.
+type DownloadPolicyManager struct {
+	policy  *DownloadPolicy
+	limiter *RateLimiter // nil = unlimited
+	mutex   sync.RWMutex
+}
+
+// NewDownloadPolicyManager creates a new download policy manager
+func NewDownloadPolicyManager() *DownloadPolicyManager {
+	policy := DefaultDownloadPolicy()
+	return &DownloadPolicyManager{
+		policy:  policy,
+		limiter: newDownloadLimiter(policy.DownloadsPerMinute),
+	}
+}
+
+// newDownloadLimiter creates the rate limiter for a downloads per minute limit
+func newDownloadLimiter(perMinute int) *RateLimiter {
+	if perMinute <= 0 {
+		return nil
+	}
+	return NewRateLimiter(perMinute, time.Minute)
+}
+
+// GetPolicy returns a copy of the current download policy
+func (dm *DownloadPolicyManager) GetPolicy() DownloadPolicy {
+	dm.mutex.RLock()
+	defer dm.mutex.RUnlock()
+
+	policy := *dm.policy
+	policy.InlineContentTypes = append([]string(nil), dm.policy.InlineContentTypes...)
+	return policy
+}
+
+// UpdatePolicy replaces the download policy. A new rate limiter is created only
+// when the rate changes, keeping existing counters otherwise.
+func (dm *DownloadPolicyManager) UpdatePolicy(policy *DownloadPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	// Keep a copy, so the caller changing policy later can't bypass the limiter swap
+	stored := *policy
+	stored.InlineContentTypes = append([]string(nil), policy.InlineContentTypes...)
+
+	dm.mutex.Lock()
+	defer dm.mutex.Unlock()
+	if stored.DownloadsPerMinute != dm.policy.DownloadsPerMinute {
+		dm.limiter = newDownloadLimiter(stored.DownloadsPerMinute)
+	}
+	dm.policy = &stored
+	return nil
+}
+
+// AllowDownload records a download by key if the rate allows it. When it does
+// not, the time the oldest download leaves the window is returned as well.
+func (dm *DownloadPolicyManager) AllowDownload(key string) (bool, time.Time) {
+	dm.mutex.RLock()
+	limiter := dm.limiter
+	dm.mutex.RUnlock()
+
+	if limiter == nil || limiter.Allow(key) {
+		return true, time.Time{}
+	}
+	return false, limiter.GetResetTime(key)
+}
+
+// Register schedules removal of download counters that left the rate window
+func (dm *DownloadPolicyManager) Register(scheduler *Scheduler) error {
+	return scheduler.Register("download_rate_limit_cleanup", FixedInterval(5*time.Minute), func() (interface{}, error) {
+		dm.mutex.RLock()
+		limiter := dm.limiter
+		dm.mutex.RUnlock()
+
+		if limiter != nil {
+			limiter.Cleanup()
+		}
+		return nil, nil
+	})
+}
+
+// GlobalDownloadPolicy is the policy applied when serving uploaded files
+var GlobalDownloadPolicy = NewDownloadPolicyManager()
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDownloadPolicy_RejectsNegativeLimits(t *testing.T) {
+	for _, policy := range []*DownloadPolicy{
+		{DefaultDisposition: DispositionAttachment, DownloadsPerMinute: -1},
+		{DefaultDisposition: DispositionAttachment, BandwidthBytesPerSecond: -1},
+	} {
+		if err := policy.Validate(); err != ErrInvalidDownloadLimit {
+			t.Errorf("Expected %+v to be rejected, got %v", policy, err)
+		}
+	}
+}
+
+func TestDownloadPolicyManager_AllowDownload(t *testing.T) {
+	dm := NewDownloadPolicyManager()
+	policy := dm.GetPolicy()
+	policy.DownloadsPerMinute = 1
+	if err := dm.UpdatePolicy(&policy); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+
+	if allowed, _ := dm.AllowDownload("user:1"); !allowed {
+		t.Fatal("Expected the first download to be allowed")
+	}
+	allowed, resetAt := dm.AllowDownload("user:1")
+	if allowed {
+		t.Fatal("Expected the second download to be refused")
+	}
+	if wait := time.Until(resetAt); wait <= 0 || wait > time.Minute {
+		t.Errorf("Expected the reset within the minute window, got %v", wait)
+	}
+
+	// 0 lifts the limit
+	policy.DownloadsPerMinute = 0
+	if err := dm.UpdatePolicy(&policy); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+	if allowed, _ := dm.AllowDownload("user:1"); !allowed {
+		t.Error("Expected downloads to be unlimited")
+	}
+}
+
+func TestDownloadPolicy_ResolveContentType(t *testing.T) {
+	pdf := []byte("%PDF-1.7\n")
+	csv := []byte("name,count\nfiles,2\n")
+	policy := DefaultDownloadPolicy()
+
+	tests := []struct {
+		name   string
+		stored string
+		head   []byte
+		want   string
+	}{
+		{"generic type replaced", "application/octet-stream", csv, "text/plain; charset=utf-8"},
+		{"missing type replaced", "", pdf, "application/pdf"},
+		{"contradicted type replaced", "image/png", pdf, "application/pdf"},
+		{"more specific type kept", "text/csv", csv, "text/csv"},
+		{"text detection never overrides", "application/json", []byte(`{"a":1}`), "application/json"},
+		{"unreadable content keeps the stored type", "text/csv", nil, "text/csv"},
+	}
+	for _, tt := range tests {
+		if got := policy.ResolveContentType(tt.stored, tt.head); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+
+	policy.DetectContentType = false
+	if got := policy.ResolveContentType("image/png", pdf); got != "image/png" {
+		t.Errorf("Expected the stored type with detection disabled, got %q", got)
+	}
+}
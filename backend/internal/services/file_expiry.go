@@ -0,0 +1,193 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"time"
+
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultFileExpiryBatchSize is the number of expired files deleted per job run
+const DefaultFileExpiryBatchSize = 100
+
+var ErrInvalidFileExpiryPolicy = errors.New("expiry hours cannot be negative and cleanup interval must be at least 1 minute")
+
+// FileExpiryPolicy represents how long uploads are kept before they are deleted.
+// Rules are in hours, 0 or absent = kept until deleted. When several rules
+// apply to an upload the shortest wins, and uploaders may only shorten it.
+type FileExpiryPolicy struct {
+	FileTypes              map[string]int `json:"file_types"` // by extension, e.g. "csv"
+	Roles                  map[string]int `json:"roles"`      // by the uploader's role
+	CleanupIntervalMinutes int            `json:"cleanup_interval_minutes"`
+}
+
+// DefaultFileExpiryPolicy returns default file expiry policy, keeping every
+// upload until an admin configures rules
+func DefaultFileExpiryPolicy() *FileExpiryPolicy {
+	return &FileExpiryPolicy{
+		FileTypes:              map[string]int{},
+		Roles:                  map[string]int{},
+		CleanupIntervalMinutes: 5,
+	}
+}
+
+// Validate checks the policy for invalid values
+func (fp *FileExpiryPolicy) Validate() error {
+	for _, rules := range []map[string]int{fp.FileTypes, fp.Roles} {
+		for _, hours := range rules {
+			if hours < 0 {
+				return ErrInvalidFileExpiryPolicy
+			}
+		}
+	}
+
+	if fp.CleanupIntervalMinutes < 1 {
+		return ErrInvalidFileExpiryPolicy
+	}
+
+	return nil
+}
+
+// ExpiredFile represents a file deleted because it expired
+type ExpiredFile struct {
+	FileID    uint      `json:"file_id"`
+	UserID    uint      `json:"user_id"`
+	Filename  string    `json:"filename"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// FileExpiryFailure represents an expired file that could not be deleted; it is retried on the next run
+type FileExpiryFailure struct {
+	FileID uint   `json:"file_id"`
+	Error  string `json:"error"`
+}
+
+// FileExpirySummary represents the result of an expiry run
+type FileExpirySummary struct {
+	Deleted []ExpiredFile       `json:"deleted"`
+	Failed  []FileExpiryFailure `json:"failed"`
+}
+
+// FileExpiryManager assigns expiry times to uploads and deletes files once they expire
+type FileExpiryManager struct {
+	policy   *FileExpiryPolicy
+	onExpiry func(*ExpiredFile)
+	mutex    sync.RWMutex
+}
+
+// NewFileExpiryManager creates a new file expiry manager
+func NewFileExpiryManager() *FileExpiryManager {
+	return &FileExpiryManager{
+		policy: DefaultFileExpiryPolicy(),
+	}
+}
+
+// GetPolicy returns the current file expiry policy
+func (em *FileExpiryManager) GetPolicy() FileExpiryPolicy {
+	em.mutex.RLock()
+	defer em.mutex.RUnlock()
+	return *em.policy
+}
+
+// UpdatePolicy validates and replaces the file expiry policy. Files uploaded
+// before keep the expiry they were given.
+func (em *FileExpiryManager) UpdatePolicy(policy *FileExpiryPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if policy.FileTypes == nil {
+		policy.FileTypes = map[string]int{}
+	}
+	if policy.Roles == nil {
+		policy.Roles = map[string]int{}
+	}
+
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	em.policy = policy
+	return nil
+}
+
+// SetExpiryHandler registers a callback invoked for every file deleted on expiry
+func (em *FileExpiryManager) SetExpiryHandler(handler func(*ExpiredFile)) {
+	em.mutex.Lock()
+	defer em.mutex.Unlock()
+	em.onExpiry = handler
+}
+
+// ExpiresAt returns when an upload of fileType by a user with role expires,
+// or nil if it never does. requestedHours is the uploader's own choice, 0 = none.
+func (em *FileExpiryManager) ExpiresAt(fileType, role string, requestedHours int, now time.Time) *time.Time {
+	policy := em.GetPolicy()
+
+	hours := 0
+	for _, candidate := range []int{policy.FileTypes[fileType], policy.Roles[role], requestedHours} {
+		if candidate > 0 && (hours == 0 || candidate < hours) {
+			hours = candidate
+		}
+	}
+	if hours == 0 {
+		return nil
+	}
+
+	expiresAt := now.Add(time.Duration(hours) * time.Hour)
+	return &expiresAt
+}
+
+// DeleteExpiredFiles deletes up to batchSize files whose expiry has passed,
+// from disk and from the database
+func (em *FileExpiryManager) DeleteExpiredFiles(database *gorm.DB, batchSize int) (*FileExpirySummary, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultFileExpiryBatchSize
+	}
+
+	files, err := models.GetExpiredFiles(database, time.Now(), batchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	em.mutex.RLock()
+	onExpiry := em.onExpiry
+	em.mutex.RUnlock()
+
+	summary := &FileExpirySummary{
+		Deleted: []ExpiredFile{},
+		Failed:  []FileExpiryFailure{},
+	}
+	for _, file := range files {
+		if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+			summary.Failed = append(summary.Failed, FileExpiryFailure{FileID: file.ID, Error: err.Error()})
+			continue
+		}
+		if err := models.DeleteFile(database, file.ID); err != nil {
+			summary.Failed = append(summary.Failed, FileExpiryFailure{FileID: file.ID, Error: err.Error()})
+			continue
+		}
+
+		expired := ExpiredFile{FileID: file.ID, UserID: file.UserID, Filename: file.OriginalName, ExpiresAt: *file.ExpiresAt}
+		summary.Deleted = append(summary.Deleted, expired)
+		if onExpiry != nil {
+			onExpiry(&expired)
+		}
+	}
+
+	return summary, nil
+}
+
+// Register schedules deletion of expired files against the application
+// database, at the interval the current policy sets
+func (em *FileExpiryManager) Register(scheduler *Scheduler) error {
+	interval := func() time.Duration {
+		return time.Duration(em.GetPolicy().CleanupIntervalMinutes) * time.Minute
+	}
+	return scheduler.Register("file_expiry", interval, func() (interface{}, error) {
+		return em.DeleteExpiredFiles(db.DB, DefaultFileExpiryBatchSize)
+	})
+}
+
+// GlobalFileExpiryManager expires uploads to the main file system
+var GlobalFileExpiryManager = NewFileExpiryManager()
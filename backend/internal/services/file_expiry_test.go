@@ -0,0 +1,116 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golangmcp/internal/models"
+)
+
+func TestFileExpiryManager_ExpiresAt(t *testing.T) {
+	em := NewFileExpiryManager()
+	if err := em.UpdatePolicy(&FileExpiryPolicy{
+		FileTypes:              map[string]int{"tmp": 24, "csv": 72},
+		Roles:                  map[string]int{"guest": 48},
+		CleanupIntervalMinutes: 5,
+	}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		name      string
+		fileType  string
+		role      string
+		requested int
+		wantHours int // 0 = never expires
+	}{
+		{"no rule", "txt", "user", 0, 0},
+		{"file type rule", "tmp", "user", 0, 24},
+		{"role rule", "txt", "guest", 0, 48},
+		{"shortest rule wins", "csv", "guest", 0, 48},
+		{"uploader shortens", "tmp", "user", 2, 2},
+		{"uploader cannot extend", "tmp", "user", 100, 24},
+		{"uploader sets expiry", "txt", "user", 5, 5},
+		{"negative request ignored", "txt", "user", -1, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expiresAt := em.ExpiresAt(tt.fileType, tt.role, tt.requested, now)
+			if tt.wantHours == 0 {
+				if expiresAt != nil {
+					t.Errorf("Expected no expiry, got %v", expiresAt)
+				}
+				return
+			}
+			if expiresAt == nil || !expiresAt.Equal(now.Add(time.Duration(tt.wantHours)*time.Hour)) {
+				t.Errorf("Expected expiry in %d hours, got %v", tt.wantHours, expiresAt)
+			}
+		})
+	}
+
+	if err := em.UpdatePolicy(&FileExpiryPolicy{FileTypes: map[string]int{"tmp": -1}, CleanupIntervalMinutes: 5}); err != ErrInvalidFileExpiryPolicy {
+		t.Errorf("Expected ErrInvalidFileExpiryPolicy, got %v", err)
+	}
+}
+
+func TestFileExpiryManager_DeletesExpiredFiles(t *testing.T) {
+	database := setupRetentionTestDB(t)
+	dir := t.TempDir()
+
+	create := func(name string, expiresAt *time.Time) *models.File {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write file: %v", err)
+		}
+		file := &models.File{Filename: name, OriginalName: name, FileType: "tmp", MimeType: "text/plain",
+			Path: path, Hash: name, UserID: 7, ExpiresAt: expiresAt}
+		if err := models.CreateFile(database, file); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+		return file
+	}
+
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	expired := create("expired.tmp", &past)
+	pending := create("pending.tmp", &future)
+	kept := create("kept.txt", nil)
+
+	em := NewFileExpiryManager()
+	var notified []ExpiredFile
+	em.SetExpiryHandler(func(f *ExpiredFile) { notified = append(notified, *f) })
+
+	summary, err := em.DeleteExpiredFiles(database, 10)
+	if err != nil {
+		t.Fatalf("Failed to delete expired files: %v", err)
+	}
+	if len(summary.Deleted) != 1 || summary.Deleted[0].FileID != expired.ID || len(summary.Failed) != 0 {
+		t.Fatalf("Expected only the expired file to be deleted, got %+v", summary)
+	}
+	if len(notified) != 1 || notified[0].UserID != 7 || notified[0].Filename != "expired.tmp" {
+		t.Errorf("Expected the deletion to be reported, got %+v", notified)
+	}
+
+	if _, err := os.Stat(expired.Path); !os.IsNotExist(err) {
+		t.Error("Expected the expired file to be removed from disk")
+	}
+	if _, err := models.GetFileByID(database, expired.ID); err == nil {
+		t.Error("Expected the expired file record to be deleted")
+	}
+	for _, file := range []*models.File{pending, kept} {
+		if _, err := os.Stat(file.Path); err != nil {
+			t.Errorf("Expected %s to stay on disk, got %v", file.Filename, err)
+		}
+		if _, err := models.GetFileByID(database, file.ID); err != nil {
+			t.Errorf("Expected %s to stay in the database, got %v", file.Filename, err)
+		}
+	}
+
+	// Nothing left to do on the next run
+	if summary, _ := em.DeleteExpiredFiles(database, 10); len(summary.Deleted) != 0 {
+		t.Errorf("Expected no further deletions, got %+v", summary)
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"os"
+	"sort"
+
+	"golangmcp/internal/models"
+	"gorm.io/gorm"
+)
+
+// DefaultRehashBatchSize is the number of files loaded and hashed per batch
+const DefaultRehashBatchSize = 100
+
+// RehashOptions represents options for RecomputeFileHashes
+type RehashOptions struct {
+	Merge     bool `json:"merge"` // remove duplicate records of the same owner
+	BatchSize int  `json:"batch_size"`
+}
+
+// DuplicateGroup represents files with identical content
+type DuplicateGroup struct {
+	Hash    string `json:"hash"`
+	FileIDs []uint `json:"file_ids"`
+	KeptID  uint   `json:"kept_id"` // oldest record, which holds the hash
+	Merged  []uint `json:"merged,omitempty"`
+	Skipped string `json:"skipped,omitempty"` // why the group was not merged
+}
+
+// RehashFailure represents a file whose hash could not be recomputed or stored
+type RehashFailure struct {
+	FileID uint   `json:"file_id"`
+	Error  string `json:"error"`
+}
+
+// RehashSummary represents the result of a hash recomputation
+type RehashSummary struct {
+	Scanned    int              `json:"scanned"`
+	Updated    int              `json:"updated"`
+	Missing    []uint           `json:"missing"` // records whose content is gone from disk
+	Duplicates []DuplicateGroup `json:"duplicates"`
+	Merged     int              `json:"merged"`
+	Failed     []RehashFailure  `json:"failed"`
+}
+
+// RecomputeFileHashes recomputes the md5 hash of every file from its content on
+// disk, stores hashes that drifted and reports records sharing the same content.
+// With Merge, duplicates owned by the same user are folded into the oldest record.
+// Files are read in batches and each change is committed on its own, so no
+// transaction spans the whole table.
+func RecomputeFileHashes(database *gorm.DB, options RehashOptions) (*RehashSummary, error) {
+	if options.BatchSize <= 0 {
+		options.BatchSize = DefaultRehashBatchSize
+	}
+
+	summary := &RehashSummary{
+		Missing:    []uint{},
+		Duplicates: []DuplicateGroup{},
+		Failed:     []RehashFailure{},
+	}
+
+	// Hash all files first, so duplicates are known before anything is updated
+	files := make(map[uint]models.File)
+	byHash := make(map[string][]uint)
+
+	var batch []models.File
+	err := database.Order("id").FindInBatches(&batch, options.BatchSize, func(tx *gorm.DB, _ int) error {
+		for _, file := range batch {
+			summary.Scanned++
+
+			hash, err := hashFileContent(file.Path)
+			if os.IsNotExist(err) {
+				summary.Missing = append(summary.Missing, file.ID)
+				continue
+			}
+			if err != nil {
+				summary.Failed = append(summary.Failed, RehashFailure{FileID: file.ID, Error: err.Error()})
+				continue
+			}
+
+			files[file.ID] = file
+			byHash[hash] = append(byHash[hash], file.ID)
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, 0, len(byHash))
+	for hash := range byHash {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+
+	// Only the oldest record of each group gets the hash, which always satisfies
+	// the per-owner uniqueness of the column
+	var updates []models.File
+	for _, hash := range hashes {
+		ids := byHash[hash]
+		kept := files[ids[0]]
+
+		if len(ids) > 1 {
+			group := DuplicateGroup{Hash: hash, FileIDs: ids, KeptID: kept.ID}
+			if options.Merge {
+				mergeDuplicates(database, files, &group, summary)
+			}
+			summary.Duplicates = append(summary.Duplicates, group)
+		}
+
+		if kept.Hash != hash {
+			kept.Hash = hash
+			updates = append(updates, kept)
+		}
+	}
+
+	// A new hash may still be held by another record whose own update comes
+	// later, so retry failed updates as long as some of them succeed
+	for len(updates) > 0 {
+		var retry []models.File
+		lastErr := make(map[uint]error)
+		for _, file := range updates {
+			if err := database.Model(&models.File{}).Where("id = ?", file.ID).Update("hash", file.Hash).Error; err != nil {
+				retry = append(retry, file)
+				lastErr[file.ID] = err
+				continue
+			}
+			summary.Updated++
+		}
+
+		if len(retry) == len(updates) {
+			for _, file := range retry {
+				summary.Failed = append(summary.Failed, RehashFailure{FileID: file.ID, Error: lastErr[file.ID].Error()})
+			}
+			break
+		}
+		updates = retry
+	}
+
+	return summary, nil
+}
+
+// mergeDuplicates removes the duplicate records of a group in favour of the
+// kept record, moving their access logs over. Groups spanning several owners
+// are left alone, since merging would change who owns the content.
+func mergeDuplicates(database *gorm.DB, files map[uint]models.File, group *DuplicateGroup, summary *RehashSummary) {
+	kept := files[group.KeptID]
+	for _, id := range group.FileIDs[1:] {
+		if files[id].UserID != kept.UserID {
+			group.Skipped = "duplicates belong to different users"
+			return
+		}
+	}
+
+	for _, id := range group.FileIDs[1:] {
+		duplicate := files[id]
+		err := database.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.FileAccessLog{}).Where("file_id = ?", duplicate.ID).Update("file_id", kept.ID).Error; err != nil {
+				return err
+			}
+			// Hard delete, so the duplicate's (possibly identical) hash is released
+			return tx.Unscoped().Delete(&models.File{}, duplicate.ID).Error
+		})
+		if err != nil {
+			summary.Failed = append(summary.Failed, RehashFailure{FileID: duplicate.ID, Error: err.Error()})
+			continue
+		}
+
+		if duplicate.Path != kept.Path {
+			os.Remove(duplicate.Path)
+		}
+		group.Merged = append(group.Merged, duplicate.ID)
+		summary.Merged++
+	}
+}
+
+// hashFileContent returns the hex md5 hash of a file's content, as computed on upload
+func hashFileContent(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := md5.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// isContentHash reports whether a stored hash is a hex md5 hash hashFileContent
+// can check. Image uploads store hashes computed otherwise.
+func isContentHash(hash string) bool {
+	decoded, err := hex.DecodeString(hash)
+	return err == nil && len(decoded) == md5.Size
+}
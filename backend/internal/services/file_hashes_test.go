@@ -0,0 +1,146 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golangmcp/internal/models"
+	"gorm.io/gorm"
+)
+
+// seedFile writes content to disk and creates a file record owned by user 1 with the given stored hash
+func seedFile(t *testing.T, database *gorm.DB, dir string, name, content, storedHash string) *models.File {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	file := &models.File{Filename: name, OriginalName: name, FileType: "txt", MimeType: "text/plain", Path: path, Hash: storedHash, UserID: 1}
+	if err := database.Create(file).Error; err != nil {
+		t.Fatalf("Failed to create file: %v", err)
+	}
+	return file
+}
+
+func TestRecomputeFileHashes_ReportsDuplicates(t *testing.T) {
+	database := setupRetentionTestDB(t)
+	dir := t.TempDir()
+
+	original := seedFile(t, database, dir, "a.txt", "same content", "stale-1")
+	duplicate := seedFile(t, database, dir, "b.txt", "same content", "stale-2")
+	unique := seedFile(t, database, dir, "c.txt", "other content", "stale-3")
+	missing := &models.File{Filename: "gone.txt", OriginalName: "gone.txt", Path: filepath.Join(dir, "gone.txt"), Hash: "stale-4", UserID: 1}
+	database.Create(missing)
+
+	summary, err := RecomputeFileHashes(database, RehashOptions{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("Failed to recompute hashes: %v", err)
+	}
+
+	if summary.Scanned != 4 || len(summary.Missing) != 1 || summary.Missing[0] != missing.ID {
+		t.Errorf("Unexpected scan summary: %+v", summary)
+	}
+	if len(summary.Duplicates) != 1 {
+		t.Fatalf("Expected 1 duplicate group, got %+v", summary.Duplicates)
+	}
+
+	group := summary.Duplicates[0]
+	if group.KeptID != original.ID || len(group.FileIDs) != 2 || group.FileIDs[1] != duplicate.ID || len(group.Merged) != 0 {
+		t.Errorf("Unexpected duplicate group: %+v", group)
+	}
+
+	// The kept record and the unique file get their real hashes
+	if summary.Updated != 2 {
+		t.Errorf("Expected 2 updated hashes, got %d (failed: %+v)", summary.Updated, summary.Failed)
+	}
+	var reloaded models.File
+	database.First(&reloaded, unique.ID)
+	if expected, _ := hashFileContent(unique.Path); reloaded.Hash != expected {
+		t.Errorf("Expected unique file to get its content hash, got %q", reloaded.Hash)
+	}
+
+	var count int64
+	database.Model(&models.File{}).Count(&count)
+	if count != 4 {
+		t.Errorf("Expected no records removed without merge, got %d", count)
+	}
+}
+
+func TestRecomputeFileHashes_MergesSameOwnerDuplicates(t *testing.T) {
+	database := setupRetentionTestDB(t)
+	dir := t.TempDir()
+
+	original := seedFile(t, database, dir, "a.txt", "same content", "stale-1")
+	duplicate := seedFile(t, database, dir, "b.txt", "same content", "stale-2")
+	otherOwnerA := seedFile(t, database, dir, "c.txt", "shared content", "stale-3")
+	otherOwnerB := seedFile(t, database, dir, "d.txt", "shared content", "stale-4")
+	database.Model(otherOwnerB).Update("user_id", 2)
+	database.Create(&models.FileAccessLog{FileID: duplicate.ID, UserID: 1, Action: "view"})
+
+	summary, err := RecomputeFileHashes(database, RehashOptions{Merge: true})
+	if err != nil {
+		t.Fatalf("Failed to recompute hashes: %v", err)
+	}
+
+	if summary.Merged != 1 || len(summary.Duplicates) != 2 {
+		t.Fatalf("Unexpected merge summary: %+v", summary)
+	}
+
+	for _, group := range summary.Duplicates {
+		switch group.KeptID {
+		case original.ID:
+			if len(group.Merged) != 1 || group.Merged[0] != duplicate.ID {
+				t.Errorf("Expected duplicate to be merged, got %+v", group)
+			}
+		case otherOwnerA.ID:
+			if len(group.Merged) != 0 || group.Skipped == "" {
+				t.Errorf("Expected cross-owner duplicates to be skipped, got %+v", group)
+			}
+		}
+	}
+
+	if err := database.Unscoped().First(&models.File{}, duplicate.ID).Error; err != gorm.ErrRecordNotFound {
+		t.Errorf("Expected merged record to be removed, got %v", err)
+	}
+	if _, err := os.Stat(duplicate.Path); !os.IsNotExist(err) {
+		t.Error("Expected merged file to be removed from disk")
+	}
+
+	var logs []models.FileAccessLog
+	database.Find(&logs)
+	if len(logs) != 1 || logs[0].FileID != original.ID {
+		t.Errorf("Expected access log to move to the kept record, got %+v", logs)
+	}
+
+	hash, _ := hashFileContent(original.Path)
+	var reloaded models.File
+	database.First(&reloaded, original.ID)
+	if reloaded.Hash != hash {
+		t.Errorf("Expected kept record to hold the content hash, got %q", reloaded.Hash)
+	}
+}
+
+func TestRecomputeFileHashes_SwappedHashes(t *testing.T) {
+	database := setupRetentionTestDB(t)
+	dir := t.TempDir()
+
+	first := seedFile(t, database, dir, "a.txt", "first", "placeholder")
+	firstHash, _ := hashFileContent(first.Path)
+	second := seedFile(t, database, dir, "b.txt", "second", firstHash)
+
+	// second holds first's hash until it is updated itself
+	summary, err := RecomputeFileHashes(database, RehashOptions{})
+	if err != nil {
+		t.Fatalf("Failed to recompute hashes: %v", err)
+	}
+	if summary.Updated != 2 || len(summary.Failed) != 0 {
+		t.Fatalf("Expected both hashes to be updated, got %+v", summary)
+	}
+
+	var reloaded models.File
+	database.First(&reloaded, second.ID)
+	if expected, _ := hashFileContent(second.Path); reloaded.Hash != expected {
+		t.Errorf("Expected second file to hold its own hash, got %q", reloaded.Hash)
+	}
+}
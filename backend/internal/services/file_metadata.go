@@ -0,0 +1,140 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golangmcp/internal/models"
+)
+
+var (
+	ErrInvalidFileMetadataPolicy = errors.New("file metadata limits must be at least 1")
+	ErrFileDescriptionTooLong    = errors.New("description is too long")
+	ErrInvalidFileTags           = errors.New("invalid tags")
+)
+
+// FileMetadataPolicy represents the limits of the description and tags users
+// store with their files. Lengths are counted in characters.
+type FileMetadataPolicy struct {
+	MaxDescriptionLength int `json:"max_description_length"`
+	MaxTags              int `json:"max_tags"`
+	MaxTagLength         int `json:"max_tag_length"`
+}
+
+// DefaultFileMetadataPolicy returns default file metadata policy
+func DefaultFileMetadataPolicy() *FileMetadataPolicy {
+	return &FileMetadataPolicy{
+		MaxDescriptionLength: models.MaxFileDescriptionLength,
+		MaxTags:              models.MaxFileTags,
+		MaxTagLength:         models.MaxFileTagLength,
+	}
+}
+
+// Validate checks the policy for invalid values
+func (fp *FileMetadataPolicy) Validate() error {
+	if fp.MaxDescriptionLength < 1 || fp.MaxTags < 1 || fp.MaxTagLength < 1 {
+		return ErrInvalidFileMetadataPolicy
+	}
+	return nil
+}
+
+// FileMetadataManager cleans and checks file descriptions and tags against the
+// file metadata policy
+type FileMetadataManager struct {
+	policy *FileMetadataPolicy
+	mutex  sync.RWMutex
+}
+
+// NewFileMetadataManager creates a new file metadata manager
+func NewFileMetadataManager() *FileMetadataManager {
+	return &FileMetadataManager{
+		policy: DefaultFileMetadataPolicy(),
+	}
+}
+
+// GetPolicy returns a copy of the current file metadata policy
+func (fm *FileMetadataManager) GetPolicy() FileMetadataPolicy {
+	fm.mutex.RLock()
+	defer fm.mutex.RUnlock()
+	return *fm.policy
+}
+
+// UpdatePolicy validates and replaces the file metadata policy
+func (fm *FileMetadataManager) UpdatePolicy(policy *FileMetadataPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	stored := *policy
+
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+	fm.policy = &stored
+	return nil
+}
+
+// Description strips control characters other than newlines and tabs from a
+// description and trims it, rejecting it if it is longer than the policy allows
+func (fm *FileMetadataManager) Description(description string) (string, error) {
+	policy := fm.GetPolicy()
+
+	description = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, description))
+	if length := len([]rune(description)); length > policy.MaxDescriptionLength {
+		return "", fmt.Errorf("%w: %d characters, at most %d are allowed", ErrFileDescriptionTooLong, length, policy.MaxDescriptionLength)
+	}
+	return description, nil
+}
+
+// Tags strips control characters from tags and trims them, rejecting empty
+// tags and more or longer tags than the policy allows
+func (fm *FileMetadataManager) Tags(tags []string) ([]string, error) {
+	policy := fm.GetPolicy()
+
+	if len(tags) > policy.MaxTags {
+		return nil, fmt.Errorf("%w: %d tags, at most %d are allowed", ErrInvalidFileTags, len(tags), policy.MaxTags)
+	}
+	cleaned := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) {
+				return -1
+			}
+			return r
+		}, tag))
+		if tag == "" {
+			return nil, fmt.Errorf("%w: tags cannot be empty", ErrInvalidFileTags)
+		}
+		if length := len([]rune(tag)); length > policy.MaxTagLength {
+			return nil, fmt.Errorf("%w: tag of %d characters, at most %d are allowed", ErrInvalidFileTags, length, policy.MaxTagLength)
+		}
+		cleaned = append(cleaned, tag)
+	}
+	return cleaned, nil
+}
+
+// TagList checks a comma-separated list of tags as sent by upload forms,
+// returning the cleaned list. Blank entries are dropped.
+func (fm *FileMetadataManager) TagList(list string) (string, error) {
+	var tags []string
+	for _, tag := range strings.Split(list, ",") {
+		if strings.TrimSpace(tag) != "" {
+			tags = append(tags, tag)
+		}
+	}
+	cleaned, err := fm.Tags(tags)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(cleaned, ","), nil
+}
+
+// GlobalFileMetadata checks the metadata users store with their files
+var GlobalFileMetadata = NewFileMetadataManager()
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFileMetadataManager_DescriptionLengthBoundary(t *testing.T) {
+	fm := NewFileMetadataManager()
+	if err := fm.UpdatePolicy(&FileMetadataPolicy{MaxDescriptionLength: 10, MaxTags: 2, MaxTagLength: 5}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+
+	// Characters are counted, not bytes
+	atLimit := strings.Repeat("é", 10)
+	if got, err := fm.Description(atLimit); err != nil || got != atLimit {
+		t.Errorf("Expected a description at the limit to be accepted, got %q, %v", got, err)
+	}
+	if _, err := fm.Description(atLimit + "a"); !errors.Is(err, ErrFileDescriptionTooLong) {
+		t.Errorf("Expected a description over the limit to be rejected, got %v", err)
+	}
+
+	// Control characters are stripped before counting, line breaks are kept
+	if got, err := fm.Description(" a\x00b\nc\x1bd\x7f "); err != nil || got != "ab\ncd" {
+		t.Errorf("Expected control characters to be stripped, got %q, %v", got, err)
+	}
+	if got, err := fm.Description(strings.Repeat("a", 10) + "\x00\x01"); err != nil || len(got) != 10 {
+		t.Errorf("Expected stripped characters not to count, got %q, %v", got, err)
+	}
+}
+
+func TestFileMetadataManager_TagLimits(t *testing.T) {
+	fm := NewFileMetadataManager()
+	if err := fm.UpdatePolicy(&FileMetadataPolicy{MaxDescriptionLength: 10, MaxTags: 2, MaxTagLength: 5}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+
+	if got, err := fm.Tags([]string{" abcde ", "q\x003"}); err != nil || strings.Join(got, ",") != "abcde,q3" {
+		t.Errorf("Expected tags at the limits to be accepted and cleaned, got %v, %v", got, err)
+	}
+	for name, tags := range map[string][]string{
+		"too many": {"a", "b", "c"},
+		"too long": {"abcdef"},
+		"empty":    {"a", "\x00 "},
+	} {
+		if _, err := fm.Tags(tags); !errors.Is(err, ErrInvalidFileTags) {
+			t.Errorf("Expected %s tags to be rejected, got %v", name, err)
+		}
+	}
+
+	// Upload forms send a comma-separated list
+	if got, err := fm.TagList("tax, q3,,"); err != nil || got != "tax,q3" {
+		t.Errorf("Expected a cleaned tag list, got %q, %v", got, err)
+	}
+	if _, err := fm.TagList("a,b,c"); err == nil {
+		t.Error("Expected a tag list over the limit to be rejected")
+	}
+
+	if err := fm.UpdatePolicy(&FileMetadataPolicy{MaxDescriptionLength: 10, MaxTags: 0, MaxTagLength: 5}); err != ErrInvalidFileMetadataPolicy {
+		t.Errorf("Expected ErrInvalidFileMetadataPolicy, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Supported upload naming strategies
+const (
+	NamingStrategyUUID = "uuid" // 1b4e28ba-2fa1-41d2-883f-0016d3cca427.png
+	NamingStrategyHash = "hash" // 9f86d081884c7d659a2feaa0c55ad015.png
+	NamingStrategyDate = "date" // 2006/01/02/1b4e28ba-2fa1-41d2-883f-0016d3cca427.png
+)
+
+var ErrUnknownNamingStrategy = errors.New("unknown naming strategy")
+
+// FileNamingConfig represents upload file naming configuration
+type FileNamingConfig struct {
+	Strategy string `json:"strategy"` // uuid, hash, date
+}
+
+// DefaultFileNamingConfig returns default file naming configuration
+func DefaultFileNamingConfig() *FileNamingConfig {
+	return &FileNamingConfig{
+		Strategy: NamingStrategyUUID,
+	}
+}
+
+// FileNamer generates storage names for uploaded files. Generated names never
+// contain user IDs or the original filename, only a sanitized extension.
+type FileNamer struct {
+	config *FileNamingConfig
+	mutex  sync.RWMutex
+}
+
+// NewFileNamer creates a new file namer
+func NewFileNamer() *FileNamer {
+	return &FileNamer{
+		config: DefaultFileNamingConfig(),
+	}
+}
+
+// GetConfig returns the current naming configuration
+func (fn *FileNamer) GetConfig() FileNamingConfig {
+	fn.mutex.RLock()
+	defer fn.mutex.RUnlock()
+	return *fn.config
+}
+
+// UpdateConfig updates the naming configuration
+func (fn *FileNamer) UpdateConfig(config *FileNamingConfig) error {
+	if !IsValidNamingStrategy(config.Strategy) {
+		return ErrUnknownNamingStrategy
+	}
+
+	fn.mutex.Lock()
+	defer fn.mutex.Unlock()
+	fn.config = config
+	return nil
+}
+
+// Generate returns a unique name for an uploaded file using the configured strategy.
+// Names may contain forward slashes (date strategy) and are relative to the upload directory.
+func (fn *FileNamer) Generate(originalName string) string {
+	return GenerateFilename(fn.GetConfig().Strategy, originalName)
+}
+
+// GenerateFilename returns a unique name for an uploaded file using the given strategy
+func GenerateFilename(strategy, originalName string) string {
+	ext := sanitizeExtension(originalName)
+
+	switch strategy {
+	case NamingStrategyHash:
+		return randomHash() + ext
+	case NamingStrategyDate:
+		return path.Join(time.Now().Format("2006/01/02"), newUUID()+ext)
+	default:
+		return newUUID() + ext
+	}
+}
+
+// IsValidNamingStrategy checks if a naming strategy is supported
+func IsValidNamingStrategy(strategy string) bool {
+	switch strategy {
+	case NamingStrategyUUID, NamingStrategyHash, NamingStrategyDate:
+		return true
+	}
+	return false
+}
+
+// sanitizeExtension returns the lowercased extension, dropping anything that
+// is not alphanumeric so it cannot alter the storage path
+func sanitizeExtension(originalName string) string {
+	ext := strings.ToLower(filepath.Ext(originalName))
+	if len(ext) < 2 || len(ext) > 10 {
+		return ""
+	}
+
+	for _, r := range ext[1:] {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') {
+			return ""
+		}
+	}
+
+	return ext
+}
+
+// newUUID generates a random (version 4) UUID
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// randomHash generates a 128-bit hex name from random input
+func randomHash() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:16])
+}
+
+// GlobalFileNamer is the file namer used by upload handlers
+var GlobalFileNamer = NewFileNamer()
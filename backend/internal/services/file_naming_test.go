@@ -0,0 +1,68 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateFilename_CollisionFree(t *testing.T) {
+	strategies := []string{NamingStrategyUUID, NamingStrategyHash, NamingStrategyDate}
+
+	for _, strategy := range strategies {
+		seen := make(map[string]bool)
+		for i := 0; i < 1000; i++ {
+			name := GenerateFilename(strategy, "report.PDF")
+			if seen[name] {
+				t.Fatalf("%s: duplicate filename %s", strategy, name)
+			}
+			seen[name] = true
+
+			if !strings.HasSuffix(name, ".pdf") {
+				t.Errorf("%s: expected .pdf extension, got %s", strategy, name)
+			}
+		}
+	}
+}
+
+func TestGenerateFilename_Formats(t *testing.T) {
+	uuidName := GenerateFilename(NamingStrategyUUID, "a.png")
+	if len(uuidName) != 36+len(".png") || strings.Count(uuidName, "-") != 4 {
+		t.Errorf("Unexpected uuid filename %s", uuidName)
+	}
+
+	hashName := GenerateFilename(NamingStrategyHash, "a.png")
+	if len(hashName) != 32+len(".png") || strings.Contains(hashName, "-") {
+		t.Errorf("Unexpected hash filename %s", hashName)
+	}
+
+	dateName := GenerateFilename(NamingStrategyDate, "a.png")
+	if parts := strings.Split(dateName, "/"); len(parts) != 4 {
+		t.Errorf("Expected date-partitioned filename, got %s", dateName)
+	}
+}
+
+func TestGenerateFilename_DoesNotLeakOriginalName(t *testing.T) {
+	name := GenerateFilename(NamingStrategyUUID, "user_42_secret.php/../x.sh;")
+	if strings.Contains(name, "user") || strings.Contains(name, "secret") {
+		t.Errorf("Filename leaks original name: %s", name)
+	}
+	if strings.Contains(name, "..") || strings.Contains(name, ";") {
+		t.Errorf("Filename contains unsafe characters: %s", name)
+	}
+}
+
+func TestFileNamer_UpdateConfig(t *testing.T) {
+	fn := NewFileNamer()
+
+	if err := fn.UpdateConfig(&FileNamingConfig{Strategy: "timestamp"}); err != ErrUnknownNamingStrategy {
+		t.Errorf("Expected ErrUnknownNamingStrategy, got %v", err)
+	}
+
+	if err := fn.UpdateConfig(&FileNamingConfig{Strategy: NamingStrategyHash}); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	if got := fn.GetConfig().Strategy; got != NamingStrategyHash {
+		t.Errorf("Expected strategy %s, got %s", NamingStrategyHash, got)
+	}
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultFileScanBatchSize is the number of pending files scanned per job run
+	DefaultFileScanBatchSize = 50
+
+	// fileScanRetryBackoff is the wait before retrying a file after its first
+	// failed scan. It doubles with each further failure up to fileScanMaxBackoff.
+	fileScanRetryBackoff = time.Minute
+	fileScanMaxBackoff   = 6 * time.Hour
+
+	// QuarantineDir holds the content of files found infected
+	QuarantineDir = "./uploads/quarantine"
+)
+
+// ErrFileNotQuarantined is returned when releasing or deleting a file that is not in quarantine
+var ErrFileNotQuarantined = errors.New("file is not quarantined")
+
+// ScanResult represents the verdict of a file scanner
+type ScanResult struct {
+	Infected bool   `json:"infected"`
+	Threat   string `json:"threat,omitempty"`
+}
+
+// FileScanner inspects file content for malware. Implementations wrap an
+// antivirus engine; SignatureScanner is used until one is configured.
+type FileScanner interface {
+	Scan(path string) (ScanResult, error)
+}
+
+// eicarSignature is the standard antivirus test file, detected by every engine
+var eicarSignature = []byte(`X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`)
+
+// SignatureScanner detects files containing any of a set of byte signatures
+type SignatureScanner struct {
+	Signatures map[string][]byte // threat name -> signature
+	MaxBytes   int64             // content read per file
+}
+
+// NewSignatureScanner creates a scanner detecting the EICAR test file
+func NewSignatureScanner() *SignatureScanner {
+	return &SignatureScanner{
+		Signatures: map[string][]byte{"EICAR-Test-File": eicarSignature},
+		MaxBytes:   10 * 1024 * 1024,
+	}
+}
+
+// Scan reads up to MaxBytes of the file and reports the first matching signature
+func (ss *SignatureScanner) Scan(path string) (ScanResult, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ScanResult{}, err
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(io.LimitReader(f, ss.MaxBytes))
+	if err != nil {
+		return ScanResult{}, err
+	}
+
+	for threat, signature := range ss.Signatures {
+		if bytes.Contains(content, signature) {
+			return ScanResult{Infected: true, Threat: threat}, nil
+		}
+	}
+	return ScanResult{}, nil
+}
+
+// Detection represents a file found infected and moved to quarantine
+type Detection struct {
+	FileID uint   `json:"file_id"`
+	UserID uint   `json:"user_id"`
+	Threat string `json:"threat"`
+	Path   string `json:"path"` // location in quarantine
+}
+
+// FileScanFailure represents a file that could not be scanned; it is retried
+// after a backoff growing with each failure
+type FileScanFailure struct {
+	FileID uint   `json:"file_id"`
+	Error  string `json:"error"`
+}
+
+// FileScanSummary represents the result of a scan run
+type FileScanSummary struct {
+	Scanned     int               `json:"scanned"`
+	Safe        int               `json:"safe"`
+	Quarantined []Detection       `json:"quarantined"`
+	Failed      []FileScanFailure `json:"failed"`
+}
+
+// FileScanManager scans uploaded files and moves infected ones to quarantine.
+// Uploads start unscanned and unsafe; only files it marks safe can be downloaded.
+type FileScanManager struct {
+	scanner       FileScanner
+	quarantineDir string
+	onDetection   func(*Detection)
+	mutex         sync.RWMutex
+}
+
+// NewFileScanManager creates a scan manager quarantining files into quarantineDir
+func NewFileScanManager(quarantineDir string) *FileScanManager {
+	return &FileScanManager{
+		scanner:       NewSignatureScanner(),
+		quarantineDir: quarantineDir,
+	}
+}
+
+// SetScanner replaces the scanner, e.g. with an antivirus engine client
+func (fm *FileScanManager) SetScanner(scanner FileScanner) {
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+	fm.scanner = scanner
+}
+
+// Scan scans a file with the current scanner without recording a verdict, for
+// uploads kept outside the files table
+func (fm *FileScanManager) Scan(path string) (ScanResult, error) {
+	fm.mutex.RLock()
+	scanner := fm.scanner
+	fm.mutex.RUnlock()
+	return scanner.Scan(path)
+}
+
+// SetDetectionHandler registers a callback invoked for every quarantined file
+func (fm *FileScanManager) SetDetectionHandler(handler func(*Detection)) {
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+	fm.onDetection = handler
+}
+
+// ScanPendingFiles scans up to batchSize unscanned files. Clean files are marked
+// safe; infected files are moved to quarantine. Files that fail to scan stay
+// pending, are reported and are retried after a backoff.
+func (fm *FileScanManager) ScanPendingFiles(database *gorm.DB, batchSize int) (*FileScanSummary, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultFileScanBatchSize
+	}
+
+	now := time.Now()
+	files, err := models.GetUnscannedFiles(database, now, batchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	fm.mutex.RLock()
+	scanner, onDetection := fm.scanner, fm.onDetection
+	fm.mutex.RUnlock()
+
+	summary := &FileScanSummary{
+		Quarantined: []Detection{},
+		Failed:      []FileScanFailure{},
+	}
+	for i := range files {
+		file := &files[i]
+		summary.Scanned++
+
+		detection, err := fm.scanFile(database, scanner, file)
+		if err != nil {
+			summary.Failed = append(summary.Failed, FileScanFailure{FileID: file.ID, Error: err.Error()})
+			if err := models.RecordFileScanFailure(database, file.ID, err.Error(), now.Add(fileScanBackoff(file.ScanAttempts+1))); err != nil {
+				log.Printf("Failed to record scan failure of file %d: %v", file.ID, err)
+			}
+			continue
+		}
+		if detection == nil {
+			summary.Safe++
+			continue
+		}
+
+		summary.Quarantined = append(summary.Quarantined, *detection)
+		if onDetection != nil {
+			onDetection(detection)
+		}
+	}
+
+	return summary, nil
+}
+
+// fileScanBackoff returns how long to wait before scanning a file again after
+// its given number of failed scans
+func fileScanBackoff(attempts int) time.Duration {
+	backoff := fileScanRetryBackoff
+	for i := 1; i < attempts && backoff < fileScanMaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > fileScanMaxBackoff {
+		backoff = fileScanMaxBackoff
+	}
+	return backoff
+}
+
+// scanFile scans a single file and records the verdict, returning the detection if it was infected
+func (fm *FileScanManager) scanFile(database *gorm.DB, scanner FileScanner, file *models.File) (*Detection, error) {
+	result, err := scanner.Scan(file.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Infected {
+		return nil, models.MarkFileSafe(database, file.ID)
+	}
+
+	if err := os.MkdirAll(fm.quarantineDir, 0755); err != nil {
+		return nil, err
+	}
+	// Prefix the ID so files with the same stored name don't collide
+	originalPath := file.Path
+	quarantinePath := filepath.Join(fm.quarantineDir, fmt.Sprintf("%d_%s", file.ID, filepath.Base(originalPath)))
+	if err := os.Rename(originalPath, quarantinePath); err != nil {
+		return nil, err
+	}
+	if err := models.QuarantineFile(database, file.ID, result.Threat, originalPath, quarantinePath); err != nil {
+		// Keep the file where its record points
+		os.Rename(quarantinePath, originalPath)
+		return nil, err
+	}
+
+	return &Detection{FileID: file.ID, UserID: file.UserID, Threat: result.Threat, Path: quarantinePath}, nil
+}
+
+// FileVerification represents the result of verifying a file's integrity and re-scanning it
+type FileVerification struct {
+	FileID       uint        `json:"file_id"`
+	Integrity    string      `json:"integrity"` // models.FileIntegrityVerified, FileIntegrityMismatch, FileIntegrityMissing or FileIntegrityUnknown
+	ExpectedHash string      `json:"expected_hash"`
+	ActualHash   string      `json:"actual_hash,omitempty"`
+	Scan         *ScanResult `json:"scan,omitempty"`
+	ScanError    string      `json:"scan_error,omitempty"`
+	Quarantined  bool        `json:"quarantined"`
+	Safe         bool        `json:"safe"` // whether the file can be downloaded now
+	VerifiedAt   time.Time   `json:"verified_at"`
+}
+
+// Verify recomputes the hash of a file's content and scans it again with the
+// current scanner, e.g. after its signatures were updated. Infected files are
+// quarantined like by the scan job and clean ones marked safe, unless their
+// content no longer matches the stored hash. Hashes not computed with md5, like
+// those of image uploads, can't be compared and are reported unknown without
+// blocking the file. A quarantined file that now scans clean stays in
+// quarantine until an admin releases it.
+func (fm *FileScanManager) Verify(database *gorm.DB, file *models.File) (*FileVerification, error) {
+	fm.mutex.RLock()
+	scanner, onDetection := fm.scanner, fm.onDetection
+	fm.mutex.RUnlock()
+
+	verification := &FileVerification{
+		FileID:       file.ID,
+		ExpectedHash: file.Hash,
+		Quarantined:  file.QuarantinedAt != nil,
+		VerifiedAt:   time.Now(),
+	}
+
+	hash, err := hashFileContent(file.Path)
+	switch {
+	case os.IsNotExist(err):
+		verification.Integrity = models.FileIntegrityMissing
+	case err != nil:
+		return nil, err
+	case !isContentHash(file.Hash):
+		verification.Integrity = models.FileIntegrityUnknown
+		verification.ActualHash = hash
+	case hash == file.Hash:
+		verification.Integrity = models.FileIntegrityVerified
+		verification.ActualHash = hash
+	default:
+		verification.Integrity = models.FileIntegrityMismatch
+		verification.ActualHash = hash
+	}
+
+	if verification.Integrity != models.FileIntegrityMissing {
+		if verification.Quarantined {
+			result, err := scanner.Scan(file.Path)
+			if err != nil {
+				verification.ScanError = err.Error()
+			} else {
+				verification.Scan = &result
+			}
+		} else {
+			detection, err := fm.scanFile(database, scanner, file)
+			switch {
+			case err != nil:
+				verification.ScanError = err.Error()
+			case detection != nil:
+				verification.Scan = &ScanResult{Infected: true, Threat: detection.Threat}
+				verification.Quarantined = true
+				if onDetection != nil {
+					onDetection(detection)
+				}
+			default:
+				verification.Scan = &ScanResult{}
+			}
+		}
+	}
+
+	if err := models.RecordFileIntegrity(database, file.ID, verification.Integrity, verification.VerifiedAt); err != nil {
+		return nil, err
+	}
+	updated, err := models.GetFileByID(database, file.ID)
+	if err != nil {
+		return nil, err
+	}
+	verification.Safe = updated.IsScanned && updated.IsSafe
+	return verification, nil
+}
+
+// Release moves a quarantined file back to where it was uploaded and marks it safe
+func (fm *FileScanManager) Release(database *gorm.DB, file *models.File) error {
+	if file.QuarantinedAt == nil {
+		return ErrFileNotQuarantined
+	}
+
+	quarantinePath, originalPath := file.Path, file.QuarantinedFrom
+	if err := os.Rename(quarantinePath, originalPath); err != nil {
+		return err
+	}
+	if err := models.ReleaseQuarantinedFile(database, file.ID, originalPath); err != nil {
+		os.Rename(originalPath, quarantinePath)
+		return err
+	}
+	return nil
+}
+
+// Delete removes a quarantined file from disk and deletes its record
+func (fm *FileScanManager) Delete(database *gorm.DB, file *models.File) error {
+	if file.QuarantinedAt == nil {
+		return ErrFileNotQuarantined
+	}
+
+	if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return models.DeleteFile(database, file.ID)
+}
+
+// Register schedules scanning of pending uploads against the application database
+func (fm *FileScanManager) Register(scheduler *Scheduler) error {
+	return scheduler.Register("file_scan", FixedInterval(time.Minute), func() (interface{}, error) {
+		return fm.ScanPendingFiles(db.DB, DefaultFileScanBatchSize)
+	})
+}
+
+// GlobalFileScanManager scans uploads into QuarantineDir
+var GlobalFileScanManager = NewFileScanManager(QuarantineDir)
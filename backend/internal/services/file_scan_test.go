@@ -0,0 +1,215 @@
+package services
+
+import (
+	"crypto/md5"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golangmcp/internal/models"
+)
+
+// failingScanner reports an error for every file
+type failingScanner struct{}
+
+func (failingScanner) Scan(string) (ScanResult, error) {
+	return ScanResult{}, errors.New("engine unavailable")
+}
+
+func TestFileScanManager_QuarantinesDetections(t *testing.T) {
+	database := setupRetentionTestDB(t)
+	dir := t.TempDir()
+	manager := NewFileScanManager(filepath.Join(dir, "quarantine"))
+
+	var detections []Detection
+	manager.SetDetectionHandler(func(d *Detection) { detections = append(detections, *d) })
+
+	clean := seedFile(t, database, dir, "clean.txt", "quarterly numbers", "h1")
+	infected := seedFile(t, database, dir, "infected.txt", "prefix "+string(eicarSignature), "h2")
+
+	summary, err := manager.ScanPendingFiles(database, 10)
+	if err != nil {
+		t.Fatalf("Failed to scan files: %v", err)
+	}
+	if summary.Scanned != 2 || summary.Safe != 1 || len(summary.Quarantined) != 1 {
+		t.Fatalf("Expected 1 safe and 1 quarantined file, got %+v", summary)
+	}
+	if len(detections) != 1 || detections[0].FileID != infected.ID || detections[0].Threat != "EICAR-Test-File" {
+		t.Errorf("Expected the detection handler to be called for the infected file, got %+v", detections)
+	}
+
+	var scannedClean, quarantined models.File
+	database.First(&scannedClean, clean.ID)
+	database.First(&quarantined, infected.ID)
+	if !scannedClean.IsScanned || !scannedClean.IsSafe {
+		t.Errorf("Expected clean file to be marked safe, got %+v", scannedClean)
+	}
+	if !quarantined.IsScanned || quarantined.IsSafe || quarantined.QuarantinedAt == nil || quarantined.ScanResult != "EICAR-Test-File" {
+		t.Errorf("Expected infected file to be quarantined, got %+v", quarantined)
+	}
+	if _, err := os.Stat(infected.Path); !os.IsNotExist(err) {
+		t.Error("Expected infected content to be moved out of the upload directory")
+	}
+	if _, err := os.Stat(quarantined.Path); err != nil {
+		t.Errorf("Expected infected content in quarantine: %v", err)
+	}
+
+	// Scanned files are not picked up again
+	if summary, _ := manager.ScanPendingFiles(database, 10); summary.Scanned != 0 {
+		t.Errorf("Expected no pending files, got %+v", summary)
+	}
+
+	if err := manager.Release(database, &quarantined); err != nil {
+		t.Fatalf("Failed to release file: %v", err)
+	}
+	var released models.File
+	database.First(&released, infected.ID)
+	if !released.IsSafe || released.QuarantinedAt != nil || released.Path != infected.Path {
+		t.Errorf("Expected released file to be safe and back in place, got %+v", released)
+	}
+	if _, err := os.Stat(infected.Path); err != nil {
+		t.Errorf("Expected released content back in the upload directory: %v", err)
+	}
+	if err := manager.Release(database, &released); err != ErrFileNotQuarantined {
+		t.Errorf("Expected ErrFileNotQuarantined, got %v", err)
+	}
+}
+
+func TestFileScanManager_ScanFailuresStayPending(t *testing.T) {
+	database := setupRetentionTestDB(t)
+	dir := t.TempDir()
+	manager := NewFileScanManager(filepath.Join(dir, "quarantine"))
+	manager.SetScanner(failingScanner{})
+
+	file := seedFile(t, database, dir, "report.txt", "content", "h1")
+
+	summary, err := manager.ScanPendingFiles(database, 10)
+	if err != nil {
+		t.Fatalf("Failed to scan files: %v", err)
+	}
+	if len(summary.Failed) != 1 || summary.Failed[0].FileID != file.ID {
+		t.Errorf("Expected the scan failure to be reported, got %+v", summary)
+	}
+
+	var pending models.File
+	database.First(&pending, file.ID)
+	if pending.IsScanned || pending.IsSafe {
+		t.Errorf("Expected file to stay pending after a failed scan, got %+v", pending)
+	}
+	if pending.ScanAttempts != 1 || pending.ScanError != "engine unavailable" || pending.NextScanAt == nil || time.Until(*pending.NextScanAt) < 50*time.Second {
+		t.Errorf("Expected the failure to be recorded with a backoff, got %+v", pending)
+	}
+
+	// The failing file doesn't hold up newer uploads while it backs off
+	upload := seedFile(t, database, dir, "upload.txt", "fresh content", "h2")
+	summary, _ = NewFileScanManager(filepath.Join(dir, "quarantine")).ScanPendingFiles(database, 1)
+	if summary.Scanned != 1 || summary.Safe != 1 {
+		t.Errorf("Expected the new upload to be scanned, got %+v", summary)
+	}
+	var scanned models.File
+	if database.First(&scanned, upload.ID); !scanned.IsSafe {
+		t.Errorf("Expected the new upload to be marked safe, got %+v", scanned)
+	}
+
+	// Once due, it is retried and backs off longer
+	database.Model(&models.File{}).Where("id = ?", file.ID).Update("next_scan_at", time.Now().Add(-time.Second))
+	if summary, _ = manager.ScanPendingFiles(database, 10); len(summary.Failed) != 1 {
+		t.Errorf("Expected the file to be retried, got %+v", summary)
+	}
+	database.First(&pending, file.ID)
+	if pending.ScanAttempts != 2 || time.Until(*pending.NextScanAt) < 110*time.Second {
+		t.Errorf("Expected the backoff to grow, got %+v", pending)
+	}
+	if backoff := fileScanBackoff(20); backoff != fileScanMaxBackoff {
+		t.Errorf("Expected the backoff to be capped, got %v", backoff)
+	}
+}
+
+func TestFileScanManager_VerifyDetectsTampering(t *testing.T) {
+	database := setupRetentionTestDB(t)
+	dir := t.TempDir()
+	manager := NewFileScanManager(filepath.Join(dir, "quarantine"))
+
+	hash := fmt.Sprintf("%x", md5.Sum([]byte("quarterly numbers")))
+	file := seedFile(t, database, dir, "report.txt", "quarterly numbers", hash)
+	manager.ScanPendingFiles(database, 10)
+
+	verification, err := manager.Verify(database, file)
+	if err != nil {
+		t.Fatalf("Failed to verify file: %v", err)
+	}
+	if verification.Integrity != models.FileIntegrityVerified || verification.Scan == nil || verification.Scan.Infected || !verification.Safe {
+		t.Fatalf("Expected an untouched file to verify, got %+v", verification)
+	}
+
+	// Content changed behind the application's back
+	if err := os.WriteFile(file.Path, []byte("quarterly numbers, edited"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	verification, err = manager.Verify(database, file)
+	if err != nil {
+		t.Fatalf("Failed to verify file: %v", err)
+	}
+	if verification.Integrity != models.FileIntegrityMismatch || verification.ActualHash == hash || verification.Safe {
+		t.Errorf("Expected a tampered file to fail verification, got %+v", verification)
+	}
+	var tampered models.File
+	database.First(&tampered, file.ID)
+	if tampered.IntegrityStatus != models.FileIntegrityMismatch || tampered.IsSafe || tampered.VerifiedAt == nil {
+		t.Errorf("Expected the mismatch to be recorded and the file blocked, got %+v", tampered)
+	}
+
+	os.Remove(file.Path)
+	if verification, err = manager.Verify(database, file); err != nil || verification.Integrity != models.FileIntegrityMissing || verification.Scan != nil {
+		t.Errorf("Expected missing content to be reported without a scan, got %+v, %v", verification, err)
+	}
+}
+
+func TestFileScanManager_VerifyRescansWithUpdatedScanner(t *testing.T) {
+	database := setupRetentionTestDB(t)
+	dir := t.TempDir()
+	manager := NewFileScanManager(filepath.Join(dir, "quarantine"))
+
+	var detections []Detection
+	manager.SetDetectionHandler(func(d *Detection) { detections = append(detections, *d) })
+
+	hash := fmt.Sprintf("%x", md5.Sum([]byte("new strain payload")))
+	file := seedFile(t, database, dir, "invoice.txt", "new strain payload", hash)
+	manager.ScanPendingFiles(database, 10)
+	database.First(file, file.ID)
+	if !file.IsSafe {
+		t.Fatalf("Expected the file to scan clean before the update, got %+v", file)
+	}
+
+	// The scanner learns a new signature
+	manager.SetScanner(&SignatureScanner{Signatures: map[string][]byte{"New-Strain": []byte("new strain")}, MaxBytes: 1024})
+	verification, err := manager.Verify(database, file)
+	if err != nil {
+		t.Fatalf("Failed to verify file: %v", err)
+	}
+	if verification.Scan == nil || !verification.Scan.Infected || verification.Scan.Threat != "New-Strain" || !verification.Quarantined || verification.Safe {
+		t.Fatalf("Expected the re-scan to quarantine the file, got %+v", verification)
+	}
+	if len(detections) != 1 || detections[0].FileID != file.ID {
+		t.Errorf("Expected the detection handler to be called, got %+v", detections)
+	}
+
+	var quarantined models.File
+	database.First(&quarantined, file.ID)
+	if quarantined.IsSafe || quarantined.QuarantinedAt == nil || quarantined.ScanResult != "New-Strain" || quarantined.IntegrityStatus != models.FileIntegrityVerified {
+		t.Errorf("Expected the file to be quarantined with its integrity intact, got %+v", quarantined)
+	}
+
+	// Verifying a quarantined file scans it where it is
+	if verification, err = manager.Verify(database, &quarantined); err != nil || !verification.Scan.Infected {
+		t.Fatalf("Expected the quarantined file to be re-scanned, got %+v, %v", verification, err)
+	}
+	var again models.File
+	database.First(&again, file.ID)
+	if again.Path != quarantined.Path || again.QuarantinedFrom != file.Path || len(detections) != 1 {
+		t.Errorf("Expected the quarantined file to stay in place, got %+v", again)
+	}
+}
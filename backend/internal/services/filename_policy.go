@@ -0,0 +1,180 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// MaxFilenameLength is the longest normalized upload filename, in bytes
+const MaxFilenameLength = 255
+
+var (
+	ErrInvalidFilename         = errors.New("filename is empty or invalid")
+	ErrDeniedExtension         = errors.New("file extension is not allowed")
+	ErrMultipleExtensions      = errors.New("filenames with more than one extension are not allowed")
+	ErrInvalidDeniedExtensions = errors.New("denied extensions must be non-empty and contain only letters and digits")
+)
+
+// FilenamePolicy represents which upload filenames are refused, whatever the
+// upload's file type allowlist says
+type FilenamePolicy struct {
+	DeniedExtensions         []string `json:"denied_extensions"`          // without the dot, matched case-insensitively
+	RejectMultipleExtensions bool     `json:"reject_multiple_extensions"` // refuse e.g. report.2024.txt too
+}
+
+// DefaultFilenamePolicy returns default filename policy, denying server-side
+// scripts, executables and web server configuration files
+func DefaultFilenamePolicy() *FilenamePolicy {
+	return &FilenamePolicy{
+		DeniedExtensions: []string{
+			"php", "php3", "php4", "php5", "php7", "phtml", "phar",
+			"asp", "aspx", "jsp", "jspx", "cgi", "pl", "py", "rb",
+			"sh", "bash", "ps1", "bat", "cmd", "com", "vbs", "wsf",
+			"exe", "dll", "scr", "msi", "jar", "hta",
+			"htaccess", "htpasswd",
+		},
+		RejectMultipleExtensions: false,
+	}
+}
+
+// Validate checks the policy for invalid values
+func (fp *FilenamePolicy) Validate() error {
+	for _, ext := range fp.DeniedExtensions {
+		if ext == "" || strings.IndexFunc(ext, func(r rune) bool { return !isASCIIAlphanumeric(r) }) >= 0 {
+			return ErrInvalidDeniedExtensions
+		}
+	}
+	return nil
+}
+
+// FilenamePolicyManager checks upload filenames against the filename policy
+type FilenamePolicyManager struct {
+	policy *FilenamePolicy
+	denied map[string]bool // lowercased DeniedExtensions
+	mutex  sync.RWMutex
+}
+
+// NewFilenamePolicyManager creates a new filename policy manager
+func NewFilenamePolicyManager() *FilenamePolicyManager {
+	policy := DefaultFilenamePolicy()
+	return &FilenamePolicyManager{
+		policy: policy,
+		denied: deniedExtensionSet(policy.DeniedExtensions),
+	}
+}
+
+// GetPolicy returns the current filename policy
+func (fm *FilenamePolicyManager) GetPolicy() FilenamePolicy {
+	fm.mutex.RLock()
+	defer fm.mutex.RUnlock()
+	policy := *fm.policy
+	policy.DeniedExtensions = append([]string(nil), fm.policy.DeniedExtensions...)
+	return policy
+}
+
+// UpdatePolicy validates and replaces the filename policy
+func (fm *FilenamePolicyManager) UpdatePolicy(policy *FilenamePolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	stored := *policy
+	stored.DeniedExtensions = append([]string(nil), policy.DeniedExtensions...)
+
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+	fm.policy = &stored
+	fm.denied = deniedExtensionSet(stored.DeniedExtensions)
+	return nil
+}
+
+// Check normalizes an upload filename and checks it against the policy. Every
+// extension is checked, not only the last one, so image.php.png is refused
+// when php is denied.
+func (fm *FilenamePolicyManager) Check(filename string) (string, error) {
+	name := NormalizeFilename(filename)
+	if name == "" {
+		return "", ErrInvalidFilename
+	}
+
+	extensions := filenameExtensions(name)
+
+	fm.mutex.RLock()
+	defer fm.mutex.RUnlock()
+	for _, ext := range extensions {
+		if fm.denied[ext] {
+			return "", fmt.Errorf("%w: .%s", ErrDeniedExtension, ext)
+		}
+	}
+	if fm.policy.RejectMultipleExtensions && len(extensions) > 1 {
+		return "", ErrMultipleExtensions
+	}
+	return name, nil
+}
+
+// NormalizeFilename reduces an upload filename to a plain base name: directory
+// components, control characters and surrounding spaces and dots are removed
+// (Windows ignores trailing dots, so "shell.php." is "shell.php"), and runs of
+// whitespace become a single space. It returns "" when nothing usable is left.
+func NormalizeFilename(filename string) string {
+	if i := strings.LastIndexAny(filename, `/\`); i >= 0 {
+		filename = filename[i+1:]
+	}
+
+	var b strings.Builder
+	space := false
+	for _, r := range filename {
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case r == unicode.ReplacementChar || unicode.IsControl(r):
+			continue
+		}
+		if space && b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(r)
+	}
+
+	name := strings.TrimRight(b.String(), ". ")
+	if len(name) > MaxFilenameLength {
+		return ""
+	}
+	return name
+}
+
+// filenameExtensions returns the lowercased extensions of a normalized name,
+// every dot-separated part after the first: "a.PHP.png" has php and png, and
+// ".htaccess" has htaccess
+func filenameExtensions(name string) []string {
+	parts := strings.Split(strings.ToLower(name), ".")
+	extensions := make([]string, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		if part = strings.TrimSpace(part); part != "" {
+			extensions = append(extensions, part)
+		}
+	}
+	return extensions
+}
+
+// deniedExtensionSet builds the lookup set of denied extensions
+func deniedExtensionSet(extensions []string) map[string]bool {
+	set := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		set[strings.ToLower(ext)] = true
+	}
+	return set
+}
+
+// isASCIIAlphanumeric reports whether r is an ASCII letter or digit
+func isASCIIAlphanumeric(r rune) bool {
+	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// GlobalFilenamePolicy is the filename policy applied to every upload
+var GlobalFilenamePolicy = NewFilenamePolicyManager()
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFilenamePolicy_RejectsDangerousExtensions(t *testing.T) {
+	fm := NewFilenamePolicyManager()
+
+	for _, name := range []string{
+		"shell.php",
+		"SHELL.PHP",
+		"image.php.png",
+		"report.exe.txt",
+		"photo.PhTmL.jpg",
+		"shell.php.",
+		"shell.php .png",
+		"shell.php\x00.png",
+		".htaccess",
+	} {
+		if _, err := fm.Check(name); !errors.Is(err, ErrDeniedExtension) {
+			t.Errorf("Expected %q to be denied, got %v", name, err)
+		}
+	}
+}
+
+func TestFilenamePolicy_AllowsPlainNames(t *testing.T) {
+	fm := NewFilenamePolicyManager()
+
+	for _, name := range []string{"notes.txt", "photo.JPG", "archive.tar.gz", "report.2024.csv", "README"} {
+		if _, err := fm.Check(name); err != nil {
+			t.Errorf("Expected %q to be allowed, got %v", name, err)
+		}
+	}
+}
+
+func TestFilenamePolicy_RejectMultipleExtensions(t *testing.T) {
+	fm := NewFilenamePolicyManager()
+	policy := DefaultFilenamePolicy()
+	policy.RejectMultipleExtensions = true
+	if err := fm.UpdatePolicy(policy); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+
+	if _, err := fm.Check("archive.tar.gz"); !errors.Is(err, ErrMultipleExtensions) {
+		t.Errorf("Expected a double extension to be rejected, got %v", err)
+	}
+	if _, err := fm.Check("notes.txt"); err != nil {
+		t.Errorf("Expected a single extension to be allowed, got %v", err)
+	}
+}
+
+func TestFilenamePolicy_UpdateReplacesDenylist(t *testing.T) {
+	fm := NewFilenamePolicyManager()
+	if err := fm.UpdatePolicy(&FilenamePolicy{DeniedExtensions: []string{"CSV"}}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+
+	if _, err := fm.Check("data.csv"); !errors.Is(err, ErrDeniedExtension) {
+		t.Errorf("Expected csv to be denied case-insensitively, got %v", err)
+	}
+	if _, err := fm.Check("shell.php"); err != nil {
+		t.Errorf("Expected php to be allowed once removed from the denylist, got %v", err)
+	}
+
+	for _, ext := range []string{"", ".php", "p/hp"} {
+		if err := fm.UpdatePolicy(&FilenamePolicy{DeniedExtensions: []string{ext}}); err != ErrInvalidDeniedExtensions {
+			t.Errorf("Expected %q to be rejected, got %v", ext, err)
+		}
+	}
+}
+
+func TestNormalizeFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"notes.txt", "notes.txt"},
+		{"  my   notes.txt  ", "my notes.txt"},
+		{"../../etc/passwd", "passwd"},
+		{`C:\Users\me\report.txt`, "report.txt"},
+		{"report.txt...", "report.txt"},
+		{"re\x00port\t\tfinal.txt", "report final.txt"},
+		{"..", ""},
+		{"   ", ""},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeFilename(tt.name); got != tt.want {
+			t.Errorf("NormalizeFilename(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
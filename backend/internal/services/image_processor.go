@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	"image/gif"
@@ -12,53 +13,122 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/nfnt/resize"
 )
 
-// ImageProcessor handles image processing and optimization
-type ImageProcessor struct {
+var ErrInvalidImageFormatRule = errors.New("format rules must map jpeg, png or gif to jpeg, png or gif with a quality between 0 and 100")
+
+// imageFormats are the formats images are decoded from and encoded to. WebP has
+// no encoder among the dependencies, so it can't be a conversion target.
+var imageFormats = map[string]bool{"jpeg": true, "png": true, "gif": true}
+
+// ImageFormatRule represents how images of an input format are re-encoded
+type ImageFormatRule struct {
+	Format  string `json:"format"`            // output format; empty keeps the input format
+	Quality int    `json:"quality,omitempty"` // JPEG quality, 0 = the processor's Quality
+}
+
+// ImageSettings represents how images are validated and optimized
+type ImageSettings struct {
 	MaxWidth     uint
 	MaxHeight    uint
 	Quality      int
 	MaxFileSize  int64 // in bytes
 	AllowedTypes []string
+	FormatRules  map[string]ImageFormatRule // keyed by input format
+}
+
+// copy returns a copy of the settings that shares no slices or maps with them
+func (is ImageSettings) copy() ImageSettings {
+	is.AllowedTypes = append([]string(nil), is.AllowedTypes...)
+	rules := make(map[string]ImageFormatRule, len(is.FormatRules))
+	for input, rule := range is.FormatRules {
+		rules[input] = rule
+	}
+	is.FormatRules = rules
+	return is
 }
 
-// NewImageProcessor creates a new image processor with default settings
+// ImageProcessor handles image processing and optimization. Settings may be
+// updated while images are processed; each image is processed with a snapshot
+// of the settings taken when it started.
+type ImageProcessor struct {
+	settings ImageSettings
+	mutex    sync.RWMutex
+}
+
+// NewImageProcessor creates a new image processor with default settings. By
+// default every image keeps its format.
 func NewImageProcessor() *ImageProcessor {
 	return &ImageProcessor{
-		MaxWidth:     1920,
-		MaxHeight:    1080,
-		Quality:      85,
-		MaxFileSize:  5 * 1024 * 1024, // 5MB
-		AllowedTypes: []string{"image/jpeg", "image/png", "image/gif"},
+		settings: ImageSettings{
+			MaxWidth:     1920,
+			MaxHeight:    1080,
+			Quality:      85,
+			MaxFileSize:  5 * 1024 * 1024, // 5MB
+			AllowedTypes: []string{"image/jpeg", "image/png", "image/gif"},
+			FormatRules: map[string]ImageFormatRule{
+				"jpeg": {Format: "jpeg"},
+				"png":  {Format: "png"},
+				"gif":  {Format: "gif"},
+			},
+		},
+	}
+}
+
+// GetSettings returns a copy of the current settings
+func (ip *ImageProcessor) GetSettings() ImageSettings {
+	ip.mutex.RLock()
+	defer ip.mutex.RUnlock()
+	return ip.settings.copy()
+}
+
+// ValidateImageFormatRules checks format rules for unknown formats and invalid qualities
+func ValidateImageFormatRules(rules map[string]ImageFormatRule) error {
+	for input, rule := range rules {
+		if !imageFormats[input] || (rule.Format != "" && !imageFormats[rule.Format]) {
+			return ErrInvalidImageFormatRule
+		}
+		if rule.Quality < 0 || rule.Quality > 100 {
+			return ErrInvalidImageFormatRule
+		}
 	}
+	return nil
 }
 
 // ProcessImage processes and optimizes an uploaded image
 func (ip *ImageProcessor) ProcessImage(file multipart.File, header *multipart.FileHeader) (*ProcessedImage, error) {
+	settings := ip.GetSettings()
+
 	// Validate file type
-	if !ip.isAllowedType(header.Header.Get("Content-Type")) {
+	if !settings.isAllowedType(header.Header.Get("Content-Type")) {
 		return nil, fmt.Errorf("file type not allowed: %s", header.Header.Get("Content-Type"))
 	}
 
-	// Read file content
-	fileBytes, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read file: %w", err)
-	}
-
 	// Check file size
-	if int64(len(fileBytes)) > ip.MaxFileSize {
-		return nil, fmt.Errorf("file size exceeds limit: %d bytes (max: %d)", len(fileBytes), ip.MaxFileSize)
+	if header.Size > settings.MaxFileSize {
+		return nil, fmt.Errorf("file size exceeds limit: %d bytes (max: %d)", header.Size, settings.MaxFileSize)
 	}
 
-	// Decode image
-	img, format, err := image.Decode(bytes.NewReader(fileBytes))
+	// Decode straight from the upload rather than a copy of it in memory; the
+	// limit also holds when the declared size is wrong
+	counter := &countingReader{reader: io.LimitReader(file, settings.MaxFileSize+1)}
+	img, format, err := image.Decode(counter)
 	if err != nil {
+		if counter.count > settings.MaxFileSize {
+			return nil, fmt.Errorf("file size exceeds limit: more than %d bytes", settings.MaxFileSize)
+		}
 		return nil, fmt.Errorf("failed to decode image: %w", err)
 	}
+	if _, err := io.Copy(io.Discard, counter); err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	originalSize := counter.count
+	if originalSize > settings.MaxFileSize {
+		return nil, fmt.Errorf("file size exceeds limit: %d bytes (max: %d)", originalSize, settings.MaxFileSize)
+	}
 
 	// Get original dimensions
 	bounds := img.Bounds()
@@ -66,7 +136,7 @@ func (ip *ImageProcessor) ProcessImage(file multipart.File, header *multipart.Fi
 	originalHeight := bounds.Dy()
 
 	// Calculate new dimensions maintaining aspect ratio
-	newWidth, newHeight := ip.calculateDimensions(uint(originalWidth), uint(originalHeight))
+	newWidth, newHeight := settings.calculateDimensions(uint(originalWidth), uint(originalHeight))
 
 	// Resize image if needed
 	var processedImg image.Image = img
@@ -74,35 +144,50 @@ func (ip *ImageProcessor) ProcessImage(file multipart.File, header *multipart.Fi
 		processedImg = resize.Resize(newWidth, newHeight, img, resize.Lanczos3)
 	}
 
-	// Encode with optimization
-	optimizedBytes, err := ip.encodeImage(processedImg, format)
+	// Encode with optimization, converting as the format rules say
+	optimizedBytes, outputFormat, err := settings.encodeImage(processedImg, format)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encode optimized image: %w", err)
 	}
 
 	// Generate unique filename
-	filename := ip.generateFilename(header.Filename, format)
+	filename := ip.generateFilename(header.Filename, outputFormat)
 
 	return &ProcessedImage{
 		OriginalFilename: header.Filename,
 		Filename:         filename,
-		Format:           format,
-		OriginalSize:     int64(len(fileBytes)),
+		Format:           outputFormat,
+		SourceFormat:     format,
+		OriginalSize:     originalSize,
 		OptimizedSize:    int64(len(optimizedBytes)),
 		OriginalWidth:    originalWidth,
 		OriginalHeight:   originalHeight,
 		OptimizedWidth:   int(newWidth),
 		OptimizedHeight:  int(newHeight),
 		Data:             optimizedBytes,
-		CompressionRatio: float64(len(optimizedBytes)) / float64(len(fileBytes)),
+		CompressionRatio: float64(len(optimizedBytes)) / float64(originalSize),
 	}, nil
 }
 
+// countingReader counts the bytes read through it
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+// Read reads from the underlying reader
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.reader.Read(p)
+	cr.count += int64(n)
+	return n, err
+}
+
 // ProcessedImage represents a processed image
 type ProcessedImage struct {
 	OriginalFilename string
 	Filename         string
-	Format           string
+	Format           string // the format Data is encoded in
+	SourceFormat     string // the format of the upload
 	OriginalSize     int64
 	OptimizedSize    int64
 	OriginalWidth    int
@@ -114,8 +199,8 @@ type ProcessedImage struct {
 }
 
 // isAllowedType checks if the file type is allowed
-func (ip *ImageProcessor) isAllowedType(contentType string) bool {
-	for _, allowedType := range ip.AllowedTypes {
+func (is *ImageSettings) isAllowedType(contentType string) bool {
+	for _, allowedType := range is.AllowedTypes {
 		if contentType == allowedType {
 			return true
 		}
@@ -124,14 +209,14 @@ func (ip *ImageProcessor) isAllowedType(contentType string) bool {
 }
 
 // calculateDimensions calculates new dimensions maintaining aspect ratio
-func (ip *ImageProcessor) calculateDimensions(width, height uint) (uint, uint) {
-	if width <= ip.MaxWidth && height <= ip.MaxHeight {
+func (is *ImageSettings) calculateDimensions(width, height uint) (uint, uint) {
+	if width <= is.MaxWidth && height <= is.MaxHeight {
 		return width, height
 	}
 
 	// Calculate scaling factor
-	widthRatio := float64(ip.MaxWidth) / float64(width)
-	heightRatio := float64(ip.MaxHeight) / float64(height)
+	widthRatio := float64(is.MaxWidth) / float64(width)
+	heightRatio := float64(is.MaxHeight) / float64(height)
 	ratio := widthRatio
 	if heightRatio < widthRatio {
 		ratio = heightRatio
@@ -143,37 +228,71 @@ func (ip *ImageProcessor) calculateDimensions(width, height uint) (uint, uint) {
 	return newWidth, newHeight
 }
 
-// encodeImage encodes the image with optimization
-func (ip *ImageProcessor) encodeImage(img image.Image, format string) ([]byte, error) {
+// encodeImage encodes an image decoded from sourceFormat with the format and
+// quality its format rule sets, returning the format used. An image with
+// transparent pixels is never converted to JPEG, which has no alpha channel; it
+// keeps its source format instead.
+func (is *ImageSettings) encodeImage(img image.Image, sourceFormat string) ([]byte, string, error) {
+	format, quality := sourceFormat, is.Quality
+	if rule, ok := is.FormatRules[sourceFormat]; ok {
+		if rule.Format != "" {
+			format = rule.Format
+		}
+		if rule.Quality > 0 {
+			quality = rule.Quality
+		}
+	}
+	if format == "jpeg" && sourceFormat != "jpeg" && hasTransparency(img) {
+		format = sourceFormat
+	}
+
 	var buf bytes.Buffer
 
 	switch format {
 	case "jpeg":
-		err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: ip.Quality})
+		err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 	case "png":
 		err := png.Encode(&buf, img)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 	case "gif":
 		err := gif.Encode(&buf, img, nil)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 	default:
-		return nil, fmt.Errorf("unsupported image format: %s", format)
+		return nil, "", fmt.Errorf("unsupported image format: %s", format)
 	}
 
-	return buf.Bytes(), nil
+	return buf.Bytes(), format, nil
+}
+
+// hasTransparency checks if any pixel of an image is not fully opaque
+func hasTransparency(img image.Image) bool {
+	if opaquer, ok := img.(interface{ Opaque() bool }); ok {
+		return !opaquer.Opaque()
+	}
+
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a != 0xffff {
+				return true
+			}
+		}
+	}
+	return false
 }
 
-// generateFilename generates a unique filename
+// generateFilename generates a unique filename. The original extension is kept
+// unless it doesn't match the format, e.g. after a conversion.
 func (ip *ImageProcessor) generateFilename(originalFilename, format string) string {
 	ext := strings.ToLower(filepath.Ext(originalFilename))
-	if ext == "" {
+	if ext != "."+format && !(format == "jpeg" && ext == ".jpg") {
 		switch format {
 		case "jpeg":
 			ext = ".jpg"
@@ -218,34 +337,86 @@ func (ip *ImageProcessor) SaveImage(processedImg *ProcessedImage, uploadDir stri
 
 // GetImageStats returns statistics about image processing
 func (ip *ImageProcessor) GetImageStats() map[string]interface{} {
+	settings := ip.GetSettings()
 	return map[string]interface{}{
-		"max_width":      ip.MaxWidth,
-		"max_height":     ip.MaxHeight,
-		"quality":        ip.Quality,
-		"max_file_size":  ip.MaxFileSize,
-		"allowed_types":  ip.AllowedTypes,
-		"max_file_size_mb": ip.MaxFileSize / (1024 * 1024),
+		"max_width":      settings.MaxWidth,
+		"max_height":     settings.MaxHeight,
+		"quality":        settings.Quality,
+		"max_file_size":  settings.MaxFileSize,
+		"allowed_types":  settings.AllowedTypes,
+		"format_rules":   settings.FormatRules,
+		"max_file_size_mb": settings.MaxFileSize / (1024 * 1024),
 	}
 }
 
-// UpdateSettings updates the image processor settings
+// UpdateSettings updates the image processor settings. Images already being
+// processed keep the settings they started with.
 func (ip *ImageProcessor) UpdateSettings(maxWidth, maxHeight uint, quality int, maxFileSize int64) {
-	ip.MaxWidth = maxWidth
-	ip.MaxHeight = maxHeight
-	ip.Quality = quality
-	ip.MaxFileSize = maxFileSize
+	ip.mutex.Lock()
+	defer ip.mutex.Unlock()
+	ip.settings.MaxWidth = maxWidth
+	ip.settings.MaxHeight = maxHeight
+	ip.settings.Quality = quality
+	ip.settings.MaxFileSize = maxFileSize
+}
+
+// UpdateFormatRules validates and replaces the per-format conversion rules.
+// Input formats without a rule keep their format and the processor's Quality.
+func (ip *ImageProcessor) UpdateFormatRules(rules map[string]ImageFormatRule) error {
+	if err := ValidateImageFormatRules(rules); err != nil {
+		return err
+	}
+
+	stored := make(map[string]ImageFormatRule, len(rules))
+	for input, rule := range rules {
+		stored[input] = rule
+	}
+
+	ip.mutex.Lock()
+	defer ip.mutex.Unlock()
+	ip.settings.FormatRules = stored
+	return nil
+}
+
+// UpdateSettingsAndRules validates the format rules, then updates the limits
+// and the rules under one lock so images never start with only half of the
+// change. Nil rules keep the current ones. Returns the settings now in effect.
+func (ip *ImageProcessor) UpdateSettingsAndRules(maxWidth, maxHeight uint, quality int, maxFileSize int64, rules map[string]ImageFormatRule) (ImageSettings, error) {
+	var stored map[string]ImageFormatRule
+	if rules != nil {
+		if err := ValidateImageFormatRules(rules); err != nil {
+			return ImageSettings{}, err
+		}
+		stored = make(map[string]ImageFormatRule, len(rules))
+		for input, rule := range rules {
+			stored[input] = rule
+		}
+	}
+
+	ip.mutex.Lock()
+	defer ip.mutex.Unlock()
+	ip.settings.MaxWidth = maxWidth
+	ip.settings.MaxHeight = maxHeight
+	ip.settings.Quality = quality
+	ip.settings.MaxFileSize = maxFileSize
+	if stored != nil {
+		ip.settings.FormatRules = stored
+	}
+	return ip.settings.copy(), nil
 }
 
 // ValidateImage validates an image file without processing
 func (ip *ImageProcessor) ValidateImage(file multipart.File, header *multipart.FileHeader) error {
+	settings := ip.GetSettings()
+
 	// Check file type
-	if !ip.isAllowedType(header.Header.Get("Content-Type")) {
+	if !settings.isAllowedType(header.Header.Get("Content-Type")) {
 		return fmt.Errorf("file type not allowed: %s", header.Header.Get("Content-Type"))
 	}
 
 	// Check file size
-	if header.Size > ip.MaxFileSize {
-		return fmt.Errorf("file size exceeds limit: %d bytes (max: %d)", header.Size, ip.MaxFileSize)
+	if header.Size > settings.MaxFileSize {
+		return fmt.Errorf("file size exceeds limit: %d bytes (max: %d)", header.Size, settings.MaxFileSize)
 	}
 
 	// Try to decode image to validate it's a valid image
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// imageUpload wraps content as an uploaded file
+type imageUpload struct {
+	*bytes.Reader
+}
+
+// Close closes the upload
+func (imageUpload) Close() error { return nil }
+
+// newImageUpload returns an upload of content with the given declared size
+func newImageUpload(content []byte, size int64) (multipart.File, *multipart.FileHeader) {
+	header := &multipart.FileHeader{
+		Filename: "photo.png",
+		Header:   textproto.MIMEHeader{"Content-Type": {"image/png"}},
+		Size:     size,
+	}
+	return imageUpload{bytes.NewReader(content)}, header
+}
+
+// encodeTestPNG returns a PNG of the given dimensions
+func encodeTestPNG(t testing.TB, width, height int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestImageProcessor_ProcessImageStreams(t *testing.T) {
+	ip := NewImageProcessor()
+	ip.UpdateSettings(50, 50, 85, 5*1024*1024)
+	content := encodeTestPNG(t, 100, 80)
+
+	processed, err := ip.ProcessImage(newImageUpload(content, int64(len(content))))
+	if err != nil {
+		t.Fatalf("Failed to process image: %v", err)
+	}
+	if processed.OriginalSize != int64(len(content)) {
+		t.Errorf("Expected original size %d, got %d", len(content), processed.OriginalSize)
+	}
+	if processed.OptimizedWidth != 50 || processed.OptimizedHeight != 40 {
+		t.Errorf("Expected 50x40, got %dx%d", processed.OptimizedWidth, processed.OptimizedHeight)
+	}
+}
+
+func TestImageProcessor_RejectsOversizedImages(t *testing.T) {
+	ip := NewImageProcessor()
+	content := encodeTestPNG(t, 100, 100)
+	ip.UpdateSettings(1920, 1080, 85, int64(len(content))-1)
+
+	// Declared too large
+	if _, err := ip.ProcessImage(newImageUpload(content, int64(len(content)))); err == nil || !strings.Contains(err.Error(), "exceeds limit") {
+		t.Errorf("Expected the declared size to be rejected, got %v", err)
+	}
+
+	// Understated, caught while reading
+	if _, err := ip.ProcessImage(newImageUpload(content, 10)); err == nil || !strings.Contains(err.Error(), "exceeds limit") {
+		t.Errorf("Expected the bytes read to be limited, got %v", err)
+	}
+}
+
+func TestImageProcessor_FormatRulesKeepTransparency(t *testing.T) {
+	ip := NewImageProcessor()
+	if err := ip.UpdateFormatRules(map[string]ImageFormatRule{"png": {Format: "jpeg", Quality: 80}}); err != nil {
+		t.Fatalf("Failed to update format rules: %v", err)
+	}
+
+	// An opaque PNG is converted to JPEG
+	opaque := image.NewRGBA(image.Rect(0, 0, 20, 20))
+	draw.Draw(opaque, opaque.Bounds(), &image.Uniform{C: color.RGBA{R: 200, A: 255}}, image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, opaque); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	processed, err := ip.ProcessImage(newImageUpload(buf.Bytes(), int64(buf.Len())))
+	if err != nil {
+		t.Fatalf("Failed to process image: %v", err)
+	}
+	if processed.Format != "jpeg" || processed.SourceFormat != "png" || !strings.HasSuffix(processed.Filename, ".jpg") {
+		t.Errorf("Expected the opaque PNG to become a .jpg, got %s from %s as %s", processed.Format, processed.SourceFormat, processed.Filename)
+	}
+	if _, format, err := image.Decode(bytes.NewReader(processed.Data)); err != nil || format != "jpeg" {
+		t.Errorf("Expected JPEG data, got %s: %v", format, err)
+	}
+
+	// A transparent one stays PNG, keeping its alpha channel
+	content := encodeTestPNG(t, 20, 20)
+	processed, err = ip.ProcessImage(newImageUpload(content, int64(len(content))))
+	if err != nil {
+		t.Fatalf("Failed to process image: %v", err)
+	}
+	if processed.Format != "png" || !strings.HasSuffix(processed.Filename, ".png") {
+		t.Errorf("Expected the transparent PNG to stay PNG, got %s as %s", processed.Format, processed.Filename)
+	}
+	img, _, err := image.Decode(bytes.NewReader(processed.Data))
+	if err != nil {
+		t.Fatalf("Failed to decode processed image: %v", err)
+	}
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("Expected the pixel to stay transparent, got alpha %d", a)
+	}
+
+	if rules := ip.GetImageStats()["format_rules"].(map[string]ImageFormatRule); rules["png"].Format != "jpeg" {
+		t.Errorf("Expected the applied rules in the stats, got %+v", rules)
+	}
+}
+
+func TestValidateImageFormatRules(t *testing.T) {
+	for _, rules := range []map[string]ImageFormatRule{
+		{"png": {Format: "webp"}},
+		{"bmp": {Format: "png"}},
+		{"jpeg": {Format: "jpeg", Quality: 101}},
+	} {
+		if err := ValidateImageFormatRules(rules); err != ErrInvalidImageFormatRule {
+			t.Errorf("Expected %+v to be rejected, got %v", rules, err)
+		}
+	}
+}
+
+func TestImageProcessor_SettingsUpdatedDuringProcessing(t *testing.T) {
+	ip := NewImageProcessor()
+	content := encodeTestPNG(t, 100, 100)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			size := uint(40 + 20*(i%2))
+			ip.UpdateSettings(size, size, 85, 5*1024*1024)
+			ip.UpdateFormatRules(map[string]ImageFormatRule{"png": {Format: "png"}})
+		}
+	}()
+
+	// Run with -race: each image is processed with a snapshot of the settings
+	var processors sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		processors.Add(1)
+		go func() {
+			defer processors.Done()
+			for j := 0; j < 5; j++ {
+				processed, err := ip.ProcessImage(newImageUpload(content, int64(len(content))))
+				if err != nil {
+					t.Errorf("Failed to process image: %v", err)
+					return
+				}
+				if width := processed.OptimizedWidth; width != 100 && width != 40 && width != 60 {
+					t.Errorf("Expected the size of one of the settings, got %dx%d", width, processed.OptimizedHeight)
+				}
+				ip.GetImageStats()
+			}
+		}()
+	}
+	processors.Wait()
+	close(stop)
+	wg.Wait()
+}
+
+func TestImageProcessor_DefaultJPEGRuleUsesGlobalQuality(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 7919 % 251)
+	}
+
+	sizes := map[int]int{}
+	for _, quality := range []int{10, 95} {
+		ip := NewImageProcessor()
+		ip.UpdateSettings(1920, 1080, quality, 5*1024*1024)
+		settings := ip.GetSettings()
+		data, format, err := settings.encodeImage(img, "jpeg")
+		if err != nil || format != "jpeg" {
+			t.Fatalf("Failed to encode JPEG: %s, %v", format, err)
+		}
+		sizes[quality] = len(data)
+	}
+	if sizes[10] >= sizes[95] {
+		t.Errorf("Expected the quality setting to apply to JPEGs, got %d bytes at 10 and %d at 95", sizes[10], sizes[95])
+	}
+}
+
+func TestImageProcessor_UpdateSettingsAndRulesIsAtomic(t *testing.T) {
+	ip := NewImageProcessor()
+
+	// Invalid rules change nothing, not even the limits
+	if _, err := ip.UpdateSettingsAndRules(10, 10, 50, 1024, map[string]ImageFormatRule{"bmp": {Format: "png"}}); err != ErrInvalidImageFormatRule {
+		t.Fatalf("Expected ErrInvalidImageFormatRule, got %v", err)
+	}
+	if settings := ip.GetSettings(); settings.MaxWidth != 1920 || settings.Quality != 85 {
+		t.Errorf("Expected the limits to be kept after a rejected update, got %+v", settings)
+	}
+
+	// Nil rules keep the current ones
+	settings, err := ip.UpdateSettingsAndRules(800, 600, 70, 1024, nil)
+	if err != nil || settings.MaxWidth != 800 || settings.FormatRules["png"].Format != "png" {
+		t.Fatalf("Expected the limits to change and the rules to stay, got %+v (%v)", settings, err)
+	}
+
+	// Readers only see the limits together with the rules they were set with
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if i%2 == 0 {
+				ip.UpdateSettingsAndRules(40, 40, 85, 1024, map[string]ImageFormatRule{"png": {Format: "png"}})
+			} else {
+				ip.UpdateSettingsAndRules(60, 60, 85, 1024, map[string]ImageFormatRule{"png": {Format: "jpeg"}})
+			}
+		}
+	}()
+	for i := 0; i < 1000; i++ {
+		settings := ip.GetSettings()
+		if format := settings.FormatRules["png"].Format; settings.MaxWidth == 40 && format != "png" || settings.MaxWidth == 60 && format != "jpeg" {
+			t.Fatalf("Expected consistent settings, got width %d with png rule %q", settings.MaxWidth, format)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
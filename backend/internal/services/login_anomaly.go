@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golangmcp/internal/models"
+	"gorm.io/gorm"
+)
+
+// LoginAnomalyConfig represents new device login detection configuration
+type LoginAnomalyConfig struct {
+	Enabled          bool   `json:"enabled"`
+	IPv4PrefixBits   int    `json:"ipv4_prefix_bits"` // logins within the same subnet share a fingerprint
+	IPv6PrefixBits   int    `json:"ipv6_prefix_bits"`
+	MatchUserAgent   bool   `json:"match_user_agent"`   // include the user agent in the fingerprint
+	MaxKnownDevices  int    `json:"max_known_devices"`  // least recently seen devices are forgotten
+	NotifyFirstLogin bool   `json:"notify_first_login"` // treat a user's very first device as new
+	WebhookURL       string `json:"webhook_url"`        // receives a JSON POST per anomaly, empty = disabled
+}
+
+// DefaultLoginAnomalyConfig returns default login anomaly configuration
+func DefaultLoginAnomalyConfig() *LoginAnomalyConfig {
+	return &LoginAnomalyConfig{
+		Enabled:          true,
+		IPv4PrefixBits:   24,
+		IPv6PrefixBits:   64,
+		MatchUserAgent:   true,
+		MaxKnownDevices:  20,
+		NotifyFirstLogin: false,
+	}
+}
+
+var ErrInvalidLoginAnomalyConfig = errors.New("prefix bits must be within the address size and max known devices at least 1")
+
+// Validate checks the configuration for invalid values
+func (lc *LoginAnomalyConfig) Validate() error {
+	if lc.IPv4PrefixBits < 0 || lc.IPv4PrefixBits > 32 || lc.IPv6PrefixBits < 0 || lc.IPv6PrefixBits > 128 {
+		return ErrInvalidLoginAnomalyConfig
+	}
+	if lc.MaxKnownDevices < 1 {
+		return ErrInvalidLoginAnomalyConfig
+	}
+	return nil
+}
+
+// LoginAnomaly describes a login from a device the user has not used before
+type LoginAnomaly struct {
+	UserID      uint      `json:"user_id"`
+	Username    string    `json:"username"`
+	Email       string    `json:"-"` // where the user is notified, kept out of webhooks
+	IPAddress   string    `json:"ip_address"`
+	UserAgent   string    `json:"user_agent"`
+	Fingerprint string    `json:"fingerprint"`
+	DetectedAt  time.Time `json:"detected_at"`
+}
+
+// LoginAnomalyDetector detects logins from unfamiliar devices
+type LoginAnomalyDetector struct {
+	config      *LoginAnomalyConfig
+	onNewDevice func(*LoginAnomaly)
+	mutex       sync.RWMutex
+}
+
+// NewLoginAnomalyDetector creates a new login anomaly detector
+func NewLoginAnomalyDetector() *LoginAnomalyDetector {
+	return &LoginAnomalyDetector{
+		config: DefaultLoginAnomalyConfig(),
+	}
+}
+
+// GetConfig returns the current configuration
+func (ld *LoginAnomalyDetector) GetConfig() LoginAnomalyConfig {
+	ld.mutex.RLock()
+	defer ld.mutex.RUnlock()
+	return *ld.config
+}
+
+// UpdateConfig validates and replaces the configuration
+func (ld *LoginAnomalyDetector) UpdateConfig(config *LoginAnomalyConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+
+	ld.mutex.Lock()
+	defer ld.mutex.Unlock()
+	ld.config = config
+	return nil
+}
+
+// SetNewDeviceHandler registers a callback invoked for every login from a new device
+func (ld *LoginAnomalyDetector) SetNewDeviceHandler(handler func(*LoginAnomaly)) {
+	ld.mutex.Lock()
+	defer ld.mutex.Unlock()
+	ld.onNewDevice = handler
+}
+
+// Fingerprint derives a device fingerprint from the IP subnet and user agent
+func (ld *LoginAnomalyDetector) Fingerprint(ipAddress, userAgent string) string {
+	config := ld.GetConfig()
+
+	subnet := ipAddress
+	if ip := net.ParseIP(ipAddress); ip != nil {
+		if ip4 := ip.To4(); ip4 != nil {
+			subnet = ip4.Mask(net.CIDRMask(config.IPv4PrefixBits, 32)).String()
+		} else {
+			subnet = ip.Mask(net.CIDRMask(config.IPv6PrefixBits, 128)).String()
+		}
+	}
+
+	if !config.MatchUserAgent {
+		userAgent = ""
+	}
+
+	sum := sha256.Sum256([]byte(subnet + "|" + strings.TrimSpace(userAgent)))
+	return hex.EncodeToString(sum[:])
+}
+
+// CheckLogin records the device of a successful login and returns the anomaly,
+// if the device is unfamiliar. The new device handler and webhook are notified.
+func (ld *LoginAnomalyDetector) CheckLogin(database *gorm.DB, user *models.User, ipAddress, userAgent string) (*LoginAnomaly, error) {
+	config := ld.GetConfig()
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	fingerprint := ld.Fingerprint(ipAddress, userAgent)
+	now := time.Now()
+
+	device, err := models.GetKnownDevice(database, user.ID, fingerprint)
+	if err == nil {
+		return nil, database.Model(device).Updates(map[string]interface{}{
+			"last_seen":  now,
+			"ip_address": ipAddress,
+		}).Error
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	knownDevices, err := models.CountKnownDevices(database, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.Create(&models.KnownDevice{
+		UserID:      user.ID,
+		Fingerprint: fingerprint,
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		FirstSeen:   now,
+		LastSeen:    now,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if err := models.PurgeKnownDevices(database, user.ID, config.MaxKnownDevices); err != nil {
+		log.Printf("Warning: Failed to purge known devices: %v", err)
+	}
+
+	// A user's first device is only a baseline
+	if knownDevices == 0 && !config.NotifyFirstLogin {
+		return nil, nil
+	}
+
+	anomaly := &LoginAnomaly{
+		UserID:      user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		IPAddress:   ipAddress,
+		UserAgent:   userAgent,
+		Fingerprint: fingerprint,
+		DetectedAt:  now,
+	}
+	ld.notify(anomaly, config.WebhookURL)
+	return anomaly, nil
+}
+
+// notify invokes the new device handler, tells the user through GlobalNotifier
+// and posts the anomaly to the webhook, if any
+func (ld *LoginAnomalyDetector) notify(anomaly *LoginAnomaly, webhookURL string) {
+	ld.mutex.RLock()
+	onNewDevice := ld.onNewDevice
+	ld.mutex.RUnlock()
+
+	if onNewDevice != nil {
+		onNewDevice(anomaly)
+	}
+
+	if anomaly.Email != "" {
+		go notifyNewDevice(anomaly)
+	}
+
+	if webhookURL != "" {
+		go sendNewDeviceWebhook(webhookURL, anomaly)
+	}
+}
+
+// notifyNewDevice tells the user about a login from a new device
+func notifyNewDevice(anomaly *LoginAnomaly) {
+	body := fmt.Sprintf("Hello %s,\n\nYour account was signed in to from a new device.\n\nTime: %s\nIP address: %s\nDevice: %s\n\nIf this wasn't you, change your password now.\n",
+		anomaly.Username, anomaly.DetectedAt.UTC().Format(time.RFC1123), anomaly.IPAddress, anomaly.UserAgent)
+	if err := GlobalNotifier.Send(context.Background(), anomaly.Email, "New sign-in to your account", body); err != nil {
+		log.Printf("Warning: Failed to notify user %d of a new device login: %v", anomaly.UserID, err)
+	}
+}
+
+// sendNewDeviceWebhook posts a new device login notification to a webhook
+// through GlobalNotifier
+func sendNewDeviceWebhook(webhookURL string, anomaly *LoginAnomaly) {
+	err := GlobalNotifier.SendWebhook(context.Background(), webhookURL, map[string]interface{}{
+		"event": "login_new_device",
+		"data":  anomaly,
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to send new device webhook: %v", err)
+	}
+}
+
+// GlobalLoginAnomalyDetector is the login anomaly detector used by the login handler
+var GlobalLoginAnomalyDetector = NewLoginAnomalyDetector()
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"golangmcp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupLoginAnomalyTestDB creates an in-memory database with the known devices table
+func setupLoginAnomalyTestDB(t *testing.T) *gorm.DB {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	if err := database.AutoMigrate(&models.KnownDevice{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	return database
+}
+
+func TestLoginAnomalyDetector_KnownVersusNewDevice(t *testing.T) {
+	database := setupLoginAnomalyTestDB(t)
+	user := &models.User{ID: 1, Username: "testuser"}
+
+	webhook := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		webhook <- payload
+	}))
+	defer server.Close()
+
+	detector := NewLoginAnomalyDetector()
+	config := DefaultLoginAnomalyConfig()
+	config.WebhookURL = server.URL
+	if err := detector.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	var notified []*LoginAnomaly
+	detector.SetNewDeviceHandler(func(a *LoginAnomaly) {
+		notified = append(notified, a)
+	})
+
+	// The first device is a baseline, and the same subnet and browser stay known
+	for _, ip := range []string{"192.168.1.10", "192.168.1.20"} {
+		anomaly, err := detector.CheckLogin(database, user, ip, "Firefox")
+		if err != nil {
+			t.Fatalf("Failed to check login: %v", err)
+		}
+		if anomaly != nil {
+			t.Errorf("Expected login from %s to be familiar", ip)
+		}
+	}
+
+	// Another network is a new device
+	anomaly, err := detector.CheckLogin(database, user, "10.0.0.5", "Firefox")
+	if err != nil {
+		t.Fatalf("Failed to check login: %v", err)
+	}
+	if anomaly == nil || anomaly.IPAddress != "10.0.0.5" {
+		t.Fatalf("Expected new device anomaly, got %+v", anomaly)
+	}
+	if len(notified) != 1 || notified[0].UserID != user.ID {
+		t.Errorf("Expected new device handler to be called once, got %d", len(notified))
+	}
+
+	select {
+	case payload := <-webhook:
+		if payload["event"] != "login_new_device" {
+			t.Errorf("Unexpected webhook payload: %v", payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("Expected webhook to be called")
+	}
+
+	// ...which is known from then on
+	if anomaly, _ := detector.CheckLogin(database, user, "10.0.0.5", "Firefox"); anomaly != nil {
+		t.Error("Expected second login from the new device to be familiar")
+	}
+
+	// A different browser on a known network is new too
+	if anomaly, _ := detector.CheckLogin(database, user, "192.168.1.10", "Chrome"); anomaly == nil {
+		t.Error("Expected a new user agent to be flagged")
+	}
+}
+
+func TestLoginAnomalyDetector_ConfigurableFingerprint(t *testing.T) {
+	detector := NewLoginAnomalyDetector()
+
+	if detector.Fingerprint("192.168.1.10", "Firefox") == detector.Fingerprint("192.168.2.10", "Firefox") {
+		t.Error("Expected different /24 subnets to differ")
+	}
+
+	config := DefaultLoginAnomalyConfig()
+	config.IPv4PrefixBits = 16
+	config.MatchUserAgent = false
+	detector.UpdateConfig(config)
+
+	if detector.Fingerprint("192.168.1.10", "Firefox") != detector.Fingerprint("192.168.2.10", "Chrome") {
+		t.Error("Expected same /16 subnet to match when user agents are ignored")
+	}
+
+	if err := detector.UpdateConfig(&LoginAnomalyConfig{IPv4PrefixBits: 40, MaxKnownDevices: 1}); err != ErrInvalidLoginAnomalyConfig {
+		t.Errorf("Expected ErrInvalidLoginAnomalyConfig, got %v", err)
+	}
+}
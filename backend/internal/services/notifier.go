@@ -0,0 +1,324 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notifier types selectable by NotifierConfig.Type
+const (
+	NotifierNone    = "none"
+	NotifierSMTP    = "smtp"
+	NotifierWebhook = "webhook"
+)
+
+// notifierTimeout bounds a single notification when the caller has no deadline
+const notifierTimeout = 10 * time.Second
+
+var (
+	ErrInvalidNotifierType = errors.New("notifier type must be none, smtp or webhook")
+	ErrInvalidRecipient    = errors.New("notification recipient is required")
+)
+
+// Notifier sends a message to a recipient. Email notifiers expect an address,
+// other notifiers pass the recipient on as is.
+type Notifier interface {
+	Send(ctx context.Context, recipient, subject, body string) error
+}
+
+// NotifierConfig represents which notifier the application sends messages with
+type NotifierConfig struct {
+	Type       string     `json:"type"` // none, smtp or webhook
+	SMTP       SMTPConfig `json:"smtp"`
+	WebhookURL string     `json:"webhook_url"`
+}
+
+// SMTPConfig represents the mail server of the SMTP notifier
+type SMTPConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	Username string `json:"username"` // empty sends without authentication
+	Password string `json:"password"`
+	From     string `json:"from"`
+}
+
+// DefaultNotifierConfig returns default notifier configuration, sending nothing
+func DefaultNotifierConfig() NotifierConfig {
+	return NotifierConfig{
+		Type: NotifierNone,
+		SMTP: SMTPConfig{Port: 587},
+	}
+}
+
+// Validate checks the settings of the selected notifier
+func (nc *NotifierConfig) Validate() error {
+	switch nc.Type {
+	case NotifierNone:
+		return nil
+	case NotifierSMTP:
+		if nc.SMTP.Host == "" || nc.SMTP.Port < 1 || nc.SMTP.Port > 65535 {
+			return errors.New("smtp notifier requires a host and a valid port")
+		}
+		if _, err := mail.ParseAddress(nc.SMTP.From); err != nil {
+			return fmt.Errorf("smtp notifier requires a valid from address: %v", err)
+		}
+		return nil
+	case NotifierWebhook:
+		u, err := url.Parse(nc.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("webhook notifier requires an http or https URL")
+		}
+		return nil
+	default:
+		return ErrInvalidNotifierType
+	}
+}
+
+// NewNotifier creates the notifier selected by config
+func NewNotifier(config NotifierConfig) (Notifier, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	switch config.Type {
+	case NotifierSMTP:
+		return NewSMTPNotifier(config.SMTP), nil
+	case NotifierWebhook:
+		return NewWebhookNotifier(config.WebhookURL), nil
+	default:
+		return NoopNotifier{}, nil
+	}
+}
+
+// NoopNotifier discards every message
+type NoopNotifier struct{}
+
+// Send discards the message
+func (NoopNotifier) Send(ctx context.Context, recipient, subject, body string) error {
+	return nil
+}
+
+// WebhookNotifier posts each message as JSON to a URL
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookNotifier creates a webhook notifier posting to webhookURL
+func NewWebhookNotifier(webhookURL string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    webhookURL,
+		client: &http.Client{Timeout: notifierTimeout},
+	}
+}
+
+// Send posts the message, failing on a non-2xx response
+func (wn *WebhookNotifier) Send(ctx context.Context, recipient, subject, body string) error {
+	if recipient == "" {
+		return ErrInvalidRecipient
+	}
+
+	return postWebhook(ctx, wn.client, wn.url, map[string]interface{}{
+		"recipient": recipient,
+		"subject":   subject,
+		"body":      body,
+		"sent_at":   time.Now().UTC(),
+	})
+}
+
+// postWebhook posts payload as JSON to webhookURL, failing on a non-2xx response
+func postWebhook(ctx context.Context, client *http.Client, webhookURL string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook notification: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook notification returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SMTPNotifier sends each message as a plain text email
+type SMTPNotifier struct {
+	config SMTPConfig
+}
+
+// NewSMTPNotifier creates an SMTP notifier
+func NewSMTPNotifier(config SMTPConfig) *SMTPNotifier {
+	return &SMTPNotifier{config: config}
+}
+
+// Send emails the message to the recipient address. STARTTLS is used when the
+// server offers it, and is required before credentials are sent.
+func (sn *SMTPNotifier) Send(ctx context.Context, recipient, subject, body string) error {
+	to, err := mail.ParseAddress(recipient)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRecipient, err)
+	}
+	from, err := mail.ParseAddress(sn.config.From)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(sn.config.Host, strconv.Itoa(sn.config.Port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to mail server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, sn.config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(nil); err != nil {
+			return err
+		}
+	}
+	if sn.config.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", sn.config.Username, sn.config.Password, sn.config.Host)); err != nil {
+			return fmt.Errorf("mail server authentication failed: %w", err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(formatEmail(from, to, subject, body)); err != nil {
+		writer.Close()
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// formatEmail builds a plain text message. Header values are stripped of line
+// breaks so a subject can't inject headers.
+func formatEmail(from, to *mail.Address, subject, body string) []byte {
+	subject = strings.NewReplacer("\r", " ", "\n", " ").Replace(subject)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", to.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(body, "\r\n", "\n"), "\n", "\r\n"))
+	return b.Bytes()
+}
+
+// NotifierManager holds the notifier every feature sends messages through, and
+// the client features with webhooks of their own post events with
+type NotifierManager struct {
+	notifier Notifier
+	kind     string
+	client   *http.Client
+	mutex    sync.RWMutex
+}
+
+// NewNotifierManager creates a notifier manager that sends nothing until configured
+func NewNotifierManager() *NotifierManager {
+	return &NotifierManager{
+		notifier: NoopNotifier{},
+		kind:     NotifierNone,
+		client:   &http.Client{Timeout: notifierTimeout},
+	}
+}
+
+// Configure replaces the notifier with the one selected by config
+func (nm *NotifierManager) Configure(config NotifierConfig) error {
+	notifier, err := NewNotifier(config)
+	if err != nil {
+		return err
+	}
+	nm.SetNotifier(config.Type, notifier)
+	return nil
+}
+
+// SetNotifier replaces the notifier, e.g. with a mock in tests
+func (nm *NotifierManager) SetNotifier(kind string, notifier Notifier) {
+	nm.mutex.Lock()
+	defer nm.mutex.Unlock()
+	nm.notifier = notifier
+	nm.kind = kind
+}
+
+// Type returns the type of the current notifier
+func (nm *NotifierManager) Type() string {
+	nm.mutex.RLock()
+	defer nm.mutex.RUnlock()
+	return nm.kind
+}
+
+// Send sends a message with the current notifier, within notifierTimeout when
+// ctx has no deadline of its own
+func (nm *NotifierManager) Send(ctx context.Context, recipient, subject, body string) error {
+	nm.mutex.RLock()
+	notifier := nm.notifier
+	nm.mutex.RUnlock()
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, notifierTimeout)
+		defer cancel()
+	}
+	return notifier.Send(ctx, recipient, subject, body)
+}
+
+// SendWebhook posts payload as JSON to a webhook configured by a feature rather
+// than the notifier, within notifierTimeout when ctx has no deadline of its own
+func (nm *NotifierManager) SendWebhook(ctx context.Context, webhookURL string, payload interface{}) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, notifierTimeout)
+		defer cancel()
+	}
+	return postWebhook(ctx, nm.client, webhookURL, payload)
+}
+
+// GlobalNotifier is the notifier shared by alerts, login notifications and
+// account emails
+var GlobalNotifier = NewNotifierManager()
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"golangmcp/internal/models"
+)
+
+// notification is a message captured by mockNotifier
+type notification struct {
+	Recipient string
+	Subject   string
+	Body      string
+}
+
+// mockNotifier records every message it is asked to send
+type mockNotifier struct {
+	sent chan notification
+	err  error
+}
+
+func newMockNotifier() *mockNotifier {
+	return &mockNotifier{sent: make(chan notification, 10)}
+}
+
+func (mn *mockNotifier) Send(ctx context.Context, recipient, subject, body string) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("expected a deadline")
+	}
+	mn.sent <- notification{Recipient: recipient, Subject: subject, Body: body}
+	return mn.err
+}
+
+// setupMockNotifier replaces the global notifier for the duration of a test
+func setupMockNotifier(t *testing.T) *mockNotifier {
+	orig := GlobalNotifier
+	t.Cleanup(func() { GlobalNotifier = orig })
+	GlobalNotifier = NewNotifierManager()
+
+	mock := newMockNotifier()
+	GlobalNotifier.SetNotifier("mock", mock)
+	return mock
+}
+
+func TestNewNotifier_SelectsByType(t *testing.T) {
+	tests := []struct {
+		config NotifierConfig
+		want   interface{}
+	}{
+		{DefaultNotifierConfig(), NoopNotifier{}},
+		{NotifierConfig{Type: NotifierWebhook, WebhookURL: "https://hooks.example.com/notify"}, &WebhookNotifier{}},
+		{NotifierConfig{Type: NotifierSMTP, SMTP: SMTPConfig{Host: "mail.example.com", Port: 587, From: "App <app@example.com>"}}, &SMTPNotifier{}},
+	}
+	for _, tt := range tests {
+		notifier, err := NewNotifier(tt.config)
+		if err != nil {
+			t.Fatalf("Failed to create %s notifier: %v", tt.config.Type, err)
+		}
+		switch tt.want.(type) {
+		case NoopNotifier:
+			if _, ok := notifier.(NoopNotifier); !ok {
+				t.Errorf("Expected a no-op notifier, got %T", notifier)
+			}
+		case *WebhookNotifier:
+			if _, ok := notifier.(*WebhookNotifier); !ok {
+				t.Errorf("Expected a webhook notifier, got %T", notifier)
+			}
+		case *SMTPNotifier:
+			if _, ok := notifier.(*SMTPNotifier); !ok {
+				t.Errorf("Expected an SMTP notifier, got %T", notifier)
+			}
+		}
+	}
+
+	invalid := []NotifierConfig{
+		{Type: "sms"},
+		{Type: NotifierWebhook, WebhookURL: "ftp://hooks.example.com"},
+		{Type: NotifierSMTP, SMTP: SMTPConfig{Port: 587, From: "app@example.com"}},
+		{Type: NotifierSMTP, SMTP: SMTPConfig{Host: "mail.example.com", Port: 587, From: "not an address"}},
+	}
+	for _, config := range invalid {
+		if _, err := NewNotifier(config); err == nil {
+			t.Errorf("Expected %+v to be rejected", config)
+		}
+	}
+}
+
+func TestWebhookNotifier_Send(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL)
+	if err := notifier.Send(context.Background(), "user@example.com", "Hello", "Body"); err != nil {
+		t.Fatalf("Failed to send notification: %v", err)
+	}
+	payload := <-received
+	if payload["recipient"] != "user@example.com" || payload["subject"] != "Hello" || payload["body"] != "Body" {
+		t.Errorf("Unexpected payload: %v", payload)
+	}
+
+	status = http.StatusInternalServerError
+	if err := notifier.Send(context.Background(), "user@example.com", "Hello", "Body"); err == nil {
+		t.Error("Expected a failed webhook to return an error")
+	}
+	<-received
+}
+
+func TestNotifierManager_SendWebhook(t *testing.T) {
+	received := make(chan map[string]interface{}, 1)
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		received <- payload
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	// Feature webhooks are posted whichever notifier is configured
+	nm := NewNotifierManager()
+	if err := nm.SendWebhook(context.Background(), server.URL, map[string]string{"event": "test"}); err != nil {
+		t.Fatalf("Failed to send webhook: %v", err)
+	}
+	if payload := <-received; payload["event"] != "test" {
+		t.Errorf("Unexpected payload: %v", payload)
+	}
+
+	status = http.StatusFound
+	if err := nm.SendWebhook(context.Background(), server.URL, map[string]string{"event": "test"}); err == nil {
+		t.Error("Expected a non-2xx response to return an error")
+	}
+	<-received
+}
+
+func TestFormatEmail_StripsHeaderInjection(t *testing.T) {
+	from := &mail.Address{Address: "app@example.com"}
+	to := &mail.Address{Name: "User", Address: "user@example.com"}
+
+	email := string(formatEmail(from, to, "Hi\r\nBcc: victim@example.com", "line one\nline two"))
+	if strings.Contains(email, "\r\nBcc:") {
+		t.Errorf("Expected the subject to stay on one header line, got %q", email)
+	}
+	if !strings.HasSuffix(email, "\r\n\r\nline one\r\nline two") {
+		t.Errorf("Expected a CRLF body after the headers, got %q", email)
+	}
+}
+
+func TestLoginAnomalyDetector_NotifiesUser(t *testing.T) {
+	mock := setupMockNotifier(t)
+	database := setupLoginAnomalyTestDB(t)
+	user := &models.User{ID: 1, Username: "testuser", Email: "testuser@example.com"}
+
+	detector := NewLoginAnomalyDetector()
+	if _, err := detector.CheckLogin(database, user, "192.168.1.10", "Firefox"); err != nil {
+		t.Fatalf("Failed to check login: %v", err)
+	}
+	if _, err := detector.CheckLogin(database, user, "10.0.0.1", "Firefox"); err != nil {
+		t.Fatalf("Failed to check login: %v", err)
+	}
+
+	select {
+	case sent := <-mock.sent:
+		if sent.Recipient != user.Email || !strings.Contains(sent.Body, "10.0.0.1") {
+			t.Errorf("Expected the user to be told about the new device, got %+v", sent)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a new device notification")
+	}
+
+	// The baseline device sends nothing
+	select {
+	case sent := <-mock.sent:
+		t.Errorf("Expected a single notification, also got %+v", sent)
+	default:
+	}
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"golangmcp/internal/models"
+)
+
+// OnboardingWelcomeNotification is the built-in hook welcoming new users
+// through GlobalNotifier
+const OnboardingWelcomeNotification = "welcome_notification"
+
+var (
+	ErrInvalidOnboardingPolicy  = errors.New("onboarding hook timeout must be at least 1 second")
+	ErrUnknownOnboardingHook    = errors.New("unknown onboarding hook")
+	ErrOnboardingHookRegistered = errors.New("onboarding hook is already registered")
+)
+
+// OnboardingHook is a side effect run for each newly registered user, such as
+// a welcome message or setting up their defaults
+type OnboardingHook func(ctx context.Context, user models.User) error
+
+// OnboardingPolicy represents which onboarding hooks run after registration
+type OnboardingPolicy struct {
+	Hooks   map[string]bool // by hook name; hooks not listed don't run
+	Timeout time.Duration   // per hook
+}
+
+// DefaultOnboardingPolicy returns default onboarding policy
+func DefaultOnboardingPolicy() *OnboardingPolicy {
+	return &OnboardingPolicy{
+		Hooks:   map[string]bool{OnboardingWelcomeNotification: true},
+		Timeout: 30 * time.Second,
+	}
+}
+
+// Validate checks the policy for invalid values
+func (op *OnboardingPolicy) Validate() error {
+	if op.Timeout < time.Second {
+		return ErrInvalidOnboardingPolicy
+	}
+	return nil
+}
+
+// OnboardingManager runs the enabled onboarding hooks of newly registered
+// users in the background, so registration never waits on them. Hooks run in
+// the order they were registered, each isolated from the failure of others.
+type OnboardingManager struct {
+	policy    *OnboardingPolicy
+	hooks     map[string]OnboardingHook
+	order     []string
+	onFailure func(hook string, user models.User, err error)
+	running   sync.WaitGroup
+	mutex     sync.RWMutex
+}
+
+// NewOnboardingManager creates an onboarding manager with the built-in hooks
+func NewOnboardingManager() *OnboardingManager {
+	om := &OnboardingManager{
+		policy: DefaultOnboardingPolicy(),
+		hooks:  make(map[string]OnboardingHook),
+	}
+	om.RegisterHook(OnboardingWelcomeNotification, sendWelcomeNotification)
+	return om
+}
+
+// RegisterHook adds a hook under name. It only runs once the policy enables it.
+func (om *OnboardingManager) RegisterHook(name string, hook OnboardingHook) error {
+	om.mutex.Lock()
+	defer om.mutex.Unlock()
+
+	if _, exists := om.hooks[name]; exists {
+		return fmt.Errorf("%w: %s", ErrOnboardingHookRegistered, name)
+	}
+	om.hooks[name] = hook
+	om.order = append(om.order, name)
+	return nil
+}
+
+// SetFailureHandler sets a function called when a hook fails, e.g. to audit it
+func (om *OnboardingManager) SetFailureHandler(handler func(hook string, user models.User, err error)) {
+	om.mutex.Lock()
+	defer om.mutex.Unlock()
+	om.onFailure = handler
+}
+
+// GetPolicy returns a copy of the current onboarding policy
+func (om *OnboardingManager) GetPolicy() OnboardingPolicy {
+	om.mutex.RLock()
+	defer om.mutex.RUnlock()
+
+	policy := *om.policy
+	policy.Hooks = make(map[string]bool, len(om.policy.Hooks))
+	for name, enabled := range om.policy.Hooks {
+		policy.Hooks[name] = enabled
+	}
+	return policy
+}
+
+// UpdatePolicy validates and replaces the onboarding policy. Every hook it
+// names must be registered, so a typo can't silently leave a hook off.
+func (om *OnboardingManager) UpdatePolicy(policy *OnboardingPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	stored := *policy
+	stored.Hooks = make(map[string]bool, len(policy.Hooks))
+
+	om.mutex.Lock()
+	defer om.mutex.Unlock()
+	for name, enabled := range policy.Hooks {
+		if _, exists := om.hooks[name]; !exists {
+			return fmt.Errorf("%w: %s", ErrUnknownOnboardingHook, name)
+		}
+		stored.Hooks[name] = enabled
+	}
+	om.policy = &stored
+	return nil
+}
+
+// Run starts the enabled hooks for a newly registered user and returns at once
+func (om *OnboardingManager) Run(user models.User) {
+	om.mutex.RLock()
+	timeout := om.policy.Timeout
+	onFailure := om.onFailure
+	var names []string
+	var hooks []OnboardingHook
+	for _, name := range om.order {
+		if om.policy.Hooks[name] {
+			names = append(names, name)
+			hooks = append(hooks, om.hooks[name])
+		}
+	}
+	om.mutex.RUnlock()
+
+	if len(hooks) == 0 {
+		return
+	}
+
+	om.running.Add(1)
+	go func() {
+		defer om.running.Done()
+		for i, hook := range hooks {
+			if err := runOnboardingHook(hook, user, timeout); err != nil {
+				log.Printf("Warning: Onboarding hook %s failed for user %d: %v", names[i], user.ID, err)
+				if onFailure != nil {
+					onFailure(names[i], user, err)
+				}
+			}
+		}
+	}()
+}
+
+// Wait blocks until every started onboarding run has finished
+func (om *OnboardingManager) Wait() {
+	om.running.Wait()
+}
+
+// runOnboardingHook runs one hook within timeout, turning a panic into an error
+func runOnboardingHook(hook OnboardingHook, user models.User, timeout time.Duration) (err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+	return hook(ctx, user)
+}
+
+// sendWelcomeNotification welcomes a new user through GlobalNotifier
+func sendWelcomeNotification(ctx context.Context, user models.User) error {
+	if user.Email == "" {
+		return nil
+	}
+	body := fmt.Sprintf("Hello %s,\n\nWelcome! Your account has been created and you can sign in now.\n", user.Username)
+	return GlobalNotifier.Send(ctx, user.Email, "Welcome to your new account", body)
+}
+
+// GlobalOnboarding runs the onboarding hooks of users registering with the application
+var GlobalOnboarding = NewOnboardingManager()
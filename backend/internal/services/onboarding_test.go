@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golangmcp/internal/models"
+)
+
+func TestOnboardingManager_RunsEnabledHooksDespiteFailures(t *testing.T) {
+	om := NewOnboardingManager()
+
+	var mutex sync.Mutex
+	var ran []string
+	record := func(name string, err error) OnboardingHook {
+		return func(ctx context.Context, user models.User) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			ran = append(ran, name)
+			return err
+		}
+	}
+	om.RegisterHook("failing", record("failing", errors.New("quota service unavailable")))
+	om.RegisterHook("panicking", func(ctx context.Context, user models.User) error {
+		panic("folder setup broke")
+	})
+	om.RegisterHook("starter_quota", record("starter_quota", nil))
+	om.RegisterHook("disabled", record("disabled", nil))
+	if err := om.RegisterHook("failing", record("failing", nil)); !errors.Is(err, ErrOnboardingHookRegistered) {
+		t.Errorf("Expected a hook name to be registered once, got %v", err)
+	}
+
+	failures := make(map[string]string)
+	om.SetFailureHandler(func(hook string, user models.User, err error) {
+		failures[hook] = err.Error()
+	})
+	err := om.UpdatePolicy(&OnboardingPolicy{
+		Hooks:   map[string]bool{"failing": true, "panicking": true, "starter_quota": true, "disabled": false},
+		Timeout: time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+
+	om.Run(models.User{ID: 7, Username: "newcomer"})
+	om.Wait()
+
+	if strings.Join(ran, ",") != "failing,starter_quota" {
+		t.Errorf("Expected the enabled hooks to run in order past failures, got %v", ran)
+	}
+	if len(failures) != 2 || failures["failing"] != "quota service unavailable" || !strings.Contains(failures["panicking"], "folder setup broke") {
+		t.Errorf("Expected both failures to be reported, got %v", failures)
+	}
+}
+
+func TestOnboardingManager_UpdatePolicyRejectsUnknownHooks(t *testing.T) {
+	om := NewOnboardingManager()
+
+	err := om.UpdatePolicy(&OnboardingPolicy{Hooks: map[string]bool{"welcome_notifcation": true}, Timeout: time.Second})
+	if !errors.Is(err, ErrUnknownOnboardingHook) {
+		t.Errorf("Expected an unknown hook to be rejected, got %v", err)
+	}
+	if err := om.UpdatePolicy(&OnboardingPolicy{Timeout: time.Millisecond}); err != ErrInvalidOnboardingPolicy {
+		t.Errorf("Expected a timeout under a second to be rejected, got %v", err)
+	}
+	if !om.GetPolicy().Hooks[OnboardingWelcomeNotification] {
+		t.Errorf("Expected the default policy to be kept, got %+v", om.GetPolicy())
+	}
+}
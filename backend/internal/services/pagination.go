@@ -31,6 +31,9 @@ type PaginationResponse struct {
 type PaginationService struct {
 	defaultPageSize int
 	maxPageSize     int
+	roleDefaults    map[string]int // default page size per role, overrides defaultPageSize
+	analyzer        *PaginationAnalyzer
+	mutex           sync.RWMutex
 }
 
 // NewPaginationService creates a new pagination service
@@ -38,13 +41,75 @@ func NewPaginationService(defaultPageSize, maxPageSize int) *PaginationService {
 	return &PaginationService{
 		defaultPageSize: defaultPageSize,
 		maxPageSize:     maxPageSize,
+		roleDefaults:    make(map[string]int),
 	}
 }
 
+// SetRoleDefaultPageSize sets the page size used for a role when the client
+// doesn't specify one. A size of 0 removes the override.
+func (ps *PaginationService) SetRoleDefaultPageSize(role string, pageSize int) error {
+	if pageSize < 0 {
+		return fmt.Errorf("page size cannot be negative")
+	}
+	if pageSize > ps.maxPageSize {
+		return fmt.Errorf("page size cannot exceed %d", ps.maxPageSize)
+	}
+
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	if pageSize == 0 {
+		delete(ps.roleDefaults, role)
+		return nil
+	}
+	ps.roleDefaults[role] = pageSize
+	return nil
+}
+
+// GetRoleDefaultPageSizes returns the per-role default page sizes
+func (ps *PaginationService) GetRoleDefaultPageSizes() map[string]int {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+
+	defaults := make(map[string]int, len(ps.roleDefaults))
+	for role, pageSize := range ps.roleDefaults {
+		defaults[role] = pageSize
+	}
+	return defaults
+}
+
+// DefaultPageSizeForRole returns the effective default page size for a role
+func (ps *PaginationService) DefaultPageSizeForRole(role string) int {
+	ps.mutex.RLock()
+	defer ps.mutex.RUnlock()
+
+	if pageSize, ok := ps.roleDefaults[role]; ok {
+		return pageSize
+	}
+	return ps.defaultPageSize
+}
+
+// SetAnalyzer sets the analyzer that records the page size of every parsed request
+func (ps *PaginationService) SetAnalyzer(analyzer *PaginationAnalyzer) {
+	ps.mutex.Lock()
+	defer ps.mutex.Unlock()
+	ps.analyzer = analyzer
+}
+
+// GetMaxPageSize returns the maximum page size
+func (ps *PaginationService) GetMaxPageSize() int {
+	return ps.maxPageSize
+}
+
 // ParsePaginationRequest parses pagination parameters from query parameters
 func (ps *PaginationService) ParsePaginationRequest(pageStr, pageSizeStr string) *PaginationRequest {
+	return ps.ParsePaginationRequestForRole("", pageStr, pageSizeStr)
+}
+
+// ParsePaginationRequestForRole parses pagination parameters from query
+// parameters, falling back to the role's default page size
+func (ps *PaginationService) ParsePaginationRequestForRole(role, pageStr, pageSizeStr string) *PaginationRequest {
 	page := 1
-	pageSize := ps.defaultPageSize
+	pageSize := ps.DefaultPageSizeForRole(role)
 	
 	if pageStr != "" {
 		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
@@ -63,6 +128,13 @@ func (ps *PaginationService) ParsePaginationRequest(pageStr, pageSizeStr string)
 	}
 	
 	offset := (page - 1) * pageSize
+
+	ps.mutex.RLock()
+	analyzer := ps.analyzer
+	ps.mutex.RUnlock()
+	if analyzer != nil {
+		analyzer.RecordRequest(pageSize)
+	}
 	
 	return &PaginationRequest{
 		Page:     page,
@@ -131,8 +203,9 @@ func NewPaginatedResult(data interface{}, pagination *PaginationResponse) *Pagin
 
 // PaginationConfig represents pagination configuration
 type PaginationConfig struct {
-	DefaultPageSize int `json:"default_page_size"`
-	MaxPageSize     int `json:"max_page_size"`
+	DefaultPageSize      int            `json:"default_page_size"`
+	MaxPageSize          int            `json:"max_page_size"`
+	RoleDefaultPageSizes map[string]int `json:"role_default_page_sizes"`
 }
 
 // DefaultPaginationConfig returns default pagination configuration
@@ -140,6 +213,10 @@ func DefaultPaginationConfig() *PaginationConfig {
 	return &PaginationConfig{
 		DefaultPageSize: 20,
 		MaxPageSize:     100,
+		RoleDefaultPageSizes: map[string]int{
+			"admin": 50,
+			"user":  20,
+		},
 	}
 }
 
@@ -216,7 +293,8 @@ func (pa *PaginationAnalyzer) GetStats() *PaginationStats {
 		totalRequests += count
 		totalPageSize += int64(pageSize) * count
 		
-		if count > maxCount {
+		// Ties go to the smaller page size so the result is stable
+		if count > maxCount || (count == maxCount && pageSize < mostUsedPageSize) {
 			maxCount = count
 			mostUsedPageSize = pageSize
 		}
@@ -0,0 +1,52 @@
+package services
+
+import "testing"
+
+func newRolePaginationService(t *testing.T) *PaginationService {
+	ps := NewPaginationService(20, 100)
+	if err := ps.SetRoleDefaultPageSize("admin", 50); err != nil {
+		t.Fatalf("Failed to set role default: %v", err)
+	}
+	return ps
+}
+
+func TestParsePaginationRequestForRole_UsesRoleDefault(t *testing.T) {
+	ps := newRolePaginationService(t)
+
+	tests := []struct {
+		role     string
+		pageSize string
+		want     int
+	}{
+		{"admin", "", 50},
+		{"user", "", 20},
+		{"", "", 20},
+		{"admin", "10", 10},   // explicit size wins
+		{"admin", "500", 100}, // still capped
+		{"admin", "abc", 50},
+	}
+
+	for _, tt := range tests {
+		req := ps.ParsePaginationRequestForRole(tt.role, "2", tt.pageSize)
+		if req.PageSize != tt.want {
+			t.Errorf("role %q page_size %q: expected page size %d, got %d", tt.role, tt.pageSize, tt.want, req.PageSize)
+		}
+		if req.Offset != tt.want {
+			t.Errorf("role %q page_size %q: expected offset %d, got %d", tt.role, tt.pageSize, tt.want, req.Offset)
+		}
+	}
+}
+
+func TestSetRoleDefaultPageSize_EnforcesMax(t *testing.T) {
+	ps := newRolePaginationService(t)
+
+	if err := ps.SetRoleDefaultPageSize("moderator", 101); err == nil {
+		t.Error("Expected a role default above the max page size to be rejected")
+	}
+	if err := ps.SetRoleDefaultPageSize("admin", 0); err != nil {
+		t.Fatalf("Failed to remove role default: %v", err)
+	}
+	if size := ps.DefaultPageSizeForRole("admin"); size != 20 {
+		t.Errorf("Expected removed override to fall back to 20, got %d", size)
+	}
+}
@@ -252,9 +252,7 @@ func NewRateLimitManager() *RateLimitManager {
 		configs:      make(map[string]*RateLimitConfig),
 	}
 	
-	// Start cleanup goroutine
-	go manager.startCleanup()
-	
+	// Old entries are removed periodically by Cleanup
 	return manager
 }
 
@@ -294,14 +292,9 @@ func (rlm *RateLimitManager) GetAllConfigs() map[string]*RateLimitConfig {
 	return configs
 }
 
-// startCleanup starts a goroutine to clean up old rate limit entries
-func (rlm *RateLimitManager) startCleanup() {
-	ticker := time.NewTicker(5 * time.Minute)
-	defer ticker.Stop()
-	
-	for range ticker.C {
-		rlm.multiLimiter.CleanupAll()
-	}
+// Cleanup removes old rate limit entries
+func (rlm *RateLimitManager) Cleanup() {
+	rlm.multiLimiter.CleanupAll()
 }
 
 // DefaultRateLimitConfigs returns default rate limiting configurations
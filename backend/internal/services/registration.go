@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"sync"
+
+	"golangmcp/internal/models"
+)
+
+var (
+	ErrInvalidRegistrationPolicy = errors.New("default role must be a valid role that is not elevated")
+	ErrElevatedRoleRequested     = errors.New("elevated roles can only be assigned by an admin")
+)
+
+// RegistrationPolicy represents how self-registered accounts are set up
+type RegistrationPolicy struct {
+	DefaultRole string `json:"default_role"` // assigned when registration requests no role
+}
+
+// DefaultRegistrationPolicy returns default registration policy
+func DefaultRegistrationPolicy() *RegistrationPolicy {
+	return &RegistrationPolicy{
+		DefaultRole: "user",
+	}
+}
+
+// Validate checks the policy for invalid values. The default role can never be
+// elevated, or anyone could register as an admin.
+func (rp *RegistrationPolicy) Validate() error {
+	if rp.DefaultRole == "" || models.ValidateRole(rp.DefaultRole) != nil || models.IsElevatedRole(rp.DefaultRole) {
+		return ErrInvalidRegistrationPolicy
+	}
+	return nil
+}
+
+// RegistrationPolicyManager decides the role of self-registered accounts
+type RegistrationPolicyManager struct {
+	policy *RegistrationPolicy
+	mutex  sync.RWMutex
+}
+
+// NewRegistrationPolicyManager creates a new registration policy manager
+func NewRegistrationPolicyManager() *RegistrationPolicyManager {
+	return &RegistrationPolicyManager{
+		policy: DefaultRegistrationPolicy(),
+	}
+}
+
+// GetPolicy returns the current registration policy
+func (rm *RegistrationPolicyManager) GetPolicy() RegistrationPolicy {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+	return *rm.policy
+}
+
+// UpdatePolicy validates and replaces the registration policy
+func (rm *RegistrationPolicyManager) UpdatePolicy(policy *RegistrationPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	rm.policy = policy
+	return nil
+}
+
+// ResolveRole returns the role a self-registration requesting requested gets,
+// the default role when none is requested. Elevated roles are refused with
+// ErrElevatedRoleRequested, unknown ones with models.ErrInvalidRole.
+func (rm *RegistrationPolicyManager) ResolveRole(requested string) (string, error) {
+	if requested == "" {
+		return rm.GetPolicy().DefaultRole, nil
+	}
+	if models.IsElevatedRole(requested) {
+		return "", ErrElevatedRoleRequested
+	}
+	if err := models.ValidateRole(requested); err != nil {
+		return "", err
+	}
+	return requested, nil
+}
+
+// GlobalRegistrationPolicy decides the role of accounts created through /register
+var GlobalRegistrationPolicy = NewRegistrationPolicyManager()
@@ -0,0 +1,49 @@
+package services
+
+import (
+	"testing"
+
+	"golangmcp/internal/models"
+)
+
+func TestRegistrationPolicy_Validate(t *testing.T) {
+	for _, role := range []string{"", "admin", "moderator", "superuser"} {
+		policy := &RegistrationPolicy{DefaultRole: role}
+		if err := policy.Validate(); err != ErrInvalidRegistrationPolicy {
+			t.Errorf("Expected default role %q to be rejected, got %v", role, err)
+		}
+	}
+
+	if err := DefaultRegistrationPolicy().Validate(); err != nil {
+		t.Errorf("Expected the default policy to be valid, got %v", err)
+	}
+}
+
+func TestRegistrationPolicyManager_ResolveRole(t *testing.T) {
+	rm := NewRegistrationPolicyManager()
+
+	tests := []struct {
+		requested string
+		want      string
+		wantErr   error
+	}{
+		{"", "user", nil},
+		{"user", "user", nil},
+		{"admin", "", ErrElevatedRoleRequested},
+		{"moderator", "", ErrElevatedRoleRequested},
+		{"superuser", "", models.ErrInvalidRole},
+	}
+	for _, tt := range tests {
+		role, err := rm.ResolveRole(tt.requested)
+		if role != tt.want || err != tt.wantErr {
+			t.Errorf("ResolveRole(%q) = %q, %v; expected %q, %v", tt.requested, role, err, tt.want, tt.wantErr)
+		}
+	}
+
+	if err := rm.UpdatePolicy(&RegistrationPolicy{DefaultRole: "admin"}); err == nil {
+		t.Error("Expected an elevated default role to be rejected")
+	}
+	if role, _ := rm.ResolveRole(""); role != "user" {
+		t.Errorf("Expected a rejected update to keep the default role, got %q", role)
+	}
+}
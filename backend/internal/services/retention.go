@@ -0,0 +1,158 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"golangmcp/internal/db"
+	"golangmcp/internal/models"
+	"golangmcp/internal/session"
+	"gorm.io/gorm"
+)
+
+// RetentionRule represents how long a resource is kept before cleanup
+type RetentionRule struct {
+	Enabled bool `json:"enabled"` // false = keep forever
+	Days    int  `json:"days"`
+}
+
+// RetentionPolicy represents data retention configuration per resource
+type RetentionPolicy struct {
+	AuditLogs              RetentionRule `json:"audit_logs"`
+	FileAccessLogs         RetentionRule `json:"file_access_logs"`
+	DeletedFiles           RetentionRule `json:"deleted_files"`    // counted from deletion
+	ExpiredSessions        RetentionRule `json:"expired_sessions"` // counted from expiry
+	Commands               RetentionRule `json:"commands"`
+	CleanupIntervalMinutes int           `json:"cleanup_interval_minutes"`
+}
+
+// DefaultRetentionPolicy returns default retention policy
+func DefaultRetentionPolicy() *RetentionPolicy {
+	return &RetentionPolicy{
+		AuditLogs:              RetentionRule{Enabled: true, Days: 90},
+		FileAccessLogs:         RetentionRule{Enabled: true, Days: 90},
+		DeletedFiles:           RetentionRule{Enabled: true, Days: 30},
+		ExpiredSessions:        RetentionRule{Enabled: true, Days: 0},
+		Commands:               RetentionRule{Enabled: false, Days: 90},
+		CleanupIntervalMinutes: 5,
+	}
+}
+
+var ErrInvalidRetentionPolicy = errors.New("retention days cannot be negative and cleanup interval must be at least 1 minute")
+
+// Validate checks the policy for invalid values
+func (rp *RetentionPolicy) Validate() error {
+	for _, rule := range []RetentionRule{rp.AuditLogs, rp.FileAccessLogs, rp.DeletedFiles, rp.ExpiredSessions, rp.Commands} {
+		if rule.Days < 0 {
+			return ErrInvalidRetentionPolicy
+		}
+	}
+
+	if rp.CleanupIntervalMinutes < 1 {
+		return ErrInvalidRetentionPolicy
+	}
+
+	return nil
+}
+
+// cutoff returns the time before which a resource is removed
+func (rr RetentionRule) cutoff(now time.Time) time.Time {
+	return now.AddDate(0, 0, -rr.Days)
+}
+
+// RetentionManager runs all data cleanup jobs from a single retention policy
+type RetentionManager struct {
+	policy *RetentionPolicy
+	mutex  sync.RWMutex
+}
+
+// NewRetentionManager creates a new retention manager
+func NewRetentionManager() *RetentionManager {
+	return &RetentionManager{
+		policy: DefaultRetentionPolicy(),
+	}
+}
+
+// GetPolicy returns the current retention policy
+func (rm *RetentionManager) GetPolicy() RetentionPolicy {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+	return *rm.policy
+}
+
+// UpdatePolicy validates and replaces the retention policy
+func (rm *RetentionManager) UpdatePolicy(policy *RetentionPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	rm.mutex.Lock()
+	defer rm.mutex.Unlock()
+	rm.policy = policy
+	return nil
+}
+
+// RunCleanup removes data older than the policy allows and returns the number
+// of records removed per resource. Failures are logged and do not stop other resources.
+func (rm *RetentionManager) RunCleanup(database *gorm.DB) map[string]int64 {
+	policy := rm.GetPolicy()
+	now := time.Now()
+	report := make(map[string]int64)
+
+	jobs := []struct {
+		name  string
+		rule  RetentionRule
+		clean func(time.Time) (int64, error)
+	}{
+		{"audit_logs", policy.AuditLogs, func(cutoff time.Time) (int64, error) {
+			return models.DeleteAuditLogsBefore(database, cutoff)
+		}},
+		{"file_access_logs", policy.FileAccessLogs, func(cutoff time.Time) (int64, error) {
+			return models.DeleteFileAccessLogsBefore(database, cutoff)
+		}},
+		{"deleted_files", policy.DeletedFiles, func(cutoff time.Time) (int64, error) {
+			return models.PurgeDeletedFilesBefore(database, cutoff)
+		}},
+		{"commands", policy.Commands, func(cutoff time.Time) (int64, error) {
+			return models.DeleteCommandsBefore(database, cutoff)
+		}},
+		{"expired_sessions", policy.ExpiredSessions, func(cutoff time.Time) (int64, error) {
+			return int64(session.GlobalSessionManager.CleanupSessionsExpiredBefore(cutoff)), nil
+		}},
+	}
+
+	for _, job := range jobs {
+		if !job.rule.Enabled {
+			continue
+		}
+
+		if database == nil && job.name != "expired_sessions" {
+			continue
+		}
+
+		removed, err := job.clean(job.rule.cutoff(now))
+		if err != nil {
+			log.Printf("Warning: Failed to clean up %s: %v", job.name, err)
+			continue
+		}
+		report[job.name] = removed
+	}
+
+	return report
+}
+
+// Register schedules cleanup against the application database, at the interval
+// the current policy sets
+func (rm *RetentionManager) Register(scheduler *Scheduler) error {
+	interval := func() time.Duration {
+		return time.Duration(rm.GetPolicy().CleanupIntervalMinutes) * time.Minute
+	}
+	return scheduler.Register("retention_cleanup", interval, func() (interface{}, error) {
+		return rm.RunCleanup(db.DB), nil
+	})
+}
+
+// GlobalRetentionManager is the retention manager driving all cleanup jobs
+var GlobalRetentionManager = NewRetentionManager()
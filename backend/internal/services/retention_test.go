@@ -0,0 +1,102 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"golangmcp/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupRetentionTestDB creates an in-memory database with the tables cleaned by retention
+func setupRetentionTestDB(t *testing.T) *gorm.DB {
+	database, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to test database: %v", err)
+	}
+
+	err = database.AutoMigrate(&models.User{}, &models.File{}, &models.FileAccessLog{}, &models.Command{}, &models.SecurityAuditLog{})
+	if err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	return database
+}
+
+func daysAgo(days int) time.Time {
+	return time.Now().AddDate(0, 0, -days)
+}
+
+func TestRetentionManager_RespectsConfiguredWindows(t *testing.T) {
+	database := setupRetentionTestDB(t)
+
+	for _, age := range []int{1, 10, 40} {
+		database.Create(&models.SecurityAuditLog{EventType: "auth", EventAction: "login", CreatedAt: daysAgo(age)})
+		database.Create(&models.FileAccessLog{FileID: 1, UserID: 1, Action: "view", CreatedAt: daysAgo(age)})
+		database.Create(&models.Command{Command: "ls", UserID: 1, CreatedAt: daysAgo(age)})
+
+		file := &models.File{Filename: "f", OriginalName: "f", Path: "p", Hash: time.Now().String(), UserID: 1}
+		database.Create(file)
+		database.Model(file).Update("deleted_at", daysAgo(age))
+	}
+
+	rm := NewRetentionManager()
+	err := rm.UpdatePolicy(&RetentionPolicy{
+		AuditLogs:              RetentionRule{Enabled: true, Days: 30},
+		FileAccessLogs:         RetentionRule{Enabled: true, Days: 5},
+		DeletedFiles:           RetentionRule{Enabled: true, Days: 20},
+		Commands:               RetentionRule{Enabled: false, Days: 1},
+		CleanupIntervalMinutes: 5,
+	})
+	if err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+
+	removed := rm.RunCleanup(database)
+
+	expected := map[string]int64{"audit_logs": 1, "file_access_logs": 2, "deleted_files": 1}
+	for resource, count := range expected {
+		if removed[resource] != count {
+			t.Errorf("%s: expected %d removed, got %d", resource, count, removed[resource])
+		}
+	}
+
+	if _, ran := removed["commands"]; ran {
+		t.Error("Expected disabled commands rule to be skipped")
+	}
+
+	var remaining int64
+	database.Model(&models.SecurityAuditLog{}).Count(&remaining)
+	if remaining != 2 {
+		t.Errorf("Expected 2 audit logs to remain, got %d", remaining)
+	}
+
+	database.Model(&models.Command{}).Count(&remaining)
+	if remaining != 3 {
+		t.Errorf("Expected all 3 commands to remain, got %d", remaining)
+	}
+
+	database.Unscoped().Model(&models.File{}).Count(&remaining)
+	if remaining != 2 {
+		t.Errorf("Expected 2 soft-deleted files to remain, got %d", remaining)
+	}
+}
+
+func TestRetentionPolicy_Validate(t *testing.T) {
+	policy := DefaultRetentionPolicy()
+	if err := policy.Validate(); err != nil {
+		t.Errorf("Expected default policy to be valid, got %v", err)
+	}
+
+	policy.AuditLogs.Days = -1
+	if err := policy.Validate(); err != ErrInvalidRetentionPolicy {
+		t.Errorf("Expected ErrInvalidRetentionPolicy for negative days, got %v", err)
+	}
+
+	policy = DefaultRetentionPolicy()
+	policy.CleanupIntervalMinutes = 0
+	if err := policy.Validate(); err != ErrInvalidRetentionPolicy {
+		t.Errorf("Expected ErrInvalidRetentionPolicy for zero interval, got %v", err)
+	}
+}
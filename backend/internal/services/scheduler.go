@@ -0,0 +1,229 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+	ErrInvalidJob  = errors.New("job needs a name, an interval and a run function")
+)
+
+// JobFunc runs a background job and returns a summary of what it did
+type JobFunc func() (interface{}, error)
+
+// JobStatus represents the state of a scheduled job
+type JobStatus struct {
+	Name         string      `json:"name"`
+	Interval     string      `json:"interval"`
+	Running      bool        `json:"running"`
+	RunCount     int64       `json:"run_count"`
+	LastRun      *time.Time  `json:"last_run,omitempty"`
+	LastDuration string      `json:"last_duration,omitempty"`
+	LastError    string      `json:"last_error,omitempty"`
+	LastResult   interface{} `json:"last_result,omitempty"`
+	NextRun      *time.Time  `json:"next_run,omitempty"`
+}
+
+// scheduledJob is a registered job and its status, guarded by the scheduler mutex
+type scheduledJob struct {
+	status   JobStatus
+	interval func() time.Duration
+	run      JobFunc
+	stop     chan struct{} // closed to end the job's loop
+}
+
+// Scheduler runs named background jobs at intervals and records how each run went.
+// Runs of the same job never overlap; a scheduled run is skipped while one is in progress.
+type Scheduler struct {
+	jobs    map[string]*scheduledJob
+	started bool
+	mutex   sync.Mutex
+}
+
+// NewScheduler creates a new scheduler
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		jobs: make(map[string]*scheduledJob),
+	}
+}
+
+// FixedInterval returns an interval function for a constant interval
+func FixedInterval(interval time.Duration) func() time.Duration {
+	return func() time.Duration { return interval }
+}
+
+// Register adds a job, replacing any job with the same name. The interval is
+// read before every wait, so jobs can follow configuration changes.
+func (s *Scheduler) Register(name string, interval func() time.Duration, run JobFunc) error {
+	if name == "" || interval == nil || run == nil {
+		return ErrInvalidJob
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if existing, exists := s.jobs[name]; exists {
+		close(existing.stop)
+	}
+
+	job := &scheduledJob{
+		status:   JobStatus{Name: name},
+		interval: interval,
+		run:      run,
+		stop:     make(chan struct{}),
+	}
+	s.jobs[name] = job
+
+	if s.started {
+		go s.loop(job)
+	}
+	return nil
+}
+
+// Start runs every registered job, and any registered later, at its interval
+func (s *Scheduler) Start() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.started {
+		return
+	}
+	s.started = true
+
+	for _, job := range s.jobs {
+		go s.loop(job)
+	}
+}
+
+// Stop ends the job loops; runs in progress finish
+func (s *Scheduler) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.started {
+		return
+	}
+	s.started = false
+
+	for _, job := range s.jobs {
+		close(job.stop)
+		job.stop = make(chan struct{})
+		job.status.NextRun = nil
+	}
+}
+
+// Running reports whether the scheduler has been started and not stopped
+func (s *Scheduler) Running() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.started
+}
+
+// Trigger runs a job now and waits for it to finish
+func (s *Scheduler) Trigger(name string) (*JobStatus, error) {
+	s.mutex.Lock()
+	job, exists := s.jobs[name]
+	s.mutex.Unlock()
+
+	if !exists {
+		return nil, ErrJobNotFound
+	}
+	return s.execute(job)
+}
+
+// Statuses returns the status of every job, sorted by name
+func (s *Scheduler) Statuses() []JobStatus {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status := job.status
+		status.Interval = job.interval().String()
+		statuses = append(statuses, status)
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// loop runs a job at its interval until its stop channel is closed
+func (s *Scheduler) loop(job *scheduledJob) {
+	s.mutex.Lock()
+	stop := job.stop
+	s.mutex.Unlock()
+
+	for {
+		interval := job.interval()
+		next := time.Now().Add(interval)
+
+		s.mutex.Lock()
+		job.status.NextRun = &next
+		s.mutex.Unlock()
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+			if _, err := s.execute(job); err != nil && err != ErrJobRunning {
+				log.Printf("Warning: Job %s failed: %v", job.status.Name, err)
+			}
+		case <-stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// execute runs a job once, recording the outcome in its status
+func (s *Scheduler) execute(job *scheduledJob) (*JobStatus, error) {
+	s.mutex.Lock()
+	if job.status.Running {
+		s.mutex.Unlock()
+		return nil, ErrJobRunning
+	}
+	job.status.Running = true
+	s.mutex.Unlock()
+
+	start := time.Now()
+	result, err := runJob(job.run)
+	duration := time.Since(start)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	job.status.Running = false
+	job.status.RunCount++
+	job.status.LastRun = &start
+	job.status.LastDuration = duration.String()
+	job.status.LastResult = result
+	job.status.LastError = ""
+	if err != nil {
+		job.status.LastError = err.Error()
+	}
+
+	status := job.status
+	status.Interval = job.interval().String()
+	return &status, err
+}
+
+// runJob runs a job function, turning a panic into an error so the loop survives
+func runJob(run JobFunc) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return run()
+}
+
+// GlobalScheduler runs the application's background jobs
+var GlobalScheduler = NewScheduler()
@@ -0,0 +1,108 @@
+package services
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler_RegisterAndTrigger(t *testing.T) {
+	scheduler := NewScheduler()
+
+	if err := scheduler.Register("", FixedInterval(time.Hour), func() (interface{}, error) { return nil, nil }); err != ErrInvalidJob {
+		t.Errorf("Expected ErrInvalidJob for an unnamed job, got %v", err)
+	}
+
+	var runs int32
+	err := scheduler.Register("cleanup", FixedInterval(time.Hour), func() (interface{}, error) {
+		return atomic.AddInt32(&runs, 1), nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to register job: %v", err)
+	}
+
+	statuses := scheduler.Statuses()
+	if len(statuses) != 1 || statuses[0].Name != "cleanup" || statuses[0].Interval != "1h0m0s" || statuses[0].LastRun != nil {
+		t.Fatalf("Expected one registered job that never ran, got %+v", statuses)
+	}
+
+	status, err := scheduler.Trigger("cleanup")
+	if err != nil {
+		t.Fatalf("Failed to trigger job: %v", err)
+	}
+	if status.RunCount != 1 || status.LastRun == nil || status.LastResult != int32(1) {
+		t.Errorf("Expected a recorded run with its result, got %+v", status)
+	}
+
+	if _, err := scheduler.Trigger("missing"); err != ErrJobNotFound {
+		t.Errorf("Expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestScheduler_RecordsErrorsAndPanics(t *testing.T) {
+	scheduler := NewScheduler()
+	scheduler.Register("failing", FixedInterval(time.Hour), func() (interface{}, error) {
+		return nil, errors.New("database is locked")
+	})
+	scheduler.Register("panicking", FixedInterval(time.Hour), func() (interface{}, error) {
+		panic("boom")
+	})
+
+	if status, err := scheduler.Trigger("failing"); err == nil || status.LastError != "database is locked" {
+		t.Errorf("Expected the job error to be recorded, got %v / %+v", err, status)
+	}
+	if status, err := scheduler.Trigger("panicking"); err == nil || status.LastError != "job panicked: boom" {
+		t.Errorf("Expected the panic to be recorded, got %v / %+v", err, status)
+	}
+}
+
+func TestScheduler_TriggerWhileRunning(t *testing.T) {
+	scheduler := NewScheduler()
+	release := make(chan struct{})
+	started := make(chan struct{})
+	scheduler.Register("slow", FixedInterval(time.Hour), func() (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+
+	go scheduler.Trigger("slow")
+	<-started
+
+	if _, err := scheduler.Trigger("slow"); err != ErrJobRunning {
+		t.Errorf("Expected ErrJobRunning, got %v", err)
+	}
+	if !scheduler.Statuses()[0].Running {
+		t.Error("Expected job to be reported as running")
+	}
+	close(release)
+}
+
+func TestScheduler_StartRunsJobsAtInterval(t *testing.T) {
+	scheduler := NewScheduler()
+	var runs int32
+	scheduler.Register("tick", FixedInterval(10*time.Millisecond), func() (interface{}, error) {
+		atomic.AddInt32(&runs, 1)
+		return nil, nil
+	})
+
+	scheduler.Start()
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&runs) < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	scheduler.Stop()
+
+	if atomic.LoadInt32(&runs) < 2 {
+		t.Fatalf("Expected the job to run periodically, ran %d times", runs)
+	}
+
+	// Wait out a run that may have been in progress when stopping
+	time.Sleep(20 * time.Millisecond)
+	stopped := atomic.LoadInt32(&runs)
+	time.Sleep(50 * time.Millisecond)
+	if atomic.LoadInt32(&runs) != stopped {
+		t.Error("Expected no runs after Stop")
+	}
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	// SVGModeReject refuses SVG uploads
+	SVGModeReject = "reject"
+	// SVGModeSanitize accepts SVG uploads after stripping everything outside the allowlist
+	SVGModeSanitize = "sanitize"
+
+	svgNamespace   = "http://www.w3.org/2000/svg"
+	xlinkNamespace = "http://www.w3.org/1999/xlink"
+)
+
+var (
+	ErrSVGRejected    = errors.New("SVG uploads are not allowed")
+	ErrInvalidSVG     = errors.New("file is not a valid SVG document")
+	ErrInvalidSVGMode = errors.New("svg mode must be reject or sanitize")
+)
+
+// SVGPolicy represents how SVG uploads are handled
+type SVGPolicy struct {
+	Mode string `json:"mode"` // reject or sanitize
+}
+
+// DefaultSVGPolicy returns default SVG policy
+func DefaultSVGPolicy() *SVGPolicy {
+	return &SVGPolicy{
+		Mode: SVGModeReject,
+	}
+}
+
+// Validate checks the policy for invalid values
+func (sp *SVGPolicy) Validate() error {
+	if sp.Mode != SVGModeReject && sp.Mode != SVGModeSanitize {
+		return ErrInvalidSVGMode
+	}
+	return nil
+}
+
+// SVGPolicyManager manages the SVG policy
+type SVGPolicyManager struct {
+	policy *SVGPolicy
+	mutex  sync.RWMutex
+}
+
+// NewSVGPolicyManager creates a new SVG policy manager
+func NewSVGPolicyManager() *SVGPolicyManager {
+	return &SVGPolicyManager{
+		policy: DefaultSVGPolicy(),
+	}
+}
+
+// GetPolicy returns the current SVG policy
+func (sm *SVGPolicyManager) GetPolicy() SVGPolicy {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return *sm.policy
+}
+
+// UpdatePolicy replaces the SVG policy
+func (sm *SVGPolicyManager) UpdatePolicy(policy *SVGPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.policy = policy
+	return nil
+}
+
+// GlobalSVGPolicy is the SVG policy applied to image uploads
+var GlobalSVGPolicy = NewSVGPolicyManager()
+
+// svgAllowedElements are the SVG elements kept by SanitizeSVG. Anything else,
+// notably script, style, foreignObject, image and animation elements, is dropped
+// together with its content.
+var svgAllowedElements = map[string]bool{
+	"svg": true, "g": true, "defs": true, "symbol": true, "use": true,
+	"title": true, "desc": true,
+	"path": true, "rect": true, "circle": true, "ellipse": true,
+	"line": true, "polyline": true, "polygon": true,
+	"text": true, "tspan": true,
+	"linearGradient": true, "radialGradient": true, "stop": true,
+	"clipPath": true, "mask": true, "pattern": true, "marker": true,
+}
+
+// svgAllowedAttributes are the presentation and geometry attributes kept by
+// SanitizeSVG. Event handlers (on*) and style are never on the list.
+var svgAllowedAttributes = map[string]bool{
+	"id": true, "class": true, "version": true, "viewBox": true, "preserveAspectRatio": true,
+	"x": true, "y": true, "x1": true, "y1": true, "x2": true, "y2": true,
+	"width": true, "height": true, "cx": true, "cy": true, "r": true, "rx": true, "ry": true,
+	"d": true, "points": true, "transform": true, "offset": true,
+	"fill": true, "fill-opacity": true, "fill-rule": true,
+	"stroke": true, "stroke-width": true, "stroke-opacity": true, "stroke-linecap": true,
+	"stroke-linejoin": true, "stroke-dasharray": true, "stroke-dashoffset": true, "stroke-miterlimit": true,
+	"opacity": true, "stop-color": true, "stop-opacity": true, "clip-path": true, "clip-rule": true, "mask": true,
+	"gradientUnits": true, "gradientTransform": true, "patternUnits": true, "patternTransform": true,
+	"markerWidth": true, "markerHeight": true, "refX": true, "refY": true, "orient": true, "markerUnits": true,
+	"font-family": true, "font-size": true, "font-weight": true, "font-style": true,
+	"text-anchor": true, "dominant-baseline": true, "dx": true, "dy": true,
+	"href": true, // local references only, see isLocalReference
+}
+
+// SanitizeSVG rewrites an SVG document keeping only allowlisted elements and
+// attributes. Scripts, event handlers, styles, DTDs and processing instructions
+// are removed, and references (href, url()) may only point inside the document.
+func SanitizeSVG(r io.Reader) ([]byte, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = true
+
+	var out bytes.Buffer
+	skipDepth := 0 // > 0 while inside a dropped element
+	sawRoot := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidSVG
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if skipDepth > 0 {
+				skipDepth++
+				continue
+			}
+			// Elements without a namespace are treated as SVG, the output declares it
+			inSVG := t.Name.Space == svgNamespace || t.Name.Space == ""
+			if !sawRoot && (t.Name.Local != "svg" || !inSVG) {
+				return nil, ErrInvalidSVG
+			}
+			if !inSVG || !svgAllowedElements[t.Name.Local] {
+				skipDepth = 1
+				continue
+			}
+
+			out.WriteString("<" + t.Name.Local)
+			if !sawRoot {
+				out.WriteString(` xmlns="` + svgNamespace + `" xmlns:xlink="` + xlinkNamespace + `"`)
+				sawRoot = true
+			}
+			for _, attr := range t.Attr {
+				name, ok := sanitizedSVGAttributeName(attr.Name)
+				if !ok || !isSafeSVGAttributeValue(attr.Name.Local, attr.Value) {
+					continue
+				}
+				out.WriteString(" " + name + `="`)
+				xml.EscapeText(&out, []byte(attr.Value))
+				out.WriteString(`"`)
+			}
+			out.WriteString(">")
+
+		case xml.EndElement:
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			out.WriteString("</" + t.Name.Local + ">")
+
+		case xml.CharData:
+			if skipDepth == 0 && sawRoot {
+				xml.EscapeText(&out, t)
+			}
+		}
+		// Comments, processing instructions and directives (DOCTYPE, entities) are dropped
+	}
+
+	if !sawRoot {
+		return nil, ErrInvalidSVG
+	}
+	return out.Bytes(), nil
+}
+
+// sanitizedSVGAttributeName returns the output name of an allowlisted attribute.
+// Namespace declarations are dropped; the root element declares its own.
+func sanitizedSVGAttributeName(name xml.Name) (string, bool) {
+	switch name.Space {
+	case "":
+		return name.Local, svgAllowedAttributes[name.Local]
+	case xlinkNamespace:
+		return "xlink:" + name.Local, name.Local == "href"
+	default:
+		return "", false
+	}
+}
+
+// isSafeSVGAttributeValue rejects values that reference content outside the document
+func isSafeSVGAttributeValue(name, value string) bool {
+	if name == "href" {
+		return isLocalReference(value)
+	}
+
+	lower := strings.ToLower(value)
+	if strings.Contains(lower, "javascript:") || strings.Contains(lower, "data:") {
+		return false
+	}
+	// url() is only allowed for fragment references such as fill="url(#gradient)"
+	for rest := lower; ; {
+		i := strings.Index(rest, "url(")
+		if i < 0 {
+			return true
+		}
+		rest = strings.TrimLeft(rest[i+len("url("):], " '\"")
+		if !strings.HasPrefix(rest, "#") {
+			return false
+		}
+	}
+}
+
+// isLocalReference checks if a reference points to an element in the same document
+func isLocalReference(value string) bool {
+	value = strings.TrimSpace(value)
+	return strings.HasPrefix(value, "#") && !strings.ContainsAny(value, ":/")
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+const maliciousSVG = `<?xml version="1.0"?>
+<!DOCTYPE svg [<!ENTITY x "y">]>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="10" height="10" onload="alert(1)">
+  <script>alert(document.cookie)</script>
+  <style>circle { background: url(https://evil.example.com/track) }</style>
+  <defs><linearGradient id="g"><stop offset="0" stop-color="red"/></linearGradient></defs>
+  <circle cx="5" cy="5" r="4" fill="url(#g)" onclick="steal()" style="fill:red"/>
+  <rect width="1" height="1" fill="url(https://evil.example.com/x.svg#p)"/>
+  <a xlink:href="javascript:alert(1)"><text>click</text></a>
+  <use xlink:href="https://evil.example.com/sprite.svg#icon"/>
+  <use href="#g"/>
+  <image href="data:image/png;base64,AAAA"/>
+  <foreignObject><body xmlns="http://www.w3.org/1999/xhtml"><iframe src="https://evil.example.com"/></body></foreignObject>
+  <!-- <script>comment</script> -->
+</svg>`
+
+func TestSanitizeSVG_StripsActiveContent(t *testing.T) {
+	out, err := SanitizeSVG(strings.NewReader(maliciousSVG))
+	if err != nil {
+		t.Fatalf("Failed to sanitize SVG: %v", err)
+	}
+	sanitized := strings.ToLower(string(out))
+
+	for _, forbidden := range []string{"<script", "alert", "onload", "onclick", "<style", "style=", "evil.example.com", "javascript:", "data:", "foreignobject", "iframe", "<image", "<!doctype", "<a"} {
+		if strings.Contains(sanitized, forbidden) {
+			t.Errorf("Expected %q to be removed, got %s", forbidden, out)
+		}
+	}
+
+	for _, kept := range []string{`<circle cx="5" cy="5" r="4" fill="url(#g)">`, `<stop offset="0" stop-color="red">`, `<use href="#g">`, `width="10"`} {
+		if !strings.Contains(string(out), kept) {
+			t.Errorf("Expected %q to be kept, got %s", kept, out)
+		}
+	}
+}
+
+func TestSanitizeSVG_RejectsNonSVG(t *testing.T) {
+	inputs := []string{
+		"",
+		"not xml at all",
+		`<html><script>alert(1)</script></html>`,
+		`<svg xmlns="http://www.w3.org/2000/svg"><text>&xxe;</text></svg>`,
+	}
+	for _, input := range inputs {
+		if _, err := SanitizeSVG(strings.NewReader(input)); err != ErrInvalidSVG {
+			t.Errorf("%q: expected ErrInvalidSVG, got %v", input, err)
+		}
+	}
+}
+
+func TestSVGPolicyManager_DefaultsToReject(t *testing.T) {
+	manager := NewSVGPolicyManager()
+	if mode := manager.GetPolicy().Mode; mode != SVGModeReject {
+		t.Errorf("Expected default mode %q, got %q", SVGModeReject, mode)
+	}
+	if err := manager.UpdatePolicy(&SVGPolicy{Mode: "allow"}); err != ErrInvalidSVGMode {
+		t.Errorf("Expected ErrInvalidSVGMode, got %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// multipartTempPrefix is the prefix upload temp files are named with, as
+	// mime/multipart names its own
+	multipartTempPrefix = "multipart-"
+	// maxUploadFormParts is the most parts ReadForm accepts, as mime/multipart does
+	maxUploadFormParts = 1000
+)
+
+var (
+	ErrInvalidUploadTempPolicy = errors.New("upload temp max age and sweep interval must be at least 1 minute")
+	ErrUploadTempDirRequired   = errors.New("sweeping upload temp files requires a dedicated upload temp directory")
+)
+
+// UploadTempPolicy represents where multipart uploads spill to disk and how
+// long their temp files may be left behind, e.g. by a crash, before they are swept.
+// Only a dedicated directory is swept; the OS temp directory is shared with other
+// processes.
+type UploadTempPolicy struct {
+	Dir           string        // empty = the OS temp directory, not swept
+	MaxAge        time.Duration // temp files last modified longer ago are removed
+	SweepInterval time.Duration
+}
+
+// DefaultUploadTempPolicy returns default upload temp policy
+func DefaultUploadTempPolicy() *UploadTempPolicy {
+	return &UploadTempPolicy{
+		MaxAge:        time.Hour,
+		SweepInterval: 15 * time.Minute,
+	}
+}
+
+// Validate checks the policy for invalid values
+func (up *UploadTempPolicy) Validate() error {
+	if up.MaxAge < time.Minute || up.SweepInterval < time.Minute {
+		return ErrInvalidUploadTempPolicy
+	}
+	return nil
+}
+
+// UploadTempSweepSummary represents the result of a temp file sweep
+type UploadTempSweepSummary struct {
+	Dir     string   `json:"dir"`
+	Removed int      `json:"removed"`
+	Failed  []string `json:"failed,omitempty"` // retried on the next sweep
+}
+
+// UploadTempManager manages the directory multipart uploads spill to and
+// sweeps the stale temp files left in it
+type UploadTempManager struct {
+	policy *UploadTempPolicy
+	mutex  sync.RWMutex
+}
+
+// NewUploadTempManager creates a new upload temp manager
+func NewUploadTempManager() *UploadTempManager {
+	return &UploadTempManager{
+		policy: DefaultUploadTempPolicy(),
+	}
+}
+
+// GetPolicy returns the current upload temp policy
+func (um *UploadTempManager) GetPolicy() UploadTempPolicy {
+	um.mutex.RLock()
+	defer um.mutex.RUnlock()
+	return *um.policy
+}
+
+// UpdatePolicy validates and replaces the upload temp policy, creating a
+// configured directory
+func (um *UploadTempManager) UpdatePolicy(policy *UploadTempPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if policy.Dir != "" {
+		if err := os.MkdirAll(policy.Dir, 0700); err != nil {
+			return fmt.Errorf("failed to create upload temp directory: %w", err)
+		}
+	}
+
+	um.mutex.Lock()
+	defer um.mutex.Unlock()
+	um.policy = policy
+	return nil
+}
+
+// Dir returns the directory multipart uploads spill to
+func (um *UploadTempManager) Dir() string {
+	if dir := um.GetPolicy().Dir; dir != "" {
+		return dir
+	}
+	return os.TempDir()
+}
+
+// Sweep removes multipart temp files older than the policy's max age. Requests
+// clean up their own temp files, so only those of interrupted requests remain.
+// Without a dedicated directory nothing is swept, as the multipart temp files of
+// other processes may share the OS temp directory.
+func (um *UploadTempManager) Sweep(now time.Time) (*UploadTempSweepSummary, error) {
+	policy := um.GetPolicy()
+	if policy.Dir == "" {
+		return nil, ErrUploadTempDirRequired
+	}
+	summary := &UploadTempSweepSummary{Dir: policy.Dir}
+
+	entries, err := os.ReadDir(summary.Dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), multipartTempPrefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < policy.MaxAge {
+			continue
+		}
+		if err := os.Remove(filepath.Join(summary.Dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			summary.Failed = append(summary.Failed, entry.Name())
+			continue
+		}
+		summary.Removed++
+	}
+	return summary, nil
+}
+
+// UploadForm represents a multipart form read by ReadForm
+type UploadForm struct {
+	Value map[string][]string
+	File  map[string][]*UploadFile
+}
+
+// UploadFile represents a file part of an UploadForm, held in memory or spilled
+// to a temp file. Open it through UploadFile rather than its FileHeader.
+type UploadFile struct {
+	*multipart.FileHeader
+	content []byte
+	tmpfile string
+}
+
+// uploadFileReader reads an UploadFile held in memory
+type uploadFileReader struct {
+	*io.SectionReader
+}
+
+func (uploadFileReader) Close() error {
+	return nil
+}
+
+// Open opens the file part for reading
+func (uf *UploadFile) Open() (multipart.File, error) {
+	if uf.tmpfile != "" {
+		return os.Open(uf.tmpfile)
+	}
+	return uploadFileReader{io.NewSectionReader(bytes.NewReader(uf.content), 0, int64(len(uf.content)))}, nil
+}
+
+// FormFile returns the first file for the form key, or http.ErrMissingFile
+func (uf *UploadForm) FormFile(key string) (multipart.File, *multipart.FileHeader, error) {
+	files := uf.File[key]
+	if len(files) == 0 {
+		return nil, nil, http.ErrMissingFile
+	}
+	file, err := files[0].Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, files[0].FileHeader, nil
+}
+
+// RemoveAll removes the temp files of the form
+func (uf *UploadForm) RemoveAll() error {
+	var firstErr error
+	for _, files := range uf.File {
+		for _, file := range files {
+			if file.tmpfile == "" {
+				continue
+			}
+			if err := os.Remove(file.tmpfile); err != nil && !os.IsNotExist(err) && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// ReadForm reads a multipart form like mime/multipart, holding at most maxMemory
+// bytes of file parts in memory and spilling the rest to the policy's directory.
+// Non-file parts may take 10MB beyond maxMemory.
+func (um *UploadTempManager) ReadForm(reader *multipart.Reader, maxMemory int64) (*UploadForm, error) {
+	dir := um.GetPolicy().Dir
+	form := &UploadForm{Value: make(map[string][]string), File: make(map[string][]*UploadFile)}
+	fail := func(err error) (*UploadForm, error) {
+		form.RemoveAll()
+		return nil, err
+	}
+
+	maxValueBytes := maxMemory + 10<<20
+	for parts := 0; ; parts++ {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+		if parts >= maxUploadFormParts {
+			return fail(multipart.ErrMessageTooLarge)
+		}
+
+		name := part.FormName()
+		if name == "" {
+			continue
+		}
+
+		var buffer bytes.Buffer
+		if part.FileName() == "" {
+			n, err := io.CopyN(&buffer, part, maxValueBytes+1)
+			if err != nil && err != io.EOF {
+				return fail(err)
+			}
+			if maxValueBytes -= n; maxValueBytes < 0 {
+				return fail(multipart.ErrMessageTooLarge)
+			}
+			form.Value[name] = append(form.Value[name], buffer.String())
+			continue
+		}
+
+		file := &UploadFile{FileHeader: &multipart.FileHeader{Filename: part.FileName(), Header: part.Header}}
+		form.File[name] = append(form.File[name], file)
+		n, err := io.CopyN(&buffer, part, maxMemory+1)
+		if err != nil && err != io.EOF {
+			return fail(err)
+		}
+		if n <= maxMemory {
+			file.content, file.Size = buffer.Bytes(), n
+			maxMemory -= n
+			maxValueBytes -= n
+			continue
+		}
+
+		tmp, err := os.CreateTemp(dir, multipartTempPrefix)
+		if err != nil {
+			return fail(err)
+		}
+		file.tmpfile = tmp.Name()
+		file.Size, err = io.Copy(tmp, io.MultiReader(&buffer, part))
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return fail(err)
+		}
+	}
+	return form, nil
+}
+
+// Register schedules the temp file sweep at the interval the current policy
+// sets. Without a dedicated directory there is nothing to sweep and no job is
+// scheduled.
+func (um *UploadTempManager) Register(scheduler *Scheduler) error {
+	if um.GetPolicy().Dir == "" {
+		return nil
+	}
+	interval := func() time.Duration {
+		return um.GetPolicy().SweepInterval
+	}
+	return scheduler.Register("upload_temp_sweep", interval, func() (interface{}, error) {
+		return um.Sweep(time.Now())
+	})
+}
+
+// GlobalUploadTempManager manages the temp files of uploads to the application
+var GlobalUploadTempManager = NewUploadTempManager()
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUploadTempManager_ReadFormSpillsToDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "uploads-tmp")
+	um := NewUploadTempManager()
+	if err := um.UpdatePolicy(&UploadTempPolicy{Dir: dir, MaxAge: time.Hour, SweepInterval: time.Minute}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Fatalf("Expected the temp directory to be created: %v", err)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	writer.WriteField("description", "quarterly report")
+	small, _ := writer.CreateFormFile("small", "small.txt")
+	small.Write([]byte("tiny"))
+	large, _ := writer.CreateFormFile("large", "large.txt")
+	large.Write(bytes.Repeat([]byte("x"), 4096))
+	writer.Close()
+
+	form, err := um.ReadForm(multipart.NewReader(&body, writer.Boundary()), 1024)
+	if err != nil {
+		t.Fatalf("Failed to read form: %v", err)
+	}
+	if got := form.Value["description"]; len(got) != 1 || got[0] != "quarterly report" {
+		t.Errorf("Unexpected form values: %v", form.Value)
+	}
+	for name, want := range map[string]int{"small": 4, "large": 4096} {
+		file, header, err := form.FormFile(name)
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", name, err)
+		}
+		content, _ := io.ReadAll(file)
+		file.Close()
+		if len(content) != want || header.Size != int64(want) {
+			t.Errorf("Expected %s to hold %d bytes, got %d (size %d)", name, want, len(content), header.Size)
+		}
+	}
+
+	// Only the part over the memory limit spills, to the configured directory
+	spilled, _ := os.ReadDir(dir)
+	if len(spilled) != 1 {
+		t.Fatalf("Expected one temp file in %s, got %d", dir, len(spilled))
+	}
+	if err := form.RemoveAll(); err != nil {
+		t.Errorf("Failed to remove temp files: %v", err)
+	}
+	if spilled, _ = os.ReadDir(dir); len(spilled) != 0 {
+		t.Errorf("Expected the temp files to be removed, found %d", len(spilled))
+	}
+
+	if err := um.UpdatePolicy(&UploadTempPolicy{MaxAge: time.Second, SweepInterval: time.Minute}); err != ErrInvalidUploadTempPolicy {
+		t.Errorf("Expected a max age under a minute to be rejected, got %v", err)
+	}
+}
+
+func TestUploadTempManager_SweepsStaleTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	um := NewUploadTempManager()
+	if err := um.UpdatePolicy(&UploadTempPolicy{Dir: dir, MaxAge: time.Hour, SweepInterval: time.Minute}); err != nil {
+		t.Fatalf("Failed to update policy: %v", err)
+	}
+
+	now := time.Now()
+	files := map[string]time.Time{
+		"multipart-stale":  now.Add(-2 * time.Hour),
+		"multipart-active": now.Add(-time.Minute),
+		"other-stale":      now.Add(-2 * time.Hour), // not ours
+	}
+	for name, modified := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("data"), 0600); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		os.Chtimes(path, modified, modified)
+	}
+
+	summary, err := um.Sweep(now)
+	if err != nil {
+		t.Fatalf("Failed to sweep: %v", err)
+	}
+	if summary.Removed != 1 || len(summary.Failed) != 0 {
+		t.Errorf("Expected one temp file removed, got %+v", summary)
+	}
+	for name, wantKept := range map[string]bool{"multipart-stale": false, "multipart-active": true, "other-stale": true} {
+		_, err := os.Stat(filepath.Join(dir, name))
+		if kept := err == nil; kept != wantKept {
+			t.Errorf("Expected %s kept=%v, got kept=%v", name, wantKept, kept)
+		}
+	}
+}
+
+func TestUploadTempManager_SweepRequiresDedicatedDir(t *testing.T) {
+	um := NewUploadTempManager()
+	if _, err := um.Sweep(time.Now()); err != ErrUploadTempDirRequired {
+		t.Errorf("Expected the OS temp directory not to be swept, got %v", err)
+	}
+	scheduler := NewScheduler()
+	if err := um.Register(scheduler); err != nil {
+		t.Errorf("Expected registration to be skipped quietly, got %v", err)
+	}
+	if jobs := scheduler.Statuses(); len(jobs) != 0 {
+		t.Errorf("Expected no sweep to be scheduled, got %+v", jobs)
+	}
+}
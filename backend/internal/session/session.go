@@ -1,7 +1,12 @@
 package session
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"net"
+	"sort"
 	"sync"
 	"time"
 
@@ -24,10 +29,56 @@ type Session struct {
 	IsActive  bool      `json:"is_active"`
 }
 
+// Session limit policies applied when a user reaches MaxSessionsPerUser
+const (
+	LimitPolicyEvictOldest = "evict_oldest"
+	LimitPolicyReject      = "reject"
+)
+
+// SessionConfig represents session management configuration
+type SessionConfig struct {
+	MaxSessionsPerUser int    `json:"max_sessions_per_user"` // 0 = unlimited
+	LimitPolicy        string `json:"limit_policy"`          // evict_oldest, reject
+	LogoutOnRoleChange bool   `json:"logout_on_role_change"` // revoke a user's tokens when their role changes
+	// BindToIP rejects a session's token when used from another address than it
+	// was created from. Mobile clients roam, so the prefixes allow binding to a
+	// subnet instead, e.g. 24 and 64; 0 requires the exact address.
+	BindToIP       bool `json:"bind_to_ip"`
+	BindIPv4Prefix int  `json:"bind_ipv4_prefix"`
+	BindIPv6Prefix int  `json:"bind_ipv6_prefix"`
+	// IDBytes is the number of random bytes in a session ID, 0 = DefaultSessionIDBytes
+	IDBytes int `json:"id_bytes"`
+}
+
+// Bounds of the random bytes in a session ID. 16 bytes (128 bits) keep IDs
+// unguessable and collisions negligible.
+const (
+	DefaultSessionIDBytes = 32
+	MinSessionIDBytes     = 16
+	MaxSessionIDBytes     = 64
+)
+
+// sessionIDPrefix marks session IDs apart from other identifiers
+const sessionIDPrefix = "sess_"
+
+// DefaultSessionConfig returns default session configuration
+func DefaultSessionConfig() *SessionConfig {
+	return &SessionConfig{
+		MaxSessionsPerUser: 5,
+		LimitPolicy:        LimitPolicyEvictOldest,
+		LogoutOnRoleChange: true,
+		IDBytes:            DefaultSessionIDBytes,
+	}
+}
+
 // SessionManager manages user sessions
 type SessionManager struct {
 	sessions map[string]*Session
 	blacklist map[string]bool
+	revokedBefore map[uint]time.Time // tokens issued before this time are rejected
+	config   *SessionConfig
+	onEvict  func(*Session)
+	onIPMismatch func(*Session, string, string)
 	mutex    sync.RWMutex
 }
 
@@ -36,6 +87,8 @@ func NewSessionManager() *SessionManager {
 	return &SessionManager{
 		sessions:  make(map[string]*Session),
 		blacklist: make(map[string]bool),
+		revokedBefore: make(map[uint]time.Time),
+		config:    DefaultSessionConfig(),
 	}
 }
 
@@ -44,20 +97,84 @@ var (
 	ErrSessionExpired  = errors.New("session expired")
 	ErrTokenBlacklisted = errors.New("token is blacklisted")
 	ErrInvalidToken    = errors.New("invalid token")
+	ErrSessionLimitReached = errors.New("maximum number of active sessions reached")
+	ErrSessionIPMismatch = errors.New("session is bound to a different IP address")
+	ErrInvalidIPBinding = errors.New("ip binding prefixes must be between 0 and 32 for IPv4 and 0 and 128 for IPv6")
+	ErrInvalidSessionIDBytes = fmt.Errorf("id_bytes must be between %d and %d", MinSessionIDBytes, MaxSessionIDBytes)
 )
 
+// GetConfig returns the current session configuration
+func (sm *SessionManager) GetConfig() SessionConfig {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+	return *sm.config
+}
+
+// UpdateConfig updates the session configuration
+func (sm *SessionManager) UpdateConfig(config *SessionConfig) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.config = config
+}
+
+// SetEvictionHandler registers a callback invoked for every session evicted
+// because its user exceeded the session limit
+func (sm *SessionManager) SetEvictionHandler(handler func(*Session)) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.onEvict = handler
+}
+
+// SetIPMismatchHandler registers a callback invoked with the session and the
+// request's address and user agent whenever IP binding rejects a session's token
+func (sm *SessionManager) SetIPMismatchHandler(handler func(*Session, string, string)) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	sm.onIPMismatch = handler
+}
+
 // CreateSession creates a new session for a user
 func (sm *SessionManager) CreateSession(user *models.User, token string, ipAddress, userAgent string) (*Session, error) {
+	session, evicted, err := sm.createSession(user, token, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	// Notify outside the lock so the handler may call back into the manager
+	sm.mutex.RLock()
+	onEvict := sm.onEvict
+	sm.mutex.RUnlock()
+
+	if onEvict != nil {
+		for _, evictedSession := range evicted {
+			onEvict(evictedSession)
+		}
+	}
+
+	return session, nil
+}
+
+// createSession registers a new session, returning any sessions evicted to make room
+func (sm *SessionManager) createSession(user *models.User, token string, ipAddress, userAgent string) (*Session, []*Session, error) {
 	sm.mutex.Lock()
 	defer sm.mutex.Unlock()
 
 	// Parse token to get expiration time
-	claims, err := auth.ValidateJWT(token, []byte("my_secret_key"))
+	claims, err := auth.ValidateJWT(token, auth.JWTSecret())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	sessionID, err := sm.newSessionID()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	evicted, err := sm.enforceSessionLimit(user.ID)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	sessionID := generateSessionID()
 	session := &Session{
 		ID:        sessionID,
 		UserID:    user.ID,
@@ -73,7 +190,51 @@ func (sm *SessionManager) CreateSession(user *models.User, token string, ipAddre
 	}
 
 	sm.sessions[sessionID] = session
-	return session, nil
+	return session, evicted, nil
+}
+
+// enforceSessionLimit makes room for a new session according to the limit policy.
+// Must be called with the write lock held.
+func (sm *SessionManager) enforceSessionLimit(userID uint) ([]*Session, error) {
+	if sm.config.MaxSessionsPerUser <= 0 {
+		return nil, nil
+	}
+
+	active := sm.activeUserSessions(userID)
+	if len(active) < sm.config.MaxSessionsPerUser {
+		return nil, nil
+	}
+
+	if sm.config.LimitPolicy == LimitPolicyReject {
+		return nil, ErrSessionLimitReached
+	}
+
+	// Evict the oldest sessions until there is room for one more
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].CreatedAt.Before(active[j].CreatedAt)
+	})
+
+	var evicted []*Session
+	for _, session := range active[:len(active)-sm.config.MaxSessionsPerUser+1] {
+		session.IsActive = false
+		sm.blacklist[session.Token] = true
+		evicted = append(evicted, session)
+	}
+
+	return evicted, nil
+}
+
+// activeUserSessions returns the active, unexpired sessions of a user.
+// Must be called with the lock held.
+func (sm *SessionManager) activeUserSessions(userID uint) []*Session {
+	var userSessions []*Session
+	for _, session := range sm.sessions {
+		if session.UserID == userID && session.IsActive && time.Now().Before(session.ExpiresAt) {
+			userSessions = append(userSessions, session)
+		}
+	}
+
+	return userSessions
 }
 
 // GetSession retrieves a session by ID
@@ -122,6 +283,79 @@ func (sm *SessionManager) GetSessionByToken(token string) (*Session, error) {
 	return nil, ErrSessionNotFound
 }
 
+// CheckSessionIP enforces IP binding for a token used from ipAddress. It returns
+// ErrSessionIPMismatch when binding is enabled and the token's session was
+// created from another address or subnet. Scoped tokens have no session and
+// are not bound; any other token without a session, e.g. one whose session was
+// lost in a restart, is rejected with ErrSessionNotFound.
+func (sm *SessionManager) CheckSessionIP(token string, claims *auth.Claims, ipAddress, userAgent string) error {
+	config := sm.GetConfig()
+	if !config.BindToIP {
+		return nil
+	}
+
+	session, err := sm.GetSessionByToken(token)
+	if err != nil {
+		if len(claims.Scopes) > 0 {
+			return nil
+		}
+		return ErrSessionNotFound
+	}
+	if sameNetwork(session.IPAddress, ipAddress, config.BindIPv4Prefix, config.BindIPv6Prefix) {
+		return nil
+	}
+
+	sm.mutex.RLock()
+	onIPMismatch := sm.onIPMismatch
+	sm.mutex.RUnlock()
+	if onIPMismatch != nil {
+		onIPMismatch(session, ipAddress, userAgent)
+	}
+	return ErrSessionIPMismatch
+}
+
+// ValidateIDBytes checks the session ID length of a session configuration
+func (config *SessionConfig) ValidateIDBytes() error {
+	if config.IDBytes != 0 && (config.IDBytes < MinSessionIDBytes || config.IDBytes > MaxSessionIDBytes) {
+		return ErrInvalidSessionIDBytes
+	}
+	return nil
+}
+
+// ValidateIPBinding checks the IP binding prefixes of a session configuration
+func (config *SessionConfig) ValidateIPBinding() error {
+	if config.BindIPv4Prefix < 0 || config.BindIPv4Prefix > 32 || config.BindIPv6Prefix < 0 || config.BindIPv6Prefix > 128 {
+		return ErrInvalidIPBinding
+	}
+	return nil
+}
+
+// sameNetwork checks if two addresses share a network of the given prefix
+// length, 0 comparing whole addresses. Unparsable addresses must be identical.
+func sameNetwork(bound, current string, ipv4Prefix, ipv6Prefix int) bool {
+	boundIP, currentIP := net.ParseIP(bound), net.ParseIP(current)
+	if boundIP == nil || currentIP == nil {
+		return bound == current
+	}
+
+	if bound4, current4 := boundIP.To4(), currentIP.To4(); bound4 != nil || current4 != nil {
+		if bound4 == nil || current4 == nil {
+			return false
+		}
+		if ipv4Prefix == 0 {
+			ipv4Prefix = 32
+		}
+		mask := net.CIDRMask(ipv4Prefix, 32)
+		return bound4.Mask(mask).Equal(current4.Mask(mask))
+	}
+
+	if ipv6Prefix == 0 {
+		ipv6Prefix = 128
+	}
+	mask := net.CIDRMask(ipv6Prefix, 128)
+	return boundIP.Mask(mask).Equal(currentIP.Mask(mask))
+}
+
 // UpdateSessionLastSeen updates the last seen time for a session
 func (sm *SessionManager) UpdateSessionLastSeen(sessionID string) error {
 	sm.mutex.Lock()
@@ -175,6 +409,39 @@ func (sm *SessionManager) InvalidateUserSessions(userID uint) error {
 	return nil
 }
 
+// RevokeUserTokens invalidates all sessions of a user and rejects every token
+// issued to them so far, including tokens not bound to a session. Returns the
+// number of sessions invalidated.
+func (sm *SessionManager) RevokeUserTokens(userID uint) int {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	count := 0
+	for _, session := range sm.sessions {
+		if session.UserID == userID && session.IsActive {
+			session.IsActive = false
+			sm.blacklist[session.Token] = true
+			count++
+		}
+	}
+
+	sm.revokedBefore[userID] = time.Now()
+	return count
+}
+
+// IsTokenRevoked checks if a token is blacklisted or was issued before its user's tokens were revoked
+func (sm *SessionManager) IsTokenRevoked(token string, claims *auth.Claims) bool {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	if sm.blacklist[token] {
+		return true
+	}
+
+	revokedAt, exists := sm.revokedBefore[claims.UserID]
+	return exists && claims.IssuedAt < revokedAt.Unix()
+}
+
 // BlacklistToken adds a token to the blacklist
 func (sm *SessionManager) BlacklistToken(token string) {
 	sm.mutex.Lock()
@@ -188,14 +455,15 @@ func (sm *SessionManager) GetUserSessions(userID uint) []*Session {
 	sm.mutex.RLock()
 	defer sm.mutex.RUnlock()
 
-	var userSessions []*Session
-	for _, session := range sm.sessions {
-		if session.UserID == userID && session.IsActive && time.Now().Before(session.ExpiresAt) {
-			userSessions = append(userSessions, session)
-		}
-	}
+	return sm.activeUserSessions(userID)
+}
 
-	return userSessions
+// CountUserSessions returns the number of active sessions for a user
+func (sm *SessionManager) CountUserSessions(userID uint) int {
+	sm.mutex.RLock()
+	defer sm.mutex.RUnlock()
+
+	return len(sm.activeUserSessions(userID))
 }
 
 // GetAllSessions returns all active sessions (admin only)
@@ -215,17 +483,25 @@ func (sm *SessionManager) GetAllSessions() []*Session {
 
 // CleanupExpiredSessions removes expired sessions
 func (sm *SessionManager) CleanupExpiredSessions() {
+	sm.CleanupSessionsExpiredBefore(time.Now())
+}
+
+// CleanupSessionsExpiredBefore removes sessions that expired before cutoff and returns the number removed
+func (sm *SessionManager) CleanupSessionsExpiredBefore(cutoff time.Time) int {
 	sm.mutex.Lock()
 	defer sm.mutex.Unlock()
 
-	now := time.Now()
+	removed := 0
 	for sessionID, session := range sm.sessions {
-		if now.After(session.ExpiresAt) {
+		if cutoff.After(session.ExpiresAt) {
 			session.IsActive = false
 			sm.blacklist[session.Token] = true
 			delete(sm.sessions, sessionID)
+			removed++
 		}
 	}
+
+	return removed
 }
 
 // GetSessionStats returns session statistics
@@ -246,39 +522,47 @@ func (sm *SessionManager) GetSessionStats() map[string]interface{} {
 	}
 
 	return map[string]interface{}{
-		"active_sessions":    activeCount,
-		"expired_sessions":   expiredCount,
-		"blacklisted_tokens": blacklistedCount,
-		"total_sessions":     len(sm.sessions),
+		"active_sessions":       activeCount,
+		"expired_sessions":      expiredCount,
+		"blacklisted_tokens":    blacklistedCount,
+		"total_sessions":        len(sm.sessions),
+		"max_sessions_per_user": sm.config.MaxSessionsPerUser,
+		"limit_policy":          sm.config.LimitPolicy,
 	}
 }
 
-// generateSessionID generates a unique session ID
-func generateSessionID() string {
-	return "sess_" + time.Now().Format("20060102150405") + "_" + randomString(8)
+// newSessionID generates a session ID no current session has, with the
+// configured number of random bytes. Must be called with the lock held.
+func (sm *SessionManager) newSessionID() (string, error) {
+	length := sm.config.IDBytes
+	if length == 0 {
+		length = DefaultSessionIDBytes
+	}
+
+	// A collision is practically impossible, but a duplicate would hand over
+	// another user's session, so it is checked anyway
+	for attempt := 0; attempt < 3; attempt++ {
+		sessionID, err := generateSessionID(length)
+		if err != nil {
+			return "", err
+		}
+		if _, exists := sm.sessions[sessionID]; !exists {
+			return sessionID, nil
+		}
+	}
+	return "", errors.New("failed to generate a unique session ID")
 }
 
-// randomString generates a random string of specified length
-func randomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+// generateSessionID generates a session ID of length cryptographically random
+// bytes, URL-safe base64 encoded. It carries nothing derived from the time or
+// the JWT, so it can't be guessed from either.
+func generateSessionID(length int) (string, error) {
 	b := make([]byte, length)
-	for i := range b {
-		b[i] = charset[time.Now().UnixNano()%int64(len(charset))]
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
 	}
-	return string(b)
+	return sessionIDPrefix + base64.RawURLEncoding.EncodeToString(b), nil
 }
 
 // Global session manager instance
 var GlobalSessionManager = NewSessionManager()
-
-// StartSessionCleanup starts a goroutine to clean up expired sessions
-func StartSessionCleanup() {
-	go func() {
-		ticker := time.NewTicker(5 * time.Minute) // Clean up every 5 minutes
-		defer ticker.Stop()
-
-		for range ticker.C {
-			GlobalSessionManager.CleanupExpiredSessions()
-		}
-	}()
-}
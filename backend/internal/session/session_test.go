@@ -0,0 +1,243 @@
+package session
+
+import (
+	"encoding/base64"
+	"math"
+	"strings"
+	"testing"
+
+	"golangmcp/internal/auth"
+	"golangmcp/internal/models"
+)
+
+// newTestToken issues a token signed with the key the session manager validates against
+func newTestToken(t *testing.T, user *models.User) string {
+	token, _, err := auth.GenerateJWT(user, []byte("my_secret_key"))
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	return token
+}
+
+func TestCreateSession_EvictsOldestWhenLimitExceeded(t *testing.T) {
+	sm := NewSessionManager()
+	sm.UpdateConfig(&SessionConfig{MaxSessionsPerUser: 2, LimitPolicy: LimitPolicyEvictOldest})
+
+	var evicted []*Session
+	sm.SetEvictionHandler(func(s *Session) {
+		evicted = append(evicted, s)
+	})
+
+	user := &models.User{ID: 1, Username: "testuser", Role: "user"}
+
+	var created []*Session
+	for i := 0; i < 3; i++ {
+		sess, err := sm.CreateSession(user, newTestToken(t, user), "127.0.0.1", "test-agent")
+		if err != nil {
+			t.Fatalf("Failed to create session %d: %v", i, err)
+		}
+		created = append(created, sess)
+	}
+
+	if count := sm.CountUserSessions(user.ID); count != 2 {
+		t.Errorf("Expected 2 active sessions, got %d", count)
+	}
+
+	if len(evicted) != 1 {
+		t.Fatalf("Expected 1 evicted session, got %d", len(evicted))
+	}
+
+	if evicted[0].ID != created[0].ID {
+		t.Errorf("Expected oldest session %s to be evicted, got %s", created[0].ID, evicted[0].ID)
+	}
+
+	if _, err := sm.GetSessionByToken(created[0].Token); err != ErrTokenBlacklisted {
+		t.Errorf("Expected evicted session token to be blacklisted, got %v", err)
+	}
+
+	if _, err := sm.GetSessionByToken(created[2].Token); err != nil {
+		t.Errorf("Expected newest session to remain valid, got %v", err)
+	}
+}
+
+func TestCreateSession_RejectsWhenLimitExceeded(t *testing.T) {
+	sm := NewSessionManager()
+	sm.UpdateConfig(&SessionConfig{MaxSessionsPerUser: 1, LimitPolicy: LimitPolicyReject})
+
+	user := &models.User{ID: 1, Username: "testuser", Role: "user"}
+
+	if _, err := sm.CreateSession(user, newTestToken(t, user), "127.0.0.1", "test-agent"); err != nil {
+		t.Fatalf("Failed to create first session: %v", err)
+	}
+
+	if _, err := sm.CreateSession(user, newTestToken(t, user), "127.0.0.1", "test-agent"); err != ErrSessionLimitReached {
+		t.Errorf("Expected ErrSessionLimitReached, got %v", err)
+	}
+
+	if count := sm.CountUserSessions(user.ID); count != 1 {
+		t.Errorf("Expected 1 active session, got %d", count)
+	}
+}
+
+func TestCreateSession_UnlimitedWhenZero(t *testing.T) {
+	sm := NewSessionManager()
+	sm.UpdateConfig(&SessionConfig{MaxSessionsPerUser: 0})
+
+	user := &models.User{ID: 1, Username: "testuser", Role: "user"}
+
+	for i := 0; i < 10; i++ {
+		if _, err := sm.CreateSession(user, newTestToken(t, user), "127.0.0.1", "test-agent"); err != nil {
+			t.Fatalf("Failed to create session %d: %v", i, err)
+		}
+	}
+
+	if count := sm.CountUserSessions(user.ID); count != 10 {
+		t.Errorf("Expected 10 active sessions, got %d", count)
+	}
+}
+
+func TestCheckSessionIP_RejectsOtherAddresses(t *testing.T) {
+	sm := NewSessionManager()
+	user := &models.User{ID: 1, Username: "testuser", Role: "user"}
+
+	var mismatches []string
+	sm.SetIPMismatchHandler(func(s *Session, ipAddress, userAgent string) {
+		mismatches = append(mismatches, ipAddress)
+	})
+
+	v4, err := sm.CreateSession(user, newTestToken(t, user), "192.168.1.10", "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	v6, err := sm.CreateSession(user, newTestToken(t, user), "2001:db8::1", "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+
+	// Unbound sessions may roam
+	if err := sm.CheckSessionIP(v4.Token, &auth.Claims{UserID: user.ID}, "10.0.0.1", "test-agent"); err != nil {
+		t.Errorf("Expected no binding by default, got %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		config     SessionConfig
+		token      string
+		scopes     []string
+		ipAddress  string
+		wantReject bool
+	}{
+		{"same address", SessionConfig{BindToIP: true}, v4.Token, nil, "192.168.1.10", false},
+		{"other address", SessionConfig{BindToIP: true}, v4.Token, nil, "192.168.1.11", true},
+		{"same subnet", SessionConfig{BindToIP: true, BindIPv4Prefix: 24}, v4.Token, nil, "192.168.1.99", false},
+		{"other subnet", SessionConfig{BindToIP: true, BindIPv4Prefix: 24}, v4.Token, nil, "192.168.2.10", true},
+		{"other family", SessionConfig{BindToIP: true, BindIPv4Prefix: 24}, v4.Token, nil, "2001:db8::1", true},
+		{"same IPv6 address", SessionConfig{BindToIP: true}, v6.Token, nil, "2001:db8::1", false},
+		{"same IPv6 subnet", SessionConfig{BindToIP: true, BindIPv6Prefix: 64}, v6.Token, nil, "2001:db8::abcd", false},
+		{"other IPv6 subnet", SessionConfig{BindToIP: true, BindIPv6Prefix: 64}, v6.Token, nil, "2001:db8:1::1", true},
+		{"scoped token without session", SessionConfig{BindToIP: true}, "scoped-token", []string{"profile.read"}, "10.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			sm.UpdateConfig(&config)
+			mismatches = nil
+
+			err := sm.CheckSessionIP(tt.token, &auth.Claims{UserID: user.ID, Scopes: tt.scopes}, tt.ipAddress, "test-agent")
+			if tt.wantReject {
+				if err != ErrSessionIPMismatch {
+					t.Errorf("Expected ErrSessionIPMismatch, got %v", err)
+				}
+				if len(mismatches) != 1 || mismatches[0] != tt.ipAddress {
+					t.Errorf("Expected the mismatch to be reported, got %v", mismatches)
+				}
+			} else if err != nil || len(mismatches) != 0 {
+				t.Errorf("Expected the token to be accepted, got %v with mismatches %v", err, mismatches)
+			}
+		})
+	}
+
+	// Bound tokens whose session is gone, e.g. after a restart, are rejected
+	sm.UpdateConfig(&SessionConfig{BindToIP: true})
+	if err := sm.CheckSessionIP("lost-session-token", &auth.Claims{UserID: user.ID}, "192.168.1.10", "test-agent"); err != ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound for a token without session, got %v", err)
+	}
+}
+
+func TestSessionConfig_ValidateIPBinding(t *testing.T) {
+	for _, config := range []SessionConfig{{BindIPv4Prefix: 33}, {BindIPv4Prefix: -1}, {BindIPv6Prefix: 129}} {
+		if err := config.ValidateIPBinding(); err != ErrInvalidIPBinding {
+			t.Errorf("Expected %+v to be rejected, got %v", config, err)
+		}
+	}
+	if err := (&SessionConfig{BindToIP: true, BindIPv4Prefix: 24, BindIPv6Prefix: 64}).ValidateIPBinding(); err != nil {
+		t.Errorf("Expected valid prefixes to be accepted, got %v", err)
+	}
+}
+
+func TestGenerateSessionID_UniqueAndRandom(t *testing.T) {
+	const count = 2000
+	seen := make(map[string]bool, count)
+	var frequencies [256]int
+	total := 0
+	for i := 0; i < count; i++ {
+		id, err := generateSessionID(MinSessionIDBytes)
+		if err != nil {
+			t.Fatalf("Failed to generate session ID: %v", err)
+		}
+		if seen[id] {
+			t.Fatalf("Duplicate session ID %s", id)
+		}
+		seen[id] = true
+
+		encoded := strings.TrimPrefix(id, sessionIDPrefix)
+		if encoded == id {
+			t.Fatalf("Expected the %q prefix, got %s", sessionIDPrefix, id)
+		}
+		raw, err := base64.RawURLEncoding.DecodeString(encoded)
+		if err != nil || len(raw) != MinSessionIDBytes {
+			t.Fatalf("Expected %d URL-safe base64 bytes, got %s: %v", MinSessionIDBytes, id, err)
+		}
+		for _, b := range raw {
+			frequencies[b]++
+			total++
+		}
+	}
+
+	// Random bytes come close to 8 bits of entropy each
+	entropy := 0.0
+	for _, n := range frequencies {
+		if n > 0 {
+			p := float64(n) / float64(total)
+			entropy -= p * math.Log2(p)
+		}
+	}
+	if entropy < 7.9 {
+		t.Errorf("Expected close to 8 bits of entropy per byte, got %.3f", entropy)
+	}
+}
+
+func TestCreateSession_UsesConfiguredIDLength(t *testing.T) {
+	sm := NewSessionManager()
+	sm.UpdateConfig(&SessionConfig{MaxSessionsPerUser: 0, IDBytes: 48})
+
+	user := &models.User{ID: 1, Username: "testuser", Role: "user"}
+	token := newTestToken(t, user)
+	session, err := sm.CreateSession(user, token, "127.0.0.1", "test-agent")
+	if err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	if want := len(sessionIDPrefix) + base64.RawURLEncoding.EncodedLen(48); len(session.ID) != want {
+		t.Errorf("Expected a %d character ID, got %s", want, session.ID)
+	}
+	if strings.Contains(session.ID, token) {
+		t.Error("Expected the session ID to be independent of the JWT")
+	}
+
+	for _, config := range []SessionConfig{{IDBytes: MinSessionIDBytes - 1}, {IDBytes: MaxSessionIDBytes + 1}} {
+		if err := config.ValidateIDBytes(); err != ErrInvalidSessionIDBytes {
+			t.Errorf("Expected %+v to be rejected, got %v", config, err)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+package websocket
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/security"
+	"golangmcp/internal/session"
+)
+
+// BearerSubprotocol is the Sec-WebSocket-Protocol value that precedes a token
+// when browsers pass credentials as subprotocols: ["bearer", "<token>"]
+const BearerSubprotocol = "bearer"
+
+// DefaultMaxMessageSize is the largest message, in bytes, a client may send
+const DefaultMaxMessageSize = 4096
+
+// WebSocketConfig represents WebSocket configuration
+type WebSocketConfig struct {
+	AllowQueryToken bool          `json:"allow_query_token"` // ?token= support, for development only
+	AllowAnyOrigin  bool          `json:"allow_any_origin"`  // skip the Origin check, for development only
+	TicketTTL       time.Duration `json:"ticket_ttl"`
+	ReadLimit       int64         `json:"read_limit"`  // max client message size in bytes
+	PongWait        time.Duration `json:"pong_wait"`   // read deadline, extended by each pong
+	PingPeriod      time.Duration `json:"ping_period"` // must be shorter than PongWait
+	WriteWait       time.Duration `json:"write_wait"`
+}
+
+// DefaultWebSocketConfig is the default WebSocket configuration
+var DefaultWebSocketConfig = WebSocketConfig{
+	AllowQueryToken: false,
+	AllowAnyOrigin:  false,
+	TicketTTL:       30 * time.Second,
+	ReadLimit:       DefaultMaxMessageSize,
+	PongWait:        60 * time.Second,
+	PingPeriod:      54 * time.Second,
+	WriteWait:       10 * time.Second,
+}
+
+// connectionSettings returns the read limit and timings to use for a connection,
+// falling back to defaults for unset values. The ping period is kept below the
+// pong wait so a healthy client always answers a ping before its read deadline.
+func (wc WebSocketConfig) connectionSettings() WebSocketConfig {
+	if wc.ReadLimit <= 0 {
+		wc.ReadLimit = DefaultMaxMessageSize
+	}
+	if wc.PongWait <= 0 {
+		wc.PongWait = 60 * time.Second
+	}
+	if wc.PingPeriod <= 0 || wc.PingPeriod >= wc.PongWait {
+		wc.PingPeriod = wc.PongWait * 9 / 10
+	}
+	if wc.WriteWait <= 0 {
+		wc.WriteWait = 10 * time.Second
+	}
+	return wc
+}
+
+// checkOrigin reports whether a connection from the request's Origin may be
+// upgraded. Browsers always send Origin, so requests without one come from
+// other clients and are allowed; browser origins must be in the CORS
+// AllowedOrigins to prevent cross-site WebSocket hijacking.
+func checkOrigin(r *http.Request, config WebSocketConfig) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || config.AllowAnyOrigin {
+		return true
+	}
+
+	for _, allowed := range security.GlobalSecurityConfig.GetConfig().AllowedOrigins {
+		if strings.EqualFold(origin, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	ErrMissingCredentials = errors.New("no credentials provided")
+	ErrInvalidTicket      = errors.New("invalid or expired ticket")
+)
+
+// Identity represents the authenticated user behind a WebSocket connection
+type Identity struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// ticket represents a single-use connection ticket
+type ticket struct {
+	identity  Identity
+	expiresAt time.Time
+}
+
+// TicketStore issues and redeems short-lived, single-use connection tickets
+type TicketStore struct {
+	tickets map[string]*ticket
+	mutex   sync.Mutex
+}
+
+// NewTicketStore creates a new ticket store
+func NewTicketStore() *TicketStore {
+	return &TicketStore{
+		tickets: make(map[string]*ticket),
+	}
+}
+
+// Issue creates a ticket for an identity that expires after ttl
+func (ts *TicketStore) Issue(identity Identity, ttl time.Duration) (string, time.Time) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	ts.cleanupExpired()
+
+	id := generateTicketID()
+	expiresAt := time.Now().Add(ttl)
+	ts.tickets[id] = &ticket{
+		identity:  identity,
+		expiresAt: expiresAt,
+	}
+
+	return id, expiresAt
+}
+
+// Redeem consumes a ticket and returns its identity; a ticket can only be redeemed once
+func (ts *TicketStore) Redeem(id string) (*Identity, error) {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	t, exists := ts.tickets[id]
+	if !exists {
+		return nil, ErrInvalidTicket
+	}
+	delete(ts.tickets, id)
+
+	if time.Now().After(t.expiresAt) {
+		return nil, ErrInvalidTicket
+	}
+
+	identity := t.identity
+	return &identity, nil
+}
+
+// cleanupExpired removes expired tickets. Must be called with the lock held.
+func (ts *TicketStore) cleanupExpired() {
+	now := time.Now()
+	for id, t := range ts.tickets {
+		if now.After(t.expiresAt) {
+			delete(ts.tickets, id)
+		}
+	}
+}
+
+// generateTicketID generates a random ticket ID
+func generateTicketID() string {
+	bytes := make([]byte, 32)
+	rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// GlobalTicketStore is the ticket store used by the WebSocket endpoint
+var GlobalTicketStore = NewTicketStore()
+
+// IssueTicketHandler exchanges an authenticated HTTP request for a connection ticket.
+// Must be mounted behind AuthMiddleware.
+func IssueTicketHandler(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	username, _ := c.Get("username")
+	role, _ := c.Get("role")
+
+	identity := Identity{UserID: userID.(uint)}
+	identity.Username, _ = username.(string)
+	identity.Role, _ = role.(string)
+
+	id, expiresAt := GlobalTicketStore.Issue(identity, DefaultWebSocketConfig.TicketTTL)
+
+	c.JSON(http.StatusOK, gin.H{
+		"ticket":     id,
+		"expires_at": expiresAt,
+		"url":        "/ws/metrics?ticket=" + id,
+	})
+}
+
+// authenticateRequest resolves the identity of a WebSocket handshake request.
+// Credentials are accepted, in order, from a single-use ticket, the Authorization
+// header, the session cookie, the Sec-WebSocket-Protocol header and, if enabled,
+// the token query parameter. Tokens are held to the session IP binding against
+// clientIP, as for HTTP requests.
+func authenticateRequest(r *http.Request, clientIP string, config WebSocketConfig, tickets *TicketStore, secretKey []byte) (*Identity, error) {
+	if id := r.URL.Query().Get("ticket"); id != "" {
+		return tickets.Redeem(id)
+	}
+
+	token := ""
+	if authHeader := r.Header.Get("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		token = strings.TrimPrefix(authHeader, "Bearer ")
+	} else if cookieToken := security.SessionCookieToken(r); cookieToken != "" {
+		token = cookieToken
+	} else if protocolToken := subprotocolToken(r); protocolToken != "" {
+		token = protocolToken
+	} else if config.AllowQueryToken {
+		token = r.URL.Query().Get("token")
+	}
+
+	if token == "" {
+		return nil, ErrMissingCredentials
+	}
+
+	claims, err := auth.ValidateJWT(token, secretKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := session.GlobalSessionManager.CheckSessionIP(token, claims, clientIP, r.UserAgent()); err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		UserID:   claims.UserID,
+		Username: claims.Username,
+		Role:     claims.Role,
+	}, nil
+}
+
+// subprotocolToken extracts a token sent as Sec-WebSocket-Protocol: bearer, <token>
+func subprotocolToken(r *http.Request) string {
+	protocols := websocketSubprotocols(r)
+	for i, protocol := range protocols {
+		if protocol == BearerSubprotocol && i+1 < len(protocols) {
+			return protocols[i+1]
+		}
+	}
+	return ""
+}
+
+// websocketSubprotocols returns the subprotocols requested by the client
+func websocketSubprotocols(r *http.Request) []string {
+	var protocols []string
+	for _, header := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if protocol = strings.TrimSpace(protocol); protocol != "" {
+				protocols = append(protocols, protocol)
+			}
+		}
+	}
+	return protocols
+}
@@ -0,0 +1,190 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/models"
+	"golangmcp/internal/security"
+	"golangmcp/internal/session"
+)
+
+var testSecret = []byte("my_secret_key")
+
+func TestTicketStore_RedeemOnce(t *testing.T) {
+	store := NewTicketStore()
+	id, _ := store.Issue(Identity{UserID: 7, Username: "testuser", Role: "user"}, time.Minute)
+
+	identity, err := store.Redeem(id)
+	if err != nil {
+		t.Fatalf("Failed to redeem ticket: %v", err)
+	}
+	if identity.UserID != 7 || identity.Username != "testuser" {
+		t.Errorf("Unexpected identity: %+v", identity)
+	}
+
+	if _, err := store.Redeem(id); err != ErrInvalidTicket {
+		t.Errorf("Expected second redemption to fail with ErrInvalidTicket, got %v", err)
+	}
+}
+
+func TestTicketStore_Expired(t *testing.T) {
+	store := NewTicketStore()
+	id, _ := store.Issue(Identity{UserID: 7}, -time.Second)
+
+	if _, err := store.Redeem(id); err != ErrInvalidTicket {
+		t.Errorf("Expected expired ticket to fail with ErrInvalidTicket, got %v", err)
+	}
+}
+
+func TestIssueTicketHandler_ExchangeForConnection(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/ws/ticket", nil)
+	c.Set("user_id", uint(3))
+	c.Set("username", "testuser")
+	c.Set("role", "user")
+
+	IssueTicketHandler(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var ticketID string
+	GlobalTicketStore.mutex.Lock()
+	for id := range GlobalTicketStore.tickets {
+		ticketID = id
+	}
+	GlobalTicketStore.mutex.Unlock()
+
+	req := httptest.NewRequest(http.MethodGet, "/ws/metrics?ticket="+ticketID, nil)
+	identity, err := authenticateRequest(req, "192.0.2.1", DefaultWebSocketConfig, GlobalTicketStore, testSecret)
+	if err != nil {
+		t.Fatalf("Failed to authenticate with ticket: %v", err)
+	}
+	if identity.UserID != 3 || identity.Role != "user" {
+		t.Errorf("Unexpected identity: %+v", identity)
+	}
+
+	if _, err := authenticateRequest(req, "192.0.2.1", DefaultWebSocketConfig, GlobalTicketStore, testSecret); err == nil {
+		t.Error("Expected replayed ticket to be rejected")
+	}
+}
+
+func TestAuthenticateRequest_TokenSources(t *testing.T) {
+	user := &models.User{ID: 5, Username: "testuser", Role: "admin"}
+	token, _, err := auth.GenerateJWT(user, testSecret)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	headerReq := httptest.NewRequest(http.MethodGet, "/ws/metrics", nil)
+	headerReq.Header.Set("Authorization", "Bearer "+token)
+
+	protocolReq := httptest.NewRequest(http.MethodGet, "/ws/metrics", nil)
+	protocolReq.Header.Set("Sec-WebSocket-Protocol", BearerSubprotocol+", "+token)
+
+	for name, req := range map[string]*http.Request{"header": headerReq, "subprotocol": protocolReq} {
+		identity, err := authenticateRequest(req, "192.0.2.1", DefaultWebSocketConfig, NewTicketStore(), testSecret)
+		if err != nil {
+			t.Errorf("%s: failed to authenticate: %v", name, err)
+			continue
+		}
+		if identity.UserID != user.ID {
+			t.Errorf("%s: expected user ID %d, got %d", name, user.ID, identity.UserID)
+		}
+	}
+
+	queryReq := httptest.NewRequest(http.MethodGet, "/ws/metrics?token="+token, nil)
+	if _, err := authenticateRequest(queryReq, "192.0.2.1", WebSocketConfig{AllowQueryToken: false}, NewTicketStore(), testSecret); err != ErrMissingCredentials {
+		t.Errorf("Expected query token to be ignored when disabled, got %v", err)
+	}
+	if _, err := authenticateRequest(queryReq, "192.0.2.1", WebSocketConfig{AllowQueryToken: true}, NewTicketStore(), testSecret); err != nil {
+		t.Errorf("Expected query token to be accepted when enabled, got %v", err)
+	}
+}
+
+func TestAuthenticateRequest_SessionIPBinding(t *testing.T) {
+	previous := session.GlobalSessionManager.GetConfig()
+	session.GlobalSessionManager.UpdateConfig(&session.SessionConfig{BindToIP: true})
+	t.Cleanup(func() { session.GlobalSessionManager.UpdateConfig(&previous) })
+
+	user := &models.User{ID: 6, Username: "testuser", Role: "user"}
+	bound, _, err := auth.GenerateJWT(user, testSecret)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	if _, err := session.GlobalSessionManager.CreateSession(user, bound, "192.0.2.1", "test-agent"); err != nil {
+		t.Fatalf("Failed to create session: %v", err)
+	}
+	lost, _, err := auth.GenerateJWT(&models.User{ID: 8, Username: "other", Role: "user"}, testSecret)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	request := func(token string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/ws/metrics", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		return req
+	}
+
+	if _, err := authenticateRequest(request(bound), "192.0.2.1", DefaultWebSocketConfig, NewTicketStore(), testSecret); err != nil {
+		t.Errorf("Expected the token to be accepted from its session address, got %v", err)
+	}
+	if _, err := authenticateRequest(request(bound), "198.51.100.7", DefaultWebSocketConfig, NewTicketStore(), testSecret); err != session.ErrSessionIPMismatch {
+		t.Errorf("Expected ErrSessionIPMismatch from another address, got %v", err)
+	}
+	if _, err := authenticateRequest(request(lost), "192.0.2.1", DefaultWebSocketConfig, NewTicketStore(), testSecret); err != session.ErrSessionNotFound {
+		t.Errorf("Expected ErrSessionNotFound for a token without session, got %v", err)
+	}
+}
+
+func TestCheckOrigin_AllowedVersusDisallowed(t *testing.T) {
+	allowed := security.GlobalSecurityConfig.GetConfig().AllowedOrigins[0]
+
+	tests := []struct {
+		name   string
+		origin string
+		config WebSocketConfig
+		want   bool
+	}{
+		{"allowed origin", allowed, DefaultWebSocketConfig, true},
+		{"disallowed origin", "https://evil.example.com", DefaultWebSocketConfig, false},
+		{"no origin", "", DefaultWebSocketConfig, true},
+		{"any origin in development", "https://evil.example.com", WebSocketConfig{AllowAnyOrigin: true}, true},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/ws/metrics", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := checkOrigin(req, tt.config); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestHandleWebSocket_RejectsDisallowedOriginBeforeUpgrade(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	token, _, _ := auth.GenerateJWT(&models.User{ID: 5, Username: "testuser", Role: "user"}, testSecret)
+
+	r := gin.New()
+	r.GET("/ws/metrics", HandleWebSocket)
+
+	req := httptest.NewRequest(http.MethodGet, "/ws/metrics", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a disallowed origin, got %d", w.Code)
+	}
+}
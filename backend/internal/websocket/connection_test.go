@@ -0,0 +1,83 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// waitForClientCount waits until the hub has the expected number of clients
+func waitForClientCount(t *testing.T, hub *Hub, expected int) {
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.ClientCount() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d clients, got %d", expected, hub.ClientCount())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReadPump_EnforcesConfiguredReadLimit(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Stop()
+
+	config := WebSocketConfig{ReadLimit: 1024}.connectionSettings()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		client := &Client{ID: "limit-test", Conn: conn, Send: make(chan []byte, 1), Hub: hub, Config: config}
+		hub.register <- client
+		go client.writePump()
+		go client.readPump()
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer conn.Close()
+	waitForClientCount(t, hub, 1)
+
+	// A message exactly at the limit is accepted
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("a", 1024))); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if hub.ClientCount() != 1 {
+		t.Fatal("Expected connection to stay open after a message at the limit")
+	}
+
+	// One byte over the limit closes the connection
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("a", 1025))); err != nil {
+		t.Fatalf("Failed to send message: %v", err)
+	}
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = conn.ReadMessage()
+	if !websocket.IsCloseError(err, websocket.CloseMessageTooBig) {
+		t.Errorf("Expected close with status 1009, got %v", err)
+	}
+	waitForClientCount(t, hub, 0)
+}
+
+func TestWebSocketConfig_ConnectionSettings(t *testing.T) {
+	settings := WebSocketConfig{PongWait: 10 * time.Second, PingPeriod: 30 * time.Second}.connectionSettings()
+	if settings.PingPeriod >= settings.PongWait {
+		t.Errorf("Expected ping period below pong wait, got %v >= %v", settings.PingPeriod, settings.PongWait)
+	}
+	if settings.ReadLimit != DefaultMaxMessageSize || settings.WriteWait <= 0 {
+		t.Errorf("Expected defaults for unset values, got %+v", settings)
+	}
+
+	defaults := DefaultWebSocketConfig.connectionSettings()
+	if defaults.PingPeriod != 54*time.Second || defaults.PongWait != 60*time.Second {
+		t.Errorf("Expected default timings to be kept, got %+v", defaults)
+	}
+}
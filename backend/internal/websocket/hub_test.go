@@ -0,0 +1,140 @@
+package websocket
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHub_ConcurrentRegisterUnregisterDuringBroadcast(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Stop()
+
+	stop := make(chan struct{})
+	var broadcasts sync.WaitGroup
+	broadcasts.Add(1)
+	go func() {
+		defer broadcasts.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			case hub.broadcast <- []byte("tick"):
+			}
+		}
+	}()
+
+	var clients sync.WaitGroup
+	for i := 0; i < 200; i++ {
+		clients.Add(1)
+		go func(i int) {
+			defer clients.Done()
+
+			// Half of the clients never read, so their buffers fill up and
+			// the hub drops them while others unregister themselves
+			bufferSize := 1
+			if i%2 == 0 {
+				bufferSize = 256
+			}
+			client := &Client{ID: fmt.Sprintf("client-%d", i), Send: make(chan []byte, bufferSize), Hub: hub}
+			hub.register <- client
+
+			if i%2 == 0 {
+				for j := 0; j < 10; j++ {
+					<-client.Send
+				}
+				hub.unregister <- client
+			}
+
+			// The Send channel must be closed exactly once in either case
+			for range client.Send {
+			}
+		}(i)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		clients.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Timed out waiting for clients to be removed")
+	}
+
+	close(stop)
+	broadcasts.Wait()
+
+	if count := hub.ClientCount(); count != 0 {
+		t.Errorf("Expected all clients to be removed, %d remain", count)
+	}
+}
+
+func TestHub_IsFull(t *testing.T) {
+	hub := NewHub()
+	hub.maxClients = 2
+	go hub.Run()
+	defer hub.Stop()
+
+	for i := 0; i < 2; i++ {
+		if hub.IsFull() {
+			t.Fatalf("Expected hub to accept client %d", i)
+		}
+		hub.register <- &Client{ID: fmt.Sprintf("client-%d", i), Send: make(chan []byte, 1), Hub: hub}
+	}
+
+	// Registration is processed by Run; wait for it to settle
+	deadline := time.Now().Add(time.Second)
+	for hub.ClientCount() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if !hub.IsFull() {
+		t.Error("Expected hub to be full")
+	}
+}
+
+func TestHub_ClientsAndDisconnect(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Stop()
+
+	now := time.Now()
+	first := &Client{ID: "first", Identity: Identity{UserID: 1, Username: "admin"}, Send: make(chan []byte, 1), Hub: hub, ConnectedAt: now.Add(-time.Minute), LastPing: now}
+	second := &Client{ID: "second", Identity: Identity{UserID: 2, Username: "testuser"}, Send: make(chan []byte, 1), Hub: hub, ConnectedAt: now, LastPing: now}
+	hub.register <- second
+	hub.register <- first
+
+	deadline := time.Now().Add(time.Second)
+	for hub.ClientCount() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	clients := hub.Clients()
+	if len(clients) != 2 || clients[0].ID != "first" || clients[1].Username != "testuser" {
+		t.Fatalf("Unexpected client snapshot: %+v", clients)
+	}
+
+	info, err := hub.Disconnect("second")
+	if err != nil {
+		t.Fatalf("Failed to disconnect client: %v", err)
+	}
+	if info.UserID != 2 {
+		t.Errorf("Expected disconnected client of user 2, got %+v", info)
+	}
+
+	if _, ok := <-second.Send; ok {
+		t.Error("Expected Send channel of disconnected client to be closed")
+	}
+	if count := hub.ClientCount(); count != 1 {
+		t.Errorf("Expected 1 remaining client, got %d", count)
+	}
+
+	if _, err := hub.Disconnect("second"); err != ErrClientNotFound {
+		t.Errorf("Expected ErrClientNotFound, got %v", err)
+	}
+}
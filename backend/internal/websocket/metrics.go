@@ -2,8 +2,11 @@ package websocket
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
@@ -13,30 +16,76 @@ import (
 	"github.com/shirou/gopsutil/v3/disk"
 	"github.com/shirou/gopsutil/v3/mem"
 	"github.com/shirou/gopsutil/v3/net"
+	"golangmcp/internal/auth"
 )
 
 // WebSocket upgrader
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
-		return true // Allow all origins for development
+		return checkOrigin(r, DefaultWebSocketConfig)
 	},
+	Subprotocols: []string{BearerSubprotocol},
 }
 
 // Client represents a WebSocket client
 type Client struct {
-	ID       string
-	Conn     *websocket.Conn
-	Send     chan []byte
-	Hub      *Hub
-	LastPing time.Time
+	ID          string
+	Identity    Identity
+	Conn        *websocket.Conn
+	Send        chan []byte
+	Hub         *Hub
+	ConnectedAt time.Time
+	LastPing    time.Time // guarded by pingMutex
+	Config      WebSocketConfig
+	pingMutex   sync.Mutex
 }
 
-// Hub maintains the set of active clients and broadcasts messages
+// ClientInfo is a snapshot of a connected client's metadata
+type ClientInfo struct {
+	ID          string    `json:"id"`
+	UserID      uint      `json:"user_id"`
+	Username    string    `json:"username"`
+	Role        string    `json:"role"`
+	ConnectedAt time.Time `json:"connected_at"`
+	LastPing    time.Time `json:"last_ping"`
+}
+
+var ErrClientNotFound = errors.New("client not found")
+
+// touch records a pong from the client
+func (c *Client) touch() {
+	c.pingMutex.Lock()
+	defer c.pingMutex.Unlock()
+	c.LastPing = time.Now()
+}
+
+// Info returns a snapshot of the client's metadata
+func (c *Client) Info() ClientInfo {
+	c.pingMutex.Lock()
+	defer c.pingMutex.Unlock()
+	return ClientInfo{
+		ID:          c.ID,
+		UserID:      c.Identity.UserID,
+		Username:    c.Identity.Username,
+		Role:        c.Identity.Role,
+		ConnectedAt: c.ConnectedAt,
+		LastPing:    c.LastPing,
+	}
+}
+
+// DefaultMaxClients is the default maximum number of concurrent metrics clients
+const DefaultMaxClients = 1000
+
+// Hub maintains the set of active clients and broadcasts messages.
+// A client's Send channel is closed exactly once, when the client is removed
+// through unregister or Disconnect.
 type Hub struct {
 	clients    map[*Client]bool
 	register   chan *Client
 	unregister chan *Client
 	broadcast  chan []byte
+	done       chan struct{}
+	maxClients int
 	mutex      sync.RWMutex
 }
 
@@ -47,7 +96,68 @@ func NewHub() *Hub {
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan []byte),
+		done:       make(chan struct{}),
+		maxClients: DefaultMaxClients,
+	}
+}
+
+// Stop stops the hub's Run loop
+func (h *Hub) Stop() {
+	close(h.done)
+}
+
+// ClientCount returns the number of registered clients
+func (h *Hub) ClientCount() int {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	return len(h.clients)
+}
+
+// Register adds a client to the hub
+func (h *Hub) Register(client *Client) {
+	h.register <- client
+}
+
+// Clients returns a snapshot of the connected clients, oldest connection first
+func (h *Hub) Clients() []ClientInfo {
+	h.mutex.RLock()
+	clients := make([]ClientInfo, 0, len(h.clients))
+	for client := range h.clients {
+		clients = append(clients, client.Info())
 	}
+	h.mutex.RUnlock()
+
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].ConnectedAt.Before(clients[j].ConnectedAt)
+	})
+	return clients
+}
+
+// Disconnect forcibly disconnects a client by ID. Closing its Send channel
+// makes writePump send a close frame and drop the connection.
+func (h *Hub) Disconnect(clientID string) (*ClientInfo, error) {
+	var target *Client
+	h.mutex.RLock()
+	for client := range h.clients {
+		if client.ID == clientID {
+			target = client
+			break
+		}
+	}
+	h.mutex.RUnlock()
+
+	// The client may have disconnected on its own in the meantime
+	if target == nil || !h.remove(target) {
+		return nil, ErrClientNotFound
+	}
+
+	info := target.Info()
+	return &info, nil
+}
+
+// IsFull checks if the hub has reached its client limit
+func (h *Hub) IsFull() bool {
+	return h.maxClients > 0 && h.ClientCount() >= h.maxClients
 }
 
 // Run starts the hub
@@ -57,36 +167,25 @@ func (h *Hub) Run() {
 
 	for {
 		select {
+		case <-h.done:
+			return
+
 		case client := <-h.register:
 			h.mutex.Lock()
 			h.clients[client] = true
+			count := len(h.clients)
 			h.mutex.Unlock()
-			log.Printf("Client %s connected. Total clients: %d", client.ID, len(h.clients))
+			log.Printf("Client %s connected. Total clients: %d", client.ID, count)
 
 		case client := <-h.unregister:
-			h.mutex.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.Send)
-			}
-			h.mutex.Unlock()
-			log.Printf("Client %s disconnected. Total clients: %d", client.ID, len(h.clients))
+			h.remove(client)
 
 		case message := <-h.broadcast:
-			h.mutex.RLock()
-			for client := range h.clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(h.clients, client)
-				}
-			}
-			h.mutex.RUnlock()
+			h.send(message)
 
 		case <-ticker.C:
 			// Send metrics to all connected clients
-			metrics, err := collectRealtimeMetrics()
+			metrics, err := GlobalMetricsSampler.Get()
 			if err != nil {
 				log.Printf("Error collecting metrics: %v", err)
 				continue
@@ -98,27 +197,63 @@ func (h *Hub) Run() {
 				continue
 			}
 
-			h.mutex.RLock()
-			for client := range h.clients {
-				select {
-				case client.Send <- data:
-				default:
-					close(client.Send)
-					delete(h.clients, client)
-				}
-			}
-			h.mutex.RUnlock()
+			h.send(data)
+		}
+	}
+}
+
+// remove removes a client and closes its Send channel. The lock and the
+// membership check guarantee the channel is closed exactly once.
+func (h *Hub) remove(client *Client) bool {
+	h.mutex.Lock()
+	_, ok := h.clients[client]
+	if ok {
+		delete(h.clients, client)
+		close(client.Send)
+	}
+	count := len(h.clients)
+	h.mutex.Unlock()
+
+	if ok {
+		log.Printf("Client %s disconnected. Total clients: %d", client.ID, count)
+	}
+	return ok
+}
+
+// send delivers a message to every client. Clients whose buffer is full are
+// handed to unregister rather than removed here, so Send is only closed there.
+func (h *Hub) send(message []byte) {
+	var slow []*Client
+
+	h.mutex.RLock()
+	for client := range h.clients {
+		select {
+		case client.Send <- message:
+		default:
+			slow = append(slow, client)
 		}
 	}
+	h.mutex.RUnlock()
+
+	// Run is the only receiver on unregister, so queue removals asynchronously
+	for _, client := range slow {
+		go func(client *Client) {
+			select {
+			case h.unregister <- client:
+			case <-h.done:
+			}
+		}(client)
+	}
 }
 
 // RealtimeMetrics represents real-time system metrics
 type RealtimeMetrics struct {
-	Timestamp time.Time `json:"timestamp"`
-	CPU       float64   `json:"cpu"`
-	Memory    float64   `json:"memory"`
-	Disk      float64   `json:"disk"`
-	Network   NetworkIO `json:"network"`
+	Timestamp time.Time         `json:"timestamp"`
+	CPU       float64           `json:"cpu"`
+	Memory    float64           `json:"memory"`
+	Disk      float64           `json:"disk"`
+	Network   NetworkIO         `json:"network"`
+	Errors    map[string]string `json:"errors,omitempty"` // per-section collection errors
 }
 
 // NetworkIO represents network I/O statistics
@@ -137,44 +272,76 @@ var (
 	networkMutex sync.Mutex
 )
 
-// collectRealtimeMetrics collects real-time system metrics
+// Section samplers used by collectRealtimeMetrics, replaceable in tests
+var (
+	cpuSampler = func() (float64, error) {
+		cpuPercent, err := cpu.Percent(time.Second, false)
+		if err != nil {
+			return 0, err
+		}
+		if len(cpuPercent) > 0 {
+			return cpuPercent[0], nil
+		}
+		return 0, nil
+	}
+	memorySampler = func() (float64, error) {
+		memStat, err := mem.VirtualMemory()
+		if err != nil {
+			return 0, err
+		}
+		return memStat.UsedPercent, nil
+	}
+	diskSampler = func() (float64, error) {
+		diskUsage, err := disk.Usage("/")
+		if err != nil {
+			return 0, err
+		}
+		return diskUsage.UsedPercent, nil
+	}
+	networkSampler = collectNetworkIO
+)
+
+// collectRealtimeMetrics collects real-time system metrics. Sections that fail
+// are reported in Errors; an error is returned only when every section fails.
 func collectRealtimeMetrics() (*RealtimeMetrics, error) {
-	// CPU usage
-	cpuPercent, err := cpu.Percent(time.Second, false)
-	if err != nil {
-		return nil, err
+	metrics := &RealtimeMetrics{
+		Timestamp: time.Now(),
 	}
+	errors := make(map[string]string)
 
-	var cpuUsage float64
-	if len(cpuPercent) > 0 {
-		cpuUsage = cpuPercent[0]
+	if cpuUsage, err := cpuSampler(); err != nil {
+		errors["cpu"] = err.Error()
+	} else {
+		metrics.CPU = cpuUsage
 	}
 
-	// Memory usage
-	memStat, err := mem.VirtualMemory()
-	if err != nil {
-		return nil, err
+	if memoryUsage, err := memorySampler(); err != nil {
+		errors["memory"] = err.Error()
+	} else {
+		metrics.Memory = memoryUsage
 	}
 
-	// Disk usage
-	diskUsage, err := disk.Usage("/")
-	if err != nil {
-		return nil, err
+	if diskUsage, err := diskSampler(); err != nil {
+		errors["disk"] = err.Error()
+	} else {
+		metrics.Disk = diskUsage
 	}
 
-	// Network I/O
-	networkIO, err := collectNetworkIO()
-	if err != nil {
-		return nil, err
+	if networkIO, err := networkSampler(); err != nil {
+		errors["network"] = err.Error()
+	} else {
+		metrics.Network = *networkIO
 	}
 
-	return &RealtimeMetrics{
-		Timestamp: time.Now(),
-		CPU:       cpuUsage,
-		Memory:    memStat.UsedPercent,
-		Disk:      diskUsage.UsedPercent,
-		Network:   *networkIO,
-	}, nil
+	if len(errors) == 4 {
+		return nil, fmt.Errorf("failed to collect any realtime metrics: %v", errors)
+	}
+
+	if len(errors) > 0 {
+		metrics.Errors = errors
+	}
+
+	return metrics, nil
 }
 
 // collectNetworkIO collects network I/O statistics
@@ -225,19 +392,26 @@ func InitializeWebSocket() {
 	log.Println("WebSocket hub initialized")
 }
 
-// HandleWebSocket handles WebSocket connections
+// HandleWebSocket handles WebSocket connections.
+// Browsers should first POST /ws/ticket with their bearer token and connect with
+// ?ticket=<ticket>; other clients may send the Authorization header directly.
 func HandleWebSocket(c *gin.Context) {
-	// Check for authentication token in query parameters
-	token := c.Query("token")
-	if token == "" {
-		log.Printf("WebSocket connection rejected: no token provided")
+	if !checkOrigin(c.Request, DefaultWebSocketConfig) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Origin not allowed"})
+		return
+	}
+
+	identity, err := authenticateRequest(c.Request, c.ClientIP(), DefaultWebSocketConfig, GlobalTicketStore, auth.JWTSecret())
+	if err != nil {
+		log.Printf("WebSocket connection rejected: %v", err)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
 		return
 	}
 
-	// TODO: Validate token here if needed
-	// For now, we'll just check if it exists
-	log.Printf("WebSocket connection with token: %s", token[:10]+"...")
+	if GlobalHub.IsFull() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Too many WebSocket connections"})
+		return
+	}
 
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -245,34 +419,29 @@ func HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	now := time.Now()
 	client := &Client{
-		ID:       generateClientID(),
-		Conn:     conn,
-		Send:     make(chan []byte, 256),
-		Hub:      GlobalHub,
-		LastPing: time.Now(),
+		ID:          generateClientID(),
+		Identity:    *identity,
+		Conn:        conn,
+		Send:        make(chan []byte, 256),
+		Hub:         GlobalHub,
+		ConnectedAt: now,
+		LastPing:    now,
+		Config:      DefaultWebSocketConfig.connectionSettings(),
 	}
 
-	client.Hub.register <- client
+	client.Hub.Register(client)
 
 	// Start goroutines for reading and writing
 	go client.writePump()
 	go client.readPump()
 }
 
-// generateClientID generates a unique client ID
+// generateClientID generates a unique client ID. IDs are used to disconnect
+// clients, so the suffix must be random rather than time-derived.
 func generateClientID() string {
-	return time.Now().Format("20060102150405") + "-" + randomString(6)
-}
-
-// randomString generates a random string of specified length
-func randomString(length int) string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, length)
-	for i := range b {
-		b[i] = charset[time.Now().UnixNano()%int64(len(charset))]
-	}
-	return string(b)
+	return time.Now().Format("20060102150405") + "-" + generateTicketID()[:12]
 }
 
 // readPump pumps messages from the WebSocket connection to the hub
@@ -282,11 +451,11 @@ func (c *Client) readPump() {
 		c.Conn.Close()
 	}()
 
-	c.Conn.SetReadLimit(512)
-	c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	c.Conn.SetReadLimit(c.Config.ReadLimit)
+	c.Conn.SetReadDeadline(time.Now().Add(c.Config.PongWait))
 	c.Conn.SetPongHandler(func(string) error {
-		c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-		c.LastPing = time.Now()
+		c.Conn.SetReadDeadline(time.Now().Add(c.Config.PongWait))
+		c.touch()
 		return nil
 	})
 
@@ -303,7 +472,7 @@ func (c *Client) readPump() {
 
 // writePump pumps messages from the hub to the WebSocket connection
 func (c *Client) writePump() {
-	ticker := time.NewTicker(54 * time.Second)
+	ticker := time.NewTicker(c.Config.PingPeriod)
 	defer func() {
 		ticker.Stop()
 		c.Conn.Close()
@@ -312,7 +481,7 @@ func (c *Client) writePump() {
 	for {
 		select {
 		case message, ok := <-c.Send:
-			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			c.Conn.SetWriteDeadline(time.Now().Add(c.Config.WriteWait))
 			if !ok {
 				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
@@ -336,7 +505,7 @@ func (c *Client) writePump() {
 			}
 
 		case <-ticker.C:
-			c.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			c.Conn.SetWriteDeadline(time.Now().Add(c.Config.WriteWait))
 			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
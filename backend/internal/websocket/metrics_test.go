@@ -0,0 +1,31 @@
+package websocket
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCollectRealtimeMetrics_PartialFailure(t *testing.T) {
+	origCPU, origMem, origDisk, origNet := cpuSampler, memorySampler, diskSampler, networkSampler
+	t.Cleanup(func() {
+		cpuSampler, memorySampler, diskSampler, networkSampler = origCPU, origMem, origDisk, origNet
+	})
+
+	cpuSampler = func() (float64, error) { return 12.5, nil }
+	memorySampler = func() (float64, error) { return 50, nil }
+	diskSampler = func() (float64, error) { return 0, errors.New("disk unavailable") }
+	networkSampler = func() (*NetworkIO, error) { return &NetworkIO{BytesSent: 10}, nil }
+
+	metrics, err := collectRealtimeMetrics()
+	if err != nil {
+		t.Fatalf("Expected partial metrics, got error: %v", err)
+	}
+
+	if metrics.CPU != 12.5 || metrics.Memory != 50 || metrics.Network.BytesSent != 10 {
+		t.Errorf("Expected successful sections to be populated, got %+v", metrics)
+	}
+
+	if metrics.Errors["disk"] != "disk unavailable" || len(metrics.Errors) != 1 {
+		t.Errorf("Expected only the disk error flag, got %v", metrics.Errors)
+	}
+}
@@ -0,0 +1,141 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+)
+
+// SSE stream interval bounds
+const (
+	DefaultSSEInterval = time.Second
+	MinSSEInterval     = time.Second
+	MaxSSEInterval     = time.Minute
+)
+
+// MetricsSampler caches realtime metrics so concurrent consumers (WebSocket hub,
+// SSE streams) share one collection per maxAge instead of sampling separately
+type MetricsSampler struct {
+	maxAge      time.Duration
+	latest      *RealtimeMetrics
+	collectedAt time.Time // when collection finished; Timestamp is taken before the CPU sample
+	collect     func() (*RealtimeMetrics, error)
+	mutex       sync.Mutex
+}
+
+// NewMetricsSampler creates a new metrics sampler
+func NewMetricsSampler(maxAge time.Duration) *MetricsSampler {
+	return &MetricsSampler{
+		maxAge:  maxAge,
+		collect: collectRealtimeMetrics,
+	}
+}
+
+// Get returns the cached metrics, collecting fresh ones if they are older than maxAge
+func (ms *MetricsSampler) Get() (*RealtimeMetrics, error) {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	if ms.latest != nil && time.Since(ms.collectedAt) < ms.maxAge {
+		return ms.latest, nil
+	}
+
+	metrics, err := ms.collect()
+	if err != nil {
+		return nil, err
+	}
+
+	ms.latest = metrics
+	ms.collectedAt = time.Now()
+	return metrics, nil
+}
+
+// GlobalMetricsSampler is the metrics sampler shared by the WebSocket and SSE endpoints
+var GlobalMetricsSampler = NewMetricsSampler(time.Second)
+
+// HandleSSEMetrics streams RealtimeMetrics as text/event-stream, for clients that
+// cannot use WebSockets. Authentication is the same as /ws/metrics. The interval
+// query parameter accepts seconds ("5") or a duration ("2s"), between 1s and 1m.
+func HandleSSEMetrics(c *gin.Context) {
+	if _, err := authenticateRequest(c.Request, c.ClientIP(), DefaultWebSocketConfig, GlobalTicketStore, auth.JWTSecret()); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	interval, err := parseSSEInterval(c.Query("interval"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // disable proxy buffering
+	c.Status(http.StatusOK)
+
+	// Tell EventSource clients to reconnect at the stream interval
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", interval.Milliseconds())
+	c.Writer.Flush()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := writeSSEMetrics(c); err != nil {
+			return
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// writeSSEMetrics writes one metrics event, or an error event if collection failed
+func writeSSEMetrics(c *gin.Context) error {
+	metrics, err := GlobalMetricsSampler.Get()
+
+	event, payload := "metrics", []byte(nil)
+	if err != nil {
+		event, payload = "error", []byte(strconv.Quote(err.Error()))
+	} else if payload, err = json.Marshal(metrics); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// parseSSEInterval parses and bounds the requested stream interval
+func parseSSEInterval(value string) (time.Duration, error) {
+	if value == "" {
+		return DefaultSSEInterval, nil
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		seconds, convErr := strconv.Atoi(value)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid interval %q", value)
+		}
+		interval = time.Duration(seconds) * time.Second
+	}
+
+	if interval < MinSSEInterval || interval > MaxSSEInterval {
+		return 0, fmt.Errorf("interval must be between %s and %s", MinSSEInterval, MaxSSEInterval)
+	}
+
+	return interval, nil
+}
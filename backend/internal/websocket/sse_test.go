@@ -0,0 +1,117 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/models"
+)
+
+func TestHandleSSEMetrics_StreamsFrames(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	origSampler := GlobalMetricsSampler
+	t.Cleanup(func() { GlobalMetricsSampler = origSampler })
+	GlobalMetricsSampler = NewMetricsSampler(time.Second)
+	GlobalMetricsSampler.collect = func() (*RealtimeMetrics, error) {
+		return &RealtimeMetrics{Timestamp: time.Now(), CPU: 25}, nil
+	}
+
+	r := gin.New()
+	r.GET("/sse/metrics", HandleSSEMetrics)
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	token, _, _ := auth.GenerateJWT(&models.User{ID: 1, Username: "testuser", Role: "user"}, testSecret)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/sse/metrics?interval=1s", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected text/event-stream, got %q", ct)
+	}
+
+	frames := 0
+	scanner := bufio.NewScanner(resp.Body)
+	for frames < 2 && scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var metrics RealtimeMetrics
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &metrics); err != nil {
+			t.Fatalf("Invalid frame %q: %v", line, err)
+		}
+		if metrics.CPU != 25 {
+			t.Errorf("Expected CPU 25, got %v", metrics.CPU)
+		}
+		frames++
+	}
+
+	if frames != 2 {
+		t.Errorf("Expected 2 frames, got %d (%v)", frames, scanner.Err())
+	}
+}
+
+func TestHandleSSEMetrics_RequiresAuthentication(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.GET("/sse/metrics", HandleSSEMetrics)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/sse/metrics", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
+
+func TestParseSSEInterval(t *testing.T) {
+	cases := map[string]time.Duration{"": time.Second, "5": 5 * time.Second, "2s": 2 * time.Second}
+	for value, expected := range cases {
+		if interval, err := parseSSEInterval(value); err != nil || interval != expected {
+			t.Errorf("parseSSEInterval(%q) = %v, %v; expected %v", value, interval, err, expected)
+		}
+	}
+
+	for _, value := range []string{"10ms", "2h", "abc"} {
+		if _, err := parseSSEInterval(value); err == nil {
+			t.Errorf("Expected error for interval %q", value)
+		}
+	}
+}
+
+func TestMetricsSampler_CachesSlowCollections(t *testing.T) {
+	sampler := NewMetricsSampler(100 * time.Millisecond)
+	collections := 0
+	sampler.collect = func() (*RealtimeMetrics, error) {
+		collections++
+		// Like the CPU sample, collection takes longer than the cache lifetime
+		timestamp := time.Now()
+		time.Sleep(150 * time.Millisecond)
+		return &RealtimeMetrics{Timestamp: timestamp}, nil
+	}
+
+	first, _ := sampler.Get()
+	second, _ := sampler.Get()
+	if collections != 1 || first != second {
+		t.Errorf("Expected the second consumer to share the first collection, got %d collections", collections)
+	}
+}
@@ -0,0 +1,373 @@
+package websocket
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"golangmcp/internal/auth"
+)
+
+// UploadIDHeader is the request header tying an upload to a progress channel
+const UploadIDHeader = "X-Upload-ID"
+
+// Upload progress event types
+const (
+	ProgressEventProgress = "progress"
+	ProgressEventComplete = "complete"
+	ProgressEventError    = "error"
+)
+
+var ErrUploadNotFound = errors.New("upload not found")
+
+// ProgressEvent represents an upload progress update
+type ProgressEvent struct {
+	UploadID      string    `json:"upload_id"`
+	Type          string    `json:"type"` // progress, complete, error
+	BytesReceived int64     `json:"bytes_received"`
+	TotalBytes    int64     `json:"total_bytes"` // -1 if unknown
+	Percent       float64   `json:"percent"`
+	Error         string    `json:"error,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// uploadProgress represents the progress state of a single upload
+type uploadProgress struct {
+	ownerID     uint
+	subscribers map[chan ProgressEvent]bool
+	last        *ProgressEvent
+	createdAt   time.Time
+}
+
+// UploadProgressTracker routes upload progress events to subscribers, keyed by upload ID
+type UploadProgressTracker struct {
+	uploads map[string]*uploadProgress
+	ttl     time.Duration
+	mutex   sync.Mutex
+}
+
+// NewUploadProgressTracker creates a new upload progress tracker
+func NewUploadProgressTracker() *UploadProgressTracker {
+	return &UploadProgressTracker{
+		uploads: make(map[string]*uploadProgress),
+		ttl:     time.Hour,
+	}
+}
+
+// Create registers a new upload owned by a user and returns its ID
+func (t *UploadProgressTracker) Create(ownerID uint) string {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.cleanupStale()
+
+	id := generateTicketID()[:32]
+	t.uploads[id] = &uploadProgress{
+		ownerID:     ownerID,
+		subscribers: make(map[chan ProgressEvent]bool),
+		createdAt:   time.Now(),
+	}
+	return id
+}
+
+// IsOwner checks if an upload exists and belongs to a user
+func (t *UploadProgressTracker) IsOwner(uploadID string, userID uint) bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	upload, exists := t.uploads[uploadID]
+	return exists && upload.ownerID == userID
+}
+
+// Subscribe returns a channel receiving the upload's events and a function to stop
+// receiving them. The channel is closed after the final complete or error event.
+func (t *UploadProgressTracker) Subscribe(uploadID string, userID uint) (<-chan ProgressEvent, func(), error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	upload, exists := t.uploads[uploadID]
+	if !exists || upload.ownerID != userID {
+		return nil, nil, ErrUploadNotFound
+	}
+
+	ch := make(chan ProgressEvent, 64)
+	upload.subscribers[ch] = true
+
+	// Late subscribers start from the latest known progress
+	if upload.last != nil {
+		ch <- *upload.last
+	}
+
+	unsubscribe := func() {
+		t.mutex.Lock()
+		defer t.mutex.Unlock()
+		if upload.subscribers[ch] {
+			delete(upload.subscribers, ch)
+			close(ch)
+		}
+	}
+
+	return ch, unsubscribe, nil
+}
+
+// Publish delivers an event to the upload's subscribers. Slow subscribers miss
+// intermediate progress events rather than blocking the upload.
+func (t *UploadProgressTracker) Publish(event ProgressEvent) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	upload, exists := t.uploads[event.UploadID]
+	if !exists {
+		return
+	}
+
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if event.TotalBytes > 0 {
+		event.Percent = float64(event.BytesReceived) / float64(event.TotalBytes) * 100
+	}
+	upload.last = &event
+
+	final := event.Type == ProgressEventComplete || event.Type == ProgressEventError
+	for ch := range upload.subscribers {
+		if final {
+			// Make room so the final event is never dropped
+			select {
+			case ch <- event:
+			default:
+				<-ch
+				ch <- event
+			}
+			close(ch)
+			delete(upload.subscribers, ch)
+			continue
+		}
+
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+
+	if final {
+		delete(t.uploads, event.UploadID)
+	}
+}
+
+// Complete publishes the final event of a successful upload
+func (t *UploadProgressTracker) Complete(uploadID string, bytesReceived int64) {
+	t.Publish(ProgressEvent{
+		UploadID:      uploadID,
+		Type:          ProgressEventComplete,
+		BytesReceived: bytesReceived,
+		TotalBytes:    bytesReceived,
+	})
+}
+
+// Fail publishes the final event of a failed upload
+func (t *UploadProgressTracker) Fail(uploadID string, message string) {
+	t.Publish(ProgressEvent{
+		UploadID: uploadID,
+		Type:     ProgressEventError,
+		Error:    message,
+	})
+}
+
+// cleanupStale removes uploads that never finished. Must be called with the lock held.
+func (t *UploadProgressTracker) cleanupStale() {
+	cutoff := time.Now().Add(-t.ttl)
+	for id, upload := range t.uploads {
+		if upload.createdAt.Before(cutoff) {
+			// Unregister the channels too, so unsubscribing later doesn't close them again
+			for ch := range upload.subscribers {
+				close(ch)
+				delete(upload.subscribers, ch)
+			}
+			delete(t.uploads, id)
+		}
+	}
+}
+
+// progressReader counts bytes read from an upload body and publishes progress events
+type progressReader struct {
+	io.ReadCloser
+	tracker   *UploadProgressTracker
+	uploadID  string
+	total     int64
+	received  int64
+	published int64
+	step      int64
+}
+
+// NewProgressReader wraps an upload body so reads publish progress for uploadID.
+// total is the expected size in bytes, or -1 if unknown.
+func (t *UploadProgressTracker) NewProgressReader(body io.ReadCloser, uploadID string, total int64) io.ReadCloser {
+	// Publish roughly every 1% of the upload, and at least every 32KB read
+	step := int64(32 * 1024)
+	if total > 0 && total/100 < step {
+		step = total / 100
+	}
+	if step < 1 {
+		step = 1
+	}
+
+	return &progressReader{
+		ReadCloser: body,
+		tracker:    t,
+		uploadID:   uploadID,
+		total:      total,
+		step:       step,
+	}
+}
+
+// Read reads from the underlying body and publishes progress
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.ReadCloser.Read(p)
+	pr.received += int64(n)
+
+	if n > 0 && (pr.received-pr.published >= pr.step || pr.received == pr.total) {
+		pr.published = pr.received
+		pr.tracker.Publish(ProgressEvent{
+			UploadID:      pr.uploadID,
+			Type:          ProgressEventProgress,
+			BytesReceived: pr.received,
+			TotalBytes:    pr.total,
+		})
+	}
+
+	return n, err
+}
+
+// GlobalUploadProgress is the upload progress tracker used by the upload handlers
+var GlobalUploadProgress = NewUploadProgressTracker()
+
+// TrackUploadProgress wraps the request body of an authenticated upload whose
+// X-Upload-ID header names an upload owned by the user. Returns the upload ID,
+// or an empty string if the request is not tracked. Must run before the
+// multipart form is parsed.
+func TrackUploadProgress(c *gin.Context) string {
+	uploadID := c.GetHeader(UploadIDHeader)
+	if uploadID == "" {
+		return ""
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return ""
+	}
+
+	if !GlobalUploadProgress.IsOwner(uploadID, userID.(uint)) {
+		return ""
+	}
+
+	c.Request.Body = GlobalUploadProgress.NewProgressReader(c.Request.Body, uploadID, c.Request.ContentLength)
+	return uploadID
+}
+
+// FinishUploadProgress publishes the final event of a tracked upload based on the
+// response status. Intended to be deferred right after TrackUploadProgress.
+func FinishUploadProgress(c *gin.Context, uploadID string) {
+	if uploadID == "" {
+		return
+	}
+
+	received := c.Request.ContentLength
+	if pr, ok := c.Request.Body.(*progressReader); ok {
+		received = pr.received
+	}
+
+	if status := c.Writer.Status(); status >= http.StatusBadRequest {
+		GlobalUploadProgress.Fail(uploadID, http.StatusText(status))
+		return
+	}
+
+	GlobalUploadProgress.Complete(uploadID, received)
+}
+
+// CreateUploadHandler registers an upload so its progress can be followed over
+// /ws/uploads/:uploadId before the file is sent. Must be mounted behind AuthMiddleware.
+func CreateUploadHandler(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	uploadID := GlobalUploadProgress.Create(userID.(uint))
+
+	c.JSON(http.StatusCreated, gin.H{
+		"upload_id": uploadID,
+		"header":    UploadIDHeader,
+		"url":       "/ws/uploads/" + uploadID,
+	})
+}
+
+// HandleUploadProgressWebSocket streams progress events of an upload owned by
+// the authenticated user until the upload completes or fails
+func HandleUploadProgressWebSocket(c *gin.Context) {
+	if !checkOrigin(c.Request, DefaultWebSocketConfig) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Origin not allowed"})
+		return
+	}
+
+	identity, err := authenticateRequest(c.Request, c.ClientIP(), DefaultWebSocketConfig, GlobalTicketStore, auth.JWTSecret())
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	events, unsubscribe, err := GlobalUploadProgress.Subscribe(c.Param("uploadId"), identity.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload not found"})
+		return
+	}
+	defer unsubscribe()
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade error: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// Stop streaming when the client goes away
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+
+		case <-closed:
+			return
+		}
+	}
+}
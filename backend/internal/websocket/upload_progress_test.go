@@ -0,0 +1,107 @@
+package websocket
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestUploadProgress_ChunkedReceipt(t *testing.T) {
+	tracker := NewUploadProgressTracker()
+	uploadID := tracker.Create(1)
+
+	events, unsubscribe, err := tracker.Subscribe(uploadID, 1)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer unsubscribe()
+
+	// 10 chunks of 1000 bytes, progress is published every 1% (100 bytes)
+	const total = 10000
+	body := tracker.NewProgressReader(io.NopCloser(bytes.NewReader(make([]byte, total))), uploadID, total)
+	chunk := make([]byte, 1000)
+	for {
+		if _, err := body.Read(chunk); err == io.EOF {
+			break
+		}
+	}
+	tracker.Complete(uploadID, total)
+
+	var received []ProgressEvent
+	for event := range events {
+		received = append(received, event)
+	}
+
+	if len(received) != 11 {
+		t.Fatalf("Expected 10 progress events and 1 completion, got %d", len(received))
+	}
+
+	var last int64
+	for i, event := range received[:10] {
+		if event.Type != ProgressEventProgress {
+			t.Errorf("Event %d: expected progress, got %s", i, event.Type)
+		}
+		if event.BytesReceived <= last {
+			t.Errorf("Event %d: progress did not increase (%d after %d)", i, event.BytesReceived, last)
+		}
+		last = event.BytesReceived
+	}
+
+	final := received[10]
+	if final.Type != ProgressEventComplete || final.BytesReceived != total || final.Percent != 100 {
+		t.Errorf("Unexpected completion event: %+v", final)
+	}
+}
+
+func TestUploadProgress_ErrorEvent(t *testing.T) {
+	tracker := NewUploadProgressTracker()
+	uploadID := tracker.Create(1)
+
+	events, _, err := tracker.Subscribe(uploadID, 1)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	tracker.Fail(uploadID, "Bad Request")
+
+	event, ok := <-events
+	if !ok || event.Type != ProgressEventError || event.Error != "Bad Request" {
+		t.Errorf("Expected error event, got %+v", event)
+	}
+
+	if _, ok := <-events; ok {
+		t.Error("Expected channel to be closed after the final event")
+	}
+}
+
+func TestUploadProgress_OnlyOwnerCanSubscribe(t *testing.T) {
+	tracker := NewUploadProgressTracker()
+	uploadID := tracker.Create(1)
+
+	if _, _, err := tracker.Subscribe(uploadID, 2); err != ErrUploadNotFound {
+		t.Errorf("Expected ErrUploadNotFound for another user, got %v", err)
+	}
+
+	if _, _, err := tracker.Subscribe("unknown", 1); err != ErrUploadNotFound {
+		t.Errorf("Expected ErrUploadNotFound for unknown upload, got %v", err)
+	}
+}
+
+func TestUploadProgress_UnsubscribeAfterStaleCleanup(t *testing.T) {
+	tracker := NewUploadProgressTracker()
+	uploadID := tracker.Create(1)
+	events, unsubscribe, err := tracker.Subscribe(uploadID, 1)
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+
+	// A later upload sweeps the stale one, closing its subscribers
+	tracker.ttl = 0
+	tracker.Create(2)
+	if _, open := <-events; open {
+		t.Fatal("Expected the stale upload's channel to be closed")
+	}
+
+	// The subscriber leaving afterwards must not close the channel again
+	unsubscribe()
+}
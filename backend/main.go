@@ -1,27 +1,31 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"golangmcp/internal/auth"
+	"golangmcp/internal/config"
 	"golangmcp/internal/db"
 	"golangmcp/internal/handlers"
 	"golangmcp/internal/models"
 	"golangmcp/internal/security"
+	"golangmcp/internal/services"
 	"golangmcp/internal/session"
 	"golangmcp/internal/websocket"
 )
 
-var jwtKey = []byte("my_secret_key")
-
 // InitializeDatabase sets up the database connection and performs migrations
-func InitializeDatabase() error {
+func InitializeDatabase(dbConfig config.DatabaseConfig) error {
 	// Connect to SQLite database
-	dsn := "./golangmcp.db"
-	return db.InitDatabase(dsn)
+	return db.InitDatabase(dbConfig.Path)
 }
 
 // MigrateDatabase performs database migrations
@@ -57,33 +61,192 @@ func SeedDatabase(database *gorm.DB) error {
 }
 
 func main() {
+	// Load and validate the configuration first, so a bad setting stops startup
+	// before anything is opened
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	gin.SetMode(cfg.Server.Mode)
+
+	// Sign tokens with the configured key rather than the development default
+	auth.SetJWTSecret([]byte(cfg.Auth.JWTSecret))
+
 	// Initialize database
-	err := InitializeDatabase()
+	err = InitializeDatabase(cfg.Database)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// Spill large uploads to the configured temp directory
+	if err := services.GlobalUploadTempManager.UpdatePolicy(cfg.UploadTempPolicy()); err != nil {
+		log.Fatalf("Invalid upload temp configuration: %v", err)
+	}
+
+	// Refuse to start when uploads could not be stored
+	if err := handlers.ValidateUploadDirectories(); err != nil {
+		log.Fatalf("Upload storage check failed, make sure the upload directories are writable: %v", err)
+	}
+
+	// Limit the description and tags stored with files
+	if err := services.GlobalFileMetadata.UpdatePolicy(&cfg.FileMetadata); err != nil {
+		log.Fatalf("Invalid file metadata configuration: %v", err)
+	}
+
+	// Render thumbnails of uploaded documents with the configured command
+	if thumbnailer := cfg.DocumentThumbnailer(); thumbnailer != nil {
+		services.GlobalDocumentPreviews.SetThumbnailer(thumbnailer)
+	}
+	if err := services.GlobalDocumentPreviews.UpdatePolicy(cfg.DocumentPreviewPolicy()); err != nil {
+		log.Fatalf("Invalid document preview configuration: %v", err)
+	}
+
+	// Extend the audit event taxonomy with the configured domain events
+	if err := cfg.RegisterAuditEvents(); err != nil {
+		log.Fatalf("Invalid audit event configuration: %v", err)
+	}
+
+	// Send alerts and account messages with the configured notifier
+	if err := services.GlobalNotifier.Configure(cfg.Notifier); err != nil {
+		log.Fatalf("Invalid notifier configuration: %v", err)
+	}
+
+	// Write the JSON access log to the configured output
+	accessLog, err := cfg.OpenAccessLog()
+	if err != nil {
+		log.Fatalf("Failed to open access log: %v", err)
+	}
+	security.GlobalAccessLogger.SetWriter(accessLog)
+	if err := security.GlobalAccessLogger.UpdateConfig(cfg.AccessLogConfig()); err != nil {
+		log.Fatalf("Invalid access log configuration: %v", err)
+	}
+
+	// Apply the configured request, upload and rate limits
+	if _, err := security.GlobalSecurityConfig.UpdateConfig(cfg.ApplySecurity); err != nil {
+		log.Fatalf("Invalid security configuration: %v", err)
+	}
+
+	// Let load tests through the rate limiter with the bypass token
+	if token := cfg.RateLimitBypassToken(); token != "" {
+		security.GlobalExemptionManager.SetBypassToken(token)
+		log.Printf("Warning: Rate limit bypass token is enabled")
+	}
+
+	// Serve HTTPS when a certificate is configured, validated by config.Load
+	tlsConfig := cfg.TLSConfig()
+
 	// Seed database with initial data
 	err = SeedDatabase(db.DB)
 	if err != nil {
 		log.Fatalf("Failed to seed database: %v", err)
 	}
 
-	// Start session cleanup
-	session.StartSessionCleanup()
-	log.Println("Session cleanup started")
+	// Audit sessions evicted by the per-user session limit or used from an address
+	// they aren't bound to, requests let through by rate limit exemptions,
+	// malware detections, logins from new devices, failed onboarding hooks and
+	// alerts raised from the audit logs
+	auditLogger := services.NewAuditLogger()
+	session.GlobalSessionManager.SetEvictionHandler(func(s *session.Session) {
+		auditLogger.LogSessionEvicted(s.UserID, s.ID, s.IPAddress, s.UserAgent)
+	})
+	session.GlobalSessionManager.SetIPMismatchHandler(func(s *session.Session, ipAddress, userAgent string) {
+		auditLogger.LogSessionIPMismatch(s.UserID, s.ID, s.IPAddress, ipAddress, userAgent)
+	})
+
+	security.GlobalExemptionManager.SetExemptionHandler(func(c *gin.Context, e *security.Exemption) {
+		auditLogger.LogRateLimitExempted(e.UserID, e.Reason, c.Request.URL.Path, c.ClientIP(), c.Request.UserAgent())
+	})
+
+	services.GlobalFileScanManager.SetDetectionHandler(func(d *services.Detection) {
+		auditLogger.LogMalwareDetected(d.UserID, d.FileID, d.Threat, d.Path)
+	})
+
+	services.GlobalFileExpiryManager.SetExpiryHandler(func(f *services.ExpiredFile) {
+		auditLogger.LogFileExpired(f.UserID, f.FileID, f.Filename, f.ExpiresAt)
+	})
+
+	services.GlobalLoginAnomalyDetector.SetNewDeviceHandler(func(a *services.LoginAnomaly) {
+		auditLogger.LogNewDeviceLogin(a.UserID, a.Fingerprint, a.IPAddress, a.UserAgent)
+	})
+
+	services.GlobalAuditAlertScanner.SetAlertHandler(func(a *services.AuditAlert) {
+		log.Printf("[ALERT] %s: %d events for %q within %s", a.Rule, a.Count, a.Group, a.Window)
+		auditLogger.LogSecurityAlert(a)
+	})
+	if err := services.GlobalAuditAlertScanner.UpdatePolicy(cfg.AuditAlertPolicy()); err != nil {
+		log.Fatalf("Invalid audit alert configuration: %v", err)
+	}
+
+	services.GlobalOnboarding.SetFailureHandler(func(hook string, user models.User, err error) {
+		auditLogger.LogOnboardingFailed(user.ID, hook, err)
+	})
+	if err := services.GlobalOnboarding.UpdatePolicy(cfg.OnboardingPolicy()); err != nil {
+		log.Fatalf("Invalid onboarding configuration: %v", err)
+	}
+
+	// Restore the JWT signing keys, so tokens signed before the restart or by
+	// another instance keep validating
+	if err := auth.GlobalSigningKeys.LoadConfig(db.DB); err != nil {
+		log.Printf("Warning: Failed to load signing keys: %v", err)
+	}
+
+	// Restore maintenance mode from before the restart
+	if err := security.GlobalMaintenanceManager.LoadState(db.DB); err != nil {
+		log.Printf("Warning: Failed to load maintenance state: %v", err)
+	}
+
+	// Start background jobs; data retention covers audit logs, file access logs,
+	// deleted files, sessions and commands
+	if err := services.GlobalRetentionManager.Register(services.GlobalScheduler); err != nil {
+		log.Printf("Warning: Failed to register retention cleanup: %v", err)
+	}
+	if err := services.GlobalFileExpiryManager.Register(services.GlobalScheduler); err != nil {
+		log.Printf("Warning: Failed to register file expiry: %v", err)
+	}
+	if err := services.GlobalFileScanManager.Register(services.GlobalScheduler); err != nil {
+		log.Printf("Warning: Failed to register file scanning: %v", err)
+	}
+	if err := services.GlobalDownloadPolicy.Register(services.GlobalScheduler); err != nil {
+		log.Printf("Warning: Failed to register download rate limit cleanup: %v", err)
+	}
+	if err := services.GlobalUploadTempManager.Register(services.GlobalScheduler); err != nil {
+		log.Printf("Warning: Failed to register upload temp file sweep: %v", err)
+	}
+	if err := services.GlobalAuditAlertScanner.Register(services.GlobalScheduler); err != nil {
+		log.Printf("Warning: Failed to register audit alert scan: %v", err)
+	}
+	services.GlobalScheduler.Start()
+	log.Println("Background jobs started")
+
+	// Write audit logs in batches off the request path
+	services.GlobalAuditWriter.Start(db.DB)
 
 	// Initialize WebSocket hub
 	websocket.InitializeWebSocket()
 
-	// Initialize Gin router
-	r := gin.Default()
+	// Initialize Gin router; panics are recovered by RecoveryMiddleware below
+	r := gin.New()
+	r.MaxMultipartMemory = cfg.Upload.MultipartMemory
+
+	// Plain HTTP only redirects once HTTPS is served
+	if tlsConfig.Enabled {
+		r.Use(security.HTTPSRedirectMiddleware(tlsConfig.Addr))
+	}
 
 	// Apply security middleware
+	r.Use(security.RequestIDMiddleware()) // first, so every log of the request carries its ID
+	r.Use(security.AccessLogMiddleware())
+	r.Use(security.RecoveryMiddleware(func(p *security.PanicReport) {
+		auditLogger.LogSystemError("panic", p.Path, map[string]string{"type": "panic", "method": p.Method, "panic": p.Value}, p.ClientIP, p.UserAgent, p.RequestID)
+	}))
 	r.Use(security.SecurityHeadersMiddleware())
 	r.Use(security.CORSMiddleware())
+	r.Use(security.CompressionMiddleware())
+	r.Use(security.StringIDMiddleware(cfg.Server.StringIDs)) // after compression, so it rewrites the plain JSON
+	r.Use(security.MaintenanceMiddleware())
 	r.Use(security.RateLimitMiddleware())
-	r.Use(security.RequestSizeMiddleware(security.DefaultSecurityConfig.MaxRequestSize))
+	r.Use(security.ConfiguredRequestSizeMiddleware()) // upload routes override it with MaxBodySize
+	r.Use(security.ConfiguredTimeoutMiddleware())     // streaming routes disable it with RequestTimeout(0)
 	r.Use(security.InputSanitizationMiddleware())
 	r.Use(security.AuditLogMiddleware())
 	
@@ -100,27 +263,35 @@ func main() {
 	r.POST("/register", handlers.RegisterHandler)
 	r.POST("/login", handlers.LoginHandler)
 	r.POST("/logout", handlers.LogoutHandler)
+	r.POST("/auth/tokens/scoped", handlers.AuthMiddleware(), handlers.CreateScopedTokenHandler)
 
 	// Profile management endpoints
 	r.GET("/profile", handlers.AuthMiddleware(), handlers.GetProfileHandler)
 	r.PUT("/profile", handlers.AuthMiddleware(), handlers.UpdateProfileHandler)
 	r.POST("/profile/change-password", handlers.AuthMiddleware(), handlers.ChangePasswordHandler)
+	r.GET("/profile/export", security.RequestTimeout(0), handlers.AuthMiddleware(), handlers.ExportMyDataHandler)
+	r.GET("/me/activity", handlers.AuthMiddleware(), handlers.GetMyActivityHandler)
 
 	// Protected endpoints
 	r.GET("/protected", handlers.AuthMiddleware(), protectedHandler)
 
 	// Secure file upload endpoints
-	r.POST("/upload/:fileType", handlers.AuthMiddleware(), handlers.SecureUploadHandler)
+	r.POST("/upload/:fileType", security.MaxBodySize(handlers.MaxDocumentSize+security.MultipartOverhead), handlers.AuthMiddleware(), security.UploadConcurrencyLimit(), handlers.SecureUploadHandler)
 	r.GET("/upload/stats", handlers.AuthMiddleware(), handlers.GetSecureUploadStatsHandler)
+	r.GET("/upload/preview/*filename", handlers.AuthMiddleware(), handlers.GetDocumentPreviewHandler)
 	r.POST("/scan/:fileId", handlers.AuthMiddleware(), handlers.ScanFileHandler)
 
 	// Avatar upload endpoints (legacy)
-	r.POST("/profile/avatar", handlers.AuthMiddleware(), handlers.UploadAvatarHandler)
+	r.POST("/profile/avatar", security.MaxBodySize(handlers.MaxFileSize+security.MultipartOverhead), handlers.AuthMiddleware(), security.UploadConcurrencyLimit(), handlers.UploadAvatarHandler)
 	r.DELETE("/profile/avatar", handlers.AuthMiddleware(), handlers.DeleteAvatarHandler)
-	r.GET("/uploads/avatars/:filename", handlers.GetAvatarHandler)
+	r.GET("/uploads/avatars/*filename", handlers.GetAvatarHandler)
 
 	// Admin upload statistics
 	r.GET("/admin/uploads/stats", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.GetUploadStatsHandler)
+	r.GET("/admin/uploads/naming", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.GetUploadNamingConfigHandler)
+	r.PUT("/admin/uploads/naming", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.UpdateUploadNamingConfigHandler)
+	r.GET("/admin/uploads/previews", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.GetDocumentPreviewPolicyHandler)
+	r.PUT("/admin/uploads/previews", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.UpdateDocumentPreviewPolicyHandler)
 
 	// Session management endpoints
 	r.GET("/sessions", handlers.AuthMiddleware(), handlers.GetUserSessionsHandler)
@@ -130,7 +301,25 @@ func main() {
 	// Admin session management
 	r.GET("/admin/sessions", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.GetAllSessionsHandler)
 	r.GET("/admin/sessions/stats", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.GetSessionStatsHandler)
+	r.GET("/admin/sessions/config", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.GetSessionConfigHandler)
+	r.PUT("/admin/sessions/config", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.UpdateSessionConfigHandler)
+	r.GET("/admin/files/expiry", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.GetFileExpiryPolicyHandler)
+	r.PUT("/admin/files/expiry", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.UpdateFileExpiryPolicyHandler)
+	r.GET("/admin/files/metadata", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.GetFileMetadataPolicyHandler)
+	r.PUT("/admin/files/metadata", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.UpdateFileMetadataPolicyHandler)
+	r.GET("/admin/retention", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.GetRetentionPolicyHandler)
+	r.PUT("/admin/retention", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.UpdateRetentionPolicyHandler)
+	r.POST("/admin/retention/run", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.RunRetentionCleanupHandler)
+	r.GET("/admin/selftest", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.SelfTestHandler)
+
+	// Manual cleanups, run synchronously
+	r.POST("/admin/cleanup/audit-logs", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.CleanupAuditLogsNowHandler)
+	r.POST("/admin/cleanup/old-data", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.RunRetentionCleanupHandler)
+	r.POST("/admin/cleanup/sessions", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.CleanupSessionsNowHandler)
+	r.POST("/admin/cleanup/cache", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.CleanupCacheNowHandler)
+	r.GET("/admin/search", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.AdminSearchHandler)
 	r.DELETE("/admin/sessions/user/:userId", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.InvalidateUserSessionsHandler)
+	r.DELETE("/admin/sessions/:sessionId", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.AdminInvalidateSessionHandler)
 
 	// Role-based authorization endpoints
 	r.GET("/roles", handlers.GetRolesHandler)
@@ -151,6 +340,8 @@ func main() {
 	r.GET("/admin/users/:id", handlers.AuthMiddleware(), handlers.RequirePermission("admin.users"), handlers.GetUserProfileHandler)
 	r.PUT("/admin/users/:id", handlers.AuthMiddleware(), handlers.RequirePermission("admin.users"), handlers.UpdateUserProfileHandler)
 	r.DELETE("/admin/users/:id", handlers.AuthMiddleware(), handlers.RequirePermission("admin.users"), handlers.DeleteUserHandler)
+	r.GET("/admin/users/:id/activity", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetUserActivityHandler)
+	r.GET("/admin/users/:id/export", security.RequestTimeout(0), handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.ExportUserDataHandler)
 
 	// Security endpoints
 	r.GET("/security/status", handlers.GetSecurityStatusHandler)
@@ -160,9 +351,42 @@ func main() {
 	r.GET("/security/headers", handlers.GetSecurityHeadersHandler)
 	r.GET("/security/test", handlers.TestSecurityFeaturesHandler)
 	r.GET("/security/metrics", handlers.AuthMiddleware(), handlers.RequirePermission("admin.stats"), handlers.GetSecurityMetricsHandler)
+	r.POST("/admin/security/metrics/reset", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.ResetSecurityMetricsHandler)
 
 	// Admin security endpoints
+	r.GET("/admin/security/status", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetSecurityStatusDetailsHandler)
 	r.PUT("/admin/security/config", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.UpdateSecurityConfigHandler)
+	r.GET("/admin/security/rate-limit-exemptions", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetRateLimitExemptionsHandler)
+	r.PUT("/admin/security/rate-limit-exemptions", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.UpdateRateLimitExemptionsHandler)
+	r.GET("/admin/security/login-anomalies", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetLoginAnomalyConfigHandler)
+	r.PUT("/admin/security/login-anomalies", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.UpdateLoginAnomalyConfigHandler)
+	r.GET("/admin/security/registration", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetRegistrationPolicyHandler)
+	r.PUT("/admin/security/registration", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.UpdateRegistrationPolicyHandler)
+	r.GET("/admin/jobs", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.ListJobsHandler)
+	r.POST("/admin/jobs/:name/run", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.RunJobHandler)
+	r.GET("/admin/maintenance", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.GetMaintenanceHandler)
+	r.PUT("/admin/maintenance", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.UpdateMaintenanceHandler)
+	r.GET("/admin/security/svg-policy", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetSVGPolicyHandler)
+	r.PUT("/admin/security/svg-policy", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.UpdateSVGPolicyHandler)
+	r.GET("/admin/security/filename-policy", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetFilenamePolicyHandler)
+	r.PUT("/admin/security/filename-policy", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.UpdateFilenamePolicyHandler)
+	r.GET("/admin/security/download-policy", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetDownloadPolicyHandler)
+	r.PUT("/admin/security/download-policy", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.UpdateDownloadPolicyHandler)
+	r.GET("/admin/security/compression", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetCompressionConfigHandler)
+	r.PUT("/admin/security/compression", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.UpdateCompressionConfigHandler)
+	r.GET("/admin/security/access-log", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetAccessLogConfigHandler)
+	r.PUT("/admin/security/access-log", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.UpdateAccessLogConfigHandler)
+	r.GET("/admin/security/audit-alerts", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetAuditAlertPolicyHandler)
+	r.PUT("/admin/security/audit-alerts", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.UpdateAuditAlertPolicyHandler)
+	r.GET("/admin/security/audit-sampling", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetAuditSamplingHandler)
+	r.PUT("/admin/security/audit-sampling", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.UpdateAuditSamplingHandler)
+	r.GET("/admin/security/password-policy", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetPasswordPolicyHandler)
+	r.PUT("/admin/security/password-policy", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.UpdatePasswordPolicyHandler)
+	r.GET("/admin/security/password-hasher", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetPasswordHasherHandler)
+	r.PUT("/admin/security/password-hasher", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.UpdatePasswordHasherHandler)
+	r.GET("/admin/security/jwt-keys", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetSigningKeysHandler)
+	r.PUT("/admin/security/jwt-keys", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.UpdateSigningKeysHandler)
+	r.POST("/admin/security/jwt-keys/rotate", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.RotateSigningKeyHandler)
 	r.GET("/admin/security/logs", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), handlers.GetSecurityLogsHandler)
 
 	// System metrics endpoints
@@ -175,16 +399,33 @@ func main() {
 	r.GET("/api/metrics/config", handlers.AuthMiddleware(), handlers.GetMetricsConfigHandler)
 
 	// WebSocket endpoint for real-time metrics
-	r.GET("/ws/metrics", websocket.HandleWebSocket)
+	r.POST("/ws/ticket", handlers.AuthMiddleware(), websocket.IssueTicketHandler)
+	r.POST("/uploads/progress", handlers.AuthMiddleware(), websocket.CreateUploadHandler)
+	r.GET("/ws/uploads/:uploadId", security.RequestTimeout(0), websocket.HandleUploadProgressWebSocket)
+	r.GET("/ws/metrics", security.RequestTimeout(0), websocket.HandleWebSocket)
+	r.GET("/sse/metrics", security.RequestTimeout(0), websocket.HandleSSEMetrics)
+	r.GET("/admin/websocket/clients", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.ListWebSocketClientsHandler)
+	r.DELETE("/admin/websocket/clients/:clientId", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.DisconnectWebSocketClientHandler)
 
 	// File management endpoints
 	r.GET("/api/files", handlers.AuthMiddleware(), handlers.GetFilesHandler)
 	r.GET("/api/files/:id", handlers.AuthMiddleware(), handlers.GetFileHandler)
-	r.POST("/api/files/upload", handlers.AuthMiddleware(), handlers.UploadFileHandler)
-	r.GET("/api/files/:id/download", handlers.AuthMiddleware(), handlers.DownloadFileHandler)
+	r.POST("/api/files/batch-get", handlers.AuthMiddleware(), handlers.BatchGetFilesHandler)
+	r.POST("/api/files/upload", security.MaxBodySize(handlers.MaxFileSizeFiles+security.MultipartOverhead), handlers.AuthMiddleware(), security.UploadConcurrencyLimit(), handlers.UploadFileHandler)
+	r.GET("/api/files/:id/download", security.RequestTimeout(0), handlers.AuthMiddleware(), handlers.DownloadLimitMiddleware(), handlers.DownloadFileHandler)
+	r.POST("/api/files/download-zip", security.RequestTimeout(0), handlers.AuthMiddleware(), handlers.DownloadLimitMiddleware(), handlers.DownloadZipHandler)
+	r.PATCH("/api/files/:id", handlers.AuthMiddleware(), handlers.UpdateFileHandler)
+	r.POST("/api/files/:id/rename", handlers.AuthMiddleware(), handlers.RenameFileHandler)
+	r.POST("/api/files/:id/verify", handlers.AuthMiddleware(), handlers.VerifyFileHandler)
 	r.DELETE("/api/files/:id", handlers.AuthMiddleware(), handlers.DeleteFileHandler)
 	r.GET("/api/files/stats", handlers.AuthMiddleware(), handlers.GetFileStatsHandler)
+	r.GET("/api/files/stats/timeline", handlers.AuthMiddleware(), handlers.GetFileTimelineHandler)
 	r.GET("/api/files/:id/logs", handlers.AuthMiddleware(), handlers.GetFileAccessLogsHandler)
+	r.POST("/admin/files/rehash", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.RehashFilesHandler)
+	r.GET("/admin/files/dedup", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.GetFileDedupReportHandler)
+	r.GET("/admin/files/quarantine", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.ListQuarantinedFilesHandler)
+	r.POST("/admin/files/quarantine/:id/release", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.ReleaseQuarantinedFileHandler)
+	r.DELETE("/admin/files/quarantine/:id", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.DeleteQuarantinedFileHandler)
 
 	// Optimized endpoints for better performance
 	optimizedHandlers := handlers.NewOptimizedHandlers()
@@ -193,8 +434,10 @@ func main() {
 	r.GET("/api/optimized/files/search", handlers.AuthMiddleware(), optimizedHandlers.SearchFilesOptimizedHandler)
 	r.GET("/api/optimized/files/stats", handlers.AuthMiddleware(), optimizedHandlers.GetFileStatsOptimizedHandler)
 	r.GET("/api/optimized/files/:id/logs", handlers.AuthMiddleware(), optimizedHandlers.GetFileAccessLogsOptimizedHandler)
-	r.POST("/api/optimized/files/batch-upload", handlers.AuthMiddleware(), optimizedHandlers.BatchUploadFilesHandler)
+	r.POST("/api/optimized/files/batch-upload", handlers.AuthMiddleware(), security.UploadConcurrencyLimit(), optimizedHandlers.BatchUploadFilesHandler)
 	r.GET("/api/optimized/database/stats", handlers.AuthMiddleware(), optimizedHandlers.GetDatabasePerformanceStatsHandler)
+	r.GET("/admin/database/stats-config", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.GetDatabaseStatsConfigHandler)
+	r.PUT("/admin/database/stats-config", handlers.AuthMiddleware(), handlers.AdminMiddleware(), handlers.UpdateDatabaseStatsConfigHandler)
 	r.POST("/api/optimized/database/cleanup", handlers.AuthMiddleware(), optimizedHandlers.CleanupOldDataHandler)
 
 	// Command execution endpoints
@@ -203,26 +446,37 @@ func main() {
 	r.GET("/api/commands", handlers.AuthMiddleware(), commandHandlers.GetCommandHistoryHandler)
 	r.GET("/api/commands/:id", handlers.AuthMiddleware(), commandHandlers.GetCommandHandler)
 	r.GET("/api/commands/stats", handlers.AuthMiddleware(), commandHandlers.GetCommandStatsHandler)
+	r.GET("/api/commands/config", handlers.AuthMiddleware(), handlers.AdminMiddleware(), commandHandlers.GetCommandConfigHandler)
+	r.PUT("/api/commands/config", handlers.AuthMiddleware(), handlers.AdminMiddleware(), commandHandlers.UpdateCommandConfigHandler)
 	r.GET("/api/commands/whitelist", handlers.AuthMiddleware(), commandHandlers.GetCommandWhitelistHandler)
 	r.POST("/api/commands/whitelist", handlers.AuthMiddleware(), commandHandlers.AddToWhitelistHandler)
 	r.DELETE("/api/commands/whitelist/:command", handlers.AuthMiddleware(), commandHandlers.RemoveFromWhitelistHandler)
 	r.POST("/api/commands/whitelist/initialize", handlers.AuthMiddleware(), commandHandlers.InitializeWhitelistHandler)
+	r.POST("/api/commands/whitelist/import", handlers.AuthMiddleware(), handlers.AdminMiddleware(), commandHandlers.ImportWhitelistHandler)
 
 	// Image processing endpoints
 	imageHandlers := handlers.NewImageHandlers()
-	r.POST("/api/images/upload", handlers.AuthMiddleware(), imageHandlers.UploadOptimizedImageHandler)
-	r.POST("/api/images/validate", handlers.AuthMiddleware(), imageHandlers.ValidateImageHandler)
+	r.POST("/api/images/upload", security.MaxBodySize(handlers.MaxImageSize+security.MultipartOverhead), handlers.AuthMiddleware(), security.UploadConcurrencyLimit(), imageHandlers.UploadOptimizedImageHandler)
+	r.POST("/api/images/validate", security.MaxBodySize(handlers.MaxImageSize+security.MultipartOverhead), handlers.AuthMiddleware(), imageHandlers.ValidateImageHandler)
 	r.GET("/api/images/stats", handlers.AuthMiddleware(), imageHandlers.GetImageStatsHandler)
 	r.PUT("/api/images/settings", handlers.AuthMiddleware(), imageHandlers.UpdateImageSettingsHandler)
-	r.GET("/api/images/:id", handlers.AuthMiddleware(), imageHandlers.GetImageFileHandler)
+	r.GET("/api/images/:id", security.RequestTimeout(0), handlers.AuthMiddleware(), handlers.DownloadLimitMiddleware(), imageHandlers.GetImageFileHandler)
 	r.POST("/api/images/batch-optimize", handlers.AuthMiddleware(), imageHandlers.BatchOptimizeImagesHandler)
 
 	// Performance optimization endpoints
-	performanceHandlers := handlers.NewPerformanceHandlers()
+	performanceHandlers, err := handlers.NewPerformanceHandlersWithTTLs(cfg.Cache.DefaultTTL.Duration, cfg.CacheTTLs())
+	if err != nil {
+		log.Fatalf("Invalid cache configuration: %v", err)
+	}
+	if err := performanceHandlers.RegisterCleanupJobs(services.GlobalScheduler); err != nil {
+		log.Printf("Warning: Failed to register cache cleanup: %v", err)
+	}
 	r.GET("/api/performance/users", handlers.AuthMiddleware(), performanceHandlers.GetUsersWithCacheHandler)
 	r.GET("/api/performance/files", handlers.AuthMiddleware(), performanceHandlers.GetFilesWithCacheHandler)
 	r.GET("/api/performance/cache/stats", handlers.AuthMiddleware(), performanceHandlers.GetCacheStatsHandler)
 	r.POST("/api/performance/cache/clear", handlers.AuthMiddleware(), performanceHandlers.ClearCacheHandler)
+	r.GET("/api/performance/cache/ttl", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), performanceHandlers.GetCacheTTLsHandler)
+	r.PUT("/api/performance/cache/ttl", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), performanceHandlers.UpdateCacheTTLHandler)
 	r.GET("/api/performance/rate-limit/stats", handlers.AuthMiddleware(), performanceHandlers.GetRateLimitStatsHandler)
 	r.GET("/api/performance/rate-limit/configs", handlers.AuthMiddleware(), performanceHandlers.GetRateLimitConfigsHandler)
 	r.PUT("/api/performance/rate-limit/config", handlers.AuthMiddleware(), performanceHandlers.UpdateRateLimitConfigHandler)
@@ -234,16 +488,78 @@ func main() {
 	r.GET("/api/audit/logs", handlers.AuthMiddleware(), auditHandlers.GetAuditLogsHandler)
 	r.GET("/api/audit/logs/:id", handlers.AuthMiddleware(), auditHandlers.GetAuditLogHandler)
 	r.GET("/api/audit/stats", handlers.AuthMiddleware(), auditHandlers.GetAuditStatsHandler)
-	r.GET("/api/audit/config", handlers.AuthMiddleware(), auditHandlers.GetAuditConfigHandler)
-	r.PUT("/api/audit/config", handlers.AuthMiddleware(), auditHandlers.UpdateAuditConfigHandler)
-	r.POST("/api/audit/cleanup", handlers.AuthMiddleware(), auditHandlers.CleanupAuditLogsHandler)
+	r.GET("/api/audit/config", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), auditHandlers.GetAuditConfigHandler)
+	r.PUT("/api/audit/config", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), auditHandlers.UpdateAuditConfigHandler)
+	r.POST("/api/audit/cleanup", handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), auditHandlers.CleanupAuditLogsHandler)
 	r.GET("/api/audit/events", handlers.AuthMiddleware(), auditHandlers.GetAuditEventsHandler)
 	r.GET("/api/audit/export", handlers.AuthMiddleware(), auditHandlers.ExportAuditLogsHandler)
+	r.GET("/api/audit/stream", security.RequestTimeout(0), handlers.AuthMiddleware(), handlers.RequirePermission("admin.security"), auditHandlers.StreamAuditLogsHandler)
 	r.GET("/api/audit/alerts", handlers.AuthMiddleware(), auditHandlers.GetSecurityAlertsHandler)
 	r.POST("/api/audit/test", handlers.AuthMiddleware(), auditHandlers.AuditTestHandler)
 
+	// Verify the server's own invariants, refusing to start in release mode
+	// when a critical one does not hold
+	report := handlers.SelfTest()
+	for _, check := range report.Checks {
+		if !check.Passed {
+			log.Printf("Self-test check %s failed (critical: %t): %s", check.Name, check.Critical, check.Error)
+		}
+	}
+	if !report.Passed && gin.Mode() == gin.ReleaseMode {
+		log.Fatalf("Self-test failed, %d critical check(s) did not pass", len(report.CriticalFailures()))
+	}
+	log.Printf("Self-test finished, passed: %t", report.Passed)
+
 	// Start server
-	r.Run(":8080")
+	server := &http.Server{
+		Addr:    cfg.Server.HTTPAddr,
+		Handler: r,
+	}
+	serverErr := make(chan error, 1)
+	if !tlsConfig.Enabled {
+		go func() {
+			log.Printf("Serving HTTP on %s", server.Addr)
+			serverErr <- server.ListenAndServe()
+		}()
+	} else {
+		go func() {
+			if err := http.ListenAndServe(tlsConfig.HTTPAddr, r); err != nil {
+				log.Fatalf("HTTP redirect server failed: %v", err)
+			}
+		}()
+
+		server.Addr = tlsConfig.Addr
+		server.TLSConfig = tlsConfig.ServerTLSConfig()
+		go func() {
+			log.Printf("Serving HTTPS on %s (minimum TLS %s), redirecting HTTP on %s", tlsConfig.Addr, tlsConfig.MinVersion, tlsConfig.HTTPAddr)
+			serverErr <- server.ListenAndServeTLS(tlsConfig.CertFile, tlsConfig.KeyFile)
+		}()
+	}
+
+	// Run until interrupted or the server fails, then stop background work and
+	// flush buffered audit logs before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var serveErr error
+	select {
+	case serveErr = <-serverErr:
+	case <-ctx.Done():
+		log.Println("Shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Duration)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Warning: Failed to shut down server: %v", err)
+		}
+	}
+
+	services.GlobalScheduler.Stop()
+	services.GlobalOnboarding.Wait() // before the audit writer, so failures are still audited
+	services.GlobalDocumentPreviews.Wait()
+	services.GlobalAuditWriter.Close()
+	if serveErr != nil && serveErr != http.ErrServerClosed {
+		log.Fatalf("Server failed: %v", serveErr)
+	}
 }
 
 
@@ -273,7 +589,7 @@ func getUsersHandler(c *gin.Context) {
 	}
 
 	log.Printf("Found %d users", len(users))
-	c.JSON(http.StatusOK, users)
+	handlers.RespondWithETag(c, users, "users")
 }
 
 // Create user handler
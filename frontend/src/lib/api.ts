@@ -155,9 +155,19 @@ export const profileAPI = {
       },
     });
   },
+  exportData: (format: 'json' | 'zip' = 'json') =>
+    api.get('/profile/export', { params: { format }, responseType: 'blob' }),
   getSessions: () => api.get('/sessions'),
   invalidateSession: (sessionId: string) => api.delete(`/sessions/${sessionId}`),
   invalidateAllSessions: () => api.delete('/sessions'),
+  getActivity: (limit?: number, offset?: number) => {
+    const queryParams = new URLSearchParams();
+    if (limit) queryParams.append('limit', limit.toString());
+    if (offset) queryParams.append('offset', offset.toString());
+
+    const queryString = queryParams.toString();
+    return api.get(`/me/activity${queryString ? `?${queryString}` : ''}`);
+  },
 };
 
 // Users API
@@ -307,6 +317,15 @@ export const filesAPI = {
     });
   },
   downloadFile: (id: number) => api.get(`/api/files/${id}/download`, { responseType: 'blob' }),
+  downloadZip: async (ids: number[]) => {
+    const token = await getCSRFToken();
+    return api.post('/api/files/download-zip', { ids }, {
+      responseType: 'blob',
+      headers: {
+        'X-CSRF-Token': token || '',
+      },
+    });
+  },
   deleteFile: async (id: number) => {
     const token = await getCSRFToken();
     return api.delete(`/api/files/${id}`, {
@@ -316,6 +335,23 @@ export const filesAPI = {
     });
   },
   getFileStats: () => api.get('/api/files/stats'),
+  getFileTimeline: (params?: {
+    granularity?: 'day' | 'week' | 'month';
+    start_date?: string;
+    end_date?: string;
+    user_id?: number;
+    type?: string;
+  }) => {
+    const queryParams = new URLSearchParams();
+    if (params?.granularity) queryParams.append('granularity', params.granularity);
+    if (params?.start_date) queryParams.append('start_date', params.start_date);
+    if (params?.end_date) queryParams.append('end_date', params.end_date);
+    if (params?.user_id) queryParams.append('user_id', params.user_id.toString());
+    if (params?.type) queryParams.append('type', params.type);
+
+    const queryString = queryParams.toString();
+    return api.get(`/api/files/stats/timeline${queryString ? `?${queryString}` : ''}`);
+  },
   getFileLogs: (id: number, limit?: number, offset?: number) => {
     const queryParams = new URLSearchParams();
     if (limit) queryParams.append('limit', limit.toString());
@@ -481,6 +517,11 @@ export interface File {
   is_public: boolean;
   description: string;
   tags: string;
+  is_scanned: boolean;
+  is_safe: boolean;
+  scan_result?: string;
+  expires_at?: string;
+  score?: number;
   created_at: string;
   updated_at: string;
 }
@@ -496,6 +537,31 @@ export interface FileStats {
   newest_file: string;
 }
 
+export interface ActivityEntry {
+  source: 'audit' | 'file' | 'command';
+  id: number;
+  action: string;
+  resource: string;
+  resource_id: number | null;
+  status: string;
+  ip_address: string;
+  details: string;
+  created_at: string;
+}
+
+export interface FileTimelineBucket {
+  start: string;
+  count: number;
+  total_bytes: number;
+}
+
+export interface FileTimeline {
+  granularity: 'day' | 'week' | 'month';
+  start: string;
+  end: string;
+  buckets: FileTimelineBucket[];
+}
+
 export interface FileAccessLog {
   id: number;
   file_id: number;
@@ -505,6 +571,9 @@ export interface FileAccessLog {
   action: string;
   ip_address: string;
   user_agent: string;
+  request_id: string;
+  status: 'success' | 'not_found' | 'denied';
+  bytes_served: number;
   created_at: string;
 }
 
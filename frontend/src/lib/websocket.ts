@@ -43,14 +43,14 @@ export class MetricsWebSocket {
           throw error;
         }
 
-        // Create WebSocket connection with auth token
-        const wsUrl = `${this.url}?token=${encodeURIComponent(token)}`;
+        // Pass the auth token as a subprotocol so it never appears in the URL
+        const wsUrl = this.url;
         console.log('Attempting WebSocket connection to:', wsUrl);
         console.log('Token length:', token.length);
         console.log('WebSocket support:', typeof WebSocket !== 'undefined');
         
         try {
-          this.ws = new WebSocket(wsUrl);
+          this.ws = new WebSocket(wsUrl, ['bearer', token]);
           console.log('WebSocket object created:', !!this.ws);
           console.log('Initial readyState:', this.ws.readyState);
           